@@ -0,0 +1,1232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+)
+
+// command is a blacklist subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(c *e.Config, args []string) error
+	// bare commands run without loading the configuration
+	bare bool
+}
+
+var (
+	commands = map[string]*command{}
+	secrets  = e.NewSecretStore
+	stdin    = io.Reader(os.Stdin)
+	stdout   = io.Writer(os.Stdout)
+)
+
+// register adds a subcommand to the commands map
+func register(cmd *command) {
+	commands[cmd.name] = cmd
+}
+
+func init() {
+	register(&command{
+		name:  "secret",
+		usage: "secret set <name> | secret delete <name> | secret list # Manage the encrypted secrets referenced as ${secret:<name>}, set reads the value from stdin",
+		run:   secretCmd,
+	})
+	register(&command{
+		name:  "source",
+		usage: "source add|delete [-apply] [-node hosts] [-desc <text>] [-ip <ip>] [-prefix <prefix>] <name> [<url>]",
+		run:   sourceCmd,
+	})
+	register(&command{
+		name:  "add-source",
+		usage: "add-source [-apply] [-url <url>] [-name <name>] [-node <node>] [-desc <text>] [-ip <ip>] [-prefix <prefix>] # Test fetch a source, detect its format and print the set commands that add it, prompting for a missing url or name",
+		run:   addSourceCmd,
+	})
+	register(&command{
+		name:  "migrate",
+		usage: "migrate [-apply] <file> # Convert a legacy blacklist configuration to set commands",
+		run:   migrateCmd,
+	})
+	register(&command{
+		name:  "import-pihole",
+		usage: "import-pihole [-apply] [-o <file>] <dir>|<gravity.db> # Convert a Pi-hole's adlists and allow and deny lists to set commands, or with -o a configuration file -f loads",
+		run:   importPiHoleCmd,
+	})
+	register(&command{
+		name:  "exclude",
+		usage: "exclude add|delete [-apply] [-node blacklist] <domain>... | exclude pending [-apply] [-node blacklist] -file <file>",
+		run:   excludeCmd,
+	})
+	register(&command{
+		name:  "effective",
+		usage: "effective [-o <file>] # Print the resolved exclusions and includes",
+		run:   effectiveCmd,
+	})
+	register(&command{
+		name:  "export",
+		usage: "export adguard -url <url> [-user <user>] [-pass <password>] | export blocky -list <file> [-group edgeos] [-o <file>] | export coredns|dnscrypt-blocked|dnscrypt-cloaking|dnsmasq|domains|hosts|rpz|wildcard [-ip <ip>] [-o <file>] | export abp [-title <title>] [-expires <duration>] [-o <file>] | export -format domains|hosts|wildcard [-o <file>] # Export the merged blacklist to other resolvers",
+		run:   exportCmd,
+	})
+	register(&command{
+		name:  "update",
+		usage: "update [-node <node>] [-source <name>] [-reload=false] # Refetch and regenerate only the matching sources, leaving the other generated files untouched",
+		run:   updateCmd,
+	})
+	register(&command{
+		name:  "render",
+		usage: "render # Print the generated dnsmasq configuration to stdout without writing files or reloading dnsmasq",
+		run:   renderCmd,
+	})
+	register(&command{
+		name:  "audit",
+		usage: "audit [-json] # Report the entries, changes, conflicts and rejected lines a run would produce, without writing files or reloading dnsmasq",
+		run:   auditCmd,
+	})
+	register(&command{
+		name:  "snapshot",
+		usage: "snapshot [-o <file>] # Write the configuration as a JSON snapshot, replay it elsewhere with -f <file>",
+		run:   snapshotCmd,
+	})
+	register(&command{
+		name:  "stats",
+		usage: "stats [-log <file>] [-since <window>] [-top <n>] [-follow <interval>] # Report blocked queries from dnsmasq's query log",
+		run:   statsCmd,
+	})
+	register(&command{
+		name:  "report",
+		usage: "report [-file <file>] [-days <n>] [-json] # Summarize the -history file's trends: blacklist growth, failed runs, run times and failing sources",
+		run:   reportCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "overlap",
+		usage: "overlap [-min <similarity>] # Report how much the sources' domains overlap, to find redundant lists",
+		run:   overlapCmd,
+	})
+	register(&command{
+		name:  "backup",
+		usage: "backup [-o <file>] [-url <url>] # Archive the generated files, source cache and state files as a tar.gz, uploading it with PUT to an http(s):// or s3:// url",
+		run:   backupCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "version",
+		usage: "version [-check] [-url <url>] # Print the version, commit and build date, with -check look for a newer release and its asset for this architecture",
+		run:   versionCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "self-update",
+		usage: "self-update [-force] -key <file> [-url <url>] # Install the latest release's program for this architecture, checking its SHA256 checksum and the checksums' ed25519 signature by -key",
+		run:   selfUpdateCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "restore",
+		usage: "restore <file>|<url> # Restore the generated files, source cache and state files from a backup archive",
+		run:   restoreCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "catalog",
+		usage: "catalog [-json] # List the built-in sources a source can name with its catalog leaf",
+		run:   catalogCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "doctor",
+		usage: "doctor [-url <url>] # Check the environment blacklist runs in and how to fix any problems",
+		run:   doctorCmd,
+		bare:  true,
+	})
+	register(&command{
+		name:  "optimize",
+		usage: "optimize [-log <file>] [-since <window>] [-hot <file>] [-min <hits>] # Report sources whose domains are never queried",
+		run:   optimizeCmd,
+	})
+	register(&command{
+		name:  "completion",
+		usage: "completion bash|zsh # Print a shell completion script for the subcommands and the configured node and source names",
+		run:   completionCmd,
+	})
+}
+
+// commandNames returns a sorted list of registered subcommands
+func commandNames() []string {
+	var names []string
+	for k := range commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runCommand runs the subcommand named by args[0]
+func runCommand(c *e.Config, args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, valid commands are: %v", args[0], strings.Join(commandNames(), ", "))
+	}
+	return cmd.run(c, args[1:])
+}
+
+// emit prints configuration commands and applies them if requested
+func emit(c *e.Config, cmds []string, apply bool) error {
+	fmt.Fprintln(stdout, strings.Join(cmds, "\n"))
+	if !apply {
+		return nil
+	}
+
+	b, err := c.Apply(cmds)
+	if err != nil {
+		return fmt.Errorf("unable to apply configuration: %v\n%s", err, b)
+	}
+	return nil
+}
+
+// subFlags returns a FlagSet for a subcommand with the common -apply and -node flags
+func subFlags(name, node string) (*flag.FlagSet, *bool, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	apply := fs.Bool("apply", false, "Apply the commands via cli-shell-api in session")
+	n := fs.String("node", node, "Blacklist node (blacklist, domains or hosts)")
+	return fs, apply, n
+}
+
+func sourceCmd(c *e.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: " + commands["source"].usage)
+	}
+
+	var (
+		act             = args[0]
+		fs, apply, node = subFlags("source "+act, "hosts")
+		desc            = fs.String("desc", "", "Source description")
+		ip              = fs.String("ip", "", "Source dns-redirect-ip")
+		prefix          = fs.String("prefix", "", "Line prefix to strip")
+	)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch {
+	case act == "add" && fs.NArg() == 2:
+		s := &e.Source{Desc: *desc, IP: *ip, Name: fs.Arg(0), Node: *node, Prefix: *prefix}
+		switch {
+		case strings.Contains(fs.Arg(1), "://"):
+			s.URL = fs.Arg(1)
+		default:
+			s.File = fs.Arg(1)
+		}
+		cmds, err := c.AddSource(s)
+		if err != nil {
+			return err
+		}
+		return emit(c, cmds, *apply)
+
+	case act == "delete" && fs.NArg() == 1:
+		cmds, err := c.DeleteSource(*node, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		return emit(c, cmds, *apply)
+	}
+
+	return errors.New("usage: " + commands["source"].usage)
+}
+
+func addSourceCmd(c *e.Config, args []string) error {
+	var (
+		fs, apply, node = subFlags("add-source", "")
+		desc            = fs.String("desc", "", "Source description")
+		ip              = fs.String("ip", "", "Source dns-redirect-ip")
+		name            = fs.String("name", "", "Source `<name>`")
+		prefix          = fs.String("prefix", "", "Line prefix to strip, detected if not set")
+		url             = fs.String("url", "", "Source `<url>`, file:// for a local file")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["add-source"].usage)
+	}
+
+	in := bufio.NewReader(stdin)
+	if err := prompt(in, "URL", url); err != nil {
+		return err
+	}
+
+	p, err := c.Probe(*url, *node, *prefix)
+	if err != nil {
+		return fmt.Errorf("unable to fetch %v: %v", *url, err)
+	}
+
+	fmt.Fprintf(stdout, "Format:   %v (node %v, prefix %q)\n", p.Format, p.Node, p.Prefix)
+	fmt.Fprintf(stdout, "Entries:  %d from %d lines, %d rejected\n", p.Entries, p.Lines, p.Rejected)
+	for _, s := range p.Sample {
+		fmt.Fprintf(stdout, "          %v\n", s)
+	}
+	for _, s := range p.Rejects {
+		fmt.Fprintf(stdout, "Rejected: %v\n", s)
+	}
+	if p.Entries == 0 {
+		return fmt.Errorf("no entries found in %v, set -prefix or -node", *url)
+	}
+
+	if err = prompt(in, "Name", name); err != nil {
+		return err
+	}
+
+	s := &e.Source{Desc: *desc, IP: *ip, Name: *name, Node: p.Node, Prefix: p.Prefix, URL: p.URL}
+	cmds, err := c.AddSource(s)
+	if err != nil {
+		return err
+	}
+	return emit(c, cmds, *apply)
+}
+
+// prompt asks for a missing value on stdin
+func prompt(in *bufio.Reader, label string, v *string) error {
+	if *v != "" {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%v: ", label)
+	s, err := in.ReadString('\n')
+	if *v = strings.TrimSpace(s); *v == "" {
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("%v is required", strings.ToLower(label))
+		}
+		return err
+	}
+	return nil
+}
+
+func excludeCmd(c *e.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: " + commands["exclude"].usage)
+	}
+
+	act := args[0]
+	fs, apply, node := subFlags("exclude "+act, "blacklist")
+	pending := fs.String("file", "", "Pending exclusions `<file>` written by the block page's unblock requests")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch {
+	case act == "pending" && *pending != "" && fs.NArg() == 0:
+		return excludePending(c, *pending, *node, *apply)
+
+	case act == "add" && fs.NArg() > 0:
+		cmds, err := c.AddExclude(*node, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		return emit(c, cmds, *apply)
+
+	case act == "delete" && fs.NArg() > 0:
+		cmds, err := c.DeleteExclude(*node, fs.Args()...)
+		if err != nil {
+			return err
+		}
+		return emit(c, cmds, *apply)
+	}
+
+	return errors.New("usage: " + commands["exclude"].usage)
+}
+
+// excludePending emits exclude commands for the pending unblock requests,
+// which are cleared once applied
+func excludePending(c *e.Config, file, node string, apply bool) error {
+	domains, err := e.ReadPending(file)
+	if err != nil || len(domains) == 0 {
+		return err
+	}
+
+	cmds, err := c.AddExclude(node, domains...)
+	if err != nil {
+		return err
+	}
+
+	if err = emit(c, cmds, apply); err != nil || !apply {
+		return err
+	}
+	return ioutil.WriteFile(file, nil, 0644)
+}
+
+func effectiveCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("effective", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	out := fs.String("o", "", "Write to `<file>` instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["effective"].usage)
+	}
+
+	if *out == "" {
+		_, err := c.Effective().WriteTo(stdout)
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+
+	if _, err = c.Effective().WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// logFlags adds the dnsmasq query log flags shared by stats and optimize
+func logFlags(fs *flag.FlagSet) (file *string, since *time.Duration) {
+	file = fs.String("log", "/var/log/messages", "dnsmasq query log `<file>`, see dnsmasq's --log-queries")
+	since = fs.Duration("since", 24*time.Hour, "Report queries logged within this `<window>`")
+	return file, since
+}
+
+func statsCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		file, since = logFlags(fs)
+		follow      = fs.Duration("follow", 0, "Keep reading the log, reporting every `<interval>`")
+		n           = fs.Int("top", 10, "Number of top blocked domains and clients to report")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["stats"].usage)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	q := e.NewQueryStats(time.Now(), *since)
+	if _, err = q.ReadFrom(f); err != nil {
+		return err
+	}
+
+	if *follow <= 0 {
+		return q.Report(stdout, *n)
+	}
+	return tailStats(q, f, *follow, *n, nil)
+}
+
+// tailStats reports q every interval, adding the lines appended to f in
+// between, until stop is closed
+func tailStats(q *e.QueryStats, f *os.File, every time.Duration, n int, stop <-chan struct{}) error {
+	t := time.NewTicker(every)
+	defer t.Stop()
+
+	for {
+		if err := q.Report(stdout, n); err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case now := <-t.C:
+			// start again if the log has been truncated or rotated in place
+			pos, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+			if fi, err := f.Stat(); err == nil && fi.Size() < pos {
+				if _, err = f.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+			}
+
+			q.Until = now
+			if _, err = q.ReadFrom(f); err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout)
+		}
+	}
+}
+
+func optimizeCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		file, since = logFlags(fs)
+		hot         = fs.String("hot", "", "Write the entries that were queried to `<file>` as a trimmed blocklist")
+		min         = fs.Int("min", 1, "Blocked queries needed for an entry to be kept in the -hot blocklist")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["optimize"].usage)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	q := e.NewQueryStats(time.Now(), *since)
+	if _, err = q.ReadFrom(f); err != nil {
+		return err
+	}
+
+	usage, err := c.Usage(q)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%-12s %-24s %8s %8s %8s\n", "Node", "Source", "Domains", "Queried", "Hits")
+	for _, u := range usage {
+		note := ""
+		if u.Queried == 0 {
+			note = "  never queried"
+		}
+		fmt.Fprintf(stdout, "%-12s %-24s %8d %8d %8d%s\n", u.Node, u.Source, u.Domains, u.Queried, u.Hits, note)
+	}
+
+	if *hot == "" {
+		return nil
+	}
+
+	var n int
+	if err = writeFile(*hot, func(w io.Writer) (err error) {
+		n, err = c.WriteHot(w, q, *min)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "\nWrote %d queried entries to %v\n", n, *hot)
+	return nil
+}
+
+func reportCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		file   = fs.String("file", c.Hist, "History `<file>` to report on, -history if not set")
+		days   = fs.Int("days", 90, "Report on the last `<n>` days")
+		asJSON = fs.Bool("json", false, "Print the report as JSON")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *days <= 0 {
+		return errors.New("usage: " + commands["report"].usage)
+	}
+
+	if *file == "" {
+		return errors.New("report needs a -history file, set -history or report -file")
+	}
+
+	runs, err := e.ReadHistory(*file)
+	if err != nil {
+		return err
+	}
+
+	r := e.NewHistoryReport(runs, time.Now(), *days)
+	if *asJSON {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(b))
+		return nil
+	}
+	return r.Report(stdout)
+}
+
+func overlapCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("overlap", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	min := fs.Float64("min", 0, "Only report source pairs with at least this Jaccard `<similarity>`, 0 to 1")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["overlap"].usage)
+	}
+
+	ov, err := c.Overlap()
+	if ov == nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%-12s %-24s %8s %8s\n", "Node", "Source", "Domains", "Unique")
+	for _, s := range ov.Sources {
+		note := ""
+		if s.Unique == 0 && s.Domains > 0 {
+			note = "  redundant"
+		}
+		fmt.Fprintf(stdout, "%-12s %-24s %8d %8d%s\n", s.Node, s.Source, s.Domains, s.Unique, note)
+	}
+
+	fmt.Fprintf(stdout, "\n%-37s %-37s %8s %8s\n", "Source", "Source", "Shared", "Jaccard")
+	for _, p := range ov.Pairs {
+		if p.Jaccard < *min {
+			continue
+		}
+		fmt.Fprintf(stdout, "%-37s %-37s %8d %8.3f\n", p.A.Node+"/"+p.A.Source, p.B.Node+"/"+p.B.Source, p.Shared, p.Jaccard)
+	}
+	return err
+}
+
+func backupCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	out := fs.String("o", "", "Write the archive to `<file>`")
+	url := fs.String("url", "", "Upload the archive to `<url>`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || (*out == "" && *url == "") {
+		return errors.New("usage: " + commands["backup"].usage)
+	}
+
+	var b bytes.Buffer
+	if err := c.Backup(&b); err != nil {
+		return err
+	}
+
+	if *out != "" {
+		if err := ioutil.WriteFile(*out, b.Bytes(), 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Backup written to %v\n", *out)
+	}
+
+	if *url != "" {
+		if err := c.Upload(*url, b.Bytes()); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Backup uploaded to %v\n", *url)
+	}
+	return nil
+}
+
+// buildInfo returns the version, commit and build date set by go build
+// -ldflags, falling back to the module and VCS details Go embeds
+func buildInfo() (v, commit, date string) {
+	v, commit, date = version, githash, build
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v, commit, date
+	}
+
+	if v == "UNKNOWN" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		v = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "vcs.revision" && commit == "UNKNOWN":
+			commit = s.Value
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+		case s.Key == "vcs.time" && date == "UNKNOWN":
+			date = s.Value
+		}
+	}
+	return v, commit, date
+}
+
+func versionCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		check = fs.Bool("check", false, "Check for a newer release")
+		url   = fs.String("url", e.ReleasesURL, "GitHub releases API `<url>` of the latest release")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["version"].usage)
+	}
+
+	v, commit, date := buildInfo()
+	fmt.Fprintf(stdout, "Version:\t%v\nCommit:\t\t%v\nBuild date:\t%v\nPlatform:\t%v/%v\n", v, commit, date, runtime.GOOS, c.Arch)
+	if !*check {
+		return nil
+	}
+
+	r, err := c.LatestRelease(*url)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Latest release:\t%v %v\n", r.Tag, r.URL)
+	switch _, ok := e.CompareVersions(r.Tag, v); {
+	case !ok:
+		fmt.Fprintf(stdout, "Unable to compare release %v with version %v\n", r.Tag, v)
+	case r.Newer(v):
+		fmt.Fprintf(stdout, "Release %v is newer than %v\n", r.Tag, v)
+	default:
+		fmt.Fprintln(stdout, "blacklist is up to date")
+	}
+
+	if a := r.Asset(c.Arch); a != nil {
+		fmt.Fprintf(stdout, "Asset for %v:\t%v %v\n", c.Arch, a.Name, a.URL)
+		return nil
+	}
+	fmt.Fprintf(stdout, "Release %v has no asset for %v\n", r.Tag, c.Arch)
+	return nil
+}
+
+var (
+	// executable and execProgram find and re-exec the running program, tests
+	// replace them
+	executable  = os.Executable
+	execProgram = syscall.Exec
+)
+
+func selfUpdateCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		force = fs.Bool("force", false, "Install the latest release even if it isn't newer")
+		key   = fs.String("key", "", "Verify the checksums with the base64 ed25519 public key in `<file>`, required")
+		url   = fs.String("url", e.ReleasesURL, "GitHub releases API `<url>` of the latest release")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *key == "" {
+		return errors.New("usage: " + commands["self-update"].usage)
+	}
+
+	pub, err := readPushKey(*key)
+	if err != nil {
+		return err
+	}
+
+	v, _, _ := buildInfo()
+	r, err := c.LatestRelease(*url)
+	if err != nil {
+		return err
+	}
+
+	if !r.Newer(v) && !*force {
+		if _, ok := e.CompareVersions(r.Tag, v); !ok {
+			return fmt.Errorf("unable to compare release %v with version %v, use -force to install it", r.Tag, v)
+		}
+		fmt.Fprintf(stdout, "blacklist %v is up to date\n", v)
+		return nil
+	}
+
+	a := r.Binary(c.Arch)
+	if a == nil {
+		return fmt.Errorf("release %v has no program for %v", r.Tag, c.Arch)
+	}
+
+	b, err := c.FetchAsset(a)
+	if err != nil {
+		return err
+	}
+	if err = c.Verify(r, a, b, pub); err != nil {
+		return err
+	}
+	if b, err = e.Program(a, b); err != nil {
+		return err
+	}
+
+	exe, err := executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if err = e.ReplaceProgram(exe, b); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Updated %v from %v to %v, the previous program is kept as %v.old\n", exe, v, r.Tag, exe)
+	return execProgram(exe, []string{exe, "version"}, os.Environ())
+}
+
+func restoreCmd(c *e.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["restore"].usage)
+	}
+
+	var r io.Reader
+	switch {
+	case strings.Contains(args[0], "://"):
+		d, err := c.Download(args[0])
+		if err != nil {
+			return err
+		}
+		r = d
+	default:
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	files, err := c.Restore(r)
+	for _, f := range files {
+		fmt.Fprintf(stdout, "Restored %v\n", f)
+	}
+	return err
+}
+
+func catalogCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	asJSON := fs.Bool("json", false, "Print the catalog as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["catalog"].usage)
+	}
+
+	if *asJSON {
+		b, err := json.MarshalIndent(c.Catalog(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(b))
+		return nil
+	}
+
+	for _, s := range c.Catalog() {
+		fmt.Fprintf(stdout, "%-16s  %-8s  %v\n%-16s  %-8s  %v\n", s.Name, s.Node, s.Desc, "", "", s.URL)
+		if s.Deprecated != "" {
+			fmt.Fprintf(stdout, "%-16s  %-8s  deprecated: %v\n", "", "", s.Deprecated)
+		}
+	}
+	return nil
+}
+
+func doctorCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	probe := fs.String("url", defaultsURL, "Test outbound HTTPS by fetching this `<url>`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["doctor"].usage)
+	}
+
+	checks := c.Doctor(*probe)
+	failed := 0
+	for _, ck := range checks {
+		status := "ok"
+		if !ck.OK {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(stdout, "%-4s  %-16s  %v\n", status, ck.Name, ck.Detail)
+		if ck.Fix != "" {
+			fmt.Fprintf(stdout, "%-4s  %-16s  fix: %v\n", "", "", ck.Fix)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// writeFile writes file via a temporary file, so readers never see it partly
+// written
+func writeFile(file string, fn func(w io.Writer) error) error {
+	tmp := file + ".tmp"
+	w, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = fn(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func exportCmd(c *e.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: " + commands["export"].usage)
+	}
+
+	act := args[0]
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		url    = fs.String("url", "", "AdGuard Home `<url>`, e.g. http://192.168.1.2:3000")
+		user   = fs.String("user", "", "AdGuard Home `<user>`")
+		pass   = fs.String("pass", os.Getenv("ADGUARD_PASSWORD"), "AdGuard Home `<password>`, defaults to $ADGUARD_PASSWORD")
+		list   = fs.String("list", "", "Write blocky's domain list to `<file>`")
+		group  = fs.String("group", "edgeos", "blocky blackLists `<group>`")
+		ip     = fs.String("ip", "", "Blocked names resolve to `<ip>`, 0.0.0.0 if unset, rpz answers NXDOMAIN instead")
+		out    = fs.String("o", "", "Write blocky's configuration or the rendered file to `<file>` instead of stdout")
+		format = fs.String("format", "", "Export `<format>`, e.g. domains, hosts or wildcard, instead of naming it first")
+		title  = fs.String("title", e.ABPTitle, "abp list `<title>`")
+		expire = fs.Duration("expires", e.ABPExpires, "abp subscribers check for updates every `<duration>`, in whole days or hours")
+		rest   = args[1:]
+	)
+
+	// the format can be named first or with -format
+	if strings.HasPrefix(act, "-") {
+		act, rest = "", args
+	}
+
+	if err := fs.Parse(rest); err != nil {
+		return err
+	}
+
+	if *format != "" {
+		act = *format
+	}
+
+	render, ok := e.Renderers[act]
+	if act == "abp" {
+		render = func(w io.Writer, entries e.Entries, _ string) error {
+			return e.WriteABP(w, entries, *title, *expire)
+		}
+	}
+
+	switch {
+	case fs.NArg() != 0, act == "adguard" && *url == "", act == "blocky" && *list == "",
+		!ok && act != "adguard" && act != "blocky":
+		return errors.New("usage: " + commands["export"].usage)
+	}
+
+	if act == "adguard" {
+		merged, err := c.Merged()
+		if err != nil {
+			return err
+		}
+
+		a := &e.AdGuard{URL: *url, User: *user, Pass: *pass}
+		n, err := a.Push(merged)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Pushed %d rules to AdGuard Home at %v\n", n, *url)
+		return nil
+	}
+
+	entries, err := c.MergedEntries()
+	if err != nil {
+		return err
+	}
+
+	var n int
+	err = entries(func(e.MergedEntry) bool {
+		n++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if act == "blocky" {
+		if err = writeFile(*list, func(w io.Writer) error { return e.WriteBlockyList(w, entries) }); err != nil {
+			return err
+		}
+
+		if *out == "" {
+			return e.WriteBlockyConfig(stdout, *group, *list)
+		}
+		if err = writeFile(*out, func(w io.Writer) error { return e.WriteBlockyConfig(w, *group, *list) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Wrote %d domains to %v\n", n, *list)
+		return nil
+	}
+
+	if n := e.WildcardLoss(act, entries); n > 0 {
+		logWarning(fmt.Sprintf("%v files only block exact names, %d wildcard entries are written without their subdomains", act, n))
+	}
+
+	if *out == "" {
+		return render(stdout, entries, *ip)
+	}
+	if err = writeFile(*out, func(w io.Writer) error { return render(w, entries, *ip) }); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %d domains to %v\n", n, *out)
+	return nil
+}
+
+func renderCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["render"].usage)
+	}
+
+	restore := c.SetOpt(e.Writer(stdout))
+	defer c.SetOpt(restore)
+	return processObjects(c, objex)
+}
+
+func updateCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	var (
+		node   = fs.String("node", "", "Only update the sources of this `<node>`, e.g. hosts")
+		reload = fs.Bool("reload", true, "Reload dnsmasq after updating")
+		source = fs.String("source", "", "Only update the sources with this `<name>`")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *node == "" && *source == "" {
+		return errors.New("usage: " + commands["update"].usage)
+	}
+
+	if err := c.Update(*node, *source); err != nil {
+		return err
+	}
+
+	if *reload {
+		if b, err := c.ReloadDNS(); err != nil {
+			return fmt.Errorf("%v: %s", err, b)
+		}
+	}
+	return nil
+}
+
+func auditCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["audit"].usage)
+	}
+
+	var cts []e.Contenter
+	for _, o := range objex {
+		ct, err := c.NewContent(o)
+		if err != nil {
+			return err
+		}
+		cts = append(cts, ct)
+	}
+
+	a, err := c.Audit(cts...)
+	if *asJSON {
+		b, jerr := json.MarshalIndent(a, "", "  ")
+		if jerr != nil {
+			return jerr
+		}
+		fmt.Fprintln(stdout, string(b))
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%-12s %-24s %8s %8s %8s %8s\n", "Node", "Source", "Entries", "Added", "Removed", "Rejected")
+	for _, s := range a.Sources {
+		fmt.Fprintf(stdout, "%-12s %-24s %8d %8d %8d %8d\n", s.Node, s.Name, s.Entries, len(s.Added), len(s.Removed), s.Rejected)
+	}
+
+	if len(a.Conflicts) > 0 {
+		fmt.Fprintln(stdout, "\nConflicts")
+		for _, cf := range a.Conflicts {
+			fmt.Fprintf(stdout, "  %v: %v include conflicts with %v exclude %v, %v wins\n", cf.Domain, cf.Include, cf.Exclude, cf.Excluded, cf.Winner)
+		}
+	}
+
+	for _, s := range a.Sources {
+		if len(s.Samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(stdout, "\nRejected lines from %v/%v, first %d of %d\n", s.Node, s.Name, len(s.Samples), s.Rejected)
+		for _, l := range s.Samples {
+			fmt.Fprintf(stdout, "  %v\n", l)
+		}
+	}
+
+	for _, s := range a.Sources {
+		if len(s.Added)+len(s.Removed) == 0 {
+			continue
+		}
+		fmt.Fprintf(stdout, "\nChanges to %v\n", s.File)
+		for _, l := range s.Added {
+			fmt.Fprintf(stdout, "+ %v\n", l)
+		}
+		for _, l := range s.Removed {
+			fmt.Fprintf(stdout, "- %v\n", l)
+		}
+	}
+	return err
+}
+
+func snapshotCmd(c *e.Config, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	out := fs.String("o", "", "Write the snapshot to `<file>` instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["snapshot"].usage)
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if *out == "" {
+		_, err = stdout.Write(b)
+		return err
+	}
+
+	return writeFile(*out, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+func secretCmd(c *e.Config, args []string) error {
+	s := secrets()
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		names, err := s.Names()
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			fmt.Fprintln(stdout, n)
+		}
+		return nil
+
+	case len(args) == 2 && args[0] == "set":
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+		return s.Set(args[1], strings.TrimRight(string(b), "\r\n"))
+
+	case len(args) == 2 && args[0] == "delete":
+		return s.Delete(args[1])
+	}
+
+	return errors.New("usage: " + commands["secret"].usage)
+}
+
+func migrateCmd(c *e.Config, args []string) error {
+	fs, apply, _ := subFlags("migrate", "")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["migrate"].usage)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmds, err := c.Migrate(f)
+	if err != nil {
+		return fmt.Errorf("%v:%v", fs.Arg(0), err)
+	}
+	return emit(c, cmds, *apply)
+}
+
+func importPiHoleCmd(c *e.Config, args []string) error {
+	fs, apply, _ := subFlags("import-pihole", "")
+	out := fs.String("o", "", "Write a configuration `<file>` instead of printing set commands")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || (*apply && *out != "") {
+		return errors.New("usage: " + commands["import-pihole"].usage)
+	}
+
+	p, err := c.ReadPiHole(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for _, rx := range p.Skipped {
+		logWarning(fmt.Sprintf("Pi-hole regex %q has no domain equivalent, skipped", rx))
+	}
+
+	if *out != "" {
+		b, err := c.PiHoleConfig(p)
+		if err != nil {
+			return err
+		}
+		return writeFile(*out, func(w io.Writer) error {
+			_, err := w.Write(b)
+			return err
+		})
+	}
+
+	cmds, err := c.PiHoleCommands(p)
+	if err != nil {
+		return err
+	}
+	return emit(c, cmds, *apply)
+}
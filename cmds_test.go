@@ -0,0 +1,677 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunCommand(t *testing.T) {
+	Convey("Testing runCommand()", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		c := getOpts().initEdgeOS()
+
+		tests := []struct {
+			args []string
+			exp  string
+			ok   bool
+		}{
+			{args: []string{"source", "add", "-prefix", "0.0.0.0 ", "sbhosts", "https://example.com/hosts"}, ok: true, exp: "set service dns forwarding blacklist hosts source 'sbhosts' prefix '0.0.0.0 '\nset service dns forwarding blacklist hosts source 'sbhosts' url 'https://example.com/hosts'\n"},
+			{args: []string{"source", "delete", "-node", "domains", "zeus"}, ok: true, exp: "delete service dns forwarding blacklist domains source 'zeus'\n"},
+			{args: []string{"exclude", "add", "apple.com", "msdn.com"}, ok: true, exp: "set service dns forwarding blacklist exclude 'apple.com'\nset service dns forwarding blacklist exclude 'msdn.com'\n"},
+			{args: []string{"exclude", "delete", "-node", "hosts", "apple.com"}, ok: true, exp: "delete service dns forwarding blacklist hosts exclude 'apple.com'\n"},
+			{args: []string{"effective"}, ok: true, exp: ""},
+			{args: []string{"effective", "apple.com"}, ok: false},
+			{args: []string{"render", "apple.com"}, ok: false},
+			{args: []string{"exclude"}, ok: false},
+			{args: []string{"source", "move", "zeus"}, ok: false},
+			{args: []string{"source", "add", "zeus;reboot", "https://example.com/hosts"}, ok: false},
+			{args: []string{"exclude", "add", "-node", "hosts;reboot", "apple.com"}, ok: false},
+			{args: []string{"bogus"}, ok: false},
+		}
+
+		for _, tt := range tests {
+			act.Reset()
+			err := runCommand(c, tt.args)
+			switch tt.ok {
+			case true:
+				So(err, ShouldBeNil)
+				So(act.String(), ShouldEqual, tt.exp)
+			default:
+				So(err, ShouldNotBeNil)
+			}
+		}
+	})
+}
+
+func TestStatsCmd(t *testing.T) {
+	Convey("Testing the stats command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		line := func(s string) string {
+			return time.Now().Format("Jan _2 15:04:05") + " router dnsmasq[812]: " + s + "\n"
+		}
+
+		file := dir + "/messages"
+		So(ioutil.WriteFile(file, []byte(line("query[A] ads.example.com from 192.168.1.10")+line("config ads.example.com is 0.0.0.0")), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		So(runCommand(c, []string{"stats", "-log", file, "-top", "1"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, "Blocked: 1 (100.0%)\n\nTop blocked domains:\n       1  ads.example.com\n")
+
+		So(runCommand(c, []string{"stats", "-log", dir + "/missing"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"stats", "extra"}), ShouldNotBeNil)
+
+		Convey("following the log", func() {
+			f, err := os.Open(file)
+			So(err, ShouldBeNil)
+			defer f.Close()
+
+			q := e.NewQueryStats(time.Now(), time.Hour)
+			_, err = q.ReadFrom(f)
+			So(err, ShouldBeNil)
+
+			w, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+			So(err, ShouldBeNil)
+			_, err = w.WriteString(line("query[A] track.example.net from 192.168.1.11") + line("config track.example.net is 0.0.0.0"))
+			So(err, ShouldBeNil)
+			w.Close()
+
+			act.Reset()
+			stop := make(chan struct{})
+			time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+			So(tailStats(q, f, 20*time.Millisecond, 5, stop), ShouldBeNil)
+			So(act.String(), ShouldContainSubstring, "Blocked: 2 (100.0%)")
+			So(q.TopClients(0), ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestOptimizeCmd(t *testing.T) {
+	Convey("Testing the optimize command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/hosts.yoyo.blacklist.conf", []byte("address=/ads.example.com/0.0.0.0\naddress=/never.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/hosts.unused.blacklist.conf", []byte("address=/never.example.org/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		stamp := time.Now().Format("Jan _2 15:04:05") + " router dnsmasq[812]: "
+		So(ioutil.WriteFile(dir+"/messages", []byte(stamp+"query[A] ads.example.com from 192.168.1.10\n"+stamp+"config ads.example.com is 0.0.0.0\n"), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Dir(dir))
+
+		hot := dir + "/hot.conf"
+		So(runCommand(c, []string{"optimize", "-log", dir + "/messages", "-hot", hot}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Node         Source                    Domains  Queried     Hits\n"+
+			"hosts        unused                          1        0        0  never queried\n"+
+			"hosts        yoyo                            2        1        1\n"+
+			"\nWrote 1 queried entries to "+hot+"\n")
+
+		b, err := ioutil.ReadFile(hot)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.example.com/0.0.0.0\n")
+
+		So(runCommand(c, []string{"optimize", "extra"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"optimize", "-log", dir + "/missing"}), ShouldNotBeNil)
+	})
+}
+
+func TestOverlapCmd(t *testing.T) {
+	Convey("Testing the overlap command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/big.txt", []byte("a.example.com\nb.example.com\nc.example.com\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/copy.txt", []byte("a.example.com\nb.example.com\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/other.txt", []byte("c.example.com\nd.example.com\ne.example.com\nf.example.com\n"), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		So(c.ReadCfg(&e.CFGstatic{Cfg: "blacklist {\n\tdomains {\n" +
+			"\t\tsource big {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/big.txt\n\t\t}\n" +
+			"\t\tsource copy {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/copy.txt\n\t\t}\n" +
+			"\t\tsource other {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/other.txt\n\t\t}\n" +
+			"\t}\n}"}), ShouldBeNil)
+
+		So(runCommand(c, []string{"overlap", "-min", "0.5"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Node         Source                    Domains   Unique\n"+
+			"domains      big                             3        0  redundant\n"+
+			"domains      copy                            2        0  redundant\n"+
+			"domains      other                           4        3\n"+
+			"\nSource                                Source                                  Shared  Jaccard\n"+
+			"domains/big                           domains/copy                                 2    0.667\n")
+
+		So(runCommand(c, []string{"overlap", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestAuditCmd(t *testing.T) {
+	Convey("Testing the audit command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			out = dir + "/domains.feed.blacklist.conf"
+			old = "address=/.ads.example.com/0.0.0.0\naddress=/.gone.example.com/0.0.0.0\n"
+		)
+		So(ioutil.WriteFile(dir+"/feed.txt", []byte("ads.example.com\nnew.example.com\n<html>\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(out, []byte(old), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Dir(dir), e.HMACKey(dir+"/blacklist.hmac"))
+		So(c.ReadCfg(&e.CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/feed.txt\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		So(runCommand(c, []string{"audit"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Node         Source                    Entries    Added  Removed Rejected\n"+
+			"domains      feed                            2        1        1        1\n"+
+			"\nRejected lines from domains/feed, first 1 of 1\n  <html>\n"+
+			"\nChanges to "+out+"\n+ address=/.new.example.com/0.0.0.0\n- address=/.gone.example.com/0.0.0.0\n")
+
+		b, err := ioutil.ReadFile(out)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, old)
+
+		files, err := filepath.Glob(dir + "/*")
+		So(err, ShouldBeNil)
+		So(files, ShouldResemble, []string{out, dir + "/feed.txt"})
+
+		act.Reset()
+		So(runCommand(c, []string{"audit", "-json"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, `"rejectedSamples": [`)
+
+		So(runCommand(c, []string{"audit", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestUpdateCmd(t *testing.T) {
+	Convey("Testing the update command", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for _, f := range []string{"feed.txt", "other.txt"} {
+			So(ioutil.WriteFile(dir+"/"+f, []byte("ads.example.com\n"), 0644), ShouldBeNil)
+		}
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Dir(dir))
+		So(c.ReadCfg(&e.CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/feed.txt\n\t\t}\n\t\tsource other {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/other.txt\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		So(runCommand(c, []string{"update", "-source", "feed", "-reload=false"}), ShouldBeNil)
+		files, err := filepath.Glob(dir + "/*.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(files, ShouldResemble, []string{dir + "/domains.feed.blacklist.conf"})
+
+		So(runCommand(c, []string{"update", "-node", "domains", "-source", "missing", "-reload=false"}).Error(), ShouldEqual, `no sources match node "domains" and source "missing"`)
+		So(runCommand(c, []string{"update"}), ShouldNotBeNil)
+	})
+}
+
+func TestVersionCmd(t *testing.T) {
+	Convey("Testing the version command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"tag_name": "v0.07", "html_url": "https://example.com/v0.07", "assets": [{"name": "blacklist_0.07_mips64.deb", "browser_download_url": "https://example.com/mips64.deb"}]}`))
+		}))
+		defer srv.Close()
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Arch("mips64"))
+
+		So(runCommand(c, []string{"version"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Version:\tUNKNOWN\nCommit:\t\tUNKNOWN\nBuild date:\tUNKNOWN\nPlatform:\t"+runtime.GOOS+"/mips64\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"version", "-check", "-url", srv.URL}), ShouldBeNil)
+		So(act.String(), ShouldEndWith, "Latest release:\tv0.07 https://example.com/v0.07\nUnable to compare release v0.07 with version UNKNOWN\nAsset for mips64:\tblacklist_0.07_mips64.deb https://example.com/mips64.deb\n")
+
+		origVersion := version
+		version = "0.06-alpha"
+		defer func() { version = origVersion }()
+		c.SetOpt(e.Arch("arm"))
+
+		act.Reset()
+		So(runCommand(c, []string{"version", "-check", "-url", srv.URL}), ShouldBeNil)
+		So(act.String(), ShouldEndWith, "Release v0.07 is newer than 0.06-alpha\nRelease v0.07 has no asset for arm\n")
+
+		So(runCommand(c, []string{"version", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestSelfUpdateCmd(t *testing.T) {
+	Convey("Testing the self-update command", t, func() {
+		act := new(bytes.Buffer)
+		origOut, origExe, origExec, origVersion := stdout, executable, execProgram, version
+		defer func() { stdout, executable, execProgram, version = origOut, origExe, origExec, origVersion }()
+		stdout = act
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		exe := dir + "/blacklist"
+		So(ioutil.WriteFile(exe, []byte("old program"), 0755), ShouldBeNil)
+		executable = func() (string, error) { return exe, nil }
+
+		var execed []string
+		execProgram = func(argv0 string, argv []string, envv []string) error {
+			execed = argv
+			return nil
+		}
+
+		pub, priv, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+		key := dir + "/release.key"
+		So(ioutil.WriteFile(key, []byte(base64.StdEncoding.EncodeToString(pub)), 0644), ShouldBeNil)
+
+		other, _, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+		otherKey := dir + "/other.key"
+		So(ioutil.WriteFile(otherKey, []byte(base64.StdEncoding.EncodeToString(other)), 0644), ShouldBeNil)
+
+		sums := fmt.Sprintf("%x  blacklist_mips64\n", sha256.Sum256([]byte("new program")))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/latest":
+				fmt.Fprintf(w, `{"tag_name": "v0.07", "assets": [{"name": "blacklist_mips64", "browser_download_url": "http://%[1]v/bin"}, {"name": "checksums.txt", "browser_download_url": "http://%[1]v/sums"}, {"name": "checksums.txt.sig", "browser_download_url": "http://%[1]v/sig"}]}`, r.Host)
+			case "/bin":
+				w.Write([]byte("new program"))
+			case "/sums":
+				w.Write([]byte(sums))
+			case "/sig":
+				w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums)))))
+			}
+		}))
+		defer srv.Close()
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Arch("mips64"))
+
+		So(runCommand(c, []string{"self-update", "-url", srv.URL + "/latest"}).Error(), ShouldEqual, "usage: "+commands["self-update"].usage)
+		So(runCommand(c, []string{"self-update", "-key", key, "-url", srv.URL + "/latest"}).Error(), ShouldEqual, "unable to compare release v0.07 with version UNKNOWN, use -force to install it")
+
+		version = "0.07"
+		So(runCommand(c, []string{"self-update", "-key", key, "-url", srv.URL + "/latest"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "blacklist 0.07 is up to date\n")
+
+		version = "0.06-alpha"
+		c.SetOpt(e.Arch("arm"))
+		So(runCommand(c, []string{"self-update", "-key", key, "-url", srv.URL + "/latest"}).Error(), ShouldEqual, "release v0.07 has no program for arm")
+
+		act.Reset()
+		c.SetOpt(e.Arch("mips64"))
+		So(runCommand(c, []string{"self-update", "-key", otherKey, "-url", srv.URL + "/latest"}), ShouldEqual, e.ErrReleaseSignature)
+		So(execed, ShouldBeNil)
+		So(runCommand(c, []string{"self-update", "-key", key, "-url", srv.URL + "/latest"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Updated "+exe+" from 0.06-alpha to v0.07, the previous program is kept as "+exe+".old\n")
+		So(execed, ShouldResemble, []string{exe, "version"})
+
+		b, err := ioutil.ReadFile(exe)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "new program")
+
+		So(runCommand(c, []string{"self-update", "-key", dir + "/missing.key"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"self-update", "-key", key, "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestAddSourceCmd(t *testing.T) {
+	Convey("Testing the add-source command", t, func() {
+		act := new(bytes.Buffer)
+		origOut, origIn := stdout, stdin
+		defer func() { stdout, stdin = origOut, origIn }()
+		stdout = act
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/hosts.txt", []byte("# hosts\n0.0.0.0 ads.example.com\n0.0.0.0 track.example.com\n"), 0644), ShouldBeNil)
+		c := getOpts().initEdgeOS()
+
+		stdin = strings.NewReader("file://" + dir + "/hosts.txt\nmyhosts\n")
+		So(runCommand(c, []string{"add-source"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, "Format:   hosts (node hosts, prefix \"0.0.0.0\")\n")
+		So(act.String(), ShouldContainSubstring, "Entries:  2 from 3 lines, 0 rejected\n")
+		So(act.String(), ShouldContainSubstring, "          ads.example.com\n")
+		So(act.String(), ShouldEndWith, "Name: set service dns forwarding blacklist hosts source 'myhosts' prefix '0.0.0.0'\nset service dns forwarding blacklist hosts source 'myhosts' url 'file://"+dir+"/hosts.txt'\n")
+
+		act.Reset()
+		stdin = strings.NewReader("")
+		So(runCommand(c, []string{"add-source", "-url", "file://" + dir + "/hosts.txt", "-node", "domains", "-prefix", "#"}).Error(), ShouldStartWith, "no entries found")
+		So(runCommand(c, []string{"add-source"}).Error(), ShouldEqual, "url is required")
+		So(runCommand(c, []string{"add-source", "-url", "file://" + dir + "/missing.txt"}), ShouldNotBeNil)
+	})
+}
+
+func TestBackupCmd(t *testing.T) {
+	Convey("Testing the backup and restore commands", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		conf := dir + "/domains.feed.blacklist.conf"
+		So(ioutil.WriteFile(conf, []byte("address=/.ads.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Dir(dir), e.SeenFile(""), e.StaleFile(""), e.FailFile(""), e.CatalogFile(""), e.StateFiles(nil))
+
+		So(runCommand(c, []string{"backup", "-o", dir + "/backup.tar.gz"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Backup written to "+dir+"/backup.tar.gz\n")
+
+		So(os.Remove(conf), ShouldBeNil)
+		act.Reset()
+		So(runCommand(c, []string{"restore", dir + "/backup.tar.gz"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Restored "+conf+"\n")
+
+		So(runCommand(c, []string{"backup"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"restore"}), ShouldNotBeNil)
+	})
+}
+
+func TestCatalogCmd(t *testing.T) {
+	Convey("Testing the catalog command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		c := e.NewConfig()
+		So(runCommand(c, []string{"catalog"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, "oisd-basic        domains   OISD small")
+		So(act.String(), ShouldContainSubstring, "                            https://small.oisd.nl/domainswild\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"catalog", "-json"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, `"name": "stevenblack"`)
+
+		So(runCommand(c, []string{"catalog", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestDoctorCmd(t *testing.T) {
+	Convey("Testing the doctor command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.API("/nonexistent/cli-shell-api"), e.Offline(true))
+
+		err := runCommand(c, []string{"doctor"})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEndWith, " of 8 checks failed")
+		So(act.String(), ShouldStartWith, "FAIL  cli-shell-api     stat /nonexistent/cli-shell-api: no such file or directory\n"+
+			"                        fix: run blacklist on EdgeOS or VyOS, or load a configuration file with -f <file>\n")
+		So(act.String(), ShouldEndWith, "ok    outbound HTTPS    skipped, -offline\n")
+		So(commands["doctor"].bare, ShouldBeTrue)
+
+		So(runCommand(c, []string{"doctor", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestExcludePending(t *testing.T) {
+	Convey("Testing exclude pending", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := getOpts().initEdgeOS()
+		file := dir + "/pending"
+
+		So(runCommand(c, []string{"exclude", "pending", "-file", file}), ShouldBeNil)
+		So(act.String(), ShouldBeEmpty)
+
+		So(ioutil.WriteFile(file, []byte("# requested from the block page\nads.example.com\ncdn.example.net\n"), 0644), ShouldBeNil)
+		So(runCommand(c, []string{"exclude", "pending", "-file", file}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "set service dns forwarding blacklist exclude 'ads.example.com'\nset service dns forwarding blacklist exclude 'cdn.example.net'\n")
+
+		So(runCommand(c, []string{"exclude", "pending"}), ShouldNotBeNil)
+	})
+}
+
+func TestExportCmd(t *testing.T) {
+	Convey("Testing the export command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/hosts.yoyo.blacklist.conf", []byte("address=/ads.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		c.SetOpt(e.Dir(dir))
+
+		So(runCommand(c, []string{"export", "blocky", "-list", dir + "/edgeos.txt"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "blocking:\n  blackLists:\n    edgeos:\n      - \""+dir+"/edgeos.txt\"\n  clientGroupsBlock:\n    default:\n      - edgeos\n")
+
+		b, err := ioutil.ReadFile(dir + "/edgeos.txt")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEndWith, "\nads.example.com\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"export", "dnscrypt-cloaking", "-ip", "192.168.1.1"}), ShouldBeNil)
+		So(act.String(), ShouldEndWith, "\n=ads.example.com 192.168.1.1\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"export", "coredns", "-o", dir + "/hosts"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "Wrote 1 domains to "+dir+"/hosts\n")
+		b, err = ioutil.ReadFile(dir + "/hosts")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEndWith, "\n0.0.0.0 ads.example.com\n")
+
+		So(ioutil.WriteFile(dir+"/domains.tasty.blacklist.conf", []byte("address=/.tracker.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		act.Reset()
+		So(runCommand(c, []string{"export", "-format", "domains"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "ads.example.com\ntracker.example.com\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"export", "-format", "wildcard", "-o", dir + "/wildcard.txt"}), ShouldBeNil)
+		b, err = ioutil.ReadFile(dir + "/wildcard.txt")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "ads.example.com\n*.tracker.example.com\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"export", "abp", "-title", "Home", "-expires", "12h"}), ShouldBeNil)
+		So(act.String(), ShouldStartWith, "[Adblock Plus 2.0]\n! Title: Home\n! Version: ")
+		So(act.String(), ShouldContainSubstring, "\n! Expires: 12 hours (update frequency)\n")
+		So(act.String(), ShouldEndWith, "\n||ads.example.com^\n||tracker.example.com^\n")
+
+		So(runCommand(c, []string{"export", "adguard"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"export", "bogus"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"export", "-format", "bogus"}), ShouldNotBeNil)
+	})
+}
+
+func TestSecretCmd(t *testing.T) {
+	Convey("Testing the secret command", t, func() {
+		act := new(bytes.Buffer)
+		origOut, origIn, origStore := stdout, stdin, secrets
+		defer func() { stdout, stdin, secrets = origOut, origIn, origStore }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		stdout = act
+		secrets = func() *e.SecretStore {
+			return &e.SecretStore{File: dir + "/blacklist.secrets", Key: dir + "/blacklist.key"}
+		}
+
+		c := getOpts().initEdgeOS()
+
+		stdin = strings.NewReader("s3cr3t\n")
+		So(runCommand(c, []string{"secret", "set", "feed"}), ShouldBeNil)
+
+		So(runCommand(c, []string{"secret", "list"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "feed\n")
+
+		v, err := secrets().Get("feed")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "s3cr3t")
+
+		So(runCommand(c, []string{"secret", "delete", "feed"}), ShouldBeNil)
+		So(runCommand(c, []string{"secret", "delete", "feed"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"secret"}), ShouldNotBeNil)
+	})
+}
+
+func TestSnapshotCmd(t *testing.T) {
+	Convey("Testing the snapshot command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		o := getOpts()
+		c := o.initEdgeOS()
+		So(c.ReadCfg(o.getCFG(c)), ShouldBeNil)
+
+		So(runCommand(c, []string{"snapshot"}), ShouldBeNil)
+		So(act.String(), ShouldStartWith, "{\n  \"parms\": {")
+
+		So(runCommand(c, []string{"snapshot", "-o", dir + "/snapshot.json"}), ShouldBeNil)
+		b, err := ioutil.ReadFile(dir + "/snapshot.json")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, act.String())
+
+		*o.File = dir + "/snapshot.json"
+		replay := o.initEdgeOS()
+		So(replay.ReadCfg(o.getCFG(replay)), ShouldBeNil)
+		So(replay.String(), ShouldEqual, c.String())
+
+		So(runCommand(c, []string{"snapshot", "extra"}), ShouldNotBeNil)
+	})
+}
+
+func TestImportPiHoleCmd(t *testing.T) {
+	Convey("Testing the import-pihole command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/adlists.list", []byte("https://adaway.org/hosts.txt\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/whitelist.txt", []byte("apple.com\n"), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		So(runCommand(c, []string{"import-pihole", dir}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "set service dns forwarding blacklist hosts source 'adaway.org' url 'https://adaway.org/hosts.txt'\n"+
+			"set service dns forwarding blacklist exclude 'apple.com'\n")
+
+		So(runCommand(c, []string{"import-pihole", "-o", dir + "/pihole.boot", dir}), ShouldBeNil)
+		b, err := ioutil.ReadFile(dir + "/pihole.boot")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "blacklist {\n    exclude apple.com\n    hosts {\n        source adaway.org {\n            url https://adaway.org/hosts.txt\n        }\n    }\n}\n")
+
+		So(runCommand(c, []string{"import-pihole"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"import-pihole", "-apply", "-o", dir + "/pihole.boot", dir}), ShouldNotBeNil)
+		So(runCommand(c, []string{"import-pihole", dir + "/missing"}), ShouldNotBeNil)
+	})
+}
+
+func TestReportCmd(t *testing.T) {
+	Convey("Testing the report command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		file := dir + "/history.jsonl"
+		now := time.Now().UTC().Truncate(time.Second)
+		var lines string
+		for i, n := range []int{1000, 1100} {
+			lines += fmt.Sprintf("{\"time\":%q,\"ok\":true,\"entries\":%d,\"seconds\":5}\n", now.Add(time.Duration(i-1)*time.Hour).Format(time.RFC3339), n)
+		}
+		So(ioutil.WriteFile(file, []byte(lines), 0644), ShouldBeNil)
+
+		c := getOpts().initEdgeOS()
+		So(runCommand(c, []string{"report", "-file", file}), ShouldBeNil)
+		So(act.String(), ShouldStartWith, "Runs:     2 from ")
+		So(act.String(), ShouldContainSubstring, "Entries:  1000 to 1100, +100 (+10.0%), min 1000, max 1100\n")
+
+		act.Reset()
+		c.SetOpt(e.HistoryFile(file))
+		So(runCommand(c, []string{"report", "-json", "-days", "7"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, "\"growth\": 100,")
+
+		c.SetOpt(e.HistoryFile(""))
+		So(runCommand(c, []string{"report"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"report", "-days", "0", "-file", file}), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+)
+
+// bashCompletion completes the subcommands, and the node and source names
+// the configuration has, by asking the program being completed
+const bashCompletion = `# bash completion for blacklist, install with:
+#   blacklist completion bash > /etc/bash_completion.d/blacklist
+_blacklist() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" opts=""
+
+	case "$prev" in
+	-node) opts=$("${COMP_WORDS[0]}" completion nodes 2>/dev/null) ;;
+	-source) opts=$("${COMP_WORDS[0]}" completion sources 2>/dev/null) ;;
+	*)
+		if [ "$COMP_CWORD" -eq 1 ]; then
+			opts=$("${COMP_WORDS[0]}" completion commands 2>/dev/null)
+		else
+			case "${COMP_WORDS[1]}" in
+			completion) [ "$COMP_CWORD" -eq 2 ] && opts="bash zsh" ;;
+			source)
+				if [ "$COMP_CWORD" -eq 2 ]; then
+					opts="add delete"
+				elif [ "${COMP_WORDS[2]}" = delete ]; then
+					opts=$("${COMP_WORDS[0]}" completion sources 2>/dev/null)
+				fi
+				;;
+			esac
+		fi
+		;;
+	esac
+
+	[ -n "$opts" ] && COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+}
+complete -o default -F _blacklist blacklist /config/scripts/blacklist
+`
+
+// zshCompletion is bashCompletion for zsh
+const zshCompletion = `#compdef blacklist
+# zsh completion for blacklist, install with:
+#   blacklist completion zsh > "${fpath[1]}/_blacklist"
+_blacklist() {
+	local -a opts
+
+	case "$words[CURRENT-1]" in
+	-node) opts=(${(f)"$($words[1] completion nodes 2>/dev/null)"}) ;;
+	-source) opts=(${(f)"$($words[1] completion sources 2>/dev/null)"}) ;;
+	*)
+		if (( CURRENT == 2 )); then
+			opts=(${(f)"$($words[1] completion commands 2>/dev/null)"})
+		else
+			case "$words[2]" in
+			completion) (( CURRENT == 3 )) && opts=(bash zsh) ;;
+			source)
+				if (( CURRENT == 3 )); then
+					opts=(add delete)
+				elif [[ "$words[3]" == delete ]]; then
+					opts=(${(f)"$($words[1] completion sources 2>/dev/null)"})
+				fi
+				;;
+			esac
+		fi
+		;;
+	esac
+
+	if (( $#opts )); then
+		compadd -a opts
+	else
+		_files
+	fi
+}
+compdef _blacklist blacklist /config/scripts/blacklist
+`
+
+// completionWords returns the words the completion scripts query: the
+// subcommands, or the configuration's nodes or source names
+func completionWords(c *e.Config, kind string) ([]string, bool) {
+	switch kind {
+	case "commands":
+		return commandNames(), true
+	case "nodes":
+		return c.Nodes(), true
+	case "sources":
+		var (
+			names []string
+			seen  = make(map[string]bool)
+		)
+		for _, name := range c.GetAll(urls, files).Names() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+func completionCmd(c *e.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["completion"].usage)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Fprint(stdout, bashCompletion)
+		return nil
+	case "zsh":
+		fmt.Fprint(stdout, zshCompletion)
+		return nil
+	}
+
+	words, ok := completionWords(c, args[0])
+	if !ok {
+		return errors.New("usage: " + commands["completion"].usage)
+	}
+	for _, w := range words {
+		fmt.Fprintln(stdout, w)
+	}
+	return nil
+}
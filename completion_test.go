@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompletionCmd(t *testing.T) {
+	Convey("Testing the completion command", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		c := getOpts().initEdgeOS()
+		So(c.ReadCfg(&e.CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tinclude local.example.com\n\t\tsource feed {\n\t\t\tfile /tmp/feed.txt\n\t\t}\n\t}\n\thosts {\n\t\tsource feed {\n\t\t\turl http://example.com/feed.txt\n\t\t}\n\t\tsource yoyo {\n\t\t\turl http://pgl.yoyo.org/as/serverlist.php\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		So(runCommand(c, []string{"completion", "nodes"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "blacklist\ndomains\nhosts\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"completion", "sources"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, "feed\nyoyo\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"completion", "commands"}), ShouldBeNil)
+		So(act.String(), ShouldContainSubstring, "\ncompletion\n")
+		So(act.String(), ShouldContainSubstring, "\nupdate\n")
+
+		act.Reset()
+		So(runCommand(c, []string{"completion", "bash"}), ShouldBeNil)
+		So(act.String(), ShouldEqual, bashCompletion)
+		if bash, err := exec.LookPath("bash"); err == nil {
+			out, err := exec.Command(bash, "-n", "-c", bashCompletion).CombinedOutput()
+			So(string(out), ShouldBeEmpty)
+			So(err, ShouldBeNil)
+		}
+
+		act.Reset()
+		So(runCommand(c, []string{"completion", "zsh"}), ShouldBeNil)
+		So(act.String(), ShouldStartWith, "#compdef blacklist\n")
+
+		So(runCommand(c, []string{"completion"}), ShouldNotBeNil)
+		So(runCommand(c, []string{"completion", "fish"}), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,110 @@
+package edgeos
+
+import "fmt"
+
+// AllowObjects implements GetList for the whitelist node's includes and
+// sources, their domains are added to the exclusions
+type AllowObjects struct {
+	*Objects
+}
+
+// addAllow returns objects for the whitelist node's includes and sources
+func (c *Config) addAllow() *Objects {
+	o := &Objects{Parms: c.Parms}
+	if c.tree[allowNode] == nil {
+		return o
+	}
+
+	if inc := c.effectiveAllow(); len(inc) > 0 {
+		o.x = append(o.x, &object{
+			desc:  allowNode + " includes",
+			exc:   inc,
+			ip:    c.tree.getIP(allowNode),
+			ltype: allowNode,
+			name:  fmt.Sprintf("includes.[%v]", len(inc)),
+			nType: allow,
+			Parms: c.Parms,
+		})
+	}
+	o.x = append(o.x, c.tree.validate(allowNode).x...)
+	return o
+}
+
+// effectiveAllow returns the whitelist node's includes, less any explicitly
+// included domains if includes override exclusions
+func (c *Config) effectiveAllow() []string {
+	if !c.includeWins() {
+		return c.tree[allowNode].live()
+	}
+	return c.uninclude(c.tree[allowNode].live())
+}
+
+// Find returns the int position of an Objects' element
+func (a *AllowObjects) Find(elem string) int {
+	for i, o := range a.x {
+		if o.name == elem {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetList implements the Contenter interface for AllowObjects
+func (a *AllowObjects) GetList() *Objects {
+	var (
+		remote    []*object
+		responses = make(chan response, len(a.x))
+	)
+
+	defer close(responses)
+
+	for _, o := range a.x {
+		o.Parms = a.Objects.Parms
+		if o.ltype == urls {
+			remote = append(remote, o)
+		}
+	}
+
+	workers, spill := a.fetchPlan(remote)
+	sem := make(chan struct{}, a.fetches(workers))
+
+	for i, o := range a.x {
+		go func(i int, o *object) {
+			switch o.ltype {
+			case files:
+				o = o.readFile()
+			case urls:
+				o.spill = spill
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				o = o.readURL()
+			default:
+				o.r = o.excludes()
+			}
+			responses <- response{i: i, o: o}
+		}(i, o)
+	}
+
+	for _ = range Iter(len(a.x)) {
+		select {
+		case r := <-responses:
+			a.x[r.i] = r.o
+		}
+	}
+
+	return a.Objects
+}
+
+// Len returns how many objects there are
+func (a *AllowObjects) Len() int { return len(a.Objects.x) }
+
+// SetURL sets the Object's url field value
+func (a *AllowObjects) SetURL(name, url string) {
+	for _, o := range a.x {
+		if o.name == name {
+			o.url = url
+		}
+	}
+}
+
+func (a *AllowObjects) String() string { return a.Objects.String() }
@@ -0,0 +1,106 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAllow(t *testing.T) {
+	Convey("Testing the whitelist node", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "# allowed\nkiosked.com\n")
+		}))
+		defer srv.Close()
+
+		cfg := `blacklist {
+    dns-redirect-ip 0.0.0.0
+    domains {
+        include adsrvr.org
+        include ads.example.com
+        include kiosked.com
+        include tracker.example.net
+    }
+    hosts {
+        include beap.gemini.yahoo.com
+    }
+    whitelist {
+        include example.com
+        include adsrvr.org
+        source feed {
+            url ` + srv.URL + `
+        }
+    }
+}`
+		newCfg := func(p string) *Config {
+			c := NewConfig(
+				FileNameFmt("%v/%v.%v.%v"),
+				Method(http.MethodGet),
+				Nodes([]string{domains, hosts}),
+				Precedence(p),
+				Prefix("address="),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			return c
+		}
+
+		tests := []struct {
+			precedence string
+			exp        string
+		}{
+			{precedence: PrecedenceInclude, exp: "address=/ads.example.com/0.0.0.0\naddress=/adsrvr.org/0.0.0.0\naddress=/tracker.example.net/0.0.0.0"},
+			{precedence: PrecedenceExclude, exp: "address=/tracker.example.net/0.0.0.0"},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.precedence+" precedence", func() {
+				c := newCfg(tt.precedence)
+
+				ct, err := c.NewContent(AlwObj)
+				So(err, ShouldBeNil)
+				So(ct.Len(), ShouldEqual, 2)
+				So(c.ProcessContent(ct), ShouldBeNil)
+				So(c.Exc.keyExists("kiosked.com"), ShouldBeTrue)
+
+				d := &dummyConfig{t: t}
+				ct, err = c.NewContent(PreDObj)
+				So(err, ShouldBeNil)
+				So(d.ProcessContent(ct), ShouldBeNil)
+				So(strings.Join(d.s, "\n"), ShouldEqual, tt.exp)
+			})
+		}
+
+		Convey("whitelisted includes aren't firewall candidates", func() {
+			So(newCfg(PrecedenceInclude).FWIncludes(), ShouldNotContain, "example.com")
+		})
+
+		Convey("the effective exclusions include the whitelist", func() {
+			So(newCfg(PrecedenceInclude).Effective().Excludes[allowNode], ShouldResemble, []string{"example.com"})
+			So(newCfg(PrecedenceExclude).Effective().Excludes[allowNode], ShouldResemble, []string{"adsrvr.org", "example.com"})
+		})
+
+		Convey("whitelist sources are fetched like any other source", func() {
+			c := newCfg(PrecedenceInclude)
+			c.SetOpt(Inject(&Chaos{Fail: map[string]bool{"feed": true}}))
+
+			ct, err := c.NewContent(AlwObj)
+			So(err, ShouldBeNil)
+			err = c.ProcessContent(ct)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrInjected.Error())
+			So(c.Exc.keyExists("kiosked.com"), ShouldBeFalse)
+		})
+
+		Convey("configurations without a whitelist have nothing to fetch", func() {
+			c := NewConfig(Nodes([]string{domains}))
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n    domains {\n        include ads.example.com\n    }\n}"}), ShouldBeNil)
+			ct, err := c.NewContent(AlwObj)
+			So(err, ShouldBeNil)
+			So(ct.Len(), ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,116 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const filesPath = "/files/"
+
+// StatusAPI serves blacklist status, generated files and the merged lists
+// over HTTP; if the Config has a PushKey it also accepts pushed
+// configurations, saving them to PushFile and applying them with OnPush.
+// Lock is held while a push is saved and applied, a caller sharing it
+// serializes pushes with its own reloads
+type StatusAPI struct {
+	*Config
+	mux      *http.ServeMux
+	Lock     sync.Locker
+	OnPush   func() error
+	PushFile string
+	tag      listTag
+}
+
+// apiStatus is the JSON document returned by the /status endpoint
+type apiStatus struct {
+	Files []string `json:"files"`
+	Gzip  bool     `json:"gzip"`
+}
+
+// NewStatusAPI returns a *StatusAPI with its routes registered
+func (c *Config) NewStatusAPI() *StatusAPI {
+	a := &StatusAPI{Config: c, mux: http.NewServeMux(), Lock: &sync.Mutex{}}
+	a.mux.HandleFunc("/status", a.status)
+	a.mux.HandleFunc(filesPath, a.files)
+	a.mux.HandleFunc(manifestPath, a.manifest)
+	a.mux.HandleFunc(listsPath, a.lists)
+	if c.PushKey != nil {
+		a.mux.HandleFunc(pushPath, a.push)
+	}
+	return a
+}
+
+// ServeHTTP implements http.Handler
+func (a *StatusAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
+
+// files serves a generated file, or its gzip copy, by base name
+func (a *StatusAPI) files(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, filesPath)
+	served, err := a.servable()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, ok := served.entry[name]; !ok || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasSuffix(name, gzExt) {
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	http.ServeFile(w, r, filepath.Join(a.Dir, name))
+}
+
+// generated returns the generated blacklist files present in Dir
+func (c *Config) generated() ([]string, error) {
+	return c.globFiles(c.Dir, "")
+}
+
+// servable returns a list of file base names the API may serve
+func (a *StatusAPI) servable() (list, error) {
+	names, err := a.generated()
+	if err != nil {
+		return list{}, err
+	}
+
+	if a.Gzip {
+		gz, err := a.globFiles(a.Dir, gzExt)
+		if err != nil {
+			return list{}, err
+		}
+		names = append(names, gz...)
+	}
+
+	for i := range names {
+		names[i] = filepath.Base(names[i])
+	}
+	return updateEntry(names), nil
+}
+
+// status writes a JSON summary of the generated files
+func (a *StatusAPI) status(w http.ResponseWriter, r *http.Request) {
+	served, err := a.servable()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s := apiStatus{Files: []string{}, Gzip: a.Gzip}
+	for k := range served.entry {
+		s.Files = append(s.Files, k)
+	}
+	sort.Strings(s.Files)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
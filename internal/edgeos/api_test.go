@@ -0,0 +1,125 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStatusAPI(t *testing.T) {
+	Convey("Testing StatusAPI", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Gzip(true),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+
+		for _, f := range []string{"domains.zeus.blacklist.conf", "domains.zeus.blacklist.conf.gz", "notblacklist.txt"} {
+			So(ioutil.WriteFile(fmt.Sprintf("%v/%v", dir, f), []byte("address=/.zeus.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		}
+
+		srv := httptest.NewServer(c.NewStatusAPI())
+		defer srv.Close()
+
+		tests := []struct {
+			code int
+			exp  string
+			path string
+		}{
+			{path: "/status", code: http.StatusOK, exp: "{\"files\":[\"domains.zeus.blacklist.conf\",\"domains.zeus.blacklist.conf.gz\"],\"gzip\":true}\n"},
+			{path: "/files/domains.zeus.blacklist.conf", code: http.StatusOK, exp: "address=/.zeus.com/0.0.0.0\n"},
+			{path: "/files/domains.zeus.blacklist.conf.gz", code: http.StatusOK, exp: "address=/.zeus.com/0.0.0.0\n"},
+			{path: "/files/notblacklist.txt", code: http.StatusNotFound, exp: "404 page not found\n"},
+			{path: "/files/../notblacklist.txt", code: http.StatusNotFound, exp: "404 page not found\n"},
+		}
+
+		for _, tt := range tests {
+			Convey("Testing GET "+tt.path, func() {
+				resp, err := http.Get(srv.URL + tt.path)
+				So(err, ShouldBeNil)
+				defer resp.Body.Close()
+
+				act, err := ioutil.ReadAll(resp.Body)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode, ShouldEqual, tt.code)
+				So(string(act), ShouldEqual, tt.exp)
+			})
+		}
+	})
+}
+
+func TestListsAPI(t *testing.T) {
+	Convey("Testing StatusAPI /lists/", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(ioutil.WriteFile(dir+"/domains.zeus.blacklist.conf", []byte("address=/.zeus.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/hosts.yoyo.blacklist.conf", []byte("address=/ads.yoyo.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		a := c.NewStatusAPI()
+		srv := httptest.NewServer(a)
+		defer srv.Close()
+
+		get := func(path, etag string) (*http.Response, string) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+			So(err, ShouldBeNil)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			return resp, string(b)
+		}
+
+		resp, body := get("/lists/merged.txt", "")
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		So(body, ShouldEqual, "ads.yoyo.com\nzeus.com\n")
+
+		etag := resp.Header.Get("ETag")
+		So(etag, ShouldStartWith, `W/"`)
+
+		resp, body = get("/lists/merged.txt", etag)
+		So(resp.StatusCode, ShouldEqual, http.StatusNotModified)
+		So(body, ShouldBeEmpty)
+
+		resp, body = get("/lists/merged.abp", etag)
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		So(resp.Header.Get("ETag"), ShouldNotEqual, etag)
+		So(body, ShouldEndWith, "\n||ads.yoyo.com^\n||zeus.com^\n")
+
+		stamp := a.tag.stamp
+		So(stamp, ShouldNotBeEmpty)
+
+		So(ioutil.WriteFile(dir+"/domains.zeus.blacklist.conf", []byte("address=/.zeus.network/0.0.0.0\n"), 0644), ShouldBeNil)
+		resp, body = get("/lists/merged.txt", etag)
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		So(resp.Header.Get("ETag"), ShouldNotEqual, etag)
+		So(body, ShouldEqual, "ads.yoyo.com\nzeus.network\n")
+		So(a.tag.stamp, ShouldNotEqual, stamp)
+
+		resp, _ = get("/lists/merged.exe", "")
+		So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,89 @@
+package edgeos
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// dedupe strategies for a source's extracted entries
+const (
+	// DedupeGrow grows each source's map as its entries are extracted,
+	// keeping memory low on small routers
+	DedupeGrow = "grow"
+	// DedupePresize sizes each source's map from its cached size up front,
+	// trading memory for fewer rehashes
+	DedupePresize = "presize"
+	// presizeLine is the average source line length assumed by DedupePresize
+	presizeLine = 24
+)
+
+// Tuning is the performance defaults for a CPU architecture, see ArchTuning
+type Tuning struct {
+	Cores   int    `json:"cores"`
+	Fetches int    `json:"fetches"`
+	LineBuf int    `json:"lineBuffer"`
+	Dedupe  string `json:"dedupe"`
+}
+
+func (t Tuning) String() string {
+	fetches := "all"
+	if t.Fetches > 0 {
+		fetches = fmt.Sprint(t.Fetches)
+	}
+	return fmt.Sprintf("%d cores, %v fetches at once, %v line buffer, %v dedupe", t.Cores, fetches, formatSize(uint64(t.LineBuf)), t.Dedupe)
+}
+
+// ArchTuning returns the tuning for arch, a GOARCH name: MIPS routers, e.g.
+// the EdgeRouter Lite, get few workers, short line buffers and maps that grow
+// as needed, while ARM and amd64 machines get more of each
+func ArchTuning(arch string) Tuning {
+	cpus := runtime.NumCPU()
+	most := func(n int) int {
+		if cpus < n {
+			return cpus
+		}
+		return n
+	}
+
+	switch arch {
+	case "mips", "mipsle", "mips64", "mips64le":
+		return Tuning{Cores: most(2), Fetches: 2, LineBuf: 64 << 10, Dedupe: DedupeGrow}
+	case "arm", "386":
+		return Tuning{Cores: most(4), Fetches: 4, LineBuf: 256 << 10, Dedupe: DedupeGrow}
+	case "arm64":
+		return Tuning{Cores: cpus, Fetches: 8, LineBuf: 1 << 20, Dedupe: DedupePresize}
+	default:
+		return Tuning{Cores: cpus, LineBuf: 1 << 20, Dedupe: DedupePresize}
+	}
+}
+
+// fetches caps workers at Fetches, if it is set
+func (p *Parms) fetches(workers int) int {
+	if p.Fetches > 0 && p.Fetches < workers {
+		return p.Fetches
+	}
+	return workers
+}
+
+// entryHint returns how many entries to size o's extracted map for, from the
+// size of its file or cached download
+func (o *object) entryHint() int {
+	var file string
+	switch {
+	case o.Dedupe != DedupePresize:
+		return 0
+	case o.ltype == files:
+		file = o.path()
+	case o.cacheable():
+		file = o.cacheFile("body")
+	default:
+		return 0
+	}
+
+	fi, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return int(fi.Size() / presizeLine)
+}
@@ -0,0 +1,93 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestArchTuning(t *testing.T) {
+	Convey("Testing ArchTuning()", t, func() {
+		cpus := runtime.NumCPU()
+		most := func(n int) int {
+			if cpus < n {
+				return cpus
+			}
+			return n
+		}
+
+		tests := []struct {
+			arch string
+			exp  Tuning
+		}{
+			{arch: "mips64", exp: Tuning{Cores: most(2), Fetches: 2, LineBuf: 64 << 10, Dedupe: DedupeGrow}},
+			{arch: "mipsle", exp: Tuning{Cores: most(2), Fetches: 2, LineBuf: 64 << 10, Dedupe: DedupeGrow}},
+			{arch: "arm", exp: Tuning{Cores: most(4), Fetches: 4, LineBuf: 256 << 10, Dedupe: DedupeGrow}},
+			{arch: "arm64", exp: Tuning{Cores: cpus, Fetches: 8, LineBuf: 1 << 20, Dedupe: DedupePresize}},
+			{arch: "amd64", exp: Tuning{Cores: cpus, LineBuf: 1 << 20, Dedupe: DedupePresize}},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.arch, func() {
+				So(ArchTuning(tt.arch), ShouldResemble, tt.exp)
+			})
+		}
+
+		So(Tuning{Cores: 2, Fetches: 2, LineBuf: 64 << 10, Dedupe: DedupeGrow}.String(), ShouldEqual, "2 cores, 2 fetches at once, 64.0K line buffer, grow dedupe")
+		So(Tuning{Cores: 8, LineBuf: 1 << 20, Dedupe: DedupePresize}.String(), ShouldEqual, "8 cores, all fetches at once, 1.0M line buffer, presize dedupe")
+	})
+
+	Convey("Testing Tune()", t, func() {
+		procs := runtime.GOMAXPROCS(0)
+		defer runtime.GOMAXPROCS(procs)
+
+		c := NewConfig(Cores(2))
+		undo := c.SetOpt(Tune(Tuning{Cores: 1, Fetches: 3, LineBuf: 1 << 20, Dedupe: DedupePresize}))
+		So(c.Cores, ShouldEqual, 1)
+		So(c.fetches(10), ShouldEqual, 3)
+		So(c.fetches(2), ShouldEqual, 2)
+
+		c.SetOpt(undo)
+		So(c.Cores, ShouldEqual, 2)
+		So(c.fetches(10), ShouldEqual, 10)
+	})
+
+	Convey("Testing the tuning's effect on extract", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		src := dir + "/feed.txt"
+		So(ioutil.WriteFile(src, []byte(fmt.Sprintf("ads.example.com\n# %v\ntracker.example.com\n", strings.Repeat("x", 128<<10))), 0644), ShouldBeNil)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tfile " + src + "\n\t\t\tprefix \"\"\n\t\t}\n\t}\n}"
+		run := func(t Tuning) (string, *object) {
+			c := NewConfig(Dir(dir), Ext("blacklist.conf"), FileNameFmt("%v/%v.%v.%v"), Nodes([]string{domains}), Prefix("address="), Tune(t))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			c.ProcessContent(ct)
+
+			b, _ := ioutil.ReadFile(dir + "/domains.feed.blacklist.conf")
+			return string(b), c.Get(domains).x[0]
+		}
+
+		Convey("a long enough line buffer reads past long lines", func() {
+			out, o := run(Tuning{LineBuf: 1 << 20, Dedupe: DedupePresize})
+			So(out, ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n")
+			So(o.entryHint(), ShouldBeGreaterThan, 0)
+		})
+
+		Convey("a short one stops at them", func() {
+			out, o := run(Tuning{LineBuf: 64 << 10, Dedupe: DedupeGrow})
+			So(out, ShouldEqual, "address=/.ads.example.com/0.0.0.0\n")
+			So(o.entryHint(), ShouldEqual, 0)
+		})
+	})
+}
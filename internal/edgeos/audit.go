@@ -0,0 +1,158 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// rejectSamples is how many rejected lines are kept per source for Audit
+const rejectSamples = 10
+
+// Audit reports what processing the sources would change, see Config.Audit
+type Audit struct {
+	*sync.Mutex `json:"-"`
+	Sources     []SourceAudit `json:"sources"`
+	Conflicts   []Conflict    `json:"conflicts,omitempty"`
+}
+
+// SourceAudit records what processing a source would write, and the lines
+// its file would gain and lose
+type SourceAudit struct {
+	Node     string   `json:"node"`
+	Name     string   `json:"name"`
+	File     string   `json:"file"`
+	Entries  int      `json:"entries"`
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Rejected int      `json:"rejected,omitempty"`
+	Samples  []string `json:"rejectedSamples,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Audit fetches and processes cts as ProcessContent does, but instead of
+// writing the generated files it compares them with the files on disk. No
+// files are written, including the quarantine state, the source cache and
+// the HMACKey, and dnsmasq isn't touched
+func (c *Config) Audit(cts ...Contenter) (*Audit, error) {
+	a := &Audit{Mutex: &sync.Mutex{}, Sources: []SourceAudit{}}
+
+	c.audit = a
+	defer func() { c.audit = nil }()
+
+	err := c.ProcessContent(cts...)
+
+	sort.Slice(a.Sources, func(i, j int) bool {
+		if a.Sources[i].Node != a.Sources[j].Node {
+			return a.Sources[i].Node < a.Sources[j].Node
+		}
+		return a.Sources[i].Name < a.Sources[j].Name
+	})
+	a.Conflicts = c.Conflicts()
+	return a, err
+}
+
+// add records the source's generated lines against its files on disk
+func (a *Audit) add(o *object, b *bList) error {
+	lines, err := readLines(b.r)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.current()
+	if err != nil {
+		return err
+	}
+
+	s := SourceAudit{
+		Node:     getType(o.nType).(string),
+		Name:     o.name,
+		File:     b.file,
+		Entries:  b.n,
+		Added:    missing(lines, cur),
+		Removed:  missing(cur, lines),
+		Rejected: o.rejected,
+		Samples:  o.rejects,
+	}
+	if o.err != nil {
+		s.Error = o.err.Error()
+	}
+
+	a.Lock()
+	a.Sources = append(a.Sources, s)
+	a.Unlock()
+	return nil
+}
+
+// current returns the lines of the file, or its shards, b would replace
+func (b *bList) current() ([]string, error) {
+	files := []string{b.file}
+	if b.shard > 0 {
+		files = nil
+		for i := 0; ; i++ {
+			if _, err := os.Stat(shardFile(b.file, i)); err != nil {
+				break
+			}
+			files = append(files, shardFile(b.file, i))
+		}
+	}
+
+	var lines []string
+	for _, file := range files {
+		f, err := os.Open(file)
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		l, err := readLines(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l...)
+	}
+	return lines, nil
+}
+
+// missing returns the sorted lines of a that aren't in b
+func missing(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, l := range b {
+		in[l] = struct{}{}
+	}
+
+	var m []string
+	for _, l := range a {
+		if _, ok := in[l]; !ok {
+			m = append(m, l)
+		}
+	}
+	sort.Strings(m)
+	return m
+}
+
+// readLines returns r's non-blank lines
+func readLines(r io.Reader) ([]string, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := bytes.TrimSpace(s.Bytes()); len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines, s.Err()
+}
+
+// reject counts a source line that isn't a comment, domain or IP address,
+// keeping the first rejectSamples for Audit
+func (o *object) reject(line []byte) {
+	if o.rejected++; len(o.rejects) < rejectSamples {
+		o.rejects = append(o.rejects, string(line))
+	}
+}
@@ -0,0 +1,90 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAudit(t *testing.T) {
+	Convey("Testing Audit()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			src = dir + "/feed.txt"
+			out = dir + "/domains.feed.blacklist.conf"
+			cfg = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude included.example.com\n\t\texclude ads.example.com\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+		)
+
+		lines := []string{"ads.example.com", "new.example.com", "# comment", "", "<html>"}
+		for i := 0; i < rejectSamples+2; i++ {
+			lines = append(lines, "not a domain")
+		}
+		So(ioutil.WriteFile(src, []byte(strings.Join(lines, "\n")), 0644), ShouldBeNil)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			HMACKey(dir+"/blacklist.hmac"),
+			Nodes([]string{domains}),
+			Prefix("address="),
+			Quarantine(time.Hour),
+			SeenFile(dir+"/seen.json"),
+			Shard(1),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		So(ioutil.WriteFile(shardFile(out, 0), []byte("address=/.gone.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/seen.json", []byte("{}"), 0644), ShouldBeNil)
+
+		audit := func() *Audit {
+			var cts []Contenter
+			for _, iface := range []IFace{ExDmObj, PreDObj, FileObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				cts = append(cts, ct)
+			}
+
+			a, err := c.Audit(cts...)
+			So(err, ShouldBeNil)
+			return a
+		}
+
+		a := audit()
+		So(a.Sources, ShouldHaveLength, 2)
+
+		feed := a.Sources[0]
+		So(feed.Name, ShouldEqual, "feed")
+		So(feed.Entries, ShouldEqual, 0)
+		So(feed.Added, ShouldBeEmpty)
+		So(feed.Removed, ShouldResemble, []string{"address=/.gone.example.com/0.0.0.0"})
+		So(feed.Rejected, ShouldEqual, rejectSamples+3)
+		So(feed.Samples, ShouldHaveLength, rejectSamples)
+		So(feed.Samples[0], ShouldEqual, "<html>")
+
+		inc := a.Sources[1]
+		So(inc.Name, ShouldEqual, "includes.[1]")
+		So(inc.Added, ShouldResemble, []string{"address=/included.example.com/0.0.0.0"})
+
+		Convey("nothing is written", func() {
+			files, err := filepath.Glob(dir + "/*")
+			So(err, ShouldBeNil)
+			So(files, ShouldResemble, []string{shardFile(out, 0), src, dir + "/seen.json"})
+
+			b, err := ioutil.ReadFile(dir + "/seen.json")
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "{}")
+
+			So(audit().Sources[0], ShouldResemble, feed)
+			So(c.audit, ShouldBeNil)
+		})
+	})
+}
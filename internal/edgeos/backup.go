@@ -0,0 +1,343 @@
+package edgeos
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// backup archive directories
+const (
+	backupCache = "cache/"
+	backupGen   = "generated/"
+	backupState = "state/"
+)
+
+// stateFiles returns the files recording run state between runs
+func (c *Config) stateFiles() []string {
+	var (
+		files []string
+		seen  = make(map[string]bool)
+	)
+	for _, f := range append([]string{c.CatFile, c.FailDB, c.Hist, c.Seen, c.StaleDB}, c.State...) {
+		if f != "" && !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// addFile adds file to the archive as name, missing files are skipped
+func addFile(tw *tar.Writer, file, name string) error {
+	fi, err := os.Stat(file)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case !fi.Mode().IsRegular():
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	h.Name = name
+	if err = tw.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Backup writes a tar.gz archive of the generated files, the source cache
+// and the state files to w
+func (c *Config) Backup(w io.Writer) error {
+	var (
+		gz = gzip.NewWriter(w)
+		tw = tar.NewWriter(gz)
+	)
+
+	var gen []string
+	for _, sfx := range []string{"", countExt, gzExt} {
+		names, err := c.globFiles(c.Dir, sfx)
+		if err != nil {
+			return err
+		}
+		gen = append(gen, names...)
+	}
+
+	for _, f := range gen {
+		if err := addFile(tw, f, backupGen+filepath.Base(f)); err != nil {
+			return err
+		}
+	}
+
+	if c.Cache != "" {
+		err := filepath.Walk(c.Cache, func(f string, fi os.FileInfo, err error) error {
+			if err != nil || fi.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(c.Cache, f)
+			if err != nil {
+				return err
+			}
+			return addFile(tw, f, backupCache+filepath.ToSlash(rel))
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	for _, f := range c.stateFiles() {
+		if err := addFile(tw, f, backupState+filepath.Base(f)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// restorePath returns where an archived file is restored to, or "" if it
+// has no place in this configuration; generated files must be named as this
+// configuration names them
+func (c *Config) restorePath(name string) (string, error) {
+	clean := path.Clean(name)
+	if clean != name || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("refusing to restore %q", name)
+	}
+
+	switch {
+	case strings.HasPrefix(name, backupGen):
+		base := strings.TrimPrefix(name, backupGen)
+		if !c.isGenerated(base, gzExt, countExt) {
+			return "", fmt.Errorf("refusing to restore %q", name)
+		}
+		return filepath.Join(c.Dir, base), nil
+
+	case strings.HasPrefix(name, backupCache):
+		if c.Cache == "" {
+			return "", nil
+		}
+		return filepath.Join(c.Cache, filepath.FromSlash(strings.TrimPrefix(name, backupCache))), nil
+
+	case strings.HasPrefix(name, backupState):
+		base := strings.TrimPrefix(name, backupState)
+		for _, f := range c.stateFiles() {
+			if filepath.Base(f) == base {
+				return f, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// restorable is true for the lines a generated file holds, so an archive
+// can't add other dnsmasq options, e.g. a dhcp-script, to the dnsmasq directory
+func restorable(line string) bool {
+	return line == "" || strings.HasPrefix(line, "address=/") || strings.HasPrefix(line, "server=/")
+}
+
+// copyLines copies r to w without the lines restorable refuses, returning
+// how many were dropped
+func copyLines(w io.Writer, r io.Reader) (int, error) {
+	var (
+		dropped int
+		bw      = bufio.NewWriter(w)
+		s       = bufio.NewScanner(r)
+	)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for s.Scan() {
+		if !restorable(s.Text()) {
+			dropped++
+			continue
+		}
+		bw.Write(s.Bytes())
+		bw.WriteByte('\n')
+	}
+	if err := s.Err(); err != nil {
+		return dropped, err
+	}
+	return dropped, bw.Flush()
+}
+
+// copyGenerated copies the archived generated file name from r to w: .count
+// files must hold a count, and the lines of the dnsmasq files and their .gz
+// copies are filtered through restorable; it returns the lines dropped
+func copyGenerated(w io.Writer, r io.Reader, name string) (int, error) {
+	switch {
+	case strings.HasSuffix(name, countExt):
+		fc := &fileCount{}
+		if err := json.NewDecoder(r).Decode(fc); err != nil {
+			return 0, fmt.Errorf("refusing to restore %q: %v", name, err)
+		}
+		b, err := json.Marshal(fc)
+		if err != nil {
+			return 0, err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return 0, err
+
+	case strings.HasSuffix(name, gzExt):
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, fmt.Errorf("refusing to restore %q: %v", name, err)
+		}
+		gw := gzip.NewWriter(w)
+		dropped, err := copyLines(gw, gr)
+		if err != nil {
+			return dropped, err
+		}
+		return dropped, gw.Close()
+	}
+	return copyLines(w, r)
+}
+
+// Restore unpacks an archive written by Backup, replacing the generated,
+// cached and state files it holds; generated files only keep the address=
+// and server= lines blacklist writes. It returns the files restored
+func (c *Config) Restore(r io.Reader) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var (
+		restored []string
+		tr       = tar.NewReader(gz)
+	)
+	for {
+		h, err := tr.Next()
+		switch {
+		case err == io.EOF:
+			return restored, nil
+		case err != nil:
+			return restored, err
+		case h.Typeflag != tar.TypeReg:
+			continue
+		}
+
+		f, err := c.restorePath(h.Name)
+		switch {
+		case err != nil:
+			return restored, err
+		case f == "":
+			c.debug(fmt.Sprintf("backup: skipping %v", h.Name))
+			continue
+		}
+
+		if err = os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			return restored, err
+		}
+
+		tmp := f + ".tmp"
+		w, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return restored, err
+		}
+		if strings.HasPrefix(h.Name, backupGen) {
+			var dropped int
+			if dropped, err = copyGenerated(w, tr, h.Name); dropped > 0 {
+				c.warn(fmt.Sprintf("backup: dropped %d lines from %v that blacklist doesn't generate", dropped, h.Name))
+			}
+		} else {
+			_, err = io.Copy(w, tr)
+		}
+		if err != nil {
+			w.Close()
+			os.Remove(tmp)
+			return restored, err
+		}
+		if err = w.Close(); err != nil {
+			return restored, err
+		}
+		if err = os.Rename(tmp, f); err != nil {
+			return restored, err
+		}
+		os.Chtimes(f, h.ModTime, h.ModTime)
+		restored = append(restored, f)
+	}
+}
+
+// Upload PUTs b to u, an http(s):// or s3:// URL
+func (c *Config) Upload(u string, b []byte) error {
+	o := &object{Parms: c.Parms, name: "backup", url: u}
+	endpoint, auth, err := o.objectURL()
+	if err != nil {
+		return err
+	}
+	if endpoint, o.secrets, err = expand(endpoint); err != nil {
+		return err
+	}
+	o.secrets = append(o.secrets, o.credentials(endpoint)...)
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+
+	if auth != nil {
+		sum := sha256.Sum256(b)
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+		if err = auth(client, req); err != nil {
+			return redactErr(err, o.secrets)
+		}
+	}
+	o.secrets = append(o.secrets, o.headerSecrets(req.Header)...)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return redactErr(err, o.secrets)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unable to upload backup to %v: %v", redact(u, o.secrets), resp.Status)
+	}
+	return nil
+}
+
+// Download fetches a backup archive from u, any URL a source can use
+func (c *Config) Download(u string) (io.Reader, error) {
+	o := getHTTP(&object{Parms: c.Parms, name: "backup", url: u})
+	if o.err != nil {
+		return nil, o.err
+	}
+	b, err := ioutil.ReadAll(o.r)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
@@ -0,0 +1,160 @@
+package edgeos
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackup(t *testing.T) {
+	Convey("Testing backup and restore", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for _, d := range []string{"/gen", "/cache/feeds", "/state"} {
+			So(os.MkdirAll(dir+d, 0755), ShouldBeNil)
+		}
+
+		files := map[string]string{
+			"/gen/domains.feed.blacklist.conf":       "address=/.ads.example.com/0.0.0.0\n",
+			"/gen/domains.feed.blacklist.conf.count": `{"entries":1,"sha256":"abc"}` + "\n",
+			"/gen/unrelated.txt":                     "not generated\n",
+			"/cache/feeds/feed.txt":                  "ads.example.com\n",
+			"/state/seen.json":                       "{}",
+			"/state/blacklist.digest":                "cafe",
+		}
+		for f, body := range files {
+			So(ioutil.WriteFile(dir+f, []byte(body), 0644), ShouldBeNil)
+		}
+
+		newConfig := func(root string) *Config {
+			return NewConfig(
+				Cache(root+"/cache"),
+				Dir(root+"/gen"),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				SeenFile(root+"/state/seen.json"),
+				StateFiles([]string{root + "/state/blacklist.digest", ""}),
+				WCard(Wildcard{Node: "*s", Name: "*"}),
+			)
+		}
+
+		var b bytes.Buffer
+		So(newConfig(dir).Backup(&b), ShouldBeNil)
+
+		to := dir + "/restored"
+		restored, err := newConfig(to).Restore(bytes.NewReader(b.Bytes()))
+		So(err, ShouldBeNil)
+		sort.Strings(restored)
+		So(restored, ShouldResemble, []string{
+			to + "/cache/feeds/feed.txt",
+			to + "/gen/domains.feed.blacklist.conf",
+			to + "/gen/domains.feed.blacklist.conf.count",
+			to + "/state/blacklist.digest",
+			to + "/state/seen.json",
+		})
+
+		for _, f := range restored {
+			got, err := ioutil.ReadFile(f)
+			So(err, ShouldBeNil)
+			So(string(got), ShouldEqual, files[f[len(to):]])
+		}
+
+		Convey("backups can be uploaded and downloaded", func() {
+			var put []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPut:
+					put, _ = ioutil.ReadAll(r.Body)
+				default:
+					w.Write(put)
+				}
+			}))
+			defer srv.Close()
+
+			c := newConfig(dir)
+			c.SetOpt(Method("GET"))
+			So(c.Upload(srv.URL+"/backup.tar.gz", b.Bytes()), ShouldBeNil)
+			So(put, ShouldResemble, b.Bytes())
+
+			r, err := c.Download(srv.URL + "/backup.tar.gz")
+			So(err, ShouldBeNil)
+			got, _ := ioutil.ReadAll(r)
+			So(got, ShouldResemble, b.Bytes())
+		})
+
+		Convey("generated files only keep the lines blacklist writes", func() {
+			var (
+				b  bytes.Buffer
+				gz = gzip.NewWriter(&b)
+				tw = tar.NewWriter(gz)
+			)
+			for _, f := range []struct{ name, body string }{
+				{name: "generated/domains.feed.blacklist.conf", body: "address=/.ads.example.com/0.0.0.0\ndhcp-script=/tmp/x\nserver=/ok.example.com/#\n"},
+				{name: "generated/hosts.feed.blacklist.conf.count", body: "conf-file=/tmp/x\n"},
+			} {
+				So(tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}), ShouldBeNil)
+				_, err := tw.Write([]byte(f.body))
+				So(err, ShouldBeNil)
+			}
+			So(tw.Close(), ShouldBeNil)
+			So(gz.Close(), ShouldBeNil)
+
+			var got bytes.Buffer
+			dropped, err := copyGenerated(&got, bytes.NewBufferString("address=/a.example.com/0.0.0.0\ndhcp-script=/tmp/x\n"), "generated/hosts.feed.blacklist.conf")
+			So(err, ShouldBeNil)
+			So(dropped, ShouldEqual, 1)
+			So(got.String(), ShouldEqual, "address=/a.example.com/0.0.0.0\n")
+
+			var zipped, plain bytes.Buffer
+			zw := gzip.NewWriter(&zipped)
+			zw.Write([]byte("address=/a.example.com/0.0.0.0\nconf-dir=/tmp\n"))
+			zw.Close()
+			got.Reset()
+			dropped, err = copyGenerated(&got, &zipped, "generated/hosts.feed.blacklist.conf.gz")
+			So(err, ShouldBeNil)
+			So(dropped, ShouldEqual, 1)
+			zr, err := gzip.NewReader(&got)
+			So(err, ShouldBeNil)
+			io.Copy(&plain, zr)
+			So(plain.String(), ShouldEqual, "address=/a.example.com/0.0.0.0\n")
+
+			restored, err := newConfig(to).Restore(bytes.NewReader(b.Bytes()))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, `refusing to restore "generated/hosts.feed.blacklist.conf.count"`)
+			So(restored, ShouldResemble, []string{to + "/gen/domains.feed.blacklist.conf"})
+
+			conf, err := ioutil.ReadFile(restored[0])
+			So(err, ShouldBeNil)
+			So(string(conf), ShouldEqual, "address=/.ads.example.com/0.0.0.0\nserver=/ok.example.com/#\n")
+			_, err = os.Stat(to + "/gen/hosts.feed.blacklist.conf.count.tmp")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("archives can't restore outside their directories", func() {
+			c := newConfig(to)
+			for _, name := range []string{"generated/../../etc/passwd", "/etc/passwd", "generated/sub/file", "../x", "generated/rc.local", "generated/domains.feed.blacklist.conf.sh"} {
+				_, err := c.restorePath(name)
+				So(err, ShouldNotBeNil)
+			}
+
+			f, err := c.restorePath("generated/domains.feed.blacklist.001.conf.gz")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, to+"/gen/domains.feed.blacklist.001.conf.gz")
+
+			f, err = c.restorePath("state/unknown.json")
+			So(err, ShouldBeNil)
+			So(f, ShouldEqual, "")
+		})
+	})
+}
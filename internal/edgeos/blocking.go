@@ -0,0 +1,22 @@
+package edgeos
+
+const (
+	// blockingMode is the node leaf choosing how its domains are blocked
+	blockingMode = "blocking-mode"
+
+	// blocking modes: dnsmasq answers NXDOMAIN, or the dns-redirect-ip
+	blockNXDomain = "nxdomain"
+	blockRedirect = "redirect"
+)
+
+// blocking returns node's blocking mode, the blacklist node's if it doesn't
+// set one
+func (b tree) blocking(node string) string {
+	switch {
+	case b[node] != nil && b[node].blocking != "":
+		return b[node].blocking
+	case b[rootNode] != nil && b[rootNode].blocking != "":
+		return b[rootNode].blocking
+	}
+	return blockRedirect
+}
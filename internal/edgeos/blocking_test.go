@@ -0,0 +1,86 @@
+package edgeos
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlockingMode(t *testing.T) {
+	Convey("Testing the blocking-mode leaf", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		src := dir + "/feed.txt"
+		So(ioutil.WriteFile(src, []byte("ads.example.com\n"), 0644), ShouldBeNil)
+
+		run := func(cfg string) string {
+			var b bytes.Buffer
+			c := NewConfig(
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains, hosts}),
+				Prefix("address="),
+				Writer(&b),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			var cts []Contenter
+			for _, iface := range []IFace{PreDObj, PreHObj, FileObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				cts = append(cts, ct)
+			}
+			So(c.ProcessContent(cts...), ShouldBeNil)
+
+			lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+			sort.Strings(lines)
+			return strings.Join(lines, "\n")
+		}
+
+		source := "\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n"
+
+		Convey("answers NXDOMAIN for every node", func() {
+			act := run("blacklist {\n\tblocking-mode nxdomain\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n" + source + "\t}\n\thosts {\n\t\tinclude tracker.example.com\n\t}\n}")
+			So(act, ShouldEqual, "address=/.ads.example.com/\naddress=/tracker.example.com/")
+		})
+
+		Convey("redirects a node that overrides it", func() {
+			act := run("blacklist {\n\tblocking-mode nxdomain\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tblocking-mode redirect\n" + source + "\t}\n\thosts {\n\t\tinclude tracker.example.com\n\t}\n}")
+			So(act, ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/tracker.example.com/")
+		})
+
+		Convey("answers NXDOMAIN for a single node", func() {
+			act := run("blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n" + source + "\t}\n\thosts {\n\t\tblocking-mode nxdomain\n\t\tinclude tracker.example.com\n\t}\n}")
+			So(act, ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/tracker.example.com/")
+		})
+
+		Convey("rejects an unknown mode", func() {
+			c := NewConfig(Nodes([]string{domains, hosts}))
+			err := c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tblocking-mode refuse\n\t}\n}"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `node "domains" has unknown blocking-mode "refuse"`)
+		})
+
+		Convey("is kept in snapshots and explained", func() {
+			c := NewConfig(Nodes([]string{domains, hosts}))
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tblocking-mode nxdomain\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n" + source + "\t}\n}"}), ShouldBeNil)
+			So(c.tree.getIP(domains), ShouldEqual, "")
+
+			x := c.Explain()
+			So(x[2].Settings[0], ShouldResemble, &Setting{Name: blackhole, Value: "", From: "node blacklist blocking-mode nxdomain"})
+
+			b, err := json.Marshal(c)
+			So(err, ShouldBeNil)
+			n := NewConfig(Nodes([]string{domains, hosts}))
+			So(n.ReadCfg(&CFGjson{Cfg: string(b)}), ShouldBeNil)
+			So(n.tree.blocking(domains), ShouldEqual, blockNXDomain)
+		})
+	})
+}
@@ -0,0 +1,226 @@
+package edgeos
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"html/template"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBlockPage is served for blocked domains unless a template is supplied
+const defaultBlockPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Blocked: {{.Domain}}</title></head>
+<body style="font-family: sans-serif; margin: 4em auto; max-width: 40em">
+<h1>Blocked by policy</h1>
+<p><b>{{.Domain}}</b> is on this network's blacklist.</p>
+{{if .Unblock}}<form method="post" action="{{.Unblock}}"><button type="submit">Request unblock</button></form>{{end}}
+</body>
+</html>
+`
+
+// unblockPath receives the block page's "request unblock" form
+const unblockPath = "/.blacklist/unblock"
+
+// maxBlockCerts limits the number of cached self-signed certificates
+const maxBlockCerts = 1024
+
+// pixelExts are path extensions answered with 204 No Content instead of the
+// block page, since they're requested by pages rather than by people
+var pixelExts = map[string]bool{
+	".css": true, ".gif": true, ".ico": true, ".jpeg": true, ".jpg": true,
+	".js": true, ".json": true, ".png": true, ".svg": true, ".webp": true,
+}
+
+// pixelWords are path segments used by tracking pixels and beacons
+var pixelWords = []string{"beacon", "collect", "pixel", "track"}
+
+// BlockPage is an http.Handler for the blackhole address that explains why a
+// domain was blocked instead of leaving browsers with a connection error
+type BlockPage struct {
+	// Pending enables the "request unblock" button, requested domains are
+	// appended to this file for the admin to review
+	Pending string
+	// Webhook is sent a JSON UnblockRequest for each new request if set
+	Webhook string
+	// OnError is called with webhook failures if set
+	OnError func(error)
+
+	tmpl  *template.Template
+	mu    sync.Mutex
+	certs map[string]*tls.Certificate
+}
+
+// BlockPageData is passed to the block page template
+type BlockPageData struct {
+	Domain  string
+	URL     string
+	Unblock string
+}
+
+// UnblockRequest records a request to unblock a domain
+type UnblockRequest struct {
+	Domain string    `json:"domain"`
+	Client string    `json:"client"`
+	Time   time.Time `json:"time"`
+}
+
+// NewBlockPage returns a *BlockPage using the html/template in file, or the
+// built-in page if file is empty
+func NewBlockPage(file string) (*BlockPage, error) {
+	text := defaultBlockPage
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+
+	t, err := template.New("blockpage").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockPage{tmpl: t, certs: make(map[string]*tls.Certificate)}, nil
+}
+
+// isPixel returns true for requests that aren't for a page
+func isPixel(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return true
+	}
+
+	if a := r.Header.Get("Accept"); a != "" && !strings.Contains(a, "text/html") && !strings.Contains(a, "*/*") {
+		return true
+	}
+
+	p := strings.ToLower(r.URL.Path)
+	if pixelExts[path.Ext(p)] {
+		return true
+	}
+	for _, w := range pixelWords {
+		if strings.Contains(p, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP implements http.Handler
+func (b *BlockPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Path == unblockPath && b.Pending != "" {
+		b.unblock(w, r)
+		return
+	}
+
+	if isPixel(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	host := hostname(r.Host)
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	d := &BlockPageData{Domain: host, URL: scheme + "://" + r.Host + r.URL.RequestURI()}
+	if b.Pending != "" {
+		d.Unblock = unblockPath
+	}
+	b.tmpl.Execute(w, d)
+}
+
+// hostname strips any port from a Host header
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// TLSConfig returns a *tls.Config that presents a self-signed certificate for
+// each requested server name, browsers will still warn, but can then show the
+// block page rather than a connection error
+func (b *BlockPage) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: b.certificate}
+}
+
+// certificate returns a cached self-signed certificate for the SNI name
+func (b *BlockPage) certificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := hello.ServerName
+	if name == "" {
+		name = "blocked"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.certs[name]; ok && time.Now().Before(c.Leaf.NotAfter.Add(-time.Hour)) {
+		return c, nil
+	}
+
+	c, err := selfSigned(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b.certs) >= maxBlockCerts {
+		b.certs = make(map[string]*tls.Certificate)
+	}
+	b.certs[name] = c
+	return c, nil
+}
+
+// selfSigned returns a short lived self-signed certificate for name
+func selfSigned(name string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"blacklist"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(7 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{name},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
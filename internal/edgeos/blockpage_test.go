@@ -0,0 +1,95 @@
+package edgeos
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlockPage(t *testing.T) {
+	Convey("Testing BlockPage", t, func() {
+		b, err := NewBlockPage("")
+		So(err, ShouldBeNil)
+
+		tests := []struct {
+			name   string
+			method string
+			path   string
+			accept string
+			status int
+			body   string
+		}{
+			{name: "page", method: http.MethodGet, path: "/index.html", accept: "text/html,application/xhtml+xml", status: http.StatusForbidden, body: "<b>ads.example.com</b> is on this network's blacklist."},
+			{name: "no accept header", method: http.MethodGet, path: "/", status: http.StatusForbidden, body: "Blocked by policy"},
+			{name: "head", method: http.MethodHead, path: "/", status: http.StatusForbidden},
+			{name: "pixel extension", method: http.MethodGet, path: "/img/1x1.GIF", status: http.StatusNoContent},
+			{name: "tracking path", method: http.MethodGet, path: "/v1/collect?id=1", status: http.StatusNoContent},
+			{name: "script", method: http.MethodGet, path: "/ads", accept: "application/javascript", status: http.StatusNoContent},
+			{name: "post", method: http.MethodPost, path: "/", status: http.StatusNoContent},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.name, func() {
+				r := httptest.NewRequest(tt.method, "http://ads.example.com"+tt.path, nil)
+				if tt.accept != "" {
+					r.Header.Set("Accept", tt.accept)
+				}
+
+				w := httptest.NewRecorder()
+				b.ServeHTTP(w, r)
+				So(w.Code, ShouldEqual, tt.status)
+				So(w.Header().Get("Cache-Control"), ShouldEqual, "no-store")
+				switch tt.body {
+				case "":
+					So(w.Body.String(), ShouldBeEmpty)
+				default:
+					So(w.Body.String(), ShouldContainSubstring, tt.body)
+				}
+			})
+		}
+
+		Convey("with a custom template", func() {
+			f, err := ioutil.TempFile("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.Remove(f.Name())
+			f.WriteString("{{.Domain}} blocked, you asked for {{.URL}}")
+			f.Close()
+
+			b, err := NewBlockPage(f.Name())
+			So(err, ShouldBeNil)
+
+			w := httptest.NewRecorder()
+			b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://ads.example.com:8080/page?a=<b>", nil))
+			So(w.Body.String(), ShouldEqual, "ads.example.com blocked, you asked for http://ads.example.com:8080/page?a=&lt;b&gt;")
+
+			_, err = NewBlockPage(f.Name() + ".missing")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("over HTTPS", func() {
+			srv := httptest.NewUnstartedServer(b)
+			srv.TLS = b.TLSConfig()
+			srv.StartTLS()
+			defer srv.Close()
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ServerName: "ads.example.com"}}}
+			resp, err := client.Get(srv.URL)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+
+			So(resp.StatusCode, ShouldEqual, http.StatusForbidden)
+			So(resp.TLS.PeerCertificates[0].DNSNames, ShouldResemble, []string{"ads.example.com"})
+
+			c1, err := b.certificate(&tls.ClientHelloInfo{ServerName: "ads.example.com"})
+			So(err, ShouldBeNil)
+			c2, err := b.certificate(&tls.ClientHelloInfo{ServerName: "ads.example.com"})
+			So(err, ShouldBeNil)
+			So(c1, ShouldEqual, c2)
+		})
+	})
+}
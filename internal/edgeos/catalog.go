@@ -0,0 +1,246 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// catalogLeaf is the source leaf naming a catalog entry, on its own it
+	// names the entry with the source's name
+	catalogLeaf = "catalog"
+	// catalogAge is how long a downloaded catalog is used before CatalogURL
+	// is checked for a newer one
+	catalogAge = 24 * time.Hour
+)
+
+var (
+	// ErrCatalogSignature is returned when a catalog's signature is invalid
+	ErrCatalogSignature = errors.New("invalid catalog signature")
+
+	// ErrCatalogKey is returned when a catalog is read without a CatalogKey
+	// to verify it with, there is no built-in publisher key
+	ErrCatalogKey = errors.New("no catalog public key to verify the catalog with")
+
+	// catalogSerial is the built-in catalog's serial, a downloaded or saved
+	// catalog older than it is refused, so an old signed catalog can't be
+	// replayed to roll sources back
+	catalogSerial int64 = 2026101600
+)
+
+// CatalogSource is a well-known list a source can name with a catalog leaf
+// instead of spelling out its url, prefix and description; Node is the node
+// its format suits and Deprecated, if set, says what to use instead
+type CatalogSource struct {
+	Name       string `json:"name"`
+	Node       string `json:"node"`
+	URL        string `json:"url"`
+	Prefix     string `json:"prefix,omitempty"`
+	Desc       string `json:"description"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// CatalogDoc is a catalog published upstream, Serial must increase with each
+// release and Signature signs Serial and Sources
+type CatalogDoc struct {
+	Serial    int64           `json:"serial"`
+	Sources   json.RawMessage `json:"sources"`
+	Signature []byte          `json:"signature"`
+}
+
+// catalog is the built-in catalog, sorted by name
+var catalog = []CatalogSource{
+	{Name: "hagezi-light", Node: domains, URL: "https://raw.githubusercontent.com/hagezi/dns-blocklists/main/domains/light.txt", Desc: "HaGeZi Light, ads, tracking and malware with few false positives"},
+	{Name: "hagezi-normal", Node: domains, URL: "https://raw.githubusercontent.com/hagezi/dns-blocklists/main/domains/multi.txt", Desc: "HaGeZi Normal, all-round protection"},
+	{Name: "hagezi-pro", Node: domains, URL: "https://raw.githubusercontent.com/hagezi/dns-blocklists/main/domains/pro.txt", Desc: "HaGeZi Pro, extended protection"},
+	{Name: "hagezi-pro-plus", Node: domains, URL: "https://raw.githubusercontent.com/hagezi/dns-blocklists/main/domains/pro.plus.txt", Desc: "HaGeZi Pro++, maximum protection, some false positives"},
+	{Name: "hagezi-ultimate", Node: domains, URL: "https://raw.githubusercontent.com/hagezi/dns-blocklists/main/domains/ultimate.txt", Desc: "HaGeZi Ultimate, aggressive, expect false positives"},
+	{Name: "oisd-basic", Node: domains, URL: "https://small.oisd.nl/domainswild", Desc: "OISD small, ads, phishing, malware and tracking without breaking sites"},
+	{Name: "oisd-full", Node: domains, URL: "https://big.oisd.nl/domainswild", Desc: "OISD big, the full OISD blocklist"},
+	{Name: "stevenblack", Node: hosts, URL: "https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts", Prefix: "0.0.0.0 ", Desc: "StevenBlack unified hosts, adware and malware"},
+	{Name: "urlhaus", Node: hosts, URL: "https://urlhaus.abuse.ch/downloads/hostfile/", Prefix: "127.0.0.1", Desc: "abuse.ch URLhaus, hosts distributing malware"},
+}
+
+// message returns the signed content
+func (d *CatalogDoc) message() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d\n", d.Serial)
+	if err := json.Compact(&b, d.Sources); err != nil {
+		b.Write(d.Sources)
+	}
+	return b.Bytes()
+}
+
+// Sign signs the document with the catalog publisher's private key
+func (d *CatalogDoc) Sign(key ed25519.PrivateKey) {
+	d.Signature = ed25519.Sign(key, d.message())
+}
+
+// parse verifies the document's signature with key and returns its sources,
+// sorted by name
+func (d *CatalogDoc) parse(key ed25519.PublicKey) ([]CatalogSource, error) {
+	if !ed25519.Verify(key, d.message(), d.Signature) {
+		return nil, ErrCatalogSignature
+	}
+
+	var s []CatalogSource
+	if err := json.Unmarshal(d.Sources, &s); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(s, func(i, j int) bool { return s[i].Name < s[j].Name })
+	return s, nil
+}
+
+// loadedCatalog is a verified catalog from CatalogURL
+type loadedCatalog struct {
+	serial  int64
+	sources []CatalogSource
+}
+
+// readCatalog reads and verifies a catalog document, signed with CatalogKey,
+// that is no older than the built-in catalog
+func (p *Parms) readCatalog(b []byte, origin string) (*loadedCatalog, error) {
+	if p.CatKey == nil {
+		return nil, ErrCatalogKey
+	}
+
+	d := &CatalogDoc{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("%v: %v", origin, err)
+	}
+
+	s, err := d.parse(p.CatKey)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("%v: %v", origin, err)
+	case d.Serial < catalogSerial:
+		return nil, fmt.Errorf("%v: catalog serial %d is older than the built-in catalog's %d", origin, d.Serial, catalogSerial)
+	}
+	return &loadedCatalog{serial: d.Serial, sources: s}, nil
+}
+
+// fetchCatalog downloads the catalog from CatalogURL, keeping the current one
+// unless the download's serial is newer, and saves it to CatalogFile
+func (p *Parms) fetchCatalog() error {
+	o := getHTTP(&object{Parms: p, name: "catalog", url: p.CatURL})
+	if o.err != nil {
+		return o.err
+	}
+
+	b, err := ioutil.ReadAll(o.r)
+	if err != nil {
+		return fmt.Errorf("%v: %v", p.CatURL, err)
+	}
+
+	l, err := p.readCatalog(b, p.CatURL)
+	switch {
+	case err != nil:
+		return err
+	case p.cat != nil && l.serial < p.cat.serial:
+		return fmt.Errorf("%v: stale catalog serial %d, already at %d", p.CatURL, l.serial, p.cat.serial)
+	}
+
+	p.cat = l
+	if p.CatFile == "" {
+		return nil
+	}
+
+	tmp := p.CatFile + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.CatFile)
+}
+
+// UpdateCatalog loads the catalog saved in CatalogFile and, if it is more
+// than a day old or can't be read or verified, downloads a newer one from
+// CatalogURL; the built-in catalog is used until a verified catalog has been
+// loaded
+func (c *Config) UpdateCatalog() error {
+	switch {
+	case c.CatURL == "":
+		return nil
+	case c.CatKey == nil:
+		return ErrCatalogKey
+	}
+
+	stale := true
+	if c.CatFile != "" {
+		fi, err := os.Stat(c.CatFile)
+		if err == nil {
+			err = c.readCatalogFile()
+		}
+
+		switch {
+		case err == nil:
+			stale = time.Since(fi.ModTime()) > catalogAge
+		case !os.IsNotExist(err):
+			c.warn(fmt.Sprintf("Ignoring the saved catalog: %v", err))
+		}
+	}
+
+	if !stale {
+		return nil
+	}
+	return c.fetchCatalog()
+}
+
+// readCatalogFile loads the catalog saved in CatalogFile
+func (c *Config) readCatalogFile() error {
+	b, err := ioutil.ReadFile(c.CatFile)
+	if err != nil {
+		return err
+	}
+
+	l, err := c.readCatalog(b, c.CatFile)
+	if err != nil {
+		return err
+	}
+	c.cat = l
+	return nil
+}
+
+// Catalog returns the catalog of well-known lists, sorted by name
+func (c *Config) Catalog() []CatalogSource {
+	if c.cat != nil {
+		return append([]CatalogSource(nil), c.cat.sources...)
+	}
+	return append([]CatalogSource(nil), catalog...)
+}
+
+// lookupCatalog returns the catalog entry called name
+func (c *Config) lookupCatalog(name string) (CatalogSource, bool) {
+	cat := catalog
+	if c.cat != nil {
+		cat = c.cat.sources
+	}
+
+	i := sort.Search(len(cat), func(i int) bool { return cat[i].Name >= name })
+	if i < len(cat) && cat[i].Name == name {
+		return cat[i], true
+	}
+	return CatalogSource{}, false
+}
+
+// fromCatalog fills in o's url, prefix and description from its catalog
+// entry s, leaves set in the configuration are kept
+func (o *object) fromCatalog(s CatalogSource) {
+	if o.url == "" && o.file == "" {
+		o.url = s.URL
+	}
+	if o.prefix == "" && s.Prefix != "" {
+		o.prefix = s.Prefix
+		o.inherit("prefix", catalogLeaf+" "+s.Name)
+	}
+	if o.desc == "" {
+		o.desc = s.Desc
+	}
+}
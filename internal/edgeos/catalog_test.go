@@ -0,0 +1,159 @@
+package edgeos
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCatalog(t *testing.T) {
+	Convey("Testing the source catalog", t, func() {
+		Convey("the catalog is sorted and complete", func() {
+			cfg := NewConfig()
+			c := cfg.Catalog()
+			So(sort.SliceIsSorted(c, func(i, j int) bool { return c[i].Name < c[j].Name }), ShouldBeTrue)
+			for _, s := range c {
+				So(s.URL, ShouldStartWith, "https://")
+				So(s.Desc, ShouldNotBeEmpty)
+				So(s.Node == domains || s.Node == hosts, ShouldBeTrue)
+
+				found, ok := cfg.lookupCatalog(s.Name)
+				So(ok, ShouldBeTrue)
+				So(found, ShouldResemble, s)
+			}
+
+			_, ok := cfg.lookupCatalog("missing")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("sources are expanded from their catalog entry", func() {
+			cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource oisd-basic {\n\t\t\tcatalog\n\t\t}\n\t}\n\thosts {\n\t\tsource sb {\n\t\t\tcatalog stevenblack\n\t\t\tdescription \"My hosts\"\n\t\t}\n\t}\n}"
+			c := NewConfig(Nodes([]string{domains, hosts}), Strict(true))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			o := c.Get(domains).Filter(urls).x[0]
+			So(o.url, ShouldEqual, "https://small.oisd.nl/domainswild")
+			So(o.ltype, ShouldEqual, urls)
+			So(o.prefix, ShouldEqual, "")
+			So(o.desc, ShouldStartWith, "OISD small")
+
+			o = c.Get(hosts).Filter(urls).x[0]
+			So(o.url, ShouldEqual, "https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts")
+			So(o.prefix, ShouldEqual, "0.0.0.0 ")
+			So(o.desc, ShouldEqual, "My hosts")
+		})
+
+		Convey("unknown catalog sources are errors", func() {
+			cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tcatalog oisd-tiny\n\t\t}\n\t}\n}"
+			err := NewConfig(Nodes([]string{domains})).ReadCfg(&CFGstatic{Cfg: cfg})
+			So(err.Error(), ShouldEqual, `config.boot:6: source "feed" has unknown catalog source "oisd-tiny"`)
+		})
+
+		Convey("the catalog is refreshed from a signed upstream catalog", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			pub, priv, err := ed25519.GenerateKey(nil)
+			So(err, ShouldBeNil)
+
+			var (
+				fetch int
+				doc   []byte
+				file  = dir + "/catalog.json"
+			)
+			sign := func(serial int64, sources string) []byte {
+				d := &CatalogDoc{Serial: serial, Sources: json.RawMessage(sources)}
+				d.Sign(priv)
+				b, err := json.Marshal(d)
+				So(err, ShouldBeNil)
+				return b
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetch++
+				w.Write(doc)
+			}))
+			defer srv.Close()
+
+			doc = sign(catalogSerial+2, `[{"name":"oisd-basic","node":"domains","url":"https://nsfw.oisd.nl/domainswild","description":"OISD moved"},{"name":"old-list","node":"hosts","url":"https://example.com/hosts","description":"Old list","deprecated":"use oisd-basic"}]`)
+			newConfig := func() *Config {
+				return NewConfig(CatalogFile(file), CatalogKey(pub), CatalogURL(srv.URL+"/catalog.json"), Method("GET"), Nodes([]string{domains, hosts}))
+			}
+
+			c := newConfig()
+			So(c.UpdateCatalog(), ShouldBeNil)
+			So(fetch, ShouldEqual, 1)
+			So(len(c.Catalog()), ShouldEqual, 2)
+			s, ok := c.lookupCatalog("oisd-basic")
+			So(ok, ShouldBeTrue)
+			So(s.URL, ShouldEqual, "https://nsfw.oisd.nl/domainswild")
+
+			Convey("the saved catalog is used until it is a day old", func() {
+				c := newConfig()
+				So(c.UpdateCatalog(), ShouldBeNil)
+				So(fetch, ShouldEqual, 1)
+				So(len(c.Catalog()), ShouldEqual, 2)
+
+				old := time.Now().Add(-catalogAge - time.Minute)
+				So(os.Chtimes(file, old, old), ShouldBeNil)
+				doc = sign(catalogSerial+3, `[{"name":"oisd-basic","node":"domains","url":"https://small.oisd.nl/domainswild","description":"OISD"}]`)
+				c = newConfig()
+				So(c.UpdateCatalog(), ShouldBeNil)
+				So(fetch, ShouldEqual, 2)
+				So(len(c.Catalog()), ShouldEqual, 1)
+			})
+
+			Convey("bad signatures and older serials keep the previous catalog", func() {
+				doc = sign(catalogSerial+1, `[]`)
+				So(c.fetchCatalog(), ShouldNotBeNil)
+				So(len(c.Catalog()), ShouldEqual, 2)
+
+				d := &CatalogDoc{}
+				So(json.Unmarshal(sign(catalogSerial+4, `[]`), d), ShouldBeNil)
+				d.Serial = catalogSerial + 5
+				doc, _ = json.Marshal(d)
+				So(c.fetchCatalog().Error(), ShouldEndWith, ErrCatalogSignature.Error())
+				So(len(c.Catalog()), ShouldEqual, 2)
+			})
+
+			Convey("deprecated entries still expand", func() {
+				cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\thosts {\n\t\tsource old-list {\n\t\t\tcatalog\n\t\t}\n\t}\n}"
+				So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+				So(c.Get(hosts).Filter(urls).x[0].url, ShouldEqual, "https://example.com/hosts")
+			})
+
+			Convey("catalogs older than the built-in one are refused", func() {
+				doc = sign(catalogSerial-1, `[]`)
+				err := newConfig().fetchCatalog()
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEndWith, fmt.Sprintf("catalog serial %d is older than the built-in catalog's %d", catalogSerial-1, catalogSerial))
+			})
+
+			Convey("an unreadable saved catalog is downloaded again", func() {
+				So(ioutil.WriteFile(file, []byte("not json"), 0644), ShouldBeNil)
+				doc = sign(catalogSerial+3, `[{"name":"oisd-basic","node":"domains","url":"https://small.oisd.nl/domainswild","description":"OISD"}]`)
+				c := newConfig()
+				So(c.UpdateCatalog(), ShouldBeNil)
+				So(fetch, ShouldEqual, 2)
+				So(len(c.Catalog()), ShouldEqual, 1)
+			})
+
+			Convey("catalogs aren't read without a key", func() {
+				c := NewConfig(CatalogFile(file), CatalogURL(srv.URL), Method("GET"))
+				So(c.UpdateCatalog(), ShouldEqual, ErrCatalogKey)
+				So(fetch, ShouldEqual, 1)
+				So(c.readCatalogFile(), ShouldEqual, ErrCatalogKey)
+				So(c.Catalog(), ShouldResemble, catalog)
+			})
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package edgeos
+
+import "fmt"
+
+// previous returns the domains in the files o's would replace
+func (o *object) previous() (map[string]struct{}, error) {
+	b := &bList{file: fmt.Sprintf(o.FnFmt, o.Dir, getType(o.nType).(string), o.name, o.Ext), shard: o.Shard}
+	lines, err := b.current()
+	if err != nil {
+		return nil, err
+	}
+
+	d := make(map[string]struct{}, len(lines))
+	for _, l := range lines {
+		if s, _ := entryDomain(l); s != "" {
+			d[s] = struct{}{}
+		}
+	}
+	return d, nil
+}
+
+// checkChange fails if objs' adds would add and remove more than MaxChg
+// percent of the entries in the files they replace, so a hijacked or broken
+// feed can't swap out the blacklist without Force; the previous files are
+// kept
+func (c *Config) checkChange(objs []*object, adds []list) error {
+	if c.MaxChg <= 0 || c.Force || c.ioWriter != nil || c.audit != nil {
+		return nil
+	}
+
+	a := &ErrAnomaly{Limit: c.MaxChg}
+	for i, o := range objs {
+		if o.err != nil {
+			continue
+		}
+
+		prev, err := o.previous()
+		if err != nil {
+			return err
+		}
+		a.Previous += len(prev)
+
+		adds[i].RLock()
+		for k := range adds[i].entry {
+			if _, ok := prev[k]; ok {
+				delete(prev, k)
+				continue
+			}
+			a.Added++
+		}
+		adds[i].RUnlock()
+		a.Removed += len(prev)
+	}
+
+	// a first run has nothing to compare with
+	if a.Previous == 0 || a.percent() <= c.MaxChg {
+		return nil
+	}
+	return a
+}
@@ -0,0 +1,65 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckChange(t *testing.T) {
+	Convey("Testing the per-run change limit", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			src  = dir + "/feed.txt"
+			out  = dir + "/domains.feed.blacklist.conf"
+			cfg  = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tfile " + src + "\n\t\t\tprefix \"\"\n\t\t}\n\t}\n}"
+			prev = "address=/.a.example.com/0.0.0.0\naddress=/.b.example.com/0.0.0.0\naddress=/.c.example.com/0.0.0.0\naddress=/.d.example.com/0.0.0.0\n"
+		)
+
+		run := func(feed string, opts ...Option) error {
+			So(ioutil.WriteFile(src, []byte(feed), 0644), ShouldBeNil)
+			c := NewConfig(append([]Option{Dir(dir), Ext("blacklist.conf"), FileNameFmt("%v/%v.%v.%v"), Nodes([]string{domains}), Prefix("address=")}, opts...)...)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			return c.ProcessContent(ct)
+		}
+
+		So(ioutil.WriteFile(out, []byte(prev), 0644), ShouldBeNil)
+
+		Convey("changes within the limit are written", func() {
+			So(run("a.example.com\nb.example.com\nc.example.com\nd.example.com\ne.example.com\n", MaxChange(25)), ShouldBeNil)
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, prev+"address=/.e.example.com/0.0.0.0\n")
+		})
+
+		Convey("an anomalous run keeps the previous files", func() {
+			err := run("evil.example.net\n", MaxChange(25))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "anomalous run: 1 entries added and 4 removed, 125.0% of the previous 4, exceeds the 25% limit, keeping the previous files, rerun with -force to allow it")
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, prev)
+
+			Convey("unless it is forced", func() {
+				So(run("evil.example.net\n", MaxChange(25), Force(true)), ShouldBeNil)
+				b, err := ioutil.ReadFile(out)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, "address=/.evil.example.net/0.0.0.0\n")
+			})
+		})
+
+		Convey("a first run has nothing to compare with", func() {
+			So(os.Remove(out), ShouldBeNil)
+			So(run("evil.example.net\n", MaxChange(25)), ShouldBeNil)
+		})
+	})
+}
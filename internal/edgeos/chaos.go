@@ -0,0 +1,64 @@
+package edgeos
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrInjected is the error a source fails with when Chaos names it
+var ErrInjected = errors.New("failure injected by -fail-source")
+
+// corruptLine is appended to generated files when Chaos corrupts output,
+// dnsmasq refuses to load a file with it
+const corruptLine = "corrupted by -corrupt-output\n"
+
+// Chaos injects failures into a run, so safe-fail, rollback and alerting
+// can be tested before they are relied on
+type Chaos struct {
+	Fail    map[string]bool
+	Slow    map[string]time.Duration
+	Corrupt bool
+}
+
+// Inject sets the failures injected into runs, nil injects none
+func Inject(ch *Chaos) Option {
+	return func(c *Config) Option {
+		previous := c.Chaos
+		c.Chaos = ch
+		return Inject(previous)
+	}
+}
+
+// injected delays o's fetch and fails it as Chaos asks, it returns true if
+// o failed
+func (o *object) injected() bool {
+	ch := o.Chaos
+	if ch == nil {
+		return false
+	}
+
+	if d := ch.Slow[o.name]; d > 0 {
+		select {
+		case <-time.After(d):
+		case <-o.context().Done():
+			o.r, o.err = strings.NewReader(""), o.expired()
+			return true
+		}
+	}
+
+	if ch.Fail[o.name] {
+		o.r, o.err = strings.NewReader(""), ErrInjected
+		return true
+	}
+	return false
+}
+
+// corrupt returns r with an invalid line appended if Chaos corrupts output
+func (p *Parms) corrupt(r io.Reader) io.Reader {
+	if p.Chaos == nil || !p.Chaos.Corrupt {
+		return r
+	}
+	return io.MultiReader(r, strings.NewReader(corruptLine))
+}
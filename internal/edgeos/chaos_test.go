@@ -0,0 +1,79 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChaos(t *testing.T) {
+	Convey("Testing injected failures", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ads.example.com\n"))
+		}))
+		defer srv.Close()
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Method(http.MethodGet),
+			Nodes([]string{domains}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource chaos {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		process := func() error {
+			ct, err := c.NewContent(URLdObj)
+			So(err, ShouldBeNil)
+			return c.ProcessContent(ct)
+		}
+		out := dir + "/domains.chaos.blacklist.conf"
+
+		Convey("fails a named source", func() {
+			c.SetOpt(Inject(&Chaos{Fail: map[string]bool{"chaos": true}}))
+			err := process()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, ErrInjected.Error())
+			So(c.RunState().Counts().Errors, ShouldResemble, map[string]string{"chaos": "chaos: " + ErrInjected.Error()})
+		})
+
+		Convey("slows a named source until the deadline", func() {
+			c.SetOpt(Inject(&Chaos{Slow: map[string]time.Duration{"chaos": time.Hour}}), Deadline(50*time.Millisecond))
+			start := time.Now()
+			err := process()
+			So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+			So(err, ShouldNotBeNil)
+			So(err.(Errors), ShouldContain, ErrDeadline)
+		})
+
+		Convey("corrupts the generated files", func() {
+			c.SetOpt(Inject(&Chaos{Corrupt: true}))
+			So(process(), ShouldBeNil)
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldStartWith, "address=/.ads.example.com/0.0.0.0\n")
+			So(strings.HasSuffix(string(b), corruptLine), ShouldBeTrue)
+		})
+
+		Convey("injects nothing when unset", func() {
+			c.SetOpt(Inject(nil))
+			So(process(), ShouldBeNil)
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldNotContainSubstring, corruptLine)
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// clientGroup labels the configuration node for DHCP client groups
+	clientGroup = "client-group"
+	// clientGroupsFile is the dnsmasq file tagging the client groups
+	clientGroupsFile = "client-groups.conf"
+)
+
+// groupNameRx matches the group names dnsmasq can use as a tag
+var groupNameRx = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ClientGroup is a named set of DHCP clients, e.g. the kids' tablets, that
+// dnsmasq tags by MAC address and hands its own resolver: a dnsmasq
+// instance serving the generated files and, whatever its schedules, the
+// group's blocking profile
+type ClientGroup struct {
+	Name     string   `json:"name"`
+	Clients  []string `json:"clients"`
+	Resolver string   `json:"resolver"`
+	Instance string   `json:"instance,omitempty"`
+	Profile  string   `json:"profile,omitempty"`
+}
+
+// ClientGroups returns the configured DHCP client groups
+func (c *Config) ClientGroups() []*ClientGroup {
+	return c.groups
+}
+
+// addClient adds a client's MAC address to the group
+func (g *ClientGroup) addClient(mac string) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("client-group %q has invalid client %q, dnsmasq tags DHCP clients by MAC address", g.Name, mac)
+	}
+	g.Clients = append(g.Clients, hw.String())
+	return nil
+}
+
+// check returns an error if the group is incomplete
+func (g *ClientGroup) check() error {
+	switch {
+	case !groupNameRx.MatchString(g.Name):
+		return fmt.Errorf("client-group %q has an invalid name, use letters, digits, _ and -", g.Name)
+	case len(g.Clients) == 0:
+		return fmt.Errorf("client-group %q missing client", g.Name)
+	case net.ParseIP(g.Resolver) == nil:
+		return fmt.Errorf("client-group %q needs a resolver IP address", g.Name)
+	case g.Profile != "" && g.Instance == "":
+		return fmt.Errorf("client-group %q needs an instance to serve profile %q", g.Name, g.Profile)
+	}
+	return nil
+}
+
+// checkGroups returns an error if a group names an unknown instance or
+// profile, they may be configured after the group
+func (c *Config) checkGroups() error {
+	for _, g := range c.groups {
+		if g.Instance != "" && c.instanceNamed(g.Instance) == nil {
+			return fmt.Errorf("client-group %q has unknown instance %q", g.Name, g.Instance)
+		}
+		if g.Profile != "" && c.profileNamed(g.Profile) == nil {
+			return fmt.Errorf("client-group %q has unknown profile %q", g.Name, g.Profile)
+		}
+	}
+	return nil
+}
+
+// instanceNamed returns the named instance, or nil
+func (c *Config) instanceNamed(name string) *Instance {
+	for _, inst := range c.instances {
+		if inst.Name == name {
+			return inst
+		}
+	}
+	return nil
+}
+
+// profileNamed returns the named profile, or nil
+func (c *Config) profileNamed(name string) *Profile {
+	for _, p := range c.profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// dhcp returns the dnsmasq lines that tag the groups' clients and hand them
+// their group's resolver
+func (c *Config) dhcp() []byte {
+	var b bytes.Buffer
+	for _, g := range c.groups {
+		fmt.Fprintf(&b, "# %v\n", g.Name)
+		for _, mac := range g.Clients {
+			fmt.Fprintf(&b, "dhcp-host=%v,set:%v\n", mac, g.Name)
+		}
+		fmt.Fprintf(&b, "dhcp-option=tag:%v,option:dns-server,%v\n", g.Name, g.Resolver)
+	}
+	return b.Bytes()
+}
+
+// WriteClientGroups writes the client groups' DHCP tags to the dnsmasq
+// directory and installs each group's profile in its instance's directory,
+// removing ones no group needs any more; changed is true if dnsmasq or an
+// instance needs reloading
+func (c *Config) WriteClientGroups() (changed bool, err error) {
+	file := filepath.Join(c.Dir, clientGroupsFile)
+	if len(c.groups) > 0 {
+		changed, err = replaceFile(file, c.dhcp())
+	} else if err = os.Remove(file); err == nil {
+		changed = true
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return changed, err
+	}
+
+	keep := make(map[string]bool)
+	for _, g := range c.groups {
+		if g.Profile == "" {
+			continue
+		}
+
+		inst, p := c.instanceNamed(g.Instance), c.profileNamed(g.Profile)
+		if inst == nil || p == nil {
+			return changed, c.checkGroups()
+		}
+
+		b, err := c.content(p)
+		if err != nil {
+			return changed, fmt.Errorf("client-group %v: %v", g.Name, err)
+		}
+
+		f := p.file(inst.Dir)
+		keep[f] = true
+		ch, err := replaceFile(f, b)
+		if changed = changed || ch; err != nil {
+			return changed, err
+		}
+	}
+
+	for _, inst := range c.instances {
+		installed, err := filepath.Glob(filepath.Join(inst.Dir, profile+".*.conf"))
+		if err != nil {
+			return changed, err
+		}
+
+		var stale []string
+		for _, f := range installed {
+			if !keep[f] {
+				stale = append(stale, f)
+			}
+		}
+
+		if stale != nil {
+			changed = true
+			if err = purgeFiles(stale); err != nil {
+				return changed, err
+			}
+		}
+	}
+	return changed, nil
+}
+
+// replaceFile atomically replaces file with b unless it already holds b,
+// changed is true if it was replaced
+func replaceFile(file string, b []byte) (changed bool, err error) {
+	if cur, err := ioutil.ReadFile(file); err == nil && bytes.Equal(cur, b) {
+		return false, nil
+	}
+
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, file)
+}
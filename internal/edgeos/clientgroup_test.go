@@ -0,0 +1,150 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientGroups(t *testing.T) {
+	Convey("Testing DHCP client groups", t, func() {
+		var (
+			dir, _    = ioutil.TempDir("/tmp", "testBlacklist")
+			strict, _ = ioutil.TempDir("/tmp", "testBlacklist")
+			social, _ = ioutil.TempDir("/tmp", "testBlacklist")
+			cfg       = `blacklist {
+	client-group kids {
+		client AA:BB:CC:DD:EE:01
+		client aa:bb:cc:dd:ee:02
+		instance strict
+		profile social
+		resolver 192.168.1.2
+	}
+	client-group guests {
+		client aa:bb:cc:dd:ee:03
+		resolver 192.168.2.1
+	}
+	instance strict {
+		directory ` + strict + `
+	}
+	profile social {
+		directory ` + social + `
+		schedule "21:00-07:00"
+	}
+	domains {
+		source zeus {
+			url http://zeus.com
+		}
+	}
+}`
+			c = NewConfig(
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				WCard(Wildcard{Node: "*s", Name: "*"}),
+			)
+		)
+		defer os.RemoveAll(dir)
+		defer os.RemoveAll(strict)
+		defer os.RemoveAll(social)
+
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+		So(c.ClientGroups(), ShouldResemble, []*ClientGroup{
+			{Name: "kids", Clients: []string{"aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"}, Instance: "strict", Profile: "social", Resolver: "192.168.1.2"},
+			{Name: "guests", Clients: []string{"aa:bb:cc:dd:ee:03"}, Resolver: "192.168.2.1"},
+		})
+
+		So(ioutil.WriteFile(social+"/domains.tiktok.blacklist.conf", []byte("address=/.tiktok.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(strict+"/profile.old.conf", []byte("stale"), 0644), ShouldBeNil)
+
+		changed, err := c.WriteClientGroups()
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+
+		b, err := ioutil.ReadFile(dir + "/" + clientGroupsFile)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "# kids\n"+
+			"dhcp-host=aa:bb:cc:dd:ee:01,set:kids\n"+
+			"dhcp-host=aa:bb:cc:dd:ee:02,set:kids\n"+
+			"dhcp-option=tag:kids,option:dns-server,192.168.1.2\n"+
+			"# guests\n"+
+			"dhcp-host=aa:bb:cc:dd:ee:03,set:guests\n"+
+			"dhcp-option=tag:guests,option:dns-server,192.168.2.1\n")
+
+		b, err = ioutil.ReadFile(strict + "/profile.social.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/.tiktok.com/0.0.0.0\n")
+		_, err = os.Stat(strict + "/profile.old.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		changed, err = c.WriteClientGroups()
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeFalse)
+
+		Convey("client groups survive a JSON snapshot", func() {
+			b, err := json.Marshal(c)
+			So(err, ShouldBeNil)
+
+			n := NewConfig()
+			So(json.Unmarshal(b, n), ShouldBeNil)
+			So(n.ClientGroups(), ShouldResemble, c.ClientGroups())
+		})
+
+		Convey("removing the groups removes their files", func() {
+			c.groups = nil
+			changed, err := c.WriteClientGroups()
+			So(err, ShouldBeNil)
+			So(changed, ShouldBeTrue)
+
+			for _, f := range []string{dir + "/" + clientGroupsFile, strict + "/profile.social.conf"} {
+				_, err = os.Stat(f)
+				So(os.IsNotExist(err), ShouldBeTrue)
+			}
+
+			changed, err = c.WriteClientGroups()
+			So(err, ShouldBeNil)
+			So(changed, ShouldBeFalse)
+		})
+
+		Convey("invalid client groups are refused", func() {
+			tests := []struct {
+				cfg string
+				err string
+			}{
+				{
+					cfg: "blacklist {\n\tclient-group kids {\n\t\tclient 192.168.1.20\n\t}\n}",
+					err: `config.boot:3: client-group "kids" has invalid client "192.168.1.20", dnsmasq tags DHCP clients by MAC address`,
+				},
+				{
+					cfg: "blacklist {\n\tclient-group kids.tablets {\n\t\tclient aa:bb:cc:dd:ee:01\n\t\tresolver 192.168.1.2\n\t}\n}",
+					err: `config.boot:5: client-group "kids.tablets" has an invalid name, use letters, digits, _ and -`,
+				},
+				{
+					cfg: "blacklist {\n\tclient-group kids {\n\t\tresolver 192.168.1.2\n\t}\n}",
+					err: `config.boot:4: client-group "kids" missing client`,
+				},
+				{
+					cfg: "blacklist {\n\tclient-group kids {\n\t\tclient aa:bb:cc:dd:ee:01\n\t}\n}",
+					err: `config.boot:4: client-group "kids" needs a resolver IP address`,
+				},
+				{
+					cfg: "blacklist {\n\tclient-group kids {\n\t\tclient aa:bb:cc:dd:ee:01\n\t\tresolver 192.168.1.2\n\t\tprofile social\n\t}\n}",
+					err: `config.boot:6: client-group "kids" needs an instance to serve profile "social"`,
+				},
+				{
+					cfg: "blacklist {\n\tclient-group kids {\n\t\tclient aa:bb:cc:dd:ee:01\n\t\tresolver 192.168.1.2\n\t\tinstance strict\n\t}\n}",
+					err: `client-group "kids" has unknown instance "strict"`,
+				},
+			}
+
+			for _, tt := range tests {
+				err := NewConfig().ReadCfg(&CFGstatic{Cfg: tt.cfg})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, tt.err)
+			}
+		})
+	})
+}
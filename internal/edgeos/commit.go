@@ -0,0 +1,68 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// Digest returns a SHA256 digest of the blacklist configuration: its nodes,
+// hooks, instances, client groups, profiles, targets and transform, but not
+// the command line parms, so it only changes when the blacklist subtree does
+func (c *Config) Digest() (string, error) {
+	b, err := json.Marshal(struct {
+		configJSON
+		Transform string `json:"transform,omitempty"`
+	}{
+		configJSON: configJSON{
+			Nodes:     c.tree,
+			Hooks:     c.hooks,
+			Instances: c.instances,
+			Groups:    c.groups,
+			Profiles:  c.profiles,
+			Targets:   c.targets,
+		},
+		Transform: c.Xform,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CfgChanged reports whether the configuration's Digest differs from the one
+// SaveDigest saved in file, a missing file counts as a change
+func (c *Config) CfgChanged(file string) (bool, error) {
+	d, err := c.Digest()
+	if err != nil {
+		return false, err
+	}
+
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return string(bytes.TrimSpace(b)) != d, nil
+}
+
+// SaveDigest saves the configuration's Digest to file, for CfgChanged
+func (c *Config) SaveDigest(file string) error {
+	d, err := c.Digest()
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, []byte(d+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
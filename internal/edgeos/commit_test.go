@@ -0,0 +1,61 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDigest(t *testing.T) {
+	Convey("Testing configuration digests", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\turl %v\n\t\t}\n\t}\n}"
+		read := func(url string, opts ...Option) *Config {
+			c := NewConfig(append(opts, Nodes([]string{domains}))...)
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, url)}), ShouldBeNil)
+			return c
+		}
+
+		Convey("only the blacklist configuration changes the digest", func() {
+			d, err := read("https://example.com/feed.txt").Digest()
+			So(err, ShouldBeNil)
+			So(d, ShouldHaveLength, 64)
+
+			same, err := read("https://example.com/feed.txt", Dir(dir), Cores(1)).Digest()
+			So(err, ShouldBeNil)
+			So(same, ShouldEqual, d)
+
+			other, err := read("https://example.com/other.txt").Digest()
+			So(err, ShouldBeNil)
+			So(other, ShouldNotEqual, d)
+		})
+
+		Convey("CfgChanged compares with the saved digest", func() {
+			file := filepath.Join(dir, "blacklist.digest")
+			c := read("https://example.com/feed.txt")
+
+			changed, err := c.CfgChanged(file)
+			So(err, ShouldBeNil)
+			So(changed, ShouldBeTrue)
+
+			So(c.SaveDigest(file), ShouldBeNil)
+			changed, err = read("https://example.com/feed.txt").CfgChanged(file)
+			So(err, ShouldBeNil)
+			So(changed, ShouldBeFalse)
+
+			changed, err = read("https://example.com/other.txt").CfgChanged(file)
+			So(err, ShouldBeNil)
+			So(changed, ShouldBeTrue)
+
+			_, err = c.CfgChanged(dir)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
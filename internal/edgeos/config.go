@@ -4,14 +4,15 @@ package edgeos
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/britannic/blacklist/internal/regx"
 )
@@ -35,9 +36,17 @@ type CFile struct {
 type Config struct {
 	*Parms
 	tree
+	instances []*Instance
+	groups    []*ClientGroup
+	hooks     []*Hook
+	profiles  []*Profile
+	targets   []*Target
+	serial    int64
+	mu        sync.RWMutex
 }
 
 const (
+	allowNode = "whitelist"
 	agent     = `Mozilla/5.0 (Macintosh; Intel Mac OS X 10_11_6) AppleWebKit/601.7.7 (KHTML, like Gecko) Version/9.1.2 Safari/601.7.7`
 	all       = "all"
 	blackhole = "dns-redirect-ip"
@@ -45,6 +54,7 @@ const (
 	disabled  = "disabled"
 	domains   = "domains"
 	files     = "file"
+	gzExt     = ".gz"
 	hosts     = "hosts"
 	notknown  = "unknown"
 	preNoun   = "pre-configured"
@@ -53,6 +63,8 @@ const (
 	urls      = "url"
 	zones     = "zones"
 
+	// DoHDomns designates string label for DNS-over-HTTPS provider domains
+	DoHDomns = "doh-provider"
 	// ExcDomns labels domain exclusions
 	ExcDomns = "domn-excludes"
 	// ExcHosts labels host exclusions
@@ -71,22 +83,17 @@ const (
 
 func (c *Config) addExc(node string) *Objects {
 	var (
-		ltype string
+		ltype = ExcRoots
 		o     = &Objects{Parms: c.Parms}
 	)
 
-	switch node {
-	case domains:
-		ltype = ExcDomns
-	case hosts:
-		ltype = ExcHosts
-	case rootNode:
-		ltype = ExcRoots
+	if k := kindNamed(node); k != nil {
+		ltype = k.Exc
 	}
 
 	o.x = append(o.x, &object{
 		desc:  ltype + " exclusions",
-		exc:   c.tree[node].exc,
+		exc:   c.effectiveExc(node),
 		ip:    c.tree.getIP(node),
 		ltype: ltype,
 		name:  ltype,
@@ -98,19 +105,14 @@ func (c *Config) addExc(node string) *Objects {
 
 func (c *Config) addInc(node string) *object {
 	var (
-		inc   = c.tree[node].inc
+		inc   = c.tree[node].live()
 		ltype string
 		n     ntype
 	)
 
 	if len(inc) > 0 {
-		switch node {
-		case domains:
-			ltype = getType(preDomn).(string)
-			n = getType(ltype).(ntype)
-		case hosts:
-			ltype = getType(preHost).(string)
-			n = getType(ltype).(ntype)
+		if k := kindNamed(node); k != nil {
+			ltype, n = k.Inc, k.inc
 		}
 
 		return &object{
@@ -126,32 +128,42 @@ func (c *Config) addInc(node string) *object {
 	return nil
 }
 
-// NewContent returns an interface of the requested IFace type
+// NewContent returns an interface of the requested IFace type, built in or
+// added by RegisterContent
 func (c *Config) NewContent(iface IFace) (Contenter, error) {
+	if ct := iface.registered(); ct != nil {
+		return ct.fn(c)
+	}
+
 	var (
 		err   error
 		ltype = iface.String()
 		o     *Objects
 	)
 
+	// the domain and host interfaces cover every registered node kind that
+	// does, or doesn't, block subdomains
 	switch ltype {
-	case ExcDomns:
-		o = c.addExc(domains)
-	case ExcHosts:
-		o = c.addExc(hosts)
+	case ExcDomns, ExcHosts:
+		o = c.byKind(iface == ExDmObj, c.addExc)
 	case ExcRoots:
 		o = c.addExc(rootNode)
+	case DoHDomns:
+		return &DoHObjects{Objects: &Objects{Parms: c.Parms, x: []*object{c.addDoH()}}}, nil
+	case PreDomns, PreHosts:
+		o = c.GetAll(incTypes(iface == PreDObj)...)
+	case allowNode:
+		return &AllowObjects{Objects: c.addAllow()}, nil
 	case urls:
+		o = c.byKind(iface == URLdObj, func(node string) *Objects { return c.Get(node).Filter(urls) })
 		switch iface {
 		case URLdObj:
-			o = c.Get(domains).Filter(urls)
 			return &URLDomnObjects{Objects: o}, nil
 		case URLhObj:
-			o = c.Get(hosts).Filter(urls)
 			return &URLHostObjects{Objects: o}, nil
 		}
 	case "unknown":
-		err = errors.New("Invalid interface requested")
+		err = ErrInvalidIFace
 	default:
 		o = c.GetAll(ltype)
 	}
@@ -210,27 +222,28 @@ func (c *Config) Get(node string) *Objects {
 // GetAll returns an array of Objects
 func (c *Config) GetAll(ltypes ...string) *Objects {
 	var (
-		newDomns = true
-		newHosts = true
-		o        = &Objects{Parms: c.Parms}
+		added = make(map[string]bool)
+		o     = &Objects{Parms: c.Parms}
 	)
 
 	for _, node := range o.Nodes {
+		if c.tree[node] == nil {
+			continue
+		}
+
 		switch ltypes {
 		case nil:
 			o.addObj(c, node)
 		default:
 			for _, ltype := range ltypes {
-				switch ltype {
-				case PreDomns:
-					if newDomns && node == domains {
-						o.x = append(o.x, c.addInc(node))
-						newDomns = false
-					}
-				case PreHosts:
-					if newHosts && node == hosts {
-						o.x = append(o.x, c.addInc(node))
-						newHosts = false
+				switch k := kindLabelled(ltype); {
+				case k != nil && ltype == k.Inc:
+					if !added[ltype] && node == k.Name {
+						// nodes without includes have nothing to add
+						if inc := c.addInc(node); inc != nil {
+							o.x = append(o.x, inc)
+						}
+						added[ltype] = true
 					}
 				default:
 					obj := c.validate(node).x
@@ -253,10 +266,7 @@ func (c *Config) InSession() bool {
 
 // load reads the config using the EdgeOS/VyOS cli-shell-api
 func (c *Config) load(act, lvl string) ([]byte, error) {
-	cmd := exec.Command(c.Bash)
-	cmd.Stdin = strings.NewReader(fmt.Sprintf("%v %v %v", c.API, apiCMD(act, c.InSession()), lvl))
-
-	return cmd.Output()
+	return c.runner().Output(fmt.Sprintf("%v %v %v", c.API, apiCMD(act, c.InSession()), lvl))
 }
 
 // Nodes returns an array of configured nodes
@@ -269,30 +279,69 @@ func (c *Config) Nodes() (nodes []string) {
 	return nodes
 }
 
-// ReadCfg extracts nodes from a EdgeOS/VyOS configuration structure
+// ReadCfg extracts nodes from a EdgeOS/VyOS configuration structure, errors
+// are reported as *ErrParse with the offending line number. In Strict mode
+// unknown leaves and unparsable lines are errors instead of being ignored.
+// A *CFGjson is read as a JSON snapshot instead. The configuration is
+// parsed aside, with a copy of the Parms for the settings it carries such as
+// the transform, and swapped in whole, so a View taken before is unchanged
 func (c *Config) ReadCfg(r ConfLoader) error {
+	p := *c.Parms
+	n := &Config{Parms: &p, tree: make(tree)}
+	if err := n.readCfg(r); err != nil {
+		return err
+	}
+
+	c.swap(n)
+	return nil
+}
+
+// readCfg parses r into c
+func (c *Config) readCfg(r ConfLoader) error {
+	if s, ok := r.(*CFGjson); ok {
+		return c.readSnapshot(s)
+	}
+
 	var (
 		tnode string
 		b     = bufio.NewScanner(r.read())
+		grp   *ClientGroup
+		inst  *Instance
 		leaf  string
+		prof  *Profile
+		tgt   *Target
+		n     int
 		nodes []string
 		rx    = regx.Obj
 		o     *object
+		perr  = func(format string, args ...interface{}) error {
+			return &ErrParse{File: c.cfgName(), Line: n, Msg: fmt.Sprintf(format, args...)}
+		}
 	)
 
 LINE:
 	for b.Scan() {
+		n++
 		line := bytes.TrimSpace(b.Bytes())
 
 		switch {
 		case rx.MLTI.Match(line):
+			if c.tree[tnode] == nil {
+				return perr("%q outside of a blacklist node", line)
+			}
+
 			incExc := regx.Get([]byte("mlti"), line)
 			switch string(incExc[1]) {
 			case "exclude":
-				c.tree[tnode].exc = append(c.tree[tnode].exc, string(incExc[2]))
+				c.tree[tnode].exc = append(c.tree[tnode].exc, normalizeDomain(string(incExc[2])))
 
 			case "include":
-				c.tree[tnode].inc = append(c.tree[tnode].inc, string(incExc[2]))
+				d, exp, err := parseInclude(string(incExc[2]))
+				if err != nil {
+					return perr("%v", err)
+				}
+				c.tree[tnode].inc = append(c.tree[tnode].inc, d)
+				c.tree[tnode].expire(d, exp)
 			}
 
 		case rx.NODE.Match(line):
@@ -306,21 +355,155 @@ LINE:
 			leaf = string(srcName[2])
 			nodes = append(nodes, string(srcName[1]))
 
-			if bytes.Equal(srcName[1], []byte(src)) {
+			switch string(srcName[1]) {
+			case src:
 				o = newObject()
 				o.name = leaf
 				o.nType = getType(tnode).(ntype)
+
+			case clientGroup:
+				grp = &ClientGroup{Name: leaf}
+				c.groups = append(c.groups, grp)
+
+			case instance:
+				inst = &Instance{Name: leaf}
+				c.instances = append(c.instances, inst)
+
+			case profile:
+				prof = &Profile{Name: leaf}
+				c.profiles = append(c.profiles, prof)
+
+			case target:
+				tgt = &Target{Name: leaf}
+				c.targets = append(c.targets, tgt)
 			}
 
 		case rx.DSBL.Match(line):
+			if c.tree[tnode] == nil {
+				return perr("%q outside of a blacklist node", line)
+			}
 			c.tree[tnode].disabled = strToBool(string(regx.Get([]byte("dsbl"), line)[1]))
 
-		case rx.IPBH.Match(line) && nodes[len(nodes)-1] != src:
+		case rx.IPBH.Match(line) && (len(nodes) == 0 || nodes[len(nodes)-1] != src):
+			if c.tree[tnode] == nil {
+				return perr("%q outside of a blacklist node", line)
+			}
 			c.tree[tnode].ip = string(regx.Get([]byte("ipbh"), line)[1])
 
 		case rx.NAME.Match(line):
 			name := regx.Get([]byte("name"), line)
+			if grp != nil {
+				switch string(name[1]) {
+				case "client":
+					if err := grp.addClient(string(name[2])); err != nil {
+						return perr("%v", err)
+					}
+				case "instance":
+					grp.Instance = string(name[2])
+				case profile:
+					grp.Profile = string(name[2])
+				case "resolver":
+					grp.Resolver = string(name[2])
+				default:
+					if c.Strict {
+						return perr("client-group %q has unknown leaf %q", grp.Name, name[1])
+					}
+				}
+				continue LINE
+			}
+
+			if inst != nil {
+				switch string(name[1]) {
+				case "directory":
+					inst.Dir = string(name[2])
+				case "reload":
+					inst.Reload = string(name[2])
+				default:
+					if c.Strict {
+						return perr("instance %q has unknown leaf %q", inst.Name, name[1])
+					}
+				}
+				continue LINE
+			}
+
+			if prof != nil {
+				switch string(name[1]) {
+				case "directory":
+					prof.Dir = string(name[2])
+				case "schedule":
+					sc, err := ParseSchedule(string(name[2]))
+					if err != nil {
+						return perr("profile %q has %v", prof.Name, err)
+					}
+					prof.Schedules = append(prof.Schedules, sc)
+				default:
+					if c.Strict {
+						return perr("profile %q has unknown leaf %q", prof.Name, name[1])
+					}
+				}
+				continue LINE
+			}
+
+			if tgt != nil {
+				switch string(name[1]) {
+				case "address":
+					tgt.Address = string(name[2])
+				case "file":
+					tgt.File = string(name[2])
+				case "format":
+					if tgt.Format = string(name[2]); !validFormat(tgt.Format) {
+						return perr("target %q has unknown format %q", tgt.Name, tgt.Format)
+					}
+				case "post-command":
+					tgt.Post = string(name[2])
+				default:
+					if c.Strict {
+						return perr("target %q has unknown leaf %q", tgt.Name, name[1])
+					}
+				}
+				continue LINE
+			}
+
+			if o == nil {
+				switch string(name[1]) {
+				case blockingMode:
+					if c.tree[tnode] == nil {
+						return perr("%q outside of a blacklist node", line)
+					}
+					switch m := string(name[2]); m {
+					case blockNXDomain, blockRedirect:
+						c.tree[tnode].blocking = m
+					default:
+						return perr("node %q has unknown %v %q", tnode, blockingMode, m)
+					}
+					continue LINE
+
+				case PreHook, PostHook:
+					h, err := ParseHook(string(name[1]), string(name[2]))
+					if err != nil {
+						return perr("%v", err)
+					}
+					c.hooks = append(c.hooks, h)
+					continue LINE
+
+				case transform:
+					if _, err := transformArgs(string(name[2])); err != nil {
+						return perr("%v", err)
+					}
+					c.Xform = string(name[2])
+					continue LINE
+				}
+
+				if c.Strict && string(name[1]) != "description" {
+					return perr("%q outside of a source", name[1])
+				}
+				continue LINE
+			}
+
 			switch string(name[1]) {
+			case catalogLeaf:
+				o.catalog = string(name[2])
+
 			case "description":
 				o.desc = string(name[2])
 
@@ -329,34 +512,205 @@ LINE:
 
 			case files:
 				o.file = string(name[2])
-				o.ltype = string(name[1])
-				c.tree[tnode].Objects.x = append(c.tree[tnode].Objects.x, o)
+
+			case identity:
+				o.identity = string(name[2])
+
+			case "max-size":
+				size, err := ParseSize(string(name[2]))
+				if err != nil {
+					return perr("source %q has %v", o.name, err)
+				}
+				o.maxsize = size
+
+			case "parked":
+				switch p := string(name[2]); p {
+				case parkedConvert, parkedSkip:
+					o.parked = p
+				default:
+					return perr("source %q has unknown parked policy %q", o.name, p)
+				}
+
+			case parseURLs:
+				switch p := string(name[2]); p {
+				case urlHost, urlRegistrable:
+					o.parseURL = p
+				default:
+					return perr("source %q has unknown %v %q", o.name, parseURLs, p)
+				}
+
+			case "pin":
+				if _, err := parsePin(string(name[2])); err != nil {
+					return perr("source %q has %v", o.name, err)
+				}
+				o.pins = append(o.pins, string(name[2]))
 
 			case "prefix":
 				o.prefix = string(name[2])
 
+			case "processor":
+				o.processor = string(name[2])
+				if strings.HasPrefix(o.processor, "[") {
+					if _, err := processorFor(nil, o.processor); err != nil {
+						return perr("source %q has %v", o.name, err)
+					}
+				}
+
+			case "rate-limit":
+				rate, err := ParseRate(string(name[2]))
+				if err != nil {
+					return perr("source %q has %v", o.name, err)
+				}
+				o.rate = rate
+
+			case "redirect-policy":
+				switch p := string(name[2]); p {
+				case redirectFollow, redirectNone, redirectSameHost:
+					o.redirect = p
+				default:
+					return perr("source %q has unknown redirect-policy %q", o.name, p)
+				}
+
+			case "sinkhole":
+				o.sinkholes = append(o.sinkholes, string(name[2]))
+
+			case stripPrefix, stripSuffix, replace:
+				r, err := newRewrite(string(name[1]), string(name[2]))
+				if err != nil {
+					return perr("source %q has %v", o.name, err)
+				}
+				o.rewrites = append(o.rewrites, r)
+
 			case urls:
-				o.ltype = string(name[1])
-				o.url = string(name[2])
-				c.tree[tnode].Objects.x = append(c.tree[tnode].Objects.x, o)
+				u := string(name[2])
+				if !strings.HasPrefix(u, fileScheme) {
+					o.url = u
+					break
+				}
+				p, err := fileURLPath(u)
+				if err != nil {
+					return perr("source %q has %v", o.name, err)
+				}
+				o.file = p
+
+			case "via":
+				if v := string(name[2]); v != viaTor {
+					return perr("source %q can't be fetched via %q", o.name, v)
+				}
+				o.via = viaTor
+
+			case "weight":
+				w, err := strconv.ParseFloat(string(name[2]), 64)
+				if err != nil || w <= 0 {
+					return perr("source %q has invalid weight %q", o.name, name[2])
+				}
+				o.weight = w
+
+			default:
+				if c.Strict {
+					return perr("source %q has unknown leaf %q", o.name, name[1])
+				}
 			}
 
+		case o != nil && string(line) == catalogLeaf:
+			o.catalog = o.name
+
 		case rx.DESC.Match(line) || rx.CMNT.Match(line) || rx.MISC.Match(line):
 			continue LINE
 
 		case rx.RBRC.Match(line):
+			if len(nodes) > 0 && nodes[len(nodes)-1] == clientGroup && grp != nil {
+				if err := grp.check(); err != nil {
+					return perr("%v", err)
+				}
+				grp = nil
+			}
+
+			if len(nodes) > 0 && nodes[len(nodes)-1] == instance && inst != nil {
+				if inst.Dir == "" {
+					return perr("instance %q missing directory", inst.Name)
+				}
+				inst = nil
+			}
+
+			if len(nodes) > 0 && nodes[len(nodes)-1] == profile && prof != nil {
+				switch {
+				case prof.Dir == "":
+					return perr("profile %q missing directory", prof.Name)
+				case prof.Schedules == nil:
+					return perr("profile %q missing schedule", prof.Name)
+				}
+				prof = nil
+			}
+
+			if len(nodes) > 0 && nodes[len(nodes)-1] == target && tgt != nil {
+				switch {
+				case tgt.Format == "":
+					return perr("target %q missing format", tgt.Name)
+				case tgt.File == "":
+					return perr("target %q missing file", tgt.Name)
+				}
+				tgt = nil
+			}
+
+			if len(nodes) > 0 && nodes[len(nodes)-1] == src && o != nil {
+				// source leaves may appear in any order, so the object
+				// is only added once its block is complete
+				if o.catalog != "" {
+					s, ok := c.lookupCatalog(o.catalog)
+					if !ok {
+						return perr("source %q has unknown catalog source %q", o.name, o.catalog)
+					}
+					if s.Deprecated != "" {
+						c.warn(fmt.Sprintf("source %q uses deprecated catalog source %q: %v", o.name, s.Name, s.Deprecated))
+					}
+					o.fromCatalog(s)
+				}
+
+				switch {
+				case o.url != "":
+					o.ltype = urls
+				case o.file != "":
+					o.ltype = files
+				default:
+					return perr("source %q missing url/file", o.name)
+				}
+
+				if c.tree[tnode] == nil {
+					return perr("source %q outside of a blacklist node", o.name)
+				}
+				c.tree[tnode].Objects.x = append(c.tree[tnode].Objects.x, o)
+				o = nil
+			}
+
 			if len(nodes) > 1 {
 				nodes = nodes[:len(nodes)-1] // pop last node
 				tnode = nodes[len(nodes)-1]
 			}
+
+		case len(line) == 0:
+			continue LINE
+
+		default:
+			if c.Strict {
+				return perr("unable to parse %q", line)
+			}
 		}
 	}
 
 	if len(c.tree) < 1 {
-		return errors.New("Configuration data is empty, cannot continue")
+		return ErrConfigEmpty
 	}
 
-	return nil
+	return c.checkGroups()
+}
+
+// cfgName returns the configuration's name for parse error messages
+func (c *Config) cfgName() string {
+	if c.File != "" {
+		return c.File
+	}
+	return "config.boot"
 }
 
 // readDir returns a listing of dnsmasq blacklist configuration files
@@ -364,22 +718,65 @@ func (c *CFile) readDir(pattern string) ([]string, error) {
 	return filepath.Glob(pattern)
 }
 
-// ReloadDNS reloads the dnsmasq configuration
+// ReloadDNS reloads the DNS service using the configured ServiceController,
+// or by running DNSsvc if there isn't one, followed by any dnsmasq instances
 func (c *Config) ReloadDNS() ([]byte, error) {
-	cmd := exec.Command(c.Bash)
-	cmd.Stdin = strings.NewReader(c.DNSsvc)
+	var (
+		b     []byte
+		err   error
+		start = time.Now()
+	)
+	defer c.Timed("reload", "", start)
 
-	return cmd.CombinedOutput()
+	switch c.DNSctl {
+	case nil:
+		b, err = c.runner().CombinedOutput(c.DNSsvc)
+	default:
+		b, err = c.DNSctl.Reload(c.runner())
+	}
+
+	if err != nil {
+		return b, &ErrReload{Output: b, Cause: err}
+	}
+
+	ib, err := c.reloadInstances()
+	return append(b, ib...), err
 }
 
 // Remove deletes a CFile array of file names
 func (c *CFile) Remove() error {
-	d, err := c.readDir(fmt.Sprintf(c.FnFmt, c.Dir, c.Wildcard.Node, c.Wildcard.Name, c.Parms.Ext))
+	d, err := c.globFiles(c.Dir, "")
+	if err != nil {
+		return err
+	}
+
+	gz, err := c.globFiles(c.Dir, gzExt)
+	if err != nil {
+		return err
+	}
+
+	counts, err := c.globFiles(c.Dir, countExt)
 	if err != nil {
 		return err
 	}
 
-	return purgeFiles(diffArray(c.names, d))
+	cur := c.current(d)
+	return purgeFiles(append(append(diffArray(cur, d), c.staleGzip(cur, gz)...), c.staleCounts(cur, counts)...))
+}
+
+// staleGzip returns gzip copies that no longer have a current blacklist file
+func (c *CFile) staleGzip(cur, gz []string) (stale []string) {
+	if !c.Gzip {
+		return gz
+	}
+
+	current := updateEntry(cur)
+	for _, f := range gz {
+		if _, ok := current.entry[strings.TrimSuffix(f, gzExt)]; !ok {
+			stale = append(stale, f)
+		}
+	}
+	return stale
 }
 
 // sortKeys returns a slice of keys in lexicographical sorted order.
@@ -396,39 +793,51 @@ func (c *Config) sortKeys() (pkeys sort.StringSlice) {
 }
 
 // String returns pretty print for the Blacklist struct
-func (c *Config) String() (s string) {
+func (c *Config) String() string {
+	w := bufPool.Get().(*bytes.Buffer)
+	w.Reset()
+	defer bufPool.Put(w)
+
 	indent := 1
 	cmma := comma
-	cnt := len(c.sortKeys())
-	s += fmt.Sprintf("{\n%v%q: [{\n", tabs(indent), "nodes")
+	pkeys := c.sortKeys()
+	cnt := len(pkeys)
+	w.WriteString("{\n")
+	w.WriteString(tabs(indent))
+	w.WriteString(`"nodes": [{` + enter)
 
-	for i, pkey := range c.sortKeys() {
+	for i, pkey := range pkeys {
 		if i == cnt-1 {
 			cmma = null
 		}
 
 		indent++
-		s += fmt.Sprintf("%v%q: {\n", tabs(indent), pkey)
+		w.WriteString(tabs(indent))
+		quoted(w, pkey)
+		w.WriteString(": {\n")
 		indent++
 
-		s += fmt.Sprintf("%v%q: %q,\n", tabs(indent), disabled,
-			booltoStr(c.tree[pkey].disabled))
-		s = is(indent, s, "ip", c.tree[pkey].ip)
-		s += getJSONArray(&cfgJSON{array: c.tree[pkey].exc, pk: pkey, leaf: "excludes", indent: indent})
+		is(w, indent, disabled, booltoStr(c.tree[pkey].disabled))
+		is(w, indent, "ip", c.tree[pkey].ip)
+		getJSONArray(w, &cfgJSON{array: c.tree[pkey].exc, pk: pkey, leaf: "excludes", indent: indent})
 
 		if pkey != rootNode {
-			s += getJSONArray(&cfgJSON{array: c.tree[pkey].inc, pk: pkey, leaf: "includes", indent: indent})
-			s += getJSONsrcArray(&cfgJSON{Config: c, pk: pkey, indent: indent})
+			getJSONArray(w, &cfgJSON{array: c.tree[pkey].inc, pk: pkey, leaf: "includes", indent: indent})
+			getJSONsrcArray(w, &cfgJSON{Config: c, pk: pkey, indent: indent})
 		}
 
 		indent--
-		s += fmt.Sprintf("%v}%v\n", tabs(indent), cmma)
+		w.WriteString(tabs(indent))
+		w.WriteString("}")
+		w.WriteString(cmma)
+		w.WriteString(enter)
 		indent--
 	}
 
-	s += tabs(indent) + "}]\n}"
+	w.WriteString(tabs(indent))
+	w.WriteString("}]\n}")
 
-	return s
+	return w.String()
 }
 
 // String implements string method
@@ -448,6 +857,11 @@ func (c *Config) LTypes() []string {
 }
 
 func (b tree) getIP(node string) (ip string) {
+	// dnsmasq answers NXDOMAIN for address=/domain/ without an IP
+	if b.blocking(node) == blockNXDomain {
+		return ""
+	}
+
 	switch b[node].ip {
 	case "":
 		ip = b[rootNode].ip
@@ -461,6 +875,7 @@ func (b tree) validate(node string) *Objects {
 	for _, obj := range b[node].Objects.x {
 		if obj.ip == "" {
 			obj.ip = b.getIP(node)
+			obj.inherit(blackhole, b.ipFrom(node))
 		}
 	}
 	return &b[node].Objects
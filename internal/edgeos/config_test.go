@@ -30,6 +30,7 @@ func TestAddInc(t *testing.T) {
 				node: domains,
 				exp: &object{
 					Parms: &Parms{
+						state: c.state,
 						Wildcard: Wildcard{
 							Node: "",
 							Name: "",
@@ -86,6 +87,7 @@ func TestAddInc(t *testing.T) {
 				node: hosts,
 				exp: &object{
 					Parms: &Parms{
+						state: c.state,
 						Wildcard: Wildcard{
 							Node: "",
 							Name: "",
@@ -239,6 +241,95 @@ func TestReadCfg(t *testing.T) {
 		exp := errors.New("Configuration data is empty, cannot continue")
 		act := NewConfig().ReadCfg(&CFGstatic{Cfg: ""})
 		So(act, ShouldResemble, exp)
+
+		Convey("Testing ReadCfg() in strict mode", func() {
+			So(NewConfig(Strict(true)).ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+		})
+
+		Convey("Testing ReadCfg() parse error locations", func() {
+			tests := []struct {
+				cfg    string
+				err    string
+				strict bool
+			}{
+				{
+					cfg: "blacklist {\n\tdomains {\n\t\tsource zeus {\n\t\t\tdescription \"no url\"\n\t\t}\n\t}\n}",
+					err: `config.boot:5: source "zeus" missing url/file`,
+				},
+				{
+					cfg: "include adsrvr.org\nblacklist {\n}",
+					err: `config.boot:1: "include adsrvr.org" outside of a blacklist node`,
+				},
+				{
+					cfg: "blacklist {\n\tdomains {\n\t\tinclude adsrvr.org@soon\n\t}\n}",
+					err: `config.boot:3: include "adsrvr.org@soon" has an invalid expiry, use a date like 2006-01-02 or an RFC3339 time`,
+				},
+				{
+					cfg:    "blacklist {\n\tsource zeus {\n\t\tcolour red\n\t\turl http://zeus.com\n\t}\n}",
+					err:    `config.boot:3: source "zeus" has unknown leaf "colour"`,
+					strict: true,
+				},
+				{
+					cfg:    "blacklist {\n\t= what?\n}",
+					err:    `config.boot:2: unable to parse "= what?"`,
+					strict: true,
+				},
+				{
+					cfg: "blacklist {\n\t= what?\n}",
+				},
+			}
+
+			for _, tt := range tests {
+				err := NewConfig(Strict(tt.strict)).ReadCfg(&CFGstatic{Cfg: tt.cfg})
+				switch tt.err {
+				case "":
+					So(err, ShouldBeNil)
+				default:
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, tt.err)
+				}
+			}
+		})
+	})
+}
+
+func TestReadCfgLeafOrder(t *testing.T) {
+	Convey("Testing ReadCfg() is independent of source leaf order", t, func() {
+		cfg := `blacklist {
+	domains {
+		description "leads the source"
+		source zeus {
+			url http://zeus.com
+			description "zeus domains"
+			prefix "zone "
+		}
+		description "trails the source"
+		source yoyo {
+			prefix ""
+			description "yoyo domains"
+			file /config/yoyo.txt
+		}
+	}
+}`
+		c := NewConfig()
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		act := c.Get(domains).x
+		So(len(act), ShouldEqual, 2)
+		So([]string{act[0].name, act[0].desc, act[0].ltype, act[0].prefix}, ShouldResemble, []string{"zeus", "zeus domains", urls, "zone "})
+		So([]string{act[1].name, act[1].desc, act[1].ltype, act[1].file}, ShouldResemble, []string{"yoyo", "yoyo domains", files, "/config/yoyo.txt"})
+	})
+}
+
+func FuzzReadCfg(f *testing.F) {
+	f.Add(tdata.Cfg)
+	f.Add("blacklist {\n\tdescription \"first\"\n\tsource zeus {\n\t\turl http://zeus.com\n\t}\n}")
+	f.Add("}\n}\nsource x {\ninclude y\n")
+
+	f.Fuzz(func(t *testing.T, cfg string) {
+		for _, strict := range []bool{false, true} {
+			NewConfig(Strict(strict)).ReadCfg(&CFGstatic{Cfg: cfg})
+		}
 	})
 }
 
@@ -307,6 +398,18 @@ func TestRemove(t *testing.T) {
 	})
 }
 
+func TestStaleGzip(t *testing.T) {
+	Convey("Testing CFile.staleGzip()", t, func() {
+		gz := []string{"/tmp/domains.zeus.blacklist.conf.gz", "/tmp/hosts.gone.blacklist.conf.gz"}
+		c := &CFile{Parms: &Parms{}, names: []string{"/tmp/domains.zeus.blacklist.conf"}}
+
+		So(c.staleGzip(c.names, gz), ShouldResemble, gz)
+
+		c.Gzip = true
+		So(c.staleGzip(c.names, gz), ShouldResemble, []string{"/tmp/hosts.gone.blacklist.conf.gz"})
+	})
+}
+
 func TestLTypes(t *testing.T) {
 	Convey("Testing LTypes()", t, func() {
 		exp := []string{files, PreDomns, PreHosts, urls}
@@ -3,14 +3,16 @@ package edgeos
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/britannic/blacklist/internal/regx"
+	"golang.org/x/sync/errgroup"
 )
 
 // IFace type for labeling interface types
@@ -27,11 +29,21 @@ const (
 	PreHObj
 	URLdObj
 	URLhObj
+	DoHObj
+	AlwObj
 )
 
+// writeMu keeps sources' lines from interleaving on a shared Writer
+var writeMu sync.Mutex
+
 type bList struct {
-	file string
-	r    io.Reader
+	count bool
+	file  string
+	gz    bool
+	key   []byte
+	n     int
+	r     io.Reader
+	shard int
 }
 
 // Contenter is a Content interface
@@ -166,8 +178,8 @@ func (u *URLDomnObjects) Find(elem string) int {
 // GetList implements the Contenter interface for ExcDomnObjects
 func (e *ExcDomnObjects) GetList() *Objects {
 	for _, o := range e.x {
-		switch o.nType {
-		case excDomn:
+		switch {
+		case o.nType.isExc():
 			if o.exc != nil {
 				o.r = o.excludes()
 				o.Parms = e.Objects.Parms
@@ -180,8 +192,8 @@ func (e *ExcDomnObjects) GetList() *Objects {
 // GetList implements the Contenter interface for ExcHostObjects
 func (e *ExcHostObjects) GetList() *Objects {
 	for _, o := range e.x {
-		switch o.nType {
-		case excHost:
+		switch {
+		case o.nType.isExc():
 			if o.exc != nil {
 				o.r = o.excludes()
 				o.Parms = e.Objects.Parms
@@ -205,34 +217,72 @@ func (e *ExcRootObjects) GetList() *Objects {
 	return e.Objects
 }
 
+// response is an object read by a GetList goroutine, i is its position, as
+// sources on different nodes may share a name
+type response struct {
+	i int
+	o *object
+}
+
 // GetList implements the Contenter interface for FIODataObjects
 func (f *FIODataObjects) GetList() *Objects {
-	var responses = make(chan *object, len(f.x))
+	var responses = make(chan response, len(f.x))
 
 	defer close(responses)
 
-	for _, o := range f.x {
+	for i, o := range f.x {
 		o.Parms = f.Objects.Parms
-		go func(o *object) {
-			o.r, o.err = getFile(o.file)
-			responses <- o
-		}(o)
+		go func(i int, o *object) {
+			responses <- response{i: i, o: o.readFile()}
+		}(i, o)
 	}
 
 	for _ = range Iter(len(f.x)) {
 		select {
-		case response := <-responses:
-			f.x[f.Find(response.name)] = response
+		case r := <-responses:
+			f.x[r.i] = r.o
 		}
 	}
 
 	return f.Objects
 }
 
+// readFile reads and processes o's file source, unless it is benched or a
+// failure is injected
+func (o *object) readFile() *object {
+	if o.benched() {
+		o.r, o.err = strings.NewReader(""), errBenched
+		return o
+	}
+
+	if o.injected() {
+		return o
+	}
+
+	if o.err = o.checkFile(); o.err != nil {
+		o.r = strings.NewReader("")
+		return o
+	}
+	start := time.Now()
+	o.r, o.err = getFile(o.path())
+	o.Timed("fetch", o.name, start)
+	o.runProcessor()
+	return o
+}
+
+// readURL downloads and processes o's url source
+func (o *object) readURL() *object {
+	start := time.Now()
+	o = getHTTP(o)
+	o.Timed("fetch", o.name, start)
+	o.runProcessor()
+	return o
+}
+
 // GetList implements the Contenter interface for PreDomnObjects
 func (p *PreDomnObjects) GetList() *Objects {
 	for _, o := range p.x {
-		if o.ltype == PreDomns && o.inc != nil {
+		if o.nType.isInc() && o.inc != nil {
 			o.r = o.includes()
 			o.Parms = p.Objects.Parms
 		}
@@ -243,7 +293,7 @@ func (p *PreDomnObjects) GetList() *Objects {
 // GetList implements the Contenter interface for PreHostObjects
 func (p *PreHostObjects) GetList() *Objects {
 	for _, o := range p.x {
-		if o.ltype == PreHosts && o.inc != nil {
+		if o.nType.isInc() && o.inc != nil {
 			o.r = o.includes()
 			o.Parms = p.Objects.Parms
 		}
@@ -253,21 +303,31 @@ func (p *PreHostObjects) GetList() *Objects {
 
 // GetList implements the Contenter interface for URLHostObjects
 func (u *URLDomnObjects) GetList() *Objects {
-	var responses = make(chan *object, len(u.x))
+	var responses = make(chan response, len(u.x))
 
 	defer close(responses)
 
 	for _, o := range u.x {
 		o.Parms = u.Objects.Parms
-		go func(o *object) {
-			responses <- getHTTP(o)
-		}(o)
+	}
+
+	workers, spill := u.fetchPlan(u.x)
+	workers = u.fetches(workers)
+	sem := make(chan struct{}, workers)
+
+	for i, o := range u.x {
+		o.spill = spill
+		go func(i int, o *object) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			responses <- response{i: i, o: o.readURL()}
+		}(i, o)
 	}
 
 	for i := 0; i < len(u.x); i++ {
 		select {
-		case response := <-responses:
-			u.x[u.Find(response.name)] = response
+		case r := <-responses:
+			u.x[r.i] = r.o
 		}
 	}
 
@@ -276,21 +336,31 @@ func (u *URLDomnObjects) GetList() *Objects {
 
 // GetList implements the Contenter interface for URLHostObjects
 func (u *URLHostObjects) GetList() *Objects {
-	var responses = make(chan *object, len(u.x))
+	var responses = make(chan response, len(u.x))
 
 	defer close(responses)
 
 	for _, o := range u.x {
 		o.Parms = u.Objects.Parms
-		go func(o *object) {
-			responses <- getHTTP(o)
-		}(o)
+	}
+
+	workers, spill := u.fetchPlan(u.x)
+	workers = u.fetches(workers)
+	sem := make(chan struct{}, workers)
+
+	for i, o := range u.x {
+		o.spill = spill
+		go func(i int, o *object) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			responses <- response{i: i, o: o.readURL()}
+		}(i, o)
 	}
 
 	for i := 0; i < len(u.x); i++ {
 		select {
-		case response := <-responses:
-			u.x[u.Find(response.name)] = response
+		case r := <-responses:
+			u.x[r.i] = r.o
 		}
 	}
 
@@ -323,32 +393,103 @@ func (u *URLHostObjects) Len() int { return len(u.Objects.x) }
 
 // Process extracts hosts/domains from downloaded raw content
 func (o *object) process() *bList {
+	return o.format(o.extract())
+}
+
+// extract returns the new hosts/domains in downloaded raw content, it updates
+// the shared exclusions so sources must be extracted one at a time
+func (o *object) extract() list {
 	var (
-		add = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+		add = list{RWMutex: &sync.RWMutex{}, entry: make(entry, o.entryHint())}
 		b   = bufio.NewScanner(o.r)
 		// d   = NewMsg(o.Name)
 		rx = regx.Obj
+		// the sinkhole address replaces the prefix for hosts format sources
+		prefix = o.prefix
+		guard  = !o.nType.isExc()
+		forms  = make(variants)
+		wilds  = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+		held   int
+		parked int
+		lines  int
+		dupes  int
 	)
 
+	// processors already reduce content to one domain per line
+	if len(o.sinkholes) > 0 || o.processor != "" {
+		prefix = ""
+	}
+	o.rejected, o.rejects = 0, nil
+
+	if o.LineBuf > bufio.MaxScanTokenSize {
+		b.Buffer(make([]byte, bufio.MaxScanTokenSize), o.LineBuf)
+	}
+
 NEXT:
 	for b.Scan() {
-		line := bytes.TrimSpace(bytes.ToLower(b.Bytes()))
+		if lines++; lines%progressLines == 0 {
+			o.progress(Progress{Lines: lines})
+		}
+
+		raw := bytes.TrimSpace(b.Bytes())
+		line := bytes.ToLower(raw)
+		lowered := line
 
 		switch {
-		case bytes.HasPrefix(line, []byte("#")), bytes.HasPrefix(line, []byte("//")):
+		case len(line) == 0, bytes.HasPrefix(line, []byte("#")), bytes.HasPrefix(line, []byte("//")):
 			continue NEXT
 
-		case bytes.HasPrefix(line, []byte(o.prefix)):
+		case bytes.HasPrefix(line, []byte(prefix)):
 			var ok bool
 
-			if line, ok = rx.StripPrefixAndSuffix(line, o.prefix); ok {
-				fqdns := rx.FQDN.FindAll(line, -1)
+			if line, ok = o.sinkhole(line); !ok {
+				parked++
+				continue NEXT
+			}
+
+			if line, ok = rx.StripPrefixAndSuffix(o.rewrite(line, prefix), prefix); ok {
+				if ip := parseIP(line); ip != nil {
+					o.ips.add(ip)
+					continue NEXT
+				}
+
+				locs := rx.FQDN.FindAllIndex(line, -1)
+				if len(locs) == 0 {
+					o.reject(line)
+				}
 
 			FQDN:
-				for _, fqdn := range fqdns {
+				for _, loc := range locs {
+					fqdn := line[loc[0]:loc[1]]
+					// *.domain entries block domain's subdomains
+					star := loc[0] > 1 && line[loc[0]-2] == '*' && line[loc[0]-1] == '.'
+
+					if forms.collides(string(fqdn), rawForm(raw, lowered, fqdn), add.keyExists(string(fqdn))) {
+						dupes++
+					}
+
 					isDEX := o.Dex.subKeyExists(string(fqdn))
 					isEXC := o.Exc.keyExists(string(fqdn))
 
+					// explicit includes override subdomain exclusions by
+					// default, see Conflicts for the precedence rules
+					if o.isInclude() && o.includeWins() {
+						isDEX = false
+					}
+
+					if d, ok := o.guards(string(fqdn)); guard && ok && !isDEX {
+						o.warn(o.protectedMsg(string(fqdn), d))
+						continue FQDN
+					}
+
+					if guard && o.suffixes().isSuffix(string(fqdn)) {
+						o.warn(o.suffixMsg(string(fqdn)))
+						if o.Refuse {
+							o.reject(fqdn)
+							continue FQDN
+						}
+					}
+
 					switch {
 					case isDEX:
 						continue FQDN
@@ -357,77 +498,389 @@ NEXT:
 						if add.keyExists(string(fqdn)) {
 						}
 
+					case !isEXC && o.quarantines() && o.hold(string(fqdn)):
+						held++
+
 					case !isEXC:
-						o.Exc.set(string(fqdn), 0)
+						// weighed sources are only deduplicated once
+						// they've been tallied, transformed ones once
+						// the script has run
+						if !o.weighed() && !o.transforms() {
+							o.Exc.set(string(fqdn), 0)
+						}
+
+						// a domains node's entries are already wildcards
+						if star && !o.nType.isWild() && !o.weighed() {
+							wilds.set(string(fqdn), 0)
+							continue FQDN
+						}
 						add.set(string(fqdn), 0)
 					}
 				}
 			}
 		default:
-			continue NEXT
+			o.reject(line)
 		}
 	}
 
-	switch o.nType {
-	case domn, excDomn, excRoot:
+	o.progress(Progress{Lines: lines, Done: true})
+
+	if o.transforms() {
+		var err error
+		if add, wilds, o.tags, err = o.transform(add, wilds); err != nil {
+			o.log(fmt.Sprintf("%v: %v", o.name, err))
+		}
+
+		if !o.weighed() {
+			for _, l := range []list{add, wilds} {
+				for k := range l.entry {
+					o.Exc.set(k, 0)
+				}
+			}
+		}
+	}
+
+	if parked > 0 {
+		o.debug(fmt.Sprintf("%v: skipped %d parked entries", o.name, parked))
+	}
+
+	if o.dupes = dupes; dupes > 0 {
+		o.log(fmt.Sprintf("%v: %d entries differ from others only in case or a trailing dot", o.name, dupes))
+	}
+
+	if o.held = held; held > 0 {
+		o.log(fmt.Sprintf("%v: quarantined %d new entries", o.name, held))
+	}
+
+	if o.nType.isWild() && !o.weighed() {
 		o.Dex = mergeList(o.Dex, add)
 	}
 
-	fmttr := o.Pfx + getSeparator(getType(o.nType).(string)) + "%v/" + o.ip
+	if o.wilds = wilds; len(wilds.entry) > 0 {
+		o.Dex = mergeList(o.Dex, wilds)
+	}
+	return add
+}
+
+// format returns the dnsmasq configuration for add, sources can be formatted
+// concurrently
+func (o *object) format(add list) *bList {
+	var (
+		fmttr = o.Pfx + getSeparator(getType(o.nType).(string)) + "%v/" + o.ip
+		n     = len(add.entry)
+		r     = formatData(fmttr, add)
+	)
+
+	// *.domain entries from a source that isn't a domains node's
+	if len(o.wilds.entry) > 0 {
+		n += len(o.wilds.entry)
+		r = io.MultiReader(r, formatData(o.Pfx+"/.%v/"+o.ip, o.wilds))
+	}
 
 	return &bList{
-		file: fmt.Sprintf(o.FnFmt, o.Dir, getType(o.nType).(string), o.name, o.Ext),
-		r:    formatData(fmttr, add),
+		count: o.Counts,
+		file:  fmt.Sprintf(o.FnFmt, o.Dir, getType(o.nType).(string), o.name, o.Ext),
+		gz:    o.Gzip,
+		n:     n,
+		r:     o.corrupt(r),
+		shard: o.Shard,
 	}
 }
 
-// ProcessContent processes the Contents array
+// ProcessContent processes the Contents array as a new run, with a fresh
+// run state, weighed sources are held back until every Contenter's have been
+// extracted, see Threshold; nothing is written until every source is
+// extracted and their files fit in Dir
 func (c *Config) ProcessContent(cts ...Contenter) error {
+	c.state.reset()
+	return c.processContent(cts...)
+}
+
+// processContent is ProcessContent keeping the run state, so Retry and Update
+// only replace the results of the sources they process again
+func (c *Config) processContent(cts ...Contenter) error {
 	var (
-		errs      []string
-		getErrors chan error
+		errs  Errors
+		bobjs [][]*object
+		badds [][]list
+		wobjs []*object
+		wadds []list
 	)
 
 	if len(cts) < 1 {
-		return errors.New("Empty Contenter interface{} passed to ProcessContent()")
+		return ErrNoContent
+	}
+
+	if err := c.loadSeen(); err != nil {
+		return err
+	}
+
+	if err := c.loadFresh(); err != nil {
+		return err
+	}
+
+	if err := c.loadFails(); err != nil {
+		return err
+	}
+	c.loadGuard()
+
+	if err := c.loadSuffixes(); err != nil {
+		return err
 	}
 
 	for _, ct := range cts {
-		for _, o := range ct.GetList().x {
-			getErrors = make(chan error)
-			if o.err != nil {
-				errs = append(errs, o.err.Error())
+		var (
+			objs []*object
+			adds []list
+		)
+
+		for _, o := range c.ordered(ct.GetList().x) {
+			if o.err == errBenched {
+				o.warn(fmt.Sprintf("Skipping source %q, it is auto-disabled after repeated failed fetches, retry it with update -source %v", o.name, o.name))
+				continue
 			}
 
-			go func(o *object) {
-				switch o.nType {
-				case excDomn, excHost, excRoot:
-					o.process()
-					getErrors <- nil
-				default:
-					getErrors <- o.process().writeFile()
-				}
-			}(o)
+			if o.tally() {
+				o.warn(fmt.Sprintf("Auto-disabled source %q after %d consecutive failed fetches", o.name, o.MaxFail))
+			}
+			o.retry = false
 
-			for i := 0; i < len(cts); i++ {
-				select {
-				case err := <-getErrors:
-					if err != nil {
-						errs = append(errs, err.Error())
-					}
-				}
+			if o.err != nil {
+				o.err = &ErrSourceFetch{Source: o.name, Cause: o.err}
+				errs = append(errs, o.err)
+			}
+
+			start := time.Now()
+			add := o.extract()
+			o.Timed("parse", o.name, start)
+			o.freshen(add)
+			switch {
+			case o.nType.isExc():
+				continue
+			case o.weighed():
+				wobjs, wadds = append(wobjs, o), append(wadds, add)
+				continue
 			}
+			objs, adds = append(objs, o), append(adds, add)
+		}
+		bobjs, badds = append(bobjs, objs), append(badds, adds)
+	}
+
+	if wobjs != nil {
+		start := time.Now()
+		c.tally(wobjs, wadds)
+		c.Timed("dedupe", "", start)
+		bobjs, badds = append(bobjs, wobjs), append(badds, wadds)
+	}
+
+	var (
+		objs []*object
+		adds []list
+	)
+	for i := range bobjs {
+		objs, adds = append(objs, bobjs[i]...), append(adds, badds[i]...)
+	}
+
+	if err := c.Exc.disk.failed(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.checkSpace(objs, adds); err != nil {
+		fail(objs, err)
+		errs = append(errs, err)
+	} else {
+		for i := range bobjs {
+			errs = append(errs, c.write(bobjs[i], badds[i])...)
+		}
+	}
+
+	if c.audit == nil {
+		if err := c.seen.save(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := c.fresh.save(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := c.fails.save(); err != nil {
+			errs = append(errs, err)
 		}
-		close(getErrors)
 	}
 
 	if errs != nil {
-		return fmt.Errorf(strings.Join(errs, "\n"))
+		return errs
 	}
 
 	return nil
 }
 
+// fail records err as the result of each of objs that hadn't failed already
+func fail(objs []*object, err error) {
+	for _, o := range objs {
+		if o.err != nil {
+			o.done(0, o.err)
+			continue
+		}
+		o.done(0, err)
+	}
+}
+
+// write formats and outputs each object's extracted domains, up to workers()
+// at once, and records the results in source order, whichever finished first;
+// none are written once the Deadline has passed, if they would change more
+// of the entries than MaxChg allows or if Sanity finds popular domains in them
+func (c *Config) write(objs []*object, adds []list) Errors {
+	var (
+		errs Errors
+		g    errgroup.Group
+		outs = make([]*bList, len(objs))
+		werr = make([]error, len(objs))
+		sem  = make(chan struct{}, c.workers())
+		key  []byte
+	)
+
+	err := c.expired()
+	if err == nil {
+		err = c.checkChange(objs, adds)
+	}
+
+	if err == nil {
+		err = c.checkPopular(objs, adds)
+	}
+
+	// audits don't write files, so they don't need the key
+	if err == nil && c.audit == nil {
+		key, err = c.macKey(true)
+	}
+
+	if err != nil {
+		fail(objs, err)
+		return Errors{err}
+	}
+
+	for i, o := range objs {
+		i, o := i, o
+		sem <- struct{}{}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			start := time.Now()
+			outs[i] = o.format(adds[i])
+			outs[i].key = key
+			o.Timed("render", o.name, start)
+
+			start = time.Now()
+			werr[i] = o.output(outs[i])
+			o.Timed("write", o.name, start)
+			return werr[i]
+		})
+	}
+	g.Wait()
+
+	for i, o := range objs {
+		switch {
+		case o.err != nil:
+			o.done(outs[i].n, o.err)
+		default:
+			o.done(outs[i].n, werr[i])
+		}
+
+		if werr[i] != nil {
+			errs = append(errs, werr[i])
+		}
+	}
+	return errs
+}
+
+// workers returns how many sources may be formatted and written at once,
+// bounded by Cores; output to a Writer is kept in source order
+func (c *Config) workers() int {
+	if c.Cores < 1 || c.ioWriter != nil {
+		return 1
+	}
+	return c.Cores
+}
+
+// rescan drops the exclusions collected by earlier runs, which hold every
+// domain they wrote, and returns the Contenters that collect the configured
+// exclusions and allowed domains again, to process ahead of a single source
+func (c *Config) rescan() ([]Contenter, error) {
+	c.ResetExclusions()
+
+	var cts []Contenter
+	for _, iface := range []IFace{ExRtObj, ExDmObj, ExHtObj, AlwObj} {
+		ct, err := c.NewContent(iface)
+		if err != nil {
+			return nil, err
+		}
+		cts = append(cts, ct)
+	}
+	return cts, nil
+}
+
+// Retry fetches and processes the named file or url source again, e.g. after
+// it failed; with a Threshold its domains are only weighed against its own
+func (c *Config) Retry(name string) error {
+	for _, o := range c.GetAll(files, urls).ByName(name).x {
+		o.err, o.retry = nil, true
+		c.Status.forget(name)
+		c.state.forget(o)
+
+		cts, err := c.rescan()
+		if err != nil {
+			return err
+		}
+		return c.processContent(append(cts, c.sourceContent(o))...)
+	}
+	return fmt.Errorf("unknown source %q", name)
+}
+
+// Update fetches and regenerates only the file and url sources of node and
+// named source, either of which may be empty to match any, leaving the other
+// generated files as they are; the configured exclusions and allowed domains
+// are collected first, as in a full run
+func (c *Config) Update(node, source string) error {
+	objs := c.GetAll(files, urls)
+	if node != "" {
+		if c.tree[node] == nil {
+			return fmt.Errorf("unknown node %q", node)
+		}
+		objs = c.Get(node)
+	}
+
+	if objs = objs.ByLType(files, urls); source != "" {
+		objs = objs.ByName(source)
+	}
+
+	if len(objs.x) == 0 {
+		return fmt.Errorf("no sources match node %q and source %q", node, source)
+	}
+
+	cts, err := c.rescan()
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objs.x {
+		o.err, o.retry = nil, true
+		c.Status.forget(o.name)
+		c.state.forget(o)
+		cts = append(cts, c.sourceContent(o))
+	}
+	return c.processContent(cts...)
+}
+
+// sourceContent returns the Contenter that fetches and processes o alone
+func (c *Config) sourceContent(o *object) Contenter {
+	objs := &Objects{Parms: c.Parms, x: []*object{o}}
+	switch {
+	case o.ltype == files:
+		return &FIODataObjects{Objects: objs}
+	case o.nType.isWild():
+		return &URLDomnObjects{Objects: objs}
+	default:
+		return &URLHostObjects{Objects: objs}
+	}
+}
+
 // SetURL sets the Object's url field value
 func (e *ExcDomnObjects) SetURL(name, url string) {
 	for _, o := range e.x {
@@ -525,20 +978,83 @@ func (i IFace) String() (s string) {
 		s = PreHosts
 	case URLhObj, URLdObj:
 		s = urls
+	case DoHObj:
+		s = DoHDomns
+	case AlwObj:
+		s = allowNode
 	default:
 		s = notknown
+		if ct := i.registered(); ct != nil {
+			s = ct.name
+		}
 	}
 	return s
 }
 
-// writeFile saves hosts/domains data to disk
+// output writes b to the Writer option if set, or to its file otherwise;
+// audits only record it
+func (o *object) output(b *bList) error {
+	if o.audit != nil {
+		return o.audit.add(o, b)
+	}
+
+	if o.ioWriter == nil {
+		return b.writeFile()
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	_, err := io.Copy(o.ioWriter, b.r)
+	return err
+}
+
+// writeFile saves hosts/domains data to disk, and its entry count, hash and
+// HMAC to a .count file beside it if count or key is set
 func (b *bList) writeFile() error {
-	w, err := os.Create(b.file)
+	if b.shard > 0 {
+		return b.writeShards()
+	}
+
+	f, err := os.Create(b.file)
 	if err != nil {
 		return err
 	}
-	defer w.Close()
+	defer f.Close()
+
+	var (
+		t *tally
+		w io.Writer = f
+	)
+	if b.count || b.key != nil {
+		t = newTally(b.key)
+		w = io.MultiWriter(f, t)
+	}
 
-	_, err = io.Copy(w, b.r)
+	switch {
+	case b.gz:
+		err = b.writeGzip(w)
+	default:
+		_, err = io.Copy(w, b.r)
+	}
+
+	if err == nil && t != nil {
+		err = t.save(b.file)
+	}
 	return err
 }
+
+// writeGzip saves hosts/domains data to w and a gzip compressed copy alongside it
+func (b *bList) writeGzip(w io.Writer) error {
+	f, err := os.Create(b.file + gzExt)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	if _, err = io.Copy(io.MultiWriter(w, zw), b.r); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
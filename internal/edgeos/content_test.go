@@ -2,15 +2,18 @@ package edgeos
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -69,7 +72,7 @@ func TestConfigProcessContent(t *testing.T) {
 				c:      newCfg(),
 				cfg:    testCfg,
 				ct:     FileObj,
-				err:    fmt.Errorf("open /:~/=../testdata/blist.hosts.src: no such file or directory\nopen /:~//hosts.tasty.blacklist.conf: no such file or directory"),
+				err:    fmt.Errorf("tasty: open /:~/=../testdata/blist.hosts.src: no such file or directory\nopen /:~//hosts.tasty.blacklist.conf: no such file or directory"),
 				expErr: true,
 				name:   "File",
 			},
@@ -389,6 +392,32 @@ func TestGetAllContent(t *testing.T) {
 	})
 }
 
+func TestGetListSharedNames(t *testing.T) {
+	Convey("Testing GetList() with sources on different nodes sharing a name", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(Dir(dir), Ext("blacklist.conf"), Nodes([]string{domains, hosts}))
+		objs := &Objects{Parms: c.Parms}
+		for i := 0; i < 16; i++ {
+			file := fmt.Sprintf("%v/feed%d.txt", dir, i)
+			So(ioutil.WriteFile(file, []byte("ads.example.com\n"), 0644), ShouldBeNil)
+
+			nType := domn
+			if i%2 == 1 {
+				nType = host
+			}
+			objs.x = append(objs.x, &object{Parms: c.Parms, file: file, ltype: files, name: "feed", nType: nType})
+		}
+		exp := append([]*object(nil), objs.x...)
+
+		for range Iter(10) {
+			So((&FIODataObjects{Objects: objs}).GetList().x, ShouldResemble, exp)
+		}
+	})
+}
+
 func TestMultiObjNewContent(t *testing.T) {
 	Convey("Testing Multi Object NewContent()", t, func() {
 		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
@@ -441,6 +470,35 @@ func TestMultiObjNewContent(t *testing.T) {
 	})
 }
 
+func FuzzProcess(f *testing.F) {
+	f.Add("127.0.0.1 ads.example.com\n# comment\n127.0.0.1 localhost", "127.0.0.1 ")
+	f.Add("https://phish.example.com/login.php?id=1\n", "http")
+	f.Add("zone \"malware.example.net\" {type master;};", "zone ")
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, data, prefix string) {
+		o := &object{
+			Parms:  NewConfig(FileNameFmt("%v/%v.%v.%v"), Prefix("address=")).Parms,
+			ip:     "0.0.0.0",
+			name:   "fuzz",
+			nType:  host,
+			prefix: prefix,
+			r:      strings.NewReader(data),
+		}
+
+		b, err := ioutil.ReadAll(o.process().r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, l := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+			if l != "" && (!strings.HasPrefix(l, "address=/") || !strings.HasSuffix(l, "/0.0.0.0")) {
+				t.Errorf("process(%q, %q) produced malformed line %q", data, prefix, l)
+			}
+		}
+	})
+}
+
 func TestProcessContent(t *testing.T) {
 	Convey("Testing ProcessContent(), setting up temporary directory in /tmp", t, func() {
 		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
@@ -525,7 +583,7 @@ func TestProcessContent(t *testing.T) {
 				},
 				{
 					name: "FileObj",
-					err:  fmt.Errorf("open %v/hosts./tasty.blacklist.conf: no such file or directory", dir),
+					err:  Errors{&os.PathError{Op: "open", Path: dir + "/hosts./tasty.blacklist.conf", Err: syscall.ENOENT}},
 					exp:  filesMin,
 					expDexMap: list{
 						entry: entry{
@@ -649,6 +707,35 @@ func TestWriteFile(t *testing.T) {
 	})
 }
 
+func TestWriteGzip(t *testing.T) {
+	Convey("Testing writeFile() with gzip copies", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		exp := "address=/.zeus.com/0.0.0.0\n"
+		b := &bList{
+			file: dir + "/domains.zeus.blacklist.conf",
+			gz:   true,
+			r:    strings.NewReader(exp),
+		}
+		So(b.writeFile(), ShouldBeNil)
+
+		act, err := ioutil.ReadFile(b.file)
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, exp)
+
+		f, err := os.Open(b.file + gzExt)
+		So(err, ShouldBeNil)
+		defer f.Close()
+
+		zr, err := gzip.NewReader(f)
+		So(err, ShouldBeNil)
+		act, err = ioutil.ReadAll(zr)
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, exp)
+	})
+}
+
 var (
 	// Cfg contains a valid full EdgeOS blacklist configuration
 	Cfg = `blacklist {
@@ -895,3 +982,232 @@ var (
 	}
 }`
 )
+
+func TestRetry(t *testing.T) {
+	Convey("Testing Retry()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude keep.tasty.com\n\tdomains {\n\t}\n\thosts {\n\t\tsource tasty {\n\t\t\tfile %v/tasty.hosts\n\t\t}\n\t}\n}"
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+			Stats(NewStatus("")),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, dir)}), ShouldBeNil)
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldNotBeNil)
+
+		r := c.Status.Results()
+		So(len(r), ShouldEqual, 1)
+		So(r[0].Error, ShouldNotEqual, "")
+
+		So(ioutil.WriteFile(dir+"/tasty.hosts", []byte("ads.tasty.com\n"), 0644), ShouldBeNil)
+		So(c.Retry("tasty"), ShouldBeNil)
+
+		r = c.Status.Results()
+		So(len(r), ShouldEqual, 1)
+		So(r[0], ShouldResemble, SourceResult{Name: "tasty", Type: hosts, Entries: 1})
+
+		b, err := ioutil.ReadFile(dir + "/hosts.tasty.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.tasty.com/0.0.0.0\n")
+
+		So(ioutil.WriteFile(dir+"/tasty.hosts", []byte("ads.tasty.com\nkeep.tasty.com\nmore.tasty.com\n"), 0644), ShouldBeNil)
+		So(c.Retry("tasty"), ShouldBeNil)
+
+		b, err = ioutil.ReadFile(dir + "/hosts.tasty.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.tasty.com/0.0.0.0\naddress=/more.tasty.com/0.0.0.0\n")
+
+		So(c.Retry("missing").Error(), ShouldEqual, `unknown source "missing"`)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	Convey("Testing Update()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude keep.yummy.com\n\tdomains {\n\t\tsource tasty {\n\t\t\tfile %[1]v/tasty.domains\n\t\t}\n\t}\n\thosts {\n\t\tsource tasty {\n\t\t\tfile %[1]v/tasty.hosts\n\t\t}\n\t\tsource yummy {\n\t\t\tfile %[1]v/yummy.hosts\n\t\t}\n\t}\n}"
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, dir)}), ShouldBeNil)
+
+		for _, f := range []string{"tasty.domains", "tasty.hosts", "yummy.hosts"} {
+			So(ioutil.WriteFile(dir+"/"+f, []byte("ads."+f+".com\n"), 0644), ShouldBeNil)
+		}
+
+		generated := func() []string {
+			names, err := filepath.Glob(dir + "/*.blacklist.conf")
+			So(err, ShouldBeNil)
+			for i := range names {
+				names[i] = filepath.Base(names[i])
+			}
+			return names
+		}
+
+		So(ioutil.WriteFile(dir+"/yummy.hosts", []byte("ads.yummy.com\nkeep.yummy.com\n"), 0644), ShouldBeNil)
+		So(c.Update(hosts, "yummy"), ShouldBeNil)
+		So(generated(), ShouldResemble, []string{"hosts.yummy.blacklist.conf"})
+
+		b, err := ioutil.ReadFile(dir + "/hosts.yummy.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.yummy.com/0.0.0.0\n")
+
+		So(c.Update("", "tasty"), ShouldBeNil)
+		So(generated(), ShouldResemble, []string{"domains.tasty.blacklist.conf", "hosts.tasty.blacklist.conf", "hosts.yummy.blacklist.conf"})
+
+		So(os.Remove(dir+"/hosts.yummy.blacklist.conf"), ShouldBeNil)
+		So(c.Update(hosts, ""), ShouldBeNil)
+		So(generated(), ShouldResemble, []string{"domains.tasty.blacklist.conf", "hosts.tasty.blacklist.conf", "hosts.yummy.blacklist.conf"})
+
+		So(c.Update("zones", "").Error(), ShouldEqual, `unknown node "zones"`)
+		So(c.Update(domains, "yummy").Error(), ShouldEqual, `no sources match node "domains" and source "yummy"`)
+	})
+}
+
+func TestProcessContentWriter(t *testing.T) {
+	Convey("Testing ProcessContent() with a Writer", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		act := new(bytes.Buffer)
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+			LTypes([]string{PreDomns, PreHosts, files, urls}),
+			Writer(act),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude adsrvr.org\n\t}\n\thosts {\n\t\tinclude beap.gemini.yahoo.com\n\t}\n}"}), ShouldBeNil)
+
+		for _, o := range []IFace{PreDObj, PreHObj} {
+			ct, err := c.NewContent(o)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+		}
+
+		So(act.String(), ShouldEqual, "address=/adsrvr.org/0.0.0.0\naddress=/beap.gemini.yahoo.com/0.0.0.0\n")
+
+		files, err := c.generated()
+		So(err, ShouldBeNil)
+		So(files, ShouldBeEmpty)
+	})
+}
+
+func TestProcessContentParallel(t *testing.T) {
+	Convey("Testing ProcessContent() formats and writes sources in parallel", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n"
+		for i := 0; i < 8; i++ {
+			f := fmt.Sprintf("%v/src%d.txt", dir, i)
+			So(ioutil.WriteFile(f, []byte(fmt.Sprintf("shared.example.com\nads%d.example.com\n", i)), 0644), ShouldBeNil)
+			cfg += fmt.Sprintf("\t\tsource src%d {\n\t\t\tprefix \"\"\n\t\t\tfile %v\n\t\t}\n", i, f)
+		}
+		cfg += "\t}\n}"
+
+		c := NewConfig(
+			Cores(4),
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains}),
+			Prefix("address="),
+			LTypes([]string{PreDomns, PreHosts, files, urls}),
+			Stats(NewStatus("")),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		files, err := c.generated()
+		So(err, ShouldBeNil)
+		So(files, ShouldHaveLength, 8)
+
+		// sources are extracted in order, so the first one keeps the duplicate
+		for i, r := range c.Status.Sources {
+			So(r.Name, ShouldEqual, fmt.Sprintf("src%d", i))
+			exp := 1
+			if i == 0 {
+				exp = 2
+			}
+			So(r.Entries, ShouldEqual, exp)
+		}
+
+		So(c.workers(), ShouldEqual, 4)
+		c.SetOpt(Writer(ioutil.Discard))
+		So(c.workers(), ShouldEqual, 1)
+	})
+}
+
+func TestWildcardEntries(t *testing.T) {
+	Convey("Testing ProcessContent() with *.domain entries", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for f, data := range map[string]string{
+			"hosts.txt":   "0.0.0.0 ads.example.com\n0.0.0.0 *.tracker.example.com\n",
+			"domains.txt": "*.cdn.example.org\nmalware.example.net\n",
+			"later.txt":   "0.0.0.0 pixel.tracker.example.com\n0.0.0.0 other.example.com\n",
+		} {
+			So(ioutil.WriteFile(dir+"/"+f, []byte(data), 0644), ShouldBeNil)
+		}
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+			LTypes([]string{PreDomns, PreHosts, files, urls}),
+			Stats(NewStatus("")),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource list {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/domains.txt\n\t\t}\n\t}\n\thosts {\n\t\tsource feed {\n\t\t\tprefix \"0.0.0.0 \"\n\t\t\tfile " + dir + "/hosts.txt\n\t\t}\n\t\tsource later {\n\t\t\tprefix \"0.0.0.0 \"\n\t\t\tfile " + dir + "/later.txt\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		b, err := ioutil.ReadFile(dir + "/hosts.feed.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.example.com/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n")
+
+		b, err = ioutil.ReadFile(dir + "/domains.list.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/.cdn.example.org/0.0.0.0\naddress=/.malware.example.net/0.0.0.0\n")
+
+		// the wildcard already blocks the later source's subdomain
+		b, err = ioutil.ReadFile(dir + "/hosts.later.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/other.example.com/0.0.0.0\n")
+
+		merged, err := c.Merged()
+		So(err, ShouldBeNil)
+		So(merged, ShouldContain, MergedEntry{Domain: "tracker.example.com", Wild: true})
+		So(merged, ShouldContain, MergedEntry{Domain: "ads.example.com"})
+	})
+}
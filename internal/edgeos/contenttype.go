@@ -0,0 +1,69 @@
+package edgeos
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ContentFunc builds a registered content type's Contenter from c, e.g. by
+// wrapping some of c's Objects in one of the built-in content types
+type ContentFunc func(c *Config) (Contenter, error)
+
+// contentType is a registered content type
+type contentType struct {
+	name string
+	fn   ContentFunc
+}
+
+var (
+	contentMu    sync.RWMutex
+	contentTypes = make(map[IFace]*contentType)
+	contentOrder []IFace
+	// registered content types are numbered after the built-in ones
+	nextIFace = AlwObj + 1
+)
+
+// RegisterContent adds a content type, such as sources kept in an object
+// store, that NewContent builds with fn; it returns the IFace the type is
+// known by, which Contents lists so the pipeline processes it after the
+// built-in types. Registering a name again replaces its fn
+func RegisterContent(name string, fn ContentFunc) (IFace, error) {
+	switch name {
+	case "", notknown, ExcDomns, ExcHosts, ExcRoots, files, PreDomns, PreHosts, urls, DoHDomns, allowNode:
+		return Invalid, fmt.Errorf("invalid content type name %q", name)
+	}
+	if fn == nil {
+		return Invalid, fmt.Errorf("content type %q has no ContentFunc", name)
+	}
+
+	contentMu.Lock()
+	defer contentMu.Unlock()
+
+	for _, i := range contentOrder {
+		if contentTypes[i].name == name {
+			contentTypes[i].fn = fn
+			return i, nil
+		}
+	}
+
+	i := nextIFace
+	nextIFace++
+	contentTypes[i] = &contentType{name: name, fn: fn}
+	contentOrder = append(contentOrder, i)
+	return i, nil
+}
+
+// Contents returns the registered content types' IFaces in the order they
+// were registered
+func Contents() []IFace {
+	contentMu.RLock()
+	defer contentMu.RUnlock()
+	return append([]IFace(nil), contentOrder...)
+}
+
+// registered returns i's registered content type, or nil if it isn't one
+func (i IFace) registered() *contentType {
+	contentMu.RLock()
+	defer contentMu.RUnlock()
+	return contentTypes[i]
+}
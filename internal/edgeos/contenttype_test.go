@@ -0,0 +1,65 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegisterContent(t *testing.T) {
+	Convey("Testing RegisterContent()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/local.txt", []byte("ads.local.com\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/other.txt", []byte("ads.other.com\n"), 0644), ShouldBeNil)
+
+		// a content type for the hosts node's "local" file source only
+		local := func(c *Config) (Contenter, error) {
+			return &FIODataObjects{Objects: c.GetAll(files).ByNode(hosts).ByName("local")}, nil
+		}
+
+		i, err := RegisterContent("local-hosts", local)
+		So(err, ShouldBeNil)
+		So(i, ShouldBeGreaterThan, AlwObj)
+		So(i.String(), ShouldEqual, "local-hosts")
+		So(Contents(), ShouldContain, i)
+
+		again, err := RegisterContent("local-hosts", local)
+		So(err, ShouldBeNil)
+		So(again, ShouldEqual, i)
+
+		for _, name := range []string{"", notknown, files, urls, allowNode} {
+			_, err = RegisterContent(name, local)
+			So(err.Error(), ShouldEqual, fmt.Sprintf("invalid content type name %q", name))
+		}
+		_, err = RegisterContent("nil", nil)
+		So(err.Error(), ShouldEqual, `content type "nil" has no ContentFunc`)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf("blacklist {\n\tdns-redirect-ip 0.0.0.0\n\thosts {\n\t\tsource local {\n\t\t\tfile %[1]v/local.txt\n\t\t\tprefix \"\"\n\t\t}\n\t\tsource other {\n\t\t\tfile %[1]v/other.txt\n\t\t\tprefix \"\"\n\t\t}\n\t}\n}", dir)}), ShouldBeNil)
+
+		ct, err := c.NewContent(i)
+		So(err, ShouldBeNil)
+		So(ct.GetList().Names(), ShouldResemble, sort.StringSlice{"local"})
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		act, err := ioutil.ReadFile(dir + "/hosts.local.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "address=/ads.local.com/0.0.0.0\n")
+
+		_, err = os.Stat(dir + "/hosts.other.blacklist.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
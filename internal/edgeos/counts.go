@@ -0,0 +1,199 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// countExt is appended to a generated file's name for its .count file
+const countExt = ".count"
+
+// fileCount records the entries written to a generated file, its hash and,
+// with an HMACKey, its HMAC
+type fileCount struct {
+	Entries int    `json:"entries"`
+	SHA256  string `json:"sha256"`
+	HMAC    string `json:"hmac,omitempty"`
+}
+
+// tally counts, hashes and, given a key, signs the lines written to it
+type tally struct {
+	h   hash.Hash
+	mac hash.Hash
+	n   int
+}
+
+func newTally(key []byte) *tally {
+	t := &tally{h: sha256.New()}
+	if key != nil {
+		t.mac = hmac.New(sha256.New, key)
+	}
+	return t
+}
+
+func (t *tally) Write(b []byte) (int, error) {
+	t.n += bytes.Count(b, []byte{'\n'})
+	if t.mac != nil {
+		t.mac.Write(b)
+	}
+	return t.h.Write(b)
+}
+
+// count returns the tallied fileCount
+func (t *tally) count() *fileCount {
+	fc := &fileCount{Entries: t.n, SHA256: hex.EncodeToString(t.h.Sum(nil))}
+	if t.mac != nil {
+		fc.HMAC = hex.EncodeToString(t.mac.Sum(nil))
+	}
+	return fc
+}
+
+// save writes the tally to file's .count file
+func (t *tally) save(file string) error {
+	b, err := json.Marshal(t.count())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file+countExt, append(b, '\n'), 0644)
+}
+
+// readCount returns the fileCount recorded for file
+func readCount(file string) (*fileCount, error) {
+	b, err := ioutil.ReadFile(file + countExt)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &fileCount{}
+	return fc, json.Unmarshal(b, fc)
+}
+
+// countFile tallies file's current contents, a missing file has none
+func countFile(file string, key []byte) (*fileCount, error) {
+	t := newTally(key)
+
+	f, err := os.Open(file)
+	switch {
+	case os.IsNotExist(err):
+		return t.count(), nil
+	case err != nil:
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(t, f); err != nil {
+		return nil, err
+	}
+	return t.count(), nil
+}
+
+// macKey returns the HMACKey, creating it if create is true, or nil if
+// HMACKey isn't set or, unless create is true, doesn't exist yet
+func (p *Parms) macKey(create bool) ([]byte, error) {
+	if p.MACKey == "" {
+		return nil, nil
+	}
+
+	k, err := ioutil.ReadFile(p.MACKey)
+	switch {
+	case os.IsNotExist(err) && !create:
+		return nil, nil
+	case os.IsNotExist(err):
+		k = make([]byte, 32)
+		if _, err = rand.Read(k); err != nil {
+			return nil, err
+		}
+		return k, ioutil.WriteFile(p.MACKey, k, 0600)
+	case err != nil:
+		return nil, err
+	case len(k) != 32:
+		return nil, fmt.Errorf("%v: key must be 32 bytes", p.MACKey)
+	}
+	return k, nil
+}
+
+// Verify checks the generated files against the .count files written with
+// them, see Counts, and returns an ErrFileChanged for each that was truncated
+// or changed since. With an HMACKey it returns an ErrTampered instead for
+// each file whose HMAC doesn't match, or that has none; otherwise files
+// without a .count file aren't checked
+func (c *CFile) Verify() error {
+	d, err := c.globFiles(c.Dir, "")
+	if err != nil {
+		return err
+	}
+
+	key, err := c.macKey(false)
+	if err != nil {
+		return err
+	}
+
+	var errs Errors
+	for _, f := range c.current(d) {
+		want, err := readCount(f)
+		switch {
+		case os.IsNotExist(err):
+			if _, err = os.Stat(f); key != nil && err == nil {
+				errs = append(errs, &ErrTampered{File: f, Reason: "has no HMAC"})
+			}
+			continue
+		case err != nil:
+			errs = append(errs, err)
+			continue
+		}
+
+		got, err := countFile(f, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		switch {
+		case key != nil && !hmac.Equal([]byte(got.HMAC), []byte(want.HMAC)):
+			reason := "failed its HMAC check"
+			if want.HMAC == "" {
+				reason = "has no HMAC"
+			}
+			errs = append(errs, &ErrTampered{File: f, Reason: reason})
+		case got.Entries != want.Entries || got.SHA256 != want.SHA256:
+			errs = append(errs, &ErrFileChanged{File: f, Want: want.Entries, Got: got.Entries})
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Discard removes a generated file, e.g. one that failed Verify, with its
+// gzip copy and .count file, so it is only served again once regenerated
+func Discard(file string) error {
+	return purgeFiles([]string{file, file + gzExt, file + countExt})
+}
+
+// staleCounts returns .count files that no longer have a current blacklist
+// file
+func (c *CFile) staleCounts(cur, counts []string) (stale []string) {
+	if !c.Counts && c.MACKey == "" {
+		return counts
+	}
+
+	current := updateEntry(cur)
+	for _, f := range counts {
+		if _, ok := current.entry[strings.TrimSuffix(f, countExt)]; !ok {
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,139 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCounts(t *testing.T) {
+	Convey("Testing Counts() and Verify()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			src = dir + "/feed.txt"
+			out = dir + "/domains.feed.blacklist.conf"
+			cfg = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+		)
+		So(ioutil.WriteFile(src, []byte("ads.example.com\ntracker.example.com\nbeacon.example.com\n"), 0644), ShouldBeNil)
+
+		run := func(opts ...Option) *Config {
+			c := NewConfig(append([]Option{
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				WCard(Wildcard{Node: "*s", Name: "*"}),
+			}, opts...)...)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+			return c
+		}
+
+		c := run(Counts(true))
+		fc, err := readCount(out)
+		So(err, ShouldBeNil)
+		So(fc.Entries, ShouldEqual, 3)
+		So(fc.SHA256, ShouldHaveLength, 64)
+
+		files := c.GetAll().Files()
+		So(files.Verify(), ShouldBeNil)
+
+		Convey("truncated files are found", func() {
+			So(ioutil.WriteFile(out, []byte("address=/.ads.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+			err := files.Verify()
+			So(err, ShouldResemble, Errors{&ErrFileChanged{File: out, Want: 3, Got: 1}})
+			So(err.Error(), ShouldEqual, out+" has 1 entries, 3 were written, it was truncated or changed outside blacklist")
+		})
+
+		Convey("changed files are found", func() {
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			b[len(b)-2] = '1'
+			So(ioutil.WriteFile(out, b, 0644), ShouldBeNil)
+			So(files.Verify().Error(), ShouldEqual, out+" was changed outside blacklist")
+		})
+
+		Convey("removed files are found", func() {
+			So(os.Remove(out), ShouldBeNil)
+			So(files.Verify(), ShouldResemble, Errors{&ErrFileChanged{File: out, Want: 3, Got: 0}})
+		})
+
+		Convey("sharded files are counted per shard", func() {
+			c := run(Counts(true), Shard(2))
+			So(c.GetAll().Files().Remove(), ShouldBeNil)
+
+			for i, n := range []int{2, 1} {
+				fc, err := readCount(shardFile(out, i))
+				So(err, ShouldBeNil)
+				So(fc.Entries, ShouldEqual, n)
+			}
+			So(c.GetAll().Files().Verify(), ShouldBeNil)
+
+			_, err := os.Stat(out + countExt)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("signed files modified outside blacklist are found", func() {
+			key := dir + "/blacklist.hmac"
+			c := run(HMACKey(key))
+			files := c.GetAll().Files()
+
+			k, err := ioutil.ReadFile(key)
+			So(err, ShouldBeNil)
+			So(k, ShouldHaveLength, 32)
+			fi, err := os.Stat(key)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+
+			fc, err := readCount(out)
+			So(err, ShouldBeNil)
+			So(fc.HMAC, ShouldHaveLength, 64)
+			So(files.Verify(), ShouldBeNil)
+
+			// a forger who rewrites the .count file can't sign the change
+			forged := []byte("address=/.example.com/10.0.0.1\n")
+			So(ioutil.WriteFile(out, forged, 0644), ShouldBeNil)
+			unsigned, err := countFile(out, nil)
+			So(err, ShouldBeNil)
+			unsigned.HMAC = fc.HMAC
+			b, err := json.Marshal(unsigned)
+			So(err, ShouldBeNil)
+			So(ioutil.WriteFile(out+countExt, b, 0644), ShouldBeNil)
+
+			err = files.Verify()
+			So(err, ShouldResemble, Errors{&ErrTampered{File: out, Reason: "failed its HMAC check"}})
+			So(err.Error(), ShouldEqual, out+" failed its HMAC check, it was modified outside blacklist")
+
+			So(os.Remove(out+countExt), ShouldBeNil)
+			So(files.Verify(), ShouldResemble, Errors{&ErrTampered{File: out, Reason: "has no HMAC"}})
+
+			So(Discard(out), ShouldBeNil)
+			_, err = os.Stat(out)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			So(files.Verify(), ShouldBeNil)
+
+			So(ioutil.WriteFile(key, []byte("short"), 0600), ShouldBeNil)
+			So(files.Verify().Error(), ShouldEqual, key+": key must be 32 bytes")
+		})
+
+		Convey(".count files are removed once counts are turned off", func() {
+			c := run()
+			So(c.GetAll().Files().Remove(), ShouldBeNil)
+
+			counts, err := filepath.Glob(dir + "/*" + countExt)
+			So(err, ShouldBeNil)
+			So(counts, ShouldBeEmpty)
+		})
+	})
+}
@@ -3,7 +3,6 @@ package edgeos
 import (
 	"bufio"
 	"bytes"
-	"fmt"
 	"io"
 	"sort"
 	"strings"
@@ -26,6 +25,7 @@ const (
 	preHost              // Pre-configured blacklisted hosts
 	root                 // Topmost root node
 	zone                 // Unused - future application
+	allow                // Whitelisted, merged into the exclusions
 )
 
 // booltoStr converts a boolean ("true" or "false") to a string equivalent
@@ -61,23 +61,53 @@ func diffArray(a, b []string) (diff sort.StringSlice) {
 	return diff
 }
 
-// formatData returns an io.Reader loaded with dnsmasq formatted data
+// formatData returns an io.Reader loaded with dnsmasq formatted data, the
+// lines are sorted and fmttr's text either side of its %v is only split once
 func formatData(fmttr string, l list) io.Reader {
-	var lines sort.StringSlice
-	l.RLock()
+	var (
+		b    strings.Builder
+		keys = make([]string, 0, len(l.entry))
+		size int
+	)
+
+	pfx, sfx := fmttr, enter
+	if i := strings.Index(fmttr, "%v"); i >= 0 {
+		pfx, sfx = fmttr[:i], fmttr[i+2:]+enter
+	}
 
+	l.RLock()
 	for k := range l.entry {
-		lines = append(lines, fmt.Sprintf(fmttr+"\n", k))
+		keys = append(keys, k)
+		size += len(k)
 	}
-
-	lines.Sort()
 	l.RUnlock()
-	return strings.NewReader(strings.Join(lines, ""))
+
+	sort.Slice(keys, func(i, j int) bool { return lessSuffixed(keys[i], keys[j], sfx) })
+
+	b.Grow(size + len(keys)*(len(pfx)+len(sfx)))
+	for _, k := range keys {
+		b.WriteString(pfx)
+		b.WriteString(k)
+		b.WriteString(sfx)
+	}
+	return strings.NewReader(b.String())
+}
+
+// lessSuffixed reports whether a+sfx sorts before b+sfx without building
+// either string, unless one is a prefix of the other
+func lessSuffixed(a, b, sfx string) bool {
+	switch {
+	case len(a) < len(b) && strings.HasPrefix(b, a):
+		return sfx < b[len(a):]+sfx
+	case len(b) < len(a) && strings.HasPrefix(a, b):
+		return a[len(b):]+sfx < sfx
+	}
+	return a < b
 }
 
 // getSeparator returns the dnsmasq conf file delimiter
 func getSeparator(node string) string {
-	if node == domains {
+	if k := kindNamed(node); k != nil && k.Wild {
 		return "/."
 	}
 	return "/"
@@ -131,52 +161,36 @@ func strToBool(s string) bool {
 
 func typeInt(n ntype) (s string) {
 	switch n {
-	case domn:
-		s = domains
-	case excDomn:
-		s = ExcDomns
-	case excHost:
-		s = ExcHosts
+	case allow:
+		s = allowNode
 	case excRoot:
 		s = ExcRoots
-	case host:
-		s = hosts
-	case preDomn:
-		s = PreDomns
-	case preHost:
-		s = PreHosts
 	case root:
 		s = rootNode
 	case unknown:
 		s = notknown
 	case zone:
 		s = zones
+	default:
+		s = typeName(n)
 	}
 	return s
 }
 
 func typeStr(s string) (n ntype) {
 	switch s {
-	case domains:
-		n = domn
-	case ExcDomns:
-		n = excDomn
-	case ExcHosts:
-		n = excHost
+	case allowNode:
+		n = allow
 	case ExcRoots:
 		n = excRoot
-	case hosts:
-		n = host
 	case notknown:
 		n = unknown
-	case PreDomns:
-		n = preDomn
-	case PreHosts:
-		n = preHost
 	case rootNode:
 		n = root
 	case zones:
 		n = zone
+	default:
+		n = nameType(s)
 	}
 	return n
 }
@@ -160,3 +160,35 @@ func TestNewWriter(t *testing.T) {
 		}
 	})
 }
+
+func TestLessSuffixed(t *testing.T) {
+	Convey("Testing lessSuffixed() sorts like the formatted lines", t, func() {
+		keys := []string{"a.com", "a.com.b", "a.co", "b.com", "a.com-b", "a", "ab.com"}
+		sfx := "/0.0.0.0\n"
+
+		exp := make([]string, len(keys))
+		for i, k := range keys {
+			exp[i] = k + sfx
+		}
+		sort.Strings(exp)
+
+		sort.Slice(keys, func(i, j int) bool { return lessSuffixed(keys[i], keys[j], sfx) })
+		for i, k := range keys {
+			So(k+sfx, ShouldEqual, exp[i])
+		}
+	})
+}
+
+func BenchmarkFormatData(b *testing.B) {
+	l := list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	for i := 0; i < 10000; i++ {
+		l.set(fmt.Sprintf("ads%d.example.com", i), 0)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := io.Copy(ioutil.Discard, formatData("address=/.%v/0.0.0.0", l)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
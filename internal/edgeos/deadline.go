@@ -0,0 +1,26 @@
+package edgeos
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDeadline is returned once a run's Deadline has passed, files that
+// weren't written yet keep their previous contents
+var ErrDeadline = errors.New("run exceeded its deadline, keeping the previous files")
+
+// context returns the run's context, which is done once its Deadline passes
+func (p *Parms) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// expired returns ErrDeadline if the run's Deadline has passed
+func (p *Parms) expired() error {
+	if p.context().Err() != nil {
+		return ErrDeadline
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeadline(t *testing.T) {
+	Convey("Testing ProcessContent() past its Deadline", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer srv.Close()
+
+		var (
+			out = dir + "/domains.slow.blacklist.conf"
+			old = "address=/.ads.example.com/0.0.0.0\n"
+		)
+		So(ioutil.WriteFile(out, []byte(old), 0644), ShouldBeNil)
+
+		c := NewConfig(
+			Deadline(50*time.Millisecond),
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Method(http.MethodGet),
+			Nodes([]string{domains}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource slow {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		ct, err := c.NewContent(URLdObj)
+		So(err, ShouldBeNil)
+
+		start := time.Now()
+		err = c.ProcessContent(ct)
+		So(time.Since(start), ShouldBeLessThan, 5*time.Second)
+		So(err, ShouldNotBeNil)
+		So(err.(Errors), ShouldContain, ErrDeadline)
+
+		b, err := ioutil.ReadFile(out)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, old)
+
+		Convey("resetting the Deadline removes it", func() {
+			c.SetOpt(Deadline(0))
+			So(c.ctx, ShouldBeNil)
+			So(c.expired(), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultsVersion is the version of the built-in default exclusions
+	defaultsVersion = 1
+	// versionTag prefixes the version line of a default exclusion list
+	versionTag = "# version:"
+)
+
+// defaultExcludes is the built-in set of safe global exclusions, it is the
+// fallback when no canonical, cached or local list can be read
+var defaultExcludes = []string{
+	"akamaihd.net",
+	"akamaized.net",
+	"apple.com",
+	"cloudfront.net",
+	"fastly.net",
+	"googleapis.com",
+	"gstatic.com",
+	"icloud.com",
+	"live.com",
+	"microsoft.com",
+	"msdn.com",
+	"mzstatic.com",
+	"office.com",
+	"paypal.com",
+	"windowsupdate.com",
+}
+
+// ExcDefaults sets where the default exclusions are read from; File is a
+// local override, URL is the canonical list and Cache keeps the last list
+// fetched from URL for use when it can't be reached
+type ExcDefaults struct {
+	Cache string `json:"Cache,omitempty"`
+	File  string `json:"File,omitempty"`
+	URL   string `json:"URL,omitempty"`
+}
+
+// Defaults is a versioned default exclusion list
+type Defaults struct {
+	Domains []string
+	Origin  string
+	Version int
+}
+
+// String returns the list in the canonical format, a version line followed
+// by one domain per line
+func (d *Defaults) String() string {
+	return fmt.Sprintf("%v %d\n%v\n", versionTag, d.Version, strings.Join(d.Domains, "\n"))
+}
+
+// parseDefaults reads a default exclusion list, the version line is required
+// so an error page can't be mistaken for a list
+func parseDefaults(r io.Reader, origin string) (*Defaults, error) {
+	var (
+		b       = bufio.NewScanner(r)
+		d       = &Defaults{Origin: origin}
+		version bool
+	)
+
+	for b.Scan() {
+		line := strings.TrimSpace(b.Text())
+		switch {
+		case strings.HasPrefix(line, versionTag):
+			v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, versionTag)))
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid version %q", origin, line)
+			}
+			d.Version, version = v, true
+
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			d.Domains = append(d.Domains, strings.ToLower(line))
+		}
+	}
+
+	if err := b.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", origin, err)
+	}
+
+	if !version {
+		return nil, fmt.Errorf("%v: missing %q line", origin, versionTag)
+	}
+
+	sort.Strings(d.Domains)
+	return d, nil
+}
+
+// readDefaults parses the default exclusion list in file
+func readDefaults(file string) (*Defaults, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDefaults(f, file)
+}
+
+// fetchDefaults downloads the canonical default exclusion list
+func (c *Config) fetchDefaults() (*Defaults, error) {
+	o := getHTTP(&object{Parms: c.Parms, name: "default excludes", url: c.DefExc.URL})
+	if o.err != nil {
+		return nil, o.err
+	}
+	return parseDefaults(o.r, c.DefExc.URL)
+}
+
+// cacheDefaults saves d to the cache file
+func (c *Config) cacheDefaults(d *Defaults) error {
+	tmp := c.DefExc.Cache + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(d.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.DefExc.Cache)
+}
+
+// defaults returns the default exclusions from, in order of preference, the
+// local override, the canonical URL, the cache or the built-in set; a
+// canonical list older than the cached one is ignored
+func (c *Config) defaults() *Defaults {
+	if c.DefExc.File != "" {
+		d, err := readDefaults(c.DefExc.File)
+		if err == nil {
+			return d
+		}
+		c.log(fmt.Sprintf("Unable to read default excludes override: %v", err))
+	}
+
+	var cached *Defaults
+	if c.DefExc.Cache != "" {
+		cached, _ = readDefaults(c.DefExc.Cache)
+	}
+
+	if c.DefExc.URL != "" && !c.Offline {
+		d, err := c.fetchDefaults()
+		switch {
+		case err != nil:
+			c.log(fmt.Sprintf("Unable to update default excludes: %v", err))
+		case cached != nil && d.Version < cached.Version:
+			c.log(fmt.Sprintf("Ignoring default excludes version %d from %v, cache has version %d", d.Version, d.Origin, cached.Version))
+		default:
+			if c.DefExc.Cache != "" {
+				if err = c.cacheDefaults(d); err != nil {
+					c.log(fmt.Sprintf("Unable to cache default excludes: %v", err))
+				}
+			}
+			return d
+		}
+	}
+
+	if cached != nil {
+		return cached
+	}
+
+	return &Defaults{
+		Domains: append([]string(nil), defaultExcludes...),
+		Origin:  "built-in",
+		Version: defaultsVersion,
+	}
+}
+
+// LoadDefaults adds the default exclusions to the global exclusions and
+// returns the list that was used
+func (c *Config) LoadDefaults() (*Defaults, error) {
+	root := c.tree[rootNode]
+	if root == nil {
+		return nil, ErrConfigEmpty
+	}
+
+	d := c.defaults()
+	exc := updateEntry(root.exc)
+	for _, domain := range d.Domains {
+		if _, ok := exc.entry[domain]; !ok {
+			root.exc = append(root.exc, domain)
+			exc.entry[domain] = 0
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,113 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseDefaults(t *testing.T) {
+	Convey("Testing parseDefaults()", t, func() {
+		tests := []struct {
+			data string
+			err  string
+			exp  *Defaults
+		}{
+			{
+				data: "# version: 7\n\nZoom.us\napple.com\n# comment\n",
+				exp:  &Defaults{Domains: []string{"apple.com", "zoom.us"}, Origin: "test", Version: 7},
+			},
+			{data: "apple.com\n", err: `test: missing "# version:" line`},
+			{data: "# version: seven\napple.com\n", err: `test: invalid version "# version: seven"`},
+		}
+
+		for _, tt := range tests {
+			d, err := parseDefaults(strings.NewReader(tt.data), "test")
+			switch tt.err {
+			case "":
+				So(err, ShouldBeNil)
+				So(d, ShouldResemble, tt.exp)
+			default:
+				So(err.Error(), ShouldEqual, tt.err)
+			}
+		}
+
+		Convey("The built-in set should match the published list", func() {
+			d, err := readDefaults("../../defaults/excludes.txt")
+			So(err, ShouldBeNil)
+			So(d.Version, ShouldEqual, defaultsVersion)
+			So(d.Domains, ShouldResemble, defaultExcludes)
+		})
+	})
+}
+
+func TestLoadDefaults(t *testing.T) {
+	Convey("Testing LoadDefaults()", t, func() {
+		version := 3
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "# version: %d\ncanonical.example.com\n", version)
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		local := dir + "/local"
+		So(ioutil.WriteFile(local, []byte("# version: 1\nlocal.example.com\n"), 0644), ShouldBeNil)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude apple.com\n}"
+		load := func(d ExcDefaults) (*Defaults, []string) {
+			c := NewConfig(Method("GET"), DefExc(d))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			def, err := c.LoadDefaults()
+			So(err, ShouldBeNil)
+			return def, c.tree[rootNode].exc
+		}
+
+		Convey("The local override should be preferred", func() {
+			d, exc := load(ExcDefaults{Cache: dir + "/cache", File: local, URL: srv.URL})
+			So(d.Origin, ShouldEqual, local)
+			So(exc, ShouldResemble, []string{"apple.com", "local.example.com"})
+		})
+
+		Convey("The canonical list should be fetched and cached", func() {
+			d, exc := load(ExcDefaults{Cache: dir + "/cache", URL: srv.URL})
+			So(d.Origin, ShouldEqual, srv.URL)
+			So(exc, ShouldResemble, []string{"apple.com", "canonical.example.com"})
+
+			b, err := ioutil.ReadFile(dir + "/cache")
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "# version: 3\ncanonical.example.com\n")
+
+			Convey("An older canonical list should be ignored", func() {
+				version = 2
+				d, _ := load(ExcDefaults{Cache: dir + "/cache", URL: srv.URL})
+				So(d.Origin, ShouldEqual, dir+"/cache")
+				So(d.Version, ShouldEqual, 3)
+			})
+
+			Convey("The cache should be used offline", func() {
+				d, _ := load(ExcDefaults{Cache: dir + "/cache", URL: "http://127.0.0.1:1/excludes.txt"})
+				So(d.Origin, ShouldEqual, dir+"/cache")
+			})
+		})
+
+		Convey("The built-in set should be the last resort", func() {
+			d, exc := load(ExcDefaults{Cache: dir + "/missing/cache", URL: "http://127.0.0.1:1/excludes.txt"})
+			So(d.Origin, ShouldEqual, "built-in")
+			So(len(exc), ShouldEqual, len(defaultExcludes))
+		})
+
+		Convey("An empty configuration should fail", func() {
+			_, err := NewConfig().LoadDefaults()
+			So(err, ShouldEqual, ErrConfigEmpty)
+		})
+	})
+}
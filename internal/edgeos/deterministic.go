@@ -0,0 +1,33 @@
+package edgeos
+
+import (
+	"hash/crc32"
+	"sort"
+)
+
+// ordered returns x sorted by source name if Determ is set, so the source a
+// domain listed by several is written under doesn't depend on their order in
+// the configuration, otherwise x as it is
+func (p *Parms) ordered(x []*object) []*object {
+	if !p.Determ {
+		return x
+	}
+
+	sorted := append([]*object(nil), x...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	return sorted
+}
+
+// entrySerial returns a checksum of entries as an RPZ zone's serial, so the
+// same entries always render the same zone
+func entrySerial(entries Entries) int64 {
+	h := crc32.NewIEEE()
+	entries(func(m MergedEntry) bool {
+		if m.Wild {
+			h.Write([]byte("*."))
+		}
+		h.Write([]byte(m.Domain + "\n"))
+		return true
+	})
+	return int64(h.Sum32())
+}
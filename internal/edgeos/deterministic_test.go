@@ -0,0 +1,99 @@
+package edgeos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeterministic(t *testing.T) {
+	Convey("Testing ProcessContent() with Deterministic", t, func() {
+		src, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(src)
+
+		So(ioutil.WriteFile(src+"/alpha.txt", []byte("shared.example.com\nalpha.example.com\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(src+"/beta.txt", []byte("beta.example.com\nshared.example.com\n"), 0644), ShouldBeNil)
+
+		source := func(name string) string {
+			return "\t\tsource " + name + " {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "/" + name + ".txt\n\t\t}\n"
+		}
+
+		// run returns the files generated from a configuration listing the
+		// sources in order
+		run := func(determ bool, order ...string) map[string]string {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n"
+			for _, name := range order {
+				cfg += source(name)
+			}
+			cfg += "\t}\n}"
+
+			c := NewConfig(
+				Deterministic(determ),
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+			)
+			defer c.SetOpt(Deterministic(false))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+
+			files, err := filepath.Glob(dir + "/*.conf")
+			So(err, ShouldBeNil)
+
+			out := make(map[string]string)
+			for _, f := range files {
+				b, err := ioutil.ReadFile(f)
+				So(err, ShouldBeNil)
+				out[filepath.Base(f)] = string(b)
+			}
+			return out
+		}
+
+		Convey("the configuration's source order decides which source a shared domain is written under", func() {
+			So(run(false, "beta", "alpha")["domains.beta.blacklist.conf"], ShouldContainSubstring, "shared.example.com")
+			So(run(false, "alpha", "beta")["domains.alpha.blacklist.conf"], ShouldContainSubstring, "shared.example.com")
+		})
+
+		Convey("deterministic runs write identical files whatever the source order", func() {
+			exp := run(true, "alpha", "beta")
+			So(exp["domains.alpha.blacklist.conf"], ShouldEqual, "address=/.alpha.example.com/0.0.0.0\naddress=/.shared.example.com/0.0.0.0\n")
+			So(exp["domains.beta.blacklist.conf"], ShouldEqual, "address=/.beta.example.com/0.0.0.0\n")
+			So(run(true, "beta", "alpha"), ShouldResemble, exp)
+		})
+	})
+}
+
+func TestEntrySerial(t *testing.T) {
+	Convey("Testing RPZ serials with Deterministic", t, func() {
+		entries := []MergedEntry{{Domain: "ads.example.com"}, {Domain: "malware.example.net", Wild: true}}
+
+		c := NewConfig(Deterministic(true))
+		defer c.SetOpt(Deterministic(false))
+
+		render := func(entries []MergedEntry) string {
+			b := new(bytes.Buffer)
+			So(WriteRPZ(b, EntriesOf(entries), ""), ShouldBeNil)
+			return b.String()
+		}
+
+		act := render(entries)
+		So(render(entries), ShouldEqual, act)
+		So(strings.SplitN(act, "\n", 4)[2], ShouldNotEqual, strings.SplitN(render(entries[:1]), "\n", 4)[2])
+		So(entrySerial(EntriesOf(entries)), ShouldNotEqual, entrySerial(EntriesOf([]MergedEntry{{Domain: "ads.example.com"}, {Domain: "malware.example.net"}})))
+	})
+}
@@ -0,0 +1,149 @@
+package edgeos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// diskSlots is how many slots a diskSet starts with, 8 MiB on disk
+const diskSlots = 1 << 20
+
+// diskSet is a set of keys kept on disk, as the 64-bit FNV-1a hashes of the
+// keys in an open addressing table, so it takes no memory per key; a hash
+// collision, which is very unlikely, makes a key look like one already set.
+// It isn't safe for concurrent use, a list's lock guards it
+type diskSet struct {
+	dir   string
+	f     *os.File
+	n     uint64
+	slots uint64
+	err   error
+}
+
+// newDiskSet returns a *diskSet of slots slots, a power of two, in an
+// unlinked temporary file in dir
+func newDiskSet(dir string, slots uint64) (*diskSet, error) {
+	f, err := spillFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.Truncate(int64(slots * 8)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &diskSet{dir: dir, f: f, slots: slots}, nil
+}
+
+// hashKey returns k's hash, never 0 as it marks an empty slot
+func hashKey(k string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	if v := h.Sum64(); v != 0 {
+		return v
+	}
+	return 1
+}
+
+// find returns h's slot and true if it is set, or the empty slot for it
+func (d *diskSet) find(h uint64) (uint64, bool, error) {
+	var b [8]byte
+	for i := h & (d.slots - 1); ; i = (i + 1) & (d.slots - 1) {
+		if _, err := d.f.ReadAt(b[:], int64(i*8)); err != nil {
+			return 0, false, err
+		}
+
+		switch binary.LittleEndian.Uint64(b[:]) {
+		case h:
+			return i, true, nil
+		case 0:
+			return i, false, nil
+		}
+	}
+}
+
+// has returns true if k is set
+func (d *diskSet) has(k string) bool {
+	_, ok, err := d.find(hashKey(k))
+	if err != nil && d.err == nil {
+		d.err = err
+	}
+	return ok
+}
+
+// add sets k, doubling the table once it is half full
+func (d *diskSet) add(k string) {
+	if d.err != nil {
+		return
+	}
+
+	if (d.n+1)*2 > d.slots {
+		if d.err = d.grow(); d.err != nil {
+			return
+		}
+	}
+
+	d.err = d.put(hashKey(k))
+}
+
+// put sets hash h
+func (d *diskSet) put(h uint64) error {
+	i, ok, err := d.find(h)
+	if err != nil || ok {
+		return err
+	}
+
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], h)
+	if _, err = d.f.WriteAt(b[:], int64(i*8)); err != nil {
+		return err
+	}
+	d.n++
+	return nil
+}
+
+// grow rehashes d into a table twice its size
+func (d *diskSet) grow() error {
+	g, err := newDiskSet(d.dir, d.slots*2)
+	if err != nil {
+		return err
+	}
+
+	var (
+		buf = make([]byte, 64<<10)
+		off int64
+	)
+	for {
+		n, err := d.f.ReadAt(buf, off)
+		for i := 0; i+8 <= n; i += 8 {
+			if h := binary.LittleEndian.Uint64(buf[i:]); h != 0 {
+				if err := g.put(h); err != nil {
+					g.f.Close()
+					return err
+				}
+			}
+		}
+		off += int64(n)
+
+		switch {
+		case err == io.EOF:
+			d.f.Close()
+			*d = *g
+			return nil
+		case err != nil:
+			g.f.Close()
+			return err
+		}
+	}
+}
+
+// failed returns the first error reading or writing d's file
+func (d *diskSet) failed() error {
+	if d == nil || d.err == nil {
+		return nil
+	}
+	return fmt.Errorf("unable to deduplicate on disk in %v: %v", d.dir, d.err)
+}
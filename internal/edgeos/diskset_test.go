@@ -0,0 +1,77 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiskSet(t *testing.T) {
+	Convey("Testing diskSet", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		Convey("keeps its keys as it grows", func() {
+			d, err := newDiskSet(dir, 4)
+			So(err, ShouldBeNil)
+
+			for i := 0; i < 100; i++ {
+				d.add(fmt.Sprintf("ads%d.example.com", i))
+			}
+			d.add("ads1.example.com")
+
+			So(d.failed(), ShouldBeNil)
+			So(d.n, ShouldEqual, 100)
+			So(d.slots, ShouldEqual, 256)
+			So(d.has("ads0.example.com"), ShouldBeTrue)
+			So(d.has("ads99.example.com"), ShouldBeTrue)
+			So(d.has("ads100.example.com"), ShouldBeFalse)
+
+			files, err := ioutil.ReadDir(dir)
+			So(err, ShouldBeNil)
+			So(files, ShouldBeEmpty)
+		})
+
+		Convey("deduplicates a spilled run's entries", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "ads.example.com\ntracker.example.com\n")
+			}))
+			defer srv.Close()
+
+			var b strings.Builder
+			c := NewConfig(
+				Cache(dir),
+				FileNameFmt("%v/%v.%v.%v"),
+				MaxMemoryMB(1),
+				Method(http.MethodGet),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				Writer(&b),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n" +
+				"\t\tinclude ads.example.com\n" +
+				"\t\tsource one {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "/1\n\t\t}\n" +
+				"\t\tsource two {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "/2\n\t\t}\n" +
+				"\t}\n}"}), ShouldBeNil)
+
+			var cts []Contenter
+			for _, iface := range []IFace{PreDObj, URLdObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				cts = append(cts, ct)
+			}
+			So(c.ProcessContent(cts...), ShouldBeNil)
+
+			So(c.Exc.disk, ShouldNotBeNil)
+			So(c.Exc.entry, ShouldBeEmpty)
+			So(b.String(), ShouldEqual, "address=/ads.example.com/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n")
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package edgeos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// gzRatio is how many times smaller than a generated file its gzip copy is
+// assumed to be, blacklist files usually compress better than this
+const gzRatio = 4
+
+// outputSize estimates the bytes o's files take for add's entries
+func (o *object) outputSize(add list) uint64 {
+	var (
+		line = uint64(len(o.Pfx+getSeparator(getType(o.nType).(string))+"/"+o.ip) + len(enter))
+		n    uint64
+	)
+
+	add.RLock()
+	for k := range add.entry {
+		n += line + uint64(len(k))
+	}
+	add.RUnlock()
+
+	if o.Gzip {
+		n += n / gzRatio
+	}
+	return n
+}
+
+// replaced returns the bytes of the files o's replace, its file or shards
+// and their gzip and .count files; they're truncated as they're rewritten so
+// their space is reused
+func (o *object) replaced() uint64 {
+	var (
+		file      = fmt.Sprintf(o.FnFmt, o.Dir, getType(o.nType).(string), o.name, o.Ext)
+		n         uint64
+		shards, _ = filepath.Glob(shardPattern(file))
+	)
+
+	for _, f := range append([]string{file}, shards...) {
+		for _, sfx := range []string{"", gzExt, countExt} {
+			if fi, err := os.Stat(f + sfx); err == nil {
+				n += uint64(fi.Size())
+			}
+		}
+	}
+	return n
+}
+
+// checkSpace fails if the files for all of a run's objs' adds won't fit in
+// Dir, so a full filesystem doesn't leave truncated files for dnsmasq to load
+func (c *Config) checkSpace(objs []*object, adds []list) error {
+	if c.ioWriter != nil || c.audit != nil || len(objs) == 0 {
+		return nil
+	}
+
+	free, err := diskFree(c.Dir)
+	if err != nil {
+		c.debug(fmt.Sprintf("unable to check free space in %v: %v", c.Dir, err))
+		return nil
+	}
+
+	var (
+		need  uint64
+		avail = free
+	)
+	for i, o := range objs {
+		need += o.outputSize(adds[i])
+		avail += o.replaced()
+	}
+
+	if need > avail {
+		return &ErrDiskSpace{Dir: c.Dir, Need: need, Avail: avail}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheckSpace(t *testing.T) {
+	Convey("Testing ProcessContent() with too little disk space", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		free := uint64(1 << 30)
+		diskFree = func(string) (uint64, error) { return free, nil }
+		defer func() { diskFree = statFree }()
+
+		var (
+			src = dir + "/feed.txt"
+			out = dir + "/domains.feed.blacklist.conf"
+			cfg = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+		)
+
+		run := func(data string) (*Status, error) {
+			So(ioutil.WriteFile(src, []byte(data), 0644), ShouldBeNil)
+
+			c := NewConfig(
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				Stats(NewStatus("")),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			return c.Status, c.ProcessContent(ct)
+		}
+
+		_, err = run("ads.example.com\ntracker.example.com\n")
+		So(err, ShouldBeNil)
+		want := "address=/.ads.example.com/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n"
+		b, err := ioutil.ReadFile(out)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, want)
+
+		Convey("the previous files are kept if the new ones won't fit", func() {
+			free = 0
+			st, err := run("ads.example.com\ntracker.example.com\nnew.example.com\n")
+			So(err, ShouldResemble, Errors{&ErrDiskSpace{Dir: dir, Need: 106, Avail: uint64(len(want))}})
+			So(err.Error(), ShouldEqual, "not enough space in "+dir+": about 106 needed, 72 available, keeping the previous files")
+			So(st.Sources[0].Error, ShouldEqual, err.Error())
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, want)
+		})
+
+		Convey("only the files being replaced count as reusable", func() {
+			So(ioutil.WriteFile(out+".old", make([]byte, 1<<10), 0644), ShouldBeNil)
+			free = 0
+			_, err := run("ads.example.com\ntracker.example.com\nnew.example.com\n")
+			So(err, ShouldResemble, Errors{&ErrDiskSpace{Dir: dir, Need: 106, Avail: uint64(len(want))}})
+		})
+
+		Convey("the space of the files being replaced is reused", func() {
+			free = 0
+			_, err := run("ads.example.com\nbig.example.com\n")
+			So(err, ShouldBeNil)
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/.big.example.com/0.0.0.0\n")
+		})
+	})
+}
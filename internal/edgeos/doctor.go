@@ -0,0 +1,243 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Check is the result of one of Doctor's environment checks
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+var (
+	// dnsmasqConf is searched for the conf-dir the blacklist is written to
+	dnsmasqConf = "/etc/dnsmasq.conf"
+	// diskFree returns the bytes free on a directory's filesystem, it is a
+	// variable so tests can replace it
+	diskFree = statFree
+	// lookPath finds the dnsmasq binary, it is a variable so tests can
+	// replace it
+	lookPath = exec.LookPath
+	// meminfo is read for the available memory
+	meminfo = "/proc/meminfo"
+	// minDisk and minMem are the free disk space and memory below which a run
+	// is likely to fail
+	minDisk uint64 = 4 << 20
+	minMem  uint64 = 32 << 20
+)
+
+// Doctor checks the environment blacklist runs in, probe is fetched to test
+// outbound HTTPS
+func (c *Config) Doctor(probe string) []*Check {
+	return []*Check{
+		checkExec("cli-shell-api", c.API, "run blacklist on EdgeOS or VyOS, or load a configuration file with -f <file>"),
+		checkExec("bash", c.Bash, "install bash at "+c.Bash),
+		c.checkDir(),
+		checkDnsmasq(),
+		c.checkConfDir(),
+		c.checkDisk(),
+		checkMem(),
+		c.checkHTTPS(probe),
+	}
+}
+
+// checkExec checks file is an executable
+func checkExec(name, file, fix string) *Check {
+	ck := &Check{Name: name, Fix: fix}
+	fi, err := os.Stat(file)
+	switch {
+	case err != nil:
+		ck.Detail = err.Error()
+	case fi.IsDir() || fi.Mode()&0111 == 0:
+		ck.Detail = file + " isn't executable"
+	default:
+		ck.OK, ck.Detail, ck.Fix = true, file, ""
+	}
+	return ck
+}
+
+// checkDir checks files can be written to the output directory
+func (c *Config) checkDir() *Check {
+	ck := &Check{Name: "output directory", Fix: "create " + c.Dir + " and make it writable, or set -dir"}
+	f, err := ioutil.TempFile(c.Dir, ".doctor")
+	if err != nil {
+		ck.Detail = err.Error()
+		return ck
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	ck.OK, ck.Detail, ck.Fix = true, c.Dir+" is writable", ""
+	return ck
+}
+
+// checkDnsmasq checks dnsmasq is installed
+func checkDnsmasq() *Check {
+	ck := &Check{Name: "dnsmasq", Fix: "install dnsmasq, or enable it with set service dns forwarding"}
+	file, err := lookPath("dnsmasq")
+	if err != nil {
+		ck.Detail = err.Error()
+		return ck
+	}
+	ck.OK, ck.Detail, ck.Fix = true, file, ""
+	return ck
+}
+
+// checkConfDir checks dnsmasq reads the output directory's files
+func (c *Config) checkConfDir() *Check {
+	ck := &Check{
+		Name: "dnsmasq conf-dir",
+		Fix:  fmt.Sprintf("add conf-dir=%v to %v, or set -dir to a directory dnsmasq reads", c.Dir, dnsmasqConf),
+	}
+
+	f, err := os.Open(dnsmasqConf)
+	if err != nil {
+		ck.Detail = err.Error()
+		return ck
+	}
+	defer f.Close()
+
+	b := bufio.NewScanner(f)
+	for b.Scan() {
+		line := strings.TrimSpace(b.Text())
+		if !strings.HasPrefix(line, "conf-dir=") {
+			continue
+		}
+
+		args := strings.Split(strings.TrimPrefix(line, "conf-dir="), ",")
+		if filepath.Clean(args[0]) == filepath.Clean(c.Dir) && confDirReads(args[1:], c.Ext) {
+			ck.OK, ck.Detail, ck.Fix = true, line, ""
+			return ck
+		}
+	}
+
+	ck.Detail = fmt.Sprintf("%v doesn't read %v/*.%v", dnsmasqConf, c.Dir, c.Ext)
+	return ck
+}
+
+// confDirReads is true if a conf-dir's extension filters let through files
+// ending in ext, "*.conf" only reads .conf files and ".bak" skips .bak files
+func confDirReads(filters []string, ext string) bool {
+	only := false
+	for _, p := range filters {
+		switch {
+		case strings.HasPrefix(p, "*"):
+			if strings.HasSuffix(ext, p[1:]) {
+				return true
+			}
+			only = true
+		case strings.HasSuffix(ext, p):
+			return false
+		}
+	}
+	return !only
+}
+
+// checkDisk checks there's room for the output
+func (c *Config) checkDisk() *Check {
+	ck := &Check{Name: "disk space", Fix: "free space on the filesystem holding " + c.Dir}
+	free, err := diskFree(c.Dir)
+	switch {
+	case err != nil:
+		ck.Detail = err.Error()
+	case free < minDisk:
+		ck.Detail = fmt.Sprintf("%v free, at least %v needed", formatSize(free), formatSize(minDisk))
+	default:
+		ck.OK, ck.Detail, ck.Fix = true, formatSize(free)+" free", ""
+	}
+	return ck
+}
+
+// statFree returns the bytes available to unprivileged users on dir's
+// filesystem
+func statFree(dir string) (uint64, error) {
+	var s syscall.Statfs_t
+	if err := syscall.Statfs(dir, &s); err != nil {
+		return 0, err
+	}
+	return uint64(s.Bavail) * uint64(s.Bsize), nil
+}
+
+// checkMem checks there's enough memory to merge the sources
+func checkMem() *Check {
+	ck := &Check{Name: "memory", Fix: "stop other services or reduce the number of sources"}
+	free, err := memAvailable()
+	switch {
+	case err != nil:
+		ck.Detail = err.Error()
+	case free < minMem:
+		ck.Detail = fmt.Sprintf("%v available, at least %v needed", formatSize(free), formatSize(minMem))
+	default:
+		ck.OK, ck.Detail, ck.Fix = true, formatSize(free)+" available", ""
+	}
+	return ck
+}
+
+// memAvailable returns the available memory in bytes, older kernels without
+// MemAvailable report MemFree
+func memAvailable() (uint64, error) {
+	b, err := ioutil.ReadFile(meminfo)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := make(map[string]uint64)
+	for _, line := range strings.Split(string(b), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(f[1], 10, 64); err == nil {
+			fields[strings.TrimSuffix(f[0], ":")] = n << 10
+		}
+	}
+
+	for _, k := range []string{"MemAvailable", "MemFree"} {
+		if n, ok := fields[k]; ok {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("no MemAvailable or MemFree in %v", meminfo)
+}
+
+// checkHTTPS checks probe can be fetched with the configured TLS settings
+func (c *Config) checkHTTPS(probe string) *Check {
+	ck := &Check{Name: "outbound HTTPS", Fix: "check the router's WAN connection, DNS servers and any -cafile or -pins settings"}
+	if c.Offline {
+		ck.OK, ck.Detail, ck.Fix = true, "skipped, -offline", ""
+		return ck
+	}
+
+	client, err := c.client()
+	if err != nil {
+		ck.Detail = err.Error()
+		return ck
+	}
+	client.Timeout = c.Timeout
+
+	resp, err := client.Head(probe)
+	if err != nil {
+		ck.Detail = err.Error()
+		return ck
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		ck.Detail = fmt.Sprintf("%v returned %v", probe, resp.Status)
+		return ck
+	}
+	ck.OK, ck.Detail, ck.Fix = true, fmt.Sprintf("%v returned %v", probe, resp.Status), ""
+	return ck
+}
@@ -0,0 +1,96 @@
+package edgeos
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDoctor(t *testing.T) {
+	Convey("Testing Doctor()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer svr.Close()
+
+		var (
+			api  = dir + "/cli-shell-api"
+			conf = dir + "/dnsmasq.conf"
+			mem  = dir + "/meminfo"
+		)
+		So(ioutil.WriteFile(api, []byte("#!/bin/sh\n"), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(conf, []byte("no-resolv\nconf-dir="+dir+"/,*.conf\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(mem, []byte("MemTotal:         250000 kB\nMemFree:           20000 kB\nMemAvailable:     120000 kB\n"), 0644), ShouldBeNil)
+
+		dnsmasqConf, meminfo = conf, mem
+		lookPath = func(string) (string, error) { return "/usr/sbin/dnsmasq", nil }
+		defer func() {
+			dnsmasqConf, lookPath, meminfo = "/etc/dnsmasq.conf", exec.LookPath, "/proc/meminfo"
+		}()
+
+		c := NewConfig(API(api), Bash(api), Dir(dir), Ext("blacklist.conf"))
+
+		Convey("a healthy environment passes every check", func() {
+			for _, ck := range c.Doctor(svr.URL) {
+				So(ck.Fix, ShouldBeEmpty)
+				So(ck.OK, ShouldBeTrue)
+			}
+		})
+
+		Convey("failures say how to fix them", func() {
+			lookPath = func(string) (string, error) {
+				return "", errors.New(`exec: "dnsmasq": executable file not found in $PATH`)
+			}
+			So(ioutil.WriteFile(conf, []byte("conf-dir=/etc/dnsmasq.d,.conf\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(mem, []byte("MemFree: 1024 kB\n"), 0644), ShouldBeNil)
+			svr.Close()
+
+			c.SetOpt(Bash(dir+"/bash"), Dir(dir+"/missing"))
+			act := make(map[string]*Check)
+			for _, ck := range c.Doctor(svr.URL) {
+				act[ck.Name] = ck
+			}
+
+			So(act["cli-shell-api"].OK, ShouldBeTrue)
+			for _, name := range []string{"bash", "output directory", "dnsmasq", "dnsmasq conf-dir", "disk space", "memory", "outbound HTTPS"} {
+				So(act[name].OK, ShouldBeFalse)
+				So(act[name].Fix, ShouldNotBeEmpty)
+			}
+			So(act["memory"].Detail, ShouldEqual, "1.0M available, at least 32.0M needed")
+			So(act["dnsmasq conf-dir"].Fix, ShouldEqual, "add conf-dir="+dir+"/missing to "+conf+", or set -dir to a directory dnsmasq reads")
+		})
+
+		Convey("the outbound HTTPS check is skipped offline", func() {
+			c.SetOpt(Offline(true))
+			So(c.checkHTTPS("https://127.0.0.1:1/"), ShouldResemble, &Check{Name: "outbound HTTPS", OK: true, Detail: "skipped, -offline"})
+		})
+	})
+}
+
+func TestConfDirReads(t *testing.T) {
+	Convey("Testing confDirReads()", t, func() {
+		tests := []struct {
+			filters []string
+			exp     bool
+		}{
+			{exp: true},
+			{filters: []string{"*.conf"}, exp: true},
+			{filters: []string{"*.hosts"}, exp: false},
+			{filters: []string{"*.hosts", "*.conf"}, exp: true},
+			{filters: []string{".bak"}, exp: true},
+			{filters: []string{".conf"}, exp: false},
+		}
+
+		for _, tt := range tests {
+			So(confDirReads(tt.filters, "blacklist.conf"), ShouldEqual, tt.exp)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package edgeos
+
+// dohDomains is the built-in set of DoH bootstrap domains, blocking these
+// stops clients from bypassing dnsmasq with their own resolvers
+var dohDomains = []string{
+	"chrome.cloudflare-dns.com",
+	"cloudflare-dns.com",
+	"dns.adguard.com",
+	"dns.cloudflare.com",
+	"dns.google",
+	"dns.google.com",
+	"dns.nextdns.io",
+	"dns.quad9.net",
+	"dns10.quad9.net",
+	"dns11.quad9.net",
+	"dns9.quad9.net",
+	"doh.cleanbrowsing.org",
+	"doh.opendns.com",
+	"doh.xfinity.com",
+	"mozilla.cloudflare-dns.com",
+	"use-application-dns.net",
+}
+
+// DoHObjects implements GetList for DNS-over-HTTPS provider domains
+type DoHObjects struct {
+	*Objects
+}
+
+// addDoH returns an object holding the DoH provider domains
+func (c *Config) addDoH() *object {
+	ip := ""
+	if _, ok := c.tree[domains]; ok {
+		ip = c.tree.getIP(domains)
+	} else if _, ok := c.tree[rootNode]; ok {
+		ip = c.tree[rootNode].ip
+	}
+
+	return &object{
+		desc:  DoHDomns + " blacklist content",
+		inc:   c.dohDomains(),
+		ip:    ip,
+		ltype: DoHDomns,
+		name:  DoHDomns + "s",
+		nType: domn,
+		Parms: c.Parms,
+		url:   c.DoHURL,
+	}
+}
+
+// dohDomains returns the configured DoH domains or the built-in set
+func (p *Parms) dohDomains() []string {
+	if p.DoHList != nil {
+		return p.DoHList
+	}
+	return append([]string(nil), dohDomains...)
+}
+
+// Find returns the int position of an Objects' element
+func (d *DoHObjects) Find(elem string) int {
+	for i, o := range d.x {
+		if o.name == elem {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetList implements the Contenter interface for DoHObjects, the canonical
+// URL takes precedence and the local set is the fallback if it can't be read
+func (d *DoHObjects) GetList() *Objects {
+	for _, o := range d.x {
+		o.Parms = d.Objects.Parms
+		if o.url != "" {
+			if getHTTP(o); o.err == nil {
+				continue
+			}
+			d.log("Unable to update " + DoHDomns + " list from " + o.url + ", using built-in set")
+			o.err = nil
+		}
+		o.r = o.includes()
+	}
+	return d.Objects
+}
+
+// Len returns how many objects there are
+func (d *DoHObjects) Len() int { return len(d.Objects.x) }
+
+// SetURL sets the Object's url field value
+func (d *DoHObjects) SetURL(name, url string) {
+	for _, o := range d.x {
+		if o.name == name {
+			o.url = url
+		}
+	}
+}
+
+func (d *DoHObjects) String() string { return d.Objects.String() }
@@ -0,0 +1,76 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDoHContent(t *testing.T) {
+	Convey("Testing DoH provider content", t, func() {
+		h := new(HTTPserver)
+		URL := h.NewHTTPServer().String()
+		h.Mux.HandleFunc("/doh.txt", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "dns.example.net\ndoh.example.org\n")
+		})
+
+		tests := []struct {
+			exp  string
+			name string
+			opts []Option
+		}{
+			{
+				name: "built-in set",
+				exp:  "address=/.use-application-dns.net/192.168.100.1",
+			},
+			{
+				name: "overridden set",
+				opts: []Option{DoHList([]string{"doh.local.lan"})},
+				exp:  "address=/.doh.local.lan/192.168.100.1",
+			},
+			{
+				name: "canonical URL",
+				opts: []Option{DoHURL(URL + "/doh.txt")},
+				exp:  "address=/.dns.example.net/192.168.100.1\naddress=/.doh.example.org/192.168.100.1",
+			},
+			{
+				name: "unreachable canonical URL falls back to the built-in set",
+				opts: []Option{DoHURL("http://127.0.0.1:808/doh.txt")},
+				exp:  "address=/.use-application-dns.net/192.168.100.1",
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("Testing "+tt.name, func() {
+				c := NewConfig(
+					Dir("/tmp"),
+					Ext("blacklist.conf"),
+					FileNameFmt("%v/%v.%v.%v"),
+					Nodes([]string{rootNode, domains, hosts}),
+					Prefix("address="),
+				)
+				c.SetOpt(tt.opts...)
+				So(c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+
+				ct, err := c.NewContent(DoHObj)
+				So(err, ShouldBeNil)
+				So(ct.Len(), ShouldEqual, 1)
+				So(ct.Find(DoHDomns+"s"), ShouldEqual, 0)
+				So(DoHObj.String(), ShouldEqual, DoHDomns)
+
+				b := ct.GetList().x[0].process()
+				So(b.file, ShouldEqual, "/tmp/domains.doh-providers.blacklist.conf")
+
+				act, err := ioutil.ReadAll(b.r)
+				So(err, ShouldBeNil)
+				So(string(act), ShouldContainSubstring, tt.exp)
+				So(strings.Count(string(act), "\n"), ShouldBeGreaterThan, 0)
+			})
+		}
+	})
+}
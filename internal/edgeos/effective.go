@@ -0,0 +1,101 @@
+package edgeos
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Effective holds the exclusions and includes the filter pipeline will
+// actually apply, keyed by node, once precedence has been resolved
+type Effective struct {
+	Excludes map[string][]string `json:"excludes"`
+	Includes map[string][]string `json:"includes"`
+}
+
+// effectiveInc returns node's includes, less any overridden by exclusions
+func (c *Config) effectiveInc(node string) []string {
+	if c.includeWins() {
+		return c.tree[node].live()
+	}
+
+	inc := make([]string, 0, len(c.tree[node].inc))
+NEXT:
+	for _, d := range c.tree[node].live() {
+		for _, exc := range append([]string{rootNode}, NodeKinds()...) {
+			if _, ok := c.excluded(exc, d); ok {
+				continue NEXT
+			}
+		}
+		inc = append(inc, d)
+	}
+	return inc
+}
+
+// Effective returns the resolved exclusions and includes for all nodes
+func (c *Config) Effective() *Effective {
+	sorted := func(s []string) []string {
+		s = append([]string{}, s...)
+		sort.Strings(s)
+		return s
+	}
+
+	e := &Effective{
+		Excludes: make(map[string][]string),
+		Includes: make(map[string][]string),
+	}
+
+	for _, node := range append([]string{rootNode}, NodeKinds()...) {
+		if c.tree[node] == nil {
+			continue
+		}
+
+		if exc := c.effectiveExc(node); len(exc) > 0 {
+			e.Excludes[node] = sorted(exc)
+		}
+
+		if node == rootNode {
+			continue
+		}
+
+		if inc := c.effectiveInc(node); len(inc) > 0 {
+			e.Includes[node] = sorted(inc)
+		}
+	}
+
+	if c.tree[allowNode] != nil {
+		if a := c.effectiveAllow(); len(a) > 0 {
+			e.Excludes[allowNode] = sorted(a)
+		}
+	}
+	return e
+}
+
+// String returns the resolved sets, one "exclude|include <node> <domain>"
+// line per entry
+func (e *Effective) String() string {
+	var s []string
+	for _, node := range append([]string{rootNode}, append(NodeKinds(), allowNode)...) {
+		for _, d := range e.Excludes[node] {
+			s = append(s, fmt.Sprintf("exclude %v %v", node, d))
+		}
+	}
+
+	for _, node := range NodeKinds() {
+		for _, d := range e.Includes[node] {
+			s = append(s, fmt.Sprintf("include %v %v", node, d))
+		}
+	}
+
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s, "\n") + "\n"
+}
+
+// WriteTo writes the resolved sets to w
+func (e *Effective) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, e.String())
+	return int64(n), err
+}
@@ -0,0 +1,67 @@
+package edgeos
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEffective(t *testing.T) {
+	Convey("Testing Effective()", t, func() {
+		cfg := `blacklist {
+    dns-redirect-ip 0.0.0.0
+    exclude example.com
+    exclude doubleclick.net
+    domains {
+        exclude adsrvr.org
+        include adsrvr.org
+        include ads.example.com
+        include kiosked.com
+    }
+    hosts {
+        include beap.gemini.yahoo.com
+        include doubleclick.net
+    }
+}`
+		tests := []struct {
+			precedence string
+			exp        string
+		}{
+			{
+				precedence: PrecedenceInclude,
+				exp: `exclude blacklist example.com
+include domains ads.example.com
+include domains adsrvr.org
+include domains kiosked.com
+include hosts beap.gemini.yahoo.com
+include hosts doubleclick.net
+`,
+			},
+			{
+				precedence: PrecedenceExclude,
+				exp: `exclude blacklist doubleclick.net
+exclude blacklist example.com
+exclude domains adsrvr.org
+include domains kiosked.com
+include hosts beap.gemini.yahoo.com
+`,
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("Testing "+tt.precedence+" precedence", func() {
+				c := NewConfig(Nodes([]string{domains, hosts}), Precedence(tt.precedence))
+				So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+				act := new(bytes.Buffer)
+				n, err := c.Effective().WriteTo(act)
+				So(err, ShouldBeNil)
+				So(n, ShouldEqual, len(tt.exp))
+				So(act.String(), ShouldEqual, tt.exp)
+			})
+		}
+
+		So(NewConfig().Effective().String(), ShouldEqual, "")
+	})
+}
@@ -0,0 +1,141 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrConfigEmpty is returned when there is no blacklist configuration to process
+	ErrConfigEmpty = errors.New("Configuration data is empty, cannot continue")
+
+	// ErrNoContent is returned when ProcessContent is called without any Contenters
+	ErrNoContent = errors.New("Empty Contenter interface{} passed to ProcessContent()")
+
+	// ErrInvalidIFace is returned when NewContent is asked for an unknown IFace
+	ErrInvalidIFace = errors.New("Invalid interface requested")
+)
+
+// ErrSourceFetch records a failure to download or read a blacklist source
+type ErrSourceFetch struct {
+	Source string
+	Cause  error
+}
+
+func (e *ErrSourceFetch) Error() string {
+	return fmt.Sprintf("%v: %v", e.Source, e.Cause)
+}
+
+// Unwrap returns the underlying cause
+func (e *ErrSourceFetch) Unwrap() error { return e.Cause }
+
+// ErrParse records the location of a configuration parse error
+type ErrParse struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ErrParse) Error() string {
+	return fmt.Sprintf("%v:%d: %v", e.File, e.Line, e.Msg)
+}
+
+// ErrDiskSpace is returned if the generated files won't fit in their
+// directory, the previous files are kept
+type ErrDiskSpace struct {
+	Dir   string
+	Need  uint64
+	Avail uint64
+}
+
+func (e *ErrDiskSpace) Error() string {
+	return fmt.Sprintf("not enough space in %v: about %v needed, %v available, keeping the previous files", e.Dir, formatSize(e.Need), formatSize(e.Avail))
+}
+
+// ErrFileChanged is returned by Verify for a generated file that no longer
+// matches what was written to it
+type ErrFileChanged struct {
+	File string
+	Want int
+	Got  int
+}
+
+func (e *ErrFileChanged) Error() string {
+	if e.Got != e.Want {
+		return fmt.Sprintf("%v has %d entries, %d were written, it was truncated or changed outside blacklist", e.File, e.Got, e.Want)
+	}
+	return fmt.Sprintf("%v was changed outside blacklist", e.File)
+}
+
+// ErrTampered is returned by Verify for a generated file whose HMAC shows it
+// was modified outside blacklist
+type ErrTampered struct {
+	File   string
+	Reason string
+}
+
+func (e *ErrTampered) Error() string {
+	return fmt.Sprintf("%v %v, it was modified outside blacklist", e.File, e.Reason)
+}
+
+// ErrAnomaly is returned if a run would add and remove more than Limit
+// percent of the previous entries, the previous files are kept
+type ErrAnomaly struct {
+	Added    int
+	Removed  int
+	Previous int
+	Limit    float64
+}
+
+// percent returns the added and removed entries as a percentage of the
+// previous ones
+func (e *ErrAnomaly) percent() float64 {
+	return float64(e.Added+e.Removed) * 100 / float64(e.Previous)
+}
+
+func (e *ErrAnomaly) Error() string {
+	return fmt.Sprintf("anomalous run: %d entries added and %d removed, %.1f%% of the previous %d, exceeds the %v%% limit, keeping the previous files, rerun with -force to allow it",
+		e.Added, e.Removed, e.percent(), e.Previous, e.Limit)
+}
+
+// ErrPopular is returned when the generated blacklist would block popular
+// domains, see Config.Popular
+type ErrPopular struct {
+	Domains []Popular
+}
+
+func (e *ErrPopular) Error() string {
+	s := make([]string, len(e.Domains))
+	for i, p := range e.Domains {
+		s[i] = p.String()
+	}
+	return fmt.Sprintf("blacklist would block popular domains: %v, rerun with -force to allow it", strings.Join(s, ", "))
+}
+
+// ErrReload records a failure to reload the dnsmasq service
+type ErrReload struct {
+	Output []byte
+	Cause  error
+}
+
+func (e *ErrReload) Error() string {
+	return fmt.Sprintf("unable to reload dnsmasq: %v", e.Cause)
+}
+
+// Unwrap returns the underlying cause
+func (e *ErrReload) Unwrap() error { return e.Cause }
+
+// Errors collects the failures from processing several sources
+type Errors []error
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// Unwrap returns the collected errors
+func (e Errors) Unwrap() []error { return e }
@@ -0,0 +1,32 @@
+package edgeos
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestErrors(t *testing.T) {
+	Convey("Testing structured errors", t, func() {
+		fetch := &ErrSourceFetch{Source: "zeus", Cause: io.EOF}
+		So(fetch.Error(), ShouldEqual, "zeus: EOF")
+		So(errors.Is(fetch, io.EOF), ShouldBeTrue)
+
+		reload := &ErrReload{Output: []byte("failed"), Cause: io.ErrUnexpectedEOF}
+		So(reload.Error(), ShouldEqual, "unable to reload dnsmasq: unexpected EOF")
+		So(errors.Is(reload, io.ErrUnexpectedEOF), ShouldBeTrue)
+
+		errs := Errors{fetch, reload}
+		So(errs.Error(), ShouldEqual, "zeus: EOF\nunable to reload dnsmasq: unexpected EOF")
+
+		var act *ErrSourceFetch
+		So(errors.As(errs, &act), ShouldBeTrue)
+		So(act.Source, ShouldEqual, "zeus")
+
+		c := NewConfig()
+		So(c.ReadCfg(&CFGstatic{Cfg: ""}), ShouldEqual, ErrConfigEmpty)
+		So(c.ProcessContent(), ShouldEqual, ErrNoContent)
+	})
+}
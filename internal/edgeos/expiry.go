@@ -0,0 +1,91 @@
+package edgeos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// expiryDate is the date only form of an include's expiry
+const expiryDate = "2006-01-02"
+
+// parseInclude splits an include value into its domain and expiry, written
+// domain@2026-10-20 or domain@2026-10-20T18:00:00Z; a date expires at the
+// start of that day, local time, and an include without one never expires
+func parseInclude(v string) (string, time.Time, error) {
+	i := strings.LastIndex(v, "@")
+	if i < 0 {
+		return normalizeDomain(v), time.Time{}, nil
+	}
+
+	d, s := normalizeDomain(v[:i]), v[i+1:]
+	if t, err := time.ParseInLocation(expiryDate, s, time.Local); err == nil {
+		return d, t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("include %q has an invalid expiry, use a date like %v or an RFC3339 time", v, expiryDate)
+	}
+	return d, t, nil
+}
+
+// expire sets when o's include d expires
+func (o *object) expire(d string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if o.expires == nil {
+		o.expires = make(map[string]time.Time)
+	}
+	o.expires[d] = t
+}
+
+// lapsed is true if o's include d has expired
+func (o *object) lapsed(d string) bool {
+	t, ok := o.expires[d]
+	return ok && !seenNow().Before(t)
+}
+
+// live returns o's includes that haven't expired
+func (o *object) live() []string {
+	if len(o.expires) == 0 {
+		return o.inc
+	}
+
+	inc := make([]string, 0, len(o.inc))
+	for _, d := range o.inc {
+		if !o.lapsed(d) {
+			inc = append(inc, d)
+		}
+	}
+	return inc
+}
+
+// Expiry is an include that expires
+type Expiry struct {
+	Node    string    `json:"node"`
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+	Expired bool      `json:"expired,omitempty"`
+}
+
+// Expiries returns the includes that expire, soonest first, expired ones
+// are no longer generated
+func (c *Config) Expiries() []Expiry {
+	var e []Expiry
+	for _, node := range c.sortKeys() {
+		o := c.tree[node]
+		for d, t := range o.expires {
+			e = append(e, Expiry{Node: node, Domain: d, Expires: t, Expired: o.lapsed(d)})
+		}
+	}
+
+	sort.Slice(e, func(i, j int) bool {
+		if !e[i].Expires.Equal(e[j].Expires) {
+			return e[i].Expires.Before(e[j].Expires)
+		}
+		return e[i].Domain < e[j].Domain
+	})
+	return e
+}
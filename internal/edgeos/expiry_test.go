@@ -0,0 +1,67 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseInclude(t *testing.T) {
+	Convey("Testing parseInclude()", t, func() {
+		tests := []struct {
+			v   string
+			d   string
+			exp time.Time
+			err bool
+		}{
+			{v: "Ads.Example.com.", d: "ads.example.com"},
+			{v: "ads.example.com@2026-10-20", d: "ads.example.com", exp: time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)},
+			{v: "ads.example.com@2026-10-20T18:00:00Z", d: "ads.example.com", exp: time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC)},
+			{v: "ads.example.com@tomorrow", err: true},
+		}
+
+		for _, tt := range tests {
+			d, exp, err := parseInclude(tt.v)
+			So(err != nil, ShouldEqual, tt.err)
+			So(d, ShouldEqual, tt.d)
+			So(exp.Equal(tt.exp), ShouldBeTrue)
+		}
+	})
+}
+
+func TestExpiry(t *testing.T) {
+	Convey("Testing include expiry", t, func() {
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		seenNow = func() time.Time { return now }
+		defer func() { seenNow = time.Now }()
+
+		c := NewConfig(Nodes([]string{domains, hosts}))
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude incident.example.com@2026-10-16T06:00:00Z\n\t\tinclude ongoing.example.com@2026-10-17T00:00:00Z\n\t\tinclude always.example.com\n\t}\n}"}), ShouldBeNil)
+
+		So(c.tree[domains].inc, ShouldResemble, []string{"incident.example.com", "ongoing.example.com", "always.example.com"})
+		So(c.tree[domains].live(), ShouldResemble, []string{"ongoing.example.com", "always.example.com"})
+		So(c.addInc(domains).inc, ShouldResemble, []string{"ongoing.example.com", "always.example.com"})
+		So(c.FWIncludes(), ShouldResemble, []string{"always.example.com", "ongoing.example.com"})
+
+		So(c.Expiries(), ShouldResemble, []Expiry{
+			{Node: domains, Domain: "incident.example.com", Expires: time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC), Expired: true},
+			{Node: domains, Domain: "ongoing.example.com", Expires: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		})
+
+		Convey("an include is dropped once its expiry passes", func() {
+			now = now.Add(12 * time.Hour)
+			So(c.tree[domains].live(), ShouldResemble, []string{"always.example.com"})
+		})
+
+		Convey("expiries survive a JSON round trip", func() {
+			b, err := json.Marshal(c.tree[domains])
+			So(err, ShouldBeNil)
+
+			o := &object{}
+			So(json.Unmarshal(b, o), ShouldBeNil)
+			So(o.live(), ShouldResemble, []string{"ongoing.example.com", "always.example.com"})
+		})
+	})
+}
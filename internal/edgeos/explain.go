@@ -0,0 +1,151 @@
+package edgeos
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Setting is an effective value and where it came from: a source or node
+// leaf, the catalog, or a default, which Flag may override
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	From  string `json:"from"`
+	Flag  string `json:"flag,omitempty"`
+}
+
+// Explained holds a node's or source's effective settings
+type Explained struct {
+	Node     string     `json:"node"`
+	Source   string     `json:"source,omitempty"`
+	Settings []*Setting `json:"settings"`
+}
+
+// inherit records that the source's setting came from elsewhere than its
+// own leaf
+func (o *object) inherit(setting, from string) {
+	if from == "" {
+		return
+	}
+	if o.origin == nil {
+		o.origin = make(map[string]string)
+	}
+	o.origin[setting] = from
+}
+
+// ipFrom names the node whose dns-redirect-ip node's sources inherit, or
+// its blocking-mode if that is nxdomain
+func (b tree) ipFrom(node string) string {
+	if b.blocking(node) == blockNXDomain {
+		if b[node] == nil || b[node].blocking == "" {
+			node = rootNode
+		}
+		return "node " + node + " " + blockingMode + " " + blockNXDomain
+	}
+
+	switch {
+	case b[node] != nil && b[node].ip != "":
+		return "node " + node
+	case b[rootNode] != nil && b[rootNode].ip != "":
+		return "node " + rootNode
+	}
+	return ""
+}
+
+// Explain returns, for each configured node and source in order, the
+// settings it runs with and whether they come from the configuration or a
+// default, to debug which one takes precedence
+func (c *Config) Explain() []*Explained {
+	var x []*Explained
+
+	for _, node := range append([]string{rootNode}, NodeKinds()...) {
+		if c.tree[node] == nil {
+			continue
+		}
+
+		ip := &Setting{Name: blackhole, Value: c.tree.getIP(node), From: c.tree.ipFrom(node)}
+		if ip.From == "" {
+			ip.From = "unset"
+		}
+		x = append(x, &Explained{Node: node, Settings: []*Setting{ip}})
+
+		if node == rootNode {
+			continue
+		}
+
+		for _, o := range c.tree.validate(node).x {
+			x = append(x, &Explained{Node: node, Source: o.name, Settings: o.explain(c.Parms)})
+		}
+	}
+	return x
+}
+
+// explain returns the source's effective settings, those it doesn't set
+// itself coming from p
+func (o *object) explain(p *Parms) []*Setting {
+	// leaf is a setting from the source's own leaf, or from where it was
+	// inherited, else from its default
+	leaf := func(name, value, def string) *Setting {
+		switch {
+		case o.origin[name] != "":
+			return &Setting{Name: name, Value: value, From: o.origin[name]}
+		case value != "":
+			return &Setting{Name: name, Value: value, From: "source"}
+		}
+		return &Setting{Name: name, Value: def, From: "default"}
+	}
+
+	size := func(n int64) string {
+		if n <= 0 {
+			return "unlimited"
+		}
+		return formatSize(uint64(n))
+	}
+
+	ip := leaf(blackhole, o.ip, "")
+	if ip.From == "default" {
+		ip.From = "unset"
+	}
+
+	maxSize := leaf("max-size", "", size(p.MaxSize))
+	if o.maxsize > 0 {
+		maxSize = leaf("max-size", size(o.maxsize), "")
+	}
+	maxSize.Flag = flagFor(maxSize, "max-size")
+
+	rate := leaf("rate-limit", "", size(p.Rate))
+	if o.rate > 0 {
+		rate = leaf("rate-limit", size(o.rate), "")
+	}
+	rate.Flag = flagFor(rate, "rate-limit")
+
+	redirs := p.Redirs
+	if redirs == 0 {
+		redirs = defaultRedirects
+	}
+
+	timeout := "none"
+	if p.Timeout > 0 {
+		timeout = p.Timeout.String()
+	}
+
+	return []*Setting{
+		ip,
+		leaf("prefix", o.prefix, ""),
+		{Name: "format", Value: p.Pfx + getSeparator(getType(o.nType).(string)) + "%v/" + o.ip, From: "default"},
+		{Name: "file", Value: fmt.Sprintf(p.FnFmt, p.Dir, getType(o.nType).(string), o.name, p.Ext), From: "default", Flag: "dir"},
+		maxSize,
+		rate,
+		leaf("redirect-policy", o.redirect, redirectFollow),
+		{Name: "redirects", Value: strconv.Itoa(redirs), From: "default", Flag: "redirects"},
+		{Name: "timeout", Value: timeout, From: "default"},
+	}
+}
+
+// flagFor returns flag if s is a default it can override
+func flagFor(s *Setting, flag string) string {
+	if s.From != "default" {
+		return ""
+	}
+	return flag
+}
@@ -0,0 +1,60 @@
+package edgeos
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExplain(t *testing.T) {
+	Convey("Testing Explain()", t, func() {
+		c := NewConfig(
+			Dir("/tmp"),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			MaxSize(20<<20),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+			Timeout(30*time.Second),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tdns-redirect-ip 192.168.1.1\n\t\tsource feed {\n\t\t\tdns-redirect-ip 10.0.0.1\n\t\t\tmax-size 1M\n\t\t\turl http://example.com/feed.txt\n\t\t}\n\t}\n\thosts {\n\t\tsource sb {\n\t\t\tcatalog stevenblack\n\t\t\tredirect-policy none\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		// settings are explained the same once the sources have been validated
+		c.GetAll()
+		x := c.Explain()
+
+		So(len(x), ShouldEqual, 5)
+		So(x[0], ShouldResemble, &Explained{Node: rootNode, Settings: []*Setting{{Name: blackhole, Value: "0.0.0.0", From: "node blacklist"}}})
+		So(x[1], ShouldResemble, &Explained{Node: domains, Settings: []*Setting{{Name: blackhole, Value: "192.168.1.1", From: "node domains"}}})
+		So(x[3], ShouldResemble, &Explained{Node: hosts, Settings: []*Setting{{Name: blackhole, Value: "0.0.0.0", From: "node blacklist"}}})
+
+		So(x[2].Source, ShouldEqual, "feed")
+		So(x[2].Settings, ShouldResemble, []*Setting{
+			{Name: blackhole, Value: "10.0.0.1", From: "source"},
+			{Name: "prefix", Value: "", From: "default"},
+			{Name: "format", Value: "address=/.%v/10.0.0.1", From: "default"},
+			{Name: "file", Value: "/tmp/domains.feed.blacklist.conf", From: "default", Flag: "dir"},
+			{Name: "max-size", Value: "1.0M", From: "source"},
+			{Name: "rate-limit", Value: "unlimited", From: "default", Flag: "rate-limit"},
+			{Name: "redirect-policy", Value: redirectFollow, From: "default"},
+			{Name: "redirects", Value: "10", From: "default", Flag: "redirects"},
+			{Name: "timeout", Value: "30s", From: "default"},
+		})
+
+		So(x[4].Source, ShouldEqual, "sb")
+		So(x[4].Settings[:2], ShouldResemble, []*Setting{
+			{Name: blackhole, Value: "0.0.0.0", From: "node blacklist"},
+			{Name: "prefix", Value: "0.0.0.0 ", From: "catalog stevenblack"},
+		})
+		So(x[4].Settings[4], ShouldResemble, &Setting{Name: "max-size", Value: "20.0M", From: "default", Flag: "max-size"})
+		So(x[4].Settings[6], ShouldResemble, &Setting{Name: "redirect-policy", Value: redirectNone, From: "source"})
+
+		Convey("with no dns-redirect-ip", func() {
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\thosts {\n\t\tsource sb {\n\t\t\turl http://example.com/hosts\n\t\t}\n\t}\n}"}), ShouldBeNil)
+			x := c.Explain()
+			So(x[1].Settings[0], ShouldResemble, &Setting{Name: blackhole, Value: "", From: "unset"})
+			So(x[2].Settings[0], ShouldResemble, &Setting{Name: blackhole, Value: "", From: "unset"})
+		})
+	})
+}
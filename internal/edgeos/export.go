@@ -0,0 +1,559 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// adGuardBegin and adGuardEnd mark the rules managed by blacklist within
+	// AdGuard Home's custom filtering rules, so the admin's own rules survive
+	adGuardBegin = "! blacklist: begin, managed by EdgeOS, do not edit"
+	adGuardEnd   = "! blacklist: end"
+)
+
+// MergedEntry is a blocked domain from the generated files, Wild is true if
+// its subdomains are also blocked
+type MergedEntry struct {
+	Domain string
+	Wild   bool
+}
+
+// Entries yields merged entries until yield returns false, it returns the
+// first error reading them
+type Entries func(yield func(MergedEntry) bool) error
+
+// mergeLess orders merged entries as the generated files are sorted, by
+// domain followed by the "/" that ends it in a dnsmasq entry
+func mergeLess(a, b string) bool {
+	return lessSuffixed(a, b, "/")
+}
+
+// mergeSection is a sorted run of entries in a generated file, from byte
+// start to end
+type mergeSection struct {
+	file       string
+	start, end int64
+}
+
+// MergedEntries finds the sorted runs of entries in the generated files and
+// returns an Entries that merges them, so exporters can stream the distinct
+// blocked domains without holding them in memory; a domain blocked as both
+// a host and a wildcard is wild
+func (c *Config) MergedEntries() (Entries, error) {
+	names, err := c.generated()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var sections []mergeSection
+	for _, name := range names {
+		s, err := sortedRuns(name)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, s...)
+	}
+
+	return func(yield func(MergedEntry) bool) error {
+		return mergeSections(sections, yield)
+	}, nil
+}
+
+// sortedRuns returns the sorted runs of entries in file, generated files
+// have one per source and one for a host source's wildcard entries
+func sortedRuns(file string) ([]mergeSection, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		r    = bufio.NewReader(f)
+		runs []mergeSection
+		s    = mergeSection{file: file}
+		prev string
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if d, _ := entryDomain(strings.TrimSuffix(line, "\n")); d != "" {
+			if prev != "" && mergeLess(d, prev) {
+				runs = append(runs, s)
+				s.start = s.end
+			}
+			prev = d
+		}
+		s.end += int64(len(line))
+
+		switch {
+		case err == io.EOF:
+			return append(runs, s), nil
+		case err != nil:
+			return nil, err
+		}
+	}
+}
+
+// mergeRun reads a mergeSection's entries
+type mergeRun struct {
+	r   *bufio.Reader
+	m   MergedEntry
+	err error
+}
+
+// next reads the run's next entry into m, it is false at the run's end
+func (m *mergeRun) next() bool {
+	for {
+		line, err := m.r.ReadString('\n')
+		if d, w := entryDomain(strings.TrimSuffix(line, "\n")); d != "" {
+			m.m = MergedEntry{Domain: d, Wild: w}
+			return true
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				m.err = err
+			}
+			return false
+		}
+	}
+}
+
+// mergeHeap is a min-heap of runs by their next entry
+type mergeHeap []*mergeRun
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return mergeLess(h[i].m.Domain, h[j].m.Domain) }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeRun)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// mergeSections yields the distinct entries of the sorted sections in
+// order, with a k-way merge that only holds each run's next entry
+func mergeSections(sections []mergeSection, yield func(MergedEntry) bool) error {
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	h := &mergeHeap{}
+	for _, s := range sections {
+		f, ok := files[s.file]
+		if !ok {
+			var err error
+			if f, err = os.Open(s.file); err != nil {
+				return err
+			}
+			files[s.file] = f
+		}
+
+		r := &mergeRun{r: bufio.NewReader(io.NewSectionReader(f, s.start, s.end-s.start))}
+		switch {
+		case r.next():
+			*h = append(*h, r)
+		case r.err != nil:
+			return r.err
+		}
+	}
+	heap.Init(h)
+
+	var (
+		cur  MergedEntry
+		have bool
+	)
+	for h.Len() > 0 {
+		r := (*h)[0]
+		m := r.m
+		switch {
+		case r.next():
+			heap.Fix(h, 0)
+		case r.err != nil:
+			return r.err
+		default:
+			heap.Pop(h)
+		}
+
+		switch {
+		case have && m.Domain == cur.Domain:
+			cur.Wild = cur.Wild || m.Wild
+			continue
+		case have && !yield(cur):
+			return nil
+		}
+		cur, have = m, true
+	}
+
+	if have {
+		yield(cur)
+	}
+	return nil
+}
+
+// EntriesOf returns an Entries over entries, for callers holding a slice
+func EntriesOf(entries []MergedEntry) Entries {
+	return func(yield func(MergedEntry) bool) error {
+		for _, m := range entries {
+			if !yield(m) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// EachMerged calls fn with each merged entry in turn, it stops at and
+// returns fn's first error
+func (c *Config) EachMerged(fn func(MergedEntry) error) error {
+	entries, err := c.MergedEntries()
+	if err != nil {
+		return err
+	}
+
+	var ferr error
+	err = entries(func(m MergedEntry) bool {
+		ferr = fn(m)
+		return ferr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return ferr
+}
+
+// Merged returns the distinct blocked domains across all generated files,
+// a domain blocked as both a host and a wildcard is wild
+func (c *Config) Merged() ([]MergedEntry, error) {
+	var merged []MergedEntry
+	err := c.EachMerged(func(m MergedEntry) error {
+		merged = append(merged, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if merged == nil {
+		merged = []MergedEntry{}
+	}
+	return merged, nil
+}
+
+// adGuardRule returns an AdGuard DNS filtering rule for an entry
+func adGuardRule(m MergedEntry) string {
+	if m.Wild {
+		return "||" + m.Domain + "^"
+	}
+	return "|" + m.Domain + "^"
+}
+
+// AdGuard pushes the merged blacklist to AdGuard Home's filtering API
+type AdGuard struct {
+	URL    string
+	User   string
+	Pass   string
+	Client *http.Client
+}
+
+// do sends an authenticated API request and decodes any JSON reply into out
+func (a *AdGuard) do(method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(a.URL, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.User != "" {
+		req.SetBasicAuth(a.User, a.Pass)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("AdGuard Home %v %v returned %v", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Push replaces the blacklist's section of AdGuard Home's custom filtering
+// rules with entries, it returns the number of rules pushed
+func (a *AdGuard) Push(entries []MergedEntry) (int, error) {
+	var status struct {
+		UserRules []string `json:"user_rules"`
+	}
+	if err := a.do(http.MethodGet, "/control/filtering/status", nil, &status); err != nil {
+		return 0, err
+	}
+
+	rules := make([]string, 0, len(status.UserRules)+len(entries)+2)
+	managed := false
+	for _, r := range status.UserRules {
+		switch {
+		case r == adGuardBegin:
+			managed = true
+		case r == adGuardEnd:
+			managed = false
+		case !managed:
+			rules = append(rules, r)
+		}
+	}
+
+	rules = append(rules, adGuardBegin)
+	for _, m := range entries {
+		rules = append(rules, adGuardRule(m))
+	}
+	rules = append(rules, adGuardEnd)
+
+	body := struct {
+		Rules []string `json:"rules"`
+	}{Rules: rules}
+	if err := a.do(http.MethodPost, "/control/filtering/set_rules", body, nil); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// WriteBlockyList writes entries to w as a blocky domain list, blocky blocks
+// each listed domain's subdomains as well
+func WriteBlockyList(w io.Writer, entries Entries) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# blacklist: generated from the EdgeOS configuration, do not edit")
+	err := entries(func(m MergedEntry) bool {
+		fmt.Fprintln(bw, m.Domain)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteBlockyConfig writes a blocky blocking configuration that loads list
+// into group and applies it to the default client group
+func WriteBlockyConfig(w io.Writer, group, list string) error {
+	_, err := fmt.Fprintf(w, "blocking:\n  blackLists:\n    %v:\n      - %q\n  clientGroupsBlock:\n    default:\n      - %v\n", group, list, group)
+	return err
+}
+
+// ABPTitle and ABPExpires are an ABP list's default title and how often
+// subscribers check it for updates
+const (
+	ABPTitle   = "EdgeOS blacklist"
+	ABPExpires = 24 * time.Hour
+)
+
+// WriteABP writes entries to w as an Adblock Plus style filter list that
+// AdGuard Home and browser extensions can subscribe to, with its Title,
+// Version and Expires headers; every entry is written as ||domain^, which
+// blocks its subdomains too. The Version changes with the entries when
+// Deterministic is set
+func WriteABP(w io.Writer, entries Entries, title string, expires time.Duration) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[Adblock Plus 2.0]\n! Title: %v\n! Version: %d\n! Expires: %v\n", title, zoneSerial(entries), abpExpiry(expires))
+	fmt.Fprintln(bw, "! blacklist: generated from the EdgeOS configuration, do not edit")
+	err := entries(func(m MergedEntry) bool {
+		fmt.Fprintf(bw, "||%v^\n", m.Domain)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// abpExpiry formats d as an ABP Expires header, in whole days or, below a
+// day, whole hours, at least one
+func abpExpiry(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%d days (update frequency)", d/(24*time.Hour))
+	}
+	if d < time.Hour {
+		d = time.Hour
+	}
+	return fmt.Sprintf("%d hours (update frequency)", d/time.Hour)
+}
+
+// zoneSerial returns the SOA serial for rendered RPZ zones and the version of
+// ABP lists, see Deterministic
+var zoneSerial = timeSerial
+
+// timeSerial returns the current time as an RPZ zone's serial
+func timeSerial(Entries) int64 { return time.Now().Unix() }
+
+// Renderers write entries in another resolver's file format, keyed by the
+// export or target format, ip is the address blocked names resolve to, which
+// defaults to 0.0.0.0 for formats that need one
+var Renderers = map[string]func(w io.Writer, entries Entries, ip string) error{
+	"abp": func(w io.Writer, entries Entries, _ string) error {
+		return WriteABP(w, entries, ABPTitle, ABPExpires)
+	},
+	"coredns":           WriteHosts,
+	"dnscrypt-blocked":  func(w io.Writer, entries Entries, _ string) error { return WriteDNSCrypt(w, entries, "") },
+	"dnscrypt-cloaking": func(w io.Writer, entries Entries, ip string) error { return WriteDNSCrypt(w, entries, ipOr(ip)) },
+	"dnsmasq":           WriteDNSmasq,
+	"domains":           func(w io.Writer, entries Entries, _ string) error { return WriteDomains(w, entries, false) },
+	"hosts":             WriteHosts,
+	"rpz":               WriteRPZ,
+	"wildcard":          func(w io.Writer, entries Entries, _ string) error { return WriteDomains(w, entries, true) },
+}
+
+// exactFormats are the Renderers formats that only match exact names
+var exactFormats = map[string]bool{"coredns": true, "domains": true, "hosts": true}
+
+// WildcardLoss returns how many of entries are wildcards format can only
+// write as their domain, leaving their subdomains unblocked
+func WildcardLoss(format string, entries Entries) (n int) {
+	if !exactFormats[format] {
+		return 0
+	}
+	entries(func(m MergedEntry) bool {
+		if m.Wild {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// WriteDNSmasq writes entries to w as a single dnsmasq configuration file
+func WriteDNSmasq(w io.Writer, entries Entries, ip string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# blacklist: generated from the EdgeOS configuration, do not edit")
+	err := entries(func(m MergedEntry) bool {
+		sep := "/"
+		if m.Wild {
+			sep = "/."
+		}
+		fmt.Fprintf(bw, "address=%v%v/%v\n", sep, m.Domain, ipOr(ip))
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteHosts writes entries to w as a hosts file, which is also the CoreDNS
+// hosts plugin's format, hosts files only match exact names, so wildcard
+// entries don't block their subdomains
+func WriteHosts(w io.Writer, entries Entries, ip string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# blacklist: generated from the EdgeOS configuration, do not edit")
+	err := entries(func(m MergedEntry) bool {
+		fmt.Fprintf(bw, "%v %v\n", ipOr(ip), m.Domain)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteDomains writes entries to w one domain per line, without a header so
+// other tools can read it as is; with wild, wildcard entries are written as
+// *.domain so their subdomains are matched
+func WriteDomains(w io.Writer, entries Entries, wild bool) error {
+	bw := bufio.NewWriter(w)
+	err := entries(func(m MergedEntry) bool {
+		if wild && m.Wild {
+			bw.WriteString("*.")
+		}
+		fmt.Fprintln(bw, m.Domain)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteRPZ writes entries to w as a DNS response policy zone, blocked names
+// get NXDOMAIN unless ip is set, wildcard entries also cover their subdomains
+func WriteRPZ(w io.Writer, entries Entries, ip string) error {
+	rr := "CNAME ."
+	if p := net.ParseIP(ip); p != nil {
+		rr = "A " + ip
+		if p.To4() == nil {
+			rr = "AAAA " + ip
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "; blacklist: generated from the EdgeOS configuration, do not edit\n$TTL 300\n@ IN SOA localhost. root.localhost. %d 3600 600 86400 300\n@ IN NS localhost.\n", zoneSerial(entries))
+	err := entries(func(m MergedEntry) bool {
+		fmt.Fprintf(bw, "%v %v\n", m.Domain, rr)
+		if m.Wild {
+			fmt.Fprintf(bw, "*.%v %v\n", m.Domain, rr)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteDNSCrypt writes entries to w as dnscrypt-proxy blocked-names rules, or
+// as cloaking rules resolving to ip if ip is set, host entries are prefixed
+// with "=" so their subdomains aren't matched
+func WriteDNSCrypt(w io.Writer, entries Entries, ip string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# blacklist: generated from the EdgeOS configuration, do not edit")
+	err := entries(func(m MergedEntry) bool {
+		d := m.Domain
+		if !m.Wild {
+			d = "=" + d
+		}
+
+		switch ip {
+		case "":
+			fmt.Fprintln(bw, d)
+		default:
+			fmt.Fprintf(bw, "%v %v\n", d, ip)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,185 @@
+package edgeos
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMerged(t *testing.T) {
+	Convey("Testing Merged() and the exporters", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+
+		files := map[string]string{
+			"domains.zeus.blacklist.conf": "address=/.zeus.com/0.0.0.0\naddress=/.malware.net/0.0.0.0\n",
+			"hosts.yoyo.blacklist.conf":   "address=/ads.yoyo.org/0.0.0.0\naddress=/zeus.com/0.0.0.0\n",
+		}
+		for f, data := range files {
+			So(ioutil.WriteFile(fmt.Sprintf("%v/%v", dir, f), []byte(data), 0644), ShouldBeNil)
+		}
+
+		merged, err := c.Merged()
+		So(err, ShouldBeNil)
+		So(merged, ShouldResemble, []MergedEntry{
+			{Domain: "ads.yoyo.org"},
+			{Domain: "malware.net", Wild: true},
+			{Domain: "zeus.com", Wild: true},
+		})
+
+		Convey("streamed with MergedEntries() and EachMerged()", func() {
+			entries, err := c.MergedEntries()
+			So(err, ShouldBeNil)
+
+			var got []MergedEntry
+			So(entries(func(m MergedEntry) bool {
+				got = append(got, m)
+				return true
+			}), ShouldBeNil)
+			So(got, ShouldResemble, merged)
+
+			got = nil
+			So(entries(func(m MergedEntry) bool {
+				got = append(got, m)
+				return false
+			}), ShouldBeNil)
+			So(got, ShouldResemble, merged[:1])
+
+			var domains []string
+			So(c.EachMerged(func(m MergedEntry) error {
+				domains = append(domains, m.Domain)
+				return nil
+			}), ShouldBeNil)
+			So(domains, ShouldResemble, []string{"ads.yoyo.org", "malware.net", "zeus.com"})
+
+			stop := errors.New("stop")
+			domains = nil
+			So(c.EachMerged(func(m MergedEntry) error {
+				domains = append(domains, m.Domain)
+				return stop
+			}), ShouldEqual, stop)
+			So(domains, ShouldResemble, []string{"ads.yoyo.org"})
+		})
+
+		Convey("merged from files with more than one sorted run", func() {
+			So(ioutil.WriteFile(dir+"/hosts.yoyo.blacklist.conf", []byte("address=/ads.yoyo.org/0.0.0.0\naddress=/zeus.com/0.0.0.0\naddress=/.ads.yoyo.org/0.0.0.0\naddress=/.b.net/0.0.0.0\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(dir+"/hosts.spam.blacklist.conf", []byte("address=/c.net/0.0.0.0\naddress=/a.net/0.0.0.0\n"), 0644), ShouldBeNil)
+
+			got, err := c.Merged()
+			So(err, ShouldBeNil)
+			So(got, ShouldResemble, []MergedEntry{
+				{Domain: "a.net"},
+				{Domain: "ads.yoyo.org", Wild: true},
+				{Domain: "b.net", Wild: true},
+				{Domain: "c.net"},
+				{Domain: "malware.net", Wild: true},
+				{Domain: "zeus.com", Wild: true},
+			})
+		})
+
+		Convey("pushed to AdGuard Home", func() {
+			var (
+				auth  bool
+				rules []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				u, p, _ := r.BasicAuth()
+				auth = u == "admin" && p == "secret"
+				switch r.URL.Path {
+				case "/control/filtering/status":
+					json.NewEncoder(w).Encode(map[string][]string{"user_rules": {"@@||good.com^", adGuardBegin, "||stale.com^", adGuardEnd, "||mine.com^"}})
+				case "/control/filtering/set_rules":
+					var body struct {
+						Rules []string `json:"rules"`
+					}
+					json.NewDecoder(r.Body).Decode(&body)
+					rules = body.Rules
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			a := &AdGuard{URL: srv.URL + "/", User: "admin", Pass: "secret"}
+			n, err := a.Push(merged)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 3)
+			So(auth, ShouldBeTrue)
+			So(rules, ShouldResemble, []string{"@@||good.com^", "||mine.com^", adGuardBegin, "|ads.yoyo.org^", "||malware.net^", "||zeus.com^", adGuardEnd})
+
+			a.URL = srv.URL + "/missing"
+			_, err = a.Push(merged)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("written for blocky", func() {
+			act := new(bytes.Buffer)
+			So(WriteBlockyList(act, EntriesOf(merged)), ShouldBeNil)
+			So(act.String(), ShouldEqual, "# blacklist: generated from the EdgeOS configuration, do not edit\nads.yoyo.org\nmalware.net\nzeus.com\n")
+
+			act.Reset()
+			So(WriteBlockyConfig(act, "edgeos", "/etc/blocky/edgeos.txt"), ShouldBeNil)
+			So(act.String(), ShouldEqual, "blocking:\n  blackLists:\n    edgeos:\n      - \"/etc/blocky/edgeos.txt\"\n  clientGroupsBlock:\n    default:\n      - edgeos\n")
+		})
+
+		Convey("with the wildcards exact formats can't write", func() {
+			So(WildcardLoss("hosts", EntriesOf(merged)), ShouldEqual, 2)
+			So(WildcardLoss("domains", EntriesOf(merged)), ShouldEqual, 2)
+			So(WildcardLoss("dnsmasq", EntriesOf(merged)), ShouldEqual, 0)
+			So(WildcardLoss("rpz", EntriesOf(merged)), ShouldEqual, 0)
+		})
+
+		Convey("rendered for each target", func() {
+			serial := zoneSerial
+			zoneSerial = func(Entries) int64 { return 1 }
+			defer func() { zoneSerial = serial }()
+
+			const hdr = "# blacklist: generated from the EdgeOS configuration, do not edit\n"
+			tests := []struct {
+				target string
+				ip     string
+				exp    string
+			}{
+				{target: "coredns", ip: "0.0.0.0", exp: hdr + "0.0.0.0 ads.yoyo.org\n0.0.0.0 malware.net\n0.0.0.0 zeus.com\n"},
+				{target: "dnscrypt-blocked", ip: "0.0.0.0", exp: hdr + "=ads.yoyo.org\nmalware.net\nzeus.com\n"},
+				{target: "dnscrypt-cloaking", ip: "", exp: hdr + "=ads.yoyo.org 0.0.0.0\nmalware.net 0.0.0.0\nzeus.com 0.0.0.0\n"},
+				{target: "dnsmasq", ip: "", exp: hdr + "address=/ads.yoyo.org/0.0.0.0\naddress=/.malware.net/0.0.0.0\naddress=/.zeus.com/0.0.0.0\n"},
+				{target: "hosts", ip: "192.168.1.1", exp: hdr + "192.168.1.1 ads.yoyo.org\n192.168.1.1 malware.net\n192.168.1.1 zeus.com\n"},
+				{target: "rpz", ip: "::1", exp: "; blacklist: generated from the EdgeOS configuration, do not edit\n$TTL 300\n@ IN SOA localhost. root.localhost. 1 3600 600 86400 300\n@ IN NS localhost.\nads.yoyo.org AAAA ::1\nmalware.net AAAA ::1\n*.malware.net AAAA ::1\nzeus.com AAAA ::1\n*.zeus.com AAAA ::1\n"},
+				{target: "dnscrypt-cloaking", ip: "192.168.1.1", exp: hdr + "=ads.yoyo.org 192.168.1.1\nmalware.net 192.168.1.1\nzeus.com 192.168.1.1\n"},
+			}
+
+			for _, tt := range tests {
+				act := new(bytes.Buffer)
+				So(Renderers[tt.target](act, EntriesOf(merged), tt.ip), ShouldBeNil)
+				So(act.String(), ShouldEqual, tt.exp)
+			}
+		})
+	})
+}
+
+func TestABPExpiry(t *testing.T) {
+	Convey("Testing abpExpiry()", t, func() {
+		So(abpExpiry(ABPExpires), ShouldEqual, "1 days (update frequency)")
+		So(abpExpiry(72*time.Hour), ShouldEqual, "3 days (update frequency)")
+		So(abpExpiry(6*time.Hour), ShouldEqual, "6 hours (update frequency)")
+		So(abpExpiry(time.Minute), ShouldEqual, "1 hours (update frequency)")
+	})
+}
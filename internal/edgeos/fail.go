@@ -0,0 +1,170 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrNoFailFile is returned if MaxFailures is set without a FailFile
+var ErrNoFailFile = errors.New("max-failures needs a fail file to remember failed fetches between runs")
+
+// errBenched marks a source skipped because it is auto-disabled
+var errBenched = errors.New("auto-disabled")
+
+// failures records a source's consecutive failed fetches and whether it was
+// auto-disabled because of them
+type failures struct {
+	Count    int    `json:"count"`
+	Disabled bool   `json:"disabled,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// failDB records each fetched source's failures, keyed by node and name
+type failDB struct {
+	sync.Mutex
+	file   string
+	source map[string]failures
+}
+
+// AutoDisabled is a source skipped after MaxFailures consecutive failed
+// fetches, until a manual retry succeeds
+type AutoDisabled struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Failures int    `json:"failures"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (a AutoDisabled) String() string {
+	return fmt.Sprintf("%v/%v was auto-disabled after %d failed fetches, the last with: %v", a.Type, a.Name, a.Failures, a.Error)
+}
+
+// key returns the node and name identifying o between runs
+func (o *object) key() string {
+	return getType(o.nType).(string) + "/" + o.name
+}
+
+// loadFails reads the fail file, a missing file is an empty one
+func (p *Parms) loadFails() error {
+	if p.MaxFail <= 0 || p.fails != nil {
+		return nil
+	}
+	if p.FailDB == "" {
+		return ErrNoFailFile
+	}
+
+	f := &failDB{file: p.FailDB, source: make(map[string]failures)}
+	b, err := ioutil.ReadFile(p.FailDB)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if err = json.Unmarshal(b, &f.source); err != nil {
+			return err
+		}
+	}
+
+	p.fails = f
+	return nil
+}
+
+// counts is true for the sources whose failed fetches are counted
+func (o *object) counts() bool {
+	return o.fails != nil && !o.Offline && !o.nType.isExc() && (o.ltype == files || o.ltype == urls)
+}
+
+// benched is true if o is auto-disabled and isn't being retried by hand
+func (o *object) benched() bool {
+	if !o.counts() || o.retry {
+		return false
+	}
+
+	return o.fails.disabled(o.key())
+}
+
+// disabled is true if the source with key is auto-disabled
+func (f *failDB) disabled(key string) bool {
+	if f == nil {
+		return false
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	return f.source[key].Disabled
+}
+
+// tally records the outcome of o's fetch, a success clears its failures; it
+// is true if this failure auto-disabled o
+func (o *object) tally() bool {
+	if !o.counts() {
+		return false
+	}
+
+	o.fails.Lock()
+	defer o.fails.Unlock()
+
+	if o.err == nil {
+		delete(o.fails.source, o.key())
+		return false
+	}
+
+	f := o.fails.source[o.key()]
+	f.Count++
+	f.Error = o.err.Error()
+	disabled := !f.Disabled && f.Count >= o.MaxFail
+	f.Disabled = f.Disabled || disabled
+	o.fails.source[o.key()] = f
+	return disabled
+}
+
+// save writes the fail file
+func (f *failDB) save() error {
+	if f == nil {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	b, err := json.Marshal(f.source)
+	if err != nil {
+		return err
+	}
+
+	tmp := f.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.file)
+}
+
+// AutoDisabled returns the configured sources that are auto-disabled
+func (c *Config) AutoDisabled() []AutoDisabled {
+	if c.fails == nil {
+		return nil
+	}
+
+	c.fails.Lock()
+	defer c.fails.Unlock()
+
+	var d []AutoDisabled
+	for _, o := range c.GetAll(files, urls).x {
+		if f := c.fails.source[o.key()]; f.Disabled {
+			d = append(d, AutoDisabled{Name: o.name, Type: getType(o.nType).(string), Failures: f.Count, Error: f.Error})
+		}
+	}
+
+	sort.Slice(d, func(i, j int) bool {
+		if d[i].Type != d[j].Type {
+			return d[i].Type < d[j].Type
+		}
+		return d[i].Name < d[j].Name
+	})
+	return d
+}
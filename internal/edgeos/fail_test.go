@@ -0,0 +1,121 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMaxFailures(t *testing.T) {
+	Convey("Testing auto-disabling repeatedly failing sources", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			mu    sync.Mutex
+			up    bool
+			fetch int
+			fails = dir + "/fails.json"
+			out   = dir + "/domains.feed.blacklist.conf"
+		)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			fetch++
+			ok := up
+			mu.Unlock()
+
+			if !ok {
+				panic(http.ErrAbortHandler)
+			}
+			fmt.Fprint(w, "ads.example.com\n")
+		}))
+		defer srv.Close()
+
+		fetched := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return fetch
+		}
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "/feed.txt\n\t\t}\n\t}\n}"
+		newConfig := func() *Config {
+			c := NewConfig(
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FailFile(fails),
+				FileNameFmt("%v/%v.%v.%v"),
+				MaxFailures(2),
+				Method("GET"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			return c
+		}
+
+		run := func() (*Config, error) {
+			c := newConfig()
+			ct, err := c.NewContent(URLdObj)
+			So(err, ShouldBeNil)
+			return c, c.ProcessContent(ct)
+		}
+
+		c, err := run()
+		So(err, ShouldNotBeNil)
+		So(c.AutoDisabled(), ShouldBeNil)
+
+		c, err = run()
+		So(err, ShouldNotBeNil)
+		disabled := c.AutoDisabled()
+		So(len(disabled), ShouldEqual, 1)
+		So(disabled[0].Name, ShouldEqual, "feed")
+		So(disabled[0].Failures, ShouldEqual, 2)
+		So(fetched(), ShouldEqual, 2)
+
+		Convey("an auto-disabled source is skipped, keeping its previous file", func() {
+			So(ioutil.WriteFile(out, []byte("address=/.old.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+			c, err = run()
+			So(err, ShouldBeNil)
+			So(fetched(), ShouldEqual, 2)
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "address=/.old.example.com/0.0.0.0\n")
+		})
+
+		Convey("a failed manual retry keeps it disabled", func() {
+			c = newConfig()
+			So(c.Retry("feed"), ShouldNotBeNil)
+			So(fetched(), ShouldEqual, 3)
+			So(len(c.AutoDisabled()), ShouldEqual, 1)
+		})
+
+		Convey("a successful manual retry enables it again", func() {
+			mu.Lock()
+			up = true
+			mu.Unlock()
+			c = newConfig()
+			So(c.Update(domains, "feed"), ShouldBeNil)
+			So(c.AutoDisabled(), ShouldBeNil)
+
+			c, err = run()
+			So(err, ShouldBeNil)
+			So(fetched(), ShouldEqual, 4)
+		})
+
+		Convey("MaxFailures needs a FailFile", func() {
+			c := NewConfig(Nodes([]string{domains}), MaxFailures(2))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			ct, err := c.NewContent(URLdObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldEqual, ErrNoFailFile)
+		})
+	})
+}
@@ -0,0 +1,199 @@
+package edgeos
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// lookupHost resolves a host name with r, it is a variable so tests can
+	// fake DNS
+	lookupHost = func(r *net.Resolver, host string) ([]string, error) {
+		return r.LookupHost(context.Background(), host)
+	}
+	// dnsmasqResolv is the resolv-file EdgeOS's dnsmasq reads its upstream
+	// servers from when it has no server= lines
+	dnsmasqResolv = "/etc/resolv.conf.dnsmasq"
+)
+
+// FWGroup is an EdgeOS firewall address-group populated from resolved
+// domains, Resolver looks them up or the system resolver if it is nil
+type FWGroup struct {
+	Name     string
+	Domains  []string
+	Errs     []error
+	Resolver *net.Resolver
+	ipv4     list
+	ipv6     list
+}
+
+// NewFWGroup returns a *FWGroup for the given domains
+func NewFWGroup(name string, domains []string) *FWGroup {
+	return &FWGroup{
+		Name:    name,
+		Domains: domains,
+		ipv4:    updateEntry(nil),
+		ipv6:    updateEntry(nil),
+	}
+}
+
+// FWIncludes returns the configured include domains for all nodes, these are
+// the curated subset usually worth bridging into packet filtering
+func (c *Config) FWIncludes() []string {
+	var inc []string
+	for _, node := range c.Nodes() {
+		if node == allowNode {
+			continue
+		}
+		inc = append(inc, c.tree[node].live()...)
+	}
+	sort.Strings(inc)
+	return inc
+}
+
+// upstreams returns the upstream DNS servers as host:port from dnsmasq's
+// server= lines and nameserver lines in files, leaving out loopback
+// addresses, which are the router's own dnsmasq, and per-domain servers
+func upstreams(files ...string) []string {
+	var servers []string
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			l := strings.TrimSpace(s.Text())
+			var addr string
+			switch {
+			case strings.HasPrefix(l, "server="):
+				addr = strings.TrimPrefix(l, "server=")
+			case strings.HasPrefix(l, "nameserver"):
+				addr = strings.TrimSpace(strings.TrimPrefix(l, "nameserver"))
+			default:
+				continue
+			}
+
+			host, port := addr, "53"
+			if i := strings.Index(addr, "#"); i >= 0 {
+				host, port = addr[:i], addr[i+1:]
+			}
+			if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+				servers = append(servers, net.JoinHostPort(host, port))
+			}
+		}
+		f.Close()
+	}
+	return servers
+}
+
+// Upstream returns a resolver that bypasses the router's dnsmasq, which
+// answers blocked domains with the blackhole address: the Resolver if one is
+// set, otherwise the upstream servers dnsmasq forwards to
+func (c *Config) Upstream() (*net.Resolver, error) {
+	if c.Resolv != "" {
+		dial, err := c.resolverDial()
+		if err != nil {
+			return nil, err
+		}
+		return &net.Resolver{PreferGo: true, Dial: dial}, nil
+	}
+
+	servers := upstreams(dnsmasqConf, dnsmasqResolv)
+	if servers == nil {
+		return nil, fmt.Errorf("no upstream DNS servers found in %v or %v, set a resolver", dnsmasqConf, dnsmasqResolv)
+	}
+
+	return &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var (
+			conn net.Conn
+			d    net.Dialer
+			err  error
+		)
+		for _, s := range servers {
+			if conn, err = d.DialContext(ctx, network, s); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}}, nil
+}
+
+// Resolve looks up each domain and records its IPv4 and IPv6 addresses,
+// failed lookups are kept in Errs and don't stop the remaining domains
+func (f *FWGroup) Resolve() *FWGroup {
+	for _, d := range f.Domains {
+		addrs, err := lookupHost(f.Resolver, d)
+		if err != nil {
+			f.Errs = append(f.Errs, err)
+			continue
+		}
+
+		for _, a := range addrs {
+			ip := net.ParseIP(a)
+			switch {
+			case ip == nil:
+				continue
+			case ip.To4() != nil:
+				f.ipv4.entry[ip.String()] = 0
+			default:
+				f.ipv6.entry[ip.String()] = 0
+			}
+		}
+	}
+	return f
+}
+
+// AddIPs adds IP addresses to the group, e.g. raw IP entries from sources
+func (f *FWGroup) AddIPs(ips ...string) *FWGroup {
+	for _, a := range ips {
+		ip := net.ParseIP(a)
+		switch {
+		case ip == nil:
+			continue
+		case ip.To4() != nil:
+			f.ipv4.entry[ip.String()] = 0
+		default:
+			f.ipv6.entry[ip.String()] = 0
+		}
+	}
+	return f
+}
+
+// String returns the EdgeOS configuration commands for the address-group
+func (f *FWGroup) String() string {
+	var s []string
+	group := func(kind, name string, ips list) {
+		if len(ips.entry) == 0 {
+			return
+		}
+		s = append(s,
+			fmt.Sprintf("delete firewall group %v %v", kind, name),
+			fmt.Sprintf("set firewall group %v %v description %q", kind, name, "blacklist resolved addresses"),
+		)
+		for _, ip := range ips.keys() {
+			s = append(s, fmt.Sprintf("set firewall group %v %v address %v", kind, name, ip))
+		}
+	}
+
+	group("address-group", f.Name, f.ipv4)
+	group("ipv6-address-group", f.Name+"-v6", f.ipv6)
+
+	if s == nil {
+		return ""
+	}
+	return strings.Join(s, "\n") + "\n"
+}
+
+// WriteTo writes the EdgeOS configuration commands for the address-group to w
+func (f *FWGroup) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, f.String())
+	return int64(n), err
+}
@@ -0,0 +1,80 @@
+package edgeos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFWGroup(t *testing.T) {
+	Convey("Testing FWGroup", t, func() {
+		orig := lookupHost
+		defer func() { lookupHost = orig }()
+
+		lookupHost = func(_ *net.Resolver, host string) ([]string, error) {
+			switch host {
+			case "adsrvr.org":
+				return []string{"192.0.2.10", "2001:db8::10", "192.0.2.1"}, nil
+			case "doubleclick.net":
+				return []string{"192.0.2.1"}, nil
+			}
+			return nil, errors.New("no such host " + host)
+		}
+
+		c := NewConfig(Nodes([]string{rootNode, domains, hosts}))
+		So(c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+		So(len(c.FWIncludes()), ShouldEqual, 10)
+
+		f := NewFWGroup("BLACKLIST", []string{"adsrvr.org", "doubleclick.net", "nxdomain.example"}).Resolve()
+		So(len(f.Errs), ShouldEqual, 1)
+
+		exp := `delete firewall group address-group BLACKLIST
+set firewall group address-group BLACKLIST description "blacklist resolved addresses"
+set firewall group address-group BLACKLIST address 192.0.2.1
+set firewall group address-group BLACKLIST address 192.0.2.10
+delete firewall group ipv6-address-group BLACKLIST-v6
+set firewall group ipv6-address-group BLACKLIST-v6 description "blacklist resolved addresses"
+set firewall group ipv6-address-group BLACKLIST-v6 address 2001:db8::10
+`
+		act := new(bytes.Buffer)
+		n, err := f.WriteTo(act)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, len(exp))
+		So(act.String(), ShouldEqual, exp)
+
+		So(NewFWGroup("EMPTY", nil).Resolve().String(), ShouldEqual, "")
+	})
+}
+
+func TestUpstream(t *testing.T) {
+	Convey("Testing Upstream()", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		origConf, origResolv := dnsmasqConf, dnsmasqResolv
+		defer func() { dnsmasqConf, dnsmasqResolv = origConf, origResolv }()
+		dnsmasqConf, dnsmasqResolv = dir+"/dnsmasq.conf", dir+"/resolv.conf.dnsmasq"
+
+		c := NewConfig()
+		_, err := c.Upstream()
+		So(err, ShouldNotBeNil)
+
+		So(ioutil.WriteFile(dnsmasqConf, []byte("conf-dir=/etc/dnsmasq.d\nserver=127.0.0.1#5353\nserver=/lan/192.168.1.1\nserver=9.9.9.9\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dnsmasqResolv, []byte("nameserver 127.0.0.1\nnameserver 2620:fe::fe\nnameserver 149.112.112.112#5353\n"), 0644), ShouldBeNil)
+		So(upstreams(dnsmasqConf, dnsmasqResolv), ShouldResemble, []string{"9.9.9.9:53", "[2620:fe::fe]:53", "149.112.112.112:5353"})
+
+		r, err := c.Upstream()
+		So(err, ShouldBeNil)
+		So(r.PreferGo, ShouldBeTrue)
+
+		c.SetOpt(Resolver("dns.example.com"))
+		_, err = c.Upstream()
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,114 @@
+package edgeos
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in ../testdata/golden with the current output")
+
+// goldenDir holds the expected output of each renderer
+const goldenDir = "../testdata/golden"
+
+// golden compares act to the golden file name, or rewrites it with -update
+func golden(name string, act []byte) {
+	file := filepath.Join(goldenDir, name+".golden")
+	if *update {
+		So(os.MkdirAll(goldenDir, 0755), ShouldBeNil)
+		So(ioutil.WriteFile(file, act, 0644), ShouldBeNil)
+		return
+	}
+
+	exp, err := ioutil.ReadFile(file)
+	So(err, ShouldBeNil)
+	So(string(act), ShouldEqual, string(exp))
+}
+
+func TestGoldenRenderers(t *testing.T) {
+	Convey("Testing Renderers against their golden files", t, func() {
+		serial := zoneSerial
+		zoneSerial = func(Entries) int64 { return 1 }
+		defer func() { zoneSerial = serial }()
+
+		entries := []MergedEntry{
+			{Domain: "ads.example.com"},
+			{Domain: "malware.example.net", Wild: true},
+			{Domain: "tracker.example.org"},
+			{Domain: "xn--80ak6aa92e.com", Wild: true},
+		}
+
+		targets := make([]string, 0, len(Renderers))
+		for target := range Renderers {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+
+		ips := []struct{ name, ip string }{
+			{name: "default"},
+			{name: "ipv4", ip: "192.168.1.1"},
+			{name: "ipv6", ip: "::1"},
+		}
+
+		for _, target := range targets {
+			for _, ip := range ips {
+				act := new(bytes.Buffer)
+				So(Renderers[target](act, EntriesOf(entries), ip.ip), ShouldBeNil)
+				golden(target+"."+ip.name, act.Bytes())
+			}
+		}
+
+		act := new(bytes.Buffer)
+		So(WriteBlockyList(act, EntriesOf(entries)), ShouldBeNil)
+		golden("blocky.list", act.Bytes())
+
+		act.Reset()
+		So(WriteBlockyConfig(act, "edgeos", "/etc/blocky/edgeos.txt"), ShouldBeNil)
+		golden("blocky.config", act.Bytes())
+	})
+}
+
+func TestGoldenConf(t *testing.T) {
+	Convey("Testing generated dnsmasq files against their golden files", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		So(ioutil.WriteFile(dir+"/domains.txt", []byte("# domains\nads.example.com\nmalware.example.net\nexcluded.example.com\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/hosts.txt", []byte("0.0.0.0 tracker.example.org\n0.0.0.0 beacon.example.org # inline comment\n127.0.0.1 localhost\n"), 0644), ShouldBeNil)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\texclude excluded.example.com\n\t\tinclude included.example.com\n" +
+			"\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + dir + "/domains.txt\n\t\t}\n\t}\n" +
+			"\thosts {\n\t\tdns-redirect-ip 192.168.1.1\n\t\tinclude host.example.com\n" +
+			"\t\tsource feed {\n\t\t\tprefix \"0.0.0.0 \"\n\t\t\tfile " + dir + "/hosts.txt\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		for _, iface := range []IFace{ExDmObj, ExHtObj, PreDObj, PreHObj, FileObj} {
+			ct, err := c.NewContent(iface)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+		}
+
+		files, err := filepath.Glob(dir + "/*.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(files, ShouldNotBeEmpty)
+
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			So(err, ShouldBeNil)
+			golden("dnsmasq."+filepath.Base(f), b)
+		}
+	})
+}
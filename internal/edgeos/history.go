@@ -0,0 +1,425 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyHeader names the columns of a CSV history file
+var historyHeader = []string{"time", "ok", "sources", "failed", "entries", "quarantined", "bytes", "lines", "seconds", "failures", "error"}
+
+// HistoryRun is one run's metrics, as recorded in the history file
+type HistoryRun struct {
+	Time     time.Time `json:"time"`
+	OK       bool      `json:"ok"`
+	Sources  int       `json:"sources"`
+	Failed   int       `json:"failed"`
+	Entries  int       `json:"entries"`
+	Held     int       `json:"quarantined"`
+	Bytes    int64     `json:"bytes"`
+	Lines    int64     `json:"lines"`
+	Seconds  float64   `json:"seconds"`
+	Failures []string  `json:"failures,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// isCSV is true if the history file is written as CSV rather than JSON lines
+func isCSV(file string) bool {
+	return strings.HasSuffix(strings.ToLower(file), ".csv")
+}
+
+// historyRun returns the run's metrics from its run state, err is the
+// run's outcome
+func (c *Config) historyRun(err error) HistoryRun {
+	n := c.state.Counts()
+	h := HistoryRun{
+		Time:    seenNow().UTC().Truncate(time.Second),
+		OK:      err == nil,
+		Sources: n.Sources,
+		Failed:  n.Failed,
+		Entries: n.Entries,
+		Held:    n.Held,
+		Bytes:   n.Bytes,
+		Lines:   n.Lines,
+		Seconds: n.Elapsed.Round(time.Millisecond).Seconds(),
+	}
+	for name := range n.Errors {
+		h.Failures = append(h.Failures, name)
+	}
+	sort.Strings(h.Failures)
+	if err != nil {
+		h.Error = err.Error()
+	}
+	return h
+}
+
+// RecordHistory appends the run's metrics to the history file, if one is
+// set, err is the run's outcome. Runs older than HistoryDays are dropped,
+// rewriting the file, so it stays small enough for a router's flash; lines
+// that don't parse are dropped the same way, so they can't block recording
+func (c *Config) RecordHistory(err error) error {
+	if c.Hist == "" {
+		return nil
+	}
+
+	runs, bad, rerr := readHistory(c.Hist)
+	if rerr != nil {
+		return rerr
+	}
+
+	h := c.historyRun(err)
+	kept := runs
+	if c.HistDay > 0 {
+		kept = retain(runs, h.Time.AddDate(0, 0, -c.HistDay))
+	}
+
+	if len(kept) < len(runs) || bad > 0 {
+		b, err := encodeHistory(c.Hist, append(kept, h), true)
+		if err != nil {
+			return err
+		}
+		_, err = replaceFile(c.Hist, b)
+		return err
+	}
+
+	b, err := encodeHistory(c.Hist, []HistoryRun{h}, len(runs) == 0)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(c.Hist, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// retain returns the runs at or after cutoff
+func retain(runs []HistoryRun, cutoff time.Time) []HistoryRun {
+	var kept []HistoryRun
+	for _, h := range runs {
+		if !h.Time.Before(cutoff) {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// encodeHistory returns runs in file's format, a CSV file starts with its
+// header if header is true
+func encodeHistory(file string, runs []HistoryRun, header bool) ([]byte, error) {
+	var b bytes.Buffer
+	if !isCSV(file) {
+		enc := json.NewEncoder(&b)
+		for _, h := range runs {
+			if err := enc.Encode(h); err != nil {
+				return nil, err
+			}
+		}
+		return b.Bytes(), nil
+	}
+
+	w := csv.NewWriter(&b)
+	if header {
+		w.Write(historyHeader)
+	}
+	for _, h := range runs {
+		w.Write([]string{
+			h.Time.Format(time.RFC3339),
+			strconv.FormatBool(h.OK),
+			strconv.Itoa(h.Sources),
+			strconv.Itoa(h.Failed),
+			strconv.Itoa(h.Entries),
+			strconv.Itoa(h.Held),
+			strconv.FormatInt(h.Bytes, 10),
+			strconv.FormatInt(h.Lines, 10),
+			strconv.FormatFloat(h.Seconds, 'f', -1, 64),
+			strings.Join(h.Failures, ";"),
+			h.Error,
+		})
+	}
+	w.Flush()
+	return b.Bytes(), w.Error()
+}
+
+// ReadHistory returns the runs recorded in a history file, oldest first, a
+// missing file has none; lines that don't parse, e.g. one cut short by a
+// power cut, are skipped
+func ReadHistory(file string) ([]HistoryRun, error) {
+	runs, _, err := readHistory(file)
+	return runs, err
+}
+
+// readHistory is ReadHistory, also returning how many lines were skipped
+func readHistory(file string) ([]HistoryRun, int, error) {
+	b, err := ioutil.ReadFile(file)
+	switch {
+	case os.IsNotExist(err):
+		return nil, 0, nil
+	case err != nil:
+		return nil, 0, err
+	}
+
+	var (
+		runs []HistoryRun
+		bad  int
+	)
+	if isCSV(file) {
+		runs, bad, err = decodeCSV(bytes.NewReader(b))
+	} else {
+		runs, bad, err = decodeJSONL(bytes.NewReader(b))
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%v: %v", file, err)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+	return runs, bad, nil
+}
+
+// decodeJSONL reads one run per line, it returns how many lines it skipped
+func decodeJSONL(r io.Reader) ([]HistoryRun, int, error) {
+	var (
+		runs []HistoryRun
+		bad  int
+		br   = bufio.NewReader(r)
+	)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var h HistoryRun
+			if json.Unmarshal(line, &h) != nil {
+				bad++
+			} else {
+				runs = append(runs, h)
+			}
+		}
+
+		switch {
+		case err == io.EOF:
+			return runs, bad, nil
+		case err != nil:
+			return nil, 0, err
+		}
+	}
+}
+
+// decodeCSV reads one run per record, after the header, it returns how
+// many records it skipped
+func decodeCSV(r io.Reader) ([]HistoryRun, int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var (
+		runs []HistoryRun
+		bad  int
+	)
+	for i := 0; ; i++ {
+		rec, err := cr.Read()
+		switch _, perr := err.(*csv.ParseError); {
+		case err == io.EOF:
+			return runs, bad, nil
+		case perr:
+			bad++
+			continue
+		case err != nil:
+			return nil, 0, err
+		}
+
+		if i == 0 && len(rec) > 0 && rec[0] == historyHeader[0] {
+			continue
+		}
+		if len(rec) != len(historyHeader) {
+			bad++
+			continue
+		}
+
+		var (
+			h    HistoryRun
+			errs []error
+			atoi = func(s string) int {
+				n, err := strconv.Atoi(s)
+				errs = append(errs, err)
+				return n
+			}
+			atoi64 = func(s string) int64 {
+				n, err := strconv.ParseInt(s, 10, 64)
+				errs = append(errs, err)
+				return n
+			}
+		)
+
+		h.Time, err = time.Parse(time.RFC3339, rec[0])
+		errs = append(errs, err)
+		h.OK, err = strconv.ParseBool(rec[1])
+		errs = append(errs, err)
+		h.Sources, h.Failed, h.Entries, h.Held = atoi(rec[2]), atoi(rec[3]), atoi(rec[4]), atoi(rec[5])
+		h.Bytes, h.Lines = atoi64(rec[6]), atoi64(rec[7])
+		h.Seconds, err = strconv.ParseFloat(rec[8], 64)
+		errs = append(errs, err)
+		if rec[9] != "" {
+			h.Failures = strings.Split(rec[9], ";")
+		}
+		h.Error = rec[10]
+
+		if parsed(errs) {
+			runs = append(runs, h)
+		} else {
+			bad++
+		}
+	}
+}
+
+// parsed is true if none of a record's fields failed to parse
+func parsed(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// HistoryWeek is the blacklist's size at the last run of a week
+type HistoryWeek struct {
+	Start   time.Time `json:"start"`
+	Entries int       `json:"entries"`
+	Change  int       `json:"change"`
+}
+
+// HistoryReport summarizes the trends in a window of the history
+type HistoryReport struct {
+	Since    time.Time     `json:"since"`
+	Until    time.Time     `json:"until"`
+	Runs     int           `json:"runs"`
+	Failed   int           `json:"failed"`
+	First    int           `json:"firstEntries"`
+	Last     int           `json:"lastEntries"`
+	Min      int           `json:"minEntries"`
+	Max      int           `json:"maxEntries"`
+	Growth   int           `json:"growth"`
+	Seconds  float64       `json:"avgSeconds"`
+	Slowest  float64       `json:"maxSeconds"`
+	Bytes    int64         `json:"avgBytes"`
+	Weeks    []HistoryWeek `json:"weeks"`
+	Failures []Count       `json:"sourceFailures,omitempty"`
+}
+
+// NewHistoryReport summarizes the runs in the days before now
+func NewHistoryReport(runs []HistoryRun, now time.Time, days int) *HistoryReport {
+	r := &HistoryReport{Since: now.AddDate(0, 0, -days), Until: now}
+	runs = retain(runs, r.Since)
+	if len(runs) == 0 {
+		return r
+	}
+
+	var (
+		fails = make(map[string]int)
+		weeks = make(map[time.Time]int)
+		secs  float64
+		down  int64
+	)
+
+	r.First, r.Last = runs[0].Entries, runs[len(runs)-1].Entries
+	r.Min, r.Max = r.First, r.First
+	for _, h := range runs {
+		r.Runs++
+		if !h.OK {
+			r.Failed++
+		}
+		if h.Entries < r.Min {
+			r.Min = h.Entries
+		}
+		if h.Entries > r.Max {
+			r.Max = h.Entries
+		}
+		if h.Seconds > r.Slowest {
+			r.Slowest = h.Seconds
+		}
+		secs += h.Seconds
+		down += h.Bytes
+		for _, name := range h.Failures {
+			fails[name]++
+		}
+		weeks[weekStart(h.Time)] = h.Entries
+	}
+	r.Growth = r.Last - r.First
+	r.Seconds = secs / float64(r.Runs)
+	r.Bytes = down / int64(r.Runs)
+
+	for start, n := range weeks {
+		r.Weeks = append(r.Weeks, HistoryWeek{Start: start, Entries: n})
+	}
+	sort.Slice(r.Weeks, func(i, j int) bool { return r.Weeks[i].Start.Before(r.Weeks[j].Start) })
+	for i := 1; i < len(r.Weeks); i++ {
+		r.Weeks[i].Change = r.Weeks[i].Entries - r.Weeks[i-1].Entries
+	}
+
+	for name, n := range fails {
+		r.Failures = append(r.Failures, Count{Name: name, N: n})
+	}
+	sort.Slice(r.Failures, func(i, j int) bool {
+		if r.Failures[i].N != r.Failures[j].N {
+			return r.Failures[i].N > r.Failures[j].N
+		}
+		return r.Failures[i].Name < r.Failures[j].Name
+	})
+	return r
+}
+
+// weekStart returns the Monday starting t's week, in UTC
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	d := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-d, 0, 0, 0, 0, time.UTC)
+}
+
+// pct returns n as a signed percentage of base
+func pct(n, base int) string {
+	if base == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", float64(n)*100/float64(base))
+}
+
+// Report writes a plain text summary of the trends to w
+func (r *HistoryReport) Report(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Runs:     %d from %v to %v, %d failed\n", r.Runs, r.Since.Format(expiryDate), r.Until.Format(expiryDate), r.Failed)
+	if r.Runs > 0 {
+		fmt.Fprintf(&b, "Entries:  %d to %d, %+d (%v), min %d, max %d\n", r.First, r.Last, r.Growth, pct(r.Growth, r.First), r.Min, r.Max)
+		fmt.Fprintf(&b, "Duration: %.1fs average, %.1fs slowest\n", r.Seconds, r.Slowest)
+		fmt.Fprintf(&b, "Download: %.1f MiB average\n", float64(r.Bytes)/(1<<20))
+	}
+
+	if len(r.Weeks) > 0 {
+		fmt.Fprintf(&b, "\n%-10s %10s %10s\n", "Week", "Entries", "Change")
+		for _, wk := range r.Weeks {
+			fmt.Fprintf(&b, "%-10s %10d %+10d\n", wk.Start.Format(expiryDate), wk.Entries, wk.Change)
+		}
+	}
+
+	if len(r.Failures) > 0 {
+		b.WriteString("\nFailing sources:\n")
+		for _, f := range r.Failures {
+			fmt.Fprintf(&b, "%8d  %s\n", f.N, f.Name)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
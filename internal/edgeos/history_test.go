@@ -0,0 +1,152 @@
+package edgeos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistory(t *testing.T) {
+	Convey("Testing the run history", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		now := time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC)
+		seenNow = func() time.Time { return now }
+		defer func() { seenNow = time.Now }()
+
+		run := func(c *Config, entries int, err error) {
+			c.state = newRunState()
+			c.state.Record(SourceResult{Name: "zeus", Entries: entries})
+			if err != nil {
+				c.state.Record(SourceResult{Name: "yoyo", Error: err.Error()})
+			}
+			So(c.RecordHistory(err), ShouldBeNil)
+		}
+
+		for _, ext := range []string{"jsonl", "csv"} {
+			Convey("appends each run to a "+ext+" file and drops old runs", func() {
+				file := dir + "/history." + ext
+				c := NewConfig(HistoryFile(file), HistoryDays(30))
+
+				run(c, 100, nil)
+				now = now.AddDate(0, 0, 7)
+				run(c, 150, errors.New("yoyo failed"))
+
+				runs, err := ReadHistory(file)
+				So(err, ShouldBeNil)
+				So(runs, ShouldHaveLength, 2)
+				So(runs[0].Time, ShouldResemble, time.Date(2026, 10, 16, 3, 0, 0, 0, time.UTC))
+				So(runs[0].OK, ShouldBeTrue)
+				So(runs[0].Entries, ShouldEqual, 100)
+				So(runs[1].OK, ShouldBeFalse)
+				So(runs[1].Sources, ShouldEqual, 2)
+				So(runs[1].Failed, ShouldEqual, 1)
+				So(runs[1].Failures, ShouldResemble, []string{"yoyo"})
+				So(runs[1].Error, ShouldEqual, "yoyo failed")
+
+				b, err := ioutil.ReadFile(file)
+				So(err, ShouldBeNil)
+				if ext == "csv" {
+					So(string(b), ShouldStartWith, strings.Join(historyHeader, ",")+"\n")
+					So(strings.Count(string(b), "\n"), ShouldEqual, 3)
+				} else {
+					So(strings.Count(string(b), "\n"), ShouldEqual, 2)
+				}
+
+				now = now.AddDate(0, 0, 25)
+				run(c, 175, nil)
+				runs, err = ReadHistory(file)
+				So(err, ShouldBeNil)
+				So(runs, ShouldHaveLength, 2)
+				So(runs[0].Entries, ShouldEqual, 150)
+				So(runs[1].Entries, ShouldEqual, 175)
+			})
+		}
+
+		Convey("records nothing without a history file", func() {
+			c := NewConfig()
+			So(c.RecordHistory(nil), ShouldBeNil)
+		})
+
+		Convey("a missing file has no runs, corrupt lines are skipped and dropped by the next run", func() {
+			runs, err := ReadHistory(dir + "/missing.jsonl")
+			So(err, ShouldBeNil)
+			So(runs, ShouldBeNil)
+
+			files := map[string]string{
+				"bad.csv":   strings.Join(historyHeader, ",") + "\n2026-10-16T00:00:00Z,true\n2026-10-15T03:00:00Z,true,1,0,5,0,0,0,1.5,,\n\"bad\n",
+				"bad.jsonl": "{\"time\":\"2026-10-15T03:00:00Z\",\"ok\":true,\"entries\":5}\nnot json\n{\"time\":\"2026-10-15T",
+			}
+			for name, data := range files {
+				file := dir + "/" + name
+				So(ioutil.WriteFile(file, []byte(data), 0644), ShouldBeNil)
+
+				runs, err = ReadHistory(file)
+				So(err, ShouldBeNil)
+				So(runs, ShouldHaveLength, 1)
+				So(runs[0].Entries, ShouldEqual, 5)
+
+				c := NewConfig(HistoryFile(file))
+				run(c, 100, nil)
+				runs, bad, err := readHistory(file)
+				So(err, ShouldBeNil)
+				So(bad, ShouldEqual, 0)
+				So(runs, ShouldHaveLength, 2)
+				So(runs[1].Entries, ShouldEqual, 100)
+			}
+		})
+
+		Convey("summarizes the trends in a window", func() {
+			day := func(d int) time.Time { return time.Date(2026, 7, 1, 3, 0, 0, 0, time.UTC).AddDate(0, 0, d) }
+			runs := []HistoryRun{
+				{Time: day(-30), OK: true, Entries: 50, Seconds: 9},
+				{Time: day(0), OK: true, Entries: 1000, Seconds: 10, Bytes: 2 << 20},
+				{Time: day(1), OK: false, Entries: 900, Seconds: 30, Bytes: 1 << 20, Failures: []string{"yoyo", "zeus"}},
+				{Time: day(8), OK: true, Entries: 1200, Seconds: 20, Bytes: 3 << 20, Failures: []string{"yoyo"}},
+			}
+
+			r := NewHistoryReport(runs, day(10), 15)
+			So(r.Runs, ShouldEqual, 3)
+			So(r.Failed, ShouldEqual, 1)
+			So(r.First, ShouldEqual, 1000)
+			So(r.Last, ShouldEqual, 1200)
+			So(r.Min, ShouldEqual, 900)
+			So(r.Max, ShouldEqual, 1200)
+			So(r.Growth, ShouldEqual, 200)
+			So(r.Seconds, ShouldEqual, 20)
+			So(r.Slowest, ShouldEqual, 30)
+			So(r.Bytes, ShouldEqual, 2<<20)
+			So(r.Weeks, ShouldResemble, []HistoryWeek{
+				{Start: time.Date(2026, 6, 29, 0, 0, 0, 0, time.UTC), Entries: 900},
+				{Start: time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC), Entries: 1200, Change: 300},
+			})
+			So(r.Failures, ShouldResemble, []Count{{Name: "yoyo", N: 2}, {Name: "zeus", N: 1}})
+
+			var b bytes.Buffer
+			So(r.Report(&b), ShouldBeNil)
+			So(b.String(), ShouldEqual, "Runs:     3 from 2026-06-26 to 2026-07-11, 1 failed\n"+
+				"Entries:  1000 to 1200, +200 (+20.0%), min 900, max 1200\n"+
+				"Duration: 20.0s average, 30.0s slowest\n"+
+				"Download: 2.0 MiB average\n"+
+				"\n"+
+				"Week          Entries     Change\n"+
+				"2026-06-29        900         +0\n"+
+				"2026-07-06       1200       +300\n"+
+				"\n"+
+				"Failing sources:\n"+
+				"       2  yoyo\n"+
+				"       1  zeus\n")
+
+			b.Reset()
+			So(NewHistoryReport(nil, day(10), 90).Report(&b), ShouldBeNil)
+			So(b.String(), ShouldEqual, "Runs:     0 from 2026-04-12 to 2026-07-11, 0 failed\n")
+		})
+	})
+}
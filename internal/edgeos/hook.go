@@ -0,0 +1,88 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// PreHook hooks run before any sources are fetched
+	PreHook = "pre-hook"
+	// PostHook hooks run after the blacklist has been generated successfully
+	PostHook = "post-hook"
+)
+
+// Hook is a command run at a stage of the blacklist run, either a shell
+// script or, if written as a JSON array, an argument list run directly
+type Hook struct {
+	Stage  string   `json:"stage"`
+	Script string   `json:"script,omitempty"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// HookResult records the outcome of running a hook
+type HookResult struct {
+	Stage   string `json:"stage"`
+	Command string `json:"command"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ParseHook returns a *Hook for stage from a configured command
+func ParseHook(stage, cmd string) (*Hook, error) {
+	if !strings.HasPrefix(strings.TrimSpace(cmd), "[") {
+		return &Hook{Stage: stage, Script: cmd}, nil
+	}
+
+	var args []string
+	if err := json.Unmarshal([]byte(cmd), &args); err != nil {
+		return nil, fmt.Errorf("invalid %v argument list: %v", stage, err)
+	}
+	if len(args) == 0 || args[0] == "" {
+		return nil, fmt.Errorf("empty %v argument list", stage)
+	}
+	return &Hook{Stage: stage, Args: args}, nil
+}
+
+// String returns the hook's command
+func (h *Hook) String() string {
+	if h.Args == nil {
+		return h.Script
+	}
+	b, _ := json.Marshal(h.Args)
+	return string(b)
+}
+
+// run runs the hook through p's Runner, argument lists are killed once the
+// run's Deadline passes
+func (h *Hook) run(p *Parms) ([]byte, error) {
+	if h.Args == nil {
+		return p.runner().CombinedOutput(h.Script)
+	}
+	return p.execute(h.Args, nil, true)
+}
+
+// Hooks returns the configured hooks
+func (c *Config) Hooks() []*Hook {
+	return c.hooks
+}
+
+// RunHooks runs stage's hooks in order, recording each one's output in the
+// run status, it stops at the first failure and returns the combined output
+func (c *Config) RunHooks(stage string) ([]byte, error) {
+	var out []byte
+	for _, h := range c.hooks {
+		if h.Stage != stage {
+			continue
+		}
+
+		b, err := h.run(c.Parms)
+		out = append(out, b...)
+		c.Status.addHook(h, b, err)
+		if err != nil {
+			return out, fmt.Errorf("%v %q: %v", stage, h, err)
+		}
+	}
+	return out, nil
+}
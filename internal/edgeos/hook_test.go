@@ -0,0 +1,84 @@
+package edgeos
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseHook(t *testing.T) {
+	Convey("Testing ParseHook()", t, func() {
+		tests := []struct {
+			cmd string
+			exp *Hook
+			str string
+			err string
+		}{
+			{cmd: "logger blacklist starting", exp: &Hook{Stage: PreHook, Script: "logger blacklist starting"}, str: "logger blacklist starting"},
+			{cmd: `["rsync", "-a", "/etc/dnsmasq.d/", "r2:/etc/dnsmasq.d/"]`, exp: &Hook{Stage: PreHook, Args: []string{"rsync", "-a", "/etc/dnsmasq.d/", "r2:/etc/dnsmasq.d/"}}, str: `["rsync","-a","/etc/dnsmasq.d/","r2:/etc/dnsmasq.d/"]`},
+			{cmd: `["rsync", `, err: "invalid pre-hook argument list: unexpected end of JSON input"},
+			{cmd: `[]`, err: "empty pre-hook argument list"},
+		}
+
+		for _, tt := range tests {
+			act, err := ParseHook(PreHook, tt.cmd)
+			switch tt.err {
+			case "":
+				So(err, ShouldBeNil)
+				So(act, ShouldResemble, tt.exp)
+				So(act.String(), ShouldEqual, tt.str)
+			default:
+				So(err.Error(), ShouldEqual, tt.err)
+			}
+		}
+	})
+}
+
+func TestRunHooks(t *testing.T) {
+	Convey("Testing RunHooks()", t, func() {
+		var (
+			f   = &fakeRunner{out: []byte("ok\n")}
+			cfg = `blacklist {
+	pre-hook "logger blacklist starting"
+	post-hook '["echo", "generated"]'
+	post-hook "/config/scripts/reload.sh"
+	domains {
+		source zeus {
+			url http://zeus.com
+		}
+	}
+}`
+			c = NewConfig(Shell(f), Stats(NewStatus("")))
+		)
+
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+		So(c.Hooks(), ShouldResemble, []*Hook{
+			{Stage: PreHook, Script: "logger blacklist starting"},
+			{Stage: PostHook, Args: []string{"echo", "generated"}},
+			{Stage: PostHook, Script: "/config/scripts/reload.sh"},
+		})
+
+		out, err := c.RunHooks(PreHook)
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "ok\n")
+		So(f.scripts, ShouldResemble, []string{"logger blacklist starting"})
+
+		f.err = errors.New("exit status 1")
+		out, err = c.RunHooks(PostHook)
+		So(err.Error(), ShouldEqual, `post-hook "/config/scripts/reload.sh": exit status 1`)
+		So(string(out), ShouldEqual, "generated\nok\n")
+		So(f.commands, ShouldResemble, [][]string{{"echo", "generated"}})
+
+		So(c.Status.Hooks, ShouldResemble, []HookResult{
+			{Stage: PreHook, Command: "logger blacklist starting", Output: "ok\n"},
+			{Stage: PostHook, Command: `["echo","generated"]`, Output: "generated\n"},
+			{Stage: PostHook, Command: "/config/scripts/reload.sh", Output: "ok\n", Error: "exit status 1"},
+		})
+
+		Convey("Testing hook configuration errors", func() {
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tpost-hook '[]'\n}"})
+			So(err.Error(), ShouldEqual, "config.boot:2: empty post-hook argument list")
+		})
+	})
+}
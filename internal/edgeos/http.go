@@ -3,40 +3,218 @@ package edgeos
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"strings"
 )
 
 // getHTTP creates http requests to download data
 func getHTTP(o *object) *object {
 	var (
-		body []byte
-		err  error
-		resp *http.Response
-		req  *http.Request
+		auth     authorizer
+		body     io.Reader
+		client   *http.Client
+		endpoint string
+		err      error
+		msg      string
+		n        int64
 	)
 
-	if req, err = http.NewRequest(o.Method, o.url, nil); err != nil {
-		o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to form request for %s...", o.url)), err
+	if o.benched() {
+		o.r, o.err = strings.NewReader(""), errBenched
 		return o
 	}
 
-	req.Header.Set("User-Agent", agent)
-	if resp, err = (&http.Client{}).Do(req); err != nil {
-		o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to get response for %s...", o.url)), err
+	if o.injected() {
 		return o
 	}
 
-	defer resp.Body.Close()
-	body, err = ioutil.ReadAll(resp.Body)
+	// keep credentials, expanded secrets and credential headers out of logs
+	// and the run status
+	o.secrets = o.credentials(o.url)
+	defer func() {
+		o.err, o.final = redactErr(o.err, o.secrets), redact(o.final, o.secrets)
+	}()
+
+	if o.Offline {
+		return o.offline()
+	}
+
+	if isSFTP(o.url) {
+		return getSFTP(o)
+	}
+
+	if o.url, err = o.sourceURL(o.url); err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("Refused plain HTTP for %s...", redact(o.url, o.secrets))), err
+		return o
+	}
+
+	if endpoint, auth, err = o.objectURL(); err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to resolve object storage URL %s...", redact(o.url, o.secrets))), err
+		return o
+	}
+
+	endpoint, expanded, err := expand(endpoint)
+	if err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to expand %s...", redact(o.url, o.secrets))), err
+		return o
+	}
+	o.secrets = append(o.secrets, expanded...)
+	o.secrets = append(o.secrets, o.credentials(endpoint)...)
+
+	if client, err = o.client(); err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to configure TLS for %s...", redact(o.url, o.secrets))), err
+		return o
+	}
+
+	if o.Resolv != "" {
+		if client, err = o.resolverClient(client); err != nil {
+			o.r, o.err = strings.NewReader(fmt.Sprintf("Unable to configure resolver for %s...", redact(o.url, o.secrets))), err
+			return o
+		}
+	}
+
+	if o.via == viaTor {
+		client = o.torClient(client)
+	}
+	client.CheckRedirect = o.checkRedirect()
 
-	if len(body) == 0 {
-		o.r, o.err = strings.NewReader(fmt.Sprintf("No data returned for %s...", o.url)), err
+	if o.deferred(client, endpoint, auth) || o.unchanged(client, endpoint, auth) {
+		o.final = o.url
+		if o.err = o.useCache(); o.err != nil {
+			o.r = strings.NewReader(fmt.Sprintf("Unable to read cached copy of %s...", redact(o.url, o.secrets)))
+		}
 		return o
 	}
 
-	o.r, o.err = bytes.NewBuffer(body), err
+	for attempt := 0; ; attempt++ {
+		if body, n, msg, err = o.fetch(client, endpoint, auth); err == nil || o.part == nil || attempt >= o.Resumes {
+			break
+		}
+		o.log(fmt.Sprintf("%v interrupted after %d bytes, resuming", o.name, len(o.part.body)))
+	}
+
+	if err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf(msg, redact(o.url, o.secrets))), err
+		return o
+	}
+
+	if n == 0 {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		o.r, o.err = strings.NewReader(fmt.Sprintf("No data returned for %s...", redact(o.url, o.secrets))), err
+		return o
+	}
 
+	o.r, o.err = body, nil
 	return o
 }
+
+// fetch makes a single request for the source, resuming a previously
+// interrupted download if there is one, and returns a reader of the body and
+// its length; on failure it returns a message format for the source's reader
+func (o *object) fetch(client *http.Client, endpoint string, auth authorizer) (io.Reader, int64, string, error) {
+	req, err := http.NewRequest(o.Method, endpoint, nil)
+	if err != nil {
+		return nil, 0, "Unable to form request for %s...", err
+	}
+
+	req = req.WithContext(o.context())
+	req.Header.Set("User-Agent", agent)
+	o.part.setRange(req)
+
+	if auth != nil {
+		if err = auth(client, req); err != nil {
+			return nil, 0, "Unable to authorize request for %s...", err
+		}
+	}
+	o.secrets = append(o.secrets, o.headerSecrets(req.Header)...)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, "Unable to get response for %s...", err
+	}
+
+	defer resp.Body.Close()
+	final := resp.Request.URL.String()
+	o.secrets = append(o.secrets, o.credentials(final)...)
+	if o.final = redact(final, o.secrets); final != endpoint {
+		o.log(fmt.Sprintf("%v redirected to %v", o.name, o.final))
+	}
+
+	var prev []byte
+	switch {
+	case o.part.resumed(resp):
+		prev = o.part.body
+	case o.part != nil:
+		o.debug(fmt.Sprintf("%v: server didn't resume the download, fetching it again", o.name))
+		o.part = nil
+	}
+
+	limit := o.maxSize()
+	if limit > 0 && resp.ContentLength > limit-int64(len(prev)) {
+		o.part = nil
+		return nil, 0, "Download exceeded max-size for %s...", fmt.Errorf("%v: %d bytes exceeds max-size of %d bytes", o.url, resp.ContentLength+int64(len(prev)), limit)
+	}
+	if limit > 0 {
+		limit -= int64(len(prev))
+	}
+
+	src := o.newProgressReader(o.rateLimited(resp.Body), resp.ContentLength)
+	if o.spill {
+		f, err := spillFile(o.spillDir())
+		if err == nil {
+			return o.fetchSpilled(resp, f, prev, src, limit)
+		}
+		o.debug(fmt.Sprintf("%v: unable to spill download to disk: %v", o.name, err))
+	}
+
+	body, err := readLimited(src, limit)
+	switch err.(type) {
+	case nil:
+		o.part, body = nil, append(prev, body...)
+		if err = o.writeCache(resp, bytes.NewReader(body), int64(len(body))); err != nil {
+			o.debug(fmt.Sprintf("%v: unable to cache download: %v", o.name, err))
+		}
+		return bytes.NewBuffer(body), int64(len(body)), "", nil
+	case errMaxSize:
+		o.part = nil
+		return nil, 0, "Download exceeded max-size for %s...", fmt.Errorf("%v: %v", o.url, errMaxSize(o.maxSize()))
+	}
+
+	o.part = newPartial(resp, append(prev, body...))
+	return nil, 0, "Download interrupted for %s...", fmt.Errorf("%v: %v", o.url, err)
+}
+
+// fetchSpilled streams resp's body from src to f, after the prev body it
+// resumes, instead of reading it into memory; only an interrupted download
+// is read back, to resume it
+func (o *object) fetchSpilled(resp *http.Response, f *os.File, prev []byte, src io.Reader, limit int64) (io.Reader, int64, string, error) {
+	n, err := spillTo(f, prev, src, limit)
+	switch err.(type) {
+	case nil:
+		o.part = nil
+		if err = o.writeCache(resp, f, n); err != nil {
+			o.debug(fmt.Sprintf("%v: unable to cache download: %v", o.name, err))
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, 0, "Unable to read download for %s...", err
+		}
+		return &spillReader{f}, n, "", nil
+	case errMaxSize:
+		f.Close()
+		o.part = nil
+		return nil, 0, "Download exceeded max-size for %s...", fmt.Errorf("%v: %v", o.url, errMaxSize(o.maxSize()))
+	}
+
+	defer f.Close()
+	if _, serr := f.Seek(0, io.SeekStart); serr == nil {
+		body, _ := ioutil.ReadAll(io.LimitReader(f, n))
+		o.part = newPartial(resp, body)
+	}
+	return nil, 0, "Download interrupted for %s...", fmt.Errorf("%v: %v", o.url, err)
+}
@@ -0,0 +1,118 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// instance labels the configuration node for additional dnsmasq instances
+const instance = "instance"
+
+// Instance is an additional named dnsmasq instance, e.g. one per interface,
+// with its own conf-dir and reload command
+type Instance struct {
+	Name   string `json:"name"`
+	Dir    string `json:"directory"`
+	Reload string `json:"reload,omitempty"`
+}
+
+// Instances returns the configured dnsmasq instances
+func (c *Config) Instances() []*Instance {
+	return c.instances
+}
+
+// SyncInstances copies the generated files to each instance's directory,
+// skipping unchanged files and removing ones that are no longer generated
+func (c *Config) SyncInstances() error {
+	if len(c.instances) == 0 {
+		return nil
+	}
+
+	names, err := c.generated()
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, inst := range c.instances {
+		if err = c.sync(inst, names); err != nil {
+			errs = append(errs, fmt.Sprintf("instance %v: %v", inst.Name, err))
+		}
+	}
+
+	if errs != nil {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// sync copies the named files into an instance's directory
+func (c *Config) sync(inst *Instance, names []string) error {
+	var keep []string
+	for _, name := range names {
+		dst := filepath.Join(inst.Dir, filepath.Base(name))
+		keep = append(keep, dst)
+
+		h, _, err := fileHash(name)
+		if err != nil {
+			return err
+		}
+
+		if d, _, err := fileHash(dst); err == nil && d == h {
+			continue
+		}
+
+		if err = copyFile(name, dst); err != nil {
+			return err
+		}
+	}
+
+	d, err := c.globFiles(inst.Dir, "")
+	if err != nil {
+		return err
+	}
+	return purgeFiles(diffArray(keep, d))
+}
+
+// copyFile atomically replaces dst with a copy of src
+func copyFile(src, dst string) error {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// reloadInstances runs each instance's reload command
+func (c *Config) reloadInstances() ([]byte, error) {
+	var (
+		errs Errors
+		out  []byte
+	)
+
+	for _, inst := range c.instances {
+		if inst.Reload == "" {
+			continue
+		}
+
+		b, err := c.runner().CombinedOutput(inst.Reload)
+		out = append(out, b...)
+		if err != nil {
+			errs = append(errs, &ErrReload{Output: b, Cause: fmt.Errorf("instance %v: %v", inst.Name, err)})
+		}
+	}
+
+	if errs != nil {
+		return out, errs
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package edgeos
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInstances(t *testing.T) {
+	Convey("Testing dnsmasq instances", t, func() {
+		var (
+			dir, _  = ioutil.TempDir("/tmp", "testBlacklist")
+			lan2, _ = ioutil.TempDir("/tmp", "testBlacklist")
+			f       = &fakeRunner{}
+			cfg     = `blacklist {
+	instance lan2 {
+		directory ` + lan2 + `
+		reload "service dnsmasq-lan2 restart"
+	}
+	instance guest {
+		directory ` + lan2 + `/guest
+	}
+	domains {
+		source zeus {
+			url http://zeus.com
+		}
+	}
+}`
+			c = NewConfig(
+				Dir(dir),
+				DNSsvc("service dnsmasq restart"),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Shell(f),
+				WCard(Wildcard{Node: "*s", Name: "*"}),
+			)
+		)
+		defer os.RemoveAll(dir)
+		defer os.RemoveAll(lan2)
+
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+		So(c.Instances(), ShouldResemble, []*Instance{
+			{Name: "lan2", Dir: lan2, Reload: "service dnsmasq-lan2 restart"},
+			{Name: "guest", Dir: lan2 + "/guest"},
+		})
+
+		So(ioutil.WriteFile(dir+"/domains.zeus.blacklist.conf", []byte("address=/.zeus.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(lan2+"/hosts.stale.blacklist.conf", []byte("stale"), 0644), ShouldBeNil)
+		So(os.Mkdir(lan2+"/guest", 0755), ShouldBeNil)
+
+		So(c.SyncInstances(), ShouldBeNil)
+		for _, d := range []string{lan2, lan2 + "/guest"} {
+			b, err := ioutil.ReadFile(d + "/domains.zeus.blacklist.conf")
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "address=/.zeus.com/0.0.0.0\n")
+		}
+
+		_, err := os.Stat(lan2 + "/hosts.stale.blacklist.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		_, err = c.ReloadDNS()
+		So(err, ShouldBeNil)
+		So(f.scripts, ShouldResemble, []string{"service dnsmasq restart", "service dnsmasq-lan2 restart"})
+
+		Convey("Testing instance configuration errors", func() {
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tinstance lan2 {\n\t}\n}"})
+			So(err.Error(), ShouldEqual, `config.boot:3: instance "lan2" missing directory`)
+
+			f.err = errors.New("exit status 1")
+			_, err = c.ReloadDNS()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
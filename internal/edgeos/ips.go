@@ -0,0 +1,48 @@
+package edgeos
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// ipSet collects raw IP address entries found in sources, which dnsmasq
+// can't block, so they can be exported to the firewall instead
+type ipSet struct {
+	*sync.Mutex
+	entry map[string]struct{}
+}
+
+// add records ip, it is a no-op if IP collection isn't enabled
+func (s *ipSet) add(ip net.IP) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	s.entry[ip.String()] = struct{}{}
+	s.Unlock()
+}
+
+// IPs returns the sorted IP address entries collected from sources
+func (c *Config) IPs() []string {
+	s := c.ips
+	if s == nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	ips := make([]string, 0, len(s.entry))
+	for ip := range s.entry {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// parseIP returns the IP address if line is only an IPv4 or IPv6 address
+func parseIP(line []byte) net.IP {
+	return net.ParseIP(string(line))
+}
@@ -0,0 +1,68 @@
+package edgeos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIPs(t *testing.T) {
+	Convey("Testing IP address entries in sources", t, func() {
+		data := "0.0.0.0 ads.example.com\n0.0.0.0 10.0.0.1\n0.0.0.0 2001:db8::1\n0.0.0.0 192.0.2.7\n0.0.0.0 not.an.ip.example.net\n0.0.0.0 10.0.0.1\n"
+		newObj := func(c *Config) *object {
+			return &object{
+				Parms:  c.Parms,
+				ip:     "0.0.0.0",
+				name:   "ips",
+				nType:  host,
+				prefix: "0.0.0.0 ",
+				r:      strings.NewReader(data),
+			}
+		}
+		exp := "address=/ads.example.com/0.0.0.0\naddress=/not.an.ip.example.net/0.0.0.0\n"
+
+		tests := []struct {
+			name    string
+			collect bool
+			ips     []string
+		}{
+			{name: "IP collection disabled", collect: false, ips: nil},
+			{name: "IP collection enabled", collect: true, ips: []string{"10.0.0.1", "192.0.2.7", "2001:db8::1"}},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.name, func() {
+				c := NewConfig(FileNameFmt("%v/%v.%v.%v"), Prefix("address="), CollectIPs(tt.collect))
+				b, err := ioutil.ReadAll(newObj(c).process().r)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, exp)
+				So(c.IPs(), ShouldResemble, tt.ips)
+			})
+		}
+
+		Convey("CollectIPs() should return its inverse", func() {
+			c := NewConfig(CollectIPs(true))
+			prev := c.SetOpt(CollectIPs(false))
+			So(c.ips, ShouldBeNil)
+			c.SetOpt(prev)
+			So(c.ips, ShouldNotBeNil)
+		})
+
+		Convey("AddIPs() should group entries by address family", func() {
+			exp := `delete firewall group address-group IPS
+set firewall group address-group IPS description "blacklist resolved addresses"
+set firewall group address-group IPS address 192.0.2.7
+delete firewall group ipv6-address-group IPS-v6
+set firewall group ipv6-address-group IPS-v6 description "blacklist resolved addresses"
+set firewall group ipv6-address-group IPS-v6 address 2001:db8::1
+`
+			act := new(bytes.Buffer)
+			_, err := NewFWGroup("IPS", nil).AddIPs("192.0.2.7", "bogus", "2001:db8::1").WriteTo(act)
+			So(err, ShouldBeNil)
+			So(act.String(), ShouldEqual, exp)
+		})
+	})
+}
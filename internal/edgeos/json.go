@@ -1,6 +1,11 @@
 package edgeos
 
-import "fmt"
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+)
 
 const (
 	comma = ","
@@ -9,6 +14,9 @@ const (
 	tab   = "  "
 )
 
+// bufPool recycles the buffers used to render JSON strings
+var bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
 type cfgJSON struct {
 	*Config
 	array        []string
@@ -16,34 +24,39 @@ type cfgJSON struct {
 	leaf, pk, sk string
 }
 
-func tabs(t int) (s string) {
+func tabs(t int) string {
 	if t <= 0 {
-		return s
+		return null
 	}
-	for _ = range Iter(t) {
-		s += tab
-	}
-	return s
+	return strings.Repeat(tab, t)
 }
 
-func getJSONArray(c *cfgJSON) (js string) {
+// quoted writes s to w as a double quoted Go string literal, quoting into
+// w's spare capacity to avoid allocating
+func quoted(w *bytes.Buffer, s string) {
+	w.Write(strconv.AppendQuote(w.Bytes()[w.Len():], s))
+}
+
+func getJSONArray(w *bytes.Buffer, c *cfgJSON) {
 	cma := comma
 	cnt := len(c.array)
 	ind := c.indent
-	js += fmt.Sprintf("%v%q: [", tabs(ind), c.leaf)
+	w.WriteString(tabs(ind))
+	quoted(w, c.leaf)
+	w.WriteString(": [")
 	ret := enter
 
 	switch {
 	case c.pk != rootNode && cnt == 0:
-		js += "],\n"
-		return js
+		w.WriteString("],\n")
+		return
 
 	case cnt == 1:
 		ret = null
 		ind = 0
 
 	case cnt > 1:
-		js += enter
+		w.WriteString(enter)
 		ind++
 	}
 
@@ -52,7 +65,10 @@ func getJSONArray(c *cfgJSON) (js string) {
 			if i == cnt-1 {
 				cma = null
 			}
-			js += fmt.Sprintf("%v%q%v%v", tabs(ind), s, cma, ret)
+			w.WriteString(tabs(ind))
+			quoted(w, s)
+			w.WriteString(cma)
+			w.WriteString(ret)
 		}
 
 		cma = comma
@@ -60,21 +76,24 @@ func getJSONArray(c *cfgJSON) (js string) {
 			cma = null
 		}
 
-		js += fmt.Sprintf("%v]%v\n", tabs(ind), cma)
+		w.WriteString(tabs(ind))
+		w.WriteString("]")
+		w.WriteString(cma)
+		w.WriteString(enter)
 	}
-
-	return js
 }
 
-func is(ind int, js, title, s string) string {
+func is(w *bytes.Buffer, ind int, title, s string) {
 	if s != "" {
-		js += fmt.Sprintf("%v%q: %q,\n", tabs(ind), title, s)
-		return js
+		w.WriteString(tabs(ind))
+		quoted(w, title)
+		w.WriteString(": ")
+		quoted(w, s)
+		w.WriteString(",\n")
 	}
-	return js
 }
 
-func getJSONsrcArray(c *cfgJSON) (js string) {
+func getJSONsrcArray(w *bytes.Buffer, c *cfgJSON) {
 	var (
 		cnt = len(c.tree[c.pk].Objects.x)
 		i   int
@@ -82,12 +101,13 @@ func getJSONsrcArray(c *cfgJSON) (js string) {
 		o   *object
 	)
 
+	w.WriteString(tabs(c.indent))
+	quoted(w, "sources")
 	if cnt == 0 {
-		js += fmt.Sprintf("%v%q: [{}]\n", tabs(c.indent), "sources")
-		return js
+		w.WriteString(": [{}]\n")
+		return
 	}
-
-	js += fmt.Sprintf("%v%q: [{%v", tabs(c.indent), "sources", enter)
+	w.WriteString(": [{\n")
 
 	for i, o = range c.tree[c.pk].Objects.x {
 		cma := comma
@@ -97,19 +117,24 @@ func getJSONsrcArray(c *cfgJSON) (js string) {
 			cma = null
 		}
 
-		js += fmt.Sprintf("%v%q: {\n", tabs(ind), o.name)
+		w.WriteString(tabs(ind))
+		quoted(w, o.name)
+		w.WriteString(": {\n")
 		ind++
-		js += fmt.Sprintf("%v%q: %q,\n", tabs(ind), disabled, booltoStr(o.disabled))
-		js = is(ind, js, "description", o.desc)
-		js = is(ind, js, "ip", o.ip)
-		js = is(ind, js, "prefix", o.prefix)
-		js = is(ind, js, files, o.file)
-		js = is(ind, js, urls, o.url)
+		is(w, ind, disabled, booltoStr(o.disabled))
+		is(w, ind, "description", o.desc)
+		is(w, ind, "ip", o.ip)
+		is(w, ind, "prefix", o.prefix)
+		is(w, ind, files, o.file)
+		is(w, ind, urls, o.url)
 		ind--
-		js += fmt.Sprintf("%v}%v%v", tabs(ind), cma, enter)
+		w.WriteString(tabs(ind))
+		w.WriteString("}")
+		w.WriteString(cma)
+		w.WriteString(enter)
 	}
 
 	ind -= 2
-	js += fmt.Sprintf("%v}]%v", tabs(ind), enter)
-	return js
+	w.WriteString(tabs(ind))
+	w.WriteString("}]\n")
 }
@@ -32,3 +32,21 @@ func TestConfigString(t *testing.T) {
 		So(c.String(), ShouldEqual, tdata.JSONcfgZeroHostSources)
 	})
 }
+
+func BenchmarkConfigString(b *testing.B) {
+	c := NewConfig(
+		Dir("/tmp"),
+		Ext("blacklist.conf"),
+		Method("GET"),
+		Nodes([]string{rootNode, domains, hosts}),
+		LTypes([]string{files, PreDomns, PreHosts, urls}),
+	)
+	if err := c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = c.String()
+	}
+}
@@ -9,10 +9,12 @@ import (
 
 type entry map[string]int
 
-// list is a struct map of int
+// list is a struct map of int, keys set while it is on disk are only in its
+// diskSet
 type list struct {
 	*sync.RWMutex
 	entry
+	disk *diskSet
 }
 
 // inc increments the entry by +1
@@ -22,8 +24,25 @@ type list struct {
 // 	l.Unlock()
 // }
 
+// keys returns the sorted keys of a list
+func (l list) keys() []string {
+	keys := make([]string, 0, len(l.entry))
+	for k := range l.entry {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // set sets the int value of entry
 func (l list) keyExists(k string) bool {
+	if l.disk != nil {
+		// a diskSet records its read errors
+		l.Lock()
+		defer l.Unlock()
+		return l.disk.has(k)
+	}
+
 	l.RLock()
 	defer l.RUnlock()
 	_, ok := l.entry[k]
@@ -45,7 +64,11 @@ func mergeList(a, b list) list {
 // mergeList combines two list maps
 func (l list) set(k string, v int) {
 	l.Lock()
-	l.entry[k] = v
+	if l.disk != nil {
+		l.disk.add(k)
+	} else {
+		l.entry[k] = v
+	}
 	l.Unlock()
 }
 
@@ -3,6 +3,7 @@ package edgeos
 import (
 	"bytes"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 
@@ -140,3 +141,24 @@ var (
 	},
 	}
 )
+
+func FuzzSubKeyExists(f *testing.F) {
+	f.Add("doubleclick.net", "ads.doubleclick.net")
+	f.Add("com", "example.com")
+	f.Add("", ".")
+	f.Add("a..b", "x.a..b")
+
+	f.Fuzz(func(t *testing.T, exc, domain string) {
+		l := list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+		l.set(exc, 0)
+
+		if !l.subKeyExists(exc) {
+			t.Errorf("subKeyExists(%q) = false, want true for an excluded key", exc)
+		}
+
+		// top level domains are never matched as parents
+		if strings.Contains(exc, ".") && !l.subKeyExists(domain+"."+exc) {
+			t.Errorf("subKeyExists(%q) = false, want true for a subdomain of %q", domain+"."+exc, exc)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const listsPath = "/lists/"
+
+// listFormats maps the merged lists served under /lists/ to their Renderers
+var listFormats = map[string]string{
+	"merged.abp":   "abp",
+	"merged.conf":  "dnsmasq",
+	"merged.hosts": "hosts",
+	"merged.rpz":   "rpz",
+	"merged.txt":   "domains",
+}
+
+// listTag caches the digest of the merged entries, keyed by the generated
+// files' names, sizes and modification times, so it is only computed again
+// once a run rewrites them
+type listTag struct {
+	sync.Mutex
+	stamp  string
+	digest string
+}
+
+// lists serves the merged blacklist in another resolver's format, with an
+// ETag so subscribers only download it again once its entries change; the
+// ETag is checked before the list is read and rendered
+func (a *StatusAPI) lists(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, listsPath)
+	format, ok := listFormats[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	digest, entries, err := a.listDigest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	etag := listETag(format, digest)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if entries == nil {
+		if entries, err = a.MergedEntries(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	b := new(bytes.Buffer)
+	if err = Renderers[format](b, entries, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(b.Bytes()))
+}
+
+// listDigest returns the merged entries' digest, cached until the generated
+// files change; it also returns the entries if it had to read them
+func (a *StatusAPI) listDigest() (string, Entries, error) {
+	stamp, err := a.generatedStamp()
+	if err != nil {
+		return "", nil, err
+	}
+
+	a.tag.Lock()
+	defer a.tag.Unlock()
+	if a.tag.digest != "" && a.tag.stamp == stamp {
+		return a.tag.digest, nil, nil
+	}
+
+	entries, err := a.MergedEntries()
+	if err != nil {
+		return "", nil, err
+	}
+
+	h := sha256.New()
+	err = entries(func(m MergedEntry) bool {
+		fmt.Fprintln(h, m.Domain, m.Wild)
+		return true
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	a.tag.stamp, a.tag.digest = stamp, fmt.Sprintf("%x", h.Sum(nil)[:16])
+	return a.tag.digest, entries, nil
+}
+
+// generatedStamp returns the generated files' names, sizes and modification
+// times, which change whenever a run rewrites them
+func (c *Config) generatedStamp() (string, error) {
+	names, err := c.generated()
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, f := range names {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(&b, f, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// listETag returns a weak ETag for entries with digest rendered in format; it
+// is weak as the abp and rpz headers carry the time they were rendered
+func listETag(format, digest string) string {
+	return fmt.Sprintf("W/%q", format+"-"+digest)
+}
+
+// noneMatch returns true if an If-None-Match header matches etag, comparing
+// weakly as RFC 7232 requires
+func noneMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
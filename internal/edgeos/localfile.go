@@ -0,0 +1,147 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileScheme prefixes url leaves that name a local file by its absolute
+// path, file:///config/x.txt or file://localhost/config/x.txt; the file
+// leaf takes relative paths
+const fileScheme = "file://"
+
+// fileURLPath returns the path a file:// url names
+func fileURLPath(u string) (string, error) {
+	p, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+
+	if (p.Host != "" && p.Host != "localhost") || !filepath.IsAbs(p.Path) {
+		return "", fmt.Errorf("file url %q must name an absolute path, e.g. file:///config/user-data/list.txt, use the file leaf for a relative one", u)
+	}
+	return p.Path, nil
+}
+
+// path returns the source's file, resolved against BaseDir if it is relative
+func (o *object) path() string {
+	if o.Base == "" || filepath.IsAbs(o.file) {
+		return o.file
+	}
+	return filepath.Join(o.Base, o.file)
+}
+
+// fileStamp identifies a version of a watched file
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// FileWatch watches the configuration's file sources for changes, with
+// inotify on Linux, polling them every Poll seconds elsewhere or if a
+// source's directory can't be watched. C receives once any of them may have
+// changed, Changed returns which did
+type FileWatch struct {
+	C     <-chan struct{}
+	c     *Config
+	done  chan struct{}
+	files map[string]fileStamp
+	n     *dirNotify
+	once  sync.Once
+	send  chan struct{}
+}
+
+// NewFileWatch returns a FileWatch of the file sources as they are now, it
+// watches them until it is closed
+func (c *Config) NewFileWatch() *FileWatch {
+	ch := make(chan struct{}, 1)
+	w := &FileWatch{C: ch, c: c, done: make(chan struct{}), send: ch}
+
+	n, err := newDirNotify(ch)
+	if err != nil {
+		w.poll(err)
+	}
+	w.n = n
+	w.Changed()
+	return w
+}
+
+// poll falls back to polling the file sources, once, as err stops w
+// watching them
+func (w *FileWatch) poll(err error) {
+	w.once.Do(func() {
+		every := time.Duration(w.c.Poll) * time.Second
+		if every < time.Second {
+			every = time.Second
+		}
+		w.c.debug(fmt.Sprintf("polling file sources every %v: %v", every, err))
+
+		t := time.NewTicker(every)
+		go func() {
+			defer t.Stop()
+			for {
+				select {
+				case <-w.done:
+					return
+				case <-t.C:
+				}
+
+				select {
+				case w.send <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	})
+}
+
+// Close stops watching the file sources
+func (w *FileWatch) Close() error {
+	close(w.done)
+	if w.n == nil {
+		return nil
+	}
+	return w.n.close()
+}
+
+// Changed returns the names of the file sources that were changed, created or
+// removed since it was last called, and watches the directories of sources
+// added since
+func (w *FileWatch) Changed() []string {
+	var (
+		stamps  = make(map[string]fileStamp)
+		changed []string
+	)
+
+	for _, o := range w.c.GetAll(files).x {
+		if o.disabled {
+			continue
+		}
+		o.Parms = w.c.Parms
+
+		if w.n != nil {
+			if err := w.n.add(filepath.Dir(o.path())); err != nil {
+				w.poll(err)
+			}
+		}
+
+		var s fileStamp
+		if fi, err := os.Stat(o.path()); err == nil {
+			s = fileStamp{mod: fi.ModTime(), size: fi.Size()}
+		}
+		stamps[o.name] = s
+
+		if prev, ok := w.files[o.name]; ok && prev != s {
+			changed = append(changed, o.name)
+		}
+	}
+
+	w.files = stamps
+	sort.Strings(changed)
+	return changed
+}
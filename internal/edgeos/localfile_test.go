@@ -0,0 +1,91 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocalFiles(t *testing.T) {
+	Convey("Testing file:// and relative file sources", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource local {\n\t\t\turl %v\n\t\t}\n\t}\n}"
+		read := func(u string, opts ...Option) *object {
+			c := NewConfig(opts...)
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, u)}), ShouldBeNil)
+			o := c.Get(domains).x[0]
+			o.Parms = c.Parms
+			return o
+		}
+
+		fileCfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource local {\n\t\t\tfile %v\n\t\t}\n\t}\n}"
+		readFile := func(f string, opts ...Option) *object {
+			c := NewConfig(opts...)
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(fileCfg, f)}), ShouldBeNil)
+			o := c.Get(domains).x[0]
+			o.Parms = c.Parms
+			return o
+		}
+
+		Convey("file:// urls are read as file sources", func() {
+			o := read("file:///config/user-data/my%20list.txt")
+			So(o.ltype, ShouldEqual, files)
+			So(o.url, ShouldBeEmpty)
+			So(o.path(), ShouldEqual, "/config/user-data/my list.txt")
+
+			o = read("https://example.com/list.txt")
+			So(o.ltype, ShouldEqual, urls)
+		})
+
+		Convey("file:// urls must name an absolute path", func() {
+			So(read("file://localhost/abs/local.txt").path(), ShouldEqual, "/abs/local.txt")
+
+			c := NewConfig()
+			err := c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "file://lists/local.txt")})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `source "local" has file url "file://lists/local.txt" must name an absolute path`)
+		})
+
+		Convey("relative paths are resolved against BaseDir", func() {
+			So(readFile("lists/local.txt").path(), ShouldEqual, "lists/local.txt")
+			So(readFile("lists/local.txt", BaseDir(dir)).path(), ShouldEqual, filepath.Join(dir, "lists/local.txt"))
+			So(read("file:///abs/local.txt", BaseDir(dir)).path(), ShouldEqual, "/abs/local.txt")
+		})
+
+		Convey("a FileWatch reports changed file sources", func() {
+			file := filepath.Join(dir, "local.txt")
+			So(ioutil.WriteFile(file, []byte("ads.zeus.com\n"), 0644), ShouldBeNil)
+
+			c := NewConfig(BaseDir(dir), Nodes([]string{domains}))
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(fileCfg, "local.txt")}), ShouldBeNil)
+
+			w := c.NewFileWatch()
+			defer w.Close()
+			So(w.Changed(), ShouldBeNil)
+
+			So(ioutil.WriteFile(file, []byte("ads.zeus.com\nbad.zeus.com\n"), 0644), ShouldBeNil)
+			select {
+			case <-w.C:
+			case <-time.After(5 * time.Second):
+				So("no notification", ShouldBeEmpty)
+			}
+			So(w.Changed(), ShouldResemble, []string{"local"})
+			So(w.Changed(), ShouldBeNil)
+
+			later := time.Now().Add(time.Minute)
+			So(os.Chtimes(file, later, later), ShouldBeNil)
+			So(w.Changed(), ShouldResemble, []string{"local"})
+
+			So(os.Remove(file), ShouldBeNil)
+			So(w.Changed(), ShouldResemble, []string{"local"})
+		})
+	})
+}
@@ -0,0 +1,101 @@
+package edgeos
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a log file, once it would grow past size it is
+// renamed file.1, an older file.1 becomes file.2 and so on, keeping keep of
+// them
+type rotatingFile struct {
+	sync.Mutex
+	f    *os.File
+	file string
+	keep int
+	n    int64
+	size int64
+}
+
+// LogWriter opens LogFile for appending, rotated as LogRotate sets
+func (p *Parms) LogWriter() (io.WriteCloser, error) {
+	r := &rotatingFile{file: p.LogFile, keep: p.LogKeep, size: p.LogSize}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file for appending and notes its size
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.f, r.n = f, fi.Size()
+	return nil
+}
+
+// Write appends b to the log file, rotating it first if b would take it past
+// its size, a single write larger than size isn't split
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.size > 0 && r.n > 0 && r.n+int64(len(b)) > r.size {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.f.Write(b)
+	r.n += int64(n)
+	return n, err
+}
+
+// rotate shifts the kept logs up by one, dropping the oldest, and starts an
+// empty log file
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+
+	if r.keep < 1 {
+		if err := os.Remove(r.file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+
+	for i := r.keep - 1; i > 0; i-- {
+		if err := os.Rename(rotated(r.file, i), rotated(r.file, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(r.file, rotated(r.file, 1)); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// Close closes the log file
+func (r *rotatingFile) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.f.Close()
+}
+
+// rotated returns the name of file's i'th rotated log
+func rotated(file string, i int) string {
+	return fmt.Sprintf("%v.%d", file, i)
+}
@@ -0,0 +1,71 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLogWriter(t *testing.T) {
+	Convey("Testing LogWriter()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		file := dir + "/blacklist.log"
+		So(ioutil.WriteFile(file, []byte("old run\n"), 0644), ShouldBeNil)
+
+		read := func(f string) string {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return ""
+			}
+			return string(b)
+		}
+
+		c := NewConfig(LogFile(file), LogRotate(16, 2))
+		w, err := c.LogWriter()
+		So(err, ShouldBeNil)
+
+		for _, s := range []string{"line one\n", "line two\n", "line three\n", "line four\n"} {
+			_, err = w.Write([]byte(s))
+			So(err, ShouldBeNil)
+		}
+		So(w.Close(), ShouldBeNil)
+
+		So(read(file), ShouldEqual, "line four\n")
+		So(read(file+".1"), ShouldEqual, "line three\n")
+		So(read(file+".2"), ShouldEqual, "line two\n")
+		So(read(file+".3"), ShouldBeEmpty)
+
+		Convey("without kept copies the log is started again", func() {
+			c.SetOpt(LogRotate(16, 0))
+			w, err := c.LogWriter()
+			So(err, ShouldBeNil)
+			defer w.Close()
+
+			_, err = w.Write([]byte("line five is long\n"))
+			So(err, ShouldBeNil)
+			So(read(file), ShouldEqual, "line five is long\n")
+		})
+
+		Convey("a zero size never rotates", func() {
+			c.SetOpt(LogRotate(0, 2))
+			w, err := c.LogWriter()
+			So(err, ShouldBeNil)
+			defer w.Close()
+
+			_, err = w.Write([]byte("line five is long\n"))
+			So(err, ShouldBeNil)
+			So(read(file), ShouldEqual, "line four\nline five is long\n")
+		})
+
+		Convey("an unwritable file fails", func() {
+			c.SetOpt(LogFile(dir + "/missing/blacklist.log"))
+			_, err := c.LogWriter()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
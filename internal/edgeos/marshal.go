@@ -0,0 +1,425 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// parmsJSON is the JSON form of Parms, it holds the configuration only, not
+// runtime state such as the exclusion lists, logger or Runner, nor the catalog
+// and push keys, which are trusted from their files alone
+type parmsJSON struct {
+	API        string      `json:"api,omitempty"`
+	Arch       string      `json:"arch,omitempty"`
+	BaseDir    string      `json:"baseDir,omitempty"`
+	Bash       string      `json:"bash,omitempty"`
+	Cache      string      `json:"cache,omitempty"`
+	CAfile     string      `json:"cafile,omitempty"`
+	CatFile    string      `json:"catalogFile,omitempty"`
+	CatURL     string      `json:"catalogURL,omitempty"`
+	Cores      int         `json:"cores,omitempty"`
+	Counts     bool        `json:"counts,omitempty"`
+	Debug      bool        `json:"debug,omitempty"`
+	Dedupe     string      `json:"dedupe,omitempty"`
+	Defaults   ExcDefaults `json:"defaults"`
+	Determ     bool        `json:"deterministic,omitempty"`
+	Dir        string      `json:"dir,omitempty"`
+	DNSsvc     string      `json:"dnsService,omitempty"`
+	DoHList    []string    `json:"dohList,omitempty"`
+	DoHURL     string      `json:"dohURL,omitempty"`
+	Ext        string      `json:"ext,omitempty"`
+	FailFile   string      `json:"failFile,omitempty"`
+	Fetches    int         `json:"fetches,omitempty"`
+	File       string      `json:"file,omitempty"`
+	FnFmt      string      `json:"fileNameFormat,omitempty"`
+	Gzip       bool        `json:"gzip,omitempty"`
+	HMACKey    string      `json:"hmacKey,omitempty"`
+	HTTPS      string      `json:"https,omitempty"`
+	InCLI      string      `json:"inCLI,omitempty"`
+	Level      string      `json:"level,omitempty"`
+	LineBuffer int         `json:"lineBuffer,omitempty"`
+	LogFile    string      `json:"logFile,omitempty"`
+	LogKeep    int         `json:"logKeep,omitempty"`
+	LogSize    int64       `json:"logSize,omitempty"`
+	Ltypes     []string    `json:"leafTypes,omitempty"`
+	MaxChange  float64     `json:"maxChange,omitempty"`
+	MaxFail    int         `json:"maxFailures,omitempty"`
+	MaxMemory  int         `json:"maxMemoryMB,omitempty"`
+	MaxSize    int64       `json:"maxSize,omitempty"`
+	Method     string      `json:"method,omitempty"`
+	Nice       int         `json:"nice,omitempty"`
+	Nodes      []string    `json:"nodes,omitempty"`
+	Offline    bool        `json:"offline,omitempty"`
+	Prefix     string      `json:"prefix,omitempty"`
+	Protect    []string    `json:"protect,omitempty"`
+	PSL        string      `json:"psl,omitempty"`
+	PSLURL     string      `json:"pslURL,omitempty"`
+	Quarantine string      `json:"quarantine,omitempty"`
+	Pins       []string    `json:"pins,omitempty"`
+	Poll       int         `json:"poll,omitempty"`
+	Precedence string      `json:"precedence,omitempty"`
+	Refuse     bool        `json:"refuseSuffixes,omitempty"`
+	RateLimit  int64       `json:"rateLimit,omitempty"`
+	Redact     []string    `json:"redact,omitempty"`
+	RefreshWin []*Schedule `json:"refreshWindows,omitempty"`
+	Redirects  int         `json:"redirects,omitempty"`
+	Resolver   string      `json:"resolver,omitempty"`
+	Resumes    int         `json:"resumes,omitempty"`
+	SeenFile   string      `json:"seenFile,omitempty"`
+	Shard      int         `json:"shard,omitempty"`
+	StaleDays  int         `json:"staleDays,omitempty"`
+	StaleFile  string      `json:"staleFile,omitempty"`
+	StateFiles []string    `json:"stateFiles,omitempty"`
+	Strict     bool        `json:"strict,omitempty"`
+	Syslog     string      `json:"syslog,omitempty"`
+	SyslogFac  string      `json:"syslogFacility,omitempty"`
+	SyslogTag  string      `json:"syslogTag,omitempty"`
+	Test       bool        `json:"test,omitempty"`
+	Threshold  float64     `json:"threshold,omitempty"`
+	Timeout    string      `json:"timeout,omitempty"`
+	TopDomains string      `json:"topDomains,omitempty"`
+	TopURL     string      `json:"topURL,omitempty"`
+	Timings    bool        `json:"timings,omitempty"`
+	Tor        string      `json:"tor,omitempty"`
+	Transform  string      `json:"transform,omitempty"`
+	Verbose    bool        `json:"verbose,omitempty"`
+	Wildcard   Wildcard    `json:"wildcard"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (p *Parms) MarshalJSON() ([]byte, error) {
+	j := parmsJSON{
+		API:        p.API,
+		Arch:       p.Arch,
+		BaseDir:    p.Base,
+		Bash:       p.Bash,
+		Cache:      p.Cache,
+		CAfile:     p.CAfile,
+		CatFile:    p.CatFile,
+		CatURL:     p.CatURL,
+		Cores:      p.Cores,
+		Counts:     p.Counts,
+		Debug:      p.Dbug,
+		Dedupe:     p.Dedupe,
+		Defaults:   p.DefExc,
+		Determ:     p.Determ,
+		Dir:        p.Dir,
+		DNSsvc:     p.DNSsvc,
+		DoHList:    p.DoHList,
+		DoHURL:     p.DoHURL,
+		Ext:        p.Ext,
+		FailFile:   p.FailDB,
+		Fetches:    p.Fetches,
+		File:       p.File,
+		FnFmt:      p.FnFmt,
+		Gzip:       p.Gzip,
+		HMACKey:    p.MACKey,
+		HTTPS:      p.HTTPS,
+		InCLI:      p.InCLI,
+		Level:      p.Level,
+		LineBuffer: p.LineBuf,
+		LogFile:    p.LogFile,
+		LogKeep:    p.LogKeep,
+		LogSize:    p.LogSize,
+		Ltypes:     p.Ltypes,
+		MaxChange:  p.MaxChg,
+		MaxFail:    p.MaxFail,
+		MaxMemory:  p.MaxMem,
+		MaxSize:    p.MaxSize,
+		Method:     p.Method,
+		Nice:       p.Nice,
+		Nodes:      p.Nodes,
+		Offline:    p.Offline,
+		Prefix:     p.Pfx,
+		Protect:    p.Protect,
+		PSL:        p.PSL,
+		PSLURL:     p.PSLURL,
+		Pins:       p.Pins,
+		Poll:       p.Poll,
+		Precedence: p.Prec,
+		Refuse:     p.Refuse,
+		RateLimit:  p.Rate,
+		Redact:     p.Redact,
+		RefreshWin: p.Windows,
+		Redirects:  p.Redirs,
+		Resolver:   p.Resolv,
+		Resumes:    p.Resumes,
+		SeenFile:   p.Seen,
+		StaleDays:  p.Stale,
+		StaleFile:  p.StaleDB,
+		StateFiles: p.State,
+		Shard:      p.Shard,
+		Strict:     p.Strict,
+		Syslog:     p.Syslog,
+		SyslogFac:  p.SysFac,
+		SyslogTag:  p.SysTag,
+		Test:       p.Test,
+		Threshold:  p.Thresh,
+		TopDomains: p.Top,
+		TopURL:     p.TopURL,
+		Timings:    p.Times,
+		Tor:        p.Tor,
+		Transform:  p.Xform,
+		Verbose:    p.Verb,
+		Wildcard:   p.Wildcard,
+	}
+
+	if p.Hold != 0 {
+		j.Quarantine = p.Hold.String()
+	}
+	if p.Timeout != 0 {
+		j.Timeout = p.Timeout.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, settings missing from b keep
+// their current values
+func (p *Parms) UnmarshalJSON(b []byte) error {
+	cur, err := p.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var j parmsJSON
+	if err = json.Unmarshal(cur, &j); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	var (
+		hold    time.Duration
+		timeout time.Duration
+	)
+
+	if j.Quarantine != "" {
+		if hold, err = time.ParseDuration(j.Quarantine); err != nil {
+			return fmt.Errorf("invalid quarantine: %v", err)
+		}
+	}
+
+	if j.Timeout != "" {
+		if timeout, err = time.ParseDuration(j.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout: %v", err)
+		}
+	}
+
+	p.API, p.Arch, p.Bash, p.Cache, p.CAfile = j.API, j.Arch, j.Bash, j.Cache, j.CAfile
+	p.Base = j.BaseDir
+	p.CatFile, p.CatURL, p.cat = j.CatFile, j.CatURL, nil
+	p.Cores, p.Dbug, p.DefExc, p.Dir, p.DNSsvc = j.Cores, j.Debug, j.Defaults, j.Dir, j.DNSsvc
+	p.DoHList, p.DoHURL, p.Ext, p.File, p.FnFmt = j.DoHList, j.DoHURL, j.Ext, j.File, j.FnFmt
+	p.Gzip, p.HTTPS, p.InCLI, p.Level, p.Ltypes = j.Gzip, j.HTTPS, j.InCLI, j.Level, j.Ltypes
+	p.MaxSize, p.Method, p.Nodes, p.Pfx, p.Pins = j.MaxSize, j.Method, j.Nodes, j.Prefix, j.Pins
+	p.Poll, p.Prec, p.Redirs, p.Resolv = j.Poll, j.Precedence, j.Redirects, j.Resolver
+	p.Resumes, p.Shard, p.Strict, p.Test, p.Timeout = j.Resumes, j.Shard, j.Strict, j.Test, timeout
+	p.Hold, p.Seen, p.seen = hold, j.SeenFile, nil
+	p.Stale, p.StaleDB, p.fresh, p.State = j.StaleDays, j.StaleFile, nil, j.StateFiles
+	p.MaxChg, p.MaxMem, p.Protect, p.guard = j.MaxChange, j.MaxMemory, j.Protect, nil
+	p.MaxFail, p.FailDB, p.fails = j.MaxFail, j.FailFile, nil
+	p.PSL, p.PSLURL, p.Refuse, p.psl = j.PSL, j.PSLURL, j.Refuse, nil
+	p.Top, p.TopURL = j.TopDomains, j.TopURL
+	p.Rate, p.limit, p.Windows = j.RateLimit, newLimiter(j.RateLimit), j.RefreshWin
+	p.Dedupe, p.Fetches, p.LineBuf, p.Nice = j.Dedupe, j.Fetches, j.LineBuffer, j.Nice
+	p.LogFile, p.LogKeep, p.LogSize = j.LogFile, j.LogKeep, j.LogSize
+	p.Syslog, p.SysFac, p.SysTag = j.Syslog, j.SyslogFac, j.SyslogTag
+	p.Counts, p.Determ, p.MACKey, p.Redact = j.Counts, j.Determ, j.HMACKey, j.Redact
+	p.Offline, p.Thresh, p.Tor, p.Xform, p.Verb, p.Wildcard = j.Offline, j.Threshold, j.Tor, j.Transform, j.Verbose, j.Wildcard
+
+	p.Times, p.watch = j.Timings, nil
+	if j.Timings {
+		p.watch = &stopwatch{}
+	}
+	return nil
+}
+
+// objectJSON is the JSON form of a blacklist node or source
+type objectJSON struct {
+	Name      string               `json:"name,omitempty"`
+	Desc      string               `json:"description,omitempty"`
+	Disabled  bool                 `json:"disabled,omitempty"`
+	IP        string               `json:"ip,omitempty"`
+	Blocking  string               `json:"blockingMode,omitempty"`
+	Excludes  []string             `json:"excludes,omitempty"`
+	Includes  []string             `json:"includes,omitempty"`
+	Expires   map[string]time.Time `json:"expires,omitempty"`
+	File      string               `json:"file,omitempty"`
+	URL       string               `json:"url,omitempty"`
+	Prefix    string               `json:"prefix,omitempty"`
+	Identity  string               `json:"identity,omitempty"`
+	MaxSize   int64                `json:"maxSize,omitempty"`
+	Parked    string               `json:"parked,omitempty"`
+	ParseURLs string               `json:"parseUrls,omitempty"`
+	Pins      []string             `json:"pins,omitempty"`
+	Processor string               `json:"processor,omitempty"`
+	RateLimit int64                `json:"rateLimit,omitempty"`
+	Redirect  string               `json:"redirectPolicy,omitempty"`
+	Rewrites  []*rewrite           `json:"rewrites,omitempty"`
+	Sinkholes []string             `json:"sinkholes,omitempty"`
+	Via       string               `json:"via,omitempty"`
+	Weight    float64              `json:"weight,omitempty"`
+	Sources   *Objects             `json:"sources,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *object) MarshalJSON() ([]byte, error) {
+	j := objectJSON{
+		Name:      o.name,
+		Desc:      o.desc,
+		Disabled:  o.disabled,
+		IP:        o.ip,
+		Blocking:  o.blocking,
+		Excludes:  o.exc,
+		Includes:  o.inc,
+		Expires:   o.expires,
+		File:      o.file,
+		URL:       o.url,
+		Prefix:    o.prefix,
+		Identity:  o.identity,
+		MaxSize:   o.maxsize,
+		Parked:    o.parked,
+		ParseURLs: o.parseURL,
+		Pins:      o.pins,
+		Processor: o.processor,
+		RateLimit: o.rate,
+		Redirect:  o.redirect,
+		Rewrites:  o.rewrites,
+		Sinkholes: o.sinkholes,
+		Via:       o.via,
+		Weight:    o.weight,
+	}
+
+	if len(o.Objects.x) > 0 {
+		j.Sources = &o.Objects
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *object) UnmarshalJSON(b []byte) error {
+	var j objectJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	*o = *newObject()
+	o.name, o.desc, o.disabled, o.ip, o.blocking = j.Name, j.Desc, j.Disabled, j.IP, j.Blocking
+	o.file, o.url, o.prefix, o.identity = j.File, j.URL, j.Prefix, j.Identity
+	o.maxsize, o.parked, o.processor, o.redirect = j.MaxSize, j.Parked, j.Processor, j.Redirect
+	o.pins, o.rate = j.Pins, j.RateLimit
+	o.parseURL, o.rewrites, o.sinkholes, o.via, o.weight = j.ParseURLs, j.Rewrites, j.Sinkholes, j.Via, j.Weight
+
+	for _, r := range o.rewrites {
+		if err := r.compile(); err != nil {
+			return err
+		}
+	}
+
+	if err := CheckPins(o.pins); err != nil {
+		return err
+	}
+
+	if j.Excludes != nil {
+		o.exc = j.Excludes
+	}
+	if j.Includes != nil {
+		o.inc = j.Includes
+	}
+	o.expires = j.Expires
+	if j.Sources != nil {
+		o.Objects = *j.Sources
+	}
+
+	switch {
+	case o.url != "":
+		o.ltype = urls
+	case o.file != "":
+		o.ltype = files
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *Objects) MarshalJSON() ([]byte, error) {
+	if o.x == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(o.x)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *Objects) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &o.x)
+}
+
+// configJSON is the JSON form of Config
+type configJSON struct {
+	Parms     *Parms             `json:"parms"`
+	Nodes     map[string]*object `json:"nodes"`
+	Hooks     []*Hook            `json:"hooks,omitempty"`
+	Instances []*Instance        `json:"instances,omitempty"`
+	Groups    []*ClientGroup     `json:"clientGroups,omitempty"`
+	Profiles  []*Profile         `json:"profiles,omitempty"`
+	Targets   []*Target          `json:"targets,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, the result is a snapshot of the
+// configuration that UnmarshalJSON restores
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(configJSON{
+		Parms:     c.Parms,
+		Nodes:     c.tree,
+		Hooks:     c.hooks,
+		Instances: c.instances,
+		Groups:    c.groups,
+		Profiles:  c.profiles,
+		Targets:   c.targets,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it replaces the configured
+// nodes and applies the snapshot's parms over the current ones
+func (c *Config) UnmarshalJSON(b []byte) error {
+	if c.Parms == nil {
+		c.Parms = NewConfig().Parms
+	}
+
+	j := configJSON{Parms: c.Parms}
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	for _, h := range j.Hooks {
+		if h.Stage != PreHook && h.Stage != PostHook {
+			return fmt.Errorf("hook %q has unknown stage %q", h, h.Stage)
+		}
+	}
+
+	for _, t := range j.Targets {
+		if !validFormat(t.Format) {
+			return fmt.Errorf("target %q has unknown format %q", t.Name, t.Format)
+		}
+	}
+
+	c.tree = make(tree)
+	for node, o := range j.Nodes {
+		if o == nil {
+			o = newObject()
+		}
+
+		for _, s := range o.Objects.x {
+			if s.ltype == "" {
+				return fmt.Errorf("source %q missing url/file", s.name)
+			}
+			s.nType = getType(node).(ntype)
+		}
+		c.tree[node] = o
+	}
+
+	c.hooks, c.instances, c.profiles, c.targets = j.Hooks, j.Instances, j.Profiles, j.Targets
+	c.groups = j.Groups
+	return c.checkGroups()
+}
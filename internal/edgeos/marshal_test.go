@@ -0,0 +1,118 @@
+package edgeos
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigMarshalJSON(t *testing.T) {
+	Convey("Testing Config.MarshalJSON() and UnmarshalJSON()", t, func() {
+		cfg := tdata.Cfg[:len(tdata.Cfg)-2] + `
+	hosts {
+		source quirky {
+			description "tab\there, \"quoted\" and back\\slash ünïcode"
+			url https://example.com/hosts?a=1&b=<2>
+			sinkhole 0.0.0.0
+			max-size 10M
+		}
+	}
+	post-hook "logger done"
+	target ipset {
+		format ipset
+		file /tmp/ipset.conf
+	}
+}
+`
+		c := NewConfig(
+			Dir("/tmp"),
+			Ext("blacklist.conf"),
+			Method("GET"),
+			Nodes([]string{rootNode, domains, hosts}),
+			LTypes([]string{files, PreDomns, PreHosts, urls}),
+			Timeout(30*time.Second),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		b, err := json.Marshal(c)
+		So(err, ShouldBeNil)
+		So(json.Valid(b), ShouldBeTrue)
+
+		act := NewConfig()
+		So(json.Unmarshal(b, act), ShouldBeNil)
+		So(act.String(), ShouldEqual, c.String())
+		So(act.Dir, ShouldEqual, "/tmp")
+		So(act.Timeout, ShouldEqual, 30*time.Second)
+		So(act.Hooks(), ShouldResemble, c.Hooks())
+		So(act.Targets(), ShouldResemble, c.Targets())
+		So(act.Exc.entry, ShouldNotBeNil)
+
+		var quirky *object
+		for _, o := range act.tree[hosts].Objects.x {
+			if o.name == "quirky" {
+				quirky = o
+			}
+		}
+		So(quirky, ShouldNotBeNil)
+		So(quirky.desc, ShouldEqual, `tab\there, \"quoted\" and back\\slash ünïcode`)
+		So(quirky.ltype, ShouldEqual, urls)
+		So(quirky.nType, ShouldEqual, host)
+		So(quirky.maxsize, ShouldEqual, 10<<20)
+
+		again, err := json.Marshal(act)
+		So(err, ShouldBeNil)
+		So(string(again), ShouldEqual, string(b))
+
+		Convey("Testing invalid snapshots", func() {
+			tests := []struct {
+				in  string
+				err string
+			}{
+				{in: `{"nodes": {"hosts": {"sources": [{"name": "x"}]}}}`, err: `source "x" missing url/file`},
+				{in: `{"targets": [{"name": "t", "format": "bogus", "file": "/tmp/t"}]}`, err: `target "t" has unknown format "bogus"`},
+				{in: `{"parms": {"timeout": "soon"}}`, err: `invalid timeout: time: invalid duration "soon"`},
+				{in: `{"nodes": [}`, err: "invalid character '}' looking for beginning of value"},
+			}
+
+			for _, tt := range tests {
+				So(json.Unmarshal([]byte(tt.in), NewConfig()).Error(), ShouldEqual, tt.err)
+			}
+		})
+	})
+}
+
+func TestParmsMarshalJSON(t *testing.T) {
+	Convey("Testing Parms.MarshalJSON() and UnmarshalJSON()", t, func() {
+		c := NewConfig(
+			Dir("/config/dnsmasq.d"),
+			PushKey(make([]byte, 32)),
+			Shard(1000),
+			Timeout(time.Minute),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+
+		b, err := json.Marshal(c.Parms)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, `{"defaults":{},"dir":"/config/dnsmasq.d","shard":1000,"timeout":"1m0s","wildcard":{"node":"*s","name":"*"}}`)
+
+		p := NewConfig(Bash("/bin/bash")).Parms
+		So(json.Unmarshal(b, p), ShouldBeNil)
+		So(p.Bash, ShouldEqual, "/bin/bash")
+		So(p.Dir, ShouldEqual, "/config/dnsmasq.d")
+		So(p.PushKey, ShouldBeNil)
+		So(p.Shard, ShouldEqual, 1000)
+		So(p.Timeout, ShouldEqual, time.Minute)
+		So(p.Wildcard, ShouldResemble, Wildcard{Node: "*s", Name: "*"})
+
+		key := make([]byte, 32)
+		key[0] = 1
+		p.PushKey, p.CatKey = key, key
+		So(json.Unmarshal([]byte(`{"pushKey": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", "catalogKey": "%%%"}`), p), ShouldBeNil)
+		So(p.PushKey, ShouldResemble, ed25519.PublicKey(key))
+		So(p.CatKey, ShouldResemble, ed25519.PublicKey(key))
+	})
+}
@@ -0,0 +1,138 @@
+package edgeos
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// srcEstimate is the size assumed for a source that hasn't been cached
+	srcEstimate = 8 << 20
+	// memFactor is how many times a source's size its download and parsed
+	// entries are assumed to take in memory
+	memFactor = 3
+)
+
+// sizeEstimate returns the source's cached size, or srcEstimate if it hasn't
+// been cached
+func (o *object) sizeEstimate() int64 {
+	if o.cacheable() {
+		if m, err := o.readCache(); err == nil {
+			return m.Length
+		}
+	}
+	return srcEstimate
+}
+
+// fetchPlan returns how many of x's sources may be downloaded at once and
+// whether their downloads are spilled to disk, to stay within MaxMem; once
+// they are, the exclusions deduplicating the entries are kept on disk too
+func (p *Parms) fetchPlan(x []*object) (int, bool) {
+	if p.MaxMem <= 0 || len(x) == 0 {
+		return len(x), false
+	}
+
+	var total, most int64
+	for _, o := range x {
+		n := o.sizeEstimate() * memFactor
+		total += n
+		if n > most {
+			most = n
+		}
+	}
+
+	budget := int64(p.MaxMem) << 20
+	if total <= budget {
+		return len(x), false
+	}
+
+	workers := int(budget / most)
+	if workers < 1 {
+		workers = 1
+	}
+	p.log(fmt.Sprintf("sources need about %v of memory, more than max-memory of %dM, spilling downloads and deduplication to disk and fetching %d at a time", formatSize(uint64(total)), p.MaxMem, workers))
+	p.spillExc()
+	return workers, true
+}
+
+// spillExc moves the exclusions to a diskSet in spillDir, so deduplicating
+// a run's entries doesn't hold all of them in memory; they stay in memory if
+// it can't be created
+func (p *Parms) spillExc() {
+	if p.Exc.RWMutex == nil || p.Exc.disk != nil {
+		return
+	}
+
+	d, err := newDiskSet(p.spillDir(), diskSlots)
+	if err != nil {
+		p.debug(fmt.Sprintf("unable to deduplicate on disk: %v", err))
+		return
+	}
+
+	p.Exc.Lock()
+	defer p.Exc.Unlock()
+	for k := range p.Exc.entry {
+		d.add(k)
+	}
+	p.Exc.entry, p.Exc.disk = make(entry), d
+}
+
+// spillDir returns the directory downloads are spilled to, the cache if it is
+// set, as /tmp is often memory backed on routers
+func (p *Parms) spillDir() string {
+	if p.Cache != "" {
+		return p.Cache
+	}
+	return os.TempDir()
+}
+
+// spillFile returns an unlinked temporary file in dir to spill a download to
+func spillFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile(dir, ".spill")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	return f, nil
+}
+
+// spillTo writes prev and then r to f, no more than limit bytes of r if limit
+// is set, and rewinds f; it returns how many bytes f holds, or errMaxSize if
+// r is longer than limit
+func spillTo(f *os.File, prev []byte, r io.Reader, limit int64) (int64, error) {
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+
+	var n int64
+	_, err := f.Write(prev)
+	if err == nil {
+		n, err = io.Copy(f, r)
+	}
+	if err == nil && limit > 0 && n > limit {
+		err = errMaxSize(limit)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	return int64(len(prev)) + n, err
+}
+
+// spillReader reads a spilled download, closing it at EOF
+type spillReader struct {
+	*os.File
+}
+
+func (s *spillReader) Read(b []byte) (int, error) {
+	n, err := s.File.Read(b)
+	if err != nil {
+		s.File.Close()
+	}
+	return n, err
+}
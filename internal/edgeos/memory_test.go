@@ -0,0 +1,91 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFetchPlan(t *testing.T) {
+	Convey("Testing fetchPlan()", t, func() {
+		x := []*object{{name: "a"}, {name: "b"}, {name: "c"}}
+
+		tests := []struct {
+			mb      int
+			workers int
+			spill   bool
+		}{
+			{mb: 0, workers: 3},
+			{mb: 100, workers: 3},
+			{mb: 50, workers: 2, spill: true},
+			{mb: 10, workers: 1, spill: true},
+		}
+
+		for _, tt := range tests {
+			for _, o := range x {
+				o.Parms = &Parms{MaxMem: tt.mb}
+			}
+			workers, spill := x[0].fetchPlan(x)
+			So(workers, ShouldEqual, tt.workers)
+			So(spill, ShouldEqual, tt.spill)
+		}
+
+		Convey("cached sources are estimated by their size", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "ads.example.com\n")
+			}))
+			defer srv.Close()
+
+			o := getHTTP(&object{Parms: &Parms{Cache: dir, Method: http.MethodGet}, ltype: urls, name: "cached", nType: domn, url: srv.URL})
+			So(o.err, ShouldBeNil)
+			So(o.sizeEstimate(), ShouldEqual, 16)
+		})
+	})
+}
+
+func TestSpill(t *testing.T) {
+	Convey("Testing GetList() over the memory budget", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "ads%v.example.com\n", r.URL.Path[1:])
+		}))
+		defer srv.Close()
+
+		c := NewConfig(Cache(dir), MaxMemoryMB(1), Method(http.MethodGet), Nodes([]string{domains}))
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n" +
+			"\t\tsource one {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "/1\n\t\t}\n" +
+			"\t\tsource two {\n\t\t\tprefix \"\"\n\t\t\turl " + srv.URL + "/2\n\t\t}\n" +
+			"\t}\n}"}), ShouldBeNil)
+
+		ct, err := c.NewContent(URLdObj)
+		So(err, ShouldBeNil)
+
+		for _, o := range ct.GetList().x {
+			So(o.err, ShouldBeNil)
+			So(o.spill, ShouldBeTrue)
+			So(o.r, ShouldHaveSameTypeAs, &spillReader{})
+
+			b, err := ioutil.ReadAll(o.r)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, fmt.Sprintf("ads%v.example.com\n", map[string]int{"one": 1, "two": 2}[o.name]))
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		So(err, ShouldBeNil)
+		for _, f := range files {
+			So(f.Name(), ShouldNotContainSubstring, ".spill")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/britannic/blacklist/internal/regx"
+)
+
+var (
+	// legacyLeaves maps deprecated leaf names to their current equivalents
+	legacyLeaves = map[string]string{
+		"blackhole":    blackhole,
+		"desc":         "description",
+		"disable":      disabled,
+		"strip-prefix": "prefix",
+	}
+
+	// legacyNodes maps deprecated node names to their current equivalents
+	legacyNodes = map[string]string{
+		"domain": domains,
+		"host":   hosts,
+	}
+)
+
+// Migrate reads a legacy blacklist configuration and returns the equivalent
+// set commands for the current node layout, preceded by a delete of the
+// existing blacklist tree
+func (c *Config) Migrate(r io.Reader) ([]string, error) {
+	base, _ := c.path(rootNode)
+	var (
+		b    = bufio.NewScanner(r)
+		cmds = []string{"delete " + base}
+		line int
+		path []string
+		root bool
+		rx   = regx.Obj
+		set  = func(leaf string, value ...string) {
+			cmd := strings.Join(append([]string{"set", base}, append(path, leaf)...), " ")
+			for _, v := range value {
+				cmd += " " + quote(v)
+			}
+			cmds = append(cmds, cmd)
+		}
+	)
+
+	for b.Scan() {
+		line++
+		l := bytes.TrimSpace(b.Bytes())
+
+		switch {
+		case len(l) == 0, rx.CMNT.Match(l):
+			continue
+
+		case rx.NODE.Match(l):
+			node := string(regx.Get([]byte("node"), l)[1])
+			if n, ok := legacyNodes[node]; ok {
+				node = n
+			}
+			if !nodeRx.MatchString(node) {
+				return nil, fmt.Errorf("line %d: invalid node %q", line, node)
+			}
+
+			switch {
+			case node == rootNode && !root && len(path) == 0:
+				root = true
+			case node == rootNode:
+				return nil, fmt.Errorf("line %d: unexpected %q node", line, node)
+			default:
+				path = append(path, node)
+			}
+
+		case rx.LEAF.Match(l):
+			leaf := regx.Get([]byte("leaf"), l)
+			if !nodeRx.MatchString(string(leaf[1])) {
+				return nil, fmt.Errorf("line %d: invalid node %q", line, leaf[1])
+			}
+			path = append(path, string(leaf[1])+" "+quote(string(leaf[2])))
+
+		case rx.RBRC.Match(l):
+			switch {
+			case len(path) > 0:
+				path = path[:len(path)-1]
+			case root:
+				root = false
+			default:
+				return nil, fmt.Errorf("line %d: unbalanced '}'", line)
+			}
+
+		case rx.NAME.Match(l):
+			name := regx.Get([]byte("name"), l)
+			leaf := string(name[1])
+			if n, ok := legacyLeaves[leaf]; ok {
+				leaf = n
+			}
+			if !nodeRx.MatchString(leaf) {
+				return nil, fmt.Errorf("line %d: invalid leaf %q", line, leaf)
+			}
+			set(leaf, string(name[2]))
+
+		case rx.MISC.Match(l):
+			if !nodeRx.MatchString(string(l)) {
+				return nil, fmt.Errorf("line %d: invalid leaf %q", line, l)
+			}
+			set(string(l))
+
+		default:
+			return nil, fmt.Errorf("line %d: unable to parse %q", line, l)
+		}
+	}
+
+	if len(path) > 0 || root {
+		return nil, fmt.Errorf("line %d: missing '}'", line)
+	}
+
+	if len(cmds) == 1 {
+		return nil, errors.New("legacy configuration is empty, nothing to migrate")
+	}
+	return cmds, b.Err()
+}
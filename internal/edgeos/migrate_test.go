@@ -0,0 +1,72 @@
+package edgeos
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMigrate(t *testing.T) {
+	Convey("Testing Migrate()", t, func() {
+		c := NewConfig(Level("service dns forwarding"))
+
+		legacy := `blacklist {
+    disable false
+    blackhole 0.0.0.0
+    exclude apple.com
+    domain {
+        include adsrvr.org
+        source malc0de {
+            desc "List of zones serving malicious executables"
+            strip-prefix "zone "
+            url http://malc0de.com/bl/ZONES
+        }
+    }
+    host {
+        blackhole 192.168.168.1
+        source yoyo {
+            strip-prefix ""
+            url "http://pgl.yoyo.org/as/serverlist.php?hostformat=nohtml"
+        }
+    }
+}`
+
+		exp := []string{
+			"delete service dns forwarding blacklist",
+			"set service dns forwarding blacklist disabled 'false'",
+			"set service dns forwarding blacklist dns-redirect-ip '0.0.0.0'",
+			"set service dns forwarding blacklist exclude 'apple.com'",
+			"set service dns forwarding blacklist domains include 'adsrvr.org'",
+			"set service dns forwarding blacklist domains source 'malc0de' description 'List of zones serving malicious executables'",
+			"set service dns forwarding blacklist domains source 'malc0de' prefix 'zone '",
+			"set service dns forwarding blacklist domains source 'malc0de' url 'http://malc0de.com/bl/ZONES'",
+			"set service dns forwarding blacklist hosts dns-redirect-ip '192.168.168.1'",
+			"set service dns forwarding blacklist hosts source 'yoyo' prefix ''",
+			"set service dns forwarding blacklist hosts source 'yoyo' url 'http://pgl.yoyo.org/as/serverlist.php?hostformat=nohtml'",
+		}
+
+		act, err := c.Migrate(strings.NewReader(legacy))
+		So(err, ShouldBeNil)
+		So(act, ShouldResemble, exp)
+
+		tests := []struct {
+			cfg string
+			err error
+		}{
+			{cfg: "", err: errors.New("legacy configuration is empty, nothing to migrate")},
+			{cfg: "blacklist {\n  domain {\n", err: errors.New("line 2: missing '}'")},
+			{cfg: "}\n", err: errors.New("line 1: unbalanced '}'")},
+			{cfg: "blacklist {\n  blacklist {\n", err: errors.New(`line 2: unexpected "blacklist" node`)},
+			{cfg: "blacklist {\n  !!bad\n}", err: errors.New(`line 2: unable to parse "!!bad"`)},
+			{cfg: "blacklist {\n  $(reboot) yoyo {\n  }\n}", err: errors.New(`line 2: invalid node "$(reboot)"`)},
+			{cfg: "blacklist {\n  Domain {\n  }\n}", err: errors.New(`line 2: invalid node "Domain"`)},
+		}
+
+		for _, tt := range tests {
+			_, err := c.Migrate(strings.NewReader(tt.cfg))
+			So(err, ShouldResemble, tt.err)
+		}
+	})
+}
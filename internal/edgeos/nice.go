@@ -0,0 +1,37 @@
+package edgeos
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// maxNice is the lowest CPU priority a process can have
+const maxNice = 19
+
+// niceCores returns the cores a low priority run may use, leaving one of
+// cpus free for routing and DNS when there is more than one
+func niceCores(cores, cpus int) int {
+	if cpus > 1 && (cores < 1 || cores >= cpus) {
+		return cpus - 1
+	}
+	if cores < 1 {
+		return 1
+	}
+	return cores
+}
+
+// LowerPriority runs blacklist at the Nice CPU priority, with the lowest
+// best-effort I/O priority where the OS has one, and clamps Cores so a core
+// is left for routing and DNS; it does nothing if Nice isn't set
+func (c *Config) LowerPriority() error {
+	if c.Nice <= 0 {
+		return nil
+	}
+	if c.Nice > maxNice {
+		return fmt.Errorf("nice %d is out of range, must be 1 to %d", c.Nice, maxNice)
+	}
+
+	c.Cores = niceCores(c.Cores, runtime.NumCPU())
+	runtime.GOMAXPROCS(c.Cores)
+	return lowerPriority(c.Nice)
+}
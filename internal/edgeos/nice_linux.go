@@ -0,0 +1,41 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+)
+
+const (
+	// ioprioWhoProcess sets a thread's I/O priority with ioprio_set
+	ioprioWhoProcess = 1
+	// ioprioLow is the best-effort class, 2 shifted by IOPRIO_CLASS_SHIFT,
+	// at its lowest level, 7
+	ioprioLow = 2<<13 | 7
+)
+
+// lowerPriority sets nice and the lowest best-effort I/O priority on each of
+// the process's threads, as Linux prioritises threads rather than processes;
+// threads started later inherit them
+func lowerPriority(nice int) error {
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+
+		if err = syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+			return err
+		}
+
+		if _, _, e := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioLow); e != 0 {
+			return e
+		}
+	}
+	return nil
+}
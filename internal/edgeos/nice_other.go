@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package edgeos
+
+import "syscall"
+
+// lowerPriority sets the process's nice value, other OSes have no portable
+// I/O priority
+func lowerPriority(nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice)
+}
@@ -0,0 +1,48 @@
+package edgeos
+
+import (
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLowerPriority(t *testing.T) {
+	Convey("Testing LowerPriority()", t, func() {
+		Convey("a core is left free for routing and DNS", func() {
+			tests := []struct {
+				cores, cpus, exp int
+			}{
+				{cores: 0, cpus: 1, exp: 1},
+				{cores: 2, cpus: 1, exp: 2},
+				{cores: 0, cpus: 4, exp: 3},
+				{cores: 4, cpus: 4, exp: 3},
+				{cores: 2, cpus: 4, exp: 2},
+				{cores: 2, cpus: 2, exp: 1},
+			}
+
+			for _, tt := range tests {
+				So(niceCores(tt.cores, tt.cpus), ShouldEqual, tt.exp)
+			}
+		})
+
+		Convey("nothing changes unless Nice is set", func() {
+			c := NewConfig(Cores(runtime.NumCPU()))
+			So(c.LowerPriority(), ShouldBeNil)
+			So(c.Cores, ShouldEqual, runtime.NumCPU())
+		})
+
+		Convey("Nice must be a valid nice value", func() {
+			So(NewConfig(Nice(20)).LowerPriority().Error(), ShouldEqual, "nice 20 is out of range, must be 1 to 19")
+		})
+
+		Convey("the run's priority is lowered", func() {
+			procs := runtime.GOMAXPROCS(0)
+			defer runtime.GOMAXPROCS(procs)
+
+			c := NewConfig(Cores(runtime.NumCPU()), Nice(1))
+			So(c.LowerPriority(), ShouldBeNil)
+			So(c.Cores, ShouldEqual, niceCores(runtime.NumCPU(), runtime.NumCPU()))
+		})
+	})
+}
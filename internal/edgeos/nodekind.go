@@ -0,0 +1,224 @@
+package edgeos
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NodeKind describes a kind of blacklist node, e.g. domains or hosts, further
+// kinds are added with RegisterNode
+type NodeKind struct {
+	// Name is the node's name in the configuration, e.g. "domains"
+	Name string
+	// Inc labels the node's includes, it defaults to "pre-configured-<Name>"
+	Inc string
+	// Exc labels the node's exclusions, it defaults to "<Name>-excludes"
+	Exc string
+	// Wild is true if the node's entries also block their subdomains
+	Wild bool
+}
+
+// nodeKind is a registered NodeKind and the ntypes of its sources, includes
+// and exclusions
+type nodeKind struct {
+	NodeKind
+	n, inc, exc ntype
+}
+
+var (
+	kindMu sync.RWMutex
+	kinds  = make(map[string]*nodeKind)
+	// nextType is the ntype the next registered kind's sources get
+	nextType = allow + 1
+)
+
+func init() {
+	registerNode(NodeKind{Name: domains, Inc: PreDomns, Exc: ExcDomns, Wild: true}, domn, preDomn, excDomn)
+	registerNode(NodeKind{Name: hosts, Inc: PreHosts, Exc: ExcHosts}, host, preHost, excHost)
+}
+
+// RegisterNode adds a kind of node, the sources, includes and exclusions of
+// "<Name>" nodes in the configuration are then fetched, filtered and written
+// like those of domains and hosts. Registering a name again replaces its
+// settings, except for the built-in domains and hosts kinds
+func RegisterNode(k NodeKind) error {
+	switch k.Name {
+	case "", allowNode, rootNode, zones, notknown:
+		return fmt.Errorf("invalid node kind name %q", k.Name)
+	case domains, hosts:
+		return fmt.Errorf("node kind %q is built in", k.Name)
+	}
+
+	if k.Inc == "" {
+		k.Inc = preNoun + "-" + k.Name
+	}
+	if k.Exc == "" {
+		k.Exc = k.Name + "-excludes"
+	}
+
+	kindMu.Lock()
+	n := nextType
+	if old, ok := kinds[k.Name]; ok {
+		n = old.n
+	} else {
+		nextType += 3
+	}
+	kindMu.Unlock()
+
+	registerNode(k, n, n+1, n+2)
+	return nil
+}
+
+// registerNode adds k with the given ntypes
+func registerNode(k NodeKind, n, inc, exc ntype) {
+	kindMu.Lock()
+	kinds[k.Name] = &nodeKind{NodeKind: k, n: n, inc: inc, exc: exc}
+	kindMu.Unlock()
+}
+
+// NodeKinds returns the sorted names of the registered node kinds
+func NodeKinds() []string {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registered returns the registered node kinds sorted by name
+func registered() []*nodeKind {
+	var k []*nodeKind
+	for _, name := range NodeKinds() {
+		k = append(k, kindNamed(name))
+	}
+	return k
+}
+
+// kindNamed returns the node kind called name, or nil
+func kindNamed(name string) *nodeKind {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+	return kinds[name]
+}
+
+// kindOf returns the node kind n labels the sources, includes or exclusions
+// of, or nil
+func kindOf(n ntype) *nodeKind {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+
+	for _, k := range kinds {
+		switch n {
+		case k.n, k.inc, k.exc:
+			return k
+		}
+	}
+	return nil
+}
+
+// kindLabelled returns the node kind ltype labels the includes or exclusions
+// of, or nil
+func kindLabelled(ltype string) *nodeKind {
+	kindMu.RLock()
+	defer kindMu.RUnlock()
+
+	for _, k := range kinds {
+		switch ltype {
+		case k.Inc, k.Exc:
+			return k
+		}
+	}
+	return nil
+}
+
+// incTypes returns the include labels of the node kinds that do, or don't,
+// block subdomains
+func incTypes(wild bool) []string {
+	var ltypes []string
+	for _, k := range registered() {
+		if k.Wild == wild {
+			ltypes = append(ltypes, k.Inc)
+		}
+	}
+	return ltypes
+}
+
+// byKind returns get's Objects for each configured node of the kinds that do,
+// or don't, block subdomains
+func (c *Config) byKind(wild bool, get func(node string) *Objects) *Objects {
+	o := &Objects{Parms: c.Parms}
+	for _, k := range registered() {
+		if k.Wild == wild && c.tree[k.Name] != nil {
+			o.x = append(o.x, get(k.Name).x...)
+		}
+	}
+	return o
+}
+
+// isExc is true for exclusions, including the whitelist
+func (n ntype) isExc() bool {
+	if n == excRoot || n == allow {
+		return true
+	}
+	k := kindOf(n)
+	return k != nil && n == k.exc
+}
+
+// isInc is true for a node's explicit includes
+func (n ntype) isInc() bool {
+	k := kindOf(n)
+	return k != nil && n == k.inc
+}
+
+// isWild is true for sources and exclusions that also match subdomains
+func (n ntype) isWild() bool {
+	if n == excRoot || n == allow {
+		return true
+	}
+	k := kindOf(n)
+	return k != nil && k.Wild && n != k.inc
+}
+
+// wildNode is true if node's entries also match subdomains
+func wildNode(node string) bool {
+	if node == rootNode {
+		return true
+	}
+	k := kindNamed(node)
+	return k != nil && k.Wild
+}
+
+// typeName returns the node or label name of a registered kind's ntype
+func typeName(n ntype) string {
+	switch k := kindOf(n); {
+	case k == nil:
+		return ""
+	case n == k.inc:
+		return k.Inc
+	case n == k.exc:
+		return k.Exc
+	default:
+		return k.Name
+	}
+}
+
+// nameType returns the ntype of a registered kind's node or label name
+func nameType(s string) ntype {
+	if k := kindNamed(s); k != nil {
+		return k.n
+	}
+
+	switch k := kindLabelled(s); {
+	case k == nil:
+		return unknown
+	case s == k.Inc:
+		return k.inc
+	default:
+		return k.exc
+	}
+}
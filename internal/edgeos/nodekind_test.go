@@ -0,0 +1,94 @@
+package edgeos
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegisterNode(t *testing.T) {
+	Convey("Testing RegisterNode()", t, func() {
+		defer func() {
+			kindMu.Lock()
+			delete(kinds, "urls")
+			kindMu.Unlock()
+		}()
+
+		for _, name := range []string{"", rootNode, domains, hosts} {
+			So(RegisterNode(NodeKind{Name: name}), ShouldNotBeNil)
+		}
+
+		So(RegisterNode(NodeKind{Name: "urls"}), ShouldBeNil)
+		So(NodeKinds(), ShouldResemble, []string{domains, hosts, "urls"})
+
+		k := kindNamed("urls")
+		So(k.Inc, ShouldEqual, "pre-configured-urls")
+		So(k.Exc, ShouldEqual, "urls-excludes")
+
+		Convey("ntypes round trip through getType()", func() {
+			for _, s := range []string{"urls", "pre-configured-urls", "urls-excludes", domains, PreHosts, ExcRoots} {
+				So(getType(getType(s).(ntype)), ShouldEqual, s)
+			}
+			So(k.exc.isExc(), ShouldBeTrue)
+			So(k.inc.isInc(), ShouldBeTrue)
+			So(k.n.isWild(), ShouldBeFalse)
+		})
+
+		Convey("registering a name again keeps its ntypes", func() {
+			So(RegisterNode(NodeKind{Name: "urls", Wild: true}), ShouldBeNil)
+			So(kindNamed("urls").n, ShouldEqual, k.n)
+			So(kindNamed("urls").n.isWild(), ShouldBeTrue)
+			So(getSeparator("urls"), ShouldEqual, "/.")
+		})
+	})
+}
+
+func TestNodeKindContent(t *testing.T) {
+	Convey("Testing a registered node kind's content", t, func() {
+		So(RegisterNode(NodeKind{Name: "trackers"}), ShouldBeNil)
+		defer func() {
+			kindMu.Lock()
+			delete(kinds, "trackers")
+			kindMu.Unlock()
+		}()
+
+		cfg := `blacklist {
+    dns-redirect-ip 0.0.0.0
+    domains {
+        include ads.example.com
+    }
+    hosts {
+        include beap.gemini.yahoo.com
+    }
+    trackers {
+        exclude b.tracker.example.net
+        include a.tracker.example.net
+        include b.tracker.example.net
+        source feed {
+            url http://feeds.example.com/trackers.txt
+        }
+    }
+}`
+		c := NewConfig(
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes(NodeKinds()),
+			Precedence(PrecedenceExclude),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		ct, err := c.NewContent(URLhObj)
+		So(err, ShouldBeNil)
+		So(ct.Len(), ShouldEqual, 1)
+		So(ct.GetList().x[0].nType, ShouldEqual, kindNamed("trackers").n)
+
+		d := &dummyConfig{t: t}
+		for _, iface := range []IFace{ExHtObj, PreHObj} {
+			ct, err := c.NewContent(iface)
+			So(err, ShouldBeNil)
+			So(d.ProcessContent(ct), ShouldBeNil)
+		}
+		So(strings.Join(d.s, "\n"), ShouldEqual, "\naddress=/b.tracker.example.net/0.0.0.0\naddress=/beap.gemini.yahoo.com/0.0.0.0\naddress=/a.tracker.example.net/0.0.0.0")
+	})
+}
@@ -0,0 +1,49 @@
+package edgeos
+
+import (
+	"bytes"
+	"strings"
+)
+
+// normalizeDomain lower cases d and removes its trailing dot, so Example.COM.
+// and example.com are the same entry
+func normalizeDomain(d string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+}
+
+// rawForm returns fqdn as it is written in raw, the source line lowered is
+// the lower cased copy of, with its trailing dot if it has one
+func rawForm(raw, lowered, fqdn []byte) string {
+	i := bytes.Index(lowered, fqdn)
+	if i < 0 || len(raw) != len(lowered) {
+		return string(fqdn)
+	}
+
+	j := i + len(fqdn)
+	if j < len(raw) && raw[j] == '.' {
+		j++
+	}
+	return string(raw[i:j])
+}
+
+// variants records the form a source first wrote each entry in, if it
+// wasn't already normalized, to count the entries it lists again differing
+// only in case or a trailing dot
+type variants map[string]string
+
+// collides records that the source wrote entry k as form, returning true if
+// it already listed k in another form
+func (v variants) collides(k, form string, listed bool) bool {
+	first, ok := v[k]
+	if !ok && !listed {
+		if form != k {
+			v[k] = form
+		}
+		return false
+	}
+
+	if !ok {
+		first = k
+	}
+	return form != first
+}
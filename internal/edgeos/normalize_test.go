@@ -0,0 +1,67 @@
+package edgeos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	Convey("Testing normalizeDomain()", t, func() {
+		for _, d := range []string{"Example.COM.", " example.com", "EXAMPLE.com", "example.com"} {
+			So(normalizeDomain(d), ShouldEqual, "example.com")
+		}
+	})
+}
+
+func TestVariants(t *testing.T) {
+	Convey("Testing rawForm() and variants", t, func() {
+		raw := []byte("0.0.0.0 Ads.Example.COM.")
+		So(rawForm(raw, bytes.ToLower(raw), []byte("ads.example.com")), ShouldEqual, "Ads.Example.COM.")
+		So(rawForm(raw, bytes.ToLower(raw), []byte("missing.com")), ShouldEqual, "missing.com")
+
+		v := make(variants)
+		So(v.collides("example.com", "Example.COM.", false), ShouldBeFalse)
+		So(v.collides("example.com", "Example.COM.", true), ShouldBeFalse)
+		So(v.collides("example.com", "example.com", true), ShouldBeTrue)
+
+		So(v.collides("ads.com", "ads.com", false), ShouldBeFalse)
+		So(v.collides("ads.com", "ads.com", true), ShouldBeFalse)
+		So(v.collides("ads.com", "ADS.com", true), ShouldBeTrue)
+		So(v, ShouldResemble, variants{"example.com": "Example.COM."})
+	})
+}
+
+func TestCollisions(t *testing.T) {
+	Convey("Testing ProcessContent() with entries differing in case and trailing dots", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		src := dir + "/feed.txt"
+		So(ioutil.WriteFile(src, []byte("Example.COM.\nexample.com\nEXAMPLE.com\nads.example.net\nads.example.net\nTracker.example.org.\nTracker.example.org.\n"), 0644), ShouldBeNil)
+
+		var b bytes.Buffer
+		c := NewConfig(
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains}),
+			Prefix("address="),
+			Stats(NewStatus("")),
+			Writer(&b),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\texclude Excluded.Example.NET.\n\t\tinclude Included.Example.COM.\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"}), ShouldBeNil)
+		So(c.tree[domains].exc, ShouldResemble, []string{"excluded.example.net"})
+		So(c.tree[domains].inc, ShouldResemble, []string{"included.example.com"})
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		So(b.String(), ShouldEqual, "address=/.ads.example.net/0.0.0.0\naddress=/.example.com/0.0.0.0\naddress=/.tracker.example.org/0.0.0.0\n")
+		So(c.Status.Sources[0].Entries, ShouldEqual, 3)
+		So(c.Status.Sources[0].Dupes, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,66 @@
+package edgeos
+
+import (
+	"os"
+	"syscall"
+)
+
+// notifyMask is the inotify events that change, create or remove a file
+const notifyMask = syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// dirNotify watches directories with inotify, sending on c whenever a file
+// in one of them changes
+type dirNotify struct {
+	f    *os.File
+	fd   int
+	dirs map[string]bool
+}
+
+// newDirNotify returns a *dirNotify sending on c, which should be buffered;
+// the send is skipped while c is full
+func newDirNotify(c chan<- struct{}) (*dirNotify, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	// a non-blocking fd is read through the runtime poller, so close
+	// interrupts the read
+	n := &dirNotify{f: os.NewFile(uintptr(fd), "inotify"), fd: fd, dirs: make(map[string]bool)}
+	go n.read(c)
+	return n, nil
+}
+
+// add watches dir, if it isn't already
+func (n *dirNotify) add(dir string) error {
+	if n.dirs[dir] {
+		return nil
+	}
+
+	if _, err := syscall.InotifyAddWatch(n.fd, dir, notifyMask); err != nil {
+		return os.NewSyscallError("inotify_add_watch "+dir, err)
+	}
+	n.dirs[dir] = true
+	return nil
+}
+
+// read sends on c for each batch of events until n is closed
+func (n *dirNotify) read(c chan<- struct{}) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		if _, err := n.f.Read(buf); err != nil {
+			return
+		}
+
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// close stops watching
+func (n *dirNotify) close() error {
+	return n.f.Close()
+}
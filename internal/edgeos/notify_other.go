@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package edgeos
+
+import "errors"
+
+// dirNotify is unsupported, a FileWatch polls instead
+type dirNotify struct{}
+
+// newDirNotify fails, other OSes have no inotify
+func newDirNotify(chan<- struct{}) (*dirNotify, error) {
+	return nil, errors.New("file notifications are only supported on Linux")
+}
+
+func (n *dirNotify) add(string) error { return nil }
+
+func (n *dirNotify) close() error { return nil }
@@ -4,9 +4,9 @@ package edgeos
 
 import "fmt"
 
-const ntypeName = "unknowndomnexcDomnexcHostexcRoothostpreDomnpreHostrootzone"
+const ntypeName = "unknowndomnexcDomnexcHostexcRoothostpreDomnpreHostrootzoneallow"
 
-var ntypeIndex = [...]uint8{7, 11, 18, 25, 32, 36, 43, 50, 54, 58}
+var ntypeIndex = [...]uint8{7, 11, 18, 25, 32, 36, 43, 50, 54, 58, 63}
 
 func (i ntype) String() string {
 	if i < 0 || i >= ntype(len(ntypeIndex)) {
@@ -5,25 +5,53 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 )
 
 // object struct for normalizing EdgeOS data.
 type object struct {
 	*Parms
+	blocking string
+	catalog  string
 	desc     string
 	disabled bool
+	dupes    int
 	err      error
 	exc      []string
+	expires  map[string]time.Time
 	file     string
+	final    string
+	held     int
+	identity string
 	inc      []string
 	ip       string
 	ltype    string
+	maxsize  int64
 	name     string
 	nType    ntype
 	Objects
-	prefix string
-	r      io.Reader
-	url    string
+	origin    map[string]string
+	parked    string
+	parseURL  string
+	part      *partial
+	pins      []string
+	prefix    string
+	processor string
+	r         io.Reader
+	rate      int64
+	redirect  string
+	rejected  int
+	rejects   []string
+	retry     bool
+	rewrites  []*rewrite
+	secrets   []secret
+	sinkholes []string
+	spill     bool
+	tags      map[string]int
+	url       string
+	via       string
+	weight    float64
+	wilds     list
 }
 
 // Objects is a struct of []*Object
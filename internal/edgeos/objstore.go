@@ -0,0 +1,445 @@
+package edgeos
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	schemeGS = "gs://"
+	schemeS3 = "s3://"
+
+	amzDate     = "20060102T150405Z"
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	gcsScope    = "https://www.googleapis.com/auth/devstorage.read_only"
+	jwtBearer   = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+// googleTokenURL is the OAuth2 endpoint used to refresh gcloud user credentials
+var googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// authorizer adds credentials to an object storage request
+type authorizer func(*http.Client, *http.Request) error
+
+// objectURL maps s3:// and gs:// source URLs to their HTTPS endpoints along
+// with an authorizer using the provider's standard credential chain, other
+// URLs are returned unchanged with a nil authorizer
+func (o *object) objectURL() (string, authorizer, error) {
+	lower := strings.ToLower(o.url)
+	switch {
+	case strings.HasPrefix(lower, schemeS3):
+		return s3URL(o.url[len(schemeS3):])
+	case strings.HasPrefix(lower, schemeGS):
+		return gsURL(o.url[len(schemeGS):])
+	}
+	return o.url, nil, nil
+}
+
+// splitObject splits "bucket/key" into its parts
+func splitObject(s string) (bucket, key string, err error) {
+	i := strings.Index(s, "/")
+	if i < 1 || i == len(s)-1 {
+		return "", "", fmt.Errorf("object URL %q must be of the form bucket/key", s)
+	}
+	return s[:i], s[i+1:], nil
+}
+
+// getenv returns the first non-empty environment variable in keys
+func getenv(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// homeFile returns a path relative to the user's home directory
+func homeFile(elem ...string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(append([]string{home}, elem...)...)
+}
+
+// awsEscape URI encodes s as required by AWS Signature Version 4, leaving
+// slashes intact
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsCreds holds an AWS access key
+type awsCreds struct {
+	id, secret, token string
+}
+
+// s3URL returns the endpoint and authorizer for an S3 object, the endpoint
+// can be overridden with AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL
+func s3URL(s string) (string, authorizer, error) {
+	bucket, key, err := splitObject(s)
+	if err != nil {
+		return "", nil, err
+	}
+
+	region := getenv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	u := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, awsEscape(key))
+	if ep := getenv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); ep != "" {
+		u = fmt.Sprintf("%s/%s/%s", strings.TrimRight(ep, "/"), bucket, awsEscape(key))
+	}
+
+	creds, err := awsCredentials()
+	if err != nil || creds == nil {
+		return u, nil, err
+	}
+
+	return u, func(_ *http.Client, req *http.Request) error {
+		creds.sign(req, region, time.Now().UTC())
+		return nil
+	}, nil
+}
+
+// awsCredentials looks for credentials in the environment and then in the
+// shared credentials file, it returns nil for anonymous access
+func awsCredentials() (*awsCreds, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return &awsCreds{id: id, secret: secret, token: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	file := getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if file == "" {
+		if file = homeFile(".aws", "credentials"); file == "" {
+			return nil, nil
+		}
+	}
+
+	profile := getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		c       = &awsCreds{}
+		section string
+	)
+
+	b := bufio.NewScanner(f)
+	for b.Scan() {
+		line := strings.TrimSpace(b.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "["):
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+		case section == profile:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v := strings.TrimSpace(kv[1])
+			switch strings.TrimSpace(kv[0]) {
+			case "aws_access_key_id":
+				c.id = v
+			case "aws_secret_access_key":
+				c.secret = v
+			case "aws_session_token":
+				c.token = v
+			}
+		}
+	}
+	if err = b.Err(); err != nil {
+		return nil, err
+	}
+
+	if c.id == "" || c.secret == "" {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req, signing
+// the payload hash in its X-Amz-Content-Sha256 header or an empty payload's
+func (c *awsCreds) sign(req *http.Request, region string, now time.Time) {
+	stamp := now.Format(amzDate)
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", stamp[:8], region)
+
+	payload := req.Header.Get("X-Amz-Content-Sha256")
+	if payload == "" {
+		payload = emptySHA256
+	}
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if c.token != "" {
+		req.Header.Set("X-Amz-Security-Token", c.token)
+	}
+
+	hdrs := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			hdrs[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+
+	names := make([]string, 0, len(hdrs))
+	for k := range hdrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canon strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canon, "%s:%s\n", k, hdrs[k])
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	creq := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canon.String(),
+		signed,
+		payload,
+	}, "\n")
+
+	sum := sha256.Sum256([]byte(creq))
+	sts := strings.Join([]string{"AWS4-HMAC-SHA256", stamp, scope, hex.EncodeToString(sum[:])}, "\n")
+
+	key := []byte("AWS4" + c.secret)
+	for _, s := range []string{stamp[:8], region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.id, scope, signed, hex.EncodeToString(hmacSHA256(key, sts))))
+}
+
+// canonicalQuery returns the sorted, encoded query string for signing
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, awsEscape(k)+"="+strings.Replace(awsEscape(v), "/", "%2F", -1))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// gcsCreds holds the fields used from a Google credentials file
+type gcsCreds struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gsURL returns the endpoint and authorizer for a GCS object, the endpoint
+// can be overridden with STORAGE_EMULATOR_HOST
+func gsURL(s string) (string, authorizer, error) {
+	bucket, key, err := splitObject(s)
+	if err != nil {
+		return "", nil, err
+	}
+
+	host := "https://storage.googleapis.com"
+	if h := getenv("STORAGE_EMULATOR_HOST"); h != "" {
+		host = strings.TrimRight(h, "/")
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+	}
+
+	u := fmt.Sprintf("%s/download/storage/v1/b/%s/o/%s?alt=media", host, url.PathEscape(bucket), url.PathEscape(key))
+
+	creds, err := gcsCredentials()
+	if err != nil || creds == nil {
+		return u, nil, err
+	}
+
+	return u, func(client *http.Client, req *http.Request) error {
+		token, err := creds.token(client, time.Now())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}, nil
+}
+
+// gcsCredentials reads GOOGLE_APPLICATION_CREDENTIALS or the gcloud
+// application default credentials, it returns nil for anonymous access
+func gcsCredentials() (*gcsCreds, error) {
+	file := getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if file == "" {
+		if file = homeFile(".config", "gcloud", "application_default_credentials.json"); file == "" {
+			return nil, nil
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &gcsCreds{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+
+	switch c.Type {
+	case "service_account", "authorized_user":
+		return c, nil
+	}
+	return nil, fmt.Errorf("%v: unsupported credentials type %q", file, c.Type)
+}
+
+// token exchanges the credentials for an OAuth2 access token
+func (c *gcsCreds) token(client *http.Client, now time.Time) (string, error) {
+	var (
+		endpoint = googleTokenURL
+		form     = url.Values{}
+	)
+
+	switch c.Type {
+	case "service_account":
+		jwt, err := c.assertion(now)
+		if err != nil {
+			return "", err
+		}
+		if c.TokenURI != "" {
+			endpoint = c.TokenURI
+		}
+		form.Set("grant_type", jwtBearer)
+		form.Set("assertion", jwt)
+	default:
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+		form.Set("refresh_token", c.RefreshToken)
+	}
+
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %v failed: %v", endpoint, resp.Status)
+	}
+
+	var t struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", err
+	}
+	if t.AccessToken == "" {
+		return "", fmt.Errorf("token request to %v returned no access token", endpoint)
+	}
+	return t.AccessToken, nil
+}
+
+// assertion returns a signed JWT for the service account token exchange
+func (c *gcsCreds) assertion(now time.Time) (string, error) {
+	blk, _ := pem.Decode([]byte(c.PrivateKey))
+	if blk == nil {
+		return "", errors.New("service account private_key is not PEM encoded")
+	}
+
+	k, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+	if err != nil {
+		if k, err = x509.ParsePKCS1PrivateKey(blk.Bytes); err != nil {
+			return "", err
+		}
+	}
+
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("service account private_key is not an RSA key")
+	}
+
+	aud := c.TokenURI
+	if aud == "" {
+		aud = googleTokenURL
+	}
+
+	hdr, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   c.ClientEmail,
+		"scope": gcsScope,
+		"aud":   aud,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	enc := base64.RawURLEncoding
+	msg := enc.EncodeToString(hdr) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(msg))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return msg + "." + enc.EncodeToString(sig), nil
+}
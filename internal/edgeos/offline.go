@@ -0,0 +1,43 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotCached is returned in Offline mode for url sources without a cached copy
+var ErrNotCached = errors.New("offline and no cached copy, url sources are only cached if Cache is set")
+
+// offline replaces getHTTP in Offline mode, reading the source's copy from
+// the Cache directory instead of fetching it
+func (o *object) offline() *object {
+	u, err := o.sourceURL(o.url)
+	if err != nil {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("Refused plain HTTP for %s...", redact(o.url, o.secrets))), err
+		return o
+	}
+
+	if !o.cacheable() {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("No cached copy of %s...", redact(o.url, o.secrets))), ErrNotCached
+		return o
+	}
+
+	m, err := o.readCache()
+	if err == nil && m.URL == u {
+		err = o.useCache()
+	}
+
+	switch {
+	case err != nil:
+		o.r, o.err = strings.NewReader(fmt.Sprintf("No cached copy of %s...", redact(o.url, o.secrets))), fmt.Errorf("offline: %v", err)
+		return o
+	case m.URL != u:
+		o.r, o.err = strings.NewReader(fmt.Sprintf("No cached copy of %s...", redact(o.url, o.secrets))), fmt.Errorf("offline: cached copy is of %v", m.URL)
+		return o
+	}
+
+	o.log(fmt.Sprintf("%v offline, using cached copy", o.name))
+	o.url, o.final, o.err = u, u, nil
+	return o
+}
@@ -0,0 +1,65 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOffline(t *testing.T) {
+	Convey("Testing getHTTP() in Offline mode", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			data     = "0.0.0.0 ads.example.com\n"
+			requests int
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprint(w, data)
+		}))
+		defer srv.Close()
+
+		o := getHTTP(&object{Parms: &Parms{Cache: dir, Method: http.MethodGet}, ltype: urls, name: "cached", nType: host, url: srv.URL})
+		So(o.err, ShouldBeNil)
+		So(requests, ShouldEqual, 1)
+
+		tests := []struct {
+			name string
+			o    *object
+			err  bool
+			exp  string
+		}{
+			{name: "cached", o: &object{Parms: &Parms{Cache: dir, Offline: true}, ltype: urls, name: "cached", nType: host, url: srv.URL}, exp: data},
+			{name: "not cached", o: &object{Parms: &Parms{Cache: dir, Offline: true}, ltype: urls, name: "missing", nType: host, url: srv.URL}, err: true, exp: "No cached copy of " + srv.URL + "..."},
+			{name: "different url", o: &object{Parms: &Parms{Cache: dir, Offline: true}, ltype: urls, name: "cached", nType: host, url: srv.URL + "/other"}, err: true, exp: "No cached copy of " + srv.URL + "/other..."},
+			{name: "no cache directory", o: &object{Parms: &Parms{Offline: true}, ltype: urls, name: "cached", nType: host, url: srv.URL}, err: true, exp: "No cached copy of " + srv.URL + "..."},
+		}
+
+		for _, tt := range tests {
+			Convey("with a source that is "+tt.name, func() {
+				o := getHTTP(tt.o)
+				So(o.err != nil, ShouldEqual, tt.err)
+				So(requests, ShouldEqual, 1)
+
+				b, err := ioutil.ReadAll(o.r)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, tt.exp)
+			})
+		}
+
+		Convey("default exclusions aren't fetched", func() {
+			c := NewConfig(Offline(true), DefExc(ExcDefaults{URL: srv.URL}))
+			So(c.defaults().Origin, ShouldEqual, "built-in")
+			So(requests, ShouldEqual, 1)
+		})
+	})
+}
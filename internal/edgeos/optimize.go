@@ -0,0 +1,150 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceUsage records how many of a generated source's domains were queried
+type SourceUsage struct {
+	Node    string
+	Source  string
+	Domains int
+	Queried int
+	Hits    int
+}
+
+// entryDomain returns the domain in a generated dnsmasq line such as
+// address=/.example.com/0.0.0.0, wild is true if it also matches subdomains
+func entryDomain(line string) (domain string, wild bool) {
+	f := strings.SplitN(line, "/", 3)
+	if len(f) < 3 || f[1] == "" {
+		return "", false
+	}
+	return strings.TrimPrefix(f[1], "."), strings.HasPrefix(f[1], ".")
+}
+
+// hits returns the number of blocked queries in q for a generated entry,
+// wildcard entries also count their subdomains' queries
+func hits(q *QueryStats, domain string, wild bool, sub map[string]int) int {
+	n := q.Domains[domain]
+	if wild {
+		n += sub[domain]
+	}
+	return n
+}
+
+// subdomainHits totals the blocked queries in q for each parent domain
+func subdomainHits(q *QueryStats) map[string]int {
+	sub := make(map[string]int)
+	for d, n := range q.Domains {
+		for i := strings.Index(d, "."); i > 0; i = strings.Index(d, ".") {
+			d = d[i+1:]
+			sub[d] += n
+		}
+	}
+	return sub
+}
+
+// eachGenerated calls fn with each generated file's node, source and lines
+func (c *Config) eachGenerated(fn func(node, source string, r io.Reader) error) error {
+	names, err := c.generated()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := strings.TrimSuffix(filepath.Base(name), "."+c.Ext)
+		node, source := base, ""
+		if i := strings.Index(base, "."); i >= 0 {
+			node, source = base[:i], base[i+1:]
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		err = fn(node, source, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Usage returns how many of each generated source's domains were blocked in
+// q, least used sources first
+func (c *Config) Usage(q *QueryStats) ([]SourceUsage, error) {
+	var (
+		sub   = subdomainHits(q)
+		usage []SourceUsage
+	)
+
+	err := c.eachGenerated(func(node, source string, r io.Reader) error {
+		u := SourceUsage{Node: node, Source: source}
+		b := bufio.NewScanner(r)
+		for b.Scan() {
+			d, wild := entryDomain(b.Text())
+			if d == "" {
+				continue
+			}
+			u.Domains++
+			if n := hits(q, d, wild, sub); n > 0 {
+				u.Queried++
+				u.Hits += n
+			}
+		}
+		usage = append(usage, u)
+		return b.Err()
+	})
+
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].Hits != usage[j].Hits {
+			return usage[i].Hits < usage[j].Hits
+		}
+		return usage[i].Domains > usage[j].Domains
+	})
+	return usage, err
+}
+
+// WriteHot writes the generated entries blocked at least min times in q to
+// w, it returns the number of entries written
+func (c *Config) WriteHot(w io.Writer, q *QueryStats, min int) (int, error) {
+	if min < 1 {
+		min = 1
+	}
+
+	var (
+		bw   = bufio.NewWriter(w)
+		n    int
+		seen = make(map[string]bool)
+		sub  = subdomainHits(q)
+	)
+
+	err := c.eachGenerated(func(_, _ string, r io.Reader) error {
+		b := bufio.NewScanner(r)
+		for b.Scan() {
+			line := b.Text()
+			d, wild := entryDomain(line)
+			if d == "" || seen[line] || hits(q, d, wild, sub) < min {
+				continue
+			}
+			seen[line] = true
+			n++
+			fmt.Fprintln(bw, line)
+		}
+		return b.Err()
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
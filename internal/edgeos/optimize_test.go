@@ -0,0 +1,95 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEntryDomain(t *testing.T) {
+	Convey("Testing entryDomain()", t, func() {
+		tests := []struct {
+			line string
+			exp  string
+			wild bool
+		}{
+			{line: "address=/.zeus.com/0.0.0.0", exp: "zeus.com", wild: true},
+			{line: "address=/ads.yoyo.org/0.0.0.0", exp: "ads.yoyo.org"},
+			{line: "server=/ads.yoyo.org/", exp: "ads.yoyo.org"},
+			{line: "# comment"},
+			{line: "address=//0.0.0.0"},
+		}
+
+		for _, tt := range tests {
+			act, wild := entryDomain(tt.line)
+			So(act, ShouldEqual, tt.exp)
+			So(wild, ShouldEqual, tt.wild)
+		}
+	})
+}
+
+func TestUsage(t *testing.T) {
+	Convey("Testing Usage() and WriteHot()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+
+		files := map[string]string{
+			"domains.zeus.blacklist.conf":  "address=/.zeus.com/0.0.0.0\naddress=/.malware.net/0.0.0.0\n",
+			"hosts.yoyo.blacklist.conf":    "address=/ads.yoyo.org/0.0.0.0\naddress=/track.yoyo.org/0.0.0.0\naddress=/pix.yoyo.org/0.0.0.0\n",
+			"hosts.unused.blacklist.conf":  "address=/never.example.com/0.0.0.0\n",
+			"hosts.unused2.blacklist.conf": "address=/never.example.com/0.0.0.0\naddress=/never.example.org/0.0.0.0\n",
+		}
+		for f, data := range files {
+			So(ioutil.WriteFile(fmt.Sprintf("%v/%v", dir, f), []byte(data), 0644), ShouldBeNil)
+		}
+
+		q := NewQueryStats(time.Now(), time.Hour)
+		q.Domains = map[string]int{
+			"c2.zeus.com":   3,
+			"zeus.com":      1,
+			"ads.yoyo.org":  2,
+			"pix.yoyo.org":  1,
+			"zeus.com.evil": 5,
+		}
+
+		usage, err := c.Usage(q)
+		So(err, ShouldBeNil)
+		So(usage, ShouldResemble, []SourceUsage{
+			{Node: "hosts", Source: "unused2", Domains: 2},
+			{Node: "hosts", Source: "unused", Domains: 1},
+			{Node: "hosts", Source: "yoyo", Domains: 3, Queried: 2, Hits: 3},
+			{Node: "domains", Source: "zeus", Domains: 2, Queried: 1, Hits: 4},
+		})
+
+		tests := []struct {
+			min int
+			exp string
+			n   int
+		}{
+			{min: 0, exp: "address=/.zeus.com/0.0.0.0\naddress=/ads.yoyo.org/0.0.0.0\naddress=/pix.yoyo.org/0.0.0.0\n", n: 3},
+			{min: 2, exp: "address=/.zeus.com/0.0.0.0\naddress=/ads.yoyo.org/0.0.0.0\n", n: 2},
+			{min: 10, exp: "", n: 0},
+		}
+
+		for _, tt := range tests {
+			act := new(bytes.Buffer)
+			n, err := c.WriteHot(act, q, tt.min)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, tt.n)
+			So(act.String(), ShouldEqual, tt.exp)
+		}
+	})
+}
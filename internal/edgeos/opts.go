@@ -1,6 +1,8 @@
 package edgeos
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"io"
 	"runtime"
@@ -12,37 +14,116 @@ import (
 
 // Parms is struct of parameters
 type Parms struct {
+	audit    *Audit
+	cancel   context.CancelFunc
+	cat      *loadedCatalog
+	ctx      context.Context
+	fails    *failDB
+	fresh    *freshDB
+	guard    map[string]string
 	ioWriter io.Writer
+	ips      *ipSet
+	limit    *limiter
+	psl      *suffixList
+	seen     *seenDB
+	state    *RunState
+	watch    *stopwatch
 	*logging.Logger
-	API     string        `json:"API, omitempty"`
-	Arch    string        `json:"Arch, omitempty"`
-	Bash    string        `json:"Bash, omitempty"`
-	Cores   int           `json:"Cores, omitempty"`
-	Dbug    bool          `json:"Dbug, omitempty"`
-	Dex     list          `json:"Dex, omitempty"`
-	Dir     string        `json:"Dir, omitempty"`
-	DNSsvc  string        `json:"dnsmasq service, omitempty"`
-	Exc     list          `json:"Exc, omitempty"`
-	Ext     string        `json:"dnsmasq fileExt., omitempty"`
-	File    string        `json:"File, omitempty"`
-	FnFmt   string        `json:"File name fmt, omitempty"`
-	InCLI   string        `json:"-"`
-	Level   string        `json:"CLI Path, omitempty"`
-	Ltypes  []string      `json:"Leaf nodes, omitempty"`
-	Method  string        `json:"HTTP method, omitempty"`
-	Nodes   []string      `json:"Nodes, omitempty"`
-	Pfx     string        `json:"Prefix, omitempty"`
-	Poll    int           `json:"Poll, omitempty"`
-	Test    bool          `json:"Test, omitempty"`
-	Timeout time.Duration `json:"Timeout, omitempty"`
-	Verb    bool          `json:"Verbosity, omitempty"`
+	API     string            `json:"API, omitempty"`
+	Arch    string            `json:"Arch, omitempty"`
+	Base    string            `json:"BaseDir,omitempty"`
+	Bash    string            `json:"Bash, omitempty"`
+	Cache   string            `json:"Cache,omitempty"`
+	CAfile  string            `json:"CAfile,omitempty"`
+	CatFile string            `json:"CatalogFile,omitempty"`
+	CatKey  ed25519.PublicKey `json:"-"`
+	CatURL  string            `json:"CatalogURL,omitempty"`
+	Chaos   *Chaos            `json:"-"`
+	Cores   int               `json:"Cores, omitempty"`
+	Counts  bool              `json:"Counts,omitempty"`
+	Dbug    bool              `json:"Dbug, omitempty"`
+	DefExc  ExcDefaults       `json:"DefExc,omitempty"`
+	Dedupe  string            `json:"Dedupe,omitempty"`
+	Determ  bool              `json:"Deterministic,omitempty"`
+	Dex     list              `json:"Dex, omitempty"`
+	Dir     string            `json:"Dir, omitempty"`
+	Dline   time.Duration     `json:"Deadline,omitempty"`
+	DNSctl  ServiceController `json:"-"`
+	DNSsvc  string            `json:"dnsmasq service, omitempty"`
+	DoHList []string          `json:"DoHList,omitempty"`
+	DoHURL  string            `json:"DoHURL,omitempty"`
+	Exc     list              `json:"Exc, omitempty"`
+	Ext     string            `json:"dnsmasq fileExt., omitempty"`
+	FailDB  string            `json:"FailFile,omitempty"`
+	Fetches int               `json:"Fetches,omitempty"`
+	File    string            `json:"File, omitempty"`
+	FnFmt   string            `json:"File name fmt, omitempty"`
+	Force   bool              `json:"Force,omitempty"`
+	Gzip    bool              `json:"Gzip,omitempty"`
+	Hist    string            `json:"HistoryFile,omitempty"`
+	HistDay int               `json:"HistoryDays,omitempty"`
+	Hold    time.Duration     `json:"Quarantine,omitempty"`
+	HTTPS   string            `json:"HTTPS,omitempty"`
+	InCLI   string            `json:"-"`
+	Level   string            `json:"CLI Path, omitempty"`
+	LineBuf int               `json:"LineBuffer,omitempty"`
+	LogFile string            `json:"LogFile,omitempty"`
+	LogKeep int               `json:"LogKeep,omitempty"`
+	LogSize int64             `json:"LogSize,omitempty"`
+	Ltypes  []string          `json:"Leaf nodes, omitempty"`
+	MACKey  string            `json:"HMACKey,omitempty"`
+	MaxChg  float64           `json:"MaxChange,omitempty"`
+	MaxFail int               `json:"MaxFailures,omitempty"`
+	MaxMem  int               `json:"MaxMemoryMB,omitempty"`
+	MaxSize int64             `json:"MaxSize,omitempty"`
+	Method  string            `json:"HTTP method, omitempty"`
+	Nice    int               `json:"Nice,omitempty"`
+	Nodes   []string          `json:"Nodes, omitempty"`
+	Offline bool              `json:"Offline,omitempty"`
+	Pfx     string            `json:"Prefix, omitempty"`
+	Pins    []string          `json:"Pins,omitempty"`
+	Poll    int               `json:"Poll, omitempty"`
+	Prec    string            `json:"Precedence,omitempty"`
+	Prog    ProgressFunc      `json:"-"`
+	Protect []string          `json:"Protect,omitempty"`
+	PSL     string            `json:"PSL,omitempty"`
+	PSLURL  string            `json:"PSLURL,omitempty"`
+	PushKey ed25519.PublicKey `json:"-"`
+	Rate    int64             `json:"RateLimit,omitempty"`
+	Redact  []string          `json:"Redact,omitempty"`
+	Redirs  int               `json:"Redirects,omitempty"`
+	Refuse  bool              `json:"RefuseSuffixes,omitempty"`
+	Resolv  string            `json:"Resolver,omitempty"`
+	Resumes int               `json:"Resumes,omitempty"`
+	Runner  Runner            `json:"-"`
+	Sanity  bool              `json:"Sanity,omitempty"`
+	Seen    string            `json:"SeenFile,omitempty"`
+	Shard   int               `json:"Shard,omitempty"`
+	Stale   int               `json:"StaleDays,omitempty"`
+	StaleDB string            `json:"StaleFile,omitempty"`
+	State   []string          `json:"StateFiles,omitempty"`
+	Status  *Status           `json:"-"`
+	Strict  bool              `json:"Strict,omitempty"`
+	SysFac  string            `json:"SyslogFacility,omitempty"`
+	Syslog  string            `json:"Syslog,omitempty"`
+	SysTag  string            `json:"SyslogTag,omitempty"`
+	Test    bool              `json:"Test, omitempty"`
+	Thresh  float64           `json:"Threshold,omitempty"`
+	Timeout time.Duration     `json:"Timeout, omitempty"`
+	Times   bool              `json:"Timings,omitempty"`
+	Top     string            `json:"TopDomains,omitempty"`
+	TopURL  string            `json:"TopURL,omitempty"`
+	Tor     string            `json:"Tor,omitempty"`
+	Xform   string            `json:"Transform,omitempty"`
+	Verb    bool              `json:"Verbosity, omitempty"`
+	Windows []*Schedule       `json:"RefreshWindows,omitempty"`
 	Wildcard/*.........*/ `json:"Wildcard, omitempty"`
 }
 
 // Wildcard struct sets globbing wildcards for filename searches
 type Wildcard struct {
-	Node string `json:"omitempty"`
-	Name string `json:"omitempty"`
+	Node string `json:"node,omitempty"`
+	Name string `json:"name,omitempty"`
 }
 
 // Option is a recursive function
@@ -95,6 +176,15 @@ func API(s string) Option {
 	}
 }
 
+// BaseDir sets the directory relative file sources are resolved against
+func BaseDir(dir string) Option {
+	return func(c *Config) Option {
+		previous := c.Base
+		c.Base = dir
+		return BaseDir(previous)
+	}
+}
+
 // Bash sets the shell processor
 func Bash(cmd string) Option {
 	return func(c *Config) Option {
@@ -104,6 +194,53 @@ func Bash(cmd string) Option {
 	}
 }
 
+// Cache sets the directory url sources are cached in, a cached source is only
+// downloaded again if a HEAD pre-check shows its length or modification time
+// has changed
+func Cache(dir string) Option {
+	return func(c *Config) Option {
+		previous := c.Cache
+		c.Cache = dir
+		return Cache(previous)
+	}
+}
+
+// CAfile sets a PEM CA bundle trusted for HTTPS sources in addition to the system roots
+func CAfile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.CAfile
+		c.CAfile = f
+		return CAfile(previous)
+	}
+}
+
+// CatalogFile sets the file a downloaded catalog is kept in between runs
+func CatalogFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.CatFile
+		c.CatFile, c.cat = f, nil
+		return CatalogFile(previous)
+	}
+}
+
+// CatalogKey sets the public key a downloaded catalog must be signed with
+func CatalogKey(k ed25519.PublicKey) Option {
+	return func(c *Config) Option {
+		previous := c.CatKey
+		c.CatKey, c.cat = k, nil
+		return CatalogKey(previous)
+	}
+}
+
+// CatalogURL sets the URL of the signed catalog that replaces the built-in one
+func CatalogURL(url string) Option {
+	return func(c *Config) Option {
+		previous := c.CatURL
+		c.CatURL, c.cat = url, nil
+		return CatalogURL(previous)
+	}
+}
+
 // Cores sets max CPU cores
 func Cores(i int) Option {
 	return func(c *Config) Option {
@@ -114,6 +251,39 @@ func Cores(i int) Option {
 	}
 }
 
+// CollectIPs toggles collecting raw IP address entries from sources, see IPs
+func CollectIPs(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.ips != nil
+		c.ips = nil
+		if b {
+			c.ips = &ipSet{Mutex: &sync.Mutex{}, entry: make(map[string]struct{})}
+		}
+		return CollectIPs(previous)
+	}
+}
+
+// HMACKey sets the file holding the key used to sign generated files, it is
+// created with a random key if missing; each file's HMAC is written to its
+// .count file and Verify reports files modified outside blacklist
+func HMACKey(file string) Option {
+	return func(c *Config) Option {
+		previous := c.MACKey
+		c.MACKey = file
+		return HMACKey(previous)
+	}
+}
+
+// Counts toggles writing a .count file of each generated file's entry count
+// and hash beside it, which Verify checks the files against
+func Counts(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Counts
+		c.Counts = b
+		return Counts(previous)
+	}
+}
+
 // Dbug toggles debug level on or off
 func Dbug(b bool) Option {
 	return func(c *Config) Option {
@@ -123,6 +293,48 @@ func Dbug(b bool) Option {
 	}
 }
 
+// Deadline limits how long the rest of the run may take, once it passes
+// downloads are cancelled and no more files are written, 0 is unlimited
+func Deadline(d time.Duration) Option {
+	return func(c *Config) Option {
+		previous := c.Dline
+		if c.cancel != nil {
+			c.cancel()
+		}
+
+		c.Dline, c.ctx, c.cancel = d, nil, nil
+		if d > 0 {
+			c.ctx, c.cancel = context.WithTimeout(context.Background(), d)
+		}
+		return Deadline(previous)
+	}
+}
+
+// DefExc sets where the default exclusions are loaded from, see LoadDefaults
+func DefExc(d ExcDefaults) Option {
+	return func(c *Config) Option {
+		previous := c.DefExc
+		c.DefExc = d
+		return DefExc(previous)
+	}
+}
+
+// Deterministic toggles reproducible output, sources are processed in name
+// order, so a domain listed by several is always written under the same one,
+// and rendered RPZ zones get a serial computed from their entries instead of
+// the time. It sets the serial for every Config, as Cores does GOMAXPROCS
+func Deterministic(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Determ
+		c.Determ = b
+		zoneSerial = timeSerial
+		if b {
+			zoneSerial = entrySerial
+		}
+		return Deterministic(previous)
+	}
+}
+
 // Dir sets directory location
 func Dir(d string) Option {
 	return func(c *Config) Option {
@@ -132,6 +344,15 @@ func Dir(d string) Option {
 	}
 }
 
+// DNSctl sets the ServiceController used to reload the DNS service
+func DNSctl(s ServiceController) Option {
+	return func(c *Config) Option {
+		previous := c.DNSctl
+		c.DNSctl = s
+		return DNSctl(previous)
+	}
+}
+
 // DNSsvc sets dnsmasq restart command
 func DNSsvc(d string) Option {
 	return func(c *Config) Option {
@@ -141,6 +362,24 @@ func DNSsvc(d string) Option {
 	}
 }
 
+// DoHList overrides the built-in DoH provider domains
+func DoHList(s []string) Option {
+	return func(c *Config) Option {
+		previous := c.DoHList
+		c.DoHList = s
+		return DoHList(previous)
+	}
+}
+
+// DoHURL sets the canonical URL the DoH provider domains are updated from
+func DoHURL(s string) Option {
+	return func(c *Config) Option {
+		previous := c.DoHURL
+		c.DoHURL = s
+		return DoHURL(previous)
+	}
+}
+
 // Ext sets the blacklist file n extension
 func Ext(e string) Option {
 	return func(c *Config) Option {
@@ -150,6 +389,16 @@ func Ext(e string) Option {
 	}
 }
 
+// FailFile sets the file recording each source's consecutive failed fetches
+func FailFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.FailDB
+		c.FailDB = f
+		c.fails = nil
+		return FailFile(previous)
+	}
+}
+
 // File sets the EdgeOS configuration file
 func File(f string) Option {
 	return func(c *Config) Option {
@@ -168,6 +417,54 @@ func FileNameFmt(f string) Option {
 	}
 }
 
+// Force writes a run's files even if it changes more entries than MaxChange
+// allows or blocks popular domains Sanity checks for
+func Force(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Force
+		c.Force = b
+		return Force(previous)
+	}
+}
+
+// Gzip toggles writing gzip compressed copies of generated files
+func Gzip(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Gzip
+		c.Gzip = b
+		return Gzip(previous)
+	}
+}
+
+// HistoryFile sets the file each run's metrics are appended to, as JSON
+// lines or, if it ends in .csv, CSV; see RecordHistory
+func HistoryFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.Hist
+		c.Hist = f
+		return HistoryFile(previous)
+	}
+}
+
+// HistoryDays sets how many days of runs the history file keeps, 0 keeps
+// them all
+func HistoryDays(n int) Option {
+	return func(c *Config) Option {
+		previous := c.HistDay
+		c.HistDay = n
+		return HistoryDays(previous)
+	}
+}
+
+// HTTPS sets the plain HTTP source policy: HTTPSallow, HTTPSupgrade or HTTPSrequire
+func HTTPS(s string) Option {
+	return func(c *Config) Option {
+		previous := c.HTTPS
+		c.HTTPS = s
+		return HTTPS(previous)
+	}
+}
+
 // InCLI sets the CLI inSession command
 func InCLI(in string) Option {
 	return func(c *Config) Option {
@@ -186,6 +483,15 @@ func Level(s string) Option {
 	}
 }
 
+// LogFile sets the file LogWriter appends to
+func LogFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.LogFile
+		c.LogFile = f
+		return LogFile(previous)
+	}
+}
+
 // Logger sets a pointer to the logger
 func Logger(l *logging.Logger) Option {
 	return func(c *Config) Option {
@@ -195,6 +501,16 @@ func Logger(l *logging.Logger) Option {
 	}
 }
 
+// LogRotate sets the size in bytes the LogWriter's file is rotated at, 0 never
+// rotates it, and how many rotated files are kept
+func LogRotate(size int64, keep int) Option {
+	return func(c *Config) Option {
+		size0, keep0 := c.LogSize, c.LogKeep
+		c.LogSize, c.LogKeep = size, keep
+		return LogRotate(size0, keep0)
+	}
+}
+
 // LTypes sets an array of legal types used by Source
 func LTypes(s []string) Option {
 	return func(c *Config) Option {
@@ -204,6 +520,49 @@ func LTypes(s []string) Option {
 	}
 }
 
+// MaxMemoryMB sets the memory budget in MB, if the sources would need more
+// their downloads are spilled to disk and fewer fetched at once, 0 is
+// unlimited
+func MaxMemoryMB(mb int) Option {
+	return func(c *Config) Option {
+		previous := c.MaxMem
+		c.MaxMem = mb
+		return MaxMemoryMB(previous)
+	}
+}
+
+// MaxChange sets the percentage of the previous entries a run may add and
+// remove before it is treated as anomalous and its files aren't written, 0
+// allows any change
+func MaxChange(pct float64) Option {
+	return func(c *Config) Option {
+		previous := c.MaxChg
+		c.MaxChg = pct
+		return MaxChange(previous)
+	}
+}
+
+// MaxFailures auto-disables sources after n consecutive failed fetches, they
+// are skipped until a Retry or Update succeeds; it needs a FailFile to
+// remember failed fetches between runs, 0 never disables sources
+func MaxFailures(n int) Option {
+	return func(c *Config) Option {
+		previous := c.MaxFail
+		c.MaxFail = n
+		c.fails = nil
+		return MaxFailures(previous)
+	}
+}
+
+// MaxSize sets the default per-source download size limit in bytes, 0 is unlimited
+func MaxSize(n int64) Option {
+	return func(c *Config) Option {
+		previous := c.MaxSize
+		c.MaxSize = n
+		return MaxSize(previous)
+	}
+}
+
 // Method sets the HTTP method
 func Method(method string) Option {
 	return func(c *Config) Option {
@@ -218,8 +577,9 @@ func NewConfig(opts ...Option) *Config {
 	c := Config{
 		tree: make(tree),
 		Parms: &Parms{
-			Dex: list{RWMutex: &sync.RWMutex{}, entry: make(entry)},
-			Exc: list{RWMutex: &sync.RWMutex{}, entry: make(entry)},
+			Dex:   list{RWMutex: &sync.RWMutex{}, entry: make(entry)},
+			Exc:   list{RWMutex: &sync.RWMutex{}, entry: make(entry)},
+			state: newRunState(),
 		},
 	}
 	for _, opt := range opts {
@@ -228,6 +588,15 @@ func NewConfig(opts ...Option) *Config {
 	return &c
 }
 
+// Nice sets the CPU priority, 1 to 19, LowerPriority runs at, 0 leaves it
+func Nice(n int) Option {
+	return func(c *Config) Option {
+		previous := c.Nice
+		c.Nice = n
+		return Nice(previous)
+	}
+}
+
 // Nodes sets the node ns array
 func Nodes(nodes []string) Option {
 	return func(c *Config) Option {
@@ -237,6 +606,66 @@ func Nodes(nodes []string) Option {
 	}
 }
 
+// PSL sets the public suffix list file, in the publicsuffix.org format, that
+// sources with parse-urls registrable collapse hostnames with and public
+// suffix entries are detected with; a built-in list of common suffixes is
+// used if it isn't set
+func PSL(file string) Option {
+	return func(c *Config) Option {
+		previous := c.PSL
+		c.PSL, c.psl = file, nil
+		return PSL(previous)
+	}
+}
+
+// PSLURL sets where the public suffix list is downloaded from, it is saved to
+// the PSL file, if set, so the last good copy is used when it can't be reached
+func PSLURL(url string) Option {
+	return func(c *Config) Option {
+		previous := c.PSLURL
+		c.PSLURL, c.psl = url, nil
+		return PSLURL(previous)
+	}
+}
+
+// RefuseSuffixes drops entries that are public suffixes, e.g. co.uk, rather
+// than only warning about them, as blocking one blocks every domain under it
+func RefuseSuffixes(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Refuse
+		c.Refuse = b
+		return RefuseSuffixes(previous)
+	}
+}
+
+// Pins restricts HTTPS sources to certificates with these SHA256 fingerprints
+func Pins(p []string) Option {
+	return func(c *Config) Option {
+		previous := c.Pins
+		c.Pins = p
+		return Pins(previous)
+	}
+}
+
+// Offline skips network fetches, url sources are read from their copies in
+// the Cache directory and the default exclusions from their cache
+func Offline(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Offline
+		c.Offline = b
+		return Offline(previous)
+	}
+}
+
+// OnProgress sets a ProgressFunc to receive source download and parse progress
+func OnProgress(f ProgressFunc) Option {
+	return func(c *Config) Option {
+		previous := c.Prog
+		c.Prog = f
+		return OnProgress(previous)
+	}
+}
+
 // Poll sets the polling interval in seconds
 func Poll(t int) Option {
 	return func(c *Config) Option {
@@ -246,6 +675,16 @@ func Poll(t int) Option {
 	}
 }
 
+// Precedence sets whether explicit includes override exclusions, either
+// PrecedenceInclude (the default) or PrecedenceExclude
+func Precedence(p string) Option {
+	return func(c *Config) Option {
+		previous := c.Prec
+		c.Prec = p
+		return Precedence(previous)
+	}
+}
+
 // Prefix sets the dnsmasq configuration address line prefix
 func Prefix(l string) Option {
 	return func(c *Config) Option {
@@ -255,12 +694,195 @@ func Prefix(l string) Option {
 	}
 }
 
+// Protect replaces the built-in protected domains, which are never blocked,
+// the router's hostname and NTP servers are always protected
+func Protect(d []string) Option {
+	return func(c *Config) Option {
+		previous := c.Protect
+		c.Protect = d
+		c.guard = nil
+		return Protect(previous)
+	}
+}
+
 // String method to implement fmt.Print interface
 func (p *Parms) String() string {
 	out, _ := json.MarshalIndent(p, "", "\t")
 	return string(out)
 }
 
+// PushKey enables configuration pushes signed by the matching private key
+func PushKey(k ed25519.PublicKey) Option {
+	return func(c *Config) Option {
+		previous := c.PushKey
+		c.PushKey = k
+		return PushKey(previous)
+	}
+}
+
+// Quarantine holds domains back for d after a source first lists them, so a
+// poisoned feed can't block them at once; it needs a SeenFile to remember
+// when domains were first listed
+func Quarantine(d time.Duration) Option {
+	return func(c *Config) Option {
+		previous := c.Hold
+		c.Hold = d
+		c.seen = nil
+		return Quarantine(previous)
+	}
+}
+
+// RateLimit caps the bandwidth all downloads share at bps bytes per second,
+// 0 is unlimited; sources can have a lower rate-limit of their own
+func RateLimit(bps int64) Option {
+	return func(c *Config) Option {
+		previous := c.Rate
+		c.Rate, c.limit = bps, newLimiter(bps)
+		return RateLimit(previous)
+	}
+}
+
+// RefreshWindows limits downloading url sources in full to the daily windows
+// w, outside them sources with a cached copy are only checked for changes;
+// nil allows downloads at any time
+func RefreshWindows(w []*Schedule) Option {
+	return func(c *Config) Option {
+		previous := c.Windows
+		c.Windows = w
+		return RefreshWindows(previous)
+	}
+}
+
+// Redact replaces the built-in query parameters whose values, along with URL
+// passwords, expanded secrets, credential headers and headers named like one
+// of params, are redacted from logs and the run status
+func Redact(params []string) Option {
+	return func(c *Config) Option {
+		previous := c.Redact
+		c.Redact = params
+		return Redact(previous)
+	}
+}
+
+// Redirects sets the maximum number of redirects followed per source
+func Redirects(n int) Option {
+	return func(c *Config) Option {
+		previous := c.Redirs
+		c.Redirs = n
+		return Redirects(previous)
+	}
+}
+
+// Resolver sets the DNS server used to look up source hostnames so updates
+// still work when the local dnsmasq is broken, either ip[:port],
+// tls://host[:port] for DNS-over-TLS or an https:// DNS-over-HTTPS URL
+func Resolver(addr string) Option {
+	return func(c *Config) Option {
+		previous := c.Resolv
+		c.Resolv = addr
+		return Resolver(previous)
+	}
+}
+
+// Resumes sets how many times an interrupted download is resumed with a Range
+// request before the source fails
+func Resumes(n int) Option {
+	return func(c *Config) Option {
+		previous := c.Resumes
+		c.Resumes = n
+		return Resumes(previous)
+	}
+}
+
+// SeenFile sets the file recording when each domain was first listed, see
+// Quarantine
+func SeenFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.Seen
+		c.Seen = f
+		c.seen = nil
+		return SeenFile(previous)
+	}
+}
+
+// Shard splits generated files into shards of at most n lines, e.g.
+// blacklist.000.conf, blacklist.001.conf..., 0 disables sharding
+func Shard(n int) Option {
+	return func(c *Config) Option {
+		previous := c.Shard
+		c.Shard = n
+		return Shard(previous)
+	}
+}
+
+// Shell sets the Runner used to execute shell commands
+func Shell(r Runner) Option {
+	return func(c *Config) Option {
+		previous := c.Runner
+		c.Runner = r
+		return Shell(previous)
+	}
+}
+
+// StaleDays reports the sources whose content hasn't changed for n days, see
+// StaleSources; it needs a StaleFile to remember when sources last changed
+func StaleDays(n int) Option {
+	return func(c *Config) Option {
+		previous := c.Stale
+		c.Stale = n
+		c.fresh = nil
+		return StaleDays(previous)
+	}
+}
+
+// StaleFile sets the file recording when each source's content last changed
+func StaleFile(f string) Option {
+	return func(c *Config) Option {
+		previous := c.StaleDB
+		c.StaleDB = f
+		c.fresh = nil
+		return StaleFile(previous)
+	}
+}
+
+// Stats enables recording the run status, see WriteStatus
+func Stats(s *Status) Option {
+	return func(c *Config) Option {
+		previous := c.Status
+		c.Status = s
+		return Stats(previous)
+	}
+}
+
+// StateFiles adds files recording run state, such as the commit digest, to
+// the ones backed up and restored with the state Parms already names
+func StateFiles(files []string) Option {
+	return func(c *Config) Option {
+		previous := c.State
+		c.State = files
+		return StateFiles(previous)
+	}
+}
+
+// Strict toggles failing ReadCfg on unknown leaves and unparsable lines
+func Strict(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Strict
+		c.Strict = b
+		return Strict(previous)
+	}
+}
+
+// Syslog sets the address SyslogBackend sends to, "local" or udp:// or tcp://
+// host[:port], and the facility and tag its messages are sent with
+func Syslog(addr, facility, tag string) Option {
+	return func(c *Config) Option {
+		addr0, facility0, tag0 := c.Syslog, c.SysFac, c.SysTag
+		c.Syslog, c.SysFac, c.SysTag = addr, facility, tag
+		return Syslog(addr0, facility0, tag0)
+	}
+}
+
 // Test toggles testing mode on or off
 func Test(b bool) Option {
 	return func(c *Config) Option {
@@ -270,6 +892,16 @@ func Test(b bool) Option {
 	}
 }
 
+// Threshold sets the summed source weight a domain must exceed to be blocked,
+// 0 blocks every domain a source lists
+func Threshold(t float64) Option {
+	return func(c *Config) Option {
+		previous := c.Thresh
+		c.Thresh = t
+		return Threshold(previous)
+	}
+}
+
 // Timeout sets how long before an unresponsive goroutine is aborted
 func Timeout(t time.Duration) Option {
 	return func(c *Config) Option {
@@ -279,6 +911,84 @@ func Timeout(t time.Duration) Option {
 	}
 }
 
+// Tune sets the performance knobs, usually from ArchTuning: Cores as Cores
+// does, how many sources are downloaded at once, the longest source line read
+// and the dedupe strategy
+func Tune(t Tuning) Option {
+	return func(c *Config) Option {
+		previous := Tuning{Cores: c.Cores, Fetches: c.Fetches, LineBuf: c.LineBuf, Dedupe: c.Dedupe}
+		if t.Cores > 0 {
+			runtime.GOMAXPROCS(t.Cores)
+		}
+		c.Cores, c.Fetches, c.LineBuf, c.Dedupe = t.Cores, t.Fetches, t.LineBuf, t.Dedupe
+		return Tune(previous)
+	}
+}
+
+// Sanity checks a run's files against the TopDomains before they're written,
+// keeping the previous files if they would block any of the popular domains
+func Sanity(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Sanity
+		c.Sanity = b
+		return Sanity(previous)
+	}
+}
+
+// TopDomains sets the top domains list file the generated blacklist is
+// checked against by Popular, one domain or rank,domain per line; a built-in
+// set of popular domains is used if it isn't set
+func TopDomains(file string) Option {
+	return func(c *Config) Option {
+		previous := c.Top
+		c.Top = file
+		return TopDomains(previous)
+	}
+}
+
+// TopURL sets where the top domains list is downloaded from, it is saved to
+// the TopDomains file, if set, so the last good copy is used when it can't be
+// reached
+func TopURL(url string) Option {
+	return func(c *Config) Option {
+		previous := c.TopURL
+		c.TopURL = url
+		return TopURL(previous)
+	}
+}
+
+// Timings logs how long each stage of a run takes and records it in the
+// Status, see Timing
+func Timings(b bool) Option {
+	return func(c *Config) Option {
+		previous := c.Times
+		c.Times, c.watch = b, nil
+		if b {
+			c.watch = &stopwatch{}
+		}
+		return Timings(previous)
+	}
+}
+
+// Tor sets the Tor SOCKS proxy address used by sources fetched via tor
+func Tor(addr string) Option {
+	return func(c *Config) Option {
+		previous := c.Tor
+		c.Tor = addr
+		return Tor(previous)
+	}
+}
+
+// Transform sets the script each source's entries are passed through, see
+// the transform configuration leaf
+func Transform(script string) Option {
+	return func(c *Config) Option {
+		previous := c.Xform
+		c.Xform = script
+		return Transform(previous)
+	}
+}
+
 // Verb sets the verbosity level to v
 func Verb(b bool) Option {
 	return func(c *Config) Option {
@@ -297,7 +1007,7 @@ func WCard(w Wildcard) Option {
 	}
 }
 
-// Writer provides an address for anything that can use io.Writer
+// Writer sends the generated dnsmasq data to w instead of writing files
 func Writer(w io.Writer) Option {
 	return func(c *Config) Option {
 		previous := c.ioWriter
@@ -71,40 +71,38 @@ func TestOption(t *testing.T) {
 		vanilla := Parms{}
 
 		exp := `{
-	"API": "/bin/cli-shell-api",
-	"Arch": "amd64",
-	"Bash": "/bin/bash",
-	"Cores": 2,
-	"Dbug": true,
-	"Dex": {
-		"entry": {}
-	},
-	"Dir": "/tmp",
-	"dnsmasq service": "service dnsmasq restart",
-	"Exc": {
-		"entry": {}
-	},
-	"dnsmasq fileExt.": "blacklist.conf",
-	"File": "/config/config.boot",
-	"File name fmt": "%v/%v.%v.%v",
-	"CLI Path": "service dns forwarding",
-	"Leaf nodes": [
+	"api": "/bin/cli-shell-api",
+	"arch": "amd64",
+	"bash": "/bin/bash",
+	"cores": 2,
+	"debug": true,
+	"defaults": {},
+	"dir": "/tmp",
+	"dnsService": "service dnsmasq restart",
+	"ext": "blacklist.conf",
+	"file": "/config/config.boot",
+	"fileNameFormat": "%v/%v.%v.%v",
+	"inCLI": "inSession",
+	"level": "service dns forwarding",
+	"leafTypes": [
 		"file",
 		"pre-configured-domain",
 		"pre-configured-host",
 		"url"
 	],
-	"HTTP method": "GET",
-	"Nodes": [
+	"method": "GET",
+	"nodes": [
 		"domains",
 		"hosts"
 	],
-	"Prefix": "address=",
-	"Poll": 10,
-	"Test": true,
-	"Timeout": 30000000000,
-	"Verbosity": false,
-	"Wildcard": {}
+	"prefix": "address=",
+	"poll": 10,
+	"test": true,
+	"timeout": "30s",
+	"wildcard": {
+		"node": "*s",
+		"name": "*"
+	}
 }`
 
 		expRaw := Parms{
@@ -135,6 +133,7 @@ func TestOption(t *testing.T) {
 		c := NewConfig()
 		vanilla.Dex = c.Dex
 		vanilla.Exc = c.Exc
+		vanilla.state = c.state
 		So(c.Parms, ShouldResemble, &vanilla)
 
 		c = NewConfig(
@@ -165,6 +164,7 @@ func TestOption(t *testing.T) {
 
 		expRaw.Dex.RWMutex = c.Dex.RWMutex
 		expRaw.Exc.RWMutex = c.Exc.RWMutex
+		expRaw.state = c.state
 
 		So(*c.Parms, ShouldResemble, expRaw)
 		So(c.Parms.String(), ShouldEqual, exp)
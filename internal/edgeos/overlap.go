@@ -0,0 +1,122 @@
+package edgeos
+
+import (
+	"sort"
+	"sync"
+)
+
+// SourceDomains records how many domains a source lists and how many of them
+// no other source does
+type SourceDomains struct {
+	Node    string
+	Source  string
+	Domains int
+	Unique  int
+}
+
+// SourcePair records how much two sources' domains overlap, Jaccard is the
+// shared domains divided by the domains either lists
+type SourcePair struct {
+	A       SourceDomains
+	B       SourceDomains
+	Shared  int
+	Jaccard float64
+}
+
+// Overlap compares the domains the configured file and url sources list
+type Overlap struct {
+	Sources []SourceDomains
+	Pairs   []SourcePair
+}
+
+// domainSet returns the domains the source's fetched content lists, ignoring
+// the exclusions and the other sources' domains
+func (o *object) domainSet() entry {
+	p := *o.Parms
+	p.Dex = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	p.Exc = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	p.ips, p.Prog, p.state, p.Thresh, p.Xform = nil, nil, nil, 0, ""
+
+	s := *o
+	s.Parms = &p
+	return s.extract().entry
+}
+
+// Overlap fetches the file and url sources and compares the domains each
+// lists, sources that can't be fetched are left out and their errors returned
+// alongside the comparison of the rest. Pairs without shared domains are
+// omitted, the most similar pairs come first
+func (c *Config) Overlap() (*Overlap, error) {
+	var (
+		errs Errors
+		ov   = &Overlap{}
+		sets []entry
+	)
+
+	for _, iface := range []IFace{FileObj, URLdObj, URLhObj} {
+		ct, err := c.NewContent(iface)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range ct.GetList().x {
+			if o.err != nil {
+				errs = append(errs, &ErrSourceFetch{Source: o.name, Cause: o.err})
+				continue
+			}
+			ov.Sources = append(ov.Sources, SourceDomains{Node: getType(o.nType).(string), Source: o.name})
+			sets = append(sets, o.domainSet())
+		}
+	}
+
+	count := make(map[string]int)
+	for i, s := range sets {
+		ov.Sources[i].Domains = len(s)
+		for d := range s {
+			count[d]++
+		}
+	}
+
+	for i, s := range sets {
+		for d := range s {
+			if count[d] == 1 {
+				ov.Sources[i].Unique++
+			}
+		}
+	}
+
+	for i := range sets {
+		for j := i + 1; j < len(sets); j++ {
+			small, big := sets[i], sets[j]
+			if len(small) > len(big) {
+				small, big = big, small
+			}
+
+			var shared int
+			for d := range small {
+				if _, ok := big[d]; ok {
+					shared++
+				}
+			}
+			if shared == 0 {
+				continue
+			}
+
+			ov.Pairs = append(ov.Pairs, SourcePair{
+				A:       ov.Sources[i],
+				B:       ov.Sources[j],
+				Shared:  shared,
+				Jaccard: float64(shared) / float64(len(sets[i])+len(sets[j])-shared),
+			})
+		}
+	}
+
+	sort.SliceStable(ov.Pairs, func(i, j int) bool {
+		return ov.Pairs[i].Jaccard > ov.Pairs[j].Jaccard
+	})
+
+	if errs != nil {
+		return ov, errs
+	}
+	return ov, nil
+}
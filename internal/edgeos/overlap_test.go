@@ -0,0 +1,60 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOverlap(t *testing.T) {
+	Convey("Testing Overlap()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		srcs := map[string]string{
+			"big":    "a.example.com\nb.example.com\nc.example.com\nd.example.com\n",
+			"subset": "a.example.com\nb.example.com\n",
+			"other":  "d.example.com\ne.example.com\n",
+			"alone":  "f.example.com\n",
+		}
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude a.example.com\n\tdomains {\n"
+		for _, name := range []string{"alone", "big", "other", "subset"} {
+			f := fmt.Sprintf("%v/%v.txt", dir, name)
+			So(ioutil.WriteFile(f, []byte(srcs[name]), 0644), ShouldBeNil)
+			cfg += fmt.Sprintf("\t\tsource %v {\n\t\t\tprefix \"\"\n\t\t\tfile %v\n\t\t}\n", name, f)
+		}
+		cfg += "\t\tsource missing {\n\t\t\tfile " + dir + "/missing.txt\n\t\t}\n\t}\n}"
+
+		c := NewConfig(
+			Nodes([]string{domains}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		ov, err := c.Overlap()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "missing")
+
+		So(ov.Sources, ShouldResemble, []SourceDomains{
+			{Node: domains, Source: "alone", Domains: 1, Unique: 1},
+			{Node: domains, Source: "big", Domains: 4, Unique: 1},
+			{Node: domains, Source: "other", Domains: 2, Unique: 1},
+			{Node: domains, Source: "subset", Domains: 2, Unique: 0},
+		})
+
+		So(ov.Pairs, ShouldResemble, []SourcePair{
+			{A: ov.Sources[1], B: ov.Sources[3], Shared: 2, Jaccard: 0.5},
+			{A: ov.Sources[1], B: ov.Sources[2], Shared: 1, Jaccard: 0.2},
+		})
+
+		Convey("the exclusions and generated output are untouched", func() {
+			So(c.Exc.keyExists("b.example.com"), ShouldBeFalse)
+			So(c.Dex.subKeyExists("b.example.com"), ShouldBeFalse)
+		})
+	})
+}
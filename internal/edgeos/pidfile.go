@@ -0,0 +1,120 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// pidInfo is the content of a PID file
+type pidInfo struct {
+	PID     int       `json:"pid"`
+	API     string    `json:"api,omitempty"`
+	Started time.Time `json:"started"`
+}
+
+// RunningError is returned by LockPID when another daemon holds the PID file
+type RunningError struct {
+	File string
+	PID  int
+	API  string
+}
+
+func (e *RunningError) Error() string {
+	switch {
+	case e.PID == 0:
+		return fmt.Sprintf("blacklist is already running (%v)", e.File)
+	case e.API != "":
+		return fmt.Sprintf("blacklist is already running as pid %d (%v), its status API is at %v", e.PID, e.File, e.API)
+	}
+	return fmt.Sprintf("blacklist is already running as pid %d (%v)", e.PID, e.File)
+}
+
+// readPID returns the PID file's content, the file may also hold a bare pid
+func readPID(file string) (*pidInfo, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pidInfo{}
+	if err = json.Unmarshal(b, p); err != nil {
+		if p.PID, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return nil, fmt.Errorf("%v: not a pid file", file)
+		}
+	}
+	return p, nil
+}
+
+// LockPID records a daemon serving its status API at api in the PID file and
+// holds an exclusive lock on it until the returned func removes it; the lock
+// is dropped with the daemon, so a PID file left by a dead one is reused
+func LockPID(file, api string) (func() error, error) {
+	b, err := json.Marshal(&pidInfo{PID: os.Getpid(), API: api, Started: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			if err != syscall.EWOULDBLOCK {
+				return nil, err
+			}
+			e := &RunningError{File: file}
+			if p, err := readPID(file); err == nil {
+				e.PID, e.API = p.PID, p.API
+			}
+			return nil, e
+		}
+
+		// the daemon that held the lock may have removed the file before
+		// releasing it, leaving this lock on an unlinked file
+		if same, err := sameFile(f, file); err != nil || !same {
+			f.Close()
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		if err = f.Truncate(0); err == nil {
+			_, err = f.WriteAt(append(b, '\n'), 0)
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		return func() error {
+			err := os.Remove(file)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		}, nil
+	}
+}
+
+// sameFile returns true if the open file f is still the one at path file
+func sameFile(f *os.File, file string) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	cur, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(fi, cur), nil
+}
@@ -0,0 +1,101 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestPIDHelperProcess stands in for a running daemon holding the PID file
+func TestPIDHelperProcess(t *testing.T) {
+	file := os.Getenv("BLACKLIST_PID_HELPER")
+	if file == "" {
+		return
+	}
+	if _, err := LockPID(file, "10.0.0.1:8080"); err != nil {
+		os.Exit(1)
+	}
+	time.Sleep(10 * time.Second)
+	os.Exit(0)
+}
+
+func TestLockPID(t *testing.T) {
+	Convey("Testing PID files", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		file := dir + "/blacklist.pid"
+		release, err := LockPID(file, ":8080")
+		So(err, ShouldBeNil)
+
+		p, err := readPID(file)
+		So(err, ShouldBeNil)
+		So(p.PID, ShouldEqual, os.Getpid())
+		So(p.API, ShouldEqual, ":8080")
+
+		So(release(), ShouldBeNil)
+		_, err = os.Stat(file)
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		Convey("a running daemon's PID file is refused until it exits", func() {
+			cmd := exec.Command(os.Args[0], "-test.run=TestPIDHelperProcess")
+			cmd.Env = append(os.Environ(), "BLACKLIST_PID_HELPER="+file)
+			So(cmd.Start(), ShouldBeNil)
+			defer func() {
+				cmd.Process.Kill()
+				cmd.Wait()
+			}()
+
+			for i := 0; i < 100; i++ {
+				if p, err := readPID(file); err == nil && p.PID == cmd.Process.Pid {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			_, err := LockPID(file, "")
+			So(err, ShouldResemble, &RunningError{File: file, PID: cmd.Process.Pid, API: "10.0.0.1:8080"})
+			So(err.Error(), ShouldEndWith, "its status API is at 10.0.0.1:8080")
+
+			So(cmd.Process.Kill(), ShouldBeNil)
+			cmd.Wait()
+
+			release, err := LockPID(file, "")
+			So(err, ShouldBeNil)
+			defer release()
+
+			p, err := readPID(file)
+			So(err, ShouldBeNil)
+			So(p.PID, ShouldEqual, os.Getpid())
+		})
+
+		Convey("a locked PID file is refused in the same process", func() {
+			release, err := LockPID(file, "")
+			So(err, ShouldBeNil)
+			defer release()
+
+			_, err = LockPID(file, "")
+			So(err, ShouldResemble, &RunningError{File: file, PID: os.Getpid()})
+		})
+
+		Convey("a stale PID file is replaced", func() {
+			cmd := exec.Command("true")
+			So(cmd.Run(), ShouldBeNil)
+
+			So(ioutil.WriteFile(file, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644), ShouldBeNil)
+			release, err := LockPID(file, "")
+			So(err, ShouldBeNil)
+			defer release()
+
+			p, err := readPID(file)
+			So(err, ShouldBeNil)
+			So(p.PID, ShouldEqual, os.Getpid())
+		})
+	})
+}
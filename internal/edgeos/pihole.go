@@ -0,0 +1,305 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pi-hole's domainlist types
+const (
+	piAllow = iota
+	piDeny
+	piAllowRx
+	piDenyRx
+)
+
+// piGravity is the Pi-hole v5 and later database of adlists and domains
+const piGravity = "gravity.db"
+
+// piFiles are the lists older Pi-hole releases keep, with their domainlist type
+var piFiles = []struct {
+	name  string
+	dtype int
+}{
+	{name: "whitelist.txt", dtype: piAllow},
+	{name: "blacklist.txt", dtype: piDeny},
+	{name: "regex.list", dtype: piDenyRx},
+}
+
+// piRx matches the Pi-hole regexes a blacklist entry can replace: ones that
+// only match a domain and its subdomains, e.g. (\.|^)example\.com$, and ones
+// that only match a host, e.g. ^example\.com$
+var piRx = regexp.MustCompile(`^(\^|\(\\\.\|\^\)|\(\^\|\\\.\))((?:[a-z0-9_-]+\\\.)+[a-z0-9-]+)\$$`)
+
+// PiHole is what an import keeps from a Pi-hole configuration: Deny holds
+// the hosts Pi-hole blocks exactly and DenyDomains the domains it blocks with
+// their subdomains; regexes without a domain equivalent are skipped
+type PiHole struct {
+	Adlists     []string `json:"adlists"`
+	Allow       []string `json:"allow"`
+	Deny        []string `json:"deny"`
+	DenyDomains []string `json:"deny_domains,omitempty"`
+	Skipped     []string `json:"skipped,omitempty"`
+}
+
+// ReadPiHole reads the Pi-hole configuration in dir, or the gravity database
+// dir names: the adlists and domains in gravity.db if there is one,
+// otherwise the adlists.list, whitelist.txt, blacklist.txt and regex.list
+// files older releases keep
+func (c *Config) ReadPiHole(dir string) (*PiHole, error) {
+	db := dir
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if fi.IsDir() {
+		db = filepath.Join(dir, piGravity)
+	}
+
+	if _, err := os.Stat(db); err == nil {
+		return c.readGravity(db)
+	}
+
+	var (
+		p     = &PiHole{}
+		found bool
+	)
+
+	adlists, err := readPiList(filepath.Join(dir, "adlists.list"))
+	if err == nil {
+		found = true
+		p.Adlists = adlists
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, f := range piFiles {
+		l, err := readPiList(filepath.Join(dir, f.name))
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		found = true
+		for _, v := range l {
+			p.add(f.dtype, v)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no Pi-hole %v, adlists.list or domain lists found in %v", piGravity, dir)
+	}
+	return p, nil
+}
+
+// readGravity reads the enabled adlists and domains from a gravity database
+// with the sqlite3 shell, Pi-hole v6 allowlist adlists are skipped
+func (c *Config) readGravity(db string) (*PiHole, error) {
+	var (
+		sqlite     = "sqlite3 -batch -separator '|' " + quote(db) + " "
+		domainlist = `"SELECT 'domain', type, domain FROM domainlist WHERE enabled = 1 ORDER BY id"`
+		adlist     = `"SELECT 'adlist', type, address FROM adlist WHERE enabled = 1 ORDER BY id"`
+		// Pi-hole v5 adlists have no type, they're all blocklists
+		v5 = `"SELECT 'adlist', 0, address FROM adlist WHERE enabled = 1 ORDER BY id"`
+	)
+
+	b, err := c.runner().Output(fmt.Sprintf("%v%v && { %v%v 2>/dev/null || %v%v; }\n", sqlite, domainlist, sqlite, adlist, sqlite, v5))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %v with sqlite3: %v", db, err)
+	}
+	return parseGravity(bytes.NewReader(b))
+}
+
+// parseGravity parses readGravity's kind|type|value rows
+func parseGravity(r io.Reader) (*PiHole, error) {
+	var (
+		p = &PiHole{}
+		s = bufio.NewScanner(r)
+	)
+
+	for s.Scan() {
+		row := strings.SplitN(s.Text(), "|", 3)
+		if len(row) != 3 {
+			continue
+		}
+
+		var dtype int
+		if _, err := fmt.Sscan(row[1], &dtype); err != nil {
+			return nil, fmt.Errorf("invalid gravity row %q", s.Text())
+		}
+
+		switch {
+		case row[0] == "domain":
+			p.add(dtype, row[2])
+		case dtype == 0:
+			p.Adlists = append(p.Adlists, strings.TrimSpace(row[2]))
+		}
+	}
+	return p, s.Err()
+}
+
+// readPiList returns file's entries, one per line without comments
+func readPiList(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		l []string
+		s = bufio.NewScanner(f)
+	)
+	for s.Scan() {
+		if v := strings.TrimSpace(s.Text()); v != "" && !strings.HasPrefix(v, "#") {
+			l = append(l, v)
+		}
+	}
+	return l, s.Err()
+}
+
+// add adds a Pi-hole domain or regex of dtype to p
+func (p *PiHole) add(dtype int, v string) {
+	var (
+		d   = normalizeDomain(v)
+		sub bool
+	)
+	if dtype == piAllowRx || dtype == piDenyRx {
+		m := piRx.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+		if m == nil {
+			p.Skipped = append(p.Skipped, v)
+			return
+		}
+		d, sub = strings.Replace(m[2], `\.`, ".", -1), m[1] != "^"
+	}
+
+	switch {
+	case dtype == piAllow || dtype == piAllowRx:
+		p.Allow = append(p.Allow, d)
+	case sub:
+		p.DenyDomains = append(p.DenyDomains, d)
+	default:
+		p.Deny = append(p.Deny, d)
+	}
+}
+
+// sources returns p's adlists as hosts sources, named after their host
+func (p *PiHole) sources() []*Source {
+	var (
+		s    []*Source
+		seen = make(map[string]int)
+	)
+
+	for _, a := range p.Adlists {
+		name := "adlist"
+		if u, err := url.Parse(a); err == nil && tagRx.MatchString(u.Hostname()) {
+			name = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%v-%d", name, seen[name])
+		}
+		s = append(s, &Source{Name: name, Node: hosts, URL: a})
+	}
+	return s
+}
+
+// uniq returns a sorted copy of l without duplicates
+func uniq(l []string) []string {
+	s := append([]string(nil), l...)
+	sort.Strings(s)
+
+	var u []string
+	for _, v := range s {
+		if len(u) == 0 || u[len(u)-1] != v {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+// PiHoleCommands returns the set commands that add p's adlists as hosts
+// sources, its allowed domains as global exclusions, its exactly denied hosts
+// as hosts includes and the domains it denies with their subdomains as
+// domains includes
+func (c *Config) PiHoleCommands(p *PiHole) ([]string, error) {
+	var cmds []string
+	for _, s := range p.sources() {
+		add, err := c.AddSource(s)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, add...)
+	}
+
+	exc, err := c.AddExclude(rootNode, uniq(p.Allow)...)
+	if err != nil {
+		return nil, err
+	}
+	inc, err := c.multi("set", "include", hosts, uniq(p.Deny))
+	if err != nil {
+		return nil, err
+	}
+	dom, err := c.multi("set", "include", domains, uniq(p.DenyDomains))
+	if err != nil {
+		return nil, err
+	}
+	cmds = append(append(append(cmds, exc...), inc...), dom...)
+
+	if len(cmds) == 0 {
+		return nil, errors.New("Pi-hole configuration is empty, nothing to import")
+	}
+	return cmds, nil
+}
+
+// PiHoleConfig returns p as a standalone blacklist configuration, in the
+// EdgeOS syntax -f loads
+func (c *Config) PiHoleConfig(p *PiHole) ([]byte, error) {
+	if _, err := c.PiHoleCommands(p); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%v {\n", rootNode)
+	for _, d := range uniq(p.Allow) {
+		fmt.Fprintf(&b, "    exclude %v\n", cfgQuote(d))
+	}
+
+	if deny := uniq(p.DenyDomains); len(deny) > 0 {
+		fmt.Fprintf(&b, "    %v {\n", domains)
+		for _, d := range deny {
+			fmt.Fprintf(&b, "        include %v\n", cfgQuote(d))
+		}
+		b.WriteString("    }\n")
+	}
+
+	if deny, s := uniq(p.Deny), p.sources(); len(deny) > 0 || len(s) > 0 {
+		fmt.Fprintf(&b, "    %v {\n", hosts)
+		for _, d := range deny {
+			fmt.Fprintf(&b, "        include %v\n", cfgQuote(d))
+		}
+		for _, src := range s {
+			fmt.Fprintf(&b, "        source %v {\n            url %v\n        }\n", cfgQuote(src.Name), cfgQuote(src.URL))
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// cfgQuote returns s double quoted for an EdgeOS configuration file if required
+func cfgQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"\\{}#;") {
+		return s
+	}
+	return `"` + strings.Replace(strings.Replace(s, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+}
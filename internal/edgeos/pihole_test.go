@@ -0,0 +1,112 @@
+package edgeos
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPiHole(t *testing.T) {
+	Convey("Testing Pi-hole imports", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(Level("service dns forwarding"))
+
+		Convey("reads the lists older releases keep", func() {
+			files := map[string]string{
+				"adlists.list":  "# StevenBlack\nhttps://raw.githubusercontent.com/StevenBlack/hosts/master/hosts\n\nhttps://raw.githubusercontent.com/other/list.txt\nhttps://www.example.org/ads.txt\n",
+				"whitelist.txt": "s.youtube.com\nCDN.example.com.\n",
+				"blacklist.txt": "tracker.example.net\n",
+				"regex.list":    "(\\.|^)doubleclick\\.net$\n^ads\\.example\\.com$\n^ad[0-9]+\\.\n",
+			}
+			for f, s := range files {
+				So(ioutil.WriteFile(dir+"/"+f, []byte(s), 0644), ShouldBeNil)
+			}
+
+			p, err := c.ReadPiHole(dir)
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, &PiHole{
+				Adlists: []string{
+					"https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts",
+					"https://raw.githubusercontent.com/other/list.txt",
+					"https://www.example.org/ads.txt",
+				},
+				Allow:       []string{"s.youtube.com", "cdn.example.com"},
+				Deny:        []string{"tracker.example.net", "ads.example.com"},
+				DenyDomains: []string{"doubleclick.net"},
+				Skipped:     []string{`^ad[0-9]+\.`},
+			})
+
+			cmds, err := c.PiHoleCommands(p)
+			So(err, ShouldBeNil)
+			So(cmds, ShouldResemble, []string{
+				"set service dns forwarding blacklist hosts source 'raw.githubusercontent.com' url 'https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts'",
+				"set service dns forwarding blacklist hosts source 'raw.githubusercontent.com-2' url 'https://raw.githubusercontent.com/other/list.txt'",
+				"set service dns forwarding blacklist hosts source 'example.org' url 'https://www.example.org/ads.txt'",
+				"set service dns forwarding blacklist exclude 'cdn.example.com'",
+				"set service dns forwarding blacklist exclude 's.youtube.com'",
+				"set service dns forwarding blacklist hosts include 'ads.example.com'",
+				"set service dns forwarding blacklist hosts include 'tracker.example.net'",
+				"set service dns forwarding blacklist domains include 'doubleclick.net'",
+			})
+
+			b, err := c.PiHoleConfig(p)
+			So(err, ShouldBeNil)
+
+			n := NewConfig()
+			So(n.ReadCfg(&CFGstatic{Cfg: string(b)}), ShouldBeNil)
+			So(n.tree[rootNode].exc, ShouldResemble, []string{"cdn.example.com", "s.youtube.com"})
+			So(n.tree[hosts].inc, ShouldResemble, []string{"ads.example.com", "tracker.example.net"})
+			So(n.tree[domains].inc, ShouldResemble, []string{"doubleclick.net"})
+			So(n.Get(hosts).Names(), ShouldResemble, sort.StringSlice{"example.org", "includes.[2]", "raw.githubusercontent.com", "raw.githubusercontent.com-2"})
+		})
+
+		Convey("reads the gravity database with sqlite3", func() {
+			So(ioutil.WriteFile(dir+"/"+piGravity, nil, 0644), ShouldBeNil)
+			f := &fakeRunner{out: []byte("domain|0|allowed.example.com\n" +
+				"domain|3|(\\.|^)tiktok\\.com$\n" +
+				"domain|2|(^|\\.)cdn\\.tiktok\\.com$\n" +
+				"domain|1|denied.example.com\n" +
+				"adlist|0|https://adaway.org/hosts.txt\n" +
+				"adlist|1|https://allow.example.com/list.txt\n")}
+			c.Runner = f
+
+			p, err := c.ReadPiHole(dir)
+			So(err, ShouldBeNil)
+			So(p, ShouldResemble, &PiHole{
+				Adlists: []string{"https://adaway.org/hosts.txt"},
+				Allow:       []string{"allowed.example.com", "cdn.tiktok.com"},
+				Deny:        []string{"denied.example.com"},
+				DenyDomains: []string{"tiktok.com"},
+			})
+			So(f.scripts, ShouldHaveLength, 1)
+			So(f.scripts[0], ShouldStartWith, "sqlite3 -batch -separator '|' '"+dir+"/gravity.db' ")
+
+			f.err = errors.New("exit status 127")
+			_, err = c.ReadPiHole(dir + "/" + piGravity)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unable to read "+dir+"/gravity.db with sqlite3: exit status 127")
+		})
+
+		Convey("refuses a directory without a Pi-hole configuration", func() {
+			_, err := c.ReadPiHole(dir)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "no Pi-hole gravity.db, adlists.list or domain lists found in "+dir)
+
+			_, err = c.PiHoleCommands(&PiHole{Skipped: []string{"^ad"}})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Pi-hole configuration is empty, nothing to import")
+		})
+
+		Convey("quotes configuration values that need it", func() {
+			So(cfgQuote("example.com"), ShouldEqual, "example.com")
+			So(strings.Count(cfgQuote(`http://a.com/?q="x"`), `\"`), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,256 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// popularLimit is how many of a top domains list's first entries are
+// checked, so a full ranking such as Tranco's can be used as it is
+const popularLimit = 1000
+
+// popularDomains is the built-in set of popular domains, used when no top
+// domains list is set
+var popularDomains = []string{
+	"akamaihd.net", "amazon.com", "amazonaws.com", "apple.com", "azure.com",
+	"baidu.com", "bbc.co.uk", "bing.com", "cloudflare.com", "cloudfront.net",
+	"digicert.com", "discord.com", "dropbox.com", "ebay.com", "facebook.com",
+	"github.com", "gmail.com", "google.com", "googleapis.com", "googleusercontent.com",
+	"gstatic.com", "icloud.com", "instagram.com", "letsencrypt.org", "linkedin.com",
+	"live.com", "microsoft.com", "microsoftonline.com", "mozilla.org", "msn.com",
+	"netflix.com", "office.com", "office365.com", "paypal.com", "reddit.com",
+	"skype.com", "spotify.com", "twitch.tv", "twitter.com", "ubnt.com",
+	"ui.com", "whatsapp.com", "wikipedia.org", "windows.com", "windowsupdate.com",
+	"yahoo.com", "youtube.com", "zoom.us",
+}
+
+// Popular is a popular domain the generated blacklist blocks, By is the
+// wildcard entry blocking it if that isn't the domain itself
+type Popular struct {
+	Domain string `json:"domain"`
+	By     string `json:"by,omitempty"`
+}
+
+func (p Popular) String() string {
+	if p.By != "" {
+		return fmt.Sprintf("%v (by %v)", p.Domain, p.By)
+	}
+	return p.Domain
+}
+
+// parsePopular reads a top domains list, one domain per line optionally
+// preceded by its rank as in Tranco's rank,domain CSV, keeping the first
+// popularLimit domains
+func parsePopular(r io.Reader, origin string) ([]string, error) {
+	var (
+		b = bufio.NewScanner(r)
+		d []string
+	)
+
+	for b.Scan() && len(d) < popularLimit {
+		line := strings.TrimSpace(b.Text())
+		if i := strings.LastIndex(line, ","); i >= 0 {
+			line = line[i+1:]
+		}
+
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+			continue
+		case strings.ContainsAny(line, " <>"):
+			return nil, fmt.Errorf("%v: invalid domain %q", origin, line)
+		}
+		d = append(d, strings.TrimSuffix(strings.ToLower(line), "."))
+	}
+
+	if err := b.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", origin, err)
+	}
+	return d, nil
+}
+
+// readPopular reads a top domains list file
+func readPopular(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePopular(f, file)
+}
+
+// fetchPopular downloads the top domains list from TopURL and saves it to
+// the Top file, if set
+func (p *Parms) fetchPopular() ([]string, error) {
+	o := getHTTP(&object{Parms: p, name: "top domains", url: p.TopURL})
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	b, err := ioutil.ReadAll(o.r)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", p.TopURL, err)
+	}
+
+	d, err := parsePopular(bytes.NewReader(b), p.TopURL)
+	if err != nil || p.Top == "" {
+		return d, err
+	}
+
+	tmp := p.Top + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return nil, err
+	}
+	return d, os.Rename(tmp, p.Top)
+}
+
+// popular returns the top domains downloaded from TopURL, if set, or read
+// from the Top file, falling back to the file's last saved copy when the
+// download fails and to the built-in set until a copy has been saved
+func (p *Parms) popular() ([]string, error) {
+	if p.TopURL != "" {
+		d, err := p.fetchPopular()
+		if err == nil {
+			return d, nil
+		}
+		p.warn(fmt.Sprintf("Unable to download the top domains list: %v", err))
+	}
+
+	if p.Top == "" {
+		return popularDomains, nil
+	}
+
+	d, err := readPopular(p.Top)
+	if err != nil && p.TopURL != "" && os.IsNotExist(err) {
+		return popularDomains, nil
+	}
+	return d, err
+}
+
+// Popular returns the popular domains the generated files block, either
+// directly or as subdomains of a wildcard entry
+func (c *Config) Popular() ([]Popular, error) {
+	wild := make(map[string]bool)
+	err := c.EachMerged(func(m MergedEntry) error {
+		wild[m.Domain] = m.Wild
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.popularHits(wild)
+}
+
+// popularHits returns the popular domains blocked by the domains in wild,
+// which are true if their subdomains are blocked too
+func (c *Config) popularHits(wild map[string]bool) ([]Popular, error) {
+	top, err := c.popular()
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []Popular
+	for _, d := range top {
+		if _, ok := wild[d]; ok {
+			hits = append(hits, Popular{Domain: d})
+			continue
+		}
+
+		for s := d; strings.Contains(s, "."); {
+			s = s[strings.Index(s, ".")+1:]
+			if wild[s] {
+				hits = append(hits, Popular{Domain: d, By: s})
+				break
+			}
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Domain < hits[j].Domain })
+	return hits, nil
+}
+
+// checkPopular fails with an *ErrPopular if objs' adds, with the generated
+// files they don't replace, would block popular domains, so the previous
+// files are kept; with Force it only warns about each of them
+func (c *Config) checkPopular(objs []*object, adds []list) error {
+	if !c.Sanity || c.ioWriter != nil || c.audit != nil {
+		return nil
+	}
+
+	var (
+		replaced = make(map[string]bool)
+		wild     = make(map[string]bool)
+	)
+	for i, o := range objs {
+		if o.err != nil {
+			continue
+		}
+		replaced[fmt.Sprintf(o.FnFmt, o.Dir, getType(o.nType).(string), o.name, o.Ext)] = true
+
+		addWild(wild, adds[i], getSeparator(getType(o.nType).(string)) == "/.")
+		// *.domain entries from a source that isn't a domains node's
+		addWild(wild, o.wilds, true)
+	}
+
+	names, err := c.generated()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if f, _ := shardOf(name); replaced[f] {
+			continue
+		}
+		if err = readWild(name, wild); err != nil {
+			return err
+		}
+	}
+
+	hits, err := c.popularHits(wild)
+	if err != nil || len(hits) == 0 {
+		return err
+	}
+
+	if c.Force {
+		for _, p := range hits {
+			c.warn(fmt.Sprintf("sanity: blacklist blocks popular domain %v", p))
+		}
+		return nil
+	}
+	return &ErrPopular{Domains: hits}
+}
+
+// addWild adds l's domains to wild, w is true if their subdomains are blocked
+func addWild(wild map[string]bool, l list, w bool) {
+	if l.RWMutex == nil {
+		return
+	}
+
+	l.RLock()
+	for k := range l.entry {
+		wild[k] = wild[k] || w
+	}
+	l.RUnlock()
+}
+
+// readWild adds the entries in a generated file to wild
+func readWild(file string, wild map[string]bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b := bufio.NewScanner(f)
+	for b.Scan() {
+		if d, w := entryDomain(b.Text()); d != "" {
+			wild[d] = wild[d] || w
+		}
+	}
+	return b.Err()
+}
@@ -0,0 +1,128 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPopular(t *testing.T) {
+	Convey("Testing the popular domains check", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := map[string]string{
+			"domains.feed.blacklist.conf": "address=/.googleapis.com/0.0.0.0\naddress=/.malware.net/0.0.0.0\n",
+			"hosts.feed.blacklist.conf":   "address=/windowsupdate.com/0.0.0.0\naddress=/ads.example.com/0.0.0.0\n",
+		}
+		for f, data := range files {
+			So(ioutil.WriteFile(fmt.Sprintf("%v/%v", dir, f), []byte(data), 0644), ShouldBeNil)
+		}
+
+		opts := []Option{Dir(dir), Ext("blacklist.conf"), FileNameFmt("%v/%v.%v.%v"), Method("GET"), WCard(Wildcard{Node: "*s", Name: "*"})}
+
+		Convey("the built-in set catches blocked popular domains", func() {
+			hits, err := NewConfig(opts...).Popular()
+			So(err, ShouldBeNil)
+			So(hits, ShouldResemble, []Popular{{Domain: "googleapis.com"}, {Domain: "windowsupdate.com"}})
+
+			err = &ErrPopular{Domains: hits}
+			So(err.Error(), ShouldEqual, "blacklist would block popular domains: googleapis.com, windowsupdate.com, rerun with -force to allow it")
+		})
+
+		Convey("a top domains list replaces the built-in set", func() {
+			top := dir + "/top.csv"
+			So(ioutil.WriteFile(top, []byte("1,fonts.googleapis.com\n2,example.com\n3,ads.example.com\n"), 0644), ShouldBeNil)
+
+			hits, err := NewConfig(append(opts, TopDomains(top))...).Popular()
+			So(err, ShouldBeNil)
+			So(hits, ShouldResemble, []Popular{{Domain: "ads.example.com"}, {Domain: "fonts.googleapis.com", By: "googleapis.com"}})
+			So(hits[1].String(), ShouldEqual, "fonts.googleapis.com (by googleapis.com)")
+
+			_, err = NewConfig(append(opts, TopDomains(dir+"/missing.csv"))...).Popular()
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("a run blocking popular domains keeps the previous files", func() {
+			var (
+				src = dir + "/feed.txt"
+				out = dir + "/domains.feed.blacklist.conf"
+				cfg = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tfile " + src + "\n\t\t\tprefix \"\"\n\t\t}\n\t}\n}"
+			)
+
+			run := func(feed string, o ...Option) error {
+				So(ioutil.WriteFile(src, []byte(feed), 0644), ShouldBeNil)
+				c := NewConfig(append(append(opts, Nodes([]string{domains}), Prefix("address="), Sanity(true)), o...)...)
+				So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+				ct, err := c.NewContent(FileObj)
+				So(err, ShouldBeNil)
+				return c.ProcessContent(ct)
+			}
+
+			err := run("malware.net\n")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "blacklist would block popular domains: windowsupdate.com, rerun with -force to allow it")
+
+			b, err := ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, files["domains.feed.blacklist.conf"])
+
+			So(run("malware.net\n", Force(true)), ShouldBeNil)
+			b, err = ioutil.ReadFile(out)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "address=/.malware.net/0.0.0.0\n")
+
+			So(ioutil.WriteFile(dir+"/hosts.feed.blacklist.conf", []byte("address=/ads.example.com/0.0.0.0\n"), 0644), ShouldBeNil)
+			So(run("mail.google.com\n"), ShouldBeNil)
+		})
+
+		Convey("only the first entries of a ranking are checked", func() {
+			var b strings.Builder
+			for i := 1; i <= popularLimit; i++ {
+				fmt.Fprintf(&b, "%d,site%d.example.org\n", i, i)
+			}
+			b.WriteString("1001,windowsupdate.com\n")
+
+			d, err := parsePopular(strings.NewReader(b.String()), "test")
+			So(err, ShouldBeNil)
+			So(d, ShouldHaveLength, popularLimit)
+
+			_, err = parsePopular(strings.NewReader("<html>Not Found</html>\n"), "test")
+			So(err.Error(), ShouldEqual, `test: invalid domain "<html>Not Found</html>"`)
+		})
+
+		Convey("the list is downloaded and saved for when it can't be", func() {
+			body := "example.com\nmalware.net\n"
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, body)
+			}))
+			defer srv.Close()
+
+			top := dir + "/top.txt"
+			hits, err := NewConfig(append(opts, TopDomains(top), TopURL(srv.URL))...).Popular()
+			So(err, ShouldBeNil)
+			So(hits, ShouldResemble, []Popular{{Domain: "malware.net"}})
+
+			b, err := ioutil.ReadFile(top)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, body)
+
+			body = "<html>Not Found</html>"
+			hits, err = NewConfig(append(opts, TopDomains(top), TopURL(srv.URL))...).Popular()
+			So(err, ShouldBeNil)
+			So(hits, ShouldResemble, []Popular{{Domain: "malware.net"}})
+
+			hits, err = NewConfig(append(opts, TopDomains(dir+"/missing.txt"), TopURL("http://127.0.0.1:1/top.txt"))...).Popular()
+			So(err, ShouldBeNil)
+			So(hits, ShouldHaveLength, 2)
+		})
+	})
+}
@@ -0,0 +1,132 @@
+package edgeos
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// PrecedenceInclude ranks explicit includes over node excludes and node
+	// excludes over global excludes, it is the default
+	PrecedenceInclude = "include"
+	// PrecedenceExclude ranks node excludes over global excludes and both
+	// over explicit includes
+	PrecedenceExclude = "exclude"
+)
+
+// Conflict records a domain that is both included and excluded
+type Conflict struct {
+	Domain   string `json:"domain"`
+	Include  string `json:"include"`
+	Exclude  string `json:"exclude"`
+	Excluded string `json:"excluded"`
+	Winner   string `json:"winner"`
+}
+
+// includeWins is true if explicit includes override exclusions
+func (p *Parms) includeWins() bool {
+	return p.Prec != PrecedenceExclude
+}
+
+// isInclude is true for the objects holding a node's explicit includes
+func (o *object) isInclude() bool {
+	return o.nType.isInc()
+}
+
+// effectiveExc returns node's exclusions, less any explicitly included
+// domains if includes override exclusions
+func (c *Config) effectiveExc(node string) []string {
+	if !c.includeWins() {
+		return c.tree[node].exc
+	}
+	return c.uninclude(c.tree[node].exc)
+}
+
+// uninclude returns exc less any explicitly included domains
+func (c *Config) uninclude(exc []string) []string {
+	inc := make(map[string]bool)
+	for _, n := range NodeKinds() {
+		if c.tree[n] != nil {
+			for _, d := range c.tree[n].live() {
+				inc[d] = true
+			}
+		}
+	}
+
+	out := make([]string, 0, len(exc))
+	for _, d := range exc {
+		if !inc[d] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// excluded returns the exclusion in node matching domain; domain and host
+// node exclusions match subdomains, host exclusions only match exactly
+func (c *Config) excluded(node, domain string) (string, bool) {
+	if c.tree[node] == nil {
+		return "", false
+	}
+
+	exc := make(map[string]bool, len(c.tree[node].exc))
+	for _, e := range c.tree[node].exc {
+		exc[e] = true
+	}
+
+	for d := domain; ; {
+		if exc[d] {
+			return d, true
+		}
+
+		i := strings.Index(d, ".")
+		if !wildNode(node) || i < 0 || !strings.Contains(d[i+1:], ".") {
+			return "", false
+		}
+		d = d[i+1:]
+	}
+}
+
+// Conflicts returns the included domains that are also excluded, each is
+// reported against its highest ranked exclusion, i.e. the node's own
+// exclusions before the other nodes' and the global exclusions
+func (c *Config) Conflicts() []Conflict {
+	var conflicts []Conflict
+
+	for _, node := range NodeKinds() {
+		if c.tree[node] == nil {
+			continue
+		}
+
+		for _, d := range c.tree[node].live() {
+			for _, exc := range append(append([]string{node}, NodeKinds()...), rootNode) {
+				e, ok := c.excluded(exc, d)
+				if !ok {
+					continue
+				}
+
+				winner := exc
+				if c.includeWins() {
+					winner = node
+				}
+
+				conflicts = append(conflicts, Conflict{
+					Domain:   d,
+					Include:  node,
+					Exclude:  exc,
+					Excluded: e,
+					Winner:   winner,
+				})
+				break
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Domain != conflicts[j].Domain {
+			return conflicts[i].Domain < conflicts[j].Domain
+		}
+		return conflicts[i].Include < conflicts[j].Include
+	})
+	return conflicts
+}
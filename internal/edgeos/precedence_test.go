@@ -0,0 +1,84 @@
+package edgeos
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrecedence(t *testing.T) {
+	Convey("Testing include and exclude precedence", t, func() {
+		cfg := `blacklist {
+    dns-redirect-ip 0.0.0.0
+    exclude example.com
+    exclude doubleclick.net
+    domains {
+        exclude adsrvr.org
+        include adsrvr.org
+        include ads.example.com
+        include kiosked.com
+    }
+    hosts {
+        exclude beap.gemini.yahoo.com
+        include beap.gemini.yahoo.com
+        include doubleclick.net
+    }
+}`
+		newCfg := func(p string) *Config {
+			c := NewConfig(
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains, hosts}),
+				Precedence(p),
+				Prefix("address="),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			return c
+		}
+
+		process := func(c *Config) string {
+			d := &dummyConfig{t: t}
+			for _, iface := range []IFace{ExRtObj, ExDmObj, ExHtObj, PreDObj, PreHObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				So(d.ProcessContent(ct), ShouldBeNil)
+			}
+			return strings.Join(d.s, "\n")
+		}
+
+		tests := []struct {
+			precedence string
+			conflicts  []Conflict
+			exp        string
+		}{
+			{
+				precedence: PrecedenceInclude,
+				conflicts: []Conflict{
+					{Domain: "ads.example.com", Include: domains, Exclude: rootNode, Excluded: "example.com", Winner: domains},
+					{Domain: "adsrvr.org", Include: domains, Exclude: domains, Excluded: "adsrvr.org", Winner: domains},
+					{Domain: "beap.gemini.yahoo.com", Include: hosts, Exclude: hosts, Excluded: "beap.gemini.yahoo.com", Winner: hosts},
+					{Domain: "doubleclick.net", Include: hosts, Exclude: rootNode, Excluded: "doubleclick.net", Winner: hosts},
+				},
+				exp: "address=/example.com/0.0.0.0\n\n\naddress=/ads.example.com/0.0.0.0\naddress=/adsrvr.org/0.0.0.0\naddress=/kiosked.com/0.0.0.0\naddress=/beap.gemini.yahoo.com/0.0.0.0\naddress=/doubleclick.net/0.0.0.0",
+			},
+			{
+				precedence: PrecedenceExclude,
+				conflicts: []Conflict{
+					{Domain: "ads.example.com", Include: domains, Exclude: rootNode, Excluded: "example.com", Winner: rootNode},
+					{Domain: "adsrvr.org", Include: domains, Exclude: domains, Excluded: "adsrvr.org", Winner: domains},
+					{Domain: "beap.gemini.yahoo.com", Include: hosts, Exclude: hosts, Excluded: "beap.gemini.yahoo.com", Winner: hosts},
+					{Domain: "doubleclick.net", Include: hosts, Exclude: rootNode, Excluded: "doubleclick.net", Winner: rootNode},
+				},
+				exp: "address=/doubleclick.net/0.0.0.0\naddress=/example.com/0.0.0.0\naddress=/adsrvr.org/0.0.0.0\naddress=/beap.gemini.yahoo.com/0.0.0.0\naddress=/kiosked.com/0.0.0.0\n",
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("Testing "+tt.precedence+" precedence", func() {
+				c := newCfg(tt.precedence)
+				So(c.Conflicts(), ShouldResemble, tt.conflicts)
+				So(process(c), ShouldEqual, tt.exp)
+			})
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package edgeos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cacheMeta records a cached source download so later runs can tell whether
+// it has changed
+type cacheMeta struct {
+	URL      string `json:"url"`
+	Length   int64  `json:"length"`
+	ETag     string `json:"etag,omitempty"`
+	Modified string `json:"modified,omitempty"`
+}
+
+// matches returns true if m describes the same download as cached: the server
+// must send an ETag or Last-Modified, as the length alone can't tell an edited
+// list from the old one, and they and the length must all match
+func (m *cacheMeta) matches(cached *cacheMeta) bool {
+	if m.ETag == "" && m.Modified == "" {
+		return false
+	}
+	return m.Length >= 0 && m.Length == cached.Length && m.ETag == cached.ETag && m.Modified == cached.Modified
+}
+
+// cacheFile returns the path of a source's cached body or metadata
+func (o *object) cacheFile(ext string) string {
+	return fmt.Sprintf("%v/%v.%v.%v", o.Cache, getType(o.nType).(string), o.name, ext)
+}
+
+// cacheable returns true if url sources are being cached
+func (o *object) cacheable() bool {
+	return o.Cache != "" && o.ltype == urls
+}
+
+// readCache returns the cached metadata for the source, if its cached body
+// is complete
+func (o *object) readCache() (*cacheMeta, error) {
+	b, err := ioutil.ReadFile(o.cacheFile("meta"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &cacheMeta{}
+	if err = json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(o.cacheFile("body"))
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() != m.Length {
+		return nil, fmt.Errorf("cached body is %d bytes, expected %d", fi.Size(), m.Length)
+	}
+	return m, nil
+}
+
+// useCache sets the source's reader to its cached body, which is read from
+// disk as it is parsed if downloads are spilled
+func (o *object) useCache() error {
+	if o.spill {
+		f, err := os.Open(o.cacheFile("body"))
+		if err != nil {
+			return err
+		}
+		o.r = &spillReader{f}
+		return nil
+	}
+
+	body, err := ioutil.ReadFile(o.cacheFile("body"))
+	if err != nil {
+		return err
+	}
+	o.r = bytes.NewBuffer(body)
+	return nil
+}
+
+// writeCache saves a downloaded body of n bytes from r and its metadata for
+// the next run's pre-check, audits leave the cache alone
+func (o *object) writeCache(resp *http.Response, r io.Reader, n int64) error {
+	if !o.cacheable() || o.audit != nil || n == 0 || resp.StatusCode/100 != 2 {
+		return nil
+	}
+
+	if err := os.MkdirAll(o.Cache, 0755); err != nil {
+		return err
+	}
+
+	m, err := json.Marshal(&cacheMeta{URL: o.url, Length: n, ETag: resp.Header.Get("ETag"), Modified: resp.Header.Get("Last-Modified")})
+	if err != nil {
+		return err
+	}
+
+	tmp := o.cacheFile("body") + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, o.cacheFile("body")); err != nil {
+		return err
+	}
+
+	tmp = o.cacheFile("meta") + ".tmp"
+	if err = ioutil.WriteFile(tmp, m, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.cacheFile("meta"))
+}
+
+// remoteMeta asks the server for the source's length, ETag and modification time
+// with a HEAD request, falling back to a ranged 0-0 GET if HEAD isn't allowed
+func (o *object) remoteMeta(client *http.Client, endpoint string, auth authorizer) (*cacheMeta, error) {
+	head := func(method string) (*http.Response, error) {
+		req, err := http.NewRequest(method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req = req.WithContext(o.context())
+		req.Header.Set("User-Agent", agent)
+		if method == http.MethodGet {
+			req.Header.Set("Range", "bytes=0-0")
+		}
+
+		if auth != nil {
+			if err = auth(client, req); err != nil {
+				return nil, err
+			}
+		}
+		o.secrets = append(o.secrets, o.headerSecrets(req.Header)...)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := head(http.MethodHead)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = head(http.MethodGet)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &cacheMeta{URL: o.url, Length: -1, ETag: resp.Header.Get("ETag"), Modified: resp.Header.Get("Last-Modified")}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		m.Length = resp.ContentLength
+	case http.StatusPartialContent:
+		// Content-Range: bytes 0-0/<length>
+		cr := resp.Header.Get("Content-Range")
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			if n, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				m.Length = n
+			}
+		}
+	default:
+		return nil, fmt.Errorf("pre-check returned %v", resp.Status)
+	}
+	return m, nil
+}
+
+// unchanged returns true if the pre-check shows the source hasn't changed
+// since it was cached, so its cached body can be used
+func (o *object) unchanged(client *http.Client, endpoint string, auth authorizer) bool {
+	if !o.cacheable() {
+		return false
+	}
+
+	cached, err := o.readCache()
+	if err != nil || cached.URL != o.url {
+		return false
+	}
+
+	remote, err := o.remoteMeta(client, endpoint, auth)
+	if err != nil {
+		o.debug(fmt.Sprintf("%v: pre-check failed: %v", o.name, redactErr(err, o.secrets)))
+		return false
+	}
+
+	if !remote.matches(cached) {
+		return false
+	}
+
+	o.log(fmt.Sprintf("%v unchanged since last download, using cached copy", o.name))
+	return true
+}
@@ -0,0 +1,99 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreCheck(t *testing.T) {
+	Convey("Testing getHTTP() with a HEAD pre-check", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var (
+			data     = "0.0.0.0 ads.example.com\n"
+			modified = "Mon, 02 Jan 2006 15:04:05 GMT"
+			etag     string
+			noHead   bool
+			requests []string
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.Method+" "+r.Header.Get("Range"))
+			if modified != "" {
+				w.Header().Set("Last-Modified", modified)
+			}
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
+
+			switch {
+			case r.Method == http.MethodHead && noHead:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			case r.Header.Get("Range") == "bytes=0-0":
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-0/%d", len(data)))
+				w.WriteHeader(http.StatusPartialContent)
+				fmt.Fprint(w, data[:1])
+			default:
+				w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+				fmt.Fprint(w, data)
+			}
+		}))
+		defer srv.Close()
+
+		tests := []struct {
+			name     string
+			data     string
+			modified string
+			etag     string
+			noHead   bool
+			exp      []string
+		}{
+			{name: "first download", exp: []string{"GET "}},
+			{name: "unchanged", exp: []string{"HEAD "}},
+			{name: "unchanged without HEAD", noHead: true, exp: []string{"HEAD ", "GET bytes=0-0"}},
+			{name: "modified", modified: "Tue, 03 Jan 2006 15:04:05 GMT", exp: []string{"HEAD ", "GET "}},
+			{name: "resized", data: "0.0.0.0 ads.example.com\n0.0.0.0 ads.example.net\n", exp: []string{"HEAD ", "GET "}},
+			{name: "no modification time", modified: "", exp: []string{"HEAD ", "GET "}},
+			{name: "length alone", exp: []string{"HEAD ", "GET "}},
+			{name: "new etag", etag: `"v1"`, exp: []string{"HEAD ", "GET "}},
+			{name: "unchanged by etag", exp: []string{"HEAD "}},
+			{name: "changed etag", etag: `"v2"`, exp: []string{"HEAD ", "GET "}},
+		}
+
+		for _, tt := range tests {
+			if tt.data != "" {
+				data = tt.data
+			}
+			if tt.modified != "" || tt.name == "no modification time" {
+				modified = tt.modified
+			}
+			if tt.etag != "" {
+				etag = tt.etag
+			}
+			noHead, requests = tt.noHead, nil
+
+			o := getHTTP(&object{Parms: &Parms{Cache: dir, Method: http.MethodGet}, ltype: urls, name: "head", nType: host, url: srv.URL})
+			So(o.err, ShouldBeNil)
+
+			b, err := ioutil.ReadAll(o.r)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, data)
+			So(requests, ShouldResemble, tt.exp)
+		}
+
+		Convey("sources aren't cached without a cache directory", func() {
+			requests = nil
+			o := getHTTP(&object{Parms: &Parms{Method: http.MethodGet}, ltype: urls, name: "head", nType: host, url: srv.URL})
+			So(o.err, ShouldBeNil)
+			So(requests, ShouldResemble, []string{"GET "})
+		})
+	})
+}
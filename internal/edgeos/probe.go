@@ -0,0 +1,143 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// probeSamples is how many of a probed source's entries are kept as a sample
+const probeSamples = 10
+
+// Source formats recognised by Probe
+const (
+	FormatAdblock = "adblock"
+	FormatDomains = "domains"
+	FormatHosts   = "hosts"
+)
+
+// SourceProbe is the outcome of test fetching a prospective source, with the
+// node and prefix its format suits
+type SourceProbe struct {
+	URL      string   `json:"url"`
+	Format   string   `json:"format"`
+	Node     string   `json:"node"`
+	Prefix   string   `json:"prefix,omitempty"`
+	Lines    int      `json:"lines"`
+	Entries  int      `json:"entries"`
+	Rejected int      `json:"rejected"`
+	Sample   []string `json:"sample"`
+	Rejects  []string `json:"rejects,omitempty"`
+}
+
+// detectFormat returns the format and prefix most of b's lines use, hosts
+// files are recognised by their leading IP address and adblock lists by ||
+func detectFormat(b []byte) (format, prefix string) {
+	var (
+		formats = make(map[string]int)
+		ips     = make(map[string]int)
+		s       = bufio.NewScanner(bytes.NewReader(b))
+	)
+
+	for s.Scan() {
+		line := bytes.TrimSpace(s.Bytes())
+		switch {
+		case len(line) == 0, line[0] == '#', line[0] == '!', line[0] == '[', bytes.HasPrefix(line, []byte("//")):
+		case bytes.HasPrefix(line, []byte("||")):
+			formats[FormatAdblock]++
+		default:
+			f := bytes.Fields(line)
+			if len(f) > 1 && parseIP(f[0]) != nil {
+				formats[FormatHosts]++
+				ips[string(f[0])]++
+				continue
+			}
+			formats[FormatDomains]++
+		}
+	}
+
+	format = FormatDomains
+	for _, f := range []string{FormatAdblock, FormatHosts} {
+		if formats[f] > formats[format] {
+			format = f
+		}
+	}
+
+	switch format {
+	case FormatAdblock:
+		prefix = "||"
+	case FormatHosts:
+		for ip, n := range ips {
+			if n > ips[prefix] || (n == ips[prefix] && ip < prefix) {
+				prefix = ip
+			}
+		}
+	}
+	return format, prefix
+}
+
+// Probe test fetches the source at u, detects its format unless node is set
+// and returns how its lines parse, without changing the configuration or the
+// generated files
+func (c *Config) Probe(u, node, prefix string) (*SourceProbe, error) {
+	p := *c.Parms
+	p.Dex = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	p.Exc = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	p.Hold, p.Prog, p.state, p.Xform = 0, nil, nil, ""
+	p.audit, p.fails, p.fresh, p.seen, p.Chaos = nil, nil, nil, nil, nil
+
+	o := &object{Parms: &p, name: "probe", ltype: urls, url: u}
+	if strings.HasPrefix(u, fileScheme) {
+		path, err := fileURLPath(u)
+		if err != nil {
+			return nil, err
+		}
+		o.file, o.ltype, o.url = path, files, ""
+		o.r, o.err = getFile(o.path())
+	} else {
+		o = getHTTP(o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	b, err := ioutil.ReadAll(o.r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, errors.New(u + " is empty")
+	}
+
+	var (
+		detected string
+		r        = &SourceProbe{URL: u, Node: node, Prefix: prefix}
+	)
+	if r.Format, detected = detectFormat(b); r.Prefix == "" {
+		r.Prefix = detected
+	}
+	if r.Node == "" {
+		r.Node = domains
+		if r.Format == FormatHosts {
+			r.Node = hosts
+		}
+	}
+
+	o.nType, o.prefix, o.r = typeStr(r.Node), r.Prefix, bytes.NewReader(b)
+	add := o.extract()
+	r.Lines = bytes.Count(b, []byte("\n"))
+	r.Entries, r.Rejected, r.Rejects = len(add.entry), o.rejected, o.rejects
+
+	for k := range add.entry {
+		r.Sample = append(r.Sample, k)
+	}
+	sort.Strings(r.Sample)
+	if len(r.Sample) > probeSamples {
+		r.Sample = r.Sample[:probeSamples]
+	}
+	return r, nil
+}
@@ -0,0 +1,56 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProbe(t *testing.T) {
+	Convey("Testing source probes", t, func() {
+		Convey("formats are detected", func() {
+			tests := []struct {
+				body   string
+				format string
+				prefix string
+			}{
+				{body: "# comment\nads.example.com\ntrack.example.com\n", format: FormatDomains},
+				{body: "127.0.0.1 localhost\n0.0.0.0 ads.example.com\n0.0.0.0 track.example.com\n", format: FormatHosts, prefix: "0.0.0.0"},
+				{body: "[Adblock Plus 2.0]\n! Title: ads\n||ads.example.com^\n||track.example.com^\n", format: FormatAdblock, prefix: "||"},
+			}
+
+			for _, tt := range tests {
+				format, prefix := detectFormat([]byte(tt.body))
+				So(format, ShouldEqual, tt.format)
+				So(prefix, ShouldEqual, tt.prefix)
+			}
+		})
+
+		Convey("a url source is fetched and parsed", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "||ads.example.com^\n||track.example.com^\n||bad..entry^\n")
+			}))
+			defer srv.Close()
+
+			c := NewConfig(Method("GET"), Nodes([]string{domains, hosts}))
+			p, err := c.Probe(srv.URL+"/list.txt", "", "")
+			So(err, ShouldBeNil)
+			So(p.Format, ShouldEqual, FormatAdblock)
+			So(p.Node, ShouldEqual, domains)
+			So(p.Prefix, ShouldEqual, "||")
+			So(p.Lines, ShouldEqual, 3)
+			So(p.Entries, ShouldEqual, 2)
+			So(p.Sample, ShouldResemble, []string{"ads.example.com", "track.example.com"})
+			So(c.Exc.entry, ShouldBeEmpty)
+
+			p, err = c.Probe(srv.URL+"/list.txt", hosts, "0.0.0.0")
+			So(err, ShouldBeNil)
+			So(p.Node, ShouldEqual, hosts)
+			So(p.Entries, ShouldEqual, 0)
+			So(p.Rejected, ShouldEqual, 3)
+		})
+	})
+}
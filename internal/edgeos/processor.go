@@ -0,0 +1,129 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SourceProcessor parses a source's raw content into the domains it lists,
+// for feed formats the built-in prefix matching can't handle
+type SourceProcessor interface {
+	Parse(r io.Reader) ([]string, error)
+}
+
+// ProcessorFunc adapts an ordinary function to a SourceProcessor
+type ProcessorFunc func(r io.Reader) ([]string, error)
+
+// Parse implements SourceProcessor
+func (f ProcessorFunc) Parse(r io.Reader) ([]string, error) {
+	return f(r)
+}
+
+var (
+	procMu     sync.RWMutex
+	processors = make(map[string]SourceProcessor)
+)
+
+// RegisterProcessor makes p available to sources with a "processor <name>"
+// leaf, registering a name again replaces its processor
+func RegisterProcessor(name string, p SourceProcessor) {
+	procMu.Lock()
+	processors[name] = p
+	procMu.Unlock()
+}
+
+// Processors returns the sorted names of the registered processors
+func Processors() []string {
+	procMu.RLock()
+	defer procMu.RUnlock()
+
+	names := make([]string, 0, len(processors))
+	for k := range processors {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExecProcessor is a SourceProcessor plugin, an external command that reads
+// a source's raw content on stdin and writes one domain per line to stdout
+type ExecProcessor struct {
+	Args []string
+
+	// parms runs the command through its Runner and Deadline
+	parms *Parms
+}
+
+// Parse implements SourceProcessor
+func (e *ExecProcessor) Parse(r io.Reader) ([]string, error) {
+	p := e.parms
+	if p == nil {
+		p = &Parms{}
+	}
+
+	out, err := p.execute(e.Args, r, false)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", e.Args[0], err)
+	}
+
+	var domains []string
+	b := bufio.NewScanner(bytes.NewReader(out))
+	for b.Scan() {
+		if d := strings.TrimSpace(b.Text()); d != "" && !strings.HasPrefix(d, "#") {
+			domains = append(domains, d)
+		}
+	}
+	return domains, b.Err()
+}
+
+// processorFor returns the SourceProcessor named by a source's processor
+// leaf, a JSON array is run as an ExecProcessor through parms
+func processorFor(parms *Parms, name string) (SourceProcessor, error) {
+	if strings.HasPrefix(strings.TrimSpace(name), "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(name), &args); err != nil {
+			return nil, fmt.Errorf("invalid processor argument list: %v", err)
+		}
+		if len(args) == 0 || args[0] == "" {
+			return nil, errors.New("empty processor argument list")
+		}
+		return &ExecProcessor{Args: args, parms: parms}, nil
+	}
+
+	procMu.RLock()
+	p, ok := processors[name]
+	procMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown processor %q", name)
+	}
+	return p, nil
+}
+
+// runProcessor replaces a fetched source's content with the domains its
+// processor parses from it
+func (o *object) runProcessor() {
+	if o.processor == "" || o.err != nil {
+		return
+	}
+
+	p, err := processorFor(o.Parms, o.processor)
+	if err != nil {
+		o.r, o.err = strings.NewReader(""), err
+		return
+	}
+
+	domains, err := p.Parse(o.r)
+	if err != nil {
+		o.r, o.err = strings.NewReader(""), fmt.Errorf("processor %v: %v", o.processor, err)
+		return
+	}
+	o.r = strings.NewReader(strings.Join(domains, "\n"))
+}
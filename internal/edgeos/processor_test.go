@@ -0,0 +1,94 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceProcessors(t *testing.T) {
+	Convey("Testing source processors", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		// csv takes the domain from the second column of a CSV feed
+		RegisterProcessor("csv", ProcessorFunc(func(r io.Reader) ([]string, error) {
+			var domains []string
+			b := bufio.NewScanner(r)
+			for b.Scan() {
+				if f := strings.Split(b.Text(), ","); len(f) > 1 {
+					domains = append(domains, f[1])
+				}
+			}
+			return domains, b.Err()
+		}))
+		So(Processors(), ShouldContain, "csv")
+
+		So(ioutil.WriteFile(dir+"/feed.csv", []byte("1,ads.csv.com,2019\n2,track.csv.com,2019\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/feed.txt", []byte("block: ads.exec.com\nallow: ok.exec.com\nblock: Pixel.Exec.com\n"), 0644), ShouldBeNil)
+
+		cfg := `blacklist {
+	dns-redirect-ip 0.0.0.0
+	domains {
+	}
+	hosts {
+		source csv {
+			file %[1]v/feed.csv
+			processor csv
+		}
+		source exec {
+			file %[1]v/feed.txt
+			processor '["sed", "-n", "s/^block: //p"]'
+		}
+	}
+}`
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Prefix("address="),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, dir)}), ShouldBeNil)
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		for f, exp := range map[string]string{
+			"hosts.csv.blacklist.conf":  "address=/ads.csv.com/0.0.0.0\naddress=/track.csv.com/0.0.0.0\n",
+			"hosts.exec.blacklist.conf": "address=/ads.exec.com/0.0.0.0\naddress=/pixel.exec.com/0.0.0.0\n",
+		} {
+			act, err := ioutil.ReadFile(dir + "/" + f)
+			So(err, ShouldBeNil)
+			So(string(act), ShouldEqual, exp)
+		}
+
+		Convey("Testing processor errors", func() {
+			tests := []struct {
+				name string
+				exp  string
+			}{
+				{name: "missing", exp: `unknown processor "missing"`},
+				{name: `["/bin/sh", "-c", "echo bad feed >&2; exit 3"]`, exp: "processor [\"/bin/sh\", \"-c\", \"echo bad feed >&2; exit 3\"]: /bin/sh: exit status 3: bad feed"},
+			}
+
+			for _, tt := range tests {
+				o := &object{processor: tt.name, r: bytes.NewBufferString("ads.com\n")}
+				o.runProcessor()
+				So(o.err.Error(), ShouldEqual, tt.exp)
+			}
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: "blacklist {\n\thosts {\n\t\tsource a {\n\t\t\tprocessor '[]'\n\t\t}\n\t}\n}"})
+			So(err.Error(), ShouldEqual, `config.boot:4: source "a" has empty processor argument list`)
+		})
+	})
+}
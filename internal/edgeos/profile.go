@@ -0,0 +1,191 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// profile labels the configuration node for time-based blocking profiles
+const profile = "profile"
+
+// weekdays maps schedule day names to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Profile is a named set of pre-generated dnsmasq files, e.g. a social media
+// category, that is only active during its schedules
+type Profile struct {
+	Name      string      `json:"name"`
+	Dir       string      `json:"directory"`
+	Schedules []*Schedule `json:"schedules"`
+}
+
+// Schedule is a daily time window, Start and End are minutes after midnight;
+// windows that end before they start run overnight and Days are the days
+// they start on
+type Schedule struct {
+	Days  [7]bool `json:"days"`
+	Start int     `json:"start"`
+	End   int     `json:"end"`
+}
+
+// ParseSchedule parses "HH:MM-HH:MM [day,...]", e.g. "21:00-07:00
+// sun,mon,tue,wed,thu", the window applies every day if no days are given
+func ParseSchedule(s string) (*Schedule, error) {
+	var (
+		fields = strings.Fields(s)
+		sc     = &Schedule{}
+	)
+
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid schedule %q", s)
+	}
+
+	win := strings.Split(fields[0], "-")
+	if len(win) != 2 {
+		return nil, fmt.Errorf("invalid schedule window %q", fields[0])
+	}
+
+	for i, p := range []*int{&sc.Start, &sc.End} {
+		t, err := time.Parse("15:04", win[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule time %q", win[i])
+		}
+		*p = t.Hour()*60 + t.Minute()
+	}
+
+	if sc.Start == sc.End {
+		return nil, fmt.Errorf("empty schedule window %q", fields[0])
+	}
+
+	if len(fields) == 1 {
+		for i := range sc.Days {
+			sc.Days[i] = true
+		}
+		return sc, nil
+	}
+
+	for _, d := range strings.Split(strings.ToLower(fields[1]), ",") {
+		wd, ok := weekdays[d]
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule day %q", d)
+		}
+		sc.Days[wd] = true
+	}
+	return sc, nil
+}
+
+// Active returns true if t falls within the schedule
+func (s *Schedule) Active(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if s.Start < s.End {
+		return s.Days[t.Weekday()] && m >= s.Start && m < s.End
+	}
+	return (s.Days[t.Weekday()] && m >= s.Start) || (s.Days[(t.Weekday()+6)%7] && m < s.End)
+}
+
+// Active returns true if any of the profile's schedules include t
+func (p *Profile) Active(t time.Time) bool {
+	for _, s := range p.Schedules {
+		if s.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// file returns the dnsmasq file the profile is installed as
+func (p *Profile) file(dir string) string {
+	return filepath.Join(dir, profile+"."+p.Name+".conf")
+}
+
+// Profiles returns the configured blocking profiles
+func (c *Config) Profiles() []*Profile {
+	return c.profiles
+}
+
+// ActiveProfiles returns the configured profiles active at t
+func (c *Config) ActiveProfiles(t time.Time) []*Profile {
+	var active []*Profile
+	for _, p := range c.profiles {
+		if p.Active(t) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// content returns the profile's pre-generated files concatenated
+func (c *Config) content(p *Profile) ([]byte, error) {
+	names, err := c.globFiles(p.Dir, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		f, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(f)
+	}
+	return b.Bytes(), nil
+}
+
+// ApplyProfile installs the profiles active at t into the dnsmasq directory
+// and removes any others; changed is true if dnsmasq needs reloading
+func (c *Config) ApplyProfile(t time.Time) (changed bool, err error) {
+	keep := make(map[string]bool)
+	for _, p := range c.ActiveProfiles(t) {
+		f := p.file(c.Dir)
+		keep[f] = true
+
+		b, err := c.content(p)
+		if err != nil {
+			return changed, fmt.Errorf("profile %v: %v", p.Name, err)
+		}
+
+		if cur, err := ioutil.ReadFile(f); err != nil || !bytes.Equal(cur, b) {
+			tmp := f + ".tmp"
+			if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+				return changed, err
+			}
+			if err = os.Rename(tmp, f); err != nil {
+				return changed, err
+			}
+			changed = true
+		}
+	}
+
+	installed, err := filepath.Glob(filepath.Join(c.Dir, profile+".*.conf"))
+	if err != nil {
+		return changed, err
+	}
+
+	var stale []string
+	for _, f := range installed {
+		if !keep[f] {
+			stale = append(stale, f)
+		}
+	}
+
+	if stale != nil {
+		changed = true
+	}
+	return changed, purgeFiles(stale)
+}
@@ -0,0 +1,154 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	Convey("Testing ParseSchedule() and Active()", t, func() {
+		// 2017-01-01 is a Sunday
+		at := func(day int, clock string) time.Time {
+			c, _ := time.Parse("15:04", clock)
+			return time.Date(2017, 1, day, c.Hour(), c.Minute(), 0, 0, time.Local)
+		}
+
+		tests := []struct {
+			sched  string
+			err    string
+			active []time.Time
+			idle   []time.Time
+		}{
+			{
+				sched:  "21:00-07:00 sun,mon,tue,wed,thu",
+				active: []time.Time{at(1, "21:00"), at(2, "06:59"), at(5, "23:30"), at(6, "06:00")},
+				idle:   []time.Time{at(1, "07:00"), at(1, "20:59"), at(6, "21:00"), at(7, "06:00")},
+			},
+			{
+				sched:  "09:00-17:00",
+				active: []time.Time{at(1, "09:00"), at(7, "16:59")},
+				idle:   []time.Time{at(1, "17:00"), at(3, "08:59")},
+			},
+			{sched: "", err: `invalid schedule ""`},
+			{sched: "21:00", err: `invalid schedule window "21:00"`},
+			{sched: "25:00-07:00", err: `invalid schedule time "25:00"`},
+			{sched: "07:00-07:00", err: `empty schedule window "07:00-07:00"`},
+			{sched: "21:00-07:00 sun,funday", err: `invalid schedule day "funday"`},
+		}
+
+		for _, tt := range tests {
+			s, err := ParseSchedule(tt.sched)
+			if tt.err != "" {
+				So(err.Error(), ShouldEqual, tt.err)
+				continue
+			}
+
+			So(err, ShouldBeNil)
+			for _, a := range tt.active {
+				So(s.Active(a), ShouldBeTrue)
+			}
+			for _, i := range tt.idle {
+				So(s.Active(i), ShouldBeFalse)
+			}
+		}
+	})
+}
+
+func TestApplyProfile(t *testing.T) {
+	Convey("Testing ApplyProfile()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		social := filepath.Join(dir, "social")
+		So(os.Mkdir(social, 0755), ShouldBeNil)
+		So(ioutil.WriteFile(social+"/domains.fb.blacklist.conf", []byte("address=/facebook.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(social+"/hosts.tiktok.blacklist.conf", []byte("address=/tiktok.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/profile.gaming.conf", []byte("address=/steam.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		video := filepath.Join(dir, "video")
+		So(os.Mkdir(video, 0755), ShouldBeNil)
+		So(ioutil.WriteFile(video+"/domains.yt.blacklist.conf", []byte("address=/youtube.com/0.0.0.0\n"), 0644), ShouldBeNil)
+
+		cfg := `blacklist {
+    dns-redirect-ip 0.0.0.0
+    profile social {
+        directory %v
+        schedule "21:00-07:00 sun,mon,tue,wed,thu"
+        schedule 12:00-13:00
+    }
+    profile video {
+        directory %v
+        schedule 22:00-23:00
+    }
+}`
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, social, video)}), ShouldBeNil)
+		So(len(c.Profiles()), ShouldEqual, 2)
+		So(len(c.Profiles()[0].Schedules), ShouldEqual, 2)
+
+		night := time.Date(2017, 1, 1, 22, 0, 0, 0, time.Local)
+		day := time.Date(2017, 1, 1, 15, 0, 0, 0, time.Local)
+		late := time.Date(2017, 1, 1, 23, 30, 0, 0, time.Local)
+		So(c.ActiveProfiles(night), ShouldResemble, c.Profiles())
+		So(c.ActiveProfiles(late), ShouldResemble, c.Profiles()[:1])
+		So(c.ActiveProfiles(day), ShouldBeNil)
+
+		changed, err := c.ApplyProfile(night)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+
+		b, err := ioutil.ReadFile(dir + "/profile.social.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/facebook.com/0.0.0.0\naddress=/tiktok.com/0.0.0.0\n")
+		b, err = ioutil.ReadFile(dir + "/profile.video.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/youtube.com/0.0.0.0\n")
+		_, err = os.Stat(dir + "/profile.gaming.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		changed, err = c.ApplyProfile(night)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeFalse)
+
+		changed, err = c.ApplyProfile(late)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+		_, err = os.Stat(dir + "/profile.social.conf")
+		So(err, ShouldBeNil)
+		_, err = os.Stat(dir + "/profile.video.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		changed, err = c.ApplyProfile(day)
+		So(err, ShouldBeNil)
+		So(changed, ShouldBeTrue)
+		_, err = os.Stat(dir + "/profile.social.conf")
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		Convey("Testing profile configuration errors", func() {
+			tests := []struct {
+				cfg string
+				err string
+			}{
+				{cfg: "blacklist {\n\tprofile social {\n\t\tschedule 09:00-17:00\n\t}\n}", err: `config.boot:4: profile "social" missing directory`},
+				{cfg: "blacklist {\n\tprofile social {\n\t\tdirectory /tmp\n\t}\n}", err: `config.boot:4: profile "social" missing schedule`},
+				{cfg: "blacklist {\n\tprofile social {\n\t\tschedule 9am-5pm\n\t}\n}", err: `config.boot:3: profile "social" has invalid schedule time "9am"`},
+			}
+
+			for _, tt := range tests {
+				So(NewConfig().ReadCfg(&CFGstatic{Cfg: tt.cfg}).Error(), ShouldEqual, tt.err)
+			}
+		})
+	})
+}
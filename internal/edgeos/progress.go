@@ -0,0 +1,63 @@
+package edgeos
+
+import "io"
+
+const (
+	// progressBytes is how often download progress is reported
+	progressBytes = 1 << 18
+	// progressLines is how often parse progress is reported
+	progressLines = 10000
+)
+
+// Progress reports how far a source has been downloaded and parsed; Total
+// is the download size or -1 if it isn't known, and Done is set once the
+// source has been parsed
+type Progress struct {
+	Source string
+	Bytes  int64
+	Total  int64
+	Lines  int
+	Done   bool
+}
+
+// ProgressFunc receives progress reports, sources are fetched concurrently
+// so it must be safe to call from multiple goroutines
+type ProgressFunc func(Progress)
+
+// progress records p in the run state and sends it to the ProgressFunc, if
+// there is one
+func (o *object) progress(p Progress) {
+	p.Source = o.name
+	o.state.Report(p)
+	if o.Prog != nil {
+		o.Prog(p)
+	}
+}
+
+// progressReader reports download progress as it is read
+type progressReader struct {
+	io.Reader
+	o     *object
+	n     int64
+	next  int64
+	total int64
+}
+
+// newProgressReader returns r, wrapped to report progress if there is a
+// ProgressFunc or run state
+func (o *object) newProgressReader(r io.Reader, total int64) io.Reader {
+	if o.Prog == nil && o.state == nil {
+		return r
+	}
+	return &progressReader{Reader: r, o: o, next: progressBytes, total: total}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	p.n += int64(n)
+	if p.n >= p.next || err == io.EOF {
+		p.o.progress(Progress{Bytes: p.n, Total: p.total})
+		p.next = p.n + progressBytes
+	}
+	return n, err
+}
@@ -0,0 +1,61 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProgress(t *testing.T) {
+	Convey("Testing progress reporting", t, func() {
+		var (
+			mu  sync.Mutex
+			act []Progress
+		)
+
+		data := strings.Repeat("ads.example.com\n", 25000)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			fmt.Fprint(w, data)
+		}))
+		defer srv.Close()
+
+		c := NewConfig(
+			FileNameFmt("%v/%v.%v.%v"),
+			Method("GET"),
+			OnProgress(func(p Progress) {
+				mu.Lock()
+				act = append(act, p)
+				mu.Unlock()
+			}),
+			Prefix("address="),
+		)
+
+		o := getHTTP(&object{Parms: c.Parms, ip: "0.0.0.0", name: "big", nType: host, url: srv.URL})
+		So(o.err, ShouldBeNil)
+		So(len(act), ShouldBeGreaterThan, 1)
+		for _, p := range act {
+			So(p.Source, ShouldEqual, "big")
+			So(p.Total, ShouldEqual, len(data))
+		}
+		So(act[len(act)-1].Bytes, ShouldEqual, len(data))
+
+		act = nil
+		o.process()
+		So(act, ShouldResemble, []Progress{
+			{Source: "big", Lines: 10000},
+			{Source: "big", Lines: 20000},
+			{Source: "big", Lines: 25000, Done: true},
+		})
+
+		Convey("Nothing should be reported without a ProgressFunc or RunState", func() {
+			r := strings.NewReader(data)
+			So((&object{Parms: &Parms{}}).newProgressReader(r, -1), ShouldEqual, r)
+		})
+	})
+}
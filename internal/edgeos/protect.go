@@ -0,0 +1,118 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// protectedDomains is the built-in set of domains that are never blocked,
+// blocking them breaks captive portal detection, time sync or firmware updates
+var protectedDomains = []string{
+	"captive.apple.com",
+	"clients3.google.com",
+	"connectivitycheck.android.com",
+	"connectivitycheck.gstatic.com",
+	"detectportal.firefox.com",
+	"fw-download.ubnt.com",
+	"fw-update.ubnt.com",
+	"network-test.debian.org",
+	"nmcheck.gnome.org",
+	"pool.ntp.org",
+	"time.apple.com",
+	"time.windows.com",
+	"www.msftconnecttest.com",
+	"www.msftncsi.com",
+}
+
+var (
+	// hostName returns the router's hostname, it is a variable so tests can
+	// replace it
+	hostName = os.Hostname
+	// ntpConf is read for the router's configured NTP servers
+	ntpConf = "/etc/ntp.conf"
+)
+
+// protectedList returns the configured protected domains or the built-in set,
+// with the router's hostname and NTP servers
+func (p *Parms) protectedList() []string {
+	d := append([]string(nil), protectedDomains...)
+	if p.Protect != nil {
+		d = append([]string(nil), p.Protect...)
+	}
+
+	if h, err := hostName(); err == nil && h != "" {
+		d = append(d, h)
+	}
+	return append(d, ntpServers(ntpConf)...)
+}
+
+// loadGuard maps each protected domain and its parent domains to the
+// protected domain, as blocking a parent also blocks its subdomains
+func (p *Parms) loadGuard() {
+	if p.guard != nil {
+		return
+	}
+
+	p.guard = make(map[string]string)
+	for _, d := range p.protectedList() {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+		for s := d; s != ""; {
+			if _, ok := p.guard[s]; !ok {
+				p.guard[s] = d
+			}
+			i := strings.Index(s, ".")
+			if i < 0 {
+				break
+			}
+			s = s[i+1:]
+		}
+	}
+}
+
+// guards returns the protected domain blocking fqdn would block, if any
+func (p *Parms) guards(fqdn string) (string, bool) {
+	d, ok := p.guard[fqdn]
+	return d, ok
+}
+
+// warn logs s as a warning, whatever the verbosity
+func (p *Parms) warn(s string) {
+	if p.Logger != nil {
+		p.Warning(s)
+	}
+}
+
+// ntpServers returns the server and pool hosts in an ntpd configuration file
+func ntpServers(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var hosts []string
+	b := bufio.NewScanner(f)
+	for b.Scan() {
+		fields := strings.Fields(b.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "server", "pool":
+			if parseIP([]byte(fields[1])) == nil {
+				hosts = append(hosts, fields[1])
+			}
+		}
+	}
+	return hosts
+}
+
+// protectedMsg describes a refused entry for the warning log
+func (o *object) protectedMsg(fqdn, d string) string {
+	if fqdn == d {
+		return fmt.Sprintf("%v: refused to block protected domain %v", o.name, d)
+	}
+	return fmt.Sprintf("%v: refused to block %v, it would also block protected domain %v", o.name, fqdn, d)
+}
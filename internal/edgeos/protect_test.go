@@ -0,0 +1,100 @@
+package edgeos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProtect(t *testing.T) {
+	Convey("Testing the protected domains", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		hostName = func() (string, error) { return "router.lan", nil }
+		ntpConf = dir + "/ntp.conf"
+		defer func() { hostName, ntpConf = os.Hostname, "/etc/ntp.conf" }()
+
+		So(ioutil.WriteFile(ntpConf, []byte("driftfile /var/lib/ntp/ntp.drift\nserver 0.ubnt.pool.ntp.org iburst\nserver 192.0.2.1\npool time.example.net\n"), 0644), ShouldBeNil)
+		So(ntpServers(ntpConf), ShouldResemble, []string{"0.ubnt.pool.ntp.org", "time.example.net"})
+		So(ntpServers(dir+"/missing.conf"), ShouldBeNil)
+
+		Convey("the built-in set, hostname and NTP servers are guarded with their parents", func() {
+			p := &Parms{}
+			p.loadGuard()
+
+			for fqdn, want := range map[string]string{
+				"captive.apple.com":        "captive.apple.com",
+				"apple.com":                "captive.apple.com",
+				"router.lan":               "router.lan",
+				"lan":                      "router.lan",
+				"ubnt.pool.ntp.org":        "0.ubnt.pool.ntp.org",
+				"time.example.net":         "time.example.net",
+				"www.msftncsi.com":         "www.msftncsi.com",
+				"fw-update.ubnt.com":       "fw-update.ubnt.com",
+				"detectportal.firefox.com": "detectportal.firefox.com",
+			} {
+				d, ok := p.guards(fqdn)
+				So(ok, ShouldBeTrue)
+				So(d, ShouldEqual, want)
+			}
+
+			_, ok := p.guards("ads.apple.com")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Protect replaces the built-in set", func() {
+			c := NewConfig(Protect([]string{"Intranet.Example.com."}))
+			c.loadGuard()
+
+			_, ok := c.guards("captive.apple.com")
+			So(ok, ShouldBeFalse)
+			d, ok := c.guards("example.com")
+			So(ok, ShouldBeTrue)
+			So(d, ShouldEqual, "intranet.example.com")
+			_, ok = c.guards("router.lan")
+			So(ok, ShouldBeTrue)
+
+			c.SetOpt(Protect(nil))
+			So(c.guard, ShouldBeNil)
+		})
+
+		Convey("sources and includes can't block protected domains", func() {
+			var (
+				act  = &bytes.Buffer{}
+				b    bytes.Buffer
+				src  = dir + "/feed.txt"
+				cfg  = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude apple.com\n\t\tinclude included.example.com\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+				back = logging.NewBackendFormatter(logging.NewLogBackend(act, "", 0), logging.MustStringFormatter(`%{level:.4s} %{message}`))
+			)
+			logging.SetBackend(back)
+			So(ioutil.WriteFile(src, []byte("ads.example.com\nconnectivitycheck.gstatic.com\ngstatic.com\n"), 0644), ShouldBeNil)
+
+			c := NewConfig(
+				FileNameFmt("%v/%v.%v.%v"),
+				Logger(logging.MustGetLogger("TestProtect")),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				Writer(&b),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			var cts []Contenter
+			for _, iface := range []IFace{PreDObj, FileObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				cts = append(cts, ct)
+			}
+			So(c.ProcessContent(cts...), ShouldBeNil)
+			So(b.String(), ShouldEqual, "address=/included.example.com/0.0.0.0\naddress=/.ads.example.com/0.0.0.0\n")
+			So(act.String(), ShouldContainSubstring, "WARN includes.[2]: refused to block apple.com, it would also block protected domain captive.apple.com\n")
+			So(act.String(), ShouldContainSubstring, "WARN feed: refused to block protected domain connectivitycheck.gstatic.com\n")
+			So(act.String(), ShouldContainSubstring, "WARN feed: refused to block gstatic.com, it would also block protected domain connectivitycheck.gstatic.com\n")
+		})
+	})
+}
@@ -0,0 +1,203 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// pslMarker begins the ICANN section of the publicsuffix.org list, a download
+// without it is taken to be an error page rather than a list
+const pslMarker = "===BEGIN ICANN DOMAINS==="
+
+// builtinSuffixes are common public suffixes with more than one label, used
+// when no PSL file is set; single label suffixes are implied
+var builtinSuffixes = []string{
+	"ac.uk", "co.uk", "gov.uk", "ltd.uk", "me.uk", "net.uk", "org.uk", "plc.uk",
+	"com.au", "edu.au", "gov.au", "net.au", "org.au",
+	"co.jp", "ne.jp", "or.jp",
+	"co.nz", "net.nz", "org.nz",
+	"co.in", "firm.in", "net.in", "org.in",
+	"co.za", "org.za",
+	"com.ar", "com.br", "com.cn", "com.hk", "com.mx", "com.my", "com.sg", "com.tr", "com.tw", "com.ua",
+	"net.br", "net.cn", "org.br", "org.cn",
+	"co.kr", "or.kr",
+	"appspot.com", "azurewebsites.net", "blogspot.com", "cloudfront.net", "github.io",
+	"herokuapp.com", "netlify.app", "pages.dev", "workers.dev",
+	"000webhostapp.com", "ddns.net", "duckdns.org", "firebaseapp.com", "gitlab.io",
+	"no-ip.org", "s3.amazonaws.com", "vercel.app", "web.app",
+}
+
+// builtinPSL is the suffixList of the builtinSuffixes
+var builtinPSL = newSuffixList(builtinSuffixes)
+
+// suffixList holds public suffix rules in the publicsuffix.org list format:
+// rules, *. wildcard rules and ! exceptions
+type suffixList struct {
+	rules map[string]bool
+	wild  map[string]bool
+	exc   map[string]bool
+}
+
+// newSuffixList returns a suffixList of rules, one per line, ignoring
+// comments and blank lines
+func newSuffixList(rules []string) *suffixList {
+	s := &suffixList{rules: make(map[string]bool), wild: make(map[string]bool), exc: make(map[string]bool)}
+	for _, r := range rules {
+		f := strings.Fields(r)
+		if len(f) == 0 {
+			continue
+		}
+
+		switch r = strings.ToLower(f[0]); {
+		case strings.HasPrefix(r, "//"):
+		case strings.HasPrefix(r, "!"):
+			s.exc[r[1:]] = true
+		case strings.HasPrefix(r, "*."):
+			s.wild[r[2:]] = true
+		default:
+			s.rules[r] = true
+		}
+	}
+	return s
+}
+
+// parseSuffixList reads a public suffix list
+func parseSuffixList(r io.Reader, origin string) (*suffixList, error) {
+	var rules []string
+	b := bufio.NewScanner(r)
+	for b.Scan() {
+		rules = append(rules, b.Text())
+	}
+	if err := b.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", origin, err)
+	}
+	return newSuffixList(rules), nil
+}
+
+// readSuffixList reads a public suffix list file
+func readSuffixList(file string) (*suffixList, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseSuffixList(f, file)
+}
+
+// suffixLen returns how many of host's labels form its public suffix, the
+// longest matching rule wins, exceptions are one label shorter than their rule
+// and a host matching no rule has a single label suffix
+func (s *suffixList) suffixLen(labels []string) int {
+	n := 1
+	for i := len(labels) - 1; i >= 0; i-- {
+		d := strings.Join(labels[i:], ".")
+		switch {
+		case s.exc[d]:
+			return len(labels) - i - 1
+		case s.rules[d]:
+			n = len(labels) - i
+		case i > 0 && s.wild[d]:
+			n = len(labels) - i + 1
+		}
+	}
+	return n
+}
+
+// registrable returns host's registrable domain, its public suffix and one
+// more label, or host if it is a public suffix itself
+func (s *suffixList) registrable(host string) string {
+	labels := strings.Split(host, ".")
+	if n := s.suffixLen(labels) + 1; n < len(labels) {
+		return strings.Join(labels[len(labels)-n:], ".")
+	}
+	return host
+}
+
+// isSuffix reports whether host is a public suffix, so blocking it would
+// block every domain registered under it
+func (s *suffixList) isSuffix(host string) bool {
+	labels := strings.Split(host, ".")
+	return s.suffixLen(labels) >= len(labels)
+}
+
+// fetchSuffixes downloads the public suffix list from PSLURL and saves it to
+// the PSL file, if set
+func (p *Parms) fetchSuffixes() (*suffixList, error) {
+	o := getHTTP(&object{Parms: p, name: "public suffix list", url: p.PSLURL})
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	b, err := ioutil.ReadAll(o.r)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", p.PSLURL, err)
+	}
+	if !bytes.Contains(b, []byte(pslMarker)) {
+		return nil, fmt.Errorf("%v: missing %q line", p.PSLURL, pslMarker)
+	}
+
+	s, err := parseSuffixList(bytes.NewReader(b), p.PSLURL)
+	if err != nil || p.PSL == "" {
+		return s, err
+	}
+
+	tmp := p.PSL + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return nil, err
+	}
+	return s, os.Rename(tmp, p.PSL)
+}
+
+// loadSuffixes downloads the public suffix list from PSLURL, if set, or reads
+// the PSL file, falling back to the file's last saved copy when the download
+// fails
+func (p *Parms) loadSuffixes() error {
+	if p.psl != nil {
+		return nil
+	}
+
+	if p.PSLURL != "" {
+		s, err := p.fetchSuffixes()
+		if err == nil {
+			p.psl = s
+			return nil
+		}
+		p.warn(fmt.Sprintf("Unable to download the public suffix list: %v", err))
+	}
+
+	if p.PSL == "" {
+		return nil
+	}
+
+	s, err := readSuffixList(p.PSL)
+	switch {
+	case err == nil:
+		p.psl = s
+	case p.PSLURL != "" && os.IsNotExist(err):
+		// nothing has been saved yet, the built-in list will do
+	default:
+		return err
+	}
+	return nil
+}
+
+// suffixes returns the PSL file's suffixList, or the built-in one
+func (p *Parms) suffixes() *suffixList {
+	if p.psl != nil {
+		return p.psl
+	}
+	return builtinPSL
+}
+
+// suffixMsg describes an entry that is a public suffix for the warning log
+func (o *object) suffixMsg(fqdn string) string {
+	if o.Refuse {
+		return fmt.Sprintf("%v: refused to block public suffix %v", o.name, fqdn)
+	}
+	return fmt.Sprintf("%v: %v is a public suffix, blocking it blocks every domain under it", o.name, fqdn)
+}
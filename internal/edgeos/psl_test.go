@@ -0,0 +1,124 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPSL(t *testing.T) {
+	Convey("Testing the public suffix list", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		psl := "// ===BEGIN ICANN DOMAINS===\ncom\nuk\n*.ck\n!www.ck\n\n// private\nexample.com\n"
+
+		Convey("a PSL file replaces the built-in suffixes", func() {
+			file := dir + "/psl.dat"
+			So(ioutil.WriteFile(file, []byte(psl), 0644), ShouldBeNil)
+
+			c := NewConfig(PSL(file))
+			So(c.loadSuffixes(), ShouldBeNil)
+
+			s := c.suffixes()
+			So(s.registrable("a.b.example.com"), ShouldEqual, "b.example.com")
+			So(s.registrable("a.b.example.co.uk"), ShouldEqual, "co.uk")
+			So(s.registrable("a.b.ck"), ShouldEqual, "a.b.ck")
+			So(s.registrable("a.www.ck"), ShouldEqual, "www.ck")
+			So(s.registrable("com"), ShouldEqual, "com")
+
+			So(NewConfig(PSL("/nonexistent/psl.dat")).loadSuffixes(), ShouldNotBeNil)
+		})
+
+		Convey("public suffixes are detected", func() {
+			tests := []struct {
+				host string
+				exp  bool
+			}{
+				{host: "co.uk", exp: true},
+				{host: "github.io", exp: true},
+				{host: "com", exp: true},
+				{host: "example.co.uk", exp: false},
+				{host: "evil.github.io", exp: false},
+				{host: "example.com", exp: false},
+			}
+
+			for _, tt := range tests {
+				So(builtinPSL.isSuffix(tt.host), ShouldEqual, tt.exp)
+			}
+		})
+
+		Convey("the list is downloaded and saved for when it can't be", func() {
+			body := psl
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, body)
+			}))
+			defer srv.Close()
+
+			file := dir + "/cache.dat"
+			c := NewConfig(Method("GET"), PSL(file), PSLURL(srv.URL))
+			So(c.loadSuffixes(), ShouldBeNil)
+			So(c.suffixes().isSuffix("example.com"), ShouldBeTrue)
+
+			b, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, psl)
+
+			Convey("an error page is ignored in favour of the saved copy", func() {
+				body = "<html>Not Found</html>"
+				c := NewConfig(Method("GET"), PSL(file), PSLURL(srv.URL))
+				So(c.loadSuffixes(), ShouldBeNil)
+				So(c.suffixes().isSuffix("example.com"), ShouldBeTrue)
+			})
+
+			Convey("the built-in list is used until a copy has been saved", func() {
+				c := NewConfig(Method("GET"), PSL(dir+"/missing.dat"), PSLURL("http://127.0.0.1:1/psl.dat"))
+				So(c.loadSuffixes(), ShouldBeNil)
+				So(c.suffixes(), ShouldEqual, builtinPSL)
+			})
+		})
+
+		Convey("sources that list public suffixes are warned about or refused", func() {
+			var (
+				act  = &bytes.Buffer{}
+				src  = dir + "/feed.txt"
+				cfg  = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+				back = logging.NewBackendFormatter(logging.NewLogBackend(act, "", 0), logging.MustStringFormatter(`%{level:.4s} %{message}`))
+			)
+			logging.SetBackend(back)
+			So(ioutil.WriteFile(src, []byte("ads.example.com\nco.uk\ngithub.io\n"), 0644), ShouldBeNil)
+
+			run := func(refuse bool) string {
+				var b bytes.Buffer
+				c := NewConfig(
+					FileNameFmt("%v/%v.%v.%v"),
+					Logger(logging.MustGetLogger("TestPSL")),
+					Nodes([]string{domains}),
+					Prefix("address="),
+					RefuseSuffixes(refuse),
+					Writer(&b),
+				)
+				So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+				ct, err := c.NewContent(FileObj)
+				So(err, ShouldBeNil)
+				So(c.ProcessContent(ct), ShouldBeNil)
+				return b.String()
+			}
+
+			So(run(false), ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/.co.uk/0.0.0.0\naddress=/.github.io/0.0.0.0\n")
+			So(act.String(), ShouldContainSubstring, "WARN feed: co.uk is a public suffix, blocking it blocks every domain under it\n")
+
+			So(run(true), ShouldEqual, "address=/.ads.example.com/0.0.0.0\n")
+			So(act.String(), ShouldContainSubstring, "WARN feed: refused to block public suffix github.io\n")
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package edgeos
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+const pushPath = "/config"
+
+var (
+	// ErrPushDisabled is returned when a configuration is pushed without a PushKey
+	ErrPushDisabled = errors.New("configuration push isn't enabled")
+
+	// ErrPushSignature is returned when a pushed configuration's signature is invalid
+	ErrPushSignature = errors.New("invalid configuration signature")
+
+	// pushMu serializes configuration pushes
+	pushMu sync.Mutex
+)
+
+// PushDoc is a blacklist configuration document pushed by a central
+// controller, Config uses the EdgeOS configuration syntax and Serial must
+// increase with each push
+type PushDoc struct {
+	Serial    int64  `json:"serial"`
+	Config    string `json:"config"`
+	Signature []byte `json:"signature"`
+}
+
+// message returns the signed content
+func (d *PushDoc) message() []byte {
+	return []byte(fmt.Sprintf("%d\n%s", d.Serial, d.Config))
+}
+
+// Sign signs the document with a controller's private key
+func (d *PushDoc) Sign(key ed25519.PrivateKey) {
+	d.Signature = ed25519.Sign(key, d.message())
+}
+
+// Push verifies a pushed document and replaces the configuration with it,
+// bypassing the EdgeOS configuration
+func (c *Config) Push(d *PushDoc) error {
+	return c.accept(d, "")
+}
+
+// saveError is returned when accept can't save a pushed document
+type saveError struct{ error }
+
+// accept is Push, saving d to file first if there is one, so a document is
+// only applied once it survives restarts
+func (c *Config) accept(d *PushDoc, file string) error {
+	pushMu.Lock()
+	defer pushMu.Unlock()
+
+	switch {
+	case c.PushKey == nil:
+		return ErrPushDisabled
+	case !ed25519.Verify(c.PushKey, d.message(), d.Signature):
+		return ErrPushSignature
+	case d.Serial <= c.serial:
+		return fmt.Errorf("stale configuration serial %d, already at %d", d.Serial, c.serial)
+	}
+
+	p := *c.Parms
+	n := &Config{Parms: &p, tree: make(tree)}
+	if err := n.readCfg(&CFGstatic{Cfg: d.Config}); err != nil {
+		return err
+	}
+
+	if file != "" {
+		if err := savePush(d, file); err != nil {
+			return saveError{err}
+		}
+	}
+
+	n.serial = d.Serial
+	c.swap(n)
+	return nil
+}
+
+// LoadPush applies the last pushed document saved in file
+func (c *Config) LoadPush(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	d := &PushDoc{}
+	if err = json.Unmarshal(b, d); err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+	return c.Push(d)
+}
+
+// savePush saves an applied document to file, so it survives restarts
+func savePush(d *PushDoc, file string) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// push accepts a signed configuration document, saves it to PushFile and
+// runs OnPush to apply it, all while holding Lock
+func (a *StatusAPI) push(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d := &PushDoc{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.Lock.Lock()
+	defer a.Lock.Unlock()
+
+	switch err := a.accept(d, a.PushFile); err.(type) {
+	case nil:
+	case saveError:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	default:
+		code := http.StatusConflict
+		if err == ErrPushSignature {
+			code = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	if a.OnPush != nil {
+		if err := a.OnPush(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
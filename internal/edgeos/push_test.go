@@ -0,0 +1,111 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPush(t *testing.T) {
+	Convey("Testing configuration push", t, func() {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+		_, other, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+
+		doc := func(serial int64, cfg string, key ed25519.PrivateKey) *PushDoc {
+			d := &PushDoc{Serial: serial, Config: cfg}
+			d.Sign(key)
+			return d
+		}
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude apple.com\n\tdomains {\n\t\tinclude pushed.example.com\n\t}\n}"
+		c := NewConfig(Nodes([]string{domains, hosts}), PushKey(pub))
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tinclude local.example.com\n\t}\n}"}), ShouldBeNil)
+		c.Exc.set("stale.example.com", 0)
+
+		tests := []struct {
+			name string
+			doc  *PushDoc
+			err  error
+		}{
+			{name: "wrong key", doc: doc(1, cfg, other), err: ErrPushSignature},
+			{name: "tampered", doc: func() *PushDoc { d := doc(1, cfg, priv); d.Serial = 2; return d }(), err: ErrPushSignature},
+			{name: "unparsable", doc: doc(1, "blacklist {\n\tsource x {\n\t}\n}", priv), err: errors.New(`config.boot:3: source "x" missing url/file`)},
+			{name: "valid", doc: doc(1, cfg, priv)},
+			{name: "replayed", doc: doc(1, cfg, priv), err: errors.New("stale configuration serial 1, already at 1")},
+		}
+
+		for _, tt := range tests {
+			err := c.Push(tt.doc)
+			switch tt.err {
+			case nil:
+				So(err, ShouldBeNil)
+			default:
+				So(err.Error(), ShouldEqual, tt.err.Error())
+			}
+		}
+
+		So(c.FWIncludes(), ShouldResemble, []string{"pushed.example.com"})
+		So(c.tree[rootNode].exc, ShouldResemble, []string{"apple.com"})
+		So(c.Exc.keyExists("stale.example.com"), ShouldBeTrue)
+
+		So(NewConfig().Push(doc(1, cfg, priv)), ShouldEqual, ErrPushDisabled)
+
+		Convey("Testing the push API", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			applied := 0
+			a := c.NewStatusAPI()
+			a.PushFile = dir + "/push.json"
+			a.OnPush = func() error { applied++; return nil }
+			srv := httptest.NewServer(a)
+			defer srv.Close()
+
+			post := func(d *PushDoc) int {
+				b, _ := json.Marshal(d)
+				resp, err := http.Post(srv.URL+pushPath, "application/json", bytes.NewReader(b))
+				So(err, ShouldBeNil)
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			So(post(doc(2, cfg, other)), ShouldEqual, http.StatusForbidden)
+			So(post(doc(1, cfg, priv)), ShouldEqual, http.StatusConflict)
+			So(post(doc(2, cfg, priv)), ShouldEqual, http.StatusNoContent)
+			So(applied, ShouldEqual, 1)
+
+			a.PushFile = dir + "/missing/push.json"
+			So(post(doc(3, cfg, priv)), ShouldEqual, http.StatusInternalServerError)
+			So(applied, ShouldEqual, 1)
+			So(c.serial, ShouldEqual, 2)
+			a.PushFile = dir + "/push.json"
+
+			resp, err := http.Get(srv.URL + pushPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusMethodNotAllowed)
+
+			n := NewConfig(Nodes([]string{domains, hosts}), PushKey(pub))
+			So(n.LoadPush(a.PushFile), ShouldBeNil)
+			So(n.serial, ShouldEqual, 2)
+			So(n.FWIncludes(), ShouldResemble, []string{"pushed.example.com"})
+
+			off := httptest.NewServer(NewConfig().NewStatusAPI())
+			defer off.Close()
+
+			resp, err = http.Post(off.URL+pushPath, "application/json", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
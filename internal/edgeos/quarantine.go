@@ -0,0 +1,124 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// seenTTL is how long a domain no source lists any more is remembered, if it
+// reappears after that it is quarantined again
+const seenTTL = 30 * 24 * time.Hour
+
+// ErrNoSeenFile is returned if Quarantine is set without a SeenFile
+var ErrNoSeenFile = errors.New("quarantine needs a seen file to remember when domains were first listed")
+
+// seenNow returns the current time, it is a variable so tests can move it
+var seenNow = time.Now
+
+// seenDB records when each domain was first and last listed by a source, as
+// Unix times; while it is seeded the domains it records are already past
+// their quarantine, so a first run doesn't hold every domain
+type seenDB struct {
+	sync.Mutex
+	file   string
+	domain map[string][2]int64
+	seed   bool
+}
+
+// loadSeen reads the seen file, without one the seen DB is seeded from this
+// run's domains and only domains listed later are quarantined
+func (p *Parms) loadSeen() error {
+	if p.Hold <= 0 || p.seen != nil {
+		return nil
+	}
+	if p.Seen == "" {
+		return ErrNoSeenFile
+	}
+
+	s := &seenDB{file: p.Seen, domain: make(map[string][2]int64)}
+	b, err := ioutil.ReadFile(p.Seen)
+	switch {
+	case os.IsNotExist(err):
+		s.seed = true
+	case err != nil:
+		return err
+	default:
+		if err = json.Unmarshal(b, &s.domain); err != nil {
+			return err
+		}
+	}
+
+	p.seen = s
+	return nil
+}
+
+// hold is true if domain was first listed less than Hold ago, it records
+// domain as listed now
+func (p *Parms) hold(domain string) bool {
+	if p.seen == nil {
+		return false
+	}
+
+	var (
+		hold = int64(p.Hold / time.Second)
+		now  = seenNow().Unix()
+	)
+
+	p.seen.Lock()
+	defer p.seen.Unlock()
+
+	t, ok := p.seen.domain[domain]
+	switch {
+	case ok:
+	case p.seen.seed:
+		// a seeded domain is already past its quarantine
+		t[0] = now - hold
+	default:
+		t[0] = now
+	}
+	t[1] = now
+	p.seen.domain[domain] = t
+	return now-t[0] < hold
+}
+
+// save writes the seen file, forgetting the domains no source has listed for
+// seenTTL
+func (s *seenDB) save() error {
+	if s == nil {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	old := seenNow().Add(-seenTTL).Unix()
+	for d, t := range s.domain {
+		if t[1] < old {
+			delete(s.domain, d)
+		}
+	}
+
+	b, err := json.Marshal(s.domain)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.file); err != nil {
+		return err
+	}
+	s.seed = false
+	return nil
+}
+
+// quarantines is true for the fetched sources whose new domains are held back
+func (o *object) quarantines() bool {
+	return o.seen != nil && !o.nType.isExc() && (o.ltype == files || o.ltype == urls)
+}
@@ -0,0 +1,91 @@
+package edgeos
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestQuarantine(t *testing.T) {
+	Convey("Testing ProcessContent() with a quarantine", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		now := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+		seenNow = func() time.Time { return now }
+		defer func() { seenNow = time.Now }()
+
+		var (
+			src  = dir + "/feed.txt"
+			seen = dir + "/seen.json"
+			cfg  = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude included.example.com\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+		)
+
+		run := func(data string) (string, *Status) {
+			So(ioutil.WriteFile(src, []byte(data), 0644), ShouldBeNil)
+
+			var b bytes.Buffer
+			c := NewConfig(
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				Quarantine(24*time.Hour),
+				SeenFile(seen),
+				Stats(NewStatus("")),
+				Writer(&b),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			var cts []Contenter
+			for _, iface := range []IFace{PreDObj, FileObj} {
+				ct, err := c.NewContent(iface)
+				So(err, ShouldBeNil)
+				cts = append(cts, ct)
+			}
+			So(c.ProcessContent(cts...), ShouldBeNil)
+			return b.String(), c.Status
+		}
+
+		out, st := run("ads.example.com\ntracker.example.com\n")
+		So(out, ShouldEqual, "address=/included.example.com/0.0.0.0\naddress=/.ads.example.com/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n")
+		So(st.Sources[1].Held, ShouldEqual, 0)
+
+		now = now.Add(23 * time.Hour)
+		out, st = run("ads.example.com\nnew.example.com\n")
+		So(out, ShouldEqual, "address=/included.example.com/0.0.0.0\naddress=/.ads.example.com/0.0.0.0\n")
+		So(st.Sources[1].Held, ShouldEqual, 1)
+
+		now = now.Add(23 * time.Hour)
+		out, st = run("ads.example.com\nnew.example.com\n")
+		So(out, ShouldEqual, "address=/included.example.com/0.0.0.0\naddress=/.ads.example.com/0.0.0.0\n")
+		So(st.Sources[1].Held, ShouldEqual, 1)
+
+		now = now.Add(time.Hour)
+		out, st = run("ads.example.com\nnew.example.com\n")
+		So(out, ShouldEqual, "address=/included.example.com/0.0.0.0\naddress=/.ads.example.com/0.0.0.0\naddress=/.new.example.com/0.0.0.0\n")
+		So(st.Sources[1].Held, ShouldEqual, 0)
+
+		Convey("domains no source lists for a while are forgotten", func() {
+			now = now.Add(seenTTL + time.Hour)
+			_, st = run("ads.example.com\n")
+			So(st.Sources[1].Held, ShouldEqual, 0)
+
+			b, err := ioutil.ReadFile(seen)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldNotContainSubstring, "tracker.example.com")
+		})
+
+		Convey("a quarantine needs a seen file", func() {
+			c := NewConfig(Nodes([]string{domains}), Quarantine(time.Hour))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldEqual, ErrNoSeenFile)
+		})
+	})
+}
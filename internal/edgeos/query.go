@@ -0,0 +1,78 @@
+package edgeos
+
+// node returns the name of the node o is configured in, includes and
+// excludes belong to their kind's node
+func (o *object) node() string {
+	if k := kindOf(o.nType); k != nil {
+		return k.Name
+	}
+	return getType(o.nType).(string)
+}
+
+// where returns the Objects keep is true for
+func (o *Objects) where(keep func(*object) bool) *Objects {
+	objects := &Objects{Parms: o.Parms, x: []*object{}}
+	for _, obj := range o.x {
+		if keep(obj) {
+			objects.x = append(objects.x, obj)
+		}
+	}
+	return objects
+}
+
+// ByNode returns the Objects of the named nodes, e.g. domains or hosts
+func (o *Objects) ByNode(nodes ...string) *Objects {
+	return o.where(func(obj *object) bool { return contains(nodes, obj.node()) })
+}
+
+// ByLType returns the Objects of the named leaf types, e.g. url or file
+func (o *Objects) ByLType(ltypes ...string) *Objects {
+	return o.where(func(obj *object) bool { return contains(ltypes, obj.ltype) })
+}
+
+// ByName returns the Objects with any of names
+func (o *Objects) ByName(names ...string) *Objects {
+	return o.where(func(obj *object) bool { return contains(names, obj.name) })
+}
+
+// WithURLs returns the Objects fetched from a URL
+func (o *Objects) WithURLs() *Objects {
+	return o.where(func(obj *object) bool { return obj.url != "" })
+}
+
+// Enabled returns the Objects that are neither disabled nor auto-disabled
+// after repeated failed fetches
+func (o *Objects) Enabled() *Objects {
+	var fails *failDB
+	if o.Parms != nil {
+		fails = o.fails
+	}
+	return o.where(func(obj *object) bool { return !obj.disabled && !fails.disabled(obj.key()) })
+}
+
+// Sources describes each of the Objects
+func (o *Objects) Sources() []*Source {
+	s := make([]*Source, 0, len(o.x))
+	for _, obj := range o.x {
+		s = append(s, &Source{
+			Desc:   obj.desc,
+			File:   obj.file,
+			IP:     obj.ip,
+			Name:   obj.name,
+			Node:   obj.node(),
+			Prefix: obj.prefix,
+			URL:    obj.url,
+		})
+	}
+	return s
+}
+
+// contains is true if s is one of list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
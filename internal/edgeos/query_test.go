@@ -0,0 +1,33 @@
+package edgeos
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestObjectsQuery(t *testing.T) {
+	Convey("Testing Objects queries", t, func() {
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tinclude big.example.com\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\turl https://example.com/feed.txt\n\t\t}\n\t\tsource local {\n\t\t\tfile /tmp/local.txt\n\t\t}\n\t}\n\thosts {\n\t\tsource feed {\n\t\t\tdescription \"Hosts feed\"\n\t\t\tprefix 0.0.0.0\n\t\t\turl https://example.com/hosts\n\t\t}\n\t}\n}"
+		c := NewConfig(Nodes([]string{domains, hosts}))
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+		all := c.GetAll()
+
+		So(all.ByNode(domains).Names(), ShouldResemble, sort.StringSlice{"feed", "includes.[1]", "local"})
+		So(all.ByNode(hosts).Names(), ShouldResemble, sort.StringSlice{"feed"})
+		So(all.ByLType(files, urls).Names(), ShouldResemble, sort.StringSlice{"feed", "feed", "local"})
+		So(all.ByName("feed").WithURLs().Len(), ShouldEqual, 2)
+		So(all.ByNode(domains).ByName("feed", "local").ByLType(files).Names(), ShouldResemble, sort.StringSlice{"local"})
+		So(all.ByName("missing").Len(), ShouldEqual, 0)
+
+		s := all.ByNode(hosts).Sources()
+		So(s, ShouldResemble, []*Source{{Desc: "Hosts feed", IP: "0.0.0.0", Name: "feed", Node: hosts, Prefix: "0.0.0.0", URL: "https://example.com/hosts"}})
+
+		Convey("Enabled skips disabled and auto-disabled sources", func() {
+			all.ByNode(domains).ByName("local").x[0].disabled = true
+			c.fails = &failDB{source: map[string]failures{"hosts/feed": {Count: 3, Disabled: true}}}
+			So(c.GetAll(files, urls).Enabled().Sources(), ShouldResemble, []*Source{{Name: "feed", Node: domains, URL: "https://example.com/feed.txt", IP: "0.0.0.0"}})
+		})
+	})
+}
@@ -0,0 +1,189 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// syslogTime is the timestamp format of dnsmasq's syslog and log-facility lines
+const syslogTime = "Jan _2 15:04:05"
+
+// QueryStats summarises the queries in dnsmasq's --log-queries output over a
+// time window
+type QueryStats struct {
+	Since   time.Time
+	Until   time.Time
+	Queries int
+	Blocked int
+	Domains map[string]int
+	Clients map[string]int
+	pending map[string]string
+}
+
+// Count is a name and its number of blocked queries
+type Count struct {
+	Name string
+	N    int
+}
+
+// NewQueryStats returns a *QueryStats counting queries logged in the window
+// before now
+func NewQueryStats(now time.Time, window time.Duration) *QueryStats {
+	return &QueryStats{
+		Since:   now.Add(-window),
+		Until:   now,
+		Domains: make(map[string]int),
+		Clients: make(map[string]int),
+		pending: make(map[string]string),
+	}
+}
+
+// logTime parses a syslog timestamp, which has no year, as the latest time
+// that isn't after Until
+func (q *QueryStats) logTime(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation(syslogTime, s, q.Until.Location())
+	if err != nil {
+		return t, false
+	}
+
+	t = t.AddDate(q.Until.Year(), 0, 0)
+	if t.After(q.Until.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
+
+// Add parses a dnsmasq log line, lines that aren't dnsmasq queries or replies,
+// or are outside the window, are ignored
+func (q *QueryStats) Add(line string) {
+	if len(line) < len(syslogTime) {
+		return
+	}
+
+	t, ok := q.logTime(line[:len(syslogTime)])
+	if !ok || t.Before(q.Since) || t.After(q.Until) {
+		return
+	}
+
+	i := strings.Index(line, "dnsmasq[")
+	if i < 0 {
+		return
+	}
+	j := strings.Index(line[i:], "]: ")
+	if j < 0 {
+		return
+	}
+
+	f := strings.Fields(line[i+j+3:])
+
+	// log-queries=extra prefixes each line with a serial number and the
+	// client's address/port, which ties a reply to its query
+	serial := ""
+	if len(f) > 2 && strings.Contains(f[1], "/") && isDigits(f[0]) {
+		serial, f = f[0], f[2:]
+	}
+
+	switch {
+	case len(f) >= 4 && strings.HasPrefix(f[0], "query[") && f[2] == "from":
+		q.Queries++
+		q.pending[serial+f[1]] = f[3]
+
+	case len(f) >= 4 && f[0] == "config" && f[2] == "is":
+		key := serial + f[1]
+		client, ok := q.pending[key]
+		if !ok {
+			return
+		}
+		delete(q.pending, key)
+
+		q.Blocked++
+		q.Domains[f[1]]++
+		q.Clients[client]++
+
+	case len(f) >= 2 && (f[0] == "reply" || f[0] == "cached" || f[0] == "forwarded"):
+		delete(q.pending, serial+f[1])
+	}
+}
+
+// isDigits returns true if s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ReadFrom adds each line read from r
+func (q *QueryStats) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	b := bufio.NewScanner(r)
+	b.Buffer(make([]byte, 64*1024), 1024*1024)
+	for b.Scan() {
+		n += int64(len(b.Bytes()) + 1)
+		q.Add(b.Text())
+	}
+	return n, b.Err()
+}
+
+// top returns the n highest counts in m, ties are sorted by name
+func top(m map[string]int, n int) []Count {
+	c := make([]Count, 0, len(m))
+	for k, v := range m {
+		c = append(c, Count{Name: k, N: v})
+	}
+
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].N != c[j].N {
+			return c[i].N > c[j].N
+		}
+		return c[i].Name < c[j].Name
+	})
+
+	if n > 0 && len(c) > n {
+		c = c[:n]
+	}
+	return c
+}
+
+// TopDomains returns the n most blocked domains
+func (q *QueryStats) TopDomains(n int) []Count { return top(q.Domains, n) }
+
+// TopClients returns the n clients with the most blocked queries
+func (q *QueryStats) TopClients(n int) []Count { return top(q.Clients, n) }
+
+// Report writes a summary with the n top blocked domains and clients to w
+func (q *QueryStats) Report(w io.Writer, n int) error {
+	pct := 0.0
+	if q.Queries > 0 {
+		pct = float64(q.Blocked) * 100 / float64(q.Queries)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Queries: %d from %v to %v\n", q.Queries, q.Since.Format(time.RFC3339), q.Until.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Blocked: %d (%.1f%%)\n", q.Blocked, pct)
+
+	for _, s := range []struct {
+		title string
+		top   []Count
+	}{
+		{title: "Top blocked domains", top: q.TopDomains(n)},
+		{title: "Top blocked clients", top: q.TopClients(n)},
+	} {
+		if len(s.top) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", s.title)
+		for _, c := range s.top {
+			fmt.Fprintf(&b, "%8d  %s\n", c.N, c.Name)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,77 @@
+package edgeos
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const queryLog = `Oct 15 09:00:00 router dnsmasq[812]: query[A] ads.example.com from 192.168.1.10
+Oct 15 09:00:00 router dnsmasq[812]: config ads.example.com is 0.0.0.0
+Oct 16 09:00:00 router dnsmasq[812]: query[A] ads.example.com from 192.168.1.10
+Oct 16 09:00:00 router dnsmasq[812]: config ads.example.com is 0.0.0.0
+Oct 16 09:00:01 router dnsmasq[812]: query[AAAA] ads.example.com from 192.168.1.10
+Oct 16 09:00:01 router dnsmasq[812]: config ads.example.com is ::
+Oct 16 09:00:02 router dnsmasq[812]: query[A] www.example.org from 192.168.1.11
+Oct 16 09:00:02 router dnsmasq[812]: forwarded www.example.org to 9.9.9.9
+Oct 16 09:00:02 router dnsmasq[812]: reply www.example.org is 93.184.216.34
+Oct 16 09:00:03 router dnsmasq[812]: 7 192.168.1.11/53431 query[A] track.example.net from 192.168.1.11
+Oct 16 09:00:03 router dnsmasq[812]: 8 192.168.1.10/40112 query[A] track.example.net from 192.168.1.10
+Oct 16 09:00:03 router dnsmasq[812]: 7 192.168.1.11/53431 config track.example.net is 0.0.0.0
+Oct 16 09:00:04 router dnsmasq[812]: query[A] www.example.org from 192.168.1.11
+Oct 16 09:00:04 router dnsmasq[812]: cached www.example.org is 93.184.216.34
+Oct 16 09:00:05 router kernel: [UFW BLOCK] IN=eth0
+Oct 16 09:00:05 router dnsmasq[812]: config orphan.example.com is 0.0.0.0
+Oct 16 11:00:00 router dnsmasq[812]: query[A] ads.example.com from 192.168.1.10
+Oct 16 11:00:00 router dnsmasq[812]: config ads.example.com is 0.0.0.0
+garbage
+`
+
+func TestQueryStats(t *testing.T) {
+	Convey("Testing QueryStats", t, func() {
+		now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+		q := NewQueryStats(now, 12*time.Hour)
+
+		_, err := q.ReadFrom(strings.NewReader(queryLog))
+		So(err, ShouldBeNil)
+
+		So(q.Queries, ShouldEqual, 6)
+		So(q.Blocked, ShouldEqual, 3)
+		So(q.TopDomains(0), ShouldResemble, []Count{{Name: "ads.example.com", N: 2}, {Name: "track.example.net", N: 1}})
+		So(q.TopClients(1), ShouldResemble, []Count{{Name: "192.168.1.10", N: 2}})
+		So(q.pending, ShouldResemble, map[string]string{"8track.example.net": "192.168.1.10"})
+
+		act := new(bytes.Buffer)
+		So(q.Report(act, 5), ShouldBeNil)
+		So(act.String(), ShouldEqual, `Queries: 6 from 2026-10-15T22:00:00Z to 2026-10-16T10:00:00Z
+Blocked: 3 (50.0%)
+
+Top blocked domains:
+       2  ads.example.com
+       1  track.example.net
+
+Top blocked clients:
+       2  192.168.1.10
+       1  192.168.1.11
+`)
+
+		Convey("timestamps without a year", func() {
+			q := NewQueryStats(time.Date(2027, 1, 1, 0, 30, 0, 0, time.UTC), 24*time.Hour)
+			t, ok := q.logTime("Dec 31 23:59:59")
+			So(ok, ShouldBeTrue)
+			So(t, ShouldResemble, time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC))
+
+			_, ok = q.logTime("garbage tim")
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("an empty log", func() {
+			act := new(bytes.Buffer)
+			So(NewQueryStats(now, time.Hour).Report(act, 5), ShouldBeNil)
+			So(act.String(), ShouldEqual, "Queries: 0 from 2026-10-16T09:00:00Z to 2026-10-16T10:00:00Z\nBlocked: 0 (0.0%)\n")
+		})
+	})
+}
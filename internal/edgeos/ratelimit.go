@@ -0,0 +1,97 @@
+package edgeos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateChunk is the most read at once from a rate limited download, so its
+// bandwidth is spread evenly rather than taken in bursts
+const rateChunk = 16 << 10
+
+// ParseRate parses a bandwidth in bytes per second, a size with an optional
+// "/s" suffix, e.g. 2M or 512K/s
+func ParseRate(s string) (int64, error) {
+	n, err := ParseSize(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return n, nil
+}
+
+// limiter paces readers sharing it to rate bytes per second
+type limiter struct {
+	sync.Mutex
+	rate int64
+	next time.Time
+}
+
+// newLimiter returns a limiter for rate bytes per second, or nil if rate is 0
+func newLimiter(rate int64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{rate: rate}
+}
+
+// wait blocks until n more bytes are within the limiter's rate, or ctx ends
+func (l *limiter) wait(ctx context.Context, n int) error {
+	l.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	d := l.next.Sub(now)
+	l.Unlock()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateReader reads from r no faster than each of its limiters allows
+type rateReader struct {
+	ctx      context.Context
+	limiters []*limiter
+	r        io.Reader
+}
+
+func (r *rateReader) Read(b []byte) (int, error) {
+	if len(b) > rateChunk {
+		b = b[:rateChunk]
+	}
+
+	n, err := r.r.Read(b)
+	for _, l := range r.limiters {
+		if werr := l.wait(r.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// rateLimited returns body paced by the source's rate-limit and the global
+// RateLimit, which all downloads share
+func (o *object) rateLimited(body io.Reader) io.Reader {
+	var limiters []*limiter
+	for _, l := range []*limiter{newLimiter(o.rate), o.limit} {
+		if l != nil {
+			limiters = append(limiters, l)
+		}
+	}
+
+	if limiters == nil {
+		return body
+	}
+	return &rateReader{ctx: o.context(), limiters: limiters, r: body}
+}
@@ -0,0 +1,79 @@
+package edgeos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRateLimit(t *testing.T) {
+	Convey("Testing download rate limits", t, func() {
+		Convey("rates are sizes per second", func() {
+			tests := []struct {
+				in  string
+				exp int64
+				err bool
+			}{
+				{in: "2M", exp: 2 << 20},
+				{in: "512K/s", exp: 512 << 10},
+				{in: "100", exp: 100},
+				{in: "fast", err: true},
+			}
+
+			for _, tt := range tests {
+				n, err := ParseRate(tt.in)
+				So(err != nil, ShouldEqual, tt.err)
+				So(n, ShouldEqual, tt.exp)
+			}
+		})
+
+		Convey("downloads are paced by the source's and the global rate", func() {
+			body := bytes.Repeat([]byte("ads.example.com\n"), 4<<10)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(body)
+			}))
+			defer srv.Close()
+
+			fetch := func(c *Config, rate int64) time.Duration {
+				start := time.Now()
+				o := getHTTP(&object{Parms: c.Parms, name: "feed", rate: rate, url: srv.URL})
+				So(o.err, ShouldBeNil)
+
+				b, err := ioutil.ReadAll(o.r)
+				So(err, ShouldBeNil)
+				So(b, ShouldResemble, body)
+				return time.Since(start)
+			}
+
+			// 64K at 128K/s takes about half a second
+			So(fetch(NewConfig(Method("GET"), RateLimit(128<<10)), 0), ShouldBeGreaterThanOrEqualTo, 400*time.Millisecond)
+			So(fetch(NewConfig(Method("GET")), 128<<10), ShouldBeGreaterThanOrEqualTo, 400*time.Millisecond)
+			So(fetch(NewConfig(Method("GET")), 0), ShouldBeLessThan, 400*time.Millisecond)
+		})
+
+		Convey("a paced download stops at the deadline", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			So(newLimiter(1).wait(ctx, 1<<10), ShouldEqual, context.Canceled)
+			So(newLimiter(0), ShouldBeNil)
+		})
+
+		Convey("sources can have a rate-limit", func() {
+			cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\turl https://example.com/feed.txt\n\t\t\trate-limit %v\n\t\t}\n\t}\n}"
+
+			c := NewConfig()
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "512K")}), ShouldBeNil)
+			So(c.Get(domains).x[0].rate, ShouldEqual, 512<<10)
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "fast")})
+			So(err.Error(), ShouldEqual, `config.boot:6: source "feed" has invalid rate "fast"`)
+		})
+	})
+}
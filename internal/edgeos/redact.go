@@ -0,0 +1,126 @@
+package edgeos
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// redactedPass replaces a URL's password, as url.URL.Redacted does
+	redactedPass = "xxxxx"
+	// redactedValue replaces the value of a redacted query parameter
+	redactedValue = "REDACTED"
+)
+
+// redactParams are the query parameters whose values are redacted unless
+// Redact replaces them
+var redactParams = []string{
+	"access_token",
+	"api_key",
+	"apikey",
+	"auth",
+	"key",
+	"passwd",
+	"password",
+	"secret",
+	"sig",
+	"signature",
+	"token",
+	"x-amz-credential",
+	"x-amz-security-token",
+	"x-amz-signature",
+}
+
+// redactHeaders are the request headers whose values are always redacted,
+// as well as headers named like a redacted query parameter
+var redactHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"X-Amz-Security-Token",
+}
+
+// redactList returns the query parameters whose values are redacted
+func (p *Parms) redactList() []string {
+	if p.Redact != nil {
+		return p.Redact
+	}
+	return redactParams
+}
+
+// credentials returns the password and the values of the redacted query
+// parameters in the URL s as secrets, so redact keeps them out of logs and
+// the run status; ${...} references aren't secrets, see expand
+func (p *Parms) credentials(s string) []secret {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil
+	}
+
+	var secrets []secret
+	if pass, ok := u.User.Password(); ok && pass != "" && !secretRx.MatchString(pass) {
+		secrets = append(secrets, secret{ref: redactedPass, value: pass})
+	}
+
+	for _, kv := range strings.FieldsFunc(u.RawQuery, func(r rune) bool { return r == '&' || r == ';' }) {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 || i == len(kv)-1 {
+			continue
+		}
+
+		k, err := url.QueryUnescape(kv[:i])
+		if err != nil || !p.redacted(k) {
+			continue
+		}
+
+		v, err := url.QueryUnescape(kv[i+1:])
+		if err != nil {
+			v = kv[i+1:]
+		}
+		if secretRx.MatchString(v) {
+			continue
+		}
+		secrets = append(secrets, secret{ref: redactedValue, value: v})
+	}
+	return secrets
+}
+
+// redacted returns true if the query parameter k's value is redacted
+func (p *Parms) redacted(k string) bool {
+	for _, r := range p.redactList() {
+		if strings.EqualFold(k, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerSecrets returns the values of the credential headers in h as
+// secrets, with the credentials of an Authorization scheme on their own too
+func (p *Parms) headerSecrets(h http.Header) []secret {
+	var secrets []secret
+	for k, vs := range h {
+		if !p.redacted(k) && !redactedHeader(k) {
+			continue
+		}
+
+		for _, v := range vs {
+			secrets = append(secrets, secret{ref: redactedValue, value: v})
+			if f := strings.Fields(v); len(f) > 1 {
+				secrets = append(secrets, secret{ref: redactedValue, value: strings.Join(f[1:], " ")})
+			}
+		}
+	}
+	return secrets
+}
+
+// redactedHeader returns true if header k's value is always redacted
+func redactedHeader(k string) bool {
+	for _, r := range redactHeaders {
+		if strings.EqualFold(k, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	// defaultRedirects matches net/http's default redirect limit
+	defaultRedirects = 10
+
+	// redirect policies set per source with the redirect-policy leaf
+	redirectFollow   = "follow"
+	redirectNone     = "none"
+	redirectSameHost = "same-host"
+)
+
+// checkRedirect returns an http.Client CheckRedirect function enforcing the
+// Redirects limit, the source's redirect policy and, with an HTTPS policy,
+// refusing redirects to plain HTTP
+func (o *object) checkRedirect() func(*http.Request, []*http.Request) error {
+	limit := o.Redirs
+	if limit == 0 {
+		limit = defaultRedirects
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		switch {
+		case o.redirect == redirectNone:
+			return fmt.Errorf("%v: redirect to %v refused by policy", o.name, req.URL)
+		case o.redirect == redirectSameHost && req.URL.Host != via[0].URL.Host:
+			return fmt.Errorf("%v: cross-host redirect to %v refused", o.name, req.URL)
+		case o.HTTPS != HTTPSallow && req.URL.Scheme != "https":
+			return fmt.Errorf("%v: redirect to plain HTTP %v refused", o.name, req.URL)
+		case len(via) > limit:
+			return fmt.Errorf("%v: stopped after %d redirects", o.name, limit)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	Convey("Testing source redirect policies", t, func() {
+		parked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "parked.example.com\n")
+		}))
+		defer parked.Close()
+
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ads.zeus.com\n")
+		})
+		mux.Handle("/moved", http.RedirectHandler("/list", http.StatusMovedPermanently))
+		mux.Handle("/defunct", http.RedirectHandler(parked.URL+"/", http.StatusFound))
+		mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+
+		tests := []struct {
+			path   string
+			policy string
+			limit  int
+			final  string
+			ok     bool
+		}{
+			{path: "/moved", final: srv.URL + "/list", ok: true},
+			{path: "/moved", policy: redirectSameHost, final: srv.URL + "/list", ok: true},
+			{path: "/moved", policy: redirectNone},
+			{path: "/defunct", final: parked.URL + "/", ok: true},
+			{path: "/defunct", policy: redirectSameHost},
+			{path: "/loop", limit: 2},
+			{path: "/loop"},
+		}
+
+		for _, tt := range tests {
+			o := getHTTP(&object{
+				Parms:    &Parms{Method: "GET", Redirs: tt.limit},
+				name:     "zeus",
+				redirect: tt.policy,
+				url:      srv.URL + tt.path,
+			})
+			So(o.err == nil, ShouldEqual, tt.ok)
+			So(o.final, ShouldEqual, tt.final)
+		}
+
+		Convey("Testing redirect-policy configuration", func() {
+			cfg := "blacklist {\n\tdomains {\n\t\tsource zeus {\n\t\t\tredirect-policy %v\n\t\t\turl http://zeus.com\n\t\t}\n\t}\n}"
+
+			c := NewConfig()
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, redirectSameHost)}), ShouldBeNil)
+			So(c.Get(domains).x[0].redirect, ShouldEqual, redirectSameHost)
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "sometimes")})
+			So(err.Error(), ShouldEqual, `config.boot:4: source "zeus" has unknown redirect-policy "sometimes"`)
+		})
+	})
+}
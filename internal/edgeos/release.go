@@ -0,0 +1,160 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReleasesURL is the GitHub API endpoint for blacklist's latest release
+const ReleasesURL = "https://api.github.com/repos/britannic/blacklist/releases/latest"
+
+// Release is a published blacklist release
+type Release struct {
+	Tag    string         `json:"tag_name"`
+	URL    string         `json:"html_url"`
+	Assets []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a Release
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// archAliases are the other names release assets use for an architecture
+var archAliases = map[string][]string{
+	"amd64":  {"x86_64", "x64"},
+	"arm64":  {"aarch64"},
+	"mips64": {"octeon"},
+	"mipsle": {"mipsel", "e50"},
+}
+
+// LatestRelease fetches the latest release from u, a GitHub releases API
+// URL such as ReleasesURL
+func (c *Config) LatestRelease(u string) (*Release, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", agent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to check %v for releases: %v", u, resp.Status)
+	}
+
+	r := &Release{}
+	if err = json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("%v: %v", u, err)
+	}
+	if r.Tag == "" {
+		return nil, fmt.Errorf("%v: release has no tag", u)
+	}
+	return r, nil
+}
+
+// Newer is true if the release is newer than version v; it is false if v
+// isn't a version, as in a build without one
+func (r *Release) Newer(v string) bool {
+	cmp, ok := CompareVersions(r.Tag, v)
+	return ok && cmp > 0
+}
+
+// Asset returns the release's asset for arch, an EdgeOS architecture such
+// as mips64 or mipsle, or nil if it has none
+func (r *Release) Asset(arch string) *ReleaseAsset {
+	names := append([]string{arch}, archAliases[arch]...)
+	for i, a := range r.Assets {
+		fields := strings.FieldsFunc(strings.ToLower(a.Name), func(ch rune) bool {
+			return ch == '_' || ch == '-' || ch == '.'
+		})
+		for _, f := range fields {
+			if contains(names, f) {
+				return &r.Assets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// CompareVersions compares semantic versions a and b, with or without a
+// leading v, returning -1, 0 or 1; a pre-release, such as 0.06-alpha,
+// comes before its release. ok is false if either isn't a version
+func CompareVersions(a, b string) (cmp int, ok bool) {
+	an, apre, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	bn, bpre, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := 0; i < len(an) || i < len(bn); i++ {
+		var x, y int
+		if i < len(an) {
+			x = an[i]
+		}
+		if i < len(bn) {
+			y = bn[i]
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+	}
+
+	switch {
+	case apre == bpre:
+		return 0, true
+	case apre == "":
+		return 1, true
+	case bpre == "", apre < bpre:
+		return -1, true
+	}
+	return 1, true
+}
+
+// parseVersion splits a version into its numbers and pre-release
+func parseVersion(v string) (nums []int, pre string, ok bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		if v[i] == '-' {
+			pre = strings.SplitN(v[i+1:], "+", 2)[0]
+		}
+		v = v[:i]
+	}
+
+	if v == "" {
+		return nil, "", false
+	}
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre, true
+}
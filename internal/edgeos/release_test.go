@@ -0,0 +1,73 @@
+package edgeos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompareVersions(t *testing.T) {
+	Convey("Testing CompareVersions()", t, func() {
+		tests := []struct {
+			a, b string
+			cmp  int
+			ok   bool
+		}{
+			{a: "v1.2.3", b: "1.2.3", cmp: 0, ok: true},
+			{a: "v1.2.10", b: "v1.2.9", cmp: 1, ok: true},
+			{a: "1.2", b: "1.2.1", cmp: -1, ok: true},
+			{a: "0.06-alpha", b: "0.06", cmp: -1, ok: true},
+			{a: "0.06", b: "0.06-alpha", cmp: 1, ok: true},
+			{a: "0.07-alpha", b: "0.06", cmp: 1, ok: true},
+			{a: "1.0.0-beta", b: "1.0.0-alpha", cmp: 1, ok: true},
+			{a: "1.0.0+build.5", b: "1.0.0", cmp: 0, ok: true},
+			{a: "UNKNOWN", b: "1.0.0"},
+			{a: "1.0.0", b: ""},
+		}
+
+		for _, tt := range tests {
+			cmp, ok := CompareVersions(tt.a, tt.b)
+			So(ok, ShouldEqual, tt.ok)
+			So(cmp, ShouldEqual, tt.cmp)
+		}
+	})
+}
+
+func TestLatestRelease(t *testing.T) {
+	Convey("Testing LatestRelease()", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/latest":
+				w.Write([]byte(`{"tag_name": "v1.1.0", "html_url": "https://example.com/v1.1.0", "assets": [
+					{"name": "edgeos-blacklist_1.1.0_mips64.deb", "browser_download_url": "https://example.com/mips64.deb"},
+					{"name": "edgeos-blacklist_1.1.0_mipsel.deb", "browser_download_url": "https://example.com/mipsel.deb"}
+				]}`))
+			case "/untagged":
+				w.Write([]byte(`{}`))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer srv.Close()
+
+		c := NewConfig()
+		r, err := c.LatestRelease(srv.URL + "/latest")
+		So(err, ShouldBeNil)
+		So(r.Tag, ShouldEqual, "v1.1.0")
+		So(r.Newer("1.0.9"), ShouldBeTrue)
+		So(r.Newer("v1.1.0"), ShouldBeFalse)
+		So(r.Newer("UNKNOWN"), ShouldBeFalse)
+
+		So(r.Asset("mips64").URL, ShouldEqual, "https://example.com/mips64.deb")
+		So(r.Asset("mipsle").URL, ShouldEqual, "https://example.com/mipsel.deb")
+		So(r.Asset("amd64"), ShouldBeNil)
+
+		_, err = c.LatestRelease(srv.URL + "/untagged")
+		So(err.Error(), ShouldEqual, srv.URL+"/untagged: release has no tag")
+
+		_, err = c.LatestRelease(srv.URL + "/missing")
+		So(err.Error(), ShouldEqual, "unable to check "+srv.URL+"/missing for releases: 404 Not Found")
+	})
+}
@@ -0,0 +1,18 @@
+package edgeos
+
+import "sync"
+
+// Reload re-reads the configuration from r into a running Config, it is
+// validated before it replaces the running one, which is kept if r fails
+// to parse. The exclusions collected for the old configuration are kept
+// until ResetExclusions drops them
+func (c *Config) Reload(r ConfLoader) error {
+	return c.ReadCfg(r)
+}
+
+// ResetExclusions drops the exclusions collected for an earlier
+// configuration, callers serialize it with the runs that use them
+func (c *Config) ResetExclusions() {
+	c.Dex = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	c.Exc = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+}
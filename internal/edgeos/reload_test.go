@@ -0,0 +1,35 @@
+package edgeos
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReload(t *testing.T) {
+	Convey("Testing Reload()", t, func() {
+		c := NewConfig(Nodes([]string{domains, hosts}))
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tinclude old.example.com\n\t}\n}"}), ShouldBeNil)
+		c.Exc.set("stale.example.com", 0)
+
+		Convey("keeps the running configuration if the new one is invalid", func() {
+			So(c.Reload(&CFGstatic{Cfg: "blacklist {\n\tsource x {\n\t}\n}"}), ShouldNotBeNil)
+			So(c.Reload(&CFGstatic{Cfg: ""}), ShouldEqual, ErrConfigEmpty)
+			So(c.FWIncludes(), ShouldResemble, []string{"old.example.com"})
+			So(c.Exc.keyExists("stale.example.com"), ShouldBeTrue)
+		})
+
+		Convey("swaps in a valid configuration", func() {
+			v := c.View()
+			So(c.Reload(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tinclude new.example.com\n\t}\n}"}), ShouldBeNil)
+			So(c.FWIncludes(), ShouldResemble, []string{"new.example.com"})
+			So(c.Exc.keyExists("stale.example.com"), ShouldBeTrue)
+			So(v.FWIncludes(), ShouldResemble, []string{"old.example.com"})
+		})
+
+		Convey("drops the collected exclusions with ResetExclusions()", func() {
+			c.ResetExclusions()
+			So(c.Exc.keyExists("stale.example.com"), ShouldBeFalse)
+		})
+	})
+}
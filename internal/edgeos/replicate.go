@@ -0,0 +1,173 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const manifestPath = "/manifest"
+
+// Manifest lists a primary router's generated files and their hashes
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes a single generated file
+type ManifestFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// fileHash returns the hex encoded SHA256 digest and size of a file
+func fileHash(f string) (string, int64, error) {
+	r, err := os.Open(f)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	return hex.EncodeToString(h.Sum(nil)), n, err
+}
+
+// Follow pulls the generated files from a primary router's status API and
+// installs any that are missing or changed, removing files the primary no
+// longer has, then reloads dnsmasq if any changed. Only names matching the
+// generated file pattern are installed, so a primary can't write other
+// dnsmasq configuration into Dir
+func (c *Config) Follow(primary string) error {
+	var (
+		changed bool
+		errs    []string
+		m       = &Manifest{}
+		client  = &http.Client{Timeout: c.Timeout}
+		keep    []string
+	)
+
+	primary = strings.TrimSuffix(primary, "/")
+	resp, err := client.Get(primary + manifestPath)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get manifest from %v: %v", primary, resp.Status)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return fmt.Errorf("unable to decode manifest from %v: %v", primary, err)
+	}
+
+	for _, f := range m.Files {
+		if !c.isGenerated(f.Name) {
+			errs = append(errs, fmt.Sprintf("refusing to install %q", f.Name))
+			continue
+		}
+
+		local := filepath.Join(c.Dir, f.Name)
+		keep = append(keep, local)
+		if h, _, err := fileHash(local); err == nil && h == f.SHA256 {
+			continue
+		}
+
+		if err = c.install(client, primary, local, f); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		changed = true
+	}
+
+	if errs != nil {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+
+	d, err := c.generated()
+	if err != nil {
+		return err
+	}
+
+	stale := diffArray(keep, d)
+	if err = purgeFiles(stale); err != nil {
+		return err
+	}
+
+	if changed || len(stale) > 0 {
+		_, err = c.ReloadDNS()
+	}
+	return err
+}
+
+// install downloads a generated file from the primary, verifies its hash and
+// atomically replaces the local copy
+func (c *Config) install(client *http.Client, primary, local string, f ManifestFile) error {
+	resp, err := client.Get(primary + filesPath + f.Name)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get %v from %v: %v", f.Name, primary, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != f.SHA256 {
+		return fmt.Errorf("%v hash mismatch, not installed", f.Name)
+	}
+
+	tmp := local + ".tmp"
+	if err = (&bList{file: tmp, r: bytes.NewReader(body)}).writeFile(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, local)
+}
+
+// manifest hashes the generated files present in Dir
+func (c *Config) manifest() (*Manifest, error) {
+	m := &Manifest{Files: []ManifestFile{}}
+	names, err := c.generated()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		h, n, err := fileHash(name)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, ManifestFile{Name: filepath.Base(name), SHA256: h, Size: n})
+	}
+	return m, nil
+}
+
+// manifest writes the generated file manifest as JSON
+func (a *StatusAPI) manifest(w http.ResponseWriter, r *http.Request) {
+	m, err := a.Config.manifest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
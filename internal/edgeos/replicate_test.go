@@ -0,0 +1,75 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFollow(t *testing.T) {
+	Convey("Testing Follow()", t, func() {
+		var (
+			primary, _   = ioutil.TempDir("/tmp", "testPrimary")
+			secondary, _ = ioutil.TempDir("/tmp", "testSecondary")
+			newConfig    = func(dir string) *Config {
+				return NewConfig(
+					Dir(dir),
+					Ext("blacklist.conf"),
+					FileNameFmt("%v/%v.%v.%v"),
+					WCard(Wildcard{Node: "*s", Name: "*"}),
+				)
+			}
+		)
+		defer os.RemoveAll(primary)
+		defer os.RemoveAll(secondary)
+
+		files := map[string]string{
+			"domains.zeus.blacklist.conf": "address=/.zeus.com/0.0.0.0\n",
+			"hosts.yoyo.blacklist.conf":   "address=/ads.yoyo.org/0.0.0.0\n",
+		}
+		for f, data := range files {
+			So(ioutil.WriteFile(fmt.Sprintf("%v/%v", primary, f), []byte(data), 0644), ShouldBeNil)
+		}
+		So(ioutil.WriteFile(secondary+"/hosts.stale.blacklist.conf", []byte("stale"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(secondary+"/hosts.yoyo.blacklist.conf", []byte("old"), 0644), ShouldBeNil)
+
+		srv := httptest.NewServer(newConfig(primary).NewStatusAPI())
+		defer srv.Close()
+
+		r := &fakeRunner{}
+		c := newConfig(secondary)
+		c.SetOpt(DNSsvc("service dnsmasq restart"), Shell(r))
+		So(c.Follow(srv.URL), ShouldBeNil)
+		So(r.scripts, ShouldResemble, []string{"service dnsmasq restart"})
+
+		act, err := c.generated()
+		So(err, ShouldBeNil)
+		So(act, ShouldResemble, []string{secondary + "/domains.zeus.blacklist.conf", secondary + "/hosts.yoyo.blacklist.conf"})
+
+		for f, data := range files {
+			b, err := ioutil.ReadFile(fmt.Sprintf("%v/%v", secondary, f))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, data)
+		}
+
+		So(c.Follow(srv.URL), ShouldBeNil)
+		So(r.scripts, ShouldHaveLength, 1)
+
+		So(c.Follow("http://127.0.0.1:808"), ShouldNotBeNil)
+
+		evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, `{"files":[{"name":"dhcp.conf","sha256":"00"},{"name":"../hosts.yoyo.blacklist.conf","sha256":"00"}]}`)
+		}))
+		defer evil.Close()
+
+		err = c.Follow(evil.URL)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "refusing to install \"dhcp.conf\"\nrefusing to install \"../hosts.yoyo.blacklist.conf\"")
+		So(r.scripts, ShouldHaveLength, 1)
+	})
+}
@@ -0,0 +1,223 @@
+package edgeos
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// resolverDoH prefixes a DNS-over-HTTPS Resolv URL
+	resolverDoH = "https://"
+	// resolverDoT prefixes a DNS-over-TLS Resolv server
+	resolverDoT = "tls://"
+
+	dnsMessage = "application/dns-message"
+)
+
+// dialer is the Dial function net.Resolver uses to reach a DNS server
+type dialer func(ctx context.Context, network, address string) (net.Conn, error)
+
+// resolverAddr returns the Resolv bootstrap server as host:port, it must be
+// an IP address since it's used when name resolution isn't working
+func (p *Parms) resolverAddr() (string, error) {
+	host, port, err := net.SplitHostPort(p.Resolv)
+	if err != nil {
+		host, port = p.Resolv, "53"
+	}
+
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("resolver %q must be an IP address", p.Resolv)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// resolverDial returns a dialer for Resolv, which is either a plain DNS
+// server, tls://host[:port] for DNS-over-TLS or an https:// DNS-over-HTTPS URL
+func (p *Parms) resolverDial() (dialer, error) {
+	switch {
+	case strings.HasPrefix(p.Resolv, resolverDoH):
+		return p.dohDial()
+	case strings.HasPrefix(p.Resolv, resolverDoT):
+		return p.dotDial()
+	}
+
+	addr, err := p.resolverAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}, nil
+}
+
+// dotDial returns a dialer for a DNS-over-TLS server, net.Resolver uses TCP
+// framing on the connection since it isn't a net.PacketConn
+func (p *Parms) dotDial() (dialer, error) {
+	addr := p.Resolv[len(resolverDoT):]
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = strings.Trim(addr, "[]"), "853"
+	}
+	if host == "" {
+		return nil, fmt.Errorf("resolver %q is missing a host", p.Resolv)
+	}
+
+	roots, err := p.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &tls.Dialer{Config: &tls.Config{RootCAs: roots, ServerName: host}}
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}, nil
+}
+
+// dohDial returns a dialer for a DNS-over-HTTPS server
+func (p *Parms) dohDial() (dialer, error) {
+	u, err := url.Parse(p.Resolv)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("resolver %q isn't a valid DNS-over-HTTPS URL", p.Resolv)
+	}
+
+	roots, err := p.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return &dohConn{ctx: ctx, client: client, url: u.String()}, nil
+	}, nil
+}
+
+// resolverClient returns a copy of c that looks up source hostnames with the
+// Resolv server instead of the system resolver, which is usually the dnsmasq
+// instance being updated
+func (p *Parms) resolverClient(c *http.Client) (*http.Client, error) {
+	dial, err := p.resolverDial()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &net.Resolver{PreferGo: true, Dial: dial}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if c.Transport != nil {
+		t = c.Transport.(*http.Transport).Clone()
+	}
+	t.DialContext = (&net.Dialer{Resolver: r, Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+
+	rc := *c
+	rc.Transport = t
+	return &rc, nil
+}
+
+// dohConn is a net.Conn that exchanges the TCP framed DNS messages written by
+// net.Resolver with a DNS-over-HTTPS server, one POST per message
+type dohConn struct {
+	ctx      context.Context
+	client   *http.Client
+	url      string
+	deadline time.Time
+	in, out  bytes.Buffer
+}
+
+// dohAddr is a dohConn's net.Addr
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
+
+// Write sends each complete message to the server and queues its answer
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.out.Write(b)
+	for c.out.Len() >= 2 {
+		n := int(binary.BigEndian.Uint16(c.out.Bytes()))
+		if c.out.Len() < n+2 {
+			break
+		}
+
+		c.out.Next(2)
+		r, err := c.exchange(c.out.Next(n))
+		if err != nil {
+			return 0, err
+		}
+
+		var l [2]byte
+		binary.BigEndian.PutUint16(l[:], uint16(len(r)))
+		c.in.Write(l[:])
+		c.in.Write(r)
+	}
+	return len(b), nil
+}
+
+// exchange POSTs a DNS message to the server and returns its answer
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", dnsMessage)
+	req.Header.Set("Content-Type", dnsMessage)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS query to %v failed: %v", c.url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 0xffff))
+}
+
+// Read returns the queued answers
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.in.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.in.Read(b)
+}
+
+// Close implements net.Conn
+func (c *dohConn) Close() error { return nil }
+
+// LocalAddr implements net.Conn
+func (c *dohConn) LocalAddr() net.Addr { return dohAddr("") }
+
+// RemoteAddr implements net.Conn
+func (c *dohConn) RemoteAddr() net.Addr { return dohAddr(c.url) }
+
+// SetDeadline implements net.Conn
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+// SetReadDeadline implements net.Conn
+func (c *dohConn) SetReadDeadline(t time.Time) error { return nil }
+
+// SetWriteDeadline implements net.Conn, the deadline applies to the POST
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
@@ -0,0 +1,241 @@
+package edgeos
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// dnsReply answers a DNS query for A records with ip and returns NOERROR with
+// no answers for anything else; it also returns the queried name
+func dnsReply(q []byte, ip net.IP) ([]byte, string) {
+	if len(q) < 12 {
+		return nil, ""
+	}
+
+	var (
+		i      = 12
+		labels []string
+	)
+	for i < len(q) && q[i] != 0 {
+		n := int(q[i])
+		if i+1+n > len(q) {
+			return nil, ""
+		}
+		labels = append(labels, string(q[i+1:i+1+n]))
+		i += n + 1
+	}
+	if i+5 > len(q) {
+		return nil, ""
+	}
+	question := q[12 : i+5]
+	qtype := binary.BigEndian.Uint16(q[i+1:])
+
+	r := append([]byte{}, q[:2]...)
+	r = append(r, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+	r = append(r, question...)
+	if qtype == 1 && ip != nil {
+		r[7] = 1
+		r = append(r, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+		r = append(r, ip.To4()...)
+	}
+	return r, strings.Join(labels, ".")
+}
+
+// fakeDNS serves A records for every name as 127.0.0.1 over UDP and records
+// the names queried
+func fakeDNS() (string, func() []string, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+
+	go func() {
+		b := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			r, name := dnsReply(b[:n], net.IPv4(127, 0, 0, 1))
+			mu.Lock()
+			names = append(names, name)
+			mu.Unlock()
+			conn.WriteTo(r, addr)
+		}
+	}()
+
+	queried := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+	return conn.LocalAddr().String(), queried, func() { conn.Close() }
+}
+
+// fakeDoT serves A records for every name as 127.0.0.1 over DNS-over-TLS
+func fakeDoT(cfg *tls.Config) (string, func()) {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					var l [2]byte
+					if _, err := io.ReadFull(conn, l[:]); err != nil {
+						return
+					}
+					q := make([]byte, binary.BigEndian.Uint16(l[:]))
+					if _, err := io.ReadFull(conn, q); err != nil {
+						return
+					}
+					r, _ := dnsReply(q, net.IPv4(127, 0, 0, 1))
+					binary.BigEndian.PutUint16(l[:], uint16(len(r)))
+					conn.Write(append(l[:], r...))
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func TestResolverAddr(t *testing.T) {
+	Convey("Testing resolverAddr()", t, func() {
+		tests := []struct {
+			resolv string
+			exp    string
+			err    error
+		}{
+			{resolv: "9.9.9.9", exp: "9.9.9.9:53"},
+			{resolv: "9.9.9.9:5353", exp: "9.9.9.9:5353"},
+			{resolv: "2620:fe::fe", exp: "[2620:fe::fe]:53"},
+			{resolv: "[2620:fe::fe]:53", exp: "[2620:fe::fe]:53"},
+			{resolv: "dns.quad9.net", err: errors.New(`resolver "dns.quad9.net" must be an IP address`)},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.resolv, func() {
+				act, err := (&Parms{Resolv: tt.resolv}).resolverAddr()
+				switch tt.err {
+				case nil:
+					So(err, ShouldBeNil)
+				default:
+					So(err.Error(), ShouldEqual, tt.err.Error())
+				}
+				So(act, ShouldEqual, tt.exp)
+			})
+		}
+	})
+}
+
+func TestResolverClient(t *testing.T) {
+	Convey("Testing getHTTP() with a bootstrap resolver", t, func() {
+		addr, queried, stop := fakeDNS()
+		defer stop()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "0.0.0.0 ads.example.com\n")
+		}))
+		defer srv.Close()
+
+		u, _ := url.Parse(srv.URL)
+		o := getHTTP(&object{
+			Parms: &Parms{Method: http.MethodGet, Resolv: addr},
+			name:  "bootstrap",
+			url:   "http://blocklist.invalid:" + u.Port() + "/hosts",
+		})
+		So(o.err, ShouldBeNil)
+
+		b, err := ioutil.ReadAll(o.r)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "0.0.0.0 ads.example.com\n")
+		So(queried(), ShouldContain, "blocklist.invalid")
+
+		Convey("over encrypted transports", func() {
+			doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q, _ := ioutil.ReadAll(r.Body)
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessage {
+					http.Error(w, "bad query", http.StatusBadRequest)
+					return
+				}
+				a, _ := dnsReply(q, net.IPv4(127, 0, 0, 1))
+				w.Header().Set("Content-Type", dnsMessage)
+				w.Write(a)
+			}))
+			defer doh.Close()
+
+			dot, stop := fakeDoT(doh.TLS)
+			defer stop()
+
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			ca := dir + "/ca.pem"
+			So(ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: doh.Certificate().Raw}), 0644), ShouldBeNil)
+
+			tests := []struct {
+				name   string
+				resolv string
+				cafile string
+				err    string
+			}{
+				{name: "DNS-over-HTTPS", resolv: doh.URL + "/dns-query", cafile: ca},
+				{name: "DNS-over-TLS", resolv: "tls://" + dot, cafile: ca},
+				{name: "untrusted DNS-over-TLS", resolv: "tls://" + dot, err: "certificate signed by unknown authority"},
+				{name: "invalid DNS-over-TLS", resolv: "tls://", err: `resolver "tls://" is missing a host`},
+			}
+
+			for _, tt := range tests {
+				Convey("with "+tt.name, func() {
+					o := getHTTP(&object{
+						Parms: &Parms{CAfile: tt.cafile, Method: http.MethodGet, Resolv: tt.resolv},
+						name:  "encrypted",
+						url:   "http://blocklist.invalid:" + u.Port() + "/hosts",
+					})
+					if tt.err != "" {
+						So(o.err.Error(), ShouldContainSubstring, tt.err)
+						return
+					}
+
+					So(o.err, ShouldBeNil)
+					b, err := ioutil.ReadAll(o.r)
+					So(err, ShouldBeNil)
+					So(string(b), ShouldEqual, "0.0.0.0 ads.example.com\n")
+				})
+			}
+		})
+
+		Convey("an invalid resolver fails the source", func() {
+			o := getHTTP(&object{Parms: &Parms{Method: http.MethodGet, Resolv: "dns.quad9.net"}, url: srv.URL})
+			So(o.err.Error(), ShouldEqual, `resolver "dns.quad9.net" must be an IP address`)
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// partial caches the body of an interrupted download so it can be resumed
+// with a Range request
+type partial struct {
+	body      []byte
+	validator string
+}
+
+// newPartial returns a *partial for body if resp can be resumed, i.e. the
+// server accepts byte ranges and sent a strong ETag or Last-Modified to
+// make sure the rest of the body belongs to the same content
+func newPartial(resp *http.Response, body []byte) *partial {
+	if len(body) == 0 || resp.Header.Get("Accept-Ranges") != "bytes" {
+		return nil
+	}
+
+	v := resp.Header.Get("ETag")
+	if v == "" || strings.HasPrefix(v, "W/") {
+		v = resp.Header.Get("Last-Modified")
+	}
+	if v == "" {
+		return nil
+	}
+	return &partial{body: body, validator: v}
+}
+
+// setRange asks for the rest of the body, If-Range makes the server send the
+// full body instead if the content has changed
+func (p *partial) setRange(req *http.Request) {
+	if p == nil {
+		return
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(p.body)))
+	req.Header.Set("If-Range", p.validator)
+}
+
+// resumed returns true if resp continues the cached body
+func (p *partial) resumed(resp *http.Response) bool {
+	if p == nil || resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+
+	var start int64
+	_, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start)
+	return err == nil && start == int64(len(p.body))
+}
@@ -0,0 +1,125 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewPartial(t *testing.T) {
+	Convey("Testing newPartial()", t, func() {
+		hdr := func(kv ...string) *http.Response {
+			r := &http.Response{Header: http.Header{}}
+			for i := 0; i < len(kv); i += 2 {
+				r.Header.Set(kv[i], kv[i+1])
+			}
+			return r
+		}
+
+		tests := []struct {
+			name string
+			resp *http.Response
+			body []byte
+			exp  *partial
+		}{
+			{name: "etag", resp: hdr("Accept-Ranges", "bytes", "ETag", `"v1"`), body: []byte("abc"), exp: &partial{body: []byte("abc"), validator: `"v1"`}},
+			{name: "weak etag", resp: hdr("Accept-Ranges", "bytes", "ETag", `W/"v1"`, "Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT"), body: []byte("abc"), exp: &partial{body: []byte("abc"), validator: "Mon, 02 Jan 2006 15:04:05 GMT"}},
+			{name: "no validator", resp: hdr("Accept-Ranges", "bytes"), body: []byte("abc")},
+			{name: "no ranges", resp: hdr("ETag", `"v1"`), body: []byte("abc")},
+			{name: "no body", resp: hdr("Accept-Ranges", "bytes", "ETag", `"v1"`)},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.name, func() {
+				So(newPartial(tt.resp, tt.body), ShouldResemble, tt.exp)
+			})
+		}
+	})
+}
+
+func TestResume(t *testing.T) {
+	Convey("Testing getHTTP() resuming interrupted downloads", t, func() {
+		var (
+			data   = []byte(strings.Repeat("0.0.0.0 ads.example.com\n", 100))
+			cut    = len(data) / 2
+			etag   string
+			ranges []string
+		)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("Range") == "" && len(ranges) == 1 {
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+				w.Write(data[:cut])
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		}))
+		defer srv.Close()
+
+		tests := []struct {
+			name    string
+			resumes int
+			etag    string
+			change  bool
+			ranges  []string
+			err     error
+		}{
+			{name: "resumed", resumes: 3, etag: `"v1"`, ranges: []string{"", fmt.Sprintf("bytes=%d-", cut)}},
+			{name: "content changed", resumes: 3, etag: `"v1"`, change: true, ranges: []string{"", fmt.Sprintf("bytes=%d-", cut)}},
+			{name: "disabled", etag: `"v1"`, ranges: []string{""}, err: fmt.Errorf("%v: unexpected EOF", srv.URL)},
+			{name: "no validator", resumes: 3, ranges: []string{""}, err: fmt.Errorf("%v: unexpected EOF", srv.URL)},
+		}
+
+		for _, tt := range tests {
+			for _, spill := range []bool{false, true} {
+				Convey(fmt.Sprintf("with %v, spilled %v", tt.name, spill), func() {
+					etag, ranges = tt.etag, nil
+					o := &object{Parms: &Parms{Method: http.MethodGet, Resumes: tt.resumes}, name: "flaky", spill: spill, url: srv.URL}
+					client := &http.Client{}
+					body, _, _, err := o.fetch(client, srv.URL, nil)
+					So(err.Error(), ShouldEqual, fmt.Sprintf("%v: unexpected EOF", srv.URL))
+					if tt.change {
+						// If-Range no longer matches so the full body is sent
+						etag = `"v2"`
+					}
+
+					for i := 0; err != nil && o.part != nil && i < o.Resumes; i++ {
+						body, _, _, err = o.fetch(client, srv.URL, nil)
+					}
+
+					So(ranges, ShouldResemble, tt.ranges)
+					switch tt.err {
+					case nil:
+						So(err, ShouldBeNil)
+						b, err := ioutil.ReadAll(body)
+						So(err, ShouldBeNil)
+						So(string(b), ShouldEqual, string(data))
+						So(o.part, ShouldBeNil)
+					default:
+						So(err.Error(), ShouldEqual, tt.err.Error())
+					}
+				})
+			}
+		}
+
+		Convey("through getHTTP()", func() {
+			etag, ranges = `"v1"`, nil
+			o := getHTTP(&object{Parms: &Parms{Method: http.MethodGet, Resumes: 1}, name: "flaky", url: srv.URL})
+			So(o.err, ShouldBeNil)
+			b, _ := ioutil.ReadAll(o.r)
+			So(string(b), ShouldEqual, string(data))
+			So(ranges, ShouldResemble, []string{"", fmt.Sprintf("bytes=%d-", cut)})
+		})
+	})
+}
@@ -0,0 +1,86 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// source leaves that clean up entries before they are validated
+const (
+	stripPrefix = "strip-prefix"
+	stripSuffix = "strip-suffix"
+	replace     = "replace"
+)
+
+// rewrite is a source's strip-prefix, strip-suffix or replace rule, Pattern
+// is literal text in which * matches any run of characters
+type rewrite struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+	To      string `json:"to,omitempty"`
+	re      *regexp.Regexp
+}
+
+// newRewrite returns the rule for a source leaf, a replace rule's value is
+// the pattern and its replacement separated by a space, e.g. "[.] ."
+func newRewrite(kind, value string) (*rewrite, error) {
+	r := &rewrite{Kind: kind, Pattern: strings.ToLower(value)}
+	if kind == replace {
+		f := strings.Fields(r.Pattern)
+		switch len(f) {
+		case 1:
+			r.Pattern = f[0]
+		case 2:
+			r.Pattern, r.To = f[0], f[1]
+		default:
+			return nil, fmt.Errorf("%v %q must be a pattern and its replacement", kind, value)
+		}
+	}
+	return r, r.compile()
+}
+
+// compile builds the rule's regexp, strip-prefix removes the shortest match
+// at the start of an entry and strip-suffix the longest at its end
+func (r *rewrite) compile() error {
+	if r.Pattern == "" || r.Pattern == "*" {
+		return fmt.Errorf("%v %q would match every entry", r.Kind, r.Pattern)
+	}
+
+	pat := regexp.QuoteMeta(r.Pattern)
+	switch r.Kind {
+	case stripPrefix:
+		pat = "^" + strings.Replace(pat, `\*`, ".*?", -1)
+	case stripSuffix:
+		pat = strings.Replace(pat, `\*`, ".*", -1) + "$"
+	case replace:
+		pat = strings.Replace(pat, `\*`, ".*?", -1)
+	default:
+		return fmt.Errorf("unknown rewrite rule %q", r.Kind)
+	}
+
+	var err error
+	r.re, err = regexp.Compile(pat)
+	return err
+}
+
+// rewrite applies the source's rules in their configured order to line's
+// entry, the text after the source's prefix, before anything is stripped
+// from it, so e.g. a URL's path can't be mistaken for a comment; sources with
+// parse-urls then reduce the entry to its URL's hostname
+func (o *object) rewrite(line []byte, prefix string) []byte {
+	if len(o.rewrites) == 0 && o.parseURL == "" {
+		return line
+	}
+
+	entry := bytes.TrimSpace(bytes.TrimPrefix(line, []byte(prefix)))
+	for _, r := range o.rewrites {
+		entry = r.re.ReplaceAllLiteral(entry, []byte(r.To))
+	}
+
+	if o.parseURL != "" {
+		entry = o.urlEntry(entry)
+	}
+	return append([]byte(prefix), entry...)
+}
@@ -0,0 +1,87 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRewrite(t *testing.T) {
+	Convey("Testing strip-prefix, strip-suffix and replace rules", t, func() {
+		tests := []struct {
+			kind  string
+			value string
+			in    string
+			exp   string
+		}{
+			{kind: stripPrefix, value: "*://", in: "https://ads.example.com", exp: "ads.example.com"},
+			{kind: stripPrefix, value: "HTTP://", in: "http://ads.example.com", exp: "ads.example.com"},
+			{kind: stripPrefix, value: "*.", in: "www.ads.example.com", exp: "ads.example.com"},
+			{kind: stripSuffix, value: "/*", in: "ads.example.com/path/to/x", exp: "ads.example.com"},
+			{kind: stripSuffix, value: ":*", in: "ads.example.com:443", exp: "ads.example.com"},
+			{kind: stripSuffix, value: ":443", in: "ads.example.com:8443", exp: "ads.example.com:8443"},
+			{kind: replace, value: "[.] .", in: "ads[.]example[.]com", exp: "ads.example.com"},
+			{kind: replace, value: "_", in: "ads_example.com", exp: "adsexample.com"},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.kind+" "+tt.value+" rewrites "+tt.in, func() {
+				r, err := newRewrite(tt.kind, tt.value)
+				So(err, ShouldBeNil)
+
+				o := &object{rewrites: []*rewrite{r}}
+				So(string(o.rewrite([]byte(tt.in), "")), ShouldEqual, tt.exp)
+			})
+		}
+
+		Convey("invalid rules are rejected", func() {
+			_, err := newRewrite(stripSuffix, "*")
+			So(err.Error(), ShouldEqual, `strip-suffix "*" would match every entry`)
+
+			_, err = newRewrite(replace, "a b c")
+			So(err.Error(), ShouldEqual, `replace "a b c" must be a pattern and its replacement`)
+		})
+
+		Convey("sources apply their rules before entries are validated", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			src := dir + "/feed.txt"
+			So(ioutil.WriteFile(src, []byte("http://ads.example.com/path\ntracker.example.com:443\nevil[.]example[.]net\n"), 0644), ShouldBeNil)
+
+			cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tfile " + src + "\n\t\t\tprefix \"\"\n" +
+				"\t\t\tstrip-prefix *://\n\t\t\tstrip-suffix /*\n\t\t\tstrip-suffix :*\n\t\t\treplace \"[.] .\"\n\t\t}\n\t}\n}"
+
+			c := NewConfig(Dir(dir), Ext("blacklist.conf"), FileNameFmt("%v/%v.%v.%v"), Nodes([]string{domains}), Prefix("address="))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			o := c.Get(domains).x[0]
+			So(o.rewrites, ShouldHaveLength, 4)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+
+			b, err := ioutil.ReadFile(dir + "/domains.feed.blacklist.conf")
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "address=/.ads.example.com/0.0.0.0\naddress=/.evil.example.net/0.0.0.0\naddress=/.tracker.example.com/0.0.0.0\n")
+
+			Convey("and keep them in snapshots", func() {
+				j, err := json.Marshal(o)
+				So(err, ShouldBeNil)
+
+				s := newObject()
+				So(json.Unmarshal(j, s), ShouldBeNil)
+				So(string(s.rewrite([]byte("0.0.0.0 https://x.example.com:80"), "0.0.0.0 ")), ShouldEqual, "0.0.0.0 x.example.com")
+			})
+
+			err = NewConfig().ReadCfg(&CFGstatic{Cfg: strings.Replace(cfg, "strip-prefix *://", "strip-prefix *", 1)})
+			So(err.Error(), ShouldEqual, `config.boot:7: source "feed" has strip-prefix "*" would match every entry`)
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package edgeos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execTimeout limits how long a hook, transform or processor command may run
+const execTimeout = 10 * time.Minute
+
+// Runner executes shell scripts, replace it to fake or sandbox command execution
+type Runner interface {
+	// Output runs script and returns its standard output
+	Output(script string) ([]byte, error)
+	// CombinedOutput runs script and returns its standard output and error
+	CombinedOutput(script string) ([]byte, error)
+	// Command returns the command that runs args directly, without a shell,
+	// it must be killed once ctx is done
+	Command(ctx context.Context, args ...string) *exec.Cmd
+}
+
+// ShellRunner is the default Runner, it feeds scripts to a shell's standard input
+type ShellRunner struct {
+	Cmd string
+}
+
+func (s *ShellRunner) command(script string) *exec.Cmd {
+	cmd := exec.Command(s.Cmd)
+	cmd.Stdin = strings.NewReader(script)
+	return cmd
+}
+
+// Output implements Runner
+func (s *ShellRunner) Output(script string) ([]byte, error) {
+	return s.command(script).Output()
+}
+
+// CombinedOutput implements Runner
+func (s *ShellRunner) CombinedOutput(script string) ([]byte, error) {
+	return s.command(script).CombinedOutput()
+}
+
+// Command implements Runner
+func (s *ShellRunner) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+// runner returns the configured Runner or a ShellRunner using Bash
+func (p *Parms) runner() Runner {
+	if p.Runner != nil {
+		return p.Runner
+	}
+	return &ShellRunner{Cmd: p.Bash}
+}
+
+// execute runs args through the Runner with stdin as its standard input and
+// returns its standard output, or its standard output and error if combined
+// is set, otherwise a failure's error carries the standard error. It is
+// killed once the run's Deadline passes or after execTimeout
+func (p *Parms) execute(args []string, stdin io.Reader, combined bool) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(p.context(), execTimeout)
+	defer cancel()
+
+	var (
+		cmd    = p.runner().Command(ctx, args...)
+		stderr bytes.Buffer
+		out    []byte
+		err    error
+	)
+
+	cmd.Stdin = stdin
+	switch {
+	case combined:
+		out, err = cmd.CombinedOutput()
+	default:
+		cmd.Stderr = &stderr
+		out, err = cmd.Output()
+	}
+
+	switch {
+	case err == nil:
+	case p.expired() != nil:
+		err = ErrDeadline
+	case ctx.Err() != nil:
+		err = fmt.Errorf("killed after running for %v", execTimeout)
+	default:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %v", err, msg)
+		}
+	}
+	return out, err
+}
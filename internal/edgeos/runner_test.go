@@ -0,0 +1,115 @@
+package edgeos
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeRunner records scripts instead of running them, commands are recorded
+// and run
+type fakeRunner struct {
+	scripts  []string
+	commands [][]string
+	out      []byte
+	err      error
+}
+
+func (f *fakeRunner) Output(script string) ([]byte, error) {
+	f.scripts = append(f.scripts, script)
+	return f.out, f.err
+}
+
+func (f *fakeRunner) CombinedOutput(script string) ([]byte, error) {
+	return f.Output(script)
+}
+
+func (f *fakeRunner) Command(ctx context.Context, args ...string) *exec.Cmd {
+	f.commands = append(f.commands, args)
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+func TestShellRunner(t *testing.T) {
+	Convey("Testing ShellRunner", t, func() {
+		r := NewConfig(Bash("/bin/bash")).runner()
+		So(r, ShouldResemble, &ShellRunner{Cmd: "/bin/bash"})
+
+		act, err := r.Output("echo out; echo err >&2")
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "out\n")
+
+		act, err = r.CombinedOutput("echo out; echo err >&2")
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "out\nerr\n")
+	})
+}
+
+func TestExecute(t *testing.T) {
+	Convey("Testing execute()", t, func() {
+		f := &fakeRunner{}
+		c := NewConfig(Shell(f))
+
+		act, err := c.execute([]string{"/bin/sh", "-c", "cat; echo err >&2"}, strings.NewReader("in\n"), false)
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "in\n")
+		So(f.commands, ShouldResemble, [][]string{{"/bin/sh", "-c", "cat; echo err >&2"}})
+
+		act, err = c.execute([]string{"/bin/sh", "-c", "echo out; echo err >&2"}, nil, true)
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "out\nerr\n")
+
+		_, err = c.execute([]string{"/bin/sh", "-c", "echo bad >&2; exit 3"}, nil, false)
+		So(err.Error(), ShouldEqual, "exit status 3: bad")
+
+		Convey("commands are killed once the run's deadline passes", func() {
+			c.SetOpt(Deadline(50 * time.Millisecond))
+			defer c.SetOpt(Deadline(0))
+
+			start := time.Now()
+			_, err := c.execute([]string{"sleep", "5"}, nil, false)
+			So(err, ShouldEqual, ErrDeadline)
+			So(time.Since(start), ShouldBeLessThan, 4*time.Second)
+		})
+	})
+}
+
+func TestRunner(t *testing.T) {
+	Convey("Testing an injected Runner", t, func() {
+		f := &fakeRunner{out: []byte("ok")}
+		c := NewConfig(
+			API("/bin/cli-shell-api"),
+			DNSsvc("service dnsmasq restart"),
+			Level("service dns forwarding"),
+			Shell(f),
+		)
+
+		act, err := c.load("showConfig", c.Level)
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "ok")
+
+		_, err = c.ReloadDNS()
+		So(err, ShouldBeNil)
+
+		_, err = c.Apply([]string{"set service dns forwarding blacklist disabled false"})
+		So(err, ShouldBeNil)
+
+		So(f.scripts, ShouldResemble, []string{
+			"/bin/cli-shell-api showCfg service dns forwarding",
+			"service dnsmasq restart",
+			cfgWrapper + " begin\n" +
+				cfgWrapper + " set service dns forwarding blacklist disabled false || { " + cfgWrapper + " end; exit 1; }\n" +
+				cfgWrapper + " commit || { " + cfgWrapper + " end; exit 1; }\n" +
+				cfgWrapper + " save || { " + cfgWrapper + " end; exit 1; }\n" +
+				cfgWrapper + " end",
+		})
+
+		f.err = errors.New("exit status 1")
+		_, err = c.ReloadDNS()
+		So(err, ShouldResemble, &ErrReload{Output: []byte("ok"), Cause: f.err})
+	})
+}
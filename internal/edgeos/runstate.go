@@ -0,0 +1,189 @@
+package edgeos
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunState is the live state of a Config's current run: each source's
+// progress and result, keyed by node and name, and the bytes downloaded and
+// lines parsed so far. The fetch, parse and write workers update it as they
+// go and it is safe to read from any goroutine, so the progress display,
+// run summary, metrics and TUI all report the same counts
+type RunState struct {
+	mu      sync.Mutex
+	bytes   int64
+	lines   int64
+	active  map[string]Progress
+	results map[string]SourceResult
+	start   time.Time
+}
+
+// RunCounts is a consistent copy of a RunState's counts
+type RunCounts struct {
+	Sources int               `json:"sources"`
+	Failed  int               `json:"failed"`
+	Entries int               `json:"entries"`
+	Held    int               `json:"quarantined"`
+	Dupes   int               `json:"collisions"`
+	Active  int               `json:"active"`
+	Bytes   int64             `json:"bytes"`
+	Lines   int64             `json:"lines"`
+	Elapsed time.Duration     `json:"elapsed"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// newRunState returns an empty *RunState
+func newRunState() *RunState {
+	s := &RunState{}
+	s.reset()
+	return s
+}
+
+// reset empties s for a new run, readers holding s see the new run's state
+func (s *RunState) reset() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bytes, s.lines = 0, 0
+	s.active = make(map[string]Progress)
+	s.results = make(map[string]SourceResult)
+	s.start = time.Now()
+}
+
+// resultKey returns the node/name key a source's result is recorded under,
+// as sources in different nodes may share a name
+func resultKey(node, name string) string {
+	return node + "/" + name
+}
+
+// RunState returns the Config's live run state
+func (c *Config) RunState() *RunState {
+	return c.state
+}
+
+// Report records a source's progress, it is a ProgressFunc; sources report
+// their bytes downloaded and lines parsed so far, so only the increase is
+// counted
+func (s *RunState) Report(p Progress) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.active[p.Source]
+	if p.Bytes > prev.Bytes {
+		s.bytes += p.Bytes - prev.Bytes
+		prev.Bytes, prev.Total = p.Bytes, p.Total
+	}
+	if p.Lines > prev.Lines {
+		s.lines += int64(p.Lines - prev.Lines)
+		prev.Lines = p.Lines
+	}
+
+	if p.Done {
+		delete(s.active, p.Source)
+		return
+	}
+	prev.Source = p.Source
+	s.active[p.Source] = prev
+}
+
+// Record sets a source's result, replacing any from an earlier run
+func (s *RunState) Record(r SourceResult) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.results[resultKey(r.Type, r.Name)] = r
+	s.mu.Unlock()
+}
+
+// forget removes o's result before it is processed again
+func (s *RunState) forget(o *object) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.results, o.key())
+	s.mu.Unlock()
+}
+
+// Active returns the progress of the sources being downloaded or parsed,
+// sorted by name
+func (s *RunState) Active() []Progress {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	p := make([]Progress, 0, len(s.active))
+	for _, a := range s.active {
+		p = append(p, a)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(p, func(i, j int) bool { return p[i].Source < p[j].Source })
+	return p
+}
+
+// Results returns each source's result, sorted by name and node
+func (s *RunState) Results() []SourceResult {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	r := make([]SourceResult, 0, len(s.results))
+	for _, res := range s.results {
+		r = append(r, res)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Name != r[j].Name {
+			return r[i].Name < r[j].Name
+		}
+		return r[i].Type < r[j].Type
+	})
+	return r
+}
+
+// Counts returns the run state's totals
+func (s *RunState) Counts() RunCounts {
+	if s == nil {
+		return RunCounts{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := RunCounts{
+		Sources: len(s.results),
+		Active:  len(s.active),
+		Bytes:   s.bytes,
+		Lines:   s.lines,
+		Elapsed: time.Since(s.start),
+	}
+	for _, r := range s.results {
+		n.Entries += r.Entries
+		n.Held += r.Held
+		n.Dupes += r.Dupes
+		if r.Error != "" {
+			if n.Failed++; n.Errors == nil {
+				n.Errors = make(map[string]string)
+			}
+			n.Errors[r.Name] = r.Error
+		}
+	}
+	return n
+}
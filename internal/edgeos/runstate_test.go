@@ -0,0 +1,102 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRunState(t *testing.T) {
+	Convey("Testing RunState", t, func() {
+		c := NewConfig()
+		s := c.RunState()
+		So(s, ShouldNotBeNil)
+
+		Convey("counts only the increase in each source's progress", func() {
+			s.Report(Progress{Source: "big", Bytes: 100, Total: 300})
+			s.Report(Progress{Source: "big", Bytes: 300, Total: 300})
+			s.Report(Progress{Source: "big", Lines: 10})
+			s.Report(Progress{Source: "small", Bytes: 50, Total: -1})
+			So(s.Active(), ShouldResemble, []Progress{
+				{Source: "big", Bytes: 300, Total: 300, Lines: 10},
+				{Source: "small", Bytes: 50, Total: -1},
+			})
+
+			s.Report(Progress{Source: "big", Lines: 12, Done: true})
+			So(s.Active(), ShouldResemble, []Progress{{Source: "small", Bytes: 50, Total: -1}})
+
+			n := s.Counts()
+			So(n.Bytes, ShouldEqual, 350)
+			So(n.Lines, ShouldEqual, 12)
+			So(n.Active, ShouldEqual, 1)
+		})
+
+		Convey("totals each source's latest result", func() {
+			o := &object{name: "zeus", nType: domn, held: 1}
+			c.state.Record(o.result(3, nil))
+			c.state.Record(o.result(4, nil))
+			c.state.Record((&object{name: "yoyo", nType: host}).result(0, errors.New("timeout")))
+			So(s.Results(), ShouldResemble, []SourceResult{
+				{Name: "yoyo", Type: hosts, Error: "timeout"},
+				{Name: "zeus", Type: domains, Entries: 4, Held: 1},
+			})
+
+			n := s.Counts()
+			So(n.Sources, ShouldEqual, 2)
+			So(n.Entries, ShouldEqual, 4)
+			So(n.Held, ShouldEqual, 1)
+			So(n.Failed, ShouldEqual, 1)
+			So(n.Errors, ShouldResemble, map[string]string{"yoyo": "timeout"})
+
+			c.state.Record((&object{name: "zeus", nType: host}).result(2, nil))
+			So(s.Counts().Entries, ShouldEqual, 6)
+
+			c.state.forget(&object{name: "yoyo", nType: host})
+			So(s.Counts().Failed, ShouldEqual, 0)
+
+			s.Report(Progress{Source: "zeus", Bytes: 10})
+			s.reset()
+			n = s.Counts()
+			So(n, ShouldResemble, RunCounts{Elapsed: n.Elapsed})
+			So(s.Results(), ShouldBeEmpty)
+			So(c.RunState(), ShouldEqual, s)
+		})
+
+		Convey("is safe to update from many goroutines", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					name := fmt.Sprint("s", i)
+					for n := 1; n <= 100; n++ {
+						s.Report(Progress{Source: name, Bytes: int64(n), Lines: n})
+					}
+					s.Report(Progress{Source: name, Done: true})
+					s.Record(SourceResult{Name: name, Entries: 1})
+				}(i)
+			}
+			wg.Wait()
+
+			n := s.Counts()
+			So(n.Bytes, ShouldEqual, 800)
+			So(n.Lines, ShouldEqual, 800)
+			So(n.Entries, ShouldEqual, 8)
+			So(n.Active, ShouldEqual, 0)
+		})
+
+		Convey("is a no-op when nil", func() {
+			var s *RunState
+			s.Report(Progress{Source: "a", Bytes: 1})
+			s.Record(SourceResult{Name: "a"})
+			s.forget(&object{name: "a"})
+			s.reset()
+			So(s.Active(), ShouldBeNil)
+			So(s.Results(), ShouldBeNil)
+			So(s.Counts(), ShouldResemble, RunCounts{})
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// authDir is where relative ${file:...} secrets are read from, EdgeOS keeps
+// /config/auth across firmware upgrades
+var authDir = "/config/auth"
+
+// secretRx matches ${NAME}, ${file:path} and ${secret:name} references
+var secretRx = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// secret is an expanded ${...} reference and its value
+type secret struct {
+	ref   string
+	value string
+}
+
+// expand replaces ${NAME} in s with the environment variable NAME,
+// ${file:path} with the trimmed contents of path, relative paths are read
+// from authDir, and ${secret:name} with the named secret from the
+// SecretStore. It returns the secrets it expanded so they can be redacted
+func expand(s string) (string, []secret, error) {
+	var (
+		errs    []string
+		secrets []secret
+	)
+
+	x := secretRx.ReplaceAllStringFunc(s, func(ref string) string {
+		name := ref[2 : len(ref)-1]
+
+		var v string
+		switch {
+		case strings.HasPrefix(name, "file:"):
+			f := name[len("file:"):]
+			if !filepath.IsAbs(f) {
+				f = filepath.Join(authDir, f)
+			}
+
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				errs = append(errs, err.Error())
+				return ref
+			}
+			v = strings.TrimSpace(string(b))
+
+		case strings.HasPrefix(name, "secret:"):
+			var err error
+			if v, err = NewSecretStore().Get(name[len("secret:"):]); err != nil {
+				errs = append(errs, err.Error())
+				return ref
+			}
+
+		default:
+			var ok bool
+			if v, ok = os.LookupEnv(name); !ok || name == "" {
+				errs = append(errs, fmt.Sprintf("environment variable %q isn't set", name))
+				return ref
+			}
+		}
+
+		secrets = append(secrets, secret{ref: ref, value: v})
+		return v
+	})
+
+	if errs != nil {
+		return s, nil, errors.New(strings.Join(errs, ", "))
+	}
+	return x, secrets, nil
+}
+
+// redact replaces the values of secrets in s with their references, including
+// their URL encoded forms
+func redact(s string, secrets []secret) string {
+	for _, x := range secrets {
+		if x.value == "" {
+			continue
+		}
+
+		for _, v := range []string{x.value, url.QueryEscape(x.value), url.PathEscape(x.value)} {
+			s = strings.Replace(s, v, x.ref, -1)
+		}
+	}
+	return s
+}
+
+// redactErr returns err with any secrets redacted from its message
+func redactErr(err error, secrets []secret) error {
+	if err == nil || secrets == nil {
+		return err
+	}
+
+	if msg := redact(err.Error(), secrets); msg != err.Error() {
+		return errors.New(msg)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package edgeos
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpand(t *testing.T) {
+	Convey("Testing expand() and redact()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		orig := authDir
+		authDir = dir
+		defer func() { authDir = orig }()
+
+		So(ioutil.WriteFile(dir+"/feed.key", []byte("s3cr3t+key\n"), 0600), ShouldBeNil)
+		os.Setenv("BLACKLIST_TEST_USER", "lists")
+		defer os.Unsetenv("BLACKLIST_TEST_USER")
+
+		tests := []struct {
+			in  string
+			exp string
+			err string
+		}{
+			{in: "https://example.com/hosts", exp: "https://example.com/hosts"},
+			{in: "https://${BLACKLIST_TEST_USER}@example.com/hosts?key=${file:feed.key}", exp: "https://lists@example.com/hosts?key=s3cr3t+key"},
+			{in: "https://example.com/hosts?key=${file:" + dir + "/feed.key}", exp: "https://example.com/hosts?key=s3cr3t+key"},
+			{in: "https://example.com/${BLACKLIST_TEST_MISSING}", err: `environment variable "BLACKLIST_TEST_MISSING" isn't set`},
+			{in: "https://example.com/${file:missing.key}", err: "open " + dir + "/missing.key: no such file or directory"},
+		}
+
+		for _, tt := range tests {
+			act, secrets, err := expand(tt.in)
+			switch tt.err {
+			case "":
+				So(err, ShouldBeNil)
+				So(act, ShouldEqual, tt.exp)
+				So(redact(act, secrets), ShouldEqual, tt.in)
+			default:
+				So(err.Error(), ShouldEqual, tt.err)
+			}
+		}
+
+		_, secrets, _ := expand("${file:feed.key}")
+		So(redact("Get https://example.com/?key=s3cr3t%2Bkey", secrets), ShouldEqual, "Get https://example.com/?key=${file:feed.key}")
+		So(redactErr(errors.New("bad key s3cr3t+key"), secrets).Error(), ShouldEqual, "bad key ${file:feed.key}")
+
+		Convey("Testing secrets in source URLs", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.RawQuery != "key=s3cr3t+key" {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+				w.Write([]byte("ads.example.com\n"))
+			}))
+			defer srv.Close()
+
+			o := getHTTP(&object{Parms: NewConfig(Method("GET")).Parms, name: "feed", url: srv.URL + "/hosts?key=${file:feed.key}"})
+			So(o.err, ShouldBeNil)
+			So(o.final, ShouldEqual, srv.URL+"/hosts?key=${file:feed.key}")
+
+			act, err := ioutil.ReadAll(o.r)
+			So(err, ShouldBeNil)
+			So(string(act), ShouldEqual, "ads.example.com\n")
+
+			o = getHTTP(&object{Parms: NewConfig(Method("GET")).Parms, name: "feed", url: "http://127.0.0.1:1/hosts?key=${file:feed.key}"})
+			So(o.err, ShouldNotBeNil)
+			So(o.err.Error(), ShouldNotContainSubstring, "s3cr3t")
+
+			script, err := (&object{url: "sftp://${BLACKLIST_TEST_USER}@jump.lan/hosts.txt", identity: "/home/${BLACKLIST_TEST_USER}/.ssh/id_ed25519"}).sftpScript("/tmp/dl")
+			So(err, ShouldBeNil)
+			So(script, ShouldEqual, "sftp -q -o BatchMode=yes -i '/home/lists/.ssh/id_ed25519' 'lists@jump.lan:/hosts.txt' '/tmp/dl'")
+		})
+	})
+}
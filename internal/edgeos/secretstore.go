@@ -0,0 +1,186 @@
+package edgeos
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	secretsFile = "blacklist.secrets"
+	secretsKey  = "blacklist.key"
+	// SecretKeyEnv names the environment variable that moves the secret
+	// store's key file out of /config/auth
+	SecretKeyEnv = "BLACKLIST_SECRET_KEY"
+)
+
+// SecretStore is an encrypted file of named secrets, referenced from the
+// configuration as ${secret:name}. It is sealed with AES-256-GCM under a key
+// file that only root can read, so the secrets aren't in config.boot backups;
+// a key kept beside the store is in any backup of /config that holds it
+type SecretStore struct {
+	File string
+	Key  string
+}
+
+// NewSecretStore returns the *SecretStore kept in /config/auth, with its key
+// there too unless SecretKeyEnv names another key file
+func NewSecretStore() *SecretStore {
+	key := os.Getenv(SecretKeyEnv)
+	if key == "" {
+		key = filepath.Join(authDir, secretsKey)
+	}
+	return &SecretStore{File: filepath.Join(authDir, secretsFile), Key: key}
+}
+
+// key returns the store's key, generating it if create is true and there
+// isn't one yet
+func (s *SecretStore) key(create bool) ([]byte, error) {
+	k, err := ioutil.ReadFile(s.Key)
+	switch {
+	case os.IsNotExist(err) && create:
+		k = make([]byte, 32)
+		if _, err = rand.Read(k); err != nil {
+			return nil, err
+		}
+		return k, ioutil.WriteFile(s.Key, k, 0600)
+	case err != nil:
+		return nil, err
+	case len(k) != 32:
+		return nil, fmt.Errorf("%v: key must be 32 bytes", s.Key)
+	}
+	return k, nil
+}
+
+// aead returns the store's cipher
+func (s *SecretStore) aead(create bool) (cipher.AEAD, error) {
+	k, err := s.key(create)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
+
+// load decrypts the store, a missing file has no secrets
+func (s *SecretStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	b, err := ioutil.ReadFile(s.File)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.aead(false)
+	if err != nil {
+		return nil, err
+	}
+
+	n := gcm.NonceSize()
+	if len(b) < n {
+		return nil, fmt.Errorf("%v: file is truncated", s.File)
+	}
+
+	plain, err := gcm.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%v: unable to decrypt, wrong key? %v", s.File, err)
+	}
+	return secrets, json.Unmarshal(plain, &secrets)
+}
+
+// save encrypts secrets and atomically replaces the store
+func (s *SecretStore) save(secrets map[string]string) error {
+	gcm, err := s.aead(true)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+
+	tmp := s.File + ".tmp"
+	if err = ioutil.WriteFile(tmp, gcm.Seal(nonce, nonce, plain, nil), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.File)
+}
+
+// Get returns the named secret
+func (s *SecretStore) Get(name string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %q isn't set", name)
+	}
+	return v, nil
+}
+
+// Set adds or replaces the named secret
+func (s *SecretStore) Set(name, value string) error {
+	if name == "" {
+		return errors.New("secret name can't be empty")
+	}
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+	return s.save(secrets)
+}
+
+// Delete removes the named secret
+func (s *SecretStore) Delete(name string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("secret %q isn't set", name)
+	}
+
+	delete(secrets, name)
+	return s.save(secrets)
+}
+
+// Names returns the sorted names of the stored secrets
+func (s *SecretStore) Names() ([]string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for k := range secrets {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,88 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSecretStore(t *testing.T) {
+	Convey("Testing SecretStore", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		orig := authDir
+		authDir = dir
+		defer func() { authDir = orig }()
+
+		s := NewSecretStore()
+		So(s.File, ShouldEqual, dir+"/"+secretsFile)
+		So(s.Key, ShouldEqual, dir+"/"+secretsKey)
+
+		env := os.Getenv(SecretKeyEnv)
+		os.Setenv(SecretKeyEnv, dir+"/elsewhere.key")
+		So(NewSecretStore().Key, ShouldEqual, dir+"/elsewhere.key")
+		os.Setenv(SecretKeyEnv, env)
+
+		names, err := s.Names()
+		So(err, ShouldBeNil)
+		So(names, ShouldBeEmpty)
+
+		So(s.Set("", "x"), ShouldNotBeNil)
+		So(s.Set("feed", "s3cr3t"), ShouldBeNil)
+		So(s.Set("adguard", "hunter2"), ShouldBeNil)
+
+		names, err = s.Names()
+		So(err, ShouldBeNil)
+		So(names, ShouldResemble, []string{"adguard", "feed"})
+
+		v, err := s.Get("feed")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "s3cr3t")
+
+		_, err = s.Get("missing")
+		So(err.Error(), ShouldEqual, `secret "missing" isn't set`)
+
+		b, err := ioutil.ReadFile(s.File)
+		So(err, ShouldBeNil)
+		So(string(b), ShouldNotContainSubstring, "s3cr3t")
+
+		fi, err := os.Stat(s.Key)
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0600))
+
+		act, secrets, err := expand("https://example.com/?key=${secret:feed}")
+		So(err, ShouldBeNil)
+		So(act, ShouldEqual, "https://example.com/?key=s3cr3t")
+		So(redact(act, secrets), ShouldEqual, "https://example.com/?key=${secret:feed}")
+
+		_, _, err = expand("${secret:missing}")
+		So(err.Error(), ShouldEqual, `secret "missing" isn't set`)
+
+		So(s.Delete("feed"), ShouldBeNil)
+		So(s.Delete("feed"), ShouldNotBeNil)
+		names, err = s.Names()
+		So(err, ShouldBeNil)
+		So(names, ShouldResemble, []string{"adguard"})
+
+		Convey("Testing a wrong key", func() {
+			So(ioutil.WriteFile(s.Key, make([]byte, 32), 0600), ShouldBeNil)
+			_, err := s.Get("adguard")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unable to decrypt")
+
+			So(ioutil.WriteFile(s.Key, []byte("short"), 0600), ShouldBeNil)
+			_, err = s.Get("adguard")
+			So(err.Error(), ShouldEqual, s.Key+": key must be 32 bytes")
+		})
+
+		Convey("Testing a truncated store", func() {
+			So(ioutil.WriteFile(s.File, []byte("abc"), 0600), ShouldBeNil)
+			_, err := s.Get("adguard")
+			So(err.Error(), ShouldEqual, s.File+": file is truncated")
+		})
+	})
+}
@@ -0,0 +1,208 @@
+package edgeos
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNoChecksums is returned by Verify when a release has no checksums
+	ErrNoChecksums = errors.New("release has no checksums asset")
+
+	// ErrReleaseSignature is returned by Verify when a release's checksums
+	// aren't signed by the key
+	ErrReleaseSignature = errors.New("invalid release signature")
+
+	// ErrReleaseKey is returned by Verify without a key, there is no built-in
+	// release key
+	ErrReleaseKey = errors.New("no release public key to verify the checksums with")
+)
+
+// Binary returns the release's program asset for arch, a bare binary or a
+// tar.gz holding one, or nil if it has none; packages and checksums are
+// skipped
+func (r *Release) Binary(arch string) *ReleaseAsset {
+	var bins []ReleaseAsset
+	for _, a := range r.Assets {
+		switch strings.ToLower(path.Ext(a.Name)) {
+		case ".deb", ".sig", ".sha256", ".txt":
+			continue
+		}
+		bins = append(bins, a)
+	}
+	return (&Release{Assets: bins}).Asset(arch)
+}
+
+// checksums returns the release's SHA256 checksums asset, and its detached
+// signature if it has one
+func (r *Release) checksums() (sums, sig *ReleaseAsset) {
+	for i, a := range r.Assets {
+		switch n := strings.ToLower(a.Name); {
+		case strings.HasSuffix(n, ".sig"):
+			continue
+		case strings.Contains(n, "checksums"), strings.Contains(n, "sha256sums"):
+			sums = &r.Assets[i]
+		}
+	}
+	if sums == nil {
+		return nil, nil
+	}
+
+	for i, a := range r.Assets {
+		if a.Name == sums.Name+".sig" {
+			sig = &r.Assets[i]
+		}
+	}
+	return sums, sig
+}
+
+// FetchAsset downloads a release asset
+func (c *Config) FetchAsset(a *ReleaseAsset) ([]byte, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, a.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	req.Header.Set("User-Agent", agent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download %v: %v", a.Name, resp.Status)
+	}
+	return b, nil
+}
+
+// Verify checks b, the downloaded asset a, against the release's SHA256
+// checksums, which must carry a base64 ed25519 signature in a .sig asset by
+// key
+func (c *Config) Verify(r *Release, a *ReleaseAsset, b []byte, key ed25519.PublicKey) error {
+	if key == nil {
+		return ErrReleaseKey
+	}
+
+	sums, sig := r.checksums()
+	if sums == nil {
+		return ErrNoChecksums
+	}
+	if sig == nil {
+		return fmt.Errorf("%v: %v has no signature", ErrReleaseSignature, sums.Name)
+	}
+
+	list, err := c.FetchAsset(sums)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.FetchAsset(sig)
+	if err != nil {
+		return err
+	}
+	d, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(s)))
+	if err != nil || !ed25519.Verify(key, list, d) {
+		return ErrReleaseSignature
+	}
+
+	sum := sha256.Sum256(b)
+	want := hex.EncodeToString(sum[:])
+	sc := bufio.NewScanner(bytes.NewReader(list))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) == 2 && strings.TrimPrefix(f[1], "*") == a.Name {
+			if !strings.EqualFold(f[0], want) {
+				return fmt.Errorf("%v: checksum mismatch", a.Name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: not listed in %v", a.Name, sums.Name)
+}
+
+// Program returns the blacklist program in b, the downloaded asset a,
+// unpacking it from a tar.gz
+func Program(a *ReleaseAsset, b []byte) ([]byte, error) {
+	n := strings.ToLower(a.Name)
+	if !strings.HasSuffix(n, ".tar.gz") && !strings.HasSuffix(n, ".tgz") {
+		return b, nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", a.Name, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		switch {
+		case err == io.EOF:
+			return nil, fmt.Errorf("%v: no blacklist program in archive", a.Name)
+		case err != nil:
+			return nil, fmt.Errorf("%v: %v", a.Name, err)
+		case h.Typeflag == tar.TypeReg && path.Base(h.Name) == "blacklist":
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// ReplaceProgram atomically replaces the program at file with b, the
+// replaced program is kept as file.old
+func ReplaceProgram(file string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	os.Remove(file + ".old")
+	if err = os.Link(file, file+".old"); err != nil && !os.IsNotExist(err) {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package edgeos
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tarGz returns a tar.gz archive holding files
+func tarGz(files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	gz.Close()
+	return b.Bytes()
+}
+
+func TestSelfUpdate(t *testing.T) {
+	Convey("Testing self-update", t, func() {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+		other, _, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+
+		archive := tarGz(map[string]string{"dist/README": "readme", "dist/blacklist": "new program"})
+		sum := sha256.Sum256(archive)
+		sums := fmt.Sprintf("%x  blacklist_1.1.0_mips64.tar.gz\n", sum)
+		assets := map[string][]byte{
+			"blacklist_1.1.0_mips64.tar.gz": archive,
+			"blacklist_1.1.0_mips64.deb":    []byte("package"),
+			"checksums.txt":                 []byte(sums),
+			"checksums.txt.sig":             []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums)))),
+		}
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, ok := assets[r.URL.Path[1:]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(b)
+		}))
+		defer srv.Close()
+
+		rel := &Release{Tag: "v1.1.0"}
+		for _, name := range []string{"blacklist_1.1.0_mips64.deb", "blacklist_1.1.0_mips64.tar.gz", "checksums.txt", "checksums.txt.sig"} {
+			rel.Assets = append(rel.Assets, ReleaseAsset{Name: name, URL: srv.URL + "/" + name})
+		}
+
+		c := NewConfig()
+		a := rel.Binary("mips64")
+		So(a.Name, ShouldEqual, "blacklist_1.1.0_mips64.tar.gz")
+		So(rel.Binary("mipsle"), ShouldBeNil)
+
+		b, err := c.FetchAsset(a)
+		So(err, ShouldBeNil)
+		So(c.Verify(rel, a, b, pub), ShouldBeNil)
+		So(c.Verify(rel, a, b, other), ShouldEqual, ErrReleaseSignature)
+		So(c.Verify(rel, a, b, nil), ShouldEqual, ErrReleaseKey)
+		So(c.Verify(rel, a, []byte("tampered"), pub).Error(), ShouldEqual, "blacklist_1.1.0_mips64.tar.gz: checksum mismatch")
+		So(c.Verify(rel, &ReleaseAsset{Name: "other"}, b, pub).Error(), ShouldEqual, "other: not listed in checksums.txt")
+		So(c.Verify(&Release{Assets: rel.Assets[:2]}, a, b, pub), ShouldEqual, ErrNoChecksums)
+		So(c.Verify(&Release{Assets: rel.Assets[:3]}, a, b, pub).Error(), ShouldEqual, "invalid release signature: checksums.txt has no signature")
+
+		_, err = c.FetchAsset(&ReleaseAsset{Name: "missing", URL: srv.URL + "/missing"})
+		So(err.Error(), ShouldEqual, "unable to download missing: 404 Not Found")
+
+		prog, err := Program(a, b)
+		So(err, ShouldBeNil)
+		So(string(prog), ShouldEqual, "new program")
+
+		prog, err = Program(&ReleaseAsset{Name: "blacklist"}, []byte("bare"))
+		So(err, ShouldBeNil)
+		So(string(prog), ShouldEqual, "bare")
+
+		_, err = Program(&ReleaseAsset{Name: "x.tgz"}, tarGz(map[string]string{"README": "readme"}))
+		So(err.Error(), ShouldEqual, "x.tgz: no blacklist program in archive")
+
+		Convey("replacing the program", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			file := dir + "/blacklist"
+			So(ioutil.WriteFile(file, []byte("old program"), 0755), ShouldBeNil)
+			So(ReplaceProgram(file, prog), ShouldBeNil)
+
+			act, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+			So(string(act), ShouldEqual, "bare")
+
+			fi, err := os.Stat(file)
+			So(err, ShouldBeNil)
+			So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0755))
+
+			act, err = ioutil.ReadFile(file + ".old")
+			So(err, ShouldBeNil)
+			So(string(act), ShouldEqual, "old program")
+
+			So(ReplaceProgram(dir+"/missing/blacklist", prog), ShouldNotBeNil)
+		})
+	})
+}
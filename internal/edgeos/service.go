@@ -0,0 +1,91 @@
+package edgeos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ServiceController reloads the DNS service after the blacklist files change
+type ServiceController interface {
+	Reload(r Runner) ([]byte, error)
+	String() string
+}
+
+// svcCtl is a ServiceController that runs a shell script, and its fallback
+// script if that fails
+type svcCtl struct {
+	name     string
+	script   string
+	fallback string
+}
+
+// Reload implements ServiceController
+func (s *svcCtl) Reload(r Runner) ([]byte, error) {
+	if s.script == "" {
+		return nil, nil
+	}
+
+	b, err := r.CombinedOutput(s.script)
+	if err == nil || s.fallback == "" {
+		return b, err
+	}
+
+	fb, err := r.CombinedOutput(s.fallback)
+	return append(b, fb...), err
+}
+
+func (s *svcCtl) String() string { return s.name }
+
+var (
+	// DNSmasqRestart restarts dnsmasq, this is the EdgeOS default and the
+	// only way dnsmasq loads changed conf-dir files, and so a new blacklist
+	DNSmasqRestart ServiceController = &svcCtl{name: "dnsmasq", script: "service dnsmasq restart"}
+
+	// DNSmasqHUP signals dnsmasq to clear its cache and reread its hosts and
+	// resolv files, falling back to a restart if dnsmasq isn't running or
+	// can't be signalled. SIGHUP doesn't reread the conf-dir's address lines,
+	// so it doesn't apply a new blacklist, only drops cached answers
+	DNSmasqHUP ServiceController = &svcCtl{
+		name:     "dnsmasq-hup",
+		script:   "kill -HUP $(pidof dnsmasq)",
+		fallback: "service dnsmasq restart",
+	}
+
+	// NoReload leaves the DNS service alone
+	NoReload ServiceController = &svcCtl{name: "none"}
+
+	// SystemdResolved restarts systemd-resolved
+	SystemdResolved ServiceController = &svcCtl{name: "systemd-resolved", script: "systemctl restart systemd-resolved"}
+
+	// Unbound reloads unbound using unbound-control
+	Unbound ServiceController = &svcCtl{name: "unbound", script: "unbound-control reload"}
+
+	// serviceControllers maps names to the built-in ServiceControllers
+	serviceControllers = map[string]ServiceController{}
+)
+
+func init() {
+	for _, s := range []ServiceController{DNSmasqRestart, DNSmasqHUP, NoReload, SystemdResolved, Unbound} {
+		serviceControllers[s.String()] = s
+	}
+}
+
+// ServiceControllers returns the sorted names of the built-in ServiceControllers
+func ServiceControllers() []string {
+	var names []string
+	for k := range serviceControllers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewServiceController returns the built-in ServiceController called name
+func NewServiceController(name string) (ServiceController, error) {
+	s, ok := serviceControllers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS service controller %q, valid controllers are: %v", name, strings.Join(ServiceControllers(), ", "))
+	}
+	return s, nil
+}
@@ -0,0 +1,47 @@
+package edgeos
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServiceController(t *testing.T) {
+	Convey("Testing ServiceController", t, func() {
+		So(ServiceControllers(), ShouldResemble, []string{"dnsmasq", "dnsmasq-hup", "none", "systemd-resolved", "unbound"})
+
+		_, err := NewServiceController("bind")
+		So(err, ShouldResemble, errors.New(`unknown DNS service controller "bind", valid controllers are: dnsmasq, dnsmasq-hup, none, systemd-resolved, unbound`))
+
+		tests := []struct {
+			name string
+			exp  []string
+		}{
+			{name: "dnsmasq", exp: []string{"service dnsmasq restart"}},
+			{name: "dnsmasq-hup", exp: []string{"kill -HUP $(pidof dnsmasq)"}},
+			{name: "none", exp: nil},
+			{name: "systemd-resolved", exp: []string{"systemctl restart systemd-resolved"}},
+			{name: "unbound", exp: []string{"unbound-control reload"}},
+		}
+
+		for _, tt := range tests {
+			s, err := NewServiceController(tt.name)
+			So(err, ShouldBeNil)
+			So(s.String(), ShouldEqual, tt.name)
+
+			f := &fakeRunner{}
+			_, err = NewConfig(DNSctl(s), DNSsvc("ignored"), Shell(f)).ReloadDNS()
+			So(err, ShouldBeNil)
+			So(f.scripts, ShouldResemble, tt.exp)
+		}
+
+		Convey("Testing SIGHUP falls back to a restart", func() {
+			f := &fakeRunner{out: []byte("no process\n"), err: errors.New("exit status 1")}
+			act, err := DNSmasqHUP.Reload(f)
+			So(err, ShouldNotBeNil)
+			So(string(act), ShouldEqual, "no process\nno process\n")
+			So(f.scripts, ShouldResemble, []string{"kill -HUP $(pidof dnsmasq)", "service dnsmasq restart"})
+		})
+	})
+}
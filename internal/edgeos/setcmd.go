@@ -0,0 +1,133 @@
+package edgeos
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// cfgWrapper is the EdgeOS/VyOS configuration session helper
+const cfgWrapper = "/opt/vyatta/sbin/vyatta-cfg-cmd-wrapper"
+
+var (
+	// nodeRx matches the EdgeOS configuration node and leaf names commands
+	// may name
+	nodeRx = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	// tagRx matches the source names commands may name
+	tagRx = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// Source describes a blacklist source for configuration write-back
+type Source struct {
+	Desc   string
+	File   string
+	IP     string
+	Name   string
+	Node   string
+	Prefix string
+	URL    string
+}
+
+// path returns the configuration path for a blacklist node, which must be a
+// valid EdgeOS node name as Apply runs the commands through bash
+func (c *Config) path(node string) (string, error) {
+	p := c.Level + " " + rootNode
+	switch {
+	case node == rootNode, node == "":
+		return p, nil
+	case !nodeRx.MatchString(node):
+		return "", fmt.Errorf("invalid node %q", node)
+	}
+	return p + " " + node, nil
+}
+
+// sourcePath returns the configuration path for a node's named source
+func (c *Config) sourcePath(node, name string) (string, error) {
+	p, err := c.path(node)
+	switch {
+	case err != nil:
+		return "", err
+	case !tagRx.MatchString(name):
+		return "", fmt.Errorf("invalid source name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return p + " " + src + " " + quote(name), nil
+}
+
+// quote returns s single quoted for bash and the EdgeOS CLI
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// AddSource returns the set commands that add a blacklist source
+func (c *Config) AddSource(s *Source) ([]string, error) {
+	p, err := c.sourcePath(s.Node, s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	p = "set " + p
+	cmds := []string{}
+	add := func(leaf, value string) {
+		if value != "" {
+			cmds = append(cmds, fmt.Sprintf("%v %v %v", p, leaf, quote(value)))
+		}
+	}
+
+	add("description", s.Desc)
+	add(blackhole, s.IP)
+	add("prefix", s.Prefix)
+	add(files, s.File)
+	add(urls, s.URL)
+	if len(cmds) == 0 {
+		cmds = append(cmds, p)
+	}
+	return cmds, nil
+}
+
+// DeleteSource returns the delete command that removes a blacklist source
+func (c *Config) DeleteSource(node, name string) ([]string, error) {
+	p, err := c.sourcePath(node, name)
+	if err != nil {
+		return nil, err
+	}
+	return []string{"delete " + p}, nil
+}
+
+// AddExclude returns the set commands that exclude domains from a node
+func (c *Config) AddExclude(node string, domains ...string) ([]string, error) {
+	return c.multi("set", "exclude", node, domains)
+}
+
+// DeleteExclude returns the delete commands that remove domain exclusions from a node
+func (c *Config) DeleteExclude(node string, domains ...string) ([]string, error) {
+	return c.multi("delete", "exclude", node, domains)
+}
+
+// multi returns act commands for a multi-valued leaf
+func (c *Config) multi(act, leaf, node string, values []string) ([]string, error) {
+	p, err := c.path(node)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]string, 0, len(values))
+	for _, v := range values {
+		cmds = append(cmds, fmt.Sprintf("%v %v %v %v", act, p, leaf, quote(v)))
+	}
+	return cmds, nil
+}
+
+// Apply runs configuration commands in an EdgeOS configuration session,
+// committing and saving them if they all succeed; a failed command or
+// commit ends the session without saving and fails the script
+func (c *Config) Apply(cmds []string) ([]byte, error) {
+	var s []string
+	s = append(s, cfgWrapper+" begin")
+	for _, cmd := range append(cmds[:len(cmds):len(cmds)], "commit", "save") {
+		s = append(s, cfgWrapper+" "+cmd+" || { "+cfgWrapper+" end; exit 1; }")
+	}
+	s = append(s, cfgWrapper+" end")
+
+	return c.runner().CombinedOutput(strings.Join(s, "\n"))
+}
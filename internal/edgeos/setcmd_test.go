@@ -0,0 +1,106 @@
+package edgeos
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSetCmds(t *testing.T) {
+	Convey("Testing configuration write-back commands", t, func() {
+		c := NewConfig(Level("service dns forwarding"))
+
+		Convey("Testing AddSource()", func() {
+			act, err := c.AddSource(&Source{
+				Desc:   "OpenPhish automatic phishing detection",
+				Name:   "openphish",
+				Node:   hosts,
+				Prefix: "http",
+				URL:    "https://openphish.com/feed.txt",
+			})
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"set service dns forwarding blacklist hosts source 'openphish' description 'OpenPhish automatic phishing detection'",
+				"set service dns forwarding blacklist hosts source 'openphish' prefix 'http'",
+				"set service dns forwarding blacklist hosts source 'openphish' url 'https://openphish.com/feed.txt'",
+			})
+
+			act, err = c.AddSource(&Source{Name: "tasty", Node: hosts, Prefix: "0.0.0.0 ", File: "/config/tasty.txt"})
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"set service dns forwarding blacklist hosts source 'tasty' prefix '0.0.0.0 '",
+				"set service dns forwarding blacklist hosts source 'tasty' file '/config/tasty.txt'",
+			})
+
+			act, err = c.AddSource(&Source{Name: "empty", Node: domains})
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"set service dns forwarding blacklist domains source 'empty'",
+			})
+
+			act, err = c.AddSource(&Source{Name: "x", Node: hosts, URL: "http://x.com/`reboot`$(reboot)\n#!~"})
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"set service dns forwarding blacklist hosts source 'x' url 'http://x.com/`reboot`$(reboot)\n#!~'",
+			})
+		})
+
+		Convey("Testing AddSource() rejects invalid node and source names", func() {
+			tests := []struct {
+				name string
+				node string
+			}{
+				{name: "ok", node: "hosts;reboot"},
+				{name: "ok", node: "$(reboot)"},
+				{name: "ok", node: "Hosts"},
+				{name: "`reboot`", node: hosts},
+				{name: "a b", node: hosts},
+				{name: "-x", node: hosts},
+				{name: "", node: hosts},
+			}
+
+			for _, tt := range tests {
+				act, err := c.AddSource(&Source{Name: tt.name, Node: tt.node, URL: "http://x.com"})
+				So(err, ShouldNotBeNil)
+				So(act, ShouldBeNil)
+			}
+		})
+
+		Convey("Testing DeleteSource()", func() {
+			act, err := c.DeleteSource(hosts, "openphish")
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"delete service dns forwarding blacklist hosts source 'openphish'",
+			})
+
+			_, err = c.DeleteSource(hosts, "x'; reboot; '")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Testing AddExclude() and DeleteExclude()", func() {
+			act, err := c.AddExclude(rootNode, "apple.com", "it's.bad")
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"set service dns forwarding blacklist exclude 'apple.com'",
+				`set service dns forwarding blacklist exclude 'it'\''s.bad'`,
+			})
+
+			act, err = c.DeleteExclude(domains, "apple.com")
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{
+				"delete service dns forwarding blacklist domains exclude 'apple.com'",
+			})
+
+			_, err = c.AddExclude("domains exclude x; reboot", "apple.com")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Testing Apply() off-router", func() {
+			c.SetOpt(Bash("/bin/bash"))
+			cmds, err := c.AddExclude(rootNode, "apple.com")
+			So(err, ShouldBeNil)
+			_, err = c.Apply(cmds)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// identity is the source leaf naming the SSH private key used for sftp:// urls
+	identity   = "identity"
+	schemeSFTP = "sftp://"
+)
+
+// isSFTP returns true if u is an sftp:// source URL
+func isSFTP(u string) bool {
+	return strings.HasPrefix(strings.ToLower(u), schemeSFTP)
+}
+
+// sftpScript returns the shell script that downloads the source's sftp:// url
+// to file, authentication is key based so sftp runs in batch mode
+func (o *object) sftpScript(file string) (string, error) {
+	raw, secrets, err := expand(o.url)
+	if err != nil {
+		return "", err
+	}
+	o.secrets = append(o.secrets, secrets...)
+	o.secrets = append(o.secrets, o.credentials(raw)...)
+
+	identity, secrets, err := expand(o.identity)
+	if err != nil {
+		return "", err
+	}
+	o.secrets = append(o.secrets, secrets...)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Hostname() == "" || u.Path == "" || u.Path == "/" {
+		return "", fmt.Errorf("sftp URL %q must be of the form sftp://[user@]host[:port]/path", o.url)
+	}
+
+	// sftp://host/~/path is relative to the user's home directory
+	path := u.Path
+	if strings.HasPrefix(path, "/~/") {
+		path = path[len("/~/"):]
+	}
+
+	host := u.Hostname()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if u.User != nil && u.User.Username() != "" {
+		host = u.User.Username() + "@" + host
+	}
+
+	args := []string{"sftp", "-q", "-o", "BatchMode=yes"}
+	if identity != "" {
+		args = append(args, "-i", quote(identity))
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-P", port)
+	}
+	args = append(args, quote(host+":"+path), quote(file))
+
+	return strings.Join(args, " "), nil
+}
+
+// getSFTP downloads a sftp:// source using the system's sftp client
+func getSFTP(o *object) *object {
+	fail := func(msg string, err error) *object {
+		o.r, o.err = strings.NewReader(fmt.Sprintf("%s %s...", msg, redact(o.url, o.secrets))), err
+		return o
+	}
+
+	if o.via == viaTor {
+		return fail("Unable to fetch", fmt.Errorf("%v: sftp sources can't be fetched via %v", o.url, viaTor))
+	}
+
+	// a spilled download stays where it was fetched to, see spillDir
+	dir := ""
+	if o.spill {
+		dir = o.spillDir()
+		os.MkdirAll(dir, 0755)
+	}
+
+	f, err := ioutil.TempFile(dir, "blacklist-sftp")
+	if err != nil {
+		return fail("Unable to create download file for", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	script, err := o.sftpScript(f.Name())
+	if err != nil {
+		return fail("Unable to form request for", err)
+	}
+
+	if out, err := o.runner().CombinedOutput(script); err != nil {
+		return fail("Unable to get response for", fmt.Errorf("%v: %v: %s", o.url, err, strings.TrimSpace(string(out))))
+	}
+
+	if f, err = os.Open(f.Name()); err != nil {
+		return fail("Unable to read download for", err)
+	}
+
+	size := int64(-1)
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+
+	if o.spill {
+		switch limit := o.maxSize(); {
+		case limit > 0 && size > limit:
+			f.Close()
+			return fail("Download exceeded max-size for", fmt.Errorf("%v: %v", o.url, errMaxSize(limit)))
+		case size == 0:
+			f.Close()
+			return fail("No data returned for", nil)
+		}
+
+		o.final = o.url
+		o.r, o.err = &spillReader{f}, nil
+		return o
+	}
+	defer f.Close()
+
+	body, err := readLimited(o.newProgressReader(f, size), o.maxSize())
+	if err != nil {
+		return fail("Download exceeded max-size for", fmt.Errorf("%v: %v", o.url, err))
+	}
+
+	if len(body) == 0 {
+		return fail("No data returned for", nil)
+	}
+
+	o.final = o.url
+	o.r, o.err = bytes.NewBuffer(body), nil
+	return o
+}
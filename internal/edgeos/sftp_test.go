@@ -0,0 +1,136 @@
+package edgeos
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// sftpRunner fakes sftp by writing data to the script's destination file
+type sftpRunner struct {
+	fakeRunner
+	data string
+}
+
+func (s *sftpRunner) CombinedOutput(script string) ([]byte, error) {
+	out, err := s.fakeRunner.CombinedOutput(script)
+	if err == nil {
+		args := strings.Fields(script)
+		err = ioutil.WriteFile(strings.Trim(args[len(args)-1], "'"), []byte(s.data), 0644)
+	}
+	return out, err
+}
+
+func TestSFTPScript(t *testing.T) {
+	Convey("Testing sftpScript()", t, func() {
+		tests := []struct {
+			name     string
+			url      string
+			identity string
+			exp      string
+			err      error
+		}{
+			{
+				name: "absolute path",
+				url:  "sftp://lists@jump.lan/srv/lists/hosts.txt",
+				exp:  "sftp -q -o BatchMode=yes 'lists@jump.lan:/srv/lists/hosts.txt' '/tmp/dl'",
+			},
+			{
+				name:     "home path with identity and port",
+				url:      "sftp://lists@jump.lan:2222/~/hosts.txt",
+				identity: "/config/auth/blacklist key",
+				exp:      "sftp -q -o BatchMode=yes -i '/config/auth/blacklist key' -P 2222 'lists@jump.lan:hosts.txt' '/tmp/dl'",
+			},
+			{
+				name: "ipv6 host",
+				url:  "sftp://[fd00::1]/hosts.txt",
+				exp:  "sftp -q -o BatchMode=yes '[fd00::1]:/hosts.txt' '/tmp/dl'",
+			},
+			{
+				name: "missing path",
+				url:  "sftp://jump.lan/",
+				err:  errors.New(`sftp URL "sftp://jump.lan/" must be of the form sftp://[user@]host[:port]/path`),
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.name, func() {
+				act, err := (&object{url: tt.url, identity: tt.identity}).sftpScript("/tmp/dl")
+				switch tt.err {
+				case nil:
+					So(err, ShouldBeNil)
+				default:
+					So(err.Error(), ShouldEqual, tt.err.Error())
+				}
+				So(act, ShouldEqual, tt.exp)
+			})
+		}
+	})
+}
+
+func TestGetSFTP(t *testing.T) {
+	Convey("Testing getHTTP() with sftp sources", t, func() {
+		tests := []struct {
+			name string
+			r    *sftpRunner
+			via  string
+			exp  string
+			err  error
+		}{
+			{name: "download", r: &sftpRunner{data: "0.0.0.0 ads.example.com\n"}, exp: "0.0.0.0 ads.example.com\n"},
+			{
+				name: "sftp failure",
+				r:    &sftpRunner{fakeRunner: fakeRunner{out: []byte("Permission denied (publickey).\n"), err: errors.New("exit status 255")}},
+				exp:  "Unable to get response for sftp://jump.lan/hosts.txt...",
+				err:  errors.New("sftp://jump.lan/hosts.txt: exit status 255: Permission denied (publickey)."),
+			},
+			{name: "empty", r: &sftpRunner{}, exp: "No data returned for sftp://jump.lan/hosts.txt..."},
+			{
+				name: "via tor",
+				r:    &sftpRunner{},
+				via:  viaTor,
+				exp:  "Unable to fetch sftp://jump.lan/hosts.txt...",
+				err:  fmt.Errorf("sftp://jump.lan/hosts.txt: sftp sources can't be fetched via tor"),
+			},
+		}
+
+		for _, tt := range tests {
+			Convey("with "+tt.name, func() {
+				o := getHTTP(&object{Parms: &Parms{Runner: tt.r}, name: "jump", url: "sftp://jump.lan/hosts.txt", via: tt.via})
+				switch tt.err {
+				case nil:
+					So(o.err, ShouldBeNil)
+				default:
+					So(o.err.Error(), ShouldEqual, tt.err.Error())
+				}
+
+				b, err := ioutil.ReadAll(o.r)
+				So(err, ShouldBeNil)
+				So(string(b), ShouldEqual, tt.exp)
+			})
+		}
+
+		Convey("a spilled download is read from where it was fetched to", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			o := getHTTP(&object{Parms: &Parms{Cache: dir, Runner: &sftpRunner{data: "0.0.0.0 ads.example.com\n"}}, name: "jump", spill: true, url: "sftp://jump.lan/hosts.txt"})
+			So(o.err, ShouldBeNil)
+			So(o.r, ShouldHaveSameTypeAs, &spillReader{})
+
+			b, err := ioutil.ReadAll(o.r)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "0.0.0.0 ads.example.com\n")
+
+			files, err := ioutil.ReadDir(dir)
+			So(err, ShouldBeNil)
+			So(files, ShouldBeEmpty)
+		})
+	})
+}
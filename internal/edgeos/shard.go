@@ -0,0 +1,166 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shardRx matches a shard's index inserted before its file's final extension
+var shardRx = regexp.MustCompile(`\.[0-9]{3,}(\.[^./]+)$`)
+
+// shardFile returns the name of file's i'th shard, e.g. blacklist.000.conf
+func shardFile(file string, i int) string {
+	ext := filepath.Ext(file)
+	return fmt.Sprintf("%v.%03d%v", strings.TrimSuffix(file, ext), i, ext)
+}
+
+// shardPattern returns a globbing pattern matching the shards of pattern
+func shardPattern(pattern string) string {
+	ext := filepath.Ext(pattern)
+	return strings.TrimSuffix(pattern, ext) + ".[0-9][0-9][0-9]*" + ext
+}
+
+// shardOf returns the unsharded file name of a shard and true, or file and
+// false if it isn't one
+func shardOf(file string) (string, bool) {
+	if !shardRx.MatchString(file) {
+		return file, false
+	}
+	return shardRx.ReplaceAllString(file, "$1"), true
+}
+
+// pattern returns the globbing pattern matching the generated files in dir
+func (p *Parms) pattern(dir string) string {
+	return fmt.Sprintf(p.FnFmt, dir, p.Wildcard.Node, p.Wildcard.Name, p.Ext)
+}
+
+// isGenerated is true if name is the base name of a generated file or one
+// of its shards, optionally ending in one of suffixes
+func (p *Parms) isGenerated(name string, suffixes ...string) bool {
+	if name != filepath.Base(name) {
+		return false
+	}
+
+	pattern := filepath.Base(p.pattern(""))
+	for _, sfx := range append([]string{""}, suffixes...) {
+		if !strings.HasSuffix(name, sfx) {
+			continue
+		}
+
+		f, _ := shardOf(strings.TrimSuffix(name, sfx))
+		if ok, _ := filepath.Match(pattern, f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// globFiles returns the generated blacklist files in dir ending in suffix,
+// including any shards
+func (p *Parms) globFiles(dir, suffix string) ([]string, error) {
+	pattern := p.pattern(dir)
+	names, err := filepath.Glob(pattern + suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	shards, err := filepath.Glob(shardPattern(pattern) + suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range shards {
+		if _, ok := shardOf(strings.TrimSuffix(f, suffix)); ok {
+			names = append(names, f)
+		}
+	}
+	return names, nil
+}
+
+// writeShards saves hosts/domains data to disk split into files of at most
+// b.shard lines, then removes any of the file's higher numbered shards left
+// by an earlier run with more entries
+func (b *bList) writeShards() error {
+	var (
+		r   = bufio.NewReader(b.r)
+		i   int
+		eof bool
+	)
+
+	for !eof {
+		var (
+			chunk bytes.Buffer
+			n     int
+		)
+
+		for n < b.shard {
+			line, err := r.ReadBytes('\n')
+			chunk.Write(line)
+			if len(line) > 0 {
+				n++
+			}
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if n == 0 && i > 0 {
+			break
+		}
+
+		if err := (&bList{count: b.count, file: shardFile(b.file, i), gz: b.gz, key: b.key, r: &chunk}).writeFile(); err != nil {
+			return err
+		}
+		i++
+	}
+
+	shards, err := filepath.Glob(shardPattern(b.file) + "*")
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, f := range shards {
+		name := strings.TrimSuffix(strings.TrimSuffix(f, gzExt), countExt)
+		if _, ok := shardOf(name); !ok {
+			continue
+		}
+
+		var idx int
+		if _, err = fmt.Sscanf(name[len(strings.TrimSuffix(b.file, filepath.Ext(b.file)))+1:], "%d", &idx); err == nil && idx >= i {
+			stale = append(stale, f)
+		}
+	}
+	return purgeFiles(stale)
+}
+
+// current returns the files in files that belong to the CFile's sources,
+// when sharding they are the shards of its unsharded names
+func (c *CFile) current(files []string) []string {
+	if c.Shard == 0 {
+		return c.names
+	}
+
+	var (
+		cur   []string
+		names = updateEntry(c.names)
+	)
+
+	for _, f := range files {
+		if base, ok := shardOf(f); ok {
+			if _, ok = names.entry[base]; ok {
+				cur = append(cur, f)
+			}
+		}
+	}
+	return cur
+}
@@ -0,0 +1,94 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShardFile(t *testing.T) {
+	Convey("Testing shardFile() and shardOf()", t, func() {
+		tests := []struct {
+			file  string
+			i     int
+			shard string
+		}{
+			{file: "/tmp/domains.zeus.blacklist.conf", i: 0, shard: "/tmp/domains.zeus.blacklist.000.conf"},
+			{file: "/tmp/hosts.ads.blacklist.conf", i: 12, shard: "/tmp/hosts.ads.blacklist.012.conf"},
+			{file: "/tmp/hosts.ads.blacklist.conf", i: 1234, shard: "/tmp/hosts.ads.blacklist.1234.conf"},
+		}
+
+		for _, tt := range tests {
+			So(shardFile(tt.file, tt.i), ShouldEqual, tt.shard)
+			base, ok := shardOf(tt.shard)
+			So(ok, ShouldBeTrue)
+			So(base, ShouldEqual, tt.file)
+		}
+
+		_, ok := shardOf("/tmp/hosts.list.123.blacklist.conf")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestWriteShards(t *testing.T) {
+	Convey("Testing sharded output files", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		files := func() []string {
+			names, err := filepath.Glob(dir + "/*")
+			So(err, ShouldBeNil)
+			for i := range names {
+				names[i] = filepath.Base(names[i])
+			}
+			sort.Strings(names)
+			return names
+		}
+
+		file := dir + "/domains.zeus.blacklist.conf"
+		b := &bList{file: file, shard: 2, r: strings.NewReader("a\nb\nc\nd\ne\n")}
+		So(b.writeFile(), ShouldBeNil)
+		So(files(), ShouldResemble, []string{"domains.zeus.blacklist.000.conf", "domains.zeus.blacklist.001.conf", "domains.zeus.blacklist.002.conf"})
+
+		act, err := ioutil.ReadFile(dir + "/domains.zeus.blacklist.002.conf")
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "e\n")
+
+		Convey("Fewer entries remove the surplus shards", func() {
+			b = &bList{file: file, shard: 2, gz: true, r: strings.NewReader("a\nb\n")}
+			So(b.writeFile(), ShouldBeNil)
+			So(files(), ShouldResemble, []string{"domains.zeus.blacklist.000.conf", "domains.zeus.blacklist.000.conf.gz"})
+		})
+
+		Convey("An empty list writes an empty first shard", func() {
+			b = &bList{file: file, shard: 2, r: strings.NewReader("")}
+			So(b.writeFile(), ShouldBeNil)
+			So(files(), ShouldResemble, []string{"domains.zeus.blacklist.000.conf"})
+		})
+
+		Convey("Testing CFile.Remove() with shards", func() {
+			for _, f := range []string{"domains.zeus.blacklist.conf", "hosts.gone.blacklist.000.conf", "hosts.gone.blacklist.conf"} {
+				So(ioutil.WriteFile(dir+"/"+f, nil, 0644), ShouldBeNil)
+			}
+
+			p := &Parms{Dir: dir, Ext: "blacklist.conf", FnFmt: "%v/%v.%v.%v", Shard: 2, Wildcard: Wildcard{Node: "*s", Name: "*"}}
+			c := &CFile{Parms: p, names: []string{file}}
+			So(c.Remove(), ShouldBeNil)
+			So(files(), ShouldResemble, []string{"domains.zeus.blacklist.000.conf", "domains.zeus.blacklist.001.conf", "domains.zeus.blacklist.002.conf"})
+
+			names, err := p.globFiles(dir, "")
+			So(err, ShouldBeNil)
+			So(names, ShouldHaveLength, 3)
+
+			p.Shard = 0
+			So(c.Remove(), ShouldBeNil)
+			So(files(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package edgeos
+
+import (
+	"bytes"
+	"net"
+	"strings"
+)
+
+// parked entry policies set per source with the parked leaf, parked entries
+// map a domain to an address that isn't one of the source's sinkholes
+const (
+	parkedConvert = "convert"
+	parkedSkip    = "skip"
+)
+
+// sinkhole checks a hosts format line's address against the source's
+// sinkhole prefixes and returns the line without it; ok is false if the
+// entry is parked and should be skipped. Lines are returned unchanged if the
+// source has no sinkholes configured.
+func (o *object) sinkhole(line []byte) (rest []byte, ok bool) {
+	if len(o.sinkholes) == 0 {
+		return line, true
+	}
+
+	fields := bytes.Fields(line)
+	if len(fields) < 2 || net.ParseIP(string(fields[0])) == nil {
+		return line, true
+	}
+
+	rest = bytes.TrimSpace(line[len(fields[0]):])
+	for _, p := range o.sinkholes {
+		if strings.HasPrefix(string(fields[0]), p) {
+			return rest, true
+		}
+	}
+	return rest, o.parked == parkedConvert
+}
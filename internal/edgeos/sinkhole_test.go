@@ -0,0 +1,80 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSinkhole(t *testing.T) {
+	Convey("Testing sinkhole prefixes for hosts format sources", t, func() {
+		data := `# mixed hosts file
+0.0.0.0 ads.example.com
+127.0.0.1 tracker.example.com
+::1 ipv6.example.com
+203.0.113.9 pixel.example.com
+198.51.100.1 cdn.example.net
+0.0.0.0
+plain.example.org
+`
+		tests := []struct {
+			name      string
+			sinkholes []string
+			parked    string
+			exp       string
+		}{
+			{
+				name:      "no sinkholes configured",
+				sinkholes: nil,
+				exp:       "address=/ads.example.com/0.0.0.0\n",
+			},
+			{
+				name:      "parked entries skipped",
+				sinkholes: []string{"0.0.0.0", "127.", "::1"},
+				exp:       "address=/ads.example.com/0.0.0.0\naddress=/ipv6.example.com/0.0.0.0\naddress=/plain.example.org/0.0.0.0\naddress=/tracker.example.com/0.0.0.0\n",
+			},
+			{
+				name:      "parked entries converted",
+				sinkholes: []string{"0.0.0.0"},
+				parked:    parkedConvert,
+				exp:       "address=/ads.example.com/0.0.0.0\naddress=/cdn.example.net/0.0.0.0\naddress=/ipv6.example.com/0.0.0.0\naddress=/pixel.example.com/0.0.0.0\naddress=/plain.example.org/0.0.0.0\naddress=/tracker.example.com/0.0.0.0\n",
+			},
+		}
+
+		for _, tt := range tests {
+			Convey(tt.name, func() {
+				o := &object{
+					Parms:     NewConfig(FileNameFmt("%v/%v.%v.%v"), Prefix("address=")).Parms,
+					ip:        "0.0.0.0",
+					name:      "mixed",
+					nType:     host,
+					parked:    tt.parked,
+					prefix:    "0.0.0.0 ",
+					r:         strings.NewReader(data),
+					sinkholes: tt.sinkholes,
+				}
+
+				b, err := ioutil.ReadAll(o.process().r)
+				So(err, ShouldBeNil)
+				act := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+				exp := strings.Split(strings.TrimSuffix(tt.exp, "\n"), "\n")
+				So(act, ShouldResemble, exp)
+			})
+		}
+
+		Convey("Testing sinkhole and parked configuration", func() {
+			cfg := "blacklist {\n\thosts {\n\t\tsource zeus {\n\t\t\tparked %v\n\t\t\tsinkhole 0.0.0.0\n\t\t\tsinkhole 127.\n\t\t\turl http://zeus.com\n\t\t}\n\t}\n}"
+
+			c := NewConfig()
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, parkedConvert)}), ShouldBeNil)
+			So(c.Get(hosts).x[0].parked, ShouldEqual, parkedConvert)
+			So(c.Get(hosts).x[0].sinkholes, ShouldResemble, []string{"0.0.0.0", "127."})
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "keep")})
+			So(err.Error(), ShouldEqual, `config.boot:4: source "zeus" has unknown parked policy "keep"`)
+		})
+	})
+}
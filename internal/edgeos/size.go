@@ -0,0 +1,94 @@
+package edgeos
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps max-size suffixes to their multipliers
+var sizeUnits = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+}
+
+// ParseSize parses a byte count with an optional k, m or g suffix, e.g. 20M
+func ParseSize(s string) (int64, error) {
+	var (
+		t    = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "b")
+		unit string
+	)
+
+	if t != "" && strings.ContainsAny(t[len(t)-1:], "kmg") {
+		t, unit = t[:len(t)-1], t[len(t)-1:]
+	}
+
+	n, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if m := sizeUnits[unit]; n > math.MaxInt64/m {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return n * sizeUnits[unit], nil
+}
+
+// formatSize formats a byte count with the largest k, m or g suffix it has at
+// least one of, e.g. 1.5M
+func formatSize(n uint64) string {
+	for _, unit := range []string{"g", "m", "k"} {
+		if m := uint64(sizeUnits[unit]); n >= m {
+			return strconv.FormatFloat(float64(n)/float64(m), 'f', 1, 64) + strings.ToUpper(unit)
+		}
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// maxSize returns the source's size limit, the global MaxSize if it doesn't
+// have one, 0 means unlimited
+func (o *object) maxSize() int64 {
+	if o.maxsize > 0 {
+		return o.maxsize
+	}
+	return o.MaxSize
+}
+
+// errMaxSize is returned by readLimited when its limit is exceeded
+type errMaxSize int64
+
+func (e errMaxSize) Error() string {
+	return fmt.Sprintf("exceeds max-size of %d bytes", int64(e))
+}
+
+// readLimited reads r, failing once more than limit bytes have been read
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(r)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(b)) > limit {
+		return b[:limit], errMaxSize(limit)
+	}
+	return b, err
+}
+
+// checkFile fails file sources that are larger than their size limit
+func (o *object) checkFile() error {
+	limit := o.maxSize()
+	if limit <= 0 {
+		return nil
+	}
+
+	fi, err := os.Stat(o.path())
+	if err != nil || fi.Size() <= limit {
+		return nil
+	}
+	return fmt.Errorf("%v: %d bytes exceeds max-size of %d bytes", o.path(), fi.Size(), limit)
+}
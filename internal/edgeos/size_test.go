@@ -0,0 +1,123 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseSize(t *testing.T) {
+	Convey("Testing ParseSize()", t, func() {
+		tests := []struct {
+			s   string
+			exp int64
+			err bool
+		}{
+			{s: "512", exp: 512},
+			{s: "64k", exp: 64 << 10},
+			{s: "20M", exp: 20 << 20},
+			{s: "1gb", exp: 1 << 30},
+			{s: "", err: true},
+			{s: "-1", err: true},
+			{s: "lots", err: true},
+			{s: "8589934591G", exp: 8589934591 << 30},
+			{s: "8589934592G", err: true},
+			{s: "9223372036854775807", exp: 9223372036854775807},
+		}
+
+		for _, tt := range tests {
+			act, err := ParseSize(tt.s)
+			So(act, ShouldEqual, tt.exp)
+			So(err != nil, ShouldEqual, tt.err)
+		}
+	})
+}
+
+func TestFormatSize(t *testing.T) {
+	Convey("Testing formatSize()", t, func() {
+		tests := []struct {
+			n   uint64
+			exp string
+		}{
+			{n: 512, exp: "512"},
+			{n: 64 << 10, exp: "64.0K"},
+			{n: 3 << 19, exp: "1.5M"},
+			{n: 1 << 30, exp: "1.0G"},
+		}
+
+		for _, tt := range tests {
+			So(formatSize(tt.n), ShouldEqual, tt.exp)
+		}
+	})
+}
+
+func TestMaxSize(t *testing.T) {
+	Convey("Testing max-size limits", t, func() {
+		data := strings.Repeat("ads.zeus.com\n", 100)
+		mux := http.NewServeMux()
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		mux.HandleFunc("/sized", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			fmt.Fprint(w, data)
+		})
+		mux.HandleFunc("/streamed", func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, data)
+		})
+
+		tests := []struct {
+			path    string
+			global  int64
+			maxsize int64
+			ok      bool
+		}{
+			{path: "/sized", ok: true},
+			{path: "/sized", global: 100},
+			{path: "/sized", global: 100, maxsize: 2048, ok: true},
+			{path: "/streamed", maxsize: 100},
+			{path: "/streamed", global: int64(len(data)), ok: true},
+		}
+
+		for _, tt := range tests {
+			o := getHTTP(&object{
+				Parms:   &Parms{Method: "GET", MaxSize: tt.global},
+				maxsize: tt.maxsize,
+				url:     srv.URL + tt.path,
+			})
+			So(o.err == nil, ShouldEqual, tt.ok)
+		}
+
+		Convey("Testing file source limits", func() {
+			f, err := ioutil.TempFile("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.Remove(f.Name())
+			f.WriteString(data)
+			f.Close()
+
+			o := &object{Parms: &Parms{MaxSize: 100}, file: f.Name()}
+			So(o.checkFile(), ShouldNotBeNil)
+
+			o.maxsize = 1 << 20
+			So(o.checkFile(), ShouldBeNil)
+		})
+
+		Convey("Testing max-size configuration", func() {
+			cfg := "blacklist {\n\thosts {\n\t\tsource zeus {\n\t\t\tmax-size %v\n\t\t\turl http://zeus.com\n\t\t}\n\t}\n}"
+
+			c := NewConfig()
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "20M")}), ShouldBeNil)
+			So(c.Get(hosts).x[0].maxsize, ShouldEqual, 20<<20)
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "huge")})
+			So(err.Error(), ShouldEqual, `config.boot:4: source "zeus" has invalid size "huge"`)
+		})
+	})
+}
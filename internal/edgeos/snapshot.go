@@ -0,0 +1,65 @@
+package edgeos
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+)
+
+// CFGjson loads a configuration from a JSON snapshot written by
+// Config.MarshalJSON, e.g. by the snapshot command, so a configuration
+// captured on one router can be replayed without EdgeOS. Cfg holds the
+// snapshot itself, if it is empty the snapshot is read from File
+type CFGjson struct {
+	*Config
+	Cfg  string
+	File string
+}
+
+// read returns the JSON snapshot io.Reader
+func (c *CFGjson) read() io.Reader {
+	if c.Cfg != "" || c.File == "" {
+		return bytes.NewBufferString(c.Cfg)
+	}
+
+	b, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		log.Print(err)
+	}
+	return bytes.NewReader(b)
+}
+
+// name returns the snapshot's name for error messages
+func (c *CFGjson) name() string {
+	if c.File != "" {
+		return c.File
+	}
+	return "snapshot"
+}
+
+// readSnapshot replaces c's configuration with the snapshot's nodes, hooks,
+// instances, profiles, targets and transform. The snapshot's other parms are
+// the capturing host's settings, so c keeps its own
+func (c *Config) readSnapshot(r *CFGjson) error {
+	b, err := ioutil.ReadAll(r.read())
+	if err != nil {
+		return err
+	}
+
+	n := NewConfig()
+	if err = json.Unmarshal(b, n); err != nil {
+		return fmt.Errorf("%v: %v", r.name(), err)
+	}
+
+	if len(n.tree) < 1 {
+		return ErrConfigEmpty
+	}
+
+	c.tree, c.hooks, c.instances, c.profiles, c.targets = n.tree, n.hooks, n.instances, n.profiles, n.targets
+	c.groups = n.groups
+	c.Xform = n.Xform
+	return nil
+}
@@ -0,0 +1,59 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadSnapshot(t *testing.T) {
+	Convey("Testing ReadCfg() with a JSON snapshot", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		newCfg := func() *Config {
+			return NewConfig(
+				Dir(dir),
+				Ext("blacklist.conf"),
+				Nodes([]string{rootNode, domains, hosts}),
+				LTypes([]string{files, PreDomns, PreHosts, urls}),
+			)
+		}
+
+		c := newCfg()
+		So(c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+		c.SetOpt(Dir("/etc/dnsmasq.d"), Transform("/config/scripts/policy.lua"))
+
+		b, err := json.Marshal(c)
+		So(err, ShouldBeNil)
+
+		file := dir + "/snapshot.json"
+		So(ioutil.WriteFile(file, b, 0644), ShouldBeNil)
+
+		for _, r := range []*CFGjson{{Cfg: string(b)}, {File: file}} {
+			act := newCfg()
+			So(act.ReadCfg(r), ShouldBeNil)
+			So(act.String(), ShouldEqual, c.String())
+			So(act.Xform, ShouldEqual, "/config/scripts/policy.lua")
+			So(act.Dir, ShouldEqual, dir)
+		}
+
+		tests := []struct {
+			r   *CFGjson
+			err string
+		}{
+			{r: &CFGjson{Cfg: "{}"}, err: ErrConfigEmpty.Error()},
+			{r: &CFGjson{Cfg: `{"nodes": 1}`}, err: "snapshot: json: cannot unmarshal number into Go struct field configJSON.nodes of type map[string]*edgeos.object"},
+			{r: &CFGjson{File: dir + "/missing.json"}, err: dir + "/missing.json: unexpected end of JSON input"},
+		}
+
+		for _, tt := range tests {
+			So(newCfg().ReadCfg(tt.r).Error(), ShouldEqual, tt.err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoStaleFile is returned if StaleDays is set without a StaleFile
+var ErrNoStaleFile = errors.New("stale-days needs a stale file to remember when sources last changed")
+
+// freshness records a source's content hash and when it last changed and was
+// last fetched, as Unix times
+type freshness struct {
+	Hash    string `json:"hash"`
+	Changed int64  `json:"changed"`
+	Fetched int64  `json:"fetched"`
+}
+
+// freshDB records each fetched source's freshness, keyed by node and name
+type freshDB struct {
+	sync.Mutex
+	file   string
+	source map[string]freshness
+}
+
+// StaleSource is a source whose content hasn't changed for StaleDays
+type StaleSource struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Changed time.Time `json:"changed"`
+	Days    int       `json:"days"`
+}
+
+func (s StaleSource) String() string {
+	return fmt.Sprintf("%v/%v hasn't changed in %d days, since %v", s.Type, s.Name, s.Days, s.Changed.Format("2006-01-02"))
+}
+
+// loadFresh reads the stale file, a missing file is an empty one
+func (p *Parms) loadFresh() error {
+	if p.Stale <= 0 || p.fresh != nil {
+		return nil
+	}
+	if p.StaleDB == "" {
+		return ErrNoStaleFile
+	}
+
+	f := &freshDB{file: p.StaleDB, source: make(map[string]freshness)}
+	b, err := ioutil.ReadFile(p.StaleDB)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		if err = json.Unmarshal(b, &f.source); err != nil {
+			return err
+		}
+	}
+
+	p.fresh = f
+	return nil
+}
+
+// contentHash returns a hash of a source's entries that doesn't depend on
+// the order they were listed in
+func contentHash(add list) string {
+	var sum uint64
+	add.RLock()
+	for k := range add.entry {
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		sum ^= h.Sum64()
+	}
+	add.RUnlock()
+	return fmt.Sprintf("%016x", sum)
+}
+
+// freshen records o's entries as fetched now, and as changed if their hash
+// differs from the last fetch's
+func (o *object) freshen(add list) {
+	if o.fresh == nil || o.err != nil || o.nType.isExc() || (o.ltype != files && o.ltype != urls) {
+		return
+	}
+
+	var (
+		hash = contentHash(add)
+		now  = seenNow().Unix()
+	)
+
+	o.fresh.Lock()
+	defer o.fresh.Unlock()
+
+	f, ok := o.fresh.source[o.key()]
+	if !ok || f.Hash != hash {
+		f.Hash, f.Changed = hash, now
+	}
+	f.Fetched = now
+	o.fresh.source[o.key()] = f
+}
+
+// save writes the stale file, forgetting the sources that haven't been
+// fetched for seenTTL, e.g. because they were removed
+func (f *freshDB) save() error {
+	if f == nil {
+		return nil
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	old := seenNow().Add(-seenTTL).Unix()
+	for k, s := range f.source {
+		if s.Fetched < old {
+			delete(f.source, k)
+		}
+	}
+
+	b, err := json.Marshal(f.source)
+	if err != nil {
+		return err
+	}
+
+	tmp := f.file + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.file)
+}
+
+// StaleSources returns the sources whose content hasn't changed for
+// StaleDays, likely abandoned feeds worth pruning
+func (c *Config) StaleSources() []StaleSource {
+	if c.fresh == nil {
+		return nil
+	}
+
+	c.fresh.Lock()
+	defer c.fresh.Unlock()
+
+	var (
+		stale []StaleSource
+		now   = seenNow()
+	)
+	for _, o := range c.GetAll(files, urls).x {
+		f, ok := c.fresh.source[o.key()]
+		if !ok {
+			continue
+		}
+
+		changed := time.Unix(f.Changed, 0)
+		if days := int(now.Sub(changed) / (24 * time.Hour)); days >= c.Stale {
+			stale = append(stale, StaleSource{Name: o.name, Type: getType(o.nType).(string), Changed: changed, Days: days})
+		}
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		if stale[i].Type != stale[j].Type {
+			return stale[i].Type < stale[j].Type
+		}
+		return stale[i].Name < stale[j].Name
+	})
+	return stale
+}
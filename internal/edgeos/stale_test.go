@@ -0,0 +1,74 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStaleSources(t *testing.T) {
+	Convey("Testing stale source detection", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		now := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+		seenNow = func() time.Time { return now }
+		defer func() { seenNow = time.Now }()
+
+		var (
+			src   = dir + "/feed.txt"
+			stale = dir + "/stale.json"
+			cfg   = "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"
+		)
+
+		run := func(data string, days int) []StaleSource {
+			So(ioutil.WriteFile(src, []byte(data), 0644), ShouldBeNil)
+
+			c := NewConfig(
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Nodes([]string{domains}),
+				Prefix("address="),
+				StaleDays(days),
+				StaleFile(stale),
+			)
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+			return c.StaleSources()
+		}
+
+		So(run("ads.example.com\nbad.example.com\n", 7), ShouldBeNil)
+
+		now = now.Add(6 * 24 * time.Hour)
+		So(run("bad.example.com\nads.example.com\n", 7), ShouldBeNil)
+
+		now = now.Add(24 * time.Hour)
+		So(run("bad.example.com\nads.example.com\n", 7), ShouldResemble, []StaleSource{
+			{Name: "feed", Type: domains, Changed: time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC).Local(), Days: 7},
+		})
+
+		now = now.Add(24 * time.Hour)
+		So(run("new.example.com\n", 7), ShouldBeNil)
+
+		Convey("StaleDays needs a StaleFile", func() {
+			c := NewConfig(Nodes([]string{domains}), StaleDays(7))
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+			ct, err := c.NewContent(FileObj)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldEqual, ErrNoStaleFile)
+		})
+
+		Convey("StaleSource prints the source and how long it hasn't changed", func() {
+			s := StaleSource{Name: "feed", Type: domains, Changed: time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC), Days: 30}
+			So(s.String(), ShouldEqual, "domains/feed hasn't changed in 30 days, since 2017-01-02")
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package edgeos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+)
+
+// statsdMTU keeps StatsD datagrams below the typical path MTU
+const statsdMTU = 1432
+
+// statsdName matches characters that aren't safe in a StatsD metric name
+var statsdName = regexp.MustCompile(`[^a-zA-Z0-9_\-]+`)
+
+// metrics returns the run status and the run state's totals, n, as StatsD
+// gauge lines
+func (s *Status) metrics(prefix string, n RunCounts) []string {
+	var (
+		failed int
+		lines  []string
+		gauge  = func(name string, v interface{}) {
+			lines = append(lines, fmt.Sprintf("%v.%v:%v|g", prefix, name, v))
+		}
+	)
+
+	for _, r := range s.Sources {
+		if r.Error != "" {
+			failed++
+		}
+		gauge(fmt.Sprintf("entries.%v.%v", statsdName.ReplaceAllString(r.Type, "_"), statsdName.ReplaceAllString(r.Name, "_")), r.Entries)
+	}
+
+	success := 0
+	if s.Success {
+		success = 1
+	}
+
+	gauge("run.success", success)
+	gauge("run.timestamp", s.Time.Unix())
+	gauge("sources.total", len(s.Sources))
+	gauge("sources.failed", failed)
+	gauge("files.total", len(s.Files))
+	gauge("fetch.bytes", n.Bytes)
+	gauge("parse.lines", n.Lines)
+	gauge("run.seconds", int64(n.Elapsed/time.Second))
+	return lines
+}
+
+// PushStatsD sends the run status metrics to a StatsD/Telegraf listener over
+// UDP, err is the run's overall result
+func (c *Config) PushStatsD(addr, prefix string, err error) error {
+	s := c.Status
+	if s == nil {
+		return errors.New("run status isn't enabled")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err = s.finish(c, err); err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("udp", addr, c.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var b bytes.Buffer
+	send := func() error {
+		if b.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(b.Bytes())
+		b.Reset()
+		return err
+	}
+
+	for _, l := range s.metrics(prefix, c.state.Counts()) {
+		if b.Len()+len(l)+1 > statsdMTU {
+			if err = send(); err != nil {
+				return err
+			}
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(l)
+	}
+	return send()
+}
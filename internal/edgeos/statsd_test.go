@@ -0,0 +1,57 @@
+package edgeos
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPushStatsD(t *testing.T) {
+	Convey("Testing PushStatsD()", t, func() {
+		c := NewConfig(
+			Dir("/:~/"),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			Timeout(time.Second),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+		So(c.PushStatsD("127.0.0.1:8125", "blacklist", nil), ShouldNotBeNil)
+
+		ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer ln.Close()
+
+		c.SetOpt(Stats(NewStatus("")))
+		c.Status.add(&object{name: "zeus", nType: domn}, 3, nil)
+		c.Status.add(&object{name: "yoyo.org", nType: host}, 0, errors.New("timeout"))
+
+		So(c.PushStatsD(ln.LocalAddr().String(), "blacklist", nil), ShouldBeNil)
+
+		b := make([]byte, statsdMTU)
+		ln.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := ln.ReadFrom(b)
+		So(err, ShouldBeNil)
+
+		act := strings.Split(string(b[:n]), "\n")
+		So(act[:2], ShouldResemble, []string{
+			"blacklist.entries.domains.zeus:3|g",
+			"blacklist.entries.hosts.yoyo_org:0|g",
+		})
+		So(act[2], ShouldEqual, "blacklist.run.success:0|g")
+		So(act[4:9], ShouldResemble, []string{
+			"blacklist.sources.total:2|g",
+			"blacklist.sources.failed:1|g",
+			"blacklist.files.total:0|g",
+			"blacklist.fetch.bytes:0|g",
+			"blacklist.parse.lines:0|g",
+		})
+		So(act[9], ShouldStartWith, "blacklist.run.seconds:")
+	})
+}
@@ -0,0 +1,179 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status records the outcome of a blacklist run for monitoring agents
+type Status struct {
+	*sync.Mutex `json:"-"`
+	File        string         `json:"-"`
+	Time        time.Time      `json:"time"`
+	Success     bool           `json:"success"`
+	Error       string         `json:"error,omitempty"`
+	Sources     []SourceResult `json:"sources"`
+	Conflicts   []Conflict     `json:"conflicts,omitempty"`
+	Stale       []StaleSource  `json:"stale,omitempty"`
+	Disabled    []AutoDisabled `json:"autoDisabled,omitempty"`
+	Expiries    []Expiry       `json:"expiries,omitempty"`
+	Hooks       []HookResult   `json:"hooks,omitempty"`
+	Files       []ManifestFile `json:"files"`
+	Timings     []Timing       `json:"timings,omitempty"`
+}
+
+// SourceResult records the outcome of processing a single source
+type SourceResult struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	URL     string         `json:"url,omitempty"`
+	Entries int            `json:"entries"`
+	Held    int            `json:"quarantined,omitempty"`
+	Dupes   int            `json:"collisions,omitempty"`
+	Tags    map[string]int `json:"tags,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// NewStatus returns a *Status that will be written to file
+func NewStatus(file string) *Status {
+	return &Status{
+		Mutex:   &sync.Mutex{},
+		File:    file,
+		Sources: []SourceResult{},
+		Files:   []ManifestFile{},
+	}
+}
+
+// result returns o's SourceResult, n entries written with err
+func (o *object) result(n int, err error) SourceResult {
+	r := SourceResult{Name: o.name, Type: getType(o.nType).(string), URL: o.final, Entries: n, Held: o.held, Dupes: o.dupes, Tags: o.tags}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// done records a processed source in the run state and the run status
+func (o *object) done(n int, err error) {
+	o.state.Record(o.result(n, err))
+	o.Status.add(o, n, err)
+}
+
+// add records a processed source, it is a no-op if status isn't enabled
+func (s *Status) add(o *object, n int, err error) {
+	if s == nil {
+		return
+	}
+
+	r := o.result(n, err)
+
+	s.Lock()
+	s.Sources = append(s.Sources, r)
+	s.Unlock()
+}
+
+// addHook records a hook's output, it is a no-op if status isn't enabled
+func (s *Status) addHook(h *Hook, out []byte, err error) {
+	if s == nil {
+		return
+	}
+
+	r := HookResult{Stage: h.Stage, Command: h.String(), Output: string(out)}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	s.Lock()
+	s.Hooks = append(s.Hooks, r)
+	s.Unlock()
+}
+
+// forget removes a source's result before it is processed again
+func (s *Status) forget(name string) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for i, r := range s.Sources {
+		if r.Name == name {
+			s.Sources = append(s.Sources[:i], s.Sources[i+1:]...)
+			return
+		}
+	}
+}
+
+// Results returns a copy of the source results recorded so far
+func (s *Status) Results() []SourceResult {
+	s.Lock()
+	defer s.Unlock()
+	return append([]SourceResult(nil), s.Sources...)
+}
+
+// finish records the run's overall result and the generated files
+func (s *Status) finish(c *Config, err error) error {
+	s.Time = time.Now()
+	s.Success = err == nil
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	sort.Slice(s.Sources, func(i, j int) bool {
+		if s.Sources[i].Type != s.Sources[j].Type {
+			return s.Sources[i].Type < s.Sources[j].Type
+		}
+		return s.Sources[i].Name < s.Sources[j].Name
+	})
+
+	for _, r := range s.Sources {
+		if r.Error != "" {
+			s.Success = false
+		}
+	}
+
+	s.Conflicts = c.Conflicts()
+	s.Stale = c.StaleSources()
+	s.Disabled = c.AutoDisabled()
+	s.Expiries = c.Expiries()
+	s.Timings = c.Timings()
+
+	m, err := c.manifest()
+	if err != nil {
+		return err
+	}
+	s.Files = m.Files
+	return nil
+}
+
+// WriteStatus writes the run status to the status file, err is the run's
+// overall result; it is a no-op if status isn't enabled
+func (c *Config) WriteStatus(err error) error {
+	s := c.Status
+	if s == nil || s.File == "" {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err = s.finish(c, err); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.File + ".tmp"
+	if err = ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.File)
+}
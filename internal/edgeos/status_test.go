@@ -0,0 +1,66 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/britannic/blacklist/internal/tdata"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteStatus(t *testing.T) {
+	Convey("Testing WriteStatus()", t, func() {
+		dir, _ := ioutil.TempDir("/tmp", "testBlacklist")
+		defer os.RemoveAll(dir)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains, hosts}),
+			WCard(Wildcard{Node: "*s", Name: "*"}),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: tdata.Cfg}), ShouldBeNil)
+
+		Convey("Status is a no-op unless enabled", func() {
+			So(c.WriteStatus(nil), ShouldBeNil)
+			_, err := os.Stat(dir + "/status.json")
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("Status records sources and generated files", func() {
+			file := dir + "/status.json"
+			c.SetOpt(Stats(NewStatus(file)))
+
+			for _, ct := range []IFace{PreDObj, PreHObj} {
+				obj, err := c.NewContent(ct)
+				So(err, ShouldBeNil)
+				So(c.ProcessContent(obj), ShouldBeNil)
+			}
+			So(c.WriteStatus(nil), ShouldBeNil)
+
+			b, err := ioutil.ReadFile(file)
+			So(err, ShouldBeNil)
+
+			act := &Status{}
+			So(json.Unmarshal(b, act), ShouldBeNil)
+			So(act.Success, ShouldBeTrue)
+			So(act.Time.IsZero(), ShouldBeFalse)
+			So(act.Sources, ShouldResemble, []SourceResult{
+				{Name: "includes.[9]", Type: PreDomns, Entries: 9},
+				{Name: "includes.[1]", Type: PreHosts, Entries: 1},
+			})
+			So(len(act.Files), ShouldEqual, 2)
+
+			So(c.WriteStatus(errors.New("reload failed")), ShouldBeNil)
+			b, _ = ioutil.ReadFile(file)
+			act = &Status{}
+			So(json.Unmarshal(b, act), ShouldBeNil)
+			So(act.Success, ShouldBeFalse)
+			So(act.Error, ShouldEqual, "reload failed")
+		})
+	})
+}
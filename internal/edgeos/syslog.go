@@ -0,0 +1,210 @@
+package edgeos
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logging "github.com/op/go-logging"
+)
+
+// syslogFacilities maps syslog facility names to their codes
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogSeverity maps logging levels to syslog severities
+var syslogSeverity = map[logging.Level]int{
+	logging.CRITICAL: 2,
+	logging.ERROR:    3,
+	logging.WARNING:  4,
+	logging.NOTICE:   5,
+	logging.INFO:     6,
+	logging.DEBUG:    7,
+}
+
+// syslogDevs are the local syslog sockets tried in turn
+var syslogDevs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogBackend is a logging backend that sends RFC5424 messages to a local
+// syslog socket or a remote collector over UDP or TCP
+type syslogBackend struct {
+	sync.Mutex
+	addr    string
+	conn    net.Conn
+	fac     int
+	host    string
+	network string
+	pid     int
+	tag     string
+}
+
+// SyslogBackend returns a logging backend for Syslog, an address of "local"
+// uses the local syslog socket, udp://host[:port] and tcp://host[:port] send
+// to a remote collector, port 514 if it isn't set
+func (p *Parms) SyslogBackend() (logging.Backend, error) {
+	fac, ok := syslogFacilities[strings.ToLower(p.SysFac)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q, must be one of %v", p.SysFac, strings.Join(facilityNames(), ", "))
+	}
+
+	network, addr, err := syslogAddr(p.Syslog)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := hostName()
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		host = host[:i]
+	}
+
+	tag := p.SysTag
+	if tag == "" {
+		tag = "blacklist"
+	}
+
+	s := &syslogBackend{
+		addr:    addr,
+		fac:     fac,
+		host:    nilValue(host),
+		network: network,
+		pid:     os.Getpid(),
+		tag:     tag,
+	}
+
+	if err = s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// syslogAddr splits a -syslog address into its network and address
+func syslogAddr(s string) (string, string, error) {
+	if s == "local" || s == "" {
+		return "unix", "", nil
+	}
+
+	network := "udp"
+	if i := strings.Index(s, "://"); i >= 0 {
+		network, s = s[:i], s[i+3:]
+	}
+
+	switch network {
+	case "tcp", "udp":
+	default:
+		return "", "", fmt.Errorf("unknown syslog network %q, must be local, udp:// or tcp://", network)
+	}
+
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		s = net.JoinHostPort(strings.Trim(s, "[]"), "514")
+	}
+	return network, s, nil
+}
+
+// dial connects to the syslog socket or collector
+func (s *syslogBackend) dial() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	if s.network != "unix" {
+		conn, err := net.DialTimeout(s.network, s.addr, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		return nil
+	}
+
+	for _, dev := range syslogDevs {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, dev); err == nil {
+				s.conn, s.network = conn, network
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no local syslog socket found in %v", strings.Join(syslogDevs, ", "))
+}
+
+// Log sends rec to syslog, a stream connection is dialed again once if the
+// send fails
+func (s *syslogBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	s.Lock()
+	defer s.Unlock()
+
+	msg := s.format(level, rec.Time, rec.Message())
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				continue
+			}
+		}
+		if _, err = s.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+// format returns msg as an RFC5424 message, framed with its length for TCP
+func (s *syslogBackend) format(level logging.Level, t time.Time, msg string) string {
+	sev, ok := syslogSeverity[level]
+	if !ok {
+		sev = 6
+	}
+
+	m := fmt.Sprintf("<%d>1 %v %v %v %d - - %v", s.fac*8+sev, t.Format(time.RFC3339Nano), s.host, s.tag, s.pid, strings.TrimRight(msg, "\n"))
+	if s.network == "tcp" {
+		return fmt.Sprintf("%d %v", len(m), m)
+	}
+	return m
+}
+
+// facilityNames returns the syslog facility names in order
+func facilityNames() []string {
+	names := make([]string, 0, len(syslogFacilities))
+	for name := range syslogFacilities {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return syslogFacilities[names[i]] < syslogFacilities[names[j]]
+	})
+	return names
+}
+
+// nilValue returns RFC5424's NILVALUE for an empty header field
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
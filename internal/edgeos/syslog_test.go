@@ -0,0 +1,110 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	logging "github.com/op/go-logging"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSyslogBackend(t *testing.T) {
+	Convey("Testing SyslogBackend()", t, func() {
+		hostName = func() (string, error) { return "ubnt.example.com", nil }
+		defer func() { hostName = os.Hostname }()
+
+		rec := func(lvl logging.Level, msg string) *logging.Record {
+			return &logging.Record{Level: lvl, Time: time.Date(2026, 10, 16, 1, 2, 3, 0, time.UTC), Args: []interface{}{msg}}
+		}
+		hdr := func(pri int) string {
+			return fmt.Sprintf("<%d>1 2026-10-16T01:02:03Z ubnt blacklist %d - - ", pri, os.Getpid())
+		}
+
+		Convey("messages are sent to a UDP collector", func() {
+			pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer pc.Close()
+
+			c := NewConfig(Syslog("udp://"+pc.LocalAddr().String(), "local3", ""))
+			b, err := c.SyslogBackend()
+			So(err, ShouldBeNil)
+
+			So(b.Log(logging.WARNING, 0, rec(logging.WARNING, "source adaway failed\n")), ShouldBeNil)
+
+			buf := make([]byte, 1024)
+			pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := pc.ReadFrom(buf)
+			So(err, ShouldBeNil)
+			So(string(buf[:n]), ShouldEqual, hdr(19*8+4)+"source adaway failed")
+		})
+
+		Convey("messages sent over TCP are framed with their length", func() {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer ln.Close()
+
+			got := make(chan string, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				var n int
+				r := bufio.NewReader(conn)
+				fmt.Fscanf(r, "%d ", &n)
+				b := make([]byte, n)
+				r.Read(b)
+				got <- string(b)
+			}()
+
+			c := NewConfig(Syslog("tcp://"+ln.Addr().String(), "daemon", "adblock"))
+			b, err := c.SyslogBackend()
+			So(err, ShouldBeNil)
+			So(b.Log(logging.INFO, 0, rec(logging.INFO, "Starting up...")), ShouldBeNil)
+
+			select {
+			case s := <-got:
+				So(s, ShouldEqual, strings.Replace(hdr(3*8+6), "blacklist", "adblock", 1)+"Starting up...")
+			case <-time.After(5 * time.Second):
+				So("timed out", ShouldBeEmpty)
+			}
+		})
+
+		Convey("unknown facilities and networks are rejected", func() {
+			_, err := NewConfig(Syslog("local", "local9", "")).SyslogBackend()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, `unknown syslog facility "local9", must be one of kern, user,`)
+
+			_, err = NewConfig(Syslog("tls://collector", "daemon", "")).SyslogBackend()
+			So(err.Error(), ShouldEqual, `unknown syslog network "tls", must be local, udp:// or tcp://`)
+		})
+	})
+}
+
+func TestSyslogAddr(t *testing.T) {
+	Convey("Testing syslogAddr()", t, func() {
+		tests := []struct {
+			addr    string
+			network string
+			exp     string
+		}{
+			{addr: "local", network: "unix"},
+			{addr: "collector", network: "udp", exp: "collector:514"},
+			{addr: "udp://10.0.0.1:1514", network: "udp", exp: "10.0.0.1:1514"},
+			{addr: "tcp://[::1]", network: "tcp", exp: "[::1]:514"},
+		}
+
+		for _, tt := range tests {
+			network, addr, err := syslogAddr(tt.addr)
+			So(err, ShouldBeNil)
+			So(network, ShouldEqual, tt.network)
+			So(addr, ShouldEqual, tt.exp)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package edgeos
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sd_notify states
+const (
+	NotifyReady    = "READY=1"
+	NotifyReload   = "RELOADING=1"
+	NotifyStopping = "STOPPING=1"
+	NotifyWatchdog = "WATCHDOG=1"
+)
+
+// Notifier sends sd_notify messages to systemd, it is nil unless blacklist
+// was started by a Type=notify unit
+type Notifier struct {
+	socket   string
+	watchdog time.Duration
+}
+
+// NewNotifier returns a Notifier for the NOTIFY_SOCKET systemd set, or nil
+// if there isn't one
+func NewNotifier() *Notifier {
+	n := &Notifier{socket: os.Getenv("NOTIFY_SOCKET")}
+	if n.socket == "" {
+		return nil
+	}
+
+	// the watchdog is for the main process, not its children
+	if pid := os.Getenv("WATCHDOG_PID"); pid == "" || pid == strconv.Itoa(os.Getpid()) {
+		if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+			n.watchdog = time.Duration(usec) * time.Microsecond
+		}
+	}
+	return n
+}
+
+// Notify sends states, such as NotifyReady or "STATUS=...", to systemd
+func (n *Notifier) Notify(states ...string) error {
+	if n == nil {
+		return nil
+	}
+
+	addr := &net.UnixAddr{Name: n.socket, Net: "unixgram"}
+	// a leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(addr.Name, "@") {
+		addr.Name = "\x00" + addr.Name[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(states, "\n")))
+	return err
+}
+
+// Status sets the status line systemctl status shows
+func (n *Notifier) Status(s string) error {
+	return n.Notify("STATUS=" + strings.Replace(s, "\n", " ", -1))
+}
+
+// WatchdogInterval is how often the watchdog must be pinged, half the
+// unit's WatchdogSec; it is 0 if the watchdog isn't enabled
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.watchdog / 2
+}
+
+// Sleep sleeps for d, pinging the watchdog often enough to keep it happy
+func (n *Notifier) Sleep(d time.Duration) {
+	n.Wait(d, nil)
+}
+
+// Wait is Sleep, cut short once wake receives
+func (n *Notifier) Wait(d time.Duration, wake <-chan struct{}) {
+	every := n.WatchdogInterval()
+	if every == 0 {
+		every = d
+	}
+
+	for d > 0 {
+		nap := every
+		if d < nap {
+			nap = d
+		}
+		n.ping()
+
+		t := time.NewTimer(nap)
+		select {
+		case <-wake:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		d -= nap
+	}
+	n.ping()
+}
+
+// ping pings the watchdog, if it is enabled
+func (n *Notifier) ping() {
+	if n.WatchdogInterval() > 0 {
+		n.Notify(NotifyWatchdog)
+	}
+}
+
+// RunSummary describes a run's outcome for Notifier.Status
+func (c *Config) RunSummary(err error) string {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	switch {
+	case err != nil:
+		return fmt.Sprintf("last run %v failed: %v", now, err)
+	}
+
+	n := c.state.Counts()
+	if n.Sources == 0 {
+		return fmt.Sprintf("last run %v succeeded", now)
+	}
+	return fmt.Sprintf("last run %v succeeded, %d entries, %d failed sources", now, n.Entries, n.Failed)
+}
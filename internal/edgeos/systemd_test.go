@@ -0,0 +1,79 @@
+package edgeos
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNotifier(t *testing.T) {
+	Convey("Testing systemd notifications", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		sock := dir + "/notify"
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		read := func() string {
+			b := make([]byte, 1024)
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, err := conn.Read(b)
+			So(err, ShouldBeNil)
+			return string(b[:n])
+		}
+
+		for k, v := range map[string]string{"NOTIFY_SOCKET": sock, "WATCHDOG_USEC": "20000", "WATCHDOG_PID": strconv.Itoa(os.Getpid())} {
+			So(os.Setenv(k, v), ShouldBeNil)
+			defer os.Unsetenv(k)
+		}
+
+		n := NewNotifier()
+		So(n, ShouldNotBeNil)
+		So(n.WatchdogInterval(), ShouldEqual, 10*time.Millisecond)
+
+		So(n.Notify(NotifyReady, "STATUS=starting"), ShouldBeNil)
+		So(read(), ShouldEqual, "READY=1\nSTATUS=starting")
+
+		So(n.Status("two\nlines"), ShouldBeNil)
+		So(read(), ShouldEqual, "STATUS=two lines")
+
+		Convey("sleeping pings the watchdog", func() {
+			n.Sleep(25 * time.Millisecond)
+			for i := 0; i < 4; i++ {
+				So(read(), ShouldEqual, NotifyWatchdog)
+			}
+		})
+
+		Convey("the watchdog belongs to its pid", func() {
+			So(os.Setenv("WATCHDOG_PID", "1"), ShouldBeNil)
+			So(NewNotifier().WatchdogInterval(), ShouldEqual, 0)
+		})
+
+		Convey("a nil Notifier does nothing", func() {
+			So(os.Unsetenv("NOTIFY_SOCKET"), ShouldBeNil)
+			n := NewNotifier()
+			So(n, ShouldBeNil)
+			So(n.Notify(NotifyReady), ShouldBeNil)
+			So(n.WatchdogInterval(), ShouldEqual, 0)
+		})
+
+		Convey("run summaries", func() {
+			c := NewConfig()
+			So(c.RunSummary(errors.New("boom")), ShouldEndWith, " failed: boom")
+			So(c.RunSummary(nil), ShouldEndWith, " succeeded")
+
+			c.state.Record(SourceResult{Name: "a", Entries: 3})
+			c.state.Record(SourceResult{Name: "b", Error: "down"})
+			So(c.RunSummary(nil), ShouldEndWith, " succeeded, 3 entries, 1 failed sources")
+		})
+	})
+}
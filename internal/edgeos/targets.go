@@ -0,0 +1,155 @@
+package edgeos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// target labels the configuration node for additional output targets
+	target = "target"
+	// ipsetFormat is the target format for an ipset restore script
+	ipsetFormat = "ipset"
+)
+
+// Target is an additional output, rendering the merged blacklist in another
+// format to its own file and running its post-command once it's written
+type Target struct {
+	Name    string `json:"name"`
+	Format  string `json:"format"`
+	File    string `json:"file"`
+	Address string `json:"address,omitempty"`
+	Post    string `json:"postCommand,omitempty"`
+}
+
+// Targets returns the configured output targets
+func (c *Config) Targets() []*Target {
+	return c.targets
+}
+
+// TargetFormats returns the sorted target formats
+func TargetFormats() []string {
+	f := []string{ipsetFormat}
+	for k := range Renderers {
+		f = append(f, k)
+	}
+	sort.Strings(f)
+	return f
+}
+
+// validFormat returns true if f is a known target format
+func validFormat(f string) bool {
+	_, ok := Renderers[f]
+	return ok || f == ipsetFormat
+}
+
+// RenderTargets writes each target's file from the generated files and runs
+// its post-command, it returns the post-commands' combined output
+func (c *Config) RenderTargets() ([]byte, error) {
+	if len(c.targets) == 0 {
+		return nil, nil
+	}
+
+	entries, err := c.MergedEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		errs Errors
+		out  []byte
+	)
+
+	for _, t := range c.targets {
+		if err = t.write(entries, c.IPs()); err != nil {
+			errs = append(errs, fmt.Errorf("target %v: %v", t.Name, err))
+			continue
+		}
+
+		if n := WildcardLoss(t.Format, entries); n > 0 {
+			c.warn(fmt.Sprintf("target %v: %v files only block exact names, %d wildcard entries are written without their subdomains", t.Name, t.Format, n))
+		}
+
+		if t.Post == "" {
+			continue
+		}
+
+		b, err := c.runner().CombinedOutput(t.Post)
+		out = append(out, b...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("target %v: %v", t.Name, err))
+		}
+	}
+
+	if errs != nil {
+		return out, errs
+	}
+	return out, nil
+}
+
+// write atomically replaces the target's file
+func (t *Target) write(entries Entries, ips []string) error {
+	tmp := t.File + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	switch t.Format {
+	case ipsetFormat:
+		err = WriteIPSet(f, t.Name, ips)
+	default:
+		err = Renderers[t.Format](f, entries, t.Address)
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, t.File)
+}
+
+// WriteIPSet writes an ipset restore script to w, loading the IP address
+// entries collected from sources into the name and name6 sets
+func WriteIPSet(w io.Writer, name string, ips []string) error {
+	var v4, v6 []string
+	for _, ip := range ips {
+		switch p := net.ParseIP(ip); {
+		case p == nil:
+			continue
+		case p.To4() != nil:
+			v4 = append(v4, ip)
+		default:
+			v6 = append(v6, ip)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# blacklist: generated from the EdgeOS configuration, do not edit")
+	for _, set := range []struct {
+		name, family string
+		ips          []string
+	}{{name, "inet", v4}, {name + "6", "inet6", v6}} {
+		fmt.Fprintf(bw, "create %v hash:ip family %v -exist\nflush %v\n", set.name, set.family, set.name)
+		for _, ip := range set.ips {
+			fmt.Fprintf(bw, "add %v %v -exist\n", set.name, ip)
+		}
+	}
+	return bw.Flush()
+}
+
+// ipOr returns ip, or 0.0.0.0 if it's empty
+func ipOr(ip string) string {
+	if strings.TrimSpace(ip) == "" {
+		return "0.0.0.0"
+	}
+	return ip
+}
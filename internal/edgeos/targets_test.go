@@ -0,0 +1,112 @@
+package edgeos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTargets(t *testing.T) {
+	Convey("Testing output targets", t, func() {
+		var (
+			dir, _ = ioutil.TempDir("/tmp", "testBlacklist")
+			f      = &fakeRunner{out: []byte("reloaded\n")}
+			cfg    = `blacklist {
+	target bind {
+		file ` + dir + `/db.rpz
+		format rpz
+		post-command "rndc reload rpz"
+	}
+	target lan {
+		address 192.168.1.1
+		file ` + dir + `/hosts
+		format hosts
+	}
+	target drop {
+		file ` + dir + `/ipset.rules
+		format ipset
+		post-command "ipset restore -f ` + dir + `/ipset.rules"
+	}
+	domains {
+		source zeus {
+			url http://zeus.com
+		}
+	}
+}`
+			c = NewConfig(
+				CollectIPs(true),
+				Dir(dir),
+				Ext("blacklist.conf"),
+				FileNameFmt("%v/%v.%v.%v"),
+				Shell(f),
+				WCard(Wildcard{Node: "*s", Name: "*"}),
+			)
+		)
+		defer os.RemoveAll(dir)
+
+		serial := zoneSerial
+		zoneSerial = func(Entries) int64 { return 1 }
+		defer func() { zoneSerial = serial }()
+
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+		So(c.Targets(), ShouldResemble, []*Target{
+			{Name: "bind", Format: "rpz", File: dir + "/db.rpz", Post: "rndc reload rpz"},
+			{Name: "lan", Format: "hosts", File: dir + "/hosts", Address: "192.168.1.1"},
+			{Name: "drop", Format: "ipset", File: dir + "/ipset.rules", Post: "ipset restore -f " + dir + "/ipset.rules"},
+		})
+
+		So(ioutil.WriteFile(dir+"/domains.zeus.blacklist.conf", []byte("address=/.zeus.com/0.0.0.0\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/hosts.yoyo.blacklist.conf", []byte("address=/ads.yoyo.org/0.0.0.0\n"), 0644), ShouldBeNil)
+		c.ips.add(net.ParseIP("203.0.113.7"))
+		c.ips.add(net.ParseIP("2001:db8::7"))
+
+		out, err := c.RenderTargets()
+		So(err, ShouldBeNil)
+		So(string(out), ShouldEqual, "reloaded\nreloaded\n")
+		So(f.scripts, ShouldResemble, []string{"rndc reload rpz", "ipset restore -f " + dir + "/ipset.rules"})
+
+		const hdr = "blacklist: generated from the EdgeOS configuration, do not edit\n"
+		tests := []struct {
+			file string
+			exp  string
+		}{
+			{file: "db.rpz", exp: "; " + hdr + "$TTL 300\n@ IN SOA localhost. root.localhost. 1 3600 600 86400 300\n@ IN NS localhost.\nads.yoyo.org CNAME .\nzeus.com CNAME .\n*.zeus.com CNAME .\n"},
+			{file: "hosts", exp: "# " + hdr + "192.168.1.1 ads.yoyo.org\n192.168.1.1 zeus.com\n"},
+			{file: "ipset.rules", exp: "# " + hdr + "create drop hash:ip family inet -exist\nflush drop\nadd drop 203.0.113.7 -exist\ncreate drop6 hash:ip family inet6 -exist\nflush drop6\nadd drop6 2001:db8::7 -exist\n"},
+		}
+
+		for _, tt := range tests {
+			act, err := ioutil.ReadFile(dir + "/" + tt.file)
+			So(err, ShouldBeNil)
+			So(string(act), ShouldEqual, tt.exp)
+		}
+
+		Convey("Testing target errors", func() {
+			f.err = errors.New("exit status 1")
+			_, err := c.RenderTargets()
+			So(err.Error(), ShouldContainSubstring, "target bind: exit status 1")
+
+			for cfg, exp := range map[string]string{
+				"blacklist {\n\ttarget a {\n\t\tfile /tmp/a\n\t}\n}":    `config.boot:4: target "a" missing format`,
+				"blacklist {\n\ttarget a {\n\t\tformat rpz\n\t}\n}":     `config.boot:4: target "a" missing file`,
+				"blacklist {\n\ttarget a {\n\t\tformat unbound\n\t}\n}": `config.boot:3: target "a" has unknown format "unbound"`,
+			} {
+				So(NewConfig().ReadCfg(&CFGstatic{Cfg: cfg}).Error(), ShouldEqual, exp)
+			}
+		})
+	})
+}
+
+func TestWriteIPSet(t *testing.T) {
+	Convey("Testing WriteIPSet()", t, func() {
+		act := new(bytes.Buffer)
+		So(WriteIPSet(act, "bl", nil), ShouldBeNil)
+		So(act.String(), ShouldEqual, "# blacklist: generated from the EdgeOS configuration, do not edit\ncreate bl hash:ip family inet -exist\nflush bl\ncreate bl6 hash:ip family inet6 -exist\nflush bl6\n")
+		So(TargetFormats(), ShouldResemble, []string{"abp", "coredns", "dnscrypt-blocked", "dnscrypt-cloaking", "dnsmasq", "domains", "hosts", "ipset", "rpz", "wildcard"})
+	})
+}
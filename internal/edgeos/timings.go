@@ -0,0 +1,82 @@
+package edgeos
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing records how long a stage of a run took, for one source if Source is
+// set. The stages are config, fetch, parse, dedupe, render, write and reload;
+// sources are deduplicated against each other as they're parsed, so dedupe is
+// only the Threshold tally
+type Timing struct {
+	Stage   string  `json:"stage"`
+	Source  string  `json:"source,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// stopwatch collects a run's timings
+type stopwatch struct {
+	sync.Mutex
+	t []Timing
+}
+
+// Timed records that stage took since start, for source if it isn't empty,
+// and logs it; it is a no-op unless Timings is set
+func (p *Parms) Timed(stage, source string, start time.Time) {
+	if p.watch == nil {
+		return
+	}
+
+	t := Timing{Stage: stage, Source: source, Seconds: time.Since(start).Seconds()}
+	p.watch.Lock()
+	p.watch.t = append(p.watch.t, t)
+	p.watch.Unlock()
+
+	if p.Logger != nil {
+		p.Info(fmt.Sprintf("timing: %v", t))
+	}
+}
+
+// Timings returns the recorded timings in the order they finished
+func (c *Config) Timings() []Timing {
+	if c.watch == nil {
+		return nil
+	}
+
+	c.watch.Lock()
+	defer c.watch.Unlock()
+	return append([]Timing(nil), c.watch.t...)
+}
+
+// TimingTotals returns each stage's timings summed over its sources, in the
+// order the stages first finished; sources are fetched and written
+// concurrently, so a total can be longer than the stage's wall clock time
+func (c *Config) TimingTotals() []Timing {
+	var (
+		totals []Timing
+		at     = make(map[string]int)
+	)
+
+	for _, t := range c.Timings() {
+		i, ok := at[t.Stage]
+		if !ok {
+			i, at[t.Stage] = len(totals), len(totals)
+			totals = append(totals, Timing{Stage: t.Stage})
+		}
+		totals[i].Seconds += t.Seconds
+	}
+	return totals
+}
+
+// String formats the timing for logs, e.g. "fetch adaway 1.25s"
+func (t Timing) String() string {
+	s := []string{t.Stage}
+	if t.Source != "" {
+		s = append(s, t.Source)
+	}
+	d := time.Duration(t.Seconds * float64(time.Second)).Round(time.Millisecond)
+	return strings.Join(append(s, d.String()), " ")
+}
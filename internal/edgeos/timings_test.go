@@ -0,0 +1,71 @@
+package edgeos
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimings(t *testing.T) {
+	Convey("Testing ProcessContent() with Timings", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		src := dir + "/feed.txt"
+		So(ioutil.WriteFile(src, []byte("ads.example.com\n"), 0644), ShouldBeNil)
+
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{domains}),
+			Prefix("address="),
+			Stats(NewStatus(dir+"/status.json")),
+			Timings(true),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n\t\tsource feed {\n\t\t\tprefix \"\"\n\t\t\tfile " + src + "\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		ct, err := c.NewContent(FileObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		var stages []string
+		for _, t := range c.Timings() {
+			So(t.Source, ShouldEqual, "feed")
+			So(t.Seconds, ShouldBeGreaterThanOrEqualTo, 0)
+			stages = append(stages, t.Stage)
+		}
+		So(stages, ShouldResemble, []string{"fetch", "parse", "render", "write"})
+
+		So(c.WriteStatus(nil), ShouldBeNil)
+		So(c.Status.Timings, ShouldResemble, c.Timings())
+
+		Convey("totals sum each stage over its sources", func() {
+			start := time.Now().Add(-time.Second)
+			c.Timed("fetch", "other", start)
+			c.Timed("reload", "", start)
+
+			var act []string
+			for _, t := range c.TimingTotals() {
+				act = append(act, t.Stage)
+			}
+			So(act, ShouldResemble, []string{"fetch", "parse", "render", "write", "reload"})
+			So(c.TimingTotals()[0].Seconds, ShouldBeGreaterThanOrEqualTo, 1)
+		})
+
+		Convey("nothing is recorded without Timings", func() {
+			c.SetOpt(Timings(false))
+			c.Timed("reload", "", time.Now())
+			So(c.Timings(), ShouldBeNil)
+		})
+	})
+
+	Convey("Testing Timing.String()", t, func() {
+		So(Timing{Stage: "fetch", Source: "adaway", Seconds: 1.2504}.String(), ShouldEqual, "fetch adaway 1.25s")
+		So(Timing{Stage: "reload", Seconds: 0.0123}.String(), ShouldEqual, "reload 12ms")
+	})
+}
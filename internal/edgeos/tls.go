@@ -0,0 +1,148 @@
+package edgeos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HTTPSallow fetches plain HTTP sources as configured
+	HTTPSallow = ""
+	// HTTPSupgrade rewrites plain HTTP source URLs to HTTPS
+	HTTPSupgrade = "upgrade"
+	// HTTPSrequire refuses plain HTTP source URLs
+	HTTPSrequire = "require"
+)
+
+// sourceURL applies the HTTPS policy to a source URL
+func (p *Parms) sourceURL(u string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(u), "http://") {
+		return u, nil
+	}
+
+	switch p.HTTPS {
+	case HTTPSupgrade:
+		return "https://" + u[len("http://"):], nil
+	case HTTPSrequire:
+		return u, fmt.Errorf("refusing plain HTTP source %v", u)
+	}
+	return u, nil
+}
+
+// client returns an *http.Client that trusts CAfile in addition to the
+// system roots and, if Pins are set, only accepts those certificates
+func (p *Parms) client() (*http.Client, error) {
+	return p.pinnedClient(p.Pins)
+}
+
+// client returns the source's *http.Client, its own pin leaves replace Pins
+func (o *object) client() (*http.Client, error) {
+	if len(o.pins) > 0 {
+		return o.Parms.pinnedClient(o.pins)
+	}
+	return o.Parms.client()
+}
+
+// pinnedClient returns an *http.Client that trusts CAfile in addition to
+// the system roots and, if there are pins, only accepts those certificates
+func (p *Parms) pinnedClient(pins []string) (*http.Client, error) {
+	if p.CAfile == "" && len(pins) == 0 {
+		return &http.Client{}, nil
+	}
+
+	roots, err := p.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{RootCAs: roots}
+
+	if len(pins) > 0 {
+		fps, err := parsePins(pins)
+		if err != nil {
+			return nil, err
+		}
+		cfg.VerifyPeerCertificate = verifyPin(fps)
+	}
+
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: cfg}}, nil
+}
+
+// rootCAs returns the system roots plus CAfile, or nil for the system
+// roots alone if CAfile isn't set
+func (p *Parms) rootCAs() (*x509.CertPool, error) {
+	if p.CAfile == "" {
+		return nil, nil
+	}
+
+	pem, err := ioutil.ReadFile(p.CAfile)
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %v", p.CAfile)
+	}
+	return roots, nil
+}
+
+// parsePin returns a certificate SHA256 fingerprint given as hex, optionally
+// colon separated
+func parsePin(pin string) ([]byte, error) {
+	fp, err := hex.DecodeString(strings.Replace(strings.TrimSpace(pin), ":", "", -1))
+	if err != nil || len(fp) != sha256.Size {
+		return nil, fmt.Errorf("invalid certificate pin %q, want a hex SHA256 fingerprint", pin)
+	}
+	return fp, nil
+}
+
+// parsePins returns the fingerprints of pins, failing on the first invalid one
+func parsePins(pins []string) ([][]byte, error) {
+	fps := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		fp, err := parsePin(pin)
+		if err != nil {
+			return nil, err
+		}
+		fps = append(fps, fp)
+	}
+	return fps, nil
+}
+
+// CheckPins returns an error for the first pin that isn't a hex SHA256
+// fingerprint
+func CheckPins(pins []string) error {
+	_, err := parsePins(pins)
+	return err
+}
+
+// verifyPin returns a check of the server's certificate SHA256 fingerprint
+// against fps
+func verifyPin(fps [][]byte) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server presented no certificate")
+		}
+
+		sum := sha256.Sum256(rawCerts[0])
+		for _, fp := range fps {
+			if bytes.Equal(fp, sum[:]) {
+				return nil
+			}
+		}
+		return fmt.Errorf("certificate fingerprint %x isn't pinned", sum)
+	}
+}
@@ -0,0 +1,116 @@
+package edgeos
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceURL(t *testing.T) {
+	Convey("Testing sourceURL()", t, func() {
+		tests := []struct {
+			mode string
+			url  string
+			exp  string
+			err  bool
+		}{
+			{mode: HTTPSallow, url: "http://zeus.com/list", exp: "http://zeus.com/list"},
+			{mode: HTTPSupgrade, url: "HTTP://zeus.com/list", exp: "https://zeus.com/list"},
+			{mode: HTTPSupgrade, url: "https://zeus.com/list", exp: "https://zeus.com/list"},
+			{mode: HTTPSrequire, url: "http://zeus.com/list", exp: "http://zeus.com/list", err: true},
+			{mode: HTTPSrequire, url: "https://zeus.com/list", exp: "https://zeus.com/list"},
+		}
+
+		for _, tt := range tests {
+			act, err := (&Parms{HTTPS: tt.mode}).sourceURL(tt.url)
+			So(act, ShouldEqual, tt.exp)
+			So(err != nil, ShouldEqual, tt.err)
+		}
+	})
+}
+
+func TestTLSClient(t *testing.T) {
+	Convey("Testing CAfile and Pins", t, func() {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ads.zeus.com\n")
+		}))
+		defer srv.Close()
+
+		f, err := ioutil.TempFile("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		So(pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), ShouldBeNil)
+		f.Close()
+
+		pin := fmt.Sprintf("%x", sha256.Sum256(srv.Certificate().Raw))
+		get := func(p *Parms) *object {
+			p.Method = "GET"
+			return getHTTP(&object{Parms: p, url: srv.URL})
+		}
+
+		So(get(&Parms{}).err, ShouldNotBeNil)
+		So(get(&Parms{CAfile: f.Name()}).err, ShouldBeNil)
+		So(get(&Parms{CAfile: f.Name(), Pins: []string{pin}}).err, ShouldBeNil)
+		So(get(&Parms{CAfile: f.Name(), Pins: []string{"00:11"}}).err, ShouldNotBeNil)
+		So(get(&Parms{CAfile: "/:~/missing.pem"}).err, ShouldNotBeNil)
+
+		o := get(&Parms{CAfile: f.Name(), Pins: []string{pin}})
+		b, _ := ioutil.ReadAll(o.r)
+		So(string(b), ShouldEqual, "ads.zeus.com\n")
+
+		Convey("a source's pins replace the global ones", func() {
+			other := fmt.Sprintf("%x", sha256.Sum256([]byte("other")))
+			p := &Parms{CAfile: f.Name(), Method: "GET", Pins: []string{other}}
+			So(getHTTP(&object{Parms: p, url: srv.URL}).err, ShouldNotBeNil)
+			So(getHTTP(&object{Parms: p, url: srv.URL, pins: []string{pin}}).err, ShouldBeNil)
+			So(getHTTP(&object{Parms: &Parms{CAfile: f.Name(), Method: "GET"}, url: srv.URL, pins: []string{other}}).err, ShouldNotBeNil)
+		})
+
+		Convey("malformed pins are rejected", func() {
+			So(CheckPins([]string{pin, strings.ToUpper(pin)}), ShouldBeNil)
+			So(CheckPins(nil), ShouldBeNil)
+			So(CheckPins([]string{"00:11"}), ShouldNotBeNil)
+			So(CheckPins([]string{"zz" + pin[2:]}), ShouldNotBeNil)
+
+			c := NewConfig(Nodes([]string{rootNode, domains, hosts}))
+			err := c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n    hosts {\n        source zeus {\n            pin 00:11\n            url https://zeus.com/list\n        }\n    }\n}\n"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, `source "zeus" has invalid certificate pin "00:11"`)
+		})
+	})
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	Convey("Testing redirects to plain HTTP under an HTTPS policy", t, func() {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ads.zeus.com\n")
+		}))
+		defer plain.Close()
+
+		srv := httptest.NewTLSServer(http.RedirectHandler(plain.URL+"/list", http.StatusFound))
+		defer srv.Close()
+
+		for _, mode := range []string{HTTPSupgrade, HTTPSrequire} {
+			client := srv.Client()
+			o := &object{Parms: &Parms{HTTPS: mode}, name: "zeus"}
+			client.CheckRedirect = o.checkRedirect()
+			_, err := client.Get(srv.URL)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "zeus: redirect to plain HTTP "+plain.URL+"/list refused")
+		}
+
+		client := srv.Client()
+		client.CheckRedirect = (&object{Parms: &Parms{}, name: "zeus"}).checkRedirect()
+		resp, err := client.Get(srv.URL)
+		So(err, ShouldBeNil)
+		resp.Body.Close()
+	})
+}
@@ -0,0 +1,39 @@
+package edgeos
+
+import (
+	"net/http"
+	"net/url"
+)
+
+const (
+	// defaultTor is the usual Tor daemon SOCKS listener
+	defaultTor = "127.0.0.1:9050"
+
+	// viaTor is the via leaf value that fetches a source through Tor
+	viaTor = "tor"
+)
+
+// torClient returns a copy of c that connects through the Tor SOCKS proxy,
+// Tor isolates circuits by SOCKS credentials, so each source authenticates
+// with its own name to get a circuit of its own
+func (o *object) torClient(c *http.Client) *http.Client {
+	addr := o.Tor
+	if addr == "" {
+		addr = defaultTor
+	}
+
+	t := &http.Transport{}
+	if c.Transport != nil {
+		t = c.Transport.(*http.Transport).Clone()
+	}
+
+	t.Proxy = http.ProxyURL(&url.URL{
+		Scheme: "socks5",
+		Host:   addr,
+		User:   url.UserPassword(o.name, "blacklist"),
+	})
+
+	tc := *c
+	tc.Transport = t
+	return &tc
+}
@@ -0,0 +1,125 @@
+package edgeos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// socksServer is a minimal SOCKS5 proxy that records each client's username
+type socksServer struct {
+	sync.Mutex
+	net.Listener
+	users []string
+}
+
+func newSOCKSServer() (*socksServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &socksServer{Listener: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s, nil
+}
+
+func (s *socksServer) serve(c net.Conn) {
+	defer c.Close()
+	b := make([]byte, 262)
+
+	// greeting, then username/password authentication
+	if _, err := io.ReadFull(c, b[:2]); err != nil {
+		return
+	}
+	io.ReadFull(c, b[:b[1]])
+	c.Write([]byte{5, 2})
+
+	io.ReadFull(c, b[:2])
+	user := make([]byte, b[1])
+	io.ReadFull(c, user)
+	io.ReadFull(c, b[:1])
+	io.ReadFull(c, b[:b[0]])
+	c.Write([]byte{1, 0})
+
+	s.Lock()
+	s.users = append(s.users, string(user))
+	s.Unlock()
+
+	// CONNECT request
+	io.ReadFull(c, b[:4])
+	var host string
+	switch b[3] {
+	case 1:
+		io.ReadFull(c, b[:4])
+		host = net.IP(b[:4]).String()
+	case 3:
+		io.ReadFull(c, b[:1])
+		n := int(b[0])
+		io.ReadFull(c, b[:n])
+		host = string(b[:n])
+	}
+	io.ReadFull(c, b[:2])
+	port := binary.BigEndian.Uint16(b[:2])
+
+	dst, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer dst.Close()
+	c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(dst, c)
+	io.Copy(c, dst)
+}
+
+func TestTor(t *testing.T) {
+	Convey("Testing sources fetched via tor", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ads.zeus.com\n")
+		}))
+		defer srv.Close()
+
+		socks, err := newSOCKSServer()
+		So(err, ShouldBeNil)
+		defer socks.Close()
+
+		p := &Parms{Method: "GET", Tor: socks.Addr().String()}
+		for _, name := range []string{"zeus", "yoyo"} {
+			o := getHTTP(&object{Parms: p, name: name, url: srv.URL, via: viaTor})
+			So(o.err, ShouldBeNil)
+		}
+
+		o := getHTTP(&object{Parms: p, name: "direct", url: srv.URL})
+		So(o.err, ShouldBeNil)
+		So(socks.users, ShouldResemble, []string{"zeus", "yoyo"})
+
+		Convey("Testing via configuration", func() {
+			cfg := "blacklist {\n\thosts {\n\t\tsource zeus {\n\t\t\turl http://zeus.com\n\t\t\tvia %v\n\t\t}\n\t}\n}"
+
+			c := NewConfig()
+			So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, viaTor)}), ShouldBeNil)
+			So(c.Get(hosts).x[0].via, ShouldEqual, viaTor)
+
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, "carrier-pigeon")})
+			So(err.Error(), ShouldEqual, `config.boot:5: source "zeus" can't be fetched via "carrier-pigeon"`)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// transform labels the configuration leaf for the entry transform script
+const transform = "transform"
+
+// transformArgs returns the transform script's command, a JSON array is an
+// argument list, anything else is the path of an executable script, e.g. a
+// Lua or Starlark script run by its #! interpreter
+func transformArgs(s string) ([]string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(s), "[") {
+		return []string{s}, nil
+	}
+
+	var args []string
+	if err := json.Unmarshal([]byte(s), &args); err != nil {
+		return nil, fmt.Errorf("invalid transform argument list: %v", err)
+	}
+	if len(args) == 0 || args[0] == "" {
+		return nil, errors.New("empty transform argument list")
+	}
+	return args, nil
+}
+
+// transforms returns true if o's entries are run through a transform script,
+// exclusions never are
+func (o *object) transforms() bool {
+	return o.Xform != "" && !o.nType.isExc()
+}
+
+// transform runs a source's entries, and its *.domain wildcard entries, through
+// the transform script as a single batch, one domain per line on stdin. The
+// script writes each domain to keep to stdout, optionally rewritten and
+// followed by a tag, domains it doesn't write are dropped. Rewritten domains
+// are checked against the exclusions again. If the script fails the entries
+// are kept unchanged
+func (o *object) transform(add, wilds list) (list, list, map[string]int, error) {
+	args, err := transformArgs(o.Xform)
+	if err != nil {
+		return add, wilds, nil, err
+	}
+
+	in := make([]string, 0, len(add.entry)+len(wilds.entry))
+	add.RLock()
+	for k := range add.entry {
+		in = append(in, k)
+	}
+	add.RUnlock()
+	wilds.RLock()
+	for k := range wilds.entry {
+		in = append(in, "*."+k)
+	}
+	wilds.RUnlock()
+	sort.Strings(in)
+
+	out, err := o.execute(args, strings.NewReader(strings.Join(in, "\n")+"\n"), false)
+	if err != nil {
+		return add, wilds, nil, fmt.Errorf("transform %v: %v", args[0], err)
+	}
+
+	var (
+		b    = bufio.NewScanner(bytes.NewReader(out))
+		tags map[string]int
+		xf   = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+		xw   = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+	)
+
+	for b.Scan() {
+		f := strings.Fields(strings.ToLower(b.Text()))
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+
+		d, to := f[0], xf
+		if strings.HasPrefix(d, "*.") {
+			d, to = d[2:], xw
+		}
+
+		known := add.keyExists(d) || wilds.keyExists(d)
+		if !known && (o.Dex.subKeyExists(d) || o.Exc.keyExists(d)) {
+			continue
+		}
+		to.set(d, 0)
+
+		if len(f) > 1 {
+			if tags == nil {
+				tags = make(map[string]int)
+			}
+			tags[f[1]]++
+		}
+	}
+
+	if err = b.Err(); err != nil {
+		return add, wilds, nil, err
+	}
+	return xf, xw, tags, nil
+}
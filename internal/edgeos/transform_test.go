@@ -0,0 +1,93 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTransform(t *testing.T) {
+	Convey("Testing the transform script", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		script := `#!/bin/sh
+while read d; do
+	case "$d" in
+	"*.wild.com") echo "*.wild.net wild" ;;
+	t.co|*.bit.ly) ;;
+	www.*) echo "${d#www.} rewritten" ;;
+	*) echo "$d" ;;
+	esac
+done
+`
+		So(ioutil.WriteFile(dir+"/policy.sh", []byte(script), 0755), ShouldBeNil)
+		So(ioutil.WriteFile(dir+"/tasty.hosts", []byte("www.ads.com\nt.co\nx.bit.ly\ntrack.com\nwww.apple.com\n*.wild.com\n"), 0644), ShouldBeNil)
+
+		cfg := `blacklist {
+	dns-redirect-ip 0.0.0.0
+	exclude apple.com
+	transform %[1]v/policy.sh
+	domains {
+	}
+	hosts {
+		source tasty {
+			file %[1]v/tasty.hosts
+		}
+	}
+}`
+		c := NewConfig(
+			Dir(dir),
+			Ext("blacklist.conf"),
+			FileNameFmt("%v/%v.%v.%v"),
+			Nodes([]string{rootNode, domains, hosts}),
+			Prefix("address="),
+			LTypes([]string{ExcDomns, ExcHosts, ExcRoots, PreDomns, PreHosts, files, urls}),
+			Stats(NewStatus("")),
+		)
+		So(c.ReadCfg(&CFGstatic{Cfg: fmt.Sprintf(cfg, dir)}), ShouldBeNil)
+		So(c.Xform, ShouldEqual, dir+"/policy.sh")
+
+		for _, o := range []IFace{ExRtObj, FileObj} {
+			ct, err := c.NewContent(o)
+			So(err, ShouldBeNil)
+			So(c.ProcessContent(ct), ShouldBeNil)
+		}
+
+		act, err := ioutil.ReadFile(dir + "/hosts.tasty.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(act), ShouldEqual, "address=/ads.com/0.0.0.0\naddress=/track.com/0.0.0.0\naddress=/.wild.net/0.0.0.0\n")
+		So(c.Status.Results(), ShouldResemble, []SourceResult{{Name: "tasty", Type: hosts, Entries: 3, Tags: map[string]int{"rewritten": 1, "wild": 1}}})
+
+		// domains the script drops are left for other sources
+		So(c.Exc.keyExists("track.com"), ShouldBeTrue)
+		So(c.Exc.keyExists("wild.net"), ShouldBeTrue)
+		So(c.Exc.keyExists("t.co"), ShouldBeFalse)
+		So(c.Exc.keyExists("wild.com"), ShouldBeFalse)
+
+		Convey("a failing script keeps the entries", func() {
+			c.SetOpt(Transform(dir + "/missing.sh"))
+			c.Status = NewStatus("")
+			c.Dex = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+			c.Exc = list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+
+			for _, o := range []IFace{ExRtObj, FileObj} {
+				ct, err := c.NewContent(o)
+				So(err, ShouldBeNil)
+				So(c.ProcessContent(ct), ShouldBeNil)
+			}
+
+			So(c.Status.Results()[0].Entries, ShouldEqual, 5)
+		})
+
+		Convey("Testing transform configuration errors", func() {
+			err := NewConfig().ReadCfg(&CFGstatic{Cfg: "blacklist {\n\ttransform '[\"lua\"'\n}"})
+			So(err.Error(), ShouldEqual, "config.boot:2: invalid transform argument list: unexpected end of JSON input")
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package edgeos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/britannic/blacklist/internal/regx"
+)
+
+// unblock records a "request unblock" form submission for the request's host
+func (b *BlockPage) unblock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := &UnblockRequest{Domain: strings.ToLower(hostname(r.Host)), Client: hostname(r.RemoteAddr), Time: time.Now()}
+	if !isFQDN(req.Domain) || net.ParseIP(req.Domain) != nil {
+		http.Error(w, "no domain to unblock", http.StatusBadRequest)
+		return
+	}
+
+	added, err := b.addPending(req.Domain)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if added && b.Webhook != "" {
+		go func() {
+			if err := notify(b.Webhook, req); err != nil && b.OnError != nil {
+				b.OnError(err)
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><body style=\"font-family: sans-serif; margin: 4em auto; max-width: 40em\"><p>Unblocking <b>%s</b> has been requested.</p></body></html>\n", html.EscapeString(req.Domain))
+}
+
+// isFQDN returns true if all of s is a domain name
+func isFQDN(s string) bool {
+	if strings.Trim(s, "abcdefghijklmnopqrstuvwxyz0123456789.-_") != "" {
+		return false
+	}
+	loc := regx.Obj.FQDN.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// addPending appends domain to the Pending file unless it's already there
+func (b *BlockPage) addPending(domain string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, err := ReadPending(b.Pending)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range pending {
+		if d == domain {
+			return false, nil
+		}
+	}
+
+	f, err := os.OpenFile(b.Pending, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = fmt.Fprintln(f, domain); err != nil {
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
+
+// ReadPending returns the domains in a pending exclusions file, one per line,
+// skipping lines that aren't domain names; a missing file has none
+func ReadPending(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var domains []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if d := strings.TrimSpace(s.Text()); isFQDN(d) {
+			domains = append(domains, d)
+		}
+	}
+	return domains, s.Err()
+}
+
+// notify POSTs an UnblockRequest to the admin's webhook
+func notify(url string, req *UnblockRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unblock webhook %v returned %v", url, resp.Status)
+	}
+	return nil
+}
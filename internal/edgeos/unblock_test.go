@@ -0,0 +1,107 @@
+package edgeos
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnblock(t *testing.T) {
+	Convey("Testing unblock requests", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		hooks := make(chan *UnblockRequest, 4)
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := &UnblockRequest{}
+			json.NewDecoder(r.Body).Decode(req)
+			hooks <- req
+		}))
+		defer hook.Close()
+
+		b, err := NewBlockPage("")
+		So(err, ShouldBeNil)
+
+		Convey("the button is only shown if Pending is set", func() {
+			w := httptest.NewRecorder()
+			b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://ads.example.com/", nil))
+			So(w.Body.String(), ShouldNotContainSubstring, unblockPath)
+
+			w = httptest.NewRecorder()
+			b.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://ads.example.com"+unblockPath, nil))
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+
+			b.Pending = dir + "/pending"
+			w = httptest.NewRecorder()
+			b.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://ads.example.com/", nil))
+			So(w.Body.String(), ShouldContainSubstring, `<form method="post" action="`+unblockPath+`">`)
+		})
+
+		Convey("requests are recorded once and notified", func() {
+			b.Pending, b.Webhook = dir+"/pending", hook.URL
+
+			tests := []struct {
+				name   string
+				method string
+				host   string
+				status int
+				notify bool
+			}{
+				{name: "first request", method: http.MethodPost, host: "Ads.Example.com:443", status: http.StatusOK, notify: true},
+				{name: "repeated request", method: http.MethodPost, host: "ads.example.com", status: http.StatusOK},
+				{name: "another domain", method: http.MethodPost, host: "cdn.example.net", status: http.StatusOK, notify: true},
+				{name: "get", method: http.MethodGet, host: "ads.example.com", status: http.StatusMethodNotAllowed},
+				{name: "ip address", method: http.MethodPost, host: "192.168.1.1", status: http.StatusBadRequest},
+				{name: "command", method: http.MethodPost, host: "x.com;reboot", status: http.StatusBadRequest},
+				{name: "quote", method: http.MethodPost, host: "x.com'", status: http.StatusBadRequest},
+				{name: "single label", method: http.MethodPost, host: "localhost", status: http.StatusBadRequest},
+				{name: "empty", method: http.MethodPost, host: "", status: http.StatusBadRequest},
+			}
+
+			for _, tt := range tests {
+				r := httptest.NewRequest(tt.method, "http://"+tt.host+unblockPath, nil)
+				r.Host = tt.host
+				w := httptest.NewRecorder()
+				b.ServeHTTP(w, r)
+				So(w.Code, ShouldEqual, tt.status)
+
+				if tt.notify {
+					select {
+					case req := <-hooks:
+						So(req.Domain, ShouldEqual, strings.ToLower(hostname(r.Host)))
+						So(req.Client, ShouldEqual, "192.0.2.1")
+					case <-time.After(time.Second):
+						So(tt.name, ShouldEqual, "notified")
+					}
+				}
+			}
+
+			So(hooks, ShouldBeEmpty)
+
+			act, err := ReadPending(b.Pending)
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{"ads.example.com", "cdn.example.net"})
+		})
+
+		Convey("lines that aren't domain names are skipped", func() {
+			So(ioutil.WriteFile(dir+"/edited", []byte("# pending\nads.example.com\nx.com;reboot\n\ncdn.example.net\n"), 0644), ShouldBeNil)
+			act, err := ReadPending(dir + "/edited")
+			So(err, ShouldBeNil)
+			So(act, ShouldResemble, []string{"ads.example.com", "cdn.example.net"})
+		})
+
+		Convey("a missing pending file has no requests", func() {
+			act, err := ReadPending(dir + "/missing")
+			So(err, ShouldBeNil)
+			So(act, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package edgeos
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// parseURLs is the source leaf for feeds that list URLs instead of hostnames,
+// its value is urlHost to keep each URL's hostname or urlRegistrable to
+// collapse it to its registrable domain
+const (
+	parseURLs      = "parse-urls"
+	urlHost        = "host"
+	urlRegistrable = "registrable"
+)
+
+// urlEntry returns the hostname of a URL-formatted entry, or its registrable
+// domain, entries without a scheme are read as http URLs and ones that don't
+// parse are returned unchanged
+func (o *object) urlEntry(entry []byte) []byte {
+	s := string(entry)
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Hostname() == "" {
+		return entry
+	}
+
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	if o.parseURL == urlRegistrable && net.ParseIP(host) == nil {
+		host = o.suffixes().registrable(host)
+	}
+	return bytes.ToLower([]byte(host))
+}
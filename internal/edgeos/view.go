@@ -0,0 +1,35 @@
+package edgeos
+
+// View returns a copy of c sharing its Parms, whose nodes, hooks,
+// instances, profiles and targets stay as they are while ReadCfg or Push
+// replace c's; readers running alongside them, such as the status API's
+// handlers, should work on a View
+func (c *Config) View() *Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Config{
+		Parms:     c.Parms,
+		tree:      c.tree,
+		hooks:     c.hooks,
+		instances: c.instances,
+		groups:    c.groups,
+		profiles:  c.profiles,
+		targets:   c.targets,
+		serial:    c.serial,
+	}
+}
+
+// swap replaces c's configuration with n's, n's serial is only taken if it
+// has one; the replaced values are never modified, so earlier Views
+// don't change
+func (c *Config) swap(n *Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Parms, c.tree, c.hooks, c.instances, c.profiles, c.targets = n.Parms, n.tree, n.hooks, n.instances, n.profiles, n.targets
+	c.groups = n.groups
+	if n.serial != 0 {
+		c.serial = n.serial
+	}
+}
@@ -0,0 +1,79 @@
+package edgeos
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestView(t *testing.T) {
+	Convey("Testing View()", t, func() {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+
+		cfg := func(d string) string {
+			return fmt.Sprintf("blacklist {\n\tdomains {\n\t\tinclude %v\n\t}\n}", d)
+		}
+
+		c := NewConfig(Nodes([]string{domains, hosts}), PushKey(pub))
+		So(c.ReadCfg(&CFGstatic{Cfg: cfg("local.example.com")}), ShouldBeNil)
+
+		v := c.View()
+		So(v.FWIncludes(), ShouldResemble, []string{"local.example.com"})
+
+		Convey("is unchanged by ReadCfg and Push", func() {
+			So(c.ReadCfg(&CFGstatic{Cfg: cfg("read.example.com")}), ShouldBeNil)
+			d := &PushDoc{Serial: 1, Config: cfg("pushed.example.com")}
+			d.Sign(priv)
+			So(c.Push(d), ShouldBeNil)
+
+			So(v.FWIncludes(), ShouldResemble, []string{"local.example.com"})
+			So(c.FWIncludes(), ShouldResemble, []string{"pushed.example.com"})
+			So(c.View().serial, ShouldEqual, 1)
+		})
+
+		Convey("keeps its transform while ReadCfg replaces it", func() {
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\ttransform /config/a.lua\n\tdomains {\n\t}\n}"}), ShouldBeNil)
+			v := c.View()
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\ttransform /config/b.lua\n\tdomains {\n\t}\n}"}), ShouldBeNil)
+
+			So(v.Xform, ShouldEqual, "/config/a.lua")
+			So(c.Xform, ShouldEqual, "/config/b.lua")
+		})
+
+		Convey("leaves the configuration as it was if ReadCfg fails", func() {
+			So(c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tsource x {\n\t}\n}"}), ShouldNotBeNil)
+			So(c.FWIncludes(), ShouldResemble, []string{"local.example.com"})
+		})
+
+		Convey("can be read while configurations are pushed", func() {
+			var (
+				wg    sync.WaitGroup
+				views = make([]*Config, 20)
+			)
+			for i := range views {
+				d := &PushDoc{Serial: int64(i + 1), Config: cfg(fmt.Sprintf("d%d.example.com", i))}
+				d.Sign(priv)
+
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					c.Push(d)
+				}()
+				go func(i int) {
+					defer wg.Done()
+					views[i] = c.View()
+				}(i)
+			}
+			wg.Wait()
+
+			for _, v := range views {
+				So(v.Nodes(), ShouldResemble, []string{rootNode, domains})
+				So(len(v.FWIncludes()), ShouldEqual, 1)
+			}
+		})
+	})
+}
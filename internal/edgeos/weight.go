@@ -0,0 +1,44 @@
+package edgeos
+
+import "sync"
+
+// weighed is true for the fetched sources whose domains are only blocked if
+// the summed weight of the sources listing them exceeds Thresh
+func (o *object) weighed() bool {
+	return o.Thresh > 0 && !o.nType.isExc() && (o.ltype == files || o.ltype == urls)
+}
+
+// weightOf returns the source's weight, sources without one count once
+func (o *object) weightOf() float64 {
+	if o.weight == 0 {
+		return 1
+	}
+	return o.weight
+}
+
+// tally drops the weighed sources' domains whose summed weight doesn't exceed
+// Thresh, a domain listed by several sources is kept by the first of them
+func (c *Config) tally(objs []*object, adds []list) {
+	votes := make(map[string]float64)
+	for i, o := range objs {
+		for d := range adds[i].entry {
+			votes[d] += o.weightOf()
+		}
+	}
+
+	for i, o := range objs {
+		keep := list{RWMutex: &sync.RWMutex{}, entry: make(entry)}
+		for d := range adds[i].entry {
+			if votes[d] <= c.Thresh || c.Exc.keyExists(d) || c.Dex.subKeyExists(d) {
+				continue
+			}
+			c.Exc.set(d, 0)
+			keep.set(d, 0)
+		}
+
+		if o.nType.isWild() {
+			c.Dex = mergeList(c.Dex, keep)
+		}
+		adds[i] = keep
+	}
+}
@@ -0,0 +1,93 @@
+package edgeos
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestThreshold(t *testing.T) {
+	Convey("Testing ProcessContent() with weighed sources", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		srcs := []struct {
+			name, node, weight, data string
+		}{
+			{name: "noisy", node: domains, data: "noisy.example.com\nshared.example.com\nconsensus.example.com\n"},
+			{name: "trusted", node: domains, weight: "2", data: "trusted.example.com\nshared.example.com\n"},
+			{name: "other", node: hosts, weight: "0.5", data: "consensus.example.com\n"},
+		}
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n"
+		for _, node := range []string{domains, hosts} {
+			cfg += "\t" + node + " {\n"
+			for _, s := range srcs {
+				if s.node != node {
+					continue
+				}
+				f := fmt.Sprintf("%v/%v.txt", dir, s.name)
+				So(ioutil.WriteFile(f, []byte(s.data), 0644), ShouldBeNil)
+				cfg += fmt.Sprintf("\t\tsource %v {\n\t\t\tprefix \"\"\n\t\t\tfile %v\n", s.name, f)
+				if s.weight != "" {
+					cfg += "\t\t\tweight " + s.weight + "\n"
+				}
+				cfg += "\t\t}\n"
+			}
+			cfg += "\t}\n"
+		}
+		cfg += "}"
+
+		tests := []struct {
+			threshold float64
+			exp       []string
+		}{
+			{threshold: 0, exp: []string{"address=/.consensus.example.com/0.0.0.0", "address=/.noisy.example.com/0.0.0.0", "address=/.shared.example.com/0.0.0.0", "address=/.trusted.example.com/0.0.0.0"}},
+			{threshold: 1, exp: []string{"address=/.consensus.example.com/0.0.0.0", "address=/.shared.example.com/0.0.0.0", "address=/.trusted.example.com/0.0.0.0"}},
+			{threshold: 2, exp: []string{"address=/.shared.example.com/0.0.0.0"}},
+			{threshold: 3, exp: nil},
+		}
+
+		for _, tt := range tests {
+			Convey(fmt.Sprintf("with a threshold of %v", tt.threshold), func() {
+				var b bytes.Buffer
+				c := NewConfig(
+					FileNameFmt("%v/%v.%v.%v"),
+					Nodes([]string{domains, hosts}),
+					Prefix("address="),
+					Threshold(tt.threshold),
+					Writer(&b),
+				)
+				So(c.ReadCfg(&CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+				ct, err := c.NewContent(FileObj)
+				So(err, ShouldBeNil)
+				So(c.ProcessContent(ct), ShouldBeNil)
+
+				var act []string
+				for _, l := range strings.Split(b.String(), "\n") {
+					if l != "" {
+						act = append(act, l)
+					}
+				}
+				sort.Strings(act)
+				So(act, ShouldResemble, tt.exp)
+			})
+		}
+
+		Convey("weights must be positive numbers", func() {
+			for _, w := range []string{"0", "-1", "heavy"} {
+				c := NewConfig(Nodes([]string{domains}))
+				err := c.ReadCfg(&CFGstatic{Cfg: "blacklist {\n\tdomains {\n\t\tsource s {\n\t\t\tfile /dev/null\n\t\t\tweight " + w + "\n\t\t}\n\t}\n}"})
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package edgeos
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// refreshable returns true if url sources may be downloaded in full at t,
+// which is always the case without RefreshWindows
+func (p *Parms) refreshable(t time.Time) bool {
+	if len(p.Windows) == 0 {
+		return true
+	}
+	for _, w := range p.Windows {
+		if w.Active(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// deferred returns true if the source's cached copy is used outside the
+// RefreshWindows, after a metadata-only check for changes; a changed source
+// is downloaded in the next window and a source without a cached copy is
+// downloaded straight away
+func (o *object) deferred(client *http.Client, endpoint string, auth authorizer) bool {
+	if o.refreshable(time.Now()) {
+		return false
+	}
+
+	if !o.cacheable() {
+		o.log(fmt.Sprintf("%v has no cached copy, downloading it outside the refresh windows", o.name))
+		return false
+	}
+
+	cached, err := o.readCache()
+	if err != nil || cached.URL != o.url {
+		o.log(fmt.Sprintf("%v has no cached copy, downloading it outside the refresh windows", o.name))
+		return false
+	}
+
+	remote, err := o.remoteMeta(client, endpoint, auth)
+	switch {
+	case err != nil:
+		o.debug(fmt.Sprintf("%v: pre-check failed: %v", o.name, redactErr(err, o.secrets)))
+		o.log(fmt.Sprintf("%v outside the refresh windows, using cached copy", o.name))
+	case !remote.matches(cached):
+		o.log(fmt.Sprintf("%v changed, using cached copy until the next refresh window", o.name))
+	default:
+		o.log(fmt.Sprintf("%v unchanged since last download, using cached copy", o.name))
+	}
+	return true
+}
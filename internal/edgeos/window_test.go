@@ -0,0 +1,74 @@
+package edgeos
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRefreshWindows(t *testing.T) {
+	Convey("Testing refresh windows", t, func() {
+		night, err := ParseSchedule("02:00-05:00")
+		So(err, ShouldBeNil)
+
+		Convey("downloads are allowed in a window or without any", func() {
+			p := &Parms{}
+			So(p.refreshable(time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)), ShouldBeTrue)
+
+			p.Windows = []*Schedule{night}
+			So(p.refreshable(time.Date(2026, 10, 16, 3, 0, 0, 0, time.Local)), ShouldBeTrue)
+			So(p.refreshable(time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)), ShouldBeFalse)
+		})
+
+		Convey("outside the windows cached sources are only checked for changes", func() {
+			dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			var (
+				body = "ads.example.com\n"
+				gets int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					gets++
+				}
+				w.Header().Set("Last-Modified", "Fri, 16 Oct 2026 00:00:00 GMT")
+				w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+				if r.Method == http.MethodGet {
+					fmt.Fprint(w, body)
+				}
+			}))
+			defer srv.Close()
+
+			// a window on no days is never open
+			closed := []*Schedule{{Start: 0, End: 1}}
+			get := func(windows []*Schedule) string {
+				c := NewConfig(Cache(dir), Method("GET"), RefreshWindows(windows))
+				o := getHTTP(&object{Parms: c.Parms, ltype: urls, name: "feed", nType: domn, url: srv.URL})
+				So(o.err, ShouldBeNil)
+
+				b, err := ioutil.ReadAll(o.r)
+				So(err, ShouldBeNil)
+				return string(b)
+			}
+
+			// there's no cached copy yet, so it is downloaded anyway
+			So(get(closed), ShouldEqual, "ads.example.com\n")
+			So(gets, ShouldEqual, 1)
+
+			body = "ads.example.com\ntracker.example.com\n"
+			So(get(closed), ShouldEqual, "ads.example.com\n")
+			So(gets, ShouldEqual, 1)
+
+			So(get(nil), ShouldEqual, body)
+			So(gets, ShouldEqual, 2)
+		})
+	})
+}
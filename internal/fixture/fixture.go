@@ -0,0 +1,231 @@
+// Package fixture serves recorded blacklist source lists from an httptest
+// server, so the whole download, parse and write pipeline can be tested
+// without network access
+//
+// Each list is served at /<name>, and the same list can be requested in a
+// number of failure and edge case scenarios by prefixing its path:
+//
+//	/gzip/<name>          sent with Content-Encoding: gzip
+//	/redirect/<n>/<name>  redirected n times before it is served
+//	/truncate/<name>      the first request is cut off halfway, later ones
+//	                      are served in full, honouring Range requests
+//	/status/<code>/<name> answered with the status code, e.g. 304 or 503,
+//	                      and no list
+//
+// Lists are served with Last-Modified and ETag headers and honour
+// If-Modified-Since and If-None-Match with 304 Not Modified, HEAD and Range
+// requests.
+package fixture
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modified is the Last-Modified time lists are served with
+var Modified = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Server is an httptest.Server serving recorded lists
+type Server struct {
+	*httptest.Server
+	sync.Mutex
+	cut   map[string]bool
+	hits  map[string]int
+	lists map[string][]byte
+}
+
+// NewServer starts a Server serving lists by name, the caller should Close it
+func NewServer(lists map[string]string) *Server {
+	s := &Server{cut: make(map[string]bool), hits: make(map[string]int), lists: make(map[string][]byte)}
+	for name, list := range lists {
+		s.lists[name] = []byte(list)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Load reads the files matching pattern, e.g. "testdata/sdata.hosts.*", into
+// lists keyed by their base names
+func Load(pattern string) (map[string]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		return nil, fmt.Errorf("no fixtures match %v", pattern)
+	}
+
+	lists := make(map[string]string)
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		lists[filepath.Base(f)] = string(b)
+	}
+	return lists, nil
+}
+
+// Set adds or replaces the list name, a changed list is served with a new
+// ETag but the same Last-Modified time
+func (s *Server) Set(name, list string) {
+	s.Lock()
+	defer s.Unlock()
+	s.lists[name] = []byte(list)
+}
+
+// URL returns the URL list name is served at in scenario, "" for the list
+// as it is, e.g. s.URL("adaway", "redirect/2")
+func (s *Server) URL(name, scenario string) string {
+	if scenario == "" {
+		return s.Server.URL + "/" + name
+	}
+	return s.Server.URL + "/" + strings.Trim(scenario, "/") + "/" + name
+}
+
+// Hits returns how many requests, including HEAD requests, were made for
+// path, e.g. "/truncate/adaway"
+func (s *Server) Hits(path string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.hits[path]
+}
+
+// serve answers a request for a list in its scenario
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.hits[r.URL.Path]++
+	cut := s.cut[r.URL.Path]
+	if r.Method == http.MethodGet {
+		s.cut[r.URL.Path] = true
+	}
+	s.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "gzip":
+		s.gzip(w, r, parts[1])
+
+	case len(parts) == 3 && parts[0] == "redirect":
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if n <= 0 {
+			s.list(w, r, parts[2])
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d/%v", n-1, parts[2]), http.StatusFound)
+
+	case len(parts) == 3 && parts[0] == "status":
+		code, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, http.StatusText(code), code)
+
+	case len(parts) == 2 && parts[0] == "truncate":
+		if !cut && r.Method == http.MethodGet {
+			s.truncate(w, parts[1])
+			return
+		}
+		s.list(w, r, parts[1])
+
+	case len(parts) == 1:
+		s.list(w, r, parts[0])
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// get returns the list name and its ETag
+func (s *Server) get(name string) ([]byte, string, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	b, ok := s.lists[name]
+	if !ok {
+		return nil, "", false
+	}
+
+	var sum uint32
+	for _, c := range b {
+		sum = sum*31 + uint32(c)
+	}
+	return b, fmt.Sprintf(`"%x-%x"`, len(b), sum), true
+}
+
+// list serves name with http.ServeContent, which handles conditional, HEAD
+// and Range requests
+func (s *Server) list(w http.ResponseWriter, r *http.Request, name string) {
+	b, etag, ok := s.get(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, Modified, bytes.NewReader(b))
+}
+
+// gzip serves name compressed with Content-Encoding: gzip
+func (s *Server) gzip(w http.ResponseWriter, r *http.Request, name string) {
+	b, _, ok := s.get(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Last-Modified", Modified.Format(http.TimeFormat))
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
+
+// truncate sends the first half of name with the full Content-Length, and
+// then drops the connection
+func (s *Server) truncate(w http.ResponseWriter, name string) {
+	b, etag, ok := s.get(name)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can't be hijacked", http.StatusInternalServerError)
+		return
+	}
+
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nAccept-Ranges: bytes\r\nContent-Length: %d\r\nContent-Type: text/plain; charset=utf-8\r\nETag: %v\r\nLast-Modified: %v\r\n\r\n",
+		len(b), etag, Modified.Format(http.TimeFormat))
+	buf.Write(b[:len(b)/2])
+	buf.Flush()
+}
@@ -0,0 +1,177 @@
+package fixture_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/britannic/blacklist/internal/edgeos"
+	"github.com/britannic/blacklist/internal/fixture"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const list = "ads.example.com\ntracker.example.com\n"
+
+func get(req *http.Request) (*http.Response, string, error) {
+	tr := &http.Transport{DisableCompression: true}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return resp, string(b), err
+}
+
+func TestServer(t *testing.T) {
+	Convey("Testing fixture.Server", t, func() {
+		s := fixture.NewServer(map[string]string{"ads": list})
+		defer s.Close()
+
+		Convey("lists are served with validators", func() {
+			req, _ := http.NewRequest(http.MethodGet, s.URL("ads", ""), nil)
+			resp, body, err := get(req)
+			So(err, ShouldBeNil)
+			So(body, ShouldEqual, list)
+			So(resp.Header.Get("Accept-Ranges"), ShouldEqual, "bytes")
+			So(resp.Header.Get("Last-Modified"), ShouldEqual, fixture.Modified.Format(http.TimeFormat))
+
+			req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+			resp, body, err = get(req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNotModified)
+			So(body, ShouldBeEmpty)
+
+			s.Set("ads", "changed.example.com\n")
+			resp, body, err = get(req)
+			So(err, ShouldBeNil)
+			So(body, ShouldEqual, "changed.example.com\n")
+			So(s.Hits("/ads"), ShouldEqual, 3)
+		})
+
+		Convey("gzip lists are compressed", func() {
+			req, _ := http.NewRequest(http.MethodGet, s.URL("ads", "gzip"), nil)
+			resp, body, err := get(req)
+			So(err, ShouldBeNil)
+			So(resp.Header.Get("Content-Encoding"), ShouldEqual, "gzip")
+
+			zr, err := gzip.NewReader(bytes.NewBufferString(body))
+			So(err, ShouldBeNil)
+			b, err := ioutil.ReadAll(zr)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, list)
+		})
+
+		Convey("redirected lists are served after the redirects", func() {
+			resp, err := http.Get(s.URL("ads", "redirect/2"))
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, list)
+			So(resp.Request.URL.Path, ShouldEqual, "/redirect/0/ads")
+		})
+
+		Convey("truncated lists are cut off once and can be resumed", func() {
+			req, _ := http.NewRequest(http.MethodGet, s.URL("ads", "truncate"), nil)
+			_, body, err := get(req)
+			So(err, ShouldNotBeNil)
+			So(body, ShouldEqual, list[:len(list)/2])
+
+			req.Header.Set("Range", "bytes=5-")
+			resp, body, err := get(req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusPartialContent)
+			So(body, ShouldEqual, list[5:])
+		})
+
+		Convey("status lists answer with their status", func() {
+			req, _ := http.NewRequest(http.MethodGet, s.URL("ads", "status/503"), nil)
+			resp, _, err := get(req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		Convey("unknown lists aren't found", func() {
+			req, _ := http.NewRequest(http.MethodGet, s.URL("missing", "redirect/0"), nil)
+			resp, _, err := get(req)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestLoad(t *testing.T) {
+	Convey("Testing fixture.Load()", t, func() {
+		lists, err := fixture.Load("../testdata/sdata.hosts.*")
+		So(err, ShouldBeNil)
+		So(lists, ShouldContainKey, "sdata.hosts.adaway")
+
+		_, err = fixture.Load("../testdata/missing.*")
+		So(err.Error(), ShouldEqual, "no fixtures match ../testdata/missing.*")
+	})
+}
+
+func TestPipeline(t *testing.T) {
+	Convey("Testing the pipeline against a fixture.Server", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		s := fixture.NewServer(map[string]string{
+			"cut":     "cut.example.com\nhalf.example.com\n",
+			"down":    "down.example.com\n",
+			"gzipped": "gzipped.example.com\n",
+			"moved":   "moved.example.com\n",
+		})
+		defer s.Close()
+
+		cfg := "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\tdomains {\n"
+		for _, src := range []struct{ name, scenario string }{
+			{name: "cut", scenario: "truncate"},
+			{name: "down", scenario: "status/503"},
+			{name: "gzipped", scenario: "gzip"},
+			{name: "moved", scenario: "redirect/3"},
+		} {
+			cfg += "\t\tsource " + src.name + " {\n\t\t\tprefix \"\"\n\t\t\turl " + s.URL(src.name, src.scenario) + "\n\t\t}\n"
+		}
+		cfg += "\t}\n}"
+
+		c := edgeos.NewConfig(
+			edgeos.Dir(dir),
+			edgeos.Ext("blacklist.conf"),
+			edgeos.FileNameFmt("%v/%v.%v.%v"),
+			edgeos.Method(http.MethodGet),
+			edgeos.Nodes([]string{"domains"}),
+			edgeos.Prefix("address="),
+			edgeos.Redirects(5),
+			edgeos.Resumes(1),
+			edgeos.Stats(edgeos.NewStatus("")),
+		)
+		So(c.ReadCfg(&edgeos.CFGstatic{Cfg: cfg}), ShouldBeNil)
+
+		ct, err := c.NewContent(edgeos.URLdObj)
+		So(err, ShouldBeNil)
+		So(c.ProcessContent(ct), ShouldBeNil)
+
+		read := func(src string) string {
+			b, _ := ioutil.ReadFile(dir + "/domains." + src + ".blacklist.conf")
+			return string(b)
+		}
+		So(read("cut"), ShouldEqual, "address=/.cut.example.com/0.0.0.0\naddress=/.half.example.com/0.0.0.0\n")
+		So(read("gzipped"), ShouldEqual, "address=/.gzipped.example.com/0.0.0.0\n")
+		So(read("moved"), ShouldEqual, "address=/.moved.example.com/0.0.0.0\n")
+		So(s.Hits("/truncate/cut"), ShouldEqual, 2)
+
+		So(read("down"), ShouldBeEmpty)
+
+		for _, r := range c.Status.Sources {
+			So(r.Error, ShouldBeEmpty)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package regx_test
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -169,3 +170,25 @@ var (
 
 	rxout = "CMNT: ^(?:[\\/*]+)(.*?)(?:[*\\/]+)$\nDESC: ^(?:description)+\\s\"?([^\"]+)?\"?$\nDSBL: ^(?:disabled)+\\s([\\S]+)$\nFLIP: ^(?:address=[/][.]{0,1}.*[/])(.*)$\nFQDN: \\b((?:(?:[^.-/]{0,1})[a-zA-Z0-9-_]{1,63}[-]{0,1}[.]{1})+(?:[a-zA-Z]{2,63}))\\b\nHOST: ^(?:address=[/][.]{0,1})(.*)(?:[/].*)$\nHTTP: (?:^(?:http|https){1}:)(?:\\/|%2f){1,2}(.*)\nIPBH: ^(?:dns-redirect-ip)+\\s([\\S]+)$\nLEAF: ^([\\S]+)+\\s([\\S]+)\\s[{]{1}$\nLBRC: [{]\nMISC: ^([\\w-]+)$\nMLTI: ^((?:include|exclude)+)\\s([\\S]+)$\nMPTY: ^$\nNAME: ^([\\w-]+)\\s[\"']{0,1}(.*?)[\"']{0,1}$\nNODE: ^([\\w-]+)\\s[{]{1}$\nRBRC: [}]\nSUFX: (?:#.*|\\{.*|[/[].*)\\z\n"
 )
+
+func FuzzStripPrefixAndSuffix(f *testing.F) {
+	f.Add([]byte(`127.0.0.1 ads.example.com # tracker`), "127.0.0.1 ")
+	f.Add([]byte(`https://phish.example.com/login.php`), "http")
+	f.Add([]byte(`zone "malware.example.net"  {type master; file "/etc/namedb/blockeddomain.hosts";};`), "zone ")
+	f.Add([]byte(``), "")
+
+	f.Fuzz(func(t *testing.T, line []byte, prefix string) {
+		act, ok := regx.Obj.StripPrefixAndSuffix(line, prefix)
+		if !ok {
+			return
+		}
+
+		if bytes.ContainsRune(act, '"') {
+			t.Errorf("StripPrefixAndSuffix(%q, %q) = %q, contains a quote", line, prefix, act)
+		}
+
+		if !bytes.Equal(act, bytes.TrimSpace(act)) {
+			t.Errorf("StripPrefixAndSuffix(%q, %q) = %q, isn't trimmed", line, prefix, act)
+		}
+	})
+}
@@ -1,11 +1,22 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	e "github.com/britannic/blacklist/internal/edgeos"
@@ -17,6 +28,15 @@ const (
 	files = "file"
 	pre   = "pre-configured"
 	urls  = "url"
+
+	// defaultsCache keeps the last default exclusions fetched, for when
+	// defaultsURL can't be reached
+	defaultsCache = "/config/user-data/blacklist.defaults"
+	// defaultsURL is the project's canonical default exclusions list
+	defaultsURL = "https://raw.githubusercontent.com/britannic/blacklist/master/defaults/excludes.txt"
+	// deadlineGrace is how long after -deadline the watchdog waits for files
+	// being written to be finished
+	deadlineGrace = 10 * time.Second
 )
 
 var (
@@ -36,31 +56,53 @@ var (
 		log.Errorf(s, args)
 	}
 
-	logCrit    = log.Critical
+	logCrit  = log.Critical
+	logFatal = func(err error) {
+		logCrit(err)
+		exitCmd(exitCode(err))
+	}
+
 	logFatalln = func(args ...interface{}) {
 		logCrit(args)
 		exitCmd(1)
 	}
 
 	logFile    = "blacklist.log"
+	logWriter  io.Writer
 	logInfo    = log.Info
 	logInfof   = log.Infof
 	logPrintf  = logInfof
 	logPrintln = logInfo
+	logWarning = log.Warning
 
-	objex = []e.IFace{
+	// content types registered with e.RegisterContent follow the built-in ones
+	objex = append([]e.IFace{
 		e.ExRtObj,
 		e.ExDmObj,
 		e.ExHtObj,
+		e.AlwObj,
 		e.PreDObj,
 		e.PreHObj,
 		e.FileObj,
 		e.URLdObj,
 		e.URLhObj,
-	}
+	}, e.Contents()...)
+
+	// sd notifies systemd of the daemon's state, it is nil unless started
+	// by a Type=notify unit
+	sd = e.NewNotifier()
 )
 
 func newLog() (*logging.Logger, error) {
+	fd, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	setLogBackend(fd)
+
+	return logging.MustGetLogger(basename(os.Args[0])), err
+}
+
+// setLogBackend logs to w, the screen and any extra backends
+func setLogBackend(w io.Writer, extra ...logging.Backend) {
+	logWriter = w
 	fdFmt := logging.MustStringFormatter(
 		`%{level:.4s}[%{id:03x}]%{time:2006-01-02 15:04:05.000} ▶ %{message}`,
 	)
@@ -69,29 +111,170 @@ func newLog() (*logging.Logger, error) {
 		`%{color:bold}%{level:.4s}%{color:reset}[%{id:03x}]%{time:15:04:05.000} ▶ %{message}`,
 	)
 
-	fd, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	fdlog := logging.NewLogBackend(fd, "", 0)
+	fdlog := logging.NewLogBackend(w, "", 0)
 	fdFmttr := logging.NewBackendFormatter(fdlog, fdFmt)
 
-	scr := logging.NewLogBackend(os.Stderr, "", 0)
-	scrFmttr := logging.NewBackendFormatter(scr, scrFmt)
+	// an interactive terminal shows the run's progress and summary instead,
+	// with only warnings and errors logged to it
+	if screen != nil && !screen.color {
+		scrFmt = logging.MustStringFormatter(`%{level:.4s}[%{id:03x}]%{time:15:04:05.000} ▶ %{message}`)
+	}
 
-	logging.SetBackend(fdFmttr, scrFmttr)
+	scr := logging.NewLogBackend(os.Stderr, "", 0)
+	scrFmttr := logging.AddModuleLevel(logging.NewBackendFormatter(scr, scrFmt))
+	if screen != nil {
+		scrFmttr.SetLevel(logging.WARNING, "")
+	}
 
-	return logging.MustGetLogger(basename(os.Args[0])), err
+	logging.SetBackend(append([]logging.Backend{fdFmttr, scrFmttr}, extra...)...)
 }
 
 func main() {
 
-	c := setUpEnv()
+	c, o := setUpEnv()
 	logInfo("Starting up...")
-	if err := removeStaleFiles(c); err != nil {
-		logFatalln(err)
+
+	if *o.DoH {
+		objex = append(objex, e.DoHObj)
+	}
+
+	if o.NArg() > 0 {
+		if err := runCommand(c, o.Args()); err != nil {
+			logFatalln(err)
+		}
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.Follow != "" {
+		followPrimary(c, *o.Follow)
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.TUI {
+		if err := runTUI(c); err != nil {
+			logFatalln(err)
+		}
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.Sched {
+		defer lockPID(o)()
+		runSchedule(c, o, time.Duration(*o.Poll)*time.Second)
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.BlkPage != "" {
+		serveBlockPage(*o.BlkPage, *o.BlkHTML, *o.BlkPend, *o.BlkNtfy)
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.Explain {
+		explainCfg(c, o)
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.FWGroup != "" {
+		exportFWGroup(c, *o.FWGroup)
+		logInfo("Shutting down...")
+		return
+	}
+
+	if *o.API != "" {
+		defer lockPID(o)()
+	}
+
+	if *o.Commit && !cfgChanged(c, *o.Digest) {
+		logInfo("Shutting down...")
+		return
 	}
 
-	// if err := processObjects(c, objex); err != nil {
-	// 	logFatalln(err)
-	// }
+	if *o.Status != "" || *o.StatsD != "" || sd != nil || screen != nil {
+		c.SetOpt(e.Stats(e.NewStatus(*o.Status)))
+	}
+
+	if *o.IPGroup != "" || wantsIPs(c) {
+		c.SetOpt(e.CollectIPs(true))
+	}
+
+	stop := runDeadline(c, *o.Dline)
+
+	if *o.Counts || *o.MACKey != "" {
+		verifyFiles(c)
+	}
+
+	err := runHooks(c, e.PreHook)
+
+	if err == nil {
+		err = removeStaleFiles(c)
+	}
+
+	if err == nil {
+		err = processObjects(c, objex)
+	}
+
+	if err == nil {
+		err = c.SyncInstances()
+	}
+
+	if err == nil {
+		var changed bool
+		if changed, err = c.WriteClientGroups(); err == nil && changed {
+			reloadDNS(c)
+		}
+	}
+
+	if err == nil {
+		err = renderTargets(c)
+	}
+
+	if err == nil {
+		err = runHooks(c, e.PostHook)
+	}
+	stop()
+
+	if *o.Timings {
+		logTimings(c)
+	}
+
+	if *o.Verb {
+		logRun(c)
+	}
+
+	logStale(c)
+	logExpired(c)
+	writeStatus(c, err)
+	recordHistory(c, err)
+	sd.Status(c.RunSummary(err))
+	if screen != nil {
+		screen.summary(c, err)
+	}
+	if *o.StatsD != "" {
+		pushStatsD(c, *o.StatsD, err)
+	}
+
+	if err != nil {
+		logFatal(err)
+	}
+
+	if *o.Commit {
+		if err = c.SaveDigest(*o.Digest); err != nil {
+			logError(err)
+		}
+	}
+
+	if *o.IPGroup != "" {
+		exportIPGroup(c, *o.IPGroup)
+	}
+
+	if *o.API != "" {
+		serveAPI(c, o)
+	}
 
 	logInfo("Shutting down...")
 	// reloadDNS(c)
@@ -117,56 +300,363 @@ func basename(s string) string {
 	return s
 }
 
+// exitCode maps an error to the process exit status, so monitoring can
+// tell failure classes apart: 2 missing or invalid configuration, 3 source fetch failure,
+// 4 dnsmasq reload failure, 5 -deadline exceeded, 1 anything else
+func exitCode(err error) int {
+	var (
+		parse  *e.ErrParse
+		fetch  *e.ErrSourceFetch
+		reload *e.ErrReload
+	)
+
+	// e.Errors unwraps to each of its errors, so the highest status wins
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, e.ErrDeadline):
+		return 5
+	case errors.As(err, &reload):
+		return 4
+	case errors.As(err, &fetch):
+		return 3
+	case errors.As(err, &parse), errors.Is(err, e.ErrConfigEmpty):
+		return 2
+	}
+	return 1
+}
+
+// watchdog exits once d has passed, for runs stuck where the -deadline can't
+// cancel them, e.g. in a hook or reloading dnsmasq
+func watchdog(d time.Duration) *time.Timer {
+	return time.AfterFunc(d, func() { logFatal(e.ErrDeadline) })
+}
+
+// runDeadline starts a run's -deadline d and its watchdog, if d is set; stop
+// ends both once the run finishes, so daemons start each run afresh
+func runDeadline(c *e.Config, d time.Duration) (stop func()) {
+	if d <= 0 {
+		return func() {}
+	}
+
+	c.SetOpt(e.Deadline(d))
+	w := watchdog(d + deadlineGrace)
+	return func() {
+		w.Stop()
+		c.SetOpt(e.Deadline(0))
+	}
+}
+
+// exportFWGroup prints the firewall address-group commands for the resolved includes
+func exportFWGroup(c *e.Config, name string) {
+	r, err := c.Upstream()
+	if err != nil {
+		logFatalln(err)
+	}
+
+	f := e.NewFWGroup(name, c.FWIncludes())
+	f.Resolver = r
+	f.Resolve()
+	for _, err := range f.Errs {
+		logError(err)
+	}
+
+	if _, err := f.WriteTo(os.Stdout); err != nil {
+		logFatalln(err)
+	}
+}
+
+// explainCfg prints the effective settings as JSON, those a flag given on
+// the command line overrides are attributed to it
+func explainCfg(c *e.Config, o *opts) {
+	set := make(map[string]bool)
+	o.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	x := c.Explain()
+	for _, ex := range x {
+		for _, s := range ex.Settings {
+			if s.Flag != "" && set[s.Flag] {
+				s.From = "flag -" + s.Flag
+			}
+		}
+	}
+
+	b, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		logFatalln(err)
+	}
+	fmt.Fprintln(stdout, string(b))
+}
+
+// loadDefaults adds the default global exclusions
+func loadDefaults(c *e.Config) {
+	d, err := c.LoadDefaults()
+	if err != nil {
+		logFatal(err)
+		return
+	}
+	logInfof("Loaded %d default excludes version %d from %v", len(d.Domains), d.Version, d.Origin)
+}
+
+// exportIPGroup prints the firewall address-group commands for the raw IP
+// entries collected from the run's sources, which dnsmasq can't block;
+// nothing is printed if none were collected, as the commands replace the
+// group
+func exportIPGroup(c *e.Config, name string) {
+	ips := c.IPs()
+	if len(ips) == 0 {
+		logWarning(fmt.Sprintf("No IP addresses collected from the sources, leaving address-group %v as it is", name))
+		return
+	}
+
+	f := e.NewFWGroup(name, nil).AddIPs(ips...)
+	if _, err := f.WriteTo(stdout); err != nil {
+		logFatalln(err)
+	}
+}
+
+// serveBlockPage answers HTTP and HTTPS requests for blocked domains sent to
+// the blackhole ip, unblock requests are recorded in pending if it's set
+func serveBlockPage(ip, html, pending, webhook string) {
+	b, err := e.NewBlockPage(html)
+	if err != nil {
+		logFatalln(err)
+	}
+
+	b.Pending, b.Webhook = pending, webhook
+	b.OnError = func(err error) { logErrorf("%v", err) }
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- http.ListenAndServe(net.JoinHostPort(ip, "80"), b)
+	}()
+	go func() {
+		srv := &http.Server{Addr: net.JoinHostPort(ip, "443"), Handler: b, TLSConfig: b.TLSConfig()}
+		errs <- srv.ListenAndServeTLS("", "")
+	}()
+
+	logInfof("Serving the block page on %v", ip)
+	logFatalln(<-errs)
+}
+
+// followPrimary installs the generated files published by a primary router
+func followPrimary(c *e.Config, primary string) {
+	logInfof("Following primary %v", primary)
+	if err := c.Follow(primary); err != nil {
+		logFatalln(err)
+	}
+}
+
+// lockPID writes the PID file, exiting if another daemon is running; the
+// returned func removes it
+func lockPID(o *opts) func() error {
+	if *o.PIDFile == "" {
+		return func() error { return nil }
+	}
+
+	release, err := e.LockPID(*o.PIDFile, *o.API)
+	switch err.(type) {
+	case nil:
+		return release
+	case *e.RunningError:
+		logFatal(err)
+	default:
+		logWarning(fmt.Sprintf("unable to write pid file: %v", err))
+	}
+	return func() error { return nil }
+}
+
+// writeStatus records the run's outcome in the status file, if enabled
+func writeStatus(c *e.Config, err error) {
+	if serr := c.WriteStatus(err); serr != nil {
+		logErrorf("unable to write status file: %v", serr)
+	}
+}
+
+// recordHistory appends the run's metrics to the -history file, if enabled
+func recordHistory(c *e.Config, err error) {
+	if herr := c.RecordHistory(err); herr != nil {
+		logErrorf("unable to record run history: %v", herr)
+	}
+}
+
+// logStale warns about each source that hasn't changed for -stale-days
+func logStale(c *e.Config) {
+	for _, s := range c.StaleSources() {
+		logWarning(fmt.Sprintf("Stale source: %v, it may be abandoned", s))
+	}
+}
+
+// logExpired reports the includes that have expired, so they can be
+// removed from the configuration
+func logExpired(c *e.Config) {
+	for _, x := range c.Expiries() {
+		if x.Expired {
+			logInfof("Include %v in %v expired %v and is no longer generated", x.Domain, x.Node, x.Expires.Format(time.RFC3339))
+		}
+	}
+}
+
+// logTimings logs how long each stage of the run took in total
+func logTimings(c *e.Config) {
+	var s []string
+	for _, t := range c.TimingTotals() {
+		s = append(s, t.String())
+	}
+	logInfof("Timings: %v", strings.Join(s, ", "))
+}
+
+// logRun logs the run's totals from its run state
+func logRun(c *e.Config) {
+	n := c.RunState().Counts()
+	logInfof("Run: %d entries from %d sources, %d failed, downloaded %v, parsed %d lines in %v",
+		n.Entries, n.Sources, n.Failed, mib(n.Bytes), n.Lines, n.Elapsed.Round(time.Millisecond))
+}
+
+// pushStatsD sends the run's metrics to a StatsD/Telegraf listener
+func pushStatsD(c *e.Config, addr string, err error) {
+	if serr := c.PushStatsD(addr, "blacklist", err); serr != nil {
+		logErrorf("unable to push StatsD metrics: %v", serr)
+	}
+}
+
 func (o *opts) initEdgeOS() *e.Config {
 	return e.NewConfig(
 		e.API("/bin/cli-shell-api"),
 		e.Arch(runtime.GOARCH),
+		e.BaseDir(*o.BaseDir),
 		e.Bash("/bin/bash"),
+		e.Cache(*o.Cache),
+		e.CAfile(*o.CAfile),
+		e.CatalogFile(*o.CatFile),
+		e.CatalogURL(*o.CatURL),
 		e.Cores(2),
+		e.Counts(*o.Counts),
 		e.Dbug(*o.Dbug),
+		e.DefExc(e.ExcDefaults{Cache: defaultsCache, File: *o.DefFile, URL: *o.DefURL}),
+		e.Deterministic(*o.Determ),
 		e.Dir(o.setDir(*o.ARCH)),
+		e.DoHList(o.dohDomains()),
+		e.DoHURL(*o.DoHURL),
 		e.DNSsvc("service dnsmasq restart"),
 		e.Ext("blacklist.conf"),
+		e.FailFile(*o.FailDB),
 		e.File(*o.File),
 		e.FileNameFmt("%v/%v.%v.%v"),
+		e.Force(*o.Force),
+		e.Gzip(*o.Gzip),
+		e.HistoryDays(*o.HistDay),
+		e.HistoryFile(*o.History),
+		e.HMACKey(*o.MACKey),
+		e.HTTPS(*o.HTTPS),
 		e.InCLI("inSession"),
 		e.Level("service dns forwarding"),
+		e.MaxChange(*o.MaxChg),
+		e.MaxFailures(*o.MaxFail),
+		e.MaxMemoryMB(*o.MaxMem),
 		e.Method("GET"),
-		e.Nodes([]string{"domains", "hosts"}),
+		e.Nice(*o.Nice),
+		e.Nodes(e.NodeKinds()),
+		e.Offline(*o.Offline),
+		e.Pins(o.pins()),
 		e.Poll(*o.Poll),
+		e.Precedence(*o.Prec),
 		e.Prefix("address="),
+		e.Protect(o.protected()),
+		e.PSL(*o.PSL),
+		e.PSLURL(*o.PSLURL),
+		e.Redact(o.redacted()),
+		e.Redirects(*o.Redirs),
+		e.RefuseSuffixes(*o.Refuse),
+		e.Resolver(*o.Resolv),
+		e.Quarantine(*o.Hold),
+		e.Resumes(*o.Resumes),
+		e.Sanity(*o.Sanity),
+		e.SeenFile(*o.Seen),
+		e.Shard(*o.Shard),
+		e.StaleDays(*o.Stale),
+		e.StaleFile(*o.StaleDB),
+		e.StateFiles([]string{*o.Digest, *o.PushDoc, *o.Status}),
+		e.Strict(*o.Strict),
+		e.Threshold(*o.Thresh),
+		e.TopDomains(*o.Top),
+		e.TopURL(*o.TopURL),
 		e.Logger(log),
 		e.LTypes([]string{files, e.PreDomns, e.PreHosts, urls}),
 		e.Timeout(30*time.Second),
+		e.Timings(*o.Timings),
+		e.Tor(*o.Tor),
 		e.Verb(*o.Verb),
 		e.WCard(e.Wildcard{Node: "*s", Name: "*"}),
-		e.Writer(ioutil.Discard),
 	)
 }
 
+// processObjects processes the objects' content together, so weighed sources
+// are tallied across all of them
 func processObjects(c *e.Config, objects []e.IFace) error {
+	var cts []e.Contenter
 	for _, o := range objects {
 		ct, err := c.NewContent(o)
 		if err != nil {
 			return err
 		}
-
-		if err = c.ProcessContent(ct); err != nil {
-			return err
-		}
+		cts = append(cts, ct)
 	}
-	return nil
+	return c.ProcessContent(cts...)
 }
 
 func reloadDNS(c *e.Config) {
 	b, err := c.ReloadDNS()
 	if err != nil {
 		logErrorf("ReloadDNS(): \n error: %v\n", string(b), err)
-		exitCmd(1)
+		exitCmd(exitCode(err))
 	}
 	logPrintf("ReloadDNS(): %v\n", string(b))
 }
 
+// cfgChanged reports whether the blacklist configuration changed since the
+// digest in file was saved, an unreadable digest counts as a change
+func cfgChanged(c *e.Config, file string) bool {
+	changed, err := c.CfgChanged(file)
+	switch {
+	case err != nil:
+		logErrorf("unable to compare the configuration with %v: %v", file, err)
+		return true
+	case !changed:
+		logInfo("Blacklist configuration unchanged since the last commit, skipping")
+	}
+	return changed
+}
+
+// renderTargets writes the configured output targets, logging their
+// post-commands' output
+func renderTargets(c *e.Config) error {
+	b, err := c.RenderTargets()
+	if len(b) > 0 {
+		logPrintf("RenderTargets(): %v\n", string(b))
+	}
+	return err
+}
+
+// runHooks runs the configured hooks for stage, logging their output
+func runHooks(c *e.Config, stage string) error {
+	b, err := c.RunHooks(stage)
+	if len(b) > 0 {
+		logPrintf("RunHooks(%v): %v\n", stage, string(b))
+	}
+	return err
+}
+
+// wantsIPs returns true if an ipset target needs the sources' IP address entries
+func wantsIPs(c *e.Config) bool {
+	for _, t := range c.Targets() {
+		if t.Format == "ipset" {
+			return true
+		}
+	}
+	return false
+}
+
 func removeStaleFiles(c *e.Config) error {
 	if err := c.GetAll().Files().Remove(); err != nil {
 		return fmt.Errorf("c.GetAll().Files().Remove() error: %v\n", err)
@@ -174,13 +664,381 @@ func removeStaleFiles(c *e.Config) error {
 	return nil
 }
 
-func setUpEnv() *e.Config {
+// verifyFiles logs the generated files that were truncated or changed since
+// they were written, the run regenerates them; files that fail their HMAC
+// check are discarded so dnsmasq never loads them
+func verifyFiles(c *e.Config) {
+	err := c.GetAll().Files().Verify()
+	if errs, ok := err.(e.Errors); ok {
+		for _, err := range errs {
+			t, ok := err.(*e.ErrTampered)
+			if !ok {
+				logError(err)
+				continue
+			}
+
+			logWarning(fmt.Sprintf("security: %v, discarding it to force regeneration", t))
+			if err := e.Discard(t.File); err != nil {
+				logError(err)
+			}
+		}
+		return
+	}
+
+	if err != nil {
+		logError(err)
+	}
+}
+
+// readPushKey reads a base64 encoded ed25519 public key from file
+func readPushKey(file string) (ed25519.PublicKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(k) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%v: not a base64 encoded ed25519 public key", file)
+	}
+	return ed25519.PublicKey(k), nil
+}
+
+// updateCatalog refreshes the source catalog from -catalog-url, a failure
+// leaves the last good catalog in use
+func updateCatalog(c *e.Config, o *opts) error {
+	switch {
+	case *o.CatURL == "":
+		return nil
+	case *o.CatKey == "":
+		return errors.New("-catalog-url needs -catalog-key <file>, the base64 ed25519 public key the catalog is signed with")
+	}
+
+	k, err := readPushKey(*o.CatKey)
+	if err != nil {
+		return err
+	}
+	c.SetOpt(e.CatalogKey(k))
+	return c.UpdateCatalog()
+}
+
+// readCfg loads the last pushed configuration if pushes are enabled and
+// there is one, otherwise the EdgeOS configuration
+func readCfg(c *e.Config, o *opts) error {
+	if *o.PushKey != "" {
+		k, err := readPushKey(*o.PushKey)
+		if err != nil {
+			return err
+		}
+		c.SetOpt(e.PushKey(k))
+
+		switch err = c.LoadPush(*o.PushDoc); {
+		case err == nil:
+			logInfof("Using pushed configuration from %v", *o.PushDoc)
+			return nil
+		case !os.IsNotExist(err):
+			logErrorf("Ignoring pushed configuration: %v", err)
+		}
+	}
+	return c.ReadCfg(o.getCFG(c))
+}
+
+// hup receives SIGHUP, which asks a daemon to reload its configuration
+var hup = make(chan os.Signal, 1)
+
+// reloadCfg re-reads the configuration into c between runs, it is true if c
+// changed; c keeps its running configuration if the new one doesn't parse,
+// and a pushed configuration is only replaced by the next push
+func reloadCfg(c *e.Config, o *opts) bool {
+	if *o.PushKey != "" {
+		if _, err := os.Stat(*o.PushDoc); err == nil {
+			logInfof("Not reloading, running the configuration pushed to %v", *o.PushDoc)
+			return false
+		}
+	}
+
+	logInfo("Reloading configuration")
+	if err := c.Reload(o.getCFG(c)); err != nil {
+		logErrorf("Keeping the running configuration: %v", err)
+		return false
+	}
+	logInfof("Reloaded configuration, %d blocking profiles", len(c.Profiles()))
+	return true
+}
+
+// runSchedule blocks, installing the active blocking profile every interval
+// and reloading dnsmasq whenever it changes, file sources are regenerated as
+// soon as they change and SIGHUP reloads the configuration
+func runSchedule(c *e.Config, o *opts, interval time.Duration) {
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	logInfof("Scheduling %d blocking profiles", len(c.Profiles()))
+	w := c.NewFileWatch()
+	defer w.Close()
+	sd.Notify(e.NotifyReady, fmt.Sprintf("STATUS=Scheduling %d blocking profiles", len(c.Profiles())))
+	for {
+		select {
+		case <-hup:
+			sd.Notify(e.NotifyReload)
+			if reloadCfg(c, o) {
+				c.ResetExclusions()
+				w.Changed()
+			}
+			sd.Notify(e.NotifyReady)
+		default:
+		}
+
+		stop := runDeadline(c, *o.Dline)
+		changed, err := c.ApplyProfile(time.Now())
+		switch {
+		case err != nil:
+			logError(err)
+			sd.Status(c.RunSummary(err))
+		case changed:
+			reloadDNS(c)
+			sd.Status(c.RunSummary(nil))
+		}
+
+		if names := w.Changed(); names != nil {
+			regenerate(c, names)
+		}
+		stop()
+		sd.Wait(interval, w.C)
+	}
+}
+
+// regenerate processes the named file sources again and reloads dnsmasq
+func regenerate(c *e.Config, names []string) {
+	for _, name := range names {
+		logInfof("Source %q changed, regenerating it", name)
+		if err := c.Retry(name); err != nil {
+			logError(err)
+			sd.Status(c.RunSummary(err))
+			return
+		}
+	}
+	reloadDNS(c)
+	sd.Status(c.RunSummary(nil))
+}
+
+// applyMu serializes applying pushed and reloaded configurations
+var applyMu sync.Mutex
+
+// applyCfg generates the blacklist from c's current configuration and
+// reloads dnsmasq, as a run does, within its own -deadline, dropping the
+// exclusions collected for the previous one; callers hold applyMu
+func applyCfg(c *e.Config, o *opts, status string) error {
+	stop := runDeadline(c, *o.Dline)
+	defer stop()
+
+	logInfo(status)
+	sd.Notify(e.NotifyReload, "STATUS="+status)
+	c.ResetExclusions()
+	// a later push or reload may replace c's configuration while this runs
+	s := c.View()
+	err := runHooks(s, e.PreHook)
+	if err == nil {
+		err = removeStaleFiles(s)
+	}
+	if err == nil {
+		err = processObjects(s, objex)
+	}
+	if err == nil {
+		err = renderTargets(s)
+	}
+	if err == nil {
+		_, err = s.ReloadDNS()
+	}
+	if err == nil {
+		err = runHooks(s, e.PostHook)
+	}
+	sd.Status(c.RunSummary(err))
+	sd.Notify(e.NotifyReady)
+	return err
+}
+
+// serveAPI blocks serving the status API on -api, pushed configurations are
+// saved to -push-doc and applied straight away, SIGHUP reloads and applies
+// the configuration and file sources are regenerated as soon as they change
+func serveAPI(c *e.Config, o *opts) {
+	a := c.NewStatusAPI()
+	a.PushFile = *o.PushDoc
+	a.Lock = &applyMu
+	a.OnPush = func() error {
+		return applyCfg(c, o, "Applying pushed configuration")
+	}
+
+	w := c.NewFileWatch()
+	defer w.Close()
+	go func() {
+		for range w.C {
+			applyMu.Lock()
+			if names := w.Changed(); names != nil {
+				stop := runDeadline(c, *o.Dline)
+				regenerate(c, names)
+				stop()
+			}
+			applyMu.Unlock()
+		}
+	}()
+
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			applyMu.Lock()
+			if reloadCfg(c, o) {
+				if err := applyCfg(c, o, "Applying reloaded configuration"); err != nil {
+					logError(err)
+				}
+			}
+			applyMu.Unlock()
+		}
+	}()
+
+	if every := sd.WatchdogInterval(); every > 0 {
+		go func() {
+			for range time.Tick(every) {
+				sd.Notify(e.NotifyWatchdog)
+			}
+		}()
+	}
+
+	l, err := net.Listen("tcp", *o.API)
+	if err != nil {
+		logFatalln(err)
+	}
+
+	logInfof("Serving status API on %v", *o.API)
+	sd.Notify(e.NotifyReady)
+	if err = http.Serve(l, a); err != nil {
+		logFatalln(err)
+	}
+}
+
+func setUpEnv() (*e.Config, *opts) {
 	o := getOpts()
 	o.Init("blacklist", flag.ExitOnError)
 	o.setArgs()
 
 	c := o.initEdgeOS()
-	c.ReadCfg(o.getCFG(c))
+	if screen = newTTY(os.Stdout, *o.NoColor); screen != nil {
+		setLogBackend(logWriter)
+	}
+	if p := *o.Prec; p != e.PrecedenceInclude && p != e.PrecedenceExclude {
+		logFatal(fmt.Errorf("unknown precedence %q, must be %v or %v", p, e.PrecedenceInclude, e.PrecedenceExclude))
+	}
+	if h := *o.HTTPS; h != e.HTTPSallow && h != e.HTTPSupgrade && h != e.HTTPSrequire {
+		logFatal(fmt.Errorf("unknown https policy %q, must be %v or %v", h, e.HTTPSupgrade, e.HTTPSrequire))
+	}
+	if err := e.CheckPins(o.pins()); err != nil {
+		logFatal(err)
+	}
+
+	if *o.MaxSize != "" {
+		n, err := e.ParseSize(*o.MaxSize)
+		if err != nil {
+			logFatal(err)
+		}
+		c.SetOpt(e.MaxSize(n))
+	}
+
+	if *o.Rate != "" {
+		n, err := e.ParseRate(*o.Rate)
+		if err != nil {
+			logFatal(err)
+		}
+		c.SetOpt(e.RateLimit(n))
+	}
+
+	w, err := o.windows()
+	if err != nil {
+		logFatal(err)
+	}
+	c.SetOpt(e.RefreshWindows(w))
+
+	ch, err := o.chaos()
+	if err != nil {
+		logFatal(err)
+	}
+	if ch != nil {
+		logWarning("Injecting failures for testing, don't use -fail-source, -slow-source or -corrupt-output in production")
+	}
+	c.SetOpt(e.Inject(ch))
+
+	t, err := o.tuning()
+	if err != nil {
+		logFatal(err)
+	}
+	c.SetOpt(e.Tune(t))
+	logInfof("Tuned for %v: %v", *o.ARCH, t)
+
+	if err = c.LowerPriority(); err != nil {
+		logFatal(err)
+	}
+
+	if *o.LogFile != "" {
+		n, err := e.ParseSize(*o.LogSize)
+		if err != nil {
+			logFatal(err)
+		}
+		c.SetOpt(e.LogFile(*o.LogFile), e.LogRotate(n, *o.LogKeep))
+
+		w, err := c.LogWriter()
+		if err != nil {
+			logFatal(err)
+		}
+		setLogBackend(w)
+	}
+
+	if *o.Syslog != "" {
+		c.SetOpt(e.Syslog(*o.Syslog, *o.SysFac, *o.SysTag))
+
+		b, err := c.SyslogBackend()
+		if err != nil {
+			logFatal(err)
+		}
+		setLogBackend(logWriter, b)
+	}
+
+	if *o.Reload != "" {
+		ctl, err := e.NewServiceController(*o.Reload)
+		if err != nil {
+			logFatal(err)
+		}
+		c.SetOpt(e.DNSctl(ctl))
+	}
+
+	switch {
+	case screen != nil:
+		c.SetOpt(e.OnProgress(screen.progress(time.Second)))
+	case *o.Verb:
+		c.SetOpt(e.OnProgress(progressLogger(5 * time.Second)))
+	}
+
+	if err := updateCatalog(c, o); err != nil {
+		logErrorf("catalog: %v, using the previous catalog", err)
+	}
+
+	// commands that check the environment run without a configuration, as
+	// loading it may be what fails
+	if cmd, ok := commands[o.Arg(0)]; ok && cmd.bare {
+		return c, o
+	}
+
+	start := time.Now()
+	if err := readCfg(c, o); err != nil {
+		logFatal(err)
+	}
+	c.Timed("config", "", start)
+
+	if *o.Defs {
+		loadDefaults(c)
+	}
+
+	for _, cf := range c.Conflicts() {
+		logInfof("%v: %v include conflicts with %v exclude %v, %v wins", cf.Domain, cf.Include, cf.Exclude, cf.Excluded, cf.Winner)
+	}
 
-	return c
+	return c, o
 }
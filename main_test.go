@@ -2,14 +2,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/britannic/blacklist/internal/edgeos"
 	"github.com/britannic/blacklist/internal/tdata"
@@ -34,6 +40,8 @@ func TestMain(t *testing.T) {
 			}
 		}
 
+		logFatal = func(err error) { act = append(act, err) }
+
 		logPrintf = func(s string, vals ...interface{}) {
 			actReloadDNS = fmt.Sprintf(s, vals)
 		}
@@ -46,8 +54,68 @@ func TestMain(t *testing.T) {
 	})
 }
 
+func TestMainRun(t *testing.T) {
+	Convey("Testing that main() fetches and writes the sources in a normal run", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		var act []error
+		origArgs, origObjex, origFatal, origFatalln := os.Args, objex, logFatal, logFatalln
+		defer func() { os.Args, objex, logFatal, logFatalln = origArgs, origObjex, origFatal, origFatalln }()
+		exitCmd = func(int) { return }
+		logFatal = func(err error) { act = append(act, err) }
+		logFatalln = func(vals ...interface{}) { act = append(act, fmt.Errorf("%v", vals)) }
+		objex = []edgeos.IFace{edgeos.ExRtObj, edgeos.ExDmObj, edgeos.ExHtObj, edgeos.PreDObj, edgeos.PreHObj, edgeos.FileObj}
+
+		src := dir + "/local.hosts"
+		So(ioutil.WriteFile(src, []byte("ads.example.com\ntracker.example.net\nkeep.example.org\n192.0.2.7\n"), 0644), ShouldBeNil)
+		cfg := dir + "/config.boot"
+		So(ioutil.WriteFile(cfg, []byte("blacklist {\n\tdns-redirect-ip 0.0.0.0\n\texclude keep.example.org\n\tdomains {\n\t\tinclude blocked.example.com\n\t}\n\thosts {\n\t\tsource local {\n\t\t\tfile "+src+"\n\t\t}\n\t}\n}\n"), 0644), ShouldBeNil)
+
+		out := new(bytes.Buffer)
+		origOut := stdout
+		defer func() { stdout = origOut }()
+		stdout = out
+
+		status := dir + "/status.json"
+		os.Args = []string{path.Base(os.Args[0]), "-f", cfg, "-tmp", dir, "-status", status, "-ipgroup", "BLACKLIST", "-doh", "-doh-domains", "doh.example.net"}
+		main()
+		So(act, ShouldBeNil)
+		So(out.String(), ShouldStartWith, "delete firewall group address-group BLACKLIST\n")
+		So(out.String(), ShouldContainSubstring, "address 192.0.2.7")
+
+		out.Reset()
+		exportIPGroup(edgeos.NewConfig(edgeos.CollectIPs(true)), "BLACKLIST")
+		So(out.String(), ShouldBeEmpty)
+
+		b, err := ioutil.ReadFile(dir + "/hosts.local.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/ads.example.com/0.0.0.0\naddress=/tracker.example.net/0.0.0.0\n")
+
+		b, err = ioutil.ReadFile(dir + "/pre-configured-domain.includes.[1].blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/blocked.example.com/0.0.0.0\n")
+
+		b, err = ioutil.ReadFile(dir + "/domains.doh-providers.blacklist.conf")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "address=/.doh.example.net/0.0.0.0\n")
+
+		var st edgeos.Status
+		b, err = ioutil.ReadFile(status)
+		So(err, ShouldBeNil)
+		So(json.Unmarshal(b, &st), ShouldBeNil)
+		So(st.Success, ShouldBeTrue)
+		var n int
+		for _, r := range st.Sources {
+			n += r.Entries
+		}
+		So(n, ShouldEqual, 4)
+	})
+}
+
 func TestProcessObjects(t *testing.T) {
-	c := setUpEnv()
+	c, _ := setUpEnv()
 	Convey("Testing processObjects", t, func() {
 		Convey("Testing that the config is correctly loaded ", func() {
 			So(c.String(), ShouldEqual, mainGetConfig)
@@ -139,6 +207,33 @@ func TestGetOpts(t *testing.T) {
 	})
 }
 
+func TestSetUpEnvPolicies(t *testing.T) {
+	Convey("Testing that setUpEnv() rejects unknown policies", t, func() {
+		var act []error
+		origArgs, origFatal := os.Args, logFatal
+		defer func() { os.Args, logFatal = origArgs, origFatal }()
+		logFatal = func(err error) { act = append(act, err) }
+
+		prog := path.Base(os.Args[0])
+		tests := []struct {
+			args []string
+			exp  []error
+		}{
+			{args: []string{"-https", "upgrade", "-precedence", "exclude"}},
+			{args: []string{"-https", "require"}},
+			{args: []string{"-https", "always"}, exp: []error{errors.New(`unknown https policy "always", must be upgrade or require`)}},
+			{args: []string{"-precedence", "none"}, exp: []error{errors.New(`unknown precedence "none", must be include or exclude`)}},
+		}
+
+		for _, tt := range tests {
+			act = nil
+			os.Args = append([]string{prog}, tt.args...)
+			setUpEnv()
+			So(act, ShouldResemble, tt.exp)
+		}
+	})
+}
+
 func TestBasename(t *testing.T) {
 	Convey("Testing basename()", t, func() {
 		tests := []struct {
@@ -169,6 +264,63 @@ func TestBuild(t *testing.T) {
 	})
 }
 
+func TestExitCode(t *testing.T) {
+	Convey("Testing exitCode()", t, func() {
+		fetch := &edgeos.ErrSourceFetch{Source: "zeus", Cause: io.EOF}
+		tests := []struct {
+			err error
+			exp int
+		}{
+			{err: nil, exp: 0},
+			{err: io.EOF, exp: 1},
+			{err: edgeos.ErrConfigEmpty, exp: 2},
+			{err: &edgeos.ErrParse{File: "config.boot", Line: 1}, exp: 2},
+			{err: fetch, exp: 3},
+			{err: &edgeos.ErrReload{Cause: io.EOF}, exp: 4},
+			{err: edgeos.Errors{io.EOF, fetch}, exp: 3},
+			{err: edgeos.ErrDeadline, exp: 5},
+			{err: edgeos.Errors{fetch, edgeos.ErrDeadline}, exp: 5},
+			{err: fmt.Errorf("feeds: %w", fetch), exp: 3},
+			{err: fmt.Errorf("reload: %w", &edgeos.ErrReload{Cause: io.EOF}), exp: 4},
+			{err: edgeos.Errors{io.EOF, fmt.Errorf("config: %w", edgeos.ErrConfigEmpty)}, exp: 2},
+			{err: fmt.Errorf("run: %w", edgeos.Errors{fetch, &edgeos.ErrReload{Cause: io.EOF}}), exp: 4},
+		}
+
+		for _, tt := range tests {
+			So(exitCode(tt.err), ShouldEqual, tt.exp)
+		}
+	})
+}
+
+func TestWatchdog(t *testing.T) {
+	Convey("Testing watchdog()", t, func() {
+		code := make(chan int, 1)
+		orig := logFatal
+		logFatal = func(err error) { code <- exitCode(err) }
+		defer func() { logFatal = orig }()
+
+		watchdog(10 * time.Millisecond)
+		So(<-code, ShouldEqual, 5)
+
+		watchdog(time.Hour).Stop()
+
+		Convey("each run has its own deadline", func() {
+			c := edgeos.NewConfig()
+			runDeadline(c, 0)()
+			So(c.Dline, ShouldEqual, 0)
+
+			stop := runDeadline(c, time.Hour)
+			So(c.Dline, ShouldEqual, time.Hour)
+			stop()
+			So(c.Dline, ShouldEqual, 0)
+
+			stop = runDeadline(c, time.Hour)
+			So(c.Dline, ShouldEqual, time.Hour)
+			stop()
+		})
+	})
+}
+
 func TestCommandLineArgs(t *testing.T) {
 	Convey("Testing command line arguments", t, func() {
 		origArgs := os.Args
@@ -206,6 +358,55 @@ func TestGetCFG(t *testing.T) {
 		c = o.initEdgeOS()
 		c.ReadCfg(o.getCFG(c))
 		So(c.String(), ShouldEqual, "{\n  \"nodes\": [{\n  }]\n}")
+
+		f, err := ioutil.TempFile("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(tdata.Cfg)
+		So(err, ShouldBeNil)
+		So(f.Close(), ShouldBeNil)
+
+		*o.File = f.Name()
+		c = o.initEdgeOS()
+		So(c.ReadCfg(o.getCFG(c)), ShouldBeNil)
+		So(c.String(), ShouldEqual, mainGetConfig)
+	})
+}
+
+func TestReadPushKey(t *testing.T) {
+	Convey("Testing readPushKey()", t, func() {
+		pub, _, err := ed25519.GenerateKey(nil)
+		So(err, ShouldBeNil)
+
+		f, err := ioutil.TempFile("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.Remove(f.Name())
+
+		So(ioutil.WriteFile(f.Name(), []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0600), ShouldBeNil)
+		k, err := readPushKey(f.Name())
+		So(err, ShouldBeNil)
+		So(k, ShouldResemble, pub)
+
+		So(ioutil.WriteFile(f.Name(), []byte("c2hvcnQ="), 0600), ShouldBeNil)
+		_, err = readPushKey(f.Name())
+		So(err.Error(), ShouldEqual, f.Name()+": not a base64 encoded ed25519 public key")
+
+		_, err = readPushKey("/tmp/does.not.exist")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestUpdateCatalog(t *testing.T) {
+	Convey("Testing updateCatalog()", t, func() {
+		o := getOpts()
+		c := o.initEdgeOS()
+		So(updateCatalog(c, o), ShouldBeNil)
+
+		*o.CatURL = "http://127.0.0.1:1/catalog.json"
+		So(updateCatalog(c, o).Error(), ShouldEqual, "-catalog-url needs -catalog-key <file>, the base64 ed25519 public key the catalog is signed with")
+
+		*o.CatKey = "/tmp/does.not.exist"
+		So(updateCatalog(c, o), ShouldNotBeNil)
 	})
 }
 
@@ -220,7 +421,7 @@ func TestReloadDNS(t *testing.T) {
 			exp = "ReloadDNS(): [dnsmasq: unrecognized service\n]\n"
 		}
 
-		c := setUpEnv()
+		c, _ := setUpEnv()
 		exitCmd = func(int) { return }
 		logPrintf = func(s string, v ...interface{}) {
 			act = fmt.Sprintf(s, v)
@@ -233,13 +434,169 @@ func TestReloadDNS(t *testing.T) {
 
 func TestRemoveStaleFiles(t *testing.T) {
 	Convey("Testing removeStaleFiles()", t, func() {
-		c := setUpEnv()
+		c, _ := setUpEnv()
 		So(removeStaleFiles(c), ShouldBeNil)
 		_ = c.SetOpt(edgeos.Dir("EinenSieAugenBlick"), edgeos.Ext("[]a]"), edgeos.FileNameFmt("[]a]"), edgeos.WCard(edgeos.Wildcard{Node: "[]a]", Name: "]"}))
 		So(removeStaleFiles(c), ShouldNotBeNil)
 	})
 }
 
+func TestCfgChanged(t *testing.T) {
+	Convey("Testing cfgChanged()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c, _ := setUpEnv()
+		file := dir + "/blacklist.digest"
+
+		So(cfgChanged(c, file), ShouldBeTrue)
+		So(c.SaveDigest(file), ShouldBeNil)
+		So(cfgChanged(c, file), ShouldBeFalse)
+		So(cfgChanged(c, dir), ShouldBeTrue)
+	})
+}
+
+func TestReloadCfg(t *testing.T) {
+	Convey("Testing reloadCfg()", t, func() {
+		dir, err := ioutil.TempDir("/tmp", "testBlacklist")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		c, o := setUpEnv()
+		file := dir + "/config.boot"
+		*o.File = file
+
+		So(ioutil.WriteFile(file, []byte("blacklist {\n\tdomains {\n\t\tinclude reloaded.example.com\n\t}\n}"), 0644), ShouldBeNil)
+		So(reloadCfg(c, o), ShouldBeTrue)
+		So(c.FWIncludes(), ShouldResemble, []string{"reloaded.example.com"})
+
+		So(ioutil.WriteFile(file, []byte("blacklist {\n\tsource x {\n\t}\n}"), 0644), ShouldBeNil)
+		So(reloadCfg(c, o), ShouldBeFalse)
+		So(c.FWIncludes(), ShouldResemble, []string{"reloaded.example.com"})
+
+		pushDoc := *o.PushDoc
+		*o.PushKey, *o.PushDoc = dir+"/push.key", file
+		defer func() { *o.PushKey, *o.PushDoc, *o.File = "", pushDoc, "" }()
+		So(reloadCfg(c, o), ShouldBeFalse)
+	})
+}
+
+func TestExplainCfg(t *testing.T) {
+	Convey("Testing explainCfg()", t, func() {
+		act := new(bytes.Buffer)
+		orig := stdout
+		stdout = act
+		defer func() { stdout = orig }()
+
+		o := getOpts()
+		So(o.Parse([]string{"-redirects", "3"}), ShouldBeNil)
+		c := o.initEdgeOS()
+		So(c.ReadCfg(&edgeos.CFGstatic{Cfg: "blacklist {\n\tdns-redirect-ip 0.0.0.0\n\thosts {\n\t\tsource yoyo {\n\t\t\turl http://pgl.yoyo.org/as/serverlist.php\n\t\t}\n\t}\n}"}), ShouldBeNil)
+
+		explainCfg(c, o)
+		var x []*edgeos.Explained
+		So(json.Unmarshal(act.Bytes(), &x), ShouldBeNil)
+		So(len(x), ShouldEqual, 3)
+		So(x[2].Source, ShouldEqual, "yoyo")
+
+		from := make(map[string]string)
+		for _, s := range x[2].Settings {
+			from[s.Name] = s.From
+		}
+		So(from["redirects"], ShouldEqual, "flag -redirects")
+		So(from["max-size"], ShouldEqual, "default")
+		So(from["dns-redirect-ip"], ShouldEqual, "node blacklist")
+	})
+}
+
+func TestTuning(t *testing.T) {
+	Convey("Testing tuning()", t, func() {
+		o := getOpts()
+		*o.ARCH = "mips64"
+
+		tn, err := o.tuning()
+		So(err, ShouldBeNil)
+		So(tn, ShouldResemble, edgeos.ArchTuning("mips64"))
+
+		*o.Cores, *o.Fetches, *o.LineBuf, *o.Dedupe = 1, 6, "2M", edgeos.DedupePresize
+		tn, err = o.tuning()
+		So(err, ShouldBeNil)
+		So(tn, ShouldResemble, edgeos.Tuning{Cores: 1, Fetches: 6, LineBuf: 2 << 20, Dedupe: edgeos.DedupePresize})
+
+		*o.Dedupe = "sorted"
+		_, err = o.tuning()
+		So(err.Error(), ShouldEqual, `unknown dedupe strategy "sorted", must be grow or presize`)
+
+		*o.Dedupe, *o.LineBuf = "", "lots"
+		_, err = o.tuning()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestWindows(t *testing.T) {
+	Convey("Testing windows()", t, func() {
+		o := getOpts()
+		w, err := o.windows()
+		So(err, ShouldBeNil)
+		So(w, ShouldBeNil)
+
+		*o.Windows = "02:00-05:00; 21:00-23:00 sat,sun"
+		_, err = o.windows()
+		So(err.Error(), ShouldEqual, "-refresh-window needs -cache to keep copies of sources between windows")
+
+		*o.Cache = "/tmp"
+		w, err = o.windows()
+		So(err, ShouldBeNil)
+		So(w, ShouldHaveLength, 2)
+		So(w[1].Days[time.Saturday], ShouldBeTrue)
+
+		*o.Windows = "02:00"
+		_, err = o.windows()
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestChaos(t *testing.T) {
+	Convey("Testing chaos()", t, func() {
+		o := getOpts()
+		ch, err := o.chaos()
+		So(err, ShouldBeNil)
+		So(ch, ShouldBeNil)
+
+		*o.FailSrc = "yoyo, malc0de"
+		*o.SlowSrc = "big=30s"
+		*o.Corrupt = true
+		ch, err = o.chaos()
+		So(err, ShouldBeNil)
+		So(ch, ShouldResemble, &edgeos.Chaos{
+			Fail:    map[string]bool{"yoyo": true, "malc0de": true},
+			Slow:    map[string]time.Duration{"big": 30 * time.Second},
+			Corrupt: true,
+		})
+
+		*o.SlowSrc = "big"
+		_, err = o.chaos()
+		So(err.Error(), ShouldEqual, `-slow-source "big" must be name=duration`)
+
+		*o.SlowSrc = "big=soon"
+		_, err = o.chaos()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Testing the chaos flags are hidden", t, func() {
+		act := new(bytes.Buffer)
+		o := getOpts()
+		o.SetOutput(act)
+		printDefaults(o.FlagSet)
+		for name := range hidden {
+			So(o.Lookup(name), ShouldNotBeNil)
+			So(act.String(), ShouldNotContainSubstring, "-"+name)
+			So(o.String(), ShouldNotContainSubstring, strings.ToUpper(name))
+		}
+	})
+}
+
 func TestSetArch(t *testing.T) {
 	Convey("Testing getCFG()", t, func() {
 		exitCmd = func(int) { return }
@@ -274,42 +631,51 @@ func TestInitEdgeOS(t *testing.T) {
 		o := getOpts()
 		p := o.initEdgeOS()
 		exp := `{
-	"Module": "blacklist",
-	"ExtraCalldepth": 0,
-	"API": "/bin/cli-shell-api",
-	"Arch": "amd64",
-	"Bash": "/bin/bash",
-	"Cores": 2,
-	"Dbug": false,
-	"Dex": {
-		"entry": {}
-	},
-	"Dir": "/tmp",
-	"dnsmasq service": "service dnsmasq restart",
-	"Exc": {
-		"entry": {}
+	"api": "/bin/cli-shell-api",
+	"arch": "amd64",
+	"bash": "/bin/bash",
+	"catalogFile": "/config/user-data/blacklist.catalog.json",
+	"cores": 2,
+	"defaults": {
+		"Cache": "/config/user-data/blacklist.defaults",
+		"URL": "https://raw.githubusercontent.com/britannic/blacklist/master/defaults/excludes.txt"
 	},
-	"dnsmasq fileExt.": "blacklist.conf",
-	"File": "",
-	"File name fmt": "%v/%v.%v.%v",
-	"CLI Path": "service dns forwarding",
-	"Leaf nodes": [
+	"dir": "/tmp",
+	"dnsService": "service dnsmasq restart",
+	"ext": "blacklist.conf",
+	"failFile": "/config/user-data/blacklist.fails.json",
+	"fileNameFormat": "%v/%v.%v.%v",
+	"inCLI": "inSession",
+	"level": "service dns forwarding",
+	"leafTypes": [
 		"file",
 		"pre-configured-domain",
 		"pre-configured-host",
 		"url"
 	],
-	"HTTP method": "GET",
-	"Nodes": [
+	"method": "GET",
+	"nodes": [
 		"domains",
 		"hosts"
 	],
-	"Prefix": "address=",
-	"Poll": 5,
-	"Test": false,
-	"Timeout": 30000000000,
-	"Verbosity": false,
-	"Wildcard": {}
+	"prefix": "address=",
+	"poll": 5,
+	"precedence": "include",
+	"redirects": 10,
+	"resumes": 3,
+	"seenFile": "/config/user-data/blacklist.seen.json",
+	"staleFile": "/config/user-data/blacklist.stale.json",
+	"stateFiles": [
+		"/config/user-data/blacklist.digest",
+		"/config/user-data/blacklist.push.json",
+		""
+	],
+	"timeout": "30s",
+	"tor": "127.0.0.1:9050",
+	"wildcard": {
+		"node": "*s",
+		"name": "*"
+	}
 }`
 		So(fmt.Sprint(p.Parms), ShouldEqual, exp)
 	})
@@ -320,30 +686,194 @@ var (
 
 	mainGetConfig = "{\n  \"nodes\": [{\n    \"blacklist\": {\n      \"disabled\": \"false\",\n      \"ip\": \"0.0.0.0\",\n      \"excludes\": [\n        \"1e100.net\",\n        \"2o7.net\",\n        \"adobedtm.com\",\n        \"akamai.net\",\n        \"akamaihd.net\",\n        \"amazon.com\",\n        \"amazonaws.com\",\n        \"apple.com\",\n        \"ask.com\",\n        \"avast.com\",\n        \"bitdefender.com\",\n        \"cdn.visiblemeasures.com\",\n        \"cloudfront.net\",\n        \"coremetrics.com\",\n        \"edgesuite.net\",\n        \"freedns.afraid.org\",\n        \"github.com\",\n        \"githubusercontent.com\",\n        \"google.com\",\n        \"googleadservices.com\",\n        \"googleapis.com\",\n        \"googletagmanager.com\",\n        \"googleusercontent.com\",\n        \"gstatic.com\",\n        \"gvt1.com\",\n        \"gvt1.net\",\n        \"hb.disney.go.com\",\n        \"hp.com\",\n        \"hulu.com\",\n        \"images-amazon.com\",\n        \"live.com\",\n        \"microsoft.com\",\n        \"msdn.com\",\n        \"msecnd.net\",\n        \"paypal.com\",\n        \"rackcdn.com\",\n        \"schema.org\",\n        \"shopify.com\",\n        \"skype.com\",\n        \"smacargo.com\",\n        \"sourceforge.net\",\n        \"ssl-on9.com\",\n        \"ssl-on9.net\",\n        \"sstatic.net\",\n        \"static.chartbeat.com\",\n        \"storage.googleapis.com\",\n        \"windows.net\",\n        \"xboxlive.com\",\n        \"yimg.com\",\n        \"ytimg.com\"\n        ]\n    },\n    \"domains\": {\n      \"disabled\": \"false\",\n      \"ip\": \"192.168.100.1\",\n      \"excludes\": [],\n      \"includes\": [\n        \"adsrvr.org\",\n        \"adtechus.net\",\n        \"advertising.com\",\n        \"centade.com\",\n        \"doubleclick.net\",\n        \"free-counter.co.uk\",\n        \"intellitxt.com\",\n        \"kiosked.com\",\n        \"patoghee.in\"\n        ],\n      \"sources\": [{\n        \"malc0de\": {\n          \"disabled\": \"false\",\n          \"description\": \"List of zones serving malicious executables observed by malc0de.com/database/\",\n          \"ip\": \"192.168.168.1\",\n          \"prefix\": \"zone \",\n          \"url\": \"http://malc0de.com/bl/ZONES\",\n        },\n        \"malwaredomains.com\": {\n          \"disabled\": \"false\",\n          \"description\": \"Just domains\",\n          \"ip\": \"10.0.0.1\",\n          \"url\": \"http://mirror1.malwaredomains.com/files/justdomains\",\n        },\n        \"simple_tracking\": {\n          \"disabled\": \"false\",\n          \"description\": \"Basic tracking list by Disconnect\",\n          \"url\": \"https://s3.amazonaws.com/lists.disconnect.me/simple_tracking.txt\",\n        },\n        \"zeus\": {\n          \"disabled\": \"false\",\n          \"description\": \"abuse.ch ZeuS domain blocklist\",\n          \"url\": \"https://zeustracker.abuse.ch/blocklist.php?download=domainblocklist\",\n        }\n    }]\n    },\n    \"hosts\": {\n      \"disabled\": \"false\",\n      \"excludes\": [],\n      \"includes\": [\"beap.gemini.yahoo.com\"],\n      \"sources\": [{\n        \"openphish\": {\n          \"disabled\": \"false\",\n          \"description\": \"OpenPhish automatic phishing detection\",\n          \"prefix\": \"http\",\n          \"url\": \"https://openphish.com/feed.txt\",\n        },\n        \"raw.github.com\": {\n          \"disabled\": \"false\",\n          \"description\": \"This hosts file is a merged collection of hosts from reputable sources\",\n          \"prefix\": \"0.0.0.0 \",\n          \"url\": \"https://raw.githubusercontent.com/StevenBlack/hosts/master/hosts\",\n        },\n        \"sysctl.org\": {\n          \"disabled\": \"false\",\n          \"description\": \"This hosts file is a merged collection of hosts from cameleon\",\n          \"ip\": \"172.16.16.1\",\n          \"prefix\": \"127.0.0.1\\t \",\n          \"url\": \"http://sysctl.org/cameleon/hosts\",\n        },\n        \"tasty\": {\n          \"disabled\": \"false\",\n          \"description\": \"File source\",\n          \"ip\": \"10.10.10.10\",\n          \"file\": \"../testdata/blist.hosts.src\",\n        },\n        \"volkerschatz\": {\n          \"disabled\": \"false\",\n          \"description\": \"Ad server blacklists\",\n          \"prefix\": \"http\",\n          \"url\": \"http://www.volkerschatz.com/net/adpaths\",\n        },\n        \"yoyo\": {\n          \"disabled\": \"false\",\n          \"description\": \"Fully Qualified Domain Names only - no prefix to strip\",\n          \"url\": \"http://pgl.yoyo.org/as/serverlist.php?hostformat=nohtml&showintro=1&mimetype=plaintext\",\n        }\n    }]\n    }\n  }]\n}"
 
-	vanillaArgs = `  -arch string
+	vanillaArgs = `  -api <address>
+    	<address> # Serve the status API, e.g. ":8080"
+  -arch string
     	Set EdgeOS CPU architecture (default "amd64")
+  -base-dir <dir>
+    	<dir> # Resolve relative file sources against this directory
+  -blockpage <ip>
+    	<ip> # Serve a "blocked by policy" page on port 80 and 443 of the blackhole IP
+  -blockpage-html <file>
+    	<file> # html/template served by -blockpage, {{.Domain}} is the blocked domain
+  -blockpage-notify <url>
+    	<url> # POST unblock requests from the block page to this webhook as JSON
+  -blockpage-pending <file>
+    	<file> # Add a "request unblock" button to the block page, recording requested domains here
+  -cache <dir>
+    	<dir> # Cache url sources here and skip downloading them when a HEAD pre-check shows no change
+  -cafile <file>
+    	<file> # Trust this PEM CA bundle for HTTPS sources
+  -catalog-file <file>
+    	<file> # Keep the catalog downloaded from -catalog-url here (default "/config/user-data/blacklist.catalog.json")
+  -catalog-key <file>
+    	<file> # Verify the -catalog-url catalog with this base64 ed25519 public key, required with -catalog-url
+  -catalog-url <url>
+    	<url> # Refresh the source catalog daily from this signed JSON catalog, signed with -catalog-key
+  -cores <n>
+    	<n> # Sources formatted and written at once, 0 uses the -arch default
+  -counts
+    	Write each generated file's entry count and hash to a .count file, and check the files against them at startup
+  -deadline <duration>
+    	<duration> # Give up on an update run that takes longer than this, keeping the previous files, e.g. 10m
   -debug
     	Enable debug mode
+  -dedupe <strategy>
+    	<strategy> # Dedupe map strategy: grow or presize, the -arch default if not set
+  -defaults
+    	Add the default global exclusions, updated from -defaults-url
+  -defaults-file <file>
+    	<file> # Local override for the default exclusions
+  -defaults-url <url>
+    	<url> # Canonical default exclusions list (default "https://raw.githubusercontent.com/britannic/blacklist/master/defaults/excludes.txt")
+  -deterministic
+    	Generate byte-identical files from identical configurations and sources, e.g. to detect drift between routers
+  -digest <file>
+    	<file> # Save the configuration digest -on-commit compares with here (default "/config/user-data/blacklist.digest")
   -dir string
     	Override dnsmasq directory (default "/etc/dnsmasq.d")
+  -doh
+    	Block DNS-over-HTTPS provider domains
+  -doh-domains <domain,...>
+    	<domain,...> # Replace the built-in DNS-over-HTTPS provider domains -doh blocks
+  -doh-url <url>
+    	<url> # Update the -doh provider domains from this list, the built-in or -doh-domains set is used if it can't be read
+  -explain
+    	Print each node's and source's effective settings as JSON, with the leaf, default or flag each comes from
   -f <file>
     	<file> # Load a configuration file
+  -fail-file <file>
+    	<file> # Where -max-failures records each source's consecutive failed fetches (default "/config/user-data/blacklist.fails.json")
+  -fetches <n>
+    	<n> # Sources downloaded at once, 0 uses the -arch default
+  -follow <url>
+    	<url> # Replicate generated files from a primary router's status API
+  -force
+    	Generate the blacklist even if -sanity finds popular domains in it or it changes more than -max-change allows
+  -fwgroup <name>
+    	<name> # Print firewall address-group commands for the include domains, resolved with -resolver or dnsmasq's upstream servers
+  -gzip
+    	Also write gzip compressed copies of generated files
   -h	Display help
+  -history <file>
+    	<file> # Append each run's metrics to this JSON lines file, or CSV if it ends in .csv, for the report command
+  -history-days <days>
+    	<days> # Drop -history runs older than this, 0 keeps them all (default 365)
+  -hmac-key <file>
+    	<file> # Sign generated files with the key in this file, created if missing, and discard files modified outside blacklist at startup
+  -https <policy>
+    	<policy> # Plain HTTP source policy: upgrade or require
   -i int
     	Polling interval (default 5)
+  -ipgroup <name>
+    	<name> # Print firewall address-group commands for raw IP entries found in sources
+  -line-buffer <size>
+    	<size> # Longest source line read, e.g. 1M, the -arch default if not set
+  -log-keep int
+    	Rotated -logfile copies kept (default 3)
+  -log-size <size>
+    	<size> # Rotate -logfile once it reaches this size, 0 never rotates it (default "1M")
+  -logfile <file>
+    	<file> # Log to this file, rotated at -log-size, instead of blacklist.log in the working directory
+  -max-change <percent>
+    	<percent> # Keep the previous files and fail if a run would add and remove more than this percentage of their entries, 0 allows any change
+  -max-failures <n>
+    	<n> # Auto-disable a source after this many consecutive failed fetches, until update -source retries it successfully
+  -max-memory <MB>
+    	<MB> # Spill downloads to disk and fetch fewer at once if the sources would need more memory
+  -max-size <size>
+    	<size> # Default per-source download limit, e.g. 20M
   -mips64 string
     	Override target EdgeOS CPU architecture (default "mips64")
+  -nice <1-19>
+    	<1-19> # Run at this lower CPU priority, with the lowest best-effort I/O priority on Linux, and leave a core free for routing and DNS
+  -no-color
+    	Show the interactive terminal output without colors, as setting NO_COLOR does
+  -offline
+    	Skip network fetches, regenerating url sources from their -cache copies
+  -on-commit
+    	Skip the run unless the blacklist configuration changed since the last -on-commit run, for an EdgeOS commit hook
   -os string
     	Override native EdgeOS OS (default "` + runtime.GOOS + `")
+  -pid-file <file>
+    	<file> # Refuse to start a second -schedule or -api daemon while the one recorded here runs (default "/config/user-data/blacklist.pid")
+  -pins <sha256,...>
+    	<sha256,...> # Only accept HTTPS source certificates with these fingerprints, unless a source sets its own pin
+  -precedence <rule>
+    	<rule> # Whether include or exclude wins when a domain is in both (default "include")
+  -protect <domain,...>
+    	<domain,...> # Replace the built-in domains that are never blocked, the router's hostname and NTP servers are always protected
+  -psl <file>
+    	<file> # Public suffix list for parse-urls registrable sources, e.g. a copy of publicsuffix.org's public_suffix_list.dat
+  -psl-url <url>
+    	<url> # Download the public suffix list from this URL, saving it to -psl for when it can't be reached
+  -push-doc <file>
+    	<file> # Where pushed configurations are saved (default "/config/user-data/blacklist.push.json")
+  -push-key <file>
+    	<file> # Accept configurations pushed to -api signed by this base64 ed25519 public key
+  -quarantine <duration>
+    	<duration> # Hold domains back for this long after a source first lists them, e.g. 24h
+  -rate-limit <size>
+    	<size> # Cap the bandwidth all downloads share at this many bytes per second, e.g. 2M
+  -redact <param,...>
+    	<param,...> # Replace the built-in query parameters whose values are redacted from logs and the run status, URL passwords and secrets are always redacted
+  -redirects int
+    	Maximum redirects followed per source (default 10)
+  -refresh-window <HH:MM-HH:MM [day,...];...>
+    	<HH:MM-HH:MM [day,...];...> # Only download url sources in full during these daily windows, outside them -cache copies are used after checking for changes
+  -refuse-suffixes
+    	Drop entries that are public suffixes, e.g. co.uk or github.io, instead of only warning about them
+  -reload <controller>
+    	<controller> # DNS service controller: dnsmasq, dnsmasq-hup, none, systemd-resolved, unbound
+  -resolver <server>
+    	<server> # Look up source hostnames with this DNS server instead of the system resolver: ip[:port], tls://host[:port] or a DoH https:// URL
+  -resumes int
+    	Maximum times an interrupted download is resumed with a Range request (default 3)
+  -sanity
+    	Check the generated blacklist against -top-domains and fail if it blocks any of them
+  -schedule
+    	Run as a daemon, swapping blocking profiles at their schedule boundaries
+  -seen <file>
+    	<file> # Where -quarantine records when domains were first listed (default "/config/user-data/blacklist.seen.json")
+  -shard <lines>
+    	<lines> # Split each generated file into blacklist.000.conf, blacklist.001.conf... of at most this many lines
+  -stale-days <days>
+    	<days> # Report the sources whose content hasn't changed in this many days, as likely abandoned
+  -stale-file <file>
+    	<file> # Where -stale-days records when each source's content last changed (default "/config/user-data/blacklist.stale.json")
+  -statsd <host:port>
+    	<host:port> # Push run metrics to a StatsD/Telegraf listener over UDP
+  -status <file>
+    	<file> # Write a JSON run status file for monitoring agents
+  -strict
+    	Fail on unknown or unparsable configuration lines
+  -syslog <address>
+    	<address> # Also log RFC5424 messages to syslog: local, udp://host[:port] or tcp://host[:port]
+  -syslog-facility <facility>
+    	<facility> # Facility -syslog messages are sent with, e.g. local3 (default "daemon")
+  -syslog-tag <tag>
+    	<tag> # App name -syslog messages are sent with (default "blacklist")
   -t	Run config and data validation tests
+  -threshold <weight>
+    	<weight> # Only block domains listed by sources whose summed weight exceeds this
+  -timings
+    	Log how long each stage of the run takes, and record it in the -status file
   -tmp string
     	Override dnsmasq temporary directory (default "/tmp")
+  -top-domains <file>
+    	<file> # Popular domains -sanity checks for, one domain or rank,domain per line, e.g. a Tranco list; a built-in set is used if not set
+  -top-url <url>
+    	<url> # Download the -sanity popular domains from this URL, saving it to -top-domains for when it can't be reached
+  -tor <host:port>
+    	<host:port> # Tor SOCKS proxy for sources configured "via tor" (default "127.0.0.1:9050")
+  -tui
+    	Show an interactive source status and control screen
   -v	Verbose display
   -version
     	Show version
 `
 
-	vanillaArgsOnDrone = "  -arch=\"amd64\": Set EdgeOS CPU architecture\n  -debug=false: Enable debug mode\n  -dir=\"/etc/dnsmasq.d\": Override dnsmasq directory\n  -f=\"\": `<file>` # Load a configuration file\n  -h=false: Display help\n  -i=5: Polling interval\n  -mips64=\"mips64\": Override target EdgeOS CPU architecture\n  -os=\"linux\": Override native EdgeOS OS\n  -t=false: Run config and data validation tests\n  -tmp=\"/tmp\": Override dnsmasq temporary directory\n  -v=false: Verbose display\n  -version=false: Show version\n"
+	vanillaArgsOnDrone = "  -api=\"\": `<address>` # Serve the status API, e.g. \":8080\"\n  -arch=\"amd64\": Set EdgeOS CPU architecture\n  -base-dir=\"\": `<dir>` # Resolve relative file sources against this directory\n  -blockpage=\"\": `<ip>` # Serve a \"blocked by policy\" page on port 80 and 443 of the blackhole IP\n  -blockpage-html=\"\": `<file>` # html/template served by -blockpage, {{.Domain}} is the blocked domain\n  -blockpage-notify=\"\": `<url>` # POST unblock requests from the block page to this webhook as JSON\n  -blockpage-pending=\"\": `<file>` # Add a \"request unblock\" button to the block page, recording requested domains here\n  -cache=\"\": `<dir>` # Cache url sources here and skip downloading them when a HEAD pre-check shows no change\n  -cafile=\"\": `<file>` # Trust this PEM CA bundle for HTTPS sources\n  -catalog-file=\"/config/user-data/blacklist.catalog.json\": `<file>` # Keep the catalog downloaded from -catalog-url here\n  -catalog-key=\"\": `<file>` # Verify the -catalog-url catalog with this base64 ed25519 public key, required with -catalog-url\n  -catalog-url=\"\": `<url>` # Refresh the source catalog daily from this signed JSON catalog, signed with -catalog-key\n  -cores=0: `<n>` # Sources formatted and written at once, 0 uses the -arch default\n  -counts=false: Write each generated file's entry count and hash to a .count file, and check the files against them at startup\n  -deadline=\"0s\": `<duration>` # Give up on an update run that takes longer than this, keeping the previous files, e.g. 10m\n  -debug=false: Enable debug mode\n  -dedupe=\"\": `<strategy>` # Dedupe map strategy: grow or presize, the -arch default if not set\n  -defaults=false: Add the default global exclusions, updated from -defaults-url\n  -defaults-file=\"\": `<file>` # Local override for the default exclusions\n  -defaults-url=\"https://raw.githubusercontent.com/britannic/blacklist/master/defaults/excludes.txt\": `<url>` # Canonical default exclusions list\n  -deterministic=false: Generate byte-identical files from identical configurations and sources, e.g. to detect drift between routers\n  -digest=\"/config/user-data/blacklist.digest\": `<file>` # Save the configuration digest -on-commit compares with here\n  -dir=\"/etc/dnsmasq.d\": Override dnsmasq directory\n  -doh=false: Block DNS-over-HTTPS provider domains\n  -doh-domains=\"\": `<domain,...>` # Replace the built-in DNS-over-HTTPS provider domains -doh blocks\n  -doh-url=\"\": `<url>` # Update the -doh provider domains from this list, the built-in or -doh-domains set is used if it can't be read\n  -explain=false: Print each node's and source's effective settings as JSON, with the leaf, default or flag each comes from\n  -f=\"\": `<file>` # Load a configuration file\n  -fail-file=\"/config/user-data/blacklist.fails.json\": `<file>` # Where -max-failures records each source's consecutive failed fetches\n  -fetches=0: `<n>` # Sources downloaded at once, 0 uses the -arch default\n  -follow=\"\": `<url>` # Replicate generated files from a primary router's status API\n  -force=false: Generate the blacklist even if -sanity finds popular domains in it or it changes more than -max-change allows\n  -fwgroup=\"\": `<name>` # Print firewall address-group commands for the include domains, resolved with -resolver or dnsmasq's upstream servers\n  -gzip=false: Also write gzip compressed copies of generated files\n  -h=false: Display help\n  -history=\"\": `<file>` # Append each run's metrics to this JSON lines file, or CSV if it ends in .csv, for the report command\n  -history-days=365: `<days>` # Drop -history runs older than this, 0 keeps them all\n  -hmac-key=\"\": `<file>` # Sign generated files with the key in this file, created if missing, and discard files modified outside blacklist at startup\n  -https=\"\": `<policy>` # Plain HTTP source policy: upgrade or require\n  -i=5: Polling interval\n  -ipgroup=\"\": `<name>` # Print firewall address-group commands for raw IP entries found in sources\n  -line-buffer=\"\": `<size>` # Longest source line read, e.g. 1M, the -arch default if not set\n  -log-keep=3: Rotated -logfile copies kept\n  -log-size=\"1M\": `<size>` # Rotate -logfile once it reaches this size, 0 never rotates it\n  -logfile=\"\": `<file>` # Log to this file, rotated at -log-size, instead of blacklist.log in the working directory\n  -max-change=0: `<percent>` # Keep the previous files and fail if a run would add and remove more than this percentage of their entries, 0 allows any change\n  -max-failures=0: `<n>` # Auto-disable a source after this many consecutive failed fetches, until update -source retries it successfully\n  -max-memory=0: `<MB>` # Spill downloads to disk and fetch fewer at once if the sources would need more memory\n  -max-size=\"\": `<size>` # Default per-source download limit, e.g. 20M\n  -mips64=\"mips64\": Override target EdgeOS CPU architecture\n  -nice=0: `<1-19>` # Run at this lower CPU priority, with the lowest best-effort I/O priority on Linux, and leave a core free for routing and DNS\n  -no-color=false: Show the interactive terminal output without colors, as setting NO_COLOR does\n  -offline=false: Skip network fetches, regenerating url sources from their -cache copies\n  -on-commit=false: Skip the run unless the blacklist configuration changed since the last -on-commit run, for an EdgeOS commit hook\n  -os=\"linux\": Override native EdgeOS OS\n  -pid-file=\"/config/user-data/blacklist.pid\": `<file>` # Refuse to start a second -schedule or -api daemon while the one recorded here runs\n  -pins=\"\": `<sha256,...>` # Only accept HTTPS source certificates with these fingerprints, unless a source sets its own pin\n  -precedence=\"include\": `<rule>` # Whether include or exclude wins when a domain is in both\n  -protect=\"\": `<domain,...>` # Replace the built-in domains that are never blocked, the router's hostname and NTP servers are always protected\n  -psl=\"\": `<file>` # Public suffix list for parse-urls registrable sources, e.g. a copy of publicsuffix.org's public_suffix_list.dat\n  -psl-url=\"\": `<url>` # Download the public suffix list from this URL, saving it to -psl for when it can't be reached\n  -push-doc=\"/config/user-data/blacklist.push.json\": `<file>` # Where pushed configurations are saved\n  -push-key=\"\": `<file>` # Accept configurations pushed to -api signed by this base64 ed25519 public key\n  -quarantine=\"0s\": `<duration>` # Hold domains back for this long after a source first lists them, e.g. 24h\n  -rate-limit=\"\": `<size>` # Cap the bandwidth all downloads share at this many bytes per second, e.g. 2M\n  -redact=\"\": `<param,...>` # Replace the built-in query parameters whose values are redacted from logs and the run status, URL passwords and secrets are always redacted\n  -redirects=10: Maximum redirects followed per source\n  -refresh-window=\"\": `<HH:MM-HH:MM [day,...];...>` # Only download url sources in full during these daily windows, outside them -cache copies are used after checking for changes\n  -refuse-suffixes=false: Drop entries that are public suffixes, e.g. co.uk or github.io, instead of only warning about them\n  -reload=\"\": `<controller>` # DNS service controller: dnsmasq, dnsmasq-hup, none, systemd-resolved, unbound\n  -resolver=\"\": `<server>` # Look up source hostnames with this DNS server instead of the system resolver: ip[:port], tls://host[:port] or a DoH https:// URL\n  -resumes=3: Maximum times an interrupted download is resumed with a Range request\n  -sanity=false: Check the generated blacklist against -top-domains and fail if it blocks any of them\n  -schedule=false: Run as a daemon, swapping blocking profiles at their schedule boundaries\n  -seen=\"/config/user-data/blacklist.seen.json\": `<file>` # Where -quarantine records when domains were first listed\n  -shard=0: `<lines>` # Split each generated file into blacklist.000.conf, blacklist.001.conf... of at most this many lines\n  -stale-days=0: `<days>` # Report the sources whose content hasn't changed in this many days, as likely abandoned\n  -stale-file=\"/config/user-data/blacklist.stale.json\": `<file>` # Where -stale-days records when each source's content last changed\n  -statsd=\"\": `<host:port>` # Push run metrics to a StatsD/Telegraf listener over UDP\n  -status=\"\": `<file>` # Write a JSON run status file for monitoring agents\n  -strict=false: Fail on unknown or unparsable configuration lines\n  -syslog=\"\": `<address>` # Also log RFC5424 messages to syslog: local, udp://host[:port] or tcp://host[:port]\n  -syslog-facility=\"daemon\": `<facility>` # Facility -syslog messages are sent with, e.g. local3\n  -syslog-tag=\"blacklist\": `<tag>` # App name -syslog messages are sent with\n  -t=false: Run config and data validation tests\n  -threshold=0: `<weight>` # Only block domains listed by sources whose summed weight exceeds this\n  -timings=false: Log how long each stage of the run takes, and record it in the -status file\n  -tmp=\"/tmp\": Override dnsmasq temporary directory\n  -top-domains=\"\": `<file>` # Popular domains -sanity checks for, one domain or rank,domain per line, e.g. a Tranco list; a built-in set is used if not set\n  -top-url=\"\": `<url>` # Download the -sanity popular domains from this URL, saving it to -top-domains for when it can't be reached\n  -tor=\"127.0.0.1:9050\": `<host:port>` # Tor SOCKS proxy for sources configured \"via tor\"\n  -tui=false: Show an interactive source status and control screen\n  -v=false: Verbose display\n  -version=false: Show version\n"
 
 	expMap = `"1e100.net":0,
 "2o7.net":0,
@@ -397,17 +927,99 @@ var (
 "ytimg.com":0,
 `
 	optsString = `FlagSet
-ARCH:    "amd64"
-DEBUG:   "false"
-DIR:     "/etc/dnsmasq.d"
-F:       "**not initialized**"
-H:       "true"
-I:       "5"
-MIPS64:  "mips64"
-OS:      "` + runtime.GOOS + `"
-T:       "false"
-TMP:     "/tmp"
-V:       "false"
-VERSION: "false"
+API:               "**not initialized**"
+ARCH:              "amd64"
+BASE-DIR:          "**not initialized**"
+BLOCKPAGE:         "**not initialized**"
+BLOCKPAGE-HTML:    "**not initialized**"
+BLOCKPAGE-NOTIFY:  "**not initialized**"
+BLOCKPAGE-PENDING: "**not initialized**"
+CACHE:             "**not initialized**"
+CAFILE:            "**not initialized**"
+CATALOG-FILE:      "/config/user-data/blacklist.catalog.json"
+CATALOG-KEY:       "**not initialized**"
+CATALOG-URL:       "**not initialized**"
+CORES:             "0"
+COUNTS:            "false"
+DEADLINE:          "0s"
+DEBUG:             "false"
+DEDUPE:            "**not initialized**"
+DEFAULTS:          "false"
+DEFAULTS-FILE:     "**not initialized**"
+DEFAULTS-URL:      "https://raw.githubusercontent.com/britannic/blacklist/master/defaults/excludes.txt"
+DETERMINISTIC:     "false"
+DIGEST:            "/config/user-data/blacklist.digest"
+DIR:               "/etc/dnsmasq.d"
+DOH:               "false"
+DOH-DOMAINS:       "**not initialized**"
+DOH-URL:           "**not initialized**"
+EXPLAIN:           "false"
+F:                 "**not initialized**"
+FAIL-FILE:         "/config/user-data/blacklist.fails.json"
+FETCHES:           "0"
+FOLLOW:            "**not initialized**"
+FORCE:             "false"
+FWGROUP:           "**not initialized**"
+GZIP:              "false"
+H:                 "true"
+HISTORY:           "**not initialized**"
+HISTORY-DAYS:      "365"
+HMAC-KEY:          "**not initialized**"
+HTTPS:             "**not initialized**"
+I:                 "5"
+IPGROUP:           "**not initialized**"
+LINE-BUFFER:       "**not initialized**"
+LOG-KEEP:          "3"
+LOG-SIZE:          "1M"
+LOGFILE:           "**not initialized**"
+MAX-CHANGE:        "0"
+MAX-FAILURES:      "0"
+MAX-MEMORY:        "0"
+MAX-SIZE:          "**not initialized**"
+MIPS64:            "mips64"
+NICE:              "0"
+NO-COLOR:          "false"
+OFFLINE:           "false"
+ON-COMMIT:         "false"
+OS:                "` + runtime.GOOS + `"
+PID-FILE:          "/config/user-data/blacklist.pid"
+PINS:              "**not initialized**"
+PRECEDENCE:        "include"
+PROTECT:           "**not initialized**"
+PSL:               "**not initialized**"
+PSL-URL:           "**not initialized**"
+PUSH-DOC:          "/config/user-data/blacklist.push.json"
+PUSH-KEY:          "**not initialized**"
+QUARANTINE:        "0s"
+RATE-LIMIT:        "**not initialized**"
+REDACT:            "**not initialized**"
+REDIRECTS:         "10"
+REFRESH-WINDOW:    "**not initialized**"
+REFUSE-SUFFIXES:   "false"
+RELOAD:            "**not initialized**"
+RESOLVER:          "**not initialized**"
+RESUMES:           "3"
+SANITY:            "false"
+SCHEDULE:          "false"
+SEEN:              "/config/user-data/blacklist.seen.json"
+SHARD:             "0"
+STALE-DAYS:        "0"
+STALE-FILE:        "/config/user-data/blacklist.stale.json"
+STATSD:            "**not initialized**"
+STATUS:            "**not initialized**"
+STRICT:            "false"
+SYSLOG:            "**not initialized**"
+SYSLOG-FACILITY:   "daemon"
+SYSLOG-TAG:        "blacklist"
+T:                 "false"
+THRESHOLD:         "0"
+TIMINGS:           "false"
+TMP:               "/tmp"
+TOP-DOMAINS:       "**not initialized**"
+TOP-URL:           "**not initialized**"
+TOR:               "127.0.0.1:9050"
+TUI:               "false"
+V:                 "false"
+VERSION:           "false"
 `
 )
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/britannic/blacklist/internal/edgeos"
 	"github.com/britannic/blacklist/internal/tdata"
@@ -14,18 +17,103 @@ import (
 // opts struct for command line options and setting initial variables
 type opts struct {
 	*flag.FlagSet
+	API     *string
 	ARCH    *string
+	BaseDir *string
+	BlkHTML *string
+	BlkNtfy *string
+	BlkPage *string
+	BlkPend *string
+	Cache   *string
+	CAfile  *string
+	CatFile *string
+	CatKey  *string
+	CatURL  *string
+	Commit  *bool
+	Cores   *int
+	Corrupt *bool
+	Counts  *bool
 	Dbug    *bool
+	Dedupe  *string
+	DefFile *string
+	Defs    *bool
+	DefURL  *string
+	Determ  *bool
+	Digest  *string
+	Dline   *time.Duration
 	DNSdir  *string
 	DNStmp  *string
+	DoH     *bool
+	DoHDom  *string
+	DoHURL  *string
+	Explain *bool
+	FailDB  *string
+	FailSrc *string
+	Fetches *int
 	File    *string
+	Follow  *string
+	Force   *bool
+	FWGroup *string
+	Gzip    *bool
+	HistDay *int
+	History *string
 	Help    *bool
+	Hold    *time.Duration
+	HTTPS   *string
+	IPGroup *string
+	LineBuf *string
+	LogFile *string
+	LogKeep *int
+	LogSize *string
+	MACKey  *string
+	MaxChg  *float64
+	MaxFail *int
+	MaxMem  *int
+	MaxSize *string
 	MIPS64  *string
+	Nice    *int
+	NoColor *bool
+	Offline *bool
 	OS      *string
+	PIDFile *string
+	Pins    *string
 	Poll    *int
+	Prec    *string
+	Protect *string
+	PSL     *string
+	PSLURL  *string
+	PushDoc *string
+	PushKey *string
+	Rate    *string
+	Redact  *string
+	Redirs  *int
+	Refuse  *bool
+	Reload  *string
+	Resolv  *string
+	Resumes *int
+	Sanity  *bool
+	Sched   *bool
+	Seen    *string
+	Shard   *int
+	SlowSrc *string
+	Stale   *int
+	StaleDB *string
+	StatsD  *string
+	Status  *string
+	Strict  *bool
+	SysFac  *string
+	Syslog  *string
+	SysTag  *string
 	Test    *bool
+	Thresh  *float64
+	Tor     *string
+	Timings *bool
+	Top     *string
+	TopURL  *string
+	TUI     *bool
 	Verb    *bool
 	Version *bool
+	Windows *string
 }
 
 // setDir sets the directory according to the host CPU arch
@@ -41,6 +129,10 @@ func (o *opts) setDir(arch string) (dir string) {
 
 // getCFG returns a e.ConfLoader
 func (o *opts) getCFG(c *edgeos.Config) (r edgeos.ConfLoader) {
+	if *o.File != "" {
+		return fileCFG(c, *o.File)
+	}
+
 	switch *o.ARCH {
 	case *o.MIPS64:
 		r = &edgeos.CFGcli{Config: c}
@@ -50,29 +142,272 @@ func (o *opts) getCFG(c *edgeos.Config) (r edgeos.ConfLoader) {
 	return r
 }
 
+// fileCFG returns a e.ConfLoader for -f, the file is either a JSON snapshot
+// or an EdgeOS configuration
+func fileCFG(c *edgeos.Config, f string) edgeos.ConfLoader {
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		logErrorf("%v", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		return &edgeos.CFGjson{Config: c, Cfg: string(b), File: f}
+	}
+	return &edgeos.CFGstatic{Config: c, Cfg: string(b)}
+}
+
+// tuning returns the -arch performance defaults with any -cores, -fetches,
+// -line-buffer or -dedupe overrides
+func (o *opts) tuning() (edgeos.Tuning, error) {
+	t := edgeos.ArchTuning(*o.ARCH)
+	if *o.Cores > 0 {
+		t.Cores = *o.Cores
+	}
+
+	if *o.Fetches > 0 {
+		t.Fetches = *o.Fetches
+	}
+
+	if *o.LineBuf != "" {
+		n, err := edgeos.ParseSize(*o.LineBuf)
+		if err != nil {
+			return t, err
+		}
+		t.LineBuf = int(n)
+	}
+
+	switch *o.Dedupe {
+	case "":
+	case edgeos.DedupeGrow, edgeos.DedupePresize:
+		t.Dedupe = *o.Dedupe
+	default:
+		return t, fmt.Errorf("unknown dedupe strategy %q, must be %v or %v", *o.Dedupe, edgeos.DedupeGrow, edgeos.DedupePresize)
+	}
+	return t, nil
+}
+
+// windows returns the -refresh-window schedules, nil allows downloads at any
+// time
+func (o *opts) windows() ([]*edgeos.Schedule, error) {
+	if *o.Windows == "" {
+		return nil, nil
+	}
+
+	if *o.Cache == "" {
+		return nil, fmt.Errorf("-refresh-window needs -cache to keep copies of sources between windows")
+	}
+
+	var w []*edgeos.Schedule
+	for _, s := range strings.Split(*o.Windows, ";") {
+		sc, err := edgeos.ParseSchedule(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		w = append(w, sc)
+	}
+	return w, nil
+}
+
+// pins returns the -pins fingerprints as a slice
+func (o *opts) pins() []string {
+	if *o.Pins == "" {
+		return nil
+	}
+	return strings.Split(*o.Pins, ",")
+}
+
+// dohDomains returns the -doh-domains as a slice, nil keeps the built-in set
+func (o *opts) dohDomains() []string {
+	if *o.DoHDom == "" {
+		return nil
+	}
+	return strings.Split(*o.DoHDom, ",")
+}
+
+// protected returns the -protect domains as a slice, nil keeps the built-in
+// set
+func (o *opts) protected() []string {
+	if *o.Protect == "" {
+		return nil
+	}
+	return strings.Split(*o.Protect, ",")
+}
+
+// redacted returns the -redact query parameters, nil for the built-in ones
+func (o *opts) redacted() []string {
+	if *o.Redact == "" {
+		return nil
+	}
+	return strings.Split(*o.Redact, ",")
+}
+
+// hidden names the flags left out of the usage and the options dump, they
+// inject failures for testing
+var hidden = map[string]bool{
+	"corrupt-output": true,
+	"fail-source":    true,
+	"slow-source":    true,
+}
+
+// printDefaults prints the usage of flags that aren't hidden
+func printDefaults(flags *flag.FlagSet) {
+	var shown flag.FlagSet
+	shown.SetOutput(flags.Output())
+	flags.VisitAll(func(f *flag.Flag) {
+		if !hidden[f.Name] {
+			shown.Var(f.Value, f.Name, f.Usage)
+			shown.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	shown.PrintDefaults()
+}
+
+// chaos returns the failures -fail-source, -slow-source and -corrupt-output
+// inject, nil if none are set
+func (o *opts) chaos() (*edgeos.Chaos, error) {
+	if *o.FailSrc == "" && *o.SlowSrc == "" && !*o.Corrupt {
+		return nil, nil
+	}
+
+	ch := &edgeos.Chaos{
+		Fail:    make(map[string]bool),
+		Slow:    make(map[string]time.Duration),
+		Corrupt: *o.Corrupt,
+	}
+	if *o.FailSrc != "" {
+		for _, name := range strings.Split(*o.FailSrc, ",") {
+			ch.Fail[strings.TrimSpace(name)] = true
+		}
+	}
+
+	if *o.SlowSrc != "" {
+		for _, s := range strings.Split(*o.SlowSrc, ",") {
+			kv := strings.SplitN(s, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("-slow-source %q must be name=duration", s)
+			}
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("-slow-source %q: %v", s, err)
+			}
+			ch.Slow[strings.TrimSpace(kv[0])] = d
+		}
+	}
+	return ch, nil
+}
+
 // getOpts returns command line flags and values or displays help
 func getOpts() *opts {
 	var flags flag.FlagSet
 	flags.Init("blacklist", flag.ExitOnError)
 	flags.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %v [options]\n\n", basename(os.Args[0]))
-		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "Usage: %v [options] [command [args]]\n\n", basename(os.Args[0]))
+		printDefaults(&flags)
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		for _, name := range commandNames() {
+			fmt.Fprintf(os.Stderr, "  %v\n", commands[name].usage)
+		}
 	}
 
 	return &opts{
+		API:     flags.String("api", "", "`<address>` # Serve the status API, e.g. \":8080\""),
 		ARCH:    flags.String("arch", runtime.GOARCH, "Set EdgeOS CPU architecture"),
+		BaseDir: flags.String("base-dir", "", "`<dir>` # Resolve relative file sources against this directory"),
+		BlkHTML: flags.String("blockpage-html", "", "`<file>` # html/template served by -blockpage, {{.Domain}} is the blocked domain"),
+		BlkNtfy: flags.String("blockpage-notify", "", "`<url>` # POST unblock requests from the block page to this webhook as JSON"),
+		BlkPend: flags.String("blockpage-pending", "", "`<file>` # Add a \"request unblock\" button to the block page, recording requested domains here"),
+		BlkPage: flags.String("blockpage", "", "`<ip>` # Serve a \"blocked by policy\" page on port 80 and 443 of the blackhole IP"),
+		Cache:   flags.String("cache", "", "`<dir>` # Cache url sources here and skip downloading them when a HEAD pre-check shows no change"),
+		CAfile:  flags.String("cafile", "", "`<file>` # Trust this PEM CA bundle for HTTPS sources"),
+		CatFile: flags.String("catalog-file", "/config/user-data/blacklist.catalog.json", "`<file>` # Keep the catalog downloaded from -catalog-url here"),
+		CatKey:  flags.String("catalog-key", "", "`<file>` # Verify the -catalog-url catalog with this base64 ed25519 public key, required with -catalog-url"),
+		CatURL:  flags.String("catalog-url", "", "`<url>` # Refresh the source catalog daily from this signed JSON catalog, signed with -catalog-key"),
+		Commit:  flags.Bool("on-commit", false, "Skip the run unless the blacklist configuration changed since the last -on-commit run, for an EdgeOS commit hook"),
+		Cores:   flags.Int("cores", 0, "`<n>` # Sources formatted and written at once, 0 uses the -arch default"),
+		Corrupt: flags.Bool("corrupt-output", false, "Append an invalid line to each generated file, to test that a failed reload is caught"),
+		Counts:  flags.Bool("counts", false, "Write each generated file's entry count and hash to a .count file, and check the files against them at startup"),
 		Dbug:    flags.Bool("debug", false, "Enable debug mode"),
+		Dedupe:  flags.String("dedupe", "", "`<strategy>` # Dedupe map strategy: grow or presize, the -arch default if not set"),
+		DefFile: flags.String("defaults-file", "", "`<file>` # Local override for the default exclusions"),
+		Defs:    flags.Bool("defaults", false, "Add the default global exclusions, updated from -defaults-url"),
+		DefURL:  flags.String("defaults-url", defaultsURL, "`<url>` # Canonical default exclusions list"),
+		Determ:  flags.Bool("deterministic", false, "Generate byte-identical files from identical configurations and sources, e.g. to detect drift between routers"),
+		Digest:  flags.String("digest", "/config/user-data/blacklist.digest", "`<file>` # Save the configuration digest -on-commit compares with here"),
+		Dline:   flags.Duration("deadline", 0, "`<duration>` # Give up on an update run that takes longer than this, keeping the previous files, e.g. 10m"),
 		DNSdir:  flags.String("dir", "/etc/dnsmasq.d", "Override dnsmasq directory"),
 		DNStmp:  flags.String("tmp", "/tmp", "Override dnsmasq temporary directory"),
+		DoH:     flags.Bool("doh", false, "Block DNS-over-HTTPS provider domains"),
+		DoHDom:  flags.String("doh-domains", "", "`<domain,...>` # Replace the built-in DNS-over-HTTPS provider domains -doh blocks"),
+		DoHURL:  flags.String("doh-url", "", "`<url>` # Update the -doh provider domains from this list, the built-in or -doh-domains set is used if it can't be read"),
+		Explain: flags.Bool("explain", false, "Print each node's and source's effective settings as JSON, with the leaf, default or flag each comes from"),
 		Help:    flags.Bool("h", false, "Display help"),
+		Hold:    flags.Duration("quarantine", 0, "`<duration>` # Hold domains back for this long after a source first lists them, e.g. 24h"),
+		HTTPS:   flags.String("https", "", "`<policy>` # Plain HTTP source policy: upgrade or require"),
+		IPGroup: flags.String("ipgroup", "", "`<name>` # Print firewall address-group commands for raw IP entries found in sources"),
+		FailSrc: flags.String("fail-source", "", "`<name,...>` # Fail fetching these sources, to test safe-fail and alerting"),
+		FailDB:  flags.String("fail-file", "/config/user-data/blacklist.fails.json", "`<file>` # Where -max-failures records each source's consecutive failed fetches"),
+		Fetches: flags.Int("fetches", 0, "`<n>` # Sources downloaded at once, 0 uses the -arch default"),
 		File:    flags.String("f", "", "`<file>` # Load a configuration file"),
 		FlagSet: &flags,
+		Follow:  flags.String("follow", "", "`<url>` # Replicate generated files from a primary router's status API"),
+		Force:   flags.Bool("force", false, "Generate the blacklist even if -sanity finds popular domains in it or it changes more than -max-change allows"),
+		FWGroup: flags.String("fwgroup", "", "`<name>` # Print firewall address-group commands for the include domains, resolved with -resolver or dnsmasq's upstream servers"),
+		Gzip:    flags.Bool("gzip", false, "Also write gzip compressed copies of generated files"),
+		HistDay: flags.Int("history-days", 365, "`<days>` # Drop -history runs older than this, 0 keeps them all"),
+		History: flags.String("history", "", "`<file>` # Append each run's metrics to this JSON lines file, or CSV if it ends in .csv, for the report command"),
+		LineBuf: flags.String("line-buffer", "", "`<size>` # Longest source line read, e.g. 1M, the -arch default if not set"),
+		LogFile: flags.String("logfile", "", "`<file>` # Log to this file, rotated at -log-size, instead of blacklist.log in the working directory"),
+		LogKeep: flags.Int("log-keep", 3, "Rotated -logfile copies kept"),
+		LogSize: flags.String("log-size", "1M", "`<size>` # Rotate -logfile once it reaches this size, 0 never rotates it"),
+		MACKey:  flags.String("hmac-key", "", "`<file>` # Sign generated files with the key in this file, created if missing, and discard files modified outside blacklist at startup"),
+		MaxFail: flags.Int("max-failures", 0, "`<n>` # Auto-disable a source after this many consecutive failed fetches, until update -source retries it successfully"),
+		MaxChg:  flags.Float64("max-change", 0, "`<percent>` # Keep the previous files and fail if a run would add and remove more than this percentage of their entries, 0 allows any change"),
+		MaxMem:  flags.Int("max-memory", 0, "`<MB>` # Spill downloads to disk and fetch fewer at once if the sources would need more memory"),
+		MaxSize: flags.String("max-size", "", "`<size>` # Default per-source download limit, e.g. 20M"),
 		MIPS64:  flags.String("mips64", "mips64", "Override target EdgeOS CPU architecture"),
+		Nice:    flags.Int("nice", 0, "`<1-19>` # Run at this lower CPU priority, with the lowest best-effort I/O priority on Linux, and leave a core free for routing and DNS"),
+		NoColor: flags.Bool("no-color", false, "Show the interactive terminal output without colors, as setting NO_COLOR does"),
+		Offline: flags.Bool("offline", false, "Skip network fetches, regenerating url sources from their -cache copies"),
 		OS:      flags.String("os", runtime.GOOS, "Override native EdgeOS OS"),
+		PIDFile: flags.String("pid-file", "/config/user-data/blacklist.pid", "`<file>` # Refuse to start a second -schedule or -api daemon while the one recorded here runs"),
+		Pins:    flags.String("pins", "", "`<sha256,...>` # Only accept HTTPS source certificates with these fingerprints, unless a source sets its own pin"),
 		Poll:    flags.Int("i", 5, "Polling interval"),
+		Prec:    flags.String("precedence", edgeos.PrecedenceInclude, "`<rule>` # Whether include or exclude wins when a domain is in both"),
+		PSL:     flags.String("psl", "", "`<file>` # Public suffix list for parse-urls registrable sources, e.g. a copy of publicsuffix.org's public_suffix_list.dat"),
+		PSLURL:  flags.String("psl-url", "", "`<url>` # Download the public suffix list from this URL, saving it to -psl for when it can't be reached"),
+		Protect: flags.String("protect", "", "`<domain,...>` # Replace the built-in domains that are never blocked, the router's hostname and NTP servers are always protected"),
+		PushDoc: flags.String("push-doc", "/config/user-data/blacklist.push.json", "`<file>` # Where pushed configurations are saved"),
+		PushKey: flags.String("push-key", "", "`<file>` # Accept configurations pushed to -api signed by this base64 ed25519 public key"),
+		Rate:    flags.String("rate-limit", "", "`<size>` # Cap the bandwidth all downloads share at this many bytes per second, e.g. 2M"),
+		Redact:  flags.String("redact", "", "`<param,...>` # Replace the built-in query parameters whose values are redacted from logs and the run status, URL passwords and secrets are always redacted"),
+		Redirs:  flags.Int("redirects", 10, "Maximum redirects followed per source"),
+		Refuse:  flags.Bool("refuse-suffixes", false, "Drop entries that are public suffixes, e.g. co.uk or github.io, instead of only warning about them"),
+		Shard:   flags.Int("shard", 0, "`<lines>` # Split each generated file into blacklist.000.conf, blacklist.001.conf... of at most this many lines"),
+		SlowSrc: flags.String("slow-source", "", "`<name=duration,...>` # Delay fetching these sources, e.g. big=30s, to test -deadline"),
+		Seen:    flags.String("seen", "/config/user-data/blacklist.seen.json", "`<file>` # Where -quarantine records when domains were first listed"),
+		Sanity:  flags.Bool("sanity", false, "Check the generated blacklist against -top-domains and fail if it blocks any of them"),
+		Sched:   flags.Bool("schedule", false, "Run as a daemon, swapping blocking profiles at their schedule boundaries"),
+		Resolv:  flags.String("resolver", "", "`<server>` # Look up source hostnames with this DNS server instead of the system resolver: ip[:port], tls://host[:port] or a DoH https:// URL"),
+		Resumes: flags.Int("resumes", 3, "Maximum times an interrupted download is resumed with a Range request"),
+		Reload:  flags.String("reload", "", "`<controller>` # DNS service controller: "+strings.Join(edgeos.ServiceControllers(), ", ")),
+		Stale:   flags.Int("stale-days", 0, "`<days>` # Report the sources whose content hasn't changed in this many days, as likely abandoned"),
+		StaleDB: flags.String("stale-file", "/config/user-data/blacklist.stale.json", "`<file>` # Where -stale-days records when each source's content last changed"),
+		StatsD:  flags.String("statsd", "", "`<host:port>` # Push run metrics to a StatsD/Telegraf listener over UDP"),
+		Status:  flags.String("status", "", "`<file>` # Write a JSON run status file for monitoring agents"),
+		Strict:  flags.Bool("strict", false, "Fail on unknown or unparsable configuration lines"),
+		SysFac:  flags.String("syslog-facility", "daemon", "`<facility>` # Facility -syslog messages are sent with, e.g. local3"),
+		Syslog:  flags.String("syslog", "", "`<address>` # Also log RFC5424 messages to syslog: local, udp://host[:port] or tcp://host[:port]"),
+		SysTag:  flags.String("syslog-tag", "blacklist", "`<tag>` # App name -syslog messages are sent with"),
 		Test:    flags.Bool("t", false, "Run config and data validation tests"),
+		Thresh:  flags.Float64("threshold", 0, "`<weight>` # Only block domains listed by sources whose summed weight exceeds this"),
+		Timings: flags.Bool("timings", false, "Log how long each stage of the run takes, and record it in the -status file"),
+		Top:     flags.String("top-domains", "", "`<file>` # Popular domains -sanity checks for, one domain or rank,domain per line, e.g. a Tranco list; a built-in set is used if not set"),
+		TopURL:  flags.String("top-url", "", "`<url>` # Download the -sanity popular domains from this URL, saving it to -top-domains for when it can't be reached"),
+		Tor:     flags.String("tor", "127.0.0.1:9050", "`<host:port>` # Tor SOCKS proxy for sources configured \"via tor\""),
+		TUI:     flags.Bool("tui", false, "Show an interactive source status and control screen"),
 		Verb:    flags.Bool("v", false, "Verbose display"),
 		Version: flags.Bool("version", false, "Show version"),
+		Windows: flags.String("refresh-window", "", "`<HH:MM-HH:MM [day,...];...>` # Only download url sources in full during these daily windows, outside them -cache copies are used after checking for changes"),
 	}
 }
 
@@ -106,7 +441,8 @@ func (o *opts) setArgs() {
 		exitCmd(0)
 
 	case *o.Version:
-		fmt.Printf(" Version:\t\t%s\n Build date:\t\t%s\n Git short hash:\t%v\n", version, build, githash)
+		v, commit, date := buildInfo()
+		fmt.Printf(" Version:\t\t%s\n Build date:\t\t%s\n Git short hash:\t%v\n", v, date, commit)
 		exitCmd(0)
 	}
 }
@@ -134,6 +470,9 @@ func (o *opts) String() string {
 	}
 
 	visitor := func(a *flag.Flag) {
+		if hidden[a.Name] {
+			return
+		}
 		field := pArray{n: fmt.Sprint(a.Name), v: fmt.Sprint(a.Value)}
 		fields = append(fields, field)
 	}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+)
+
+// mib formats a byte count in MiB
+func mib(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
+
+// progressText describes a source's progress
+func progressText(p e.Progress) string {
+	switch {
+	case p.Lines > 0:
+		return fmt.Sprintf("parsed %d lines", p.Lines)
+	case p.Total > 0:
+		return fmt.Sprintf("downloaded %v of %v (%d%%)", mib(p.Bytes), mib(p.Total), p.Bytes*100/p.Total)
+	default:
+		return fmt.Sprintf("downloaded %v", mib(p.Bytes))
+	}
+}
+
+// progressLogger returns a ProgressFunc that logs each source's progress at
+// most once per interval, so long downloads don't appear to hang
+func progressLogger(interval time.Duration) e.ProgressFunc {
+	var (
+		mu   sync.Mutex
+		last = make(map[string]time.Time)
+	)
+
+	return func(p e.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p.Done {
+			delete(last, p.Source)
+			return
+		}
+
+		if now := time.Now(); now.Sub(last[p.Source]) >= interval {
+			last[p.Source] = now
+			logInfof("%v: %v", p.Source, progressText(p))
+		}
+	}
+}
+
+// ANSI terminal colors
+const (
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiGreen  = "\x1b[32m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+	ansiYellow = "\x1b[33m"
+)
+
+// tty shows a run's progress and summary on an interactive terminal instead
+// of raw log lines
+type tty struct {
+	w     io.Writer
+	color bool
+}
+
+// screen is the interactive terminal, nil if stdout isn't one
+var screen *tty
+
+// isTerminal is true if f is a terminal
+var isTerminal = func(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// newTTY returns a *tty writing to f, or nil if f isn't a terminal; it is
+// colored unless noColor or NO_COLOR is set or the terminal is dumb
+func newTTY(f *os.File, noColor bool) *tty {
+	if !isTerminal(f) {
+		return nil
+	}
+	return &tty{
+		w:     f,
+		color: !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb",
+	}
+}
+
+// paint wraps s in an ANSI color code if the terminal is colored
+func (t *tty) paint(code, s string) string {
+	if !t.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// progress returns a ProgressFunc that shows each source's progress at most
+// once per interval, with its name in an aligned column, and a check mark
+// once it is parsed
+func (t *tty) progress(interval time.Duration) e.ProgressFunc {
+	var (
+		mu   sync.Mutex
+		last = make(map[string]time.Time)
+	)
+
+	return func(p e.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p.Done {
+			delete(last, p.Source)
+			fmt.Fprintf(t.w, "%v %-24v %v\n", t.paint(ansiGreen, "✓"), p.Source, t.paint(ansiDim, progressText(p)))
+			return
+		}
+
+		if now := time.Now(); now.Sub(last[p.Source]) >= interval {
+			last[p.Source] = now
+			fmt.Fprintf(t.w, "%v %-24v %v\n", t.paint(ansiYellow, "↓"), p.Source, progressText(p))
+		}
+	}
+}
+
+// summary shows each source's result in a table and the run's outcome
+func (t *tty) summary(c *e.Config, err error) {
+	results := c.RunState().Results()
+
+	// colors would throw a tabwriter's widths, so the columns are sized here
+	nw, tw := len("SOURCE"), len("TYPE")
+	for _, r := range results {
+		if len(r.Name) > nw {
+			nw = len(r.Name)
+		}
+		if len(r.Type) > tw {
+			tw = len(r.Type)
+		}
+	}
+
+	if len(results) > 0 {
+		fmt.Fprintf(t.w, "  %v\n", t.paint(ansiBold, fmt.Sprintf("%-*v  %-*v  %7v", nw, "SOURCE", tw, "TYPE", "ENTRIES")))
+	}
+	for _, r := range results {
+		glyph, note := t.paint(ansiGreen, "✓"), ""
+		switch {
+		case r.Error != "":
+			glyph, note = t.paint(ansiRed, "✗"), t.paint(ansiRed, r.Error)
+		case r.Entries == 0:
+			glyph, note = t.paint(ansiYellow, "!"), t.paint(ansiDim, "no entries")
+		case r.Dupes > 0:
+			note = t.paint(ansiDim, fmt.Sprintf("%d differ only in case or a trailing dot", r.Dupes))
+		}
+		line := fmt.Sprintf("%v %-*v  %-*v  %7d  %v", glyph, nw, r.Name, tw, r.Type, r.Entries, note)
+		fmt.Fprintln(t.w, strings.TrimRight(line, " "))
+	}
+
+	if err != nil {
+		fmt.Fprintf(t.w, "%v %v\n", t.paint(ansiRed, "✗ Run failed:"), err)
+		return
+	}
+	n := c.RunState().Counts()
+	fmt.Fprintf(t.w, "%v %d entries from %d sources, %d failed\n", t.paint(ansiGreen, "✓ Run succeeded:"), n.Entries, n.Sources, n.Failed)
+}
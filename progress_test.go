@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProgressText(t *testing.T) {
+	Convey("Testing progressText()", t, func() {
+		tests := []struct {
+			p   e.Progress
+			exp string
+		}{
+			{p: e.Progress{Bytes: 1 << 20, Total: 4 << 20}, exp: "downloaded 1.0 MiB of 4.0 MiB (25%)"},
+			{p: e.Progress{Bytes: 3 << 19, Total: -1}, exp: "downloaded 1.5 MiB"},
+			{p: e.Progress{Bytes: 4 << 20, Lines: 120000}, exp: "parsed 120000 lines"},
+		}
+
+		for _, tt := range tests {
+			So(progressText(tt.p), ShouldEqual, tt.exp)
+		}
+	})
+}
+
+func TestProgressLogger(t *testing.T) {
+	Convey("Testing progressLogger()", t, func() {
+		var act []string
+		orig := logInfof
+		defer func() { logInfof = orig }()
+		logInfof = func(s string, v ...interface{}) { act = append(act, fmt.Sprintf(s, v...)) }
+
+		f := progressLogger(time.Hour)
+		f(e.Progress{Source: "big", Bytes: 1 << 20, Total: -1})
+		f(e.Progress{Source: "big", Bytes: 2 << 20, Total: -1})
+		f(e.Progress{Source: "small", Lines: 10000})
+		f(e.Progress{Source: "big", Lines: 10000, Done: true})
+		f(e.Progress{Source: "big", Lines: 10000})
+
+		So(act, ShouldResemble, []string{
+			"big: downloaded 1.0 MiB",
+			"small: parsed 10000 lines",
+			"big: parsed 10000 lines",
+		})
+	})
+}
+
+func TestNewTTY(t *testing.T) {
+	Convey("Testing newTTY()", t, func() {
+		orig := isTerminal
+		defer func() { isTerminal = orig }()
+		for _, k := range []string{"NO_COLOR", "TERM"} {
+			v, ok := os.LookupEnv(k)
+			defer func(k, v string, ok bool) {
+				if ok {
+					os.Setenv(k, v)
+					return
+				}
+				os.Unsetenv(k)
+			}(k, v, ok)
+		}
+		os.Unsetenv("NO_COLOR")
+		os.Setenv("TERM", "xterm")
+
+		isTerminal = func(*os.File) bool { return false }
+		So(newTTY(os.Stdout, false), ShouldBeNil)
+
+		isTerminal = func(*os.File) bool { return true }
+		So(newTTY(os.Stdout, false).color, ShouldBeTrue)
+		So(newTTY(os.Stdout, true).color, ShouldBeFalse)
+
+		os.Setenv("TERM", "dumb")
+		So(newTTY(os.Stdout, false).color, ShouldBeFalse)
+
+		os.Setenv("TERM", "xterm")
+		os.Setenv("NO_COLOR", "1")
+		So(newTTY(os.Stdout, false).color, ShouldBeFalse)
+	})
+}
+
+func TestTTYProgress(t *testing.T) {
+	Convey("Testing tty.progress()", t, func() {
+		act := new(bytes.Buffer)
+		f := (&tty{w: act}).progress(time.Hour)
+		f(e.Progress{Source: "big", Bytes: 1 << 20, Total: -1})
+		f(e.Progress{Source: "big", Bytes: 2 << 20, Total: -1})
+		f(e.Progress{Source: "big", Lines: 10000, Done: true})
+
+		So(act.String(), ShouldEqual, fmt.Sprintf("↓ %-24v downloaded 1.0 MiB\n✓ %-24v parsed 10000 lines\n", "big", "big"))
+
+		Convey("with colors", func() {
+			act.Reset()
+			f = (&tty{w: act, color: true}).progress(time.Hour)
+			f(e.Progress{Source: "big", Lines: 10000, Done: true})
+			So(act.String(), ShouldEqual, fmt.Sprintf("%v✓%v %-24v %vparsed 10000 lines%v\n", ansiGreen, ansiReset, "big", ansiDim, ansiReset))
+		})
+	})
+}
+
+func TestTTYSummary(t *testing.T) {
+	Convey("Testing tty.summary()", t, func() {
+		c := getOpts().initEdgeOS()
+		for _, r := range []e.SourceResult{
+			{Name: "yoyo", Type: "hosts", Entries: 120, Dupes: 2},
+			{Name: "malc0de", Type: "domains", Error: "unable to fetch"},
+			{Name: "empty", Type: "hosts"},
+		} {
+			c.RunState().Record(r)
+		}
+
+		act := new(bytes.Buffer)
+		(&tty{w: act}).summary(c, nil)
+		So(act.String(), ShouldEqual, "  SOURCE   TYPE     ENTRIES\n"+
+			"! empty    hosts          0  no entries\n"+
+			"✗ malc0de  domains        0  unable to fetch\n"+
+			"✓ yoyo     hosts        120  2 differ only in case or a trailing dot\n"+
+			"✓ Run succeeded: 120 entries from 3 sources, 1 failed\n")
+
+		act.Reset()
+		(&tty{w: act, color: true}).summary(c, errors.New("timed out"))
+		So(act.String(), ShouldContainSubstring, ansiRed+"✗"+ansiReset+" malc0de")
+		So(act.String(), ShouldEndWith, ansiRed+"✗ Run failed:"+ansiReset+" timed out\n")
+	})
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+)
+
+// tui is the interactive status and control screen
+type tui struct {
+	*sync.Mutex
+	c       *e.Config
+	out     io.Writer
+	msg     string
+	running bool
+}
+
+// newTUI returns a *tui that renders the Config's run state to out
+func newTUI(c *e.Config, out io.Writer) *tui {
+	return &tui{Mutex: &sync.Mutex{}, c: c, out: out}
+}
+
+// active returns the in-progress sources as rows
+func (t *tui) active() (rows []string) {
+	for _, p := range t.c.RunState().Active() {
+		rows = append(rows, fmt.Sprintf("%v			%v", p.Source, progressText(p)))
+	}
+	return rows
+}
+
+// set records the status line message
+func (t *tui) set(format string, args ...interface{}) {
+	t.Lock()
+	t.msg = fmt.Sprintf(format, args...)
+	t.Unlock()
+}
+
+// run processes the sources in the background
+func (t *tui) run(name string, f func() error) {
+	t.Lock()
+	if t.running {
+		t.Unlock()
+		return
+	}
+	t.running = true
+	t.msg = name + "..."
+	t.Unlock()
+
+	go func() {
+		err := f()
+
+		t.Lock()
+		defer t.Unlock()
+		t.running = false
+		t.msg = name + " done"
+		if err != nil {
+			t.msg = name + " failed, press r to retry"
+		}
+	}()
+}
+
+// failed returns the names of the sources that failed
+func (t *tui) failed() (names []string) {
+	for _, r := range t.c.RunState().Results() {
+		if r.Error != "" {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// handle acts on a key press, it returns false if the user quit
+func (t *tui) handle(key byte) bool {
+	switch key {
+	case 'l':
+		if b, err := t.c.ReloadDNS(); err != nil {
+			t.set("Reload failed: %v %s", err, b)
+			break
+		}
+		t.set("Reloaded dnsmasq")
+
+	case 'q':
+		return false
+
+	case 'r':
+		names := t.failed()
+		if names == nil {
+			t.set("No failed sources to retry")
+			break
+		}
+
+		t.run("Retrying "+strings.Join(names, ", "), func() error {
+			var errs e.Errors
+			for _, name := range names {
+				if err := t.c.Retry(name); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if errs != nil {
+				return errs
+			}
+			return nil
+		})
+	}
+	return true
+}
+
+// render returns the screen contents
+func (t *tui) render() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "blacklist %v - %v\n\n", version, time.Now().Format("2006-01-02 15:04:05"))
+
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tTYPE\tENTRIES\tSTATUS")
+	for _, row := range t.active() {
+		fmt.Fprintln(w, row)
+	}
+	for _, r := range t.c.RunState().Results() {
+		status := "ok"
+		switch {
+		case r.Error != "":
+			status = "FAILED: " + r.Error
+		case r.Held > 0:
+			status = fmt.Sprintf("ok, %d quarantined", r.Held)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%d\t%v\n", r.Name, r.Type, r.Entries, status)
+	}
+	w.Flush()
+
+	t.Lock()
+	fmt.Fprintf(&b, "\n%v\n[r] retry failed  [l] reload dnsmasq  [q] quit\n", t.msg)
+	t.Unlock()
+	return b.String()
+}
+
+// draw clears the terminal and renders the screen
+func (t *tui) draw() {
+	fmt.Fprint(t.out, "\x1b[H\x1b[2J"+strings.Replace(t.render(), "\n", "\r\n", -1))
+}
+
+// stty runs stty against the controlling terminal
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	b, err := cmd.Output()
+	return strings.TrimSpace(string(b)), err
+}
+
+// runTUI processes the sources while showing their status, until the user quits
+func runTUI(c *e.Config) error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("-tui needs a terminal: %v", err)
+	}
+	if _, err = stty("cbreak", "-echo"); err != nil {
+		return err
+	}
+	defer stty(saved)
+
+	t := newTUI(c, os.Stdout)
+	keys := make(chan byte)
+	go func() {
+		r := bufio.NewReader(os.Stdin)
+		for {
+			k, err := r.ReadByte()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- k
+		}
+	}()
+
+	t.run("Processing sources", func() error {
+		if err := removeStaleFiles(c); err != nil {
+			return err
+		}
+		return processObjects(c, objex)
+	})
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		t.draw()
+		select {
+		case k, ok := <-keys:
+			if !ok || !t.handle(k) {
+				fmt.Fprint(t.out, "\r\n")
+				return nil
+			}
+		case <-tick.C:
+		}
+	}
+}
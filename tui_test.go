@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	e "github.com/britannic/blacklist/internal/edgeos"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tuiRunner fakes reloading dnsmasq
+type tuiRunner struct{ err error }
+
+func (r *tuiRunner) Output(script string) ([]byte, error)         { return nil, r.err }
+func (r *tuiRunner) CombinedOutput(script string) ([]byte, error) { return nil, r.err }
+func (r *tuiRunner) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+func TestTUI(t *testing.T) {
+	Convey("Testing the TUI", t, func() {
+		act := new(bytes.Buffer)
+		c := getOpts().initEdgeOS()
+		r := &tuiRunner{}
+		c.SetOpt(e.Shell(r))
+
+		tu := newTUI(c, act)
+
+		tests := []struct {
+			key  byte
+			err  error
+			more bool
+			msg  string
+		}{
+			{key: 'r', more: true, msg: "No failed sources to retry"},
+			{key: 'l', more: true, msg: "Reloaded dnsmasq"},
+			{key: 'l', err: errors.New("exit status 1"), more: true, msg: "Reload failed: unable to reload dnsmasq: exit status 1 "},
+			{key: 'x', more: true, msg: "Reload failed: unable to reload dnsmasq: exit status 1 "},
+			{key: 'q', more: false},
+		}
+
+		for _, tt := range tests {
+			r.err = tt.err
+			So(tu.handle(tt.key), ShouldEqual, tt.more)
+			if tt.more {
+				So(tu.msg, ShouldEqual, tt.msg)
+			}
+		}
+
+		c.RunState().Report(e.Progress{Source: "big", Bytes: 1 << 20, Total: 2 << 20})
+		c.RunState().Report(e.Progress{Source: "done", Bytes: 1 << 20, Total: -1})
+		c.RunState().Report(e.Progress{Source: "done", Lines: 10, Done: true})
+		So(tu.active(), ShouldResemble, []string{"big\t\t\tdownloaded 1.0 MiB of 2.0 MiB (50%)"})
+
+		tu.draw()
+		So(act.String(), ShouldStartWith, "\x1b[H\x1b[2Jblacklist ")
+		So(act.String(), ShouldContainSubstring, "SOURCE  TYPE  ENTRIES  STATUS\r\n")
+		So(strings.HasSuffix(act.String(), "[r] retry failed  [l] reload dnsmasq  [q] quit\r\n"), ShouldBeTrue)
+	})
+}